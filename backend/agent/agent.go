@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net"
@@ -460,6 +461,47 @@ func (sa *StorkAgent) TailTextFile(ctx context.Context, in *agentapi.TailTextFil
 	return response, nil
 }
 
+// Returns the parsed configuration of the BIND 9 server. The server is
+// identified by its control access point. The secrets of the keys are
+// hidden.
+func (sa *StorkAgent) GetBind9Config(ctx context.Context, in *agentapi.GetBind9ConfigReq) (*agentapi.GetBind9ConfigRsp, error) {
+	response := &agentapi.GetBind9ConfigRsp{
+		Status: &agentapi.Status{
+			Code: agentapi.Status_OK, // all ok
+		},
+	}
+
+	app := sa.AppMonitor.GetApp(AppTypeBind9, AccessPointControl, in.ControlAddress, in.ControlPort)
+	bind9App, ok := app.(*Bind9App)
+	if !ok || bind9App == nil {
+		response.Status.Code = agentapi.Status_ERROR
+		response.Status.Message = "Cannot find BIND 9 app"
+		return response, nil
+	}
+
+	config, err := bind9App.getConfig()
+	if err != nil {
+		log.WithError(err).
+			WithFields(log.Fields{
+				"Address": in.ControlAddress,
+				"Port":    in.ControlPort,
+			}).Error("Failed to get BIND 9 configuration")
+		response.Status.Code = agentapi.Status_ERROR
+		response.Status.Message = fmt.Sprintf("Failed to get BIND 9 configuration: %s", err)
+		return response, nil
+	}
+
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		response.Status.Code = agentapi.Status_ERROR
+		response.Status.Message = fmt.Sprintf("Failed to serialize BIND 9 configuration: %s", err)
+		return response, nil
+	}
+	response.Config = string(configJSON)
+
+	return response, nil
+}
+
 // Starts the gRPC and HTTP listeners.
 func (sa *StorkAgent) Serve() error {
 	// Install gRPC API handlers.
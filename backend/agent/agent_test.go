@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"os"
@@ -18,6 +19,7 @@ import (
 
 	"isc.org/stork"
 	agentapi "isc.org/stork/api"
+	bind9config "isc.org/stork/appcfg/bind9"
 	"isc.org/stork/hooks"
 	"isc.org/stork/testutil"
 )
@@ -472,25 +474,50 @@ func TestForwardRndcCommandSuccess(t *testing.T) {
 	// Check expected output.
 	require.Equal(t, rsp.RndcResponse.Response, "Server is up and running")
 
-	// Empty request.
-	cmd = &agentapi.RndcRequest{Request: ""}
+	// Allowed request with arguments.
+	cmd = &agentapi.RndcRequest{Request: "flushname www.example.org"}
 	req.RndcRequest = cmd
 	rsp, err = sa.ForwardRndcCommand(ctx, req)
 	require.NotNil(t, rsp)
 	require.NoError(t, err)
 	require.Equal(t, agentapi.Status_OK, rsp.Status.Code)
 	require.Empty(t, rsp.Status.Message)
-	require.Equal(t, rsp.RndcResponse.Response, "unknown command")
+}
 
-	// Unknown request.
-	cmd = &agentapi.RndcRequest{Request: "foobar"}
-	req.RndcRequest = cmd
-	rsp, err = sa.ForwardRndcCommand(ctx, req)
-	require.NotNil(t, rsp)
-	require.NoError(t, err)
-	require.Equal(t, agentapi.Status_OK, rsp.Status.Code)
-	require.Empty(t, rsp.Status.Message)
-	require.Equal(t, rsp.RndcResponse.Response, "unknown command")
+// Test that the rndc commands not in the allow-list are not forwarded.
+func TestForwardRndcCommandNotAllowed(t *testing.T) {
+	sa, ctx, teardown := setupAgentTest()
+	defer teardown()
+	executor := newTestCommandExecutorDefault()
+	rndcClient := NewRndcClient(executor)
+	rndcClient.BaseCommand = []string{"/rndc"}
+
+	accessPoints := makeAccessPoint(AccessPointControl, "127.0.0.1", "_", 1234, false)
+	var apps []App
+	apps = append(apps, &Bind9App{
+		BaseApp: BaseApp{
+			Type:         AppTypeBind9,
+			AccessPoints: accessPoints,
+		},
+		RndcClient: rndcClient,
+	})
+	fam, _ := sa.AppMonitor.(*FakeAppMonitor)
+	fam.Apps = apps
+
+	for _, request := range []string{"", "foobar", "stop", "reload", "status -p 953"} {
+		req := &agentapi.ForwardRndcCommandReq{
+			Address:     "127.0.0.1",
+			Port:        1234,
+			RndcRequest: &agentapi.RndcRequest{Request: request},
+		}
+		// Expect an error status code and no response.
+		rsp, err := sa.ForwardRndcCommand(ctx, req)
+		require.NotNil(t, rsp)
+		require.NoError(t, err)
+		require.Equal(t, agentapi.Status_ERROR, rsp.Status.Code, request)
+		require.Contains(t, rsp.Status.Message, "Failed to forward commands to rndc")
+		require.Empty(t, rsp.RndcResponse.Response)
+	}
 }
 
 // Test rndc command failed to forward.
@@ -634,6 +661,77 @@ func TestTailTextFile(t *testing.T) {
 	require.Equal(t, "in testing TailTextFile", rsp.Lines[2])
 }
 
+// Test that the parsed BIND 9 configuration is returned with the secrets
+// hidden.
+func TestGetBind9Config(t *testing.T) {
+	sa, ctx, teardown := setupAgentTest()
+	defer teardown()
+
+	rootDir := t.TempDir()
+	err := os.WriteFile(path.Join(rootDir, "named.conf"), []byte(`
+		key "rndc-key" {
+			algorithm hmac-sha256;
+			secret "OmItW1lOyLVUEuvv+Fme+Q==";
+		};
+		include "zones.conf";`), 0o600)
+	require.NoError(t, err)
+	err = os.WriteFile(path.Join(rootDir, "zones.conf"), []byte(`zone "example.org" { type primary; };`), 0o600)
+	require.NoError(t, err)
+
+	accessPoints := makeAccessPoint(AccessPointControl, "127.0.0.1", "_", 1234, false)
+	fam, _ := sa.AppMonitor.(*FakeAppMonitor)
+	fam.Apps = []App{
+		&Bind9App{
+			BaseApp: BaseApp{
+				Type:         AppTypeBind9,
+				AccessPoints: accessPoints,
+			},
+			ConfigPath: "/named.conf",
+			RootPrefix: rootDir,
+		},
+	}
+
+	rsp, err := sa.GetBind9Config(ctx, &agentapi.GetBind9ConfigReq{
+		ControlAddress: "127.0.0.1",
+		ControlPort:    1234,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, rsp)
+	require.Equal(t, agentapi.Status_OK, rsp.Status.Code)
+
+	var config bind9config.Config
+	require.NoError(t, json.Unmarshal([]byte(rsp.Config), &config))
+	require.Equal(t, bind9config.HiddenSecret, config.GetKey("rndc-key").Secret)
+	require.Len(t, config.GetZones(), 1)
+	require.NotContains(t, rsp.Config, "OmItW1lOyLVUEuvv+Fme+Q==")
+
+	// The configuration file is missing.
+	fam.Apps[0].(*Bind9App).ConfigPath = "/missing.conf"
+	rsp, err = sa.GetBind9Config(ctx, &agentapi.GetBind9ConfigReq{
+		ControlAddress: "127.0.0.1",
+		ControlPort:    1234,
+	})
+	require.NoError(t, err)
+	require.Equal(t, agentapi.Status_ERROR, rsp.Status.Code)
+	require.Contains(t, rsp.Status.Message, "cannot read the BIND 9 configuration file /missing.conf")
+	require.Empty(t, rsp.Config)
+}
+
+// Test that an error is returned when the BIND 9 app is not found.
+func TestGetBind9ConfigNoApp(t *testing.T) {
+	sa, ctx, teardown := setupAgentTest()
+	defer teardown()
+
+	rsp, err := sa.GetBind9Config(ctx, &agentapi.GetBind9ConfigReq{
+		ControlAddress: "127.0.0.1",
+		ControlPort:    1234,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, rsp)
+	require.Equal(t, agentapi.Status_ERROR, rsp.Status.Code)
+	require.Equal(t, "Cannot find BIND 9 app", rsp.Status.Message)
+}
+
 // Checks if getRootCertificates:
 // - returns an error if the cert file doesn't exist.
 func TestGetRootCertificatesForMissingOrInvalidFiles(t *testing.T) {
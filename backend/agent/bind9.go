@@ -6,11 +6,12 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	bind9config "isc.org/stork/appcfg/bind9"
+	bind9ctrl "isc.org/stork/appctrl/bind9"
 	storkutil "isc.org/stork/util"
 )
 
@@ -45,6 +46,12 @@ func (k *Bind9RndcKey) String() string {
 type Bind9App struct {
 	BaseApp
 	RndcClient *RndcClient // to communicate with BIND 9 via rndc
+	// Path to the named configuration file. It is relative to the
+	// RootPrefix if named is chrooted.
+	ConfigPath string
+	// Path to the chroot directory of named or an empty string if named
+	// is not chrooted.
+	RootPrefix string
 }
 
 // Get base information about BIND 9 app.
@@ -136,8 +143,14 @@ func (rc *RndcClient) DetermineDetails(baseNamedDir, bind9ConfDir string, ctrlAd
 	return nil
 }
 
-// Send command to named using rndc executable.
+// Send command to named using rndc executable. Only the commands from the
+// allow-list are sent. The other commands are rejected to prevent running
+// arbitrary rndc commands, e.g., stop, remotely.
 func (rc *RndcClient) SendCommand(command []string) (output []byte, err error) {
+	if err := bind9ctrl.ValidateRndcCommand(command); err != nil {
+		return nil, err
+	}
+
 	var rndcCommand []string
 	rndcCommand = append(rndcCommand, rc.BaseCommand...)
 	rndcCommand = append(rndcCommand, command...)
@@ -159,40 +172,33 @@ func (rc *RndcClient) SendCommand(command []string) (output []byte, err error) {
 //		secret "OmItW1lOyLVUEuvv+Fme+Q==";
 //	};
 func getRndcKey(contents, name string) (controlKey *Bind9RndcKey) {
-	pattern := regexp.MustCompile(`(?s)key\s+\"(\S+)\"\s+\{(.*?)\}\s*;`)
-	keys := pattern.FindAllStringSubmatch(contents, -1)
-	if len(keys) == 0 {
+	config, err := bind9config.Parse(contents)
+	if err != nil {
+		log.WithError(err).Warnf("Cannot parse BIND 9 configuration to find the key %s", name)
 		return nil
 	}
+	return getRndcKeyFromConfig(config, name)
+}
 
-	for _, key := range keys {
-		if key[1] != name {
-			continue
-		}
-		pattern = regexp.MustCompile(`(?s)algorithm\s+\"(\S+)\";`)
-		algorithm := pattern.FindStringSubmatch(key[2])
-		if len(algorithm) < 2 {
-			log.Warnf("No key algorithm found for name %s", name)
-			return nil
-		}
-
-		pattern = regexp.MustCompile(`(?s)secret\s+\"(\S+)\";`)
-		secret := pattern.FindStringSubmatch(key[2])
-		if len(secret) < 2 {
-			log.Warnf("No key secret found for name %s", name)
-			return nil
-		}
-
-		// this key clause matches the name we are looking for
-		controlKey = &Bind9RndcKey{
-			Name:      name,
-			Algorithm: algorithm[1],
-			Secret:    secret[1],
-		}
-		break
+// Looks for the key with a given name in the parsed configuration.
+func getRndcKeyFromConfig(config *bind9config.Config, name string) *Bind9RndcKey {
+	key := config.GetKey(name)
+	if key == nil {
+		return nil
+	}
+	if key.Algorithm == "" {
+		log.Warnf("No key algorithm found for name %s", name)
+		return nil
+	}
+	if key.Secret == "" {
+		log.Warnf("No key secret found for name %s", name)
+		return nil
+	}
+	return &Bind9RndcKey{
+		Name:      key.Name,
+		Algorithm: key.Algorithm,
+		Secret:    key.Secret,
 	}
-
-	return controlKey
 }
 
 // parseInetSpec parses an inet statement from a named configuration excerpt.
@@ -206,74 +212,39 @@ func getRndcKey(contents, name string) (controlKey *Bind9RndcKey) {
 // referenced in the key_list.  If instead of an ip_addr, the asterisk (*) is
 // specified, this function will return 'localhost' as an address.
 func parseInetSpec(config, excerpt string) (address string, port int64, key *Bind9RndcKey) {
-	pattern := regexp.MustCompile(
-		`(?s)` + // Enable multiline mode (\s matches a new line).
-			`inet` + // Fixed prefix statement.
-			`\s+` + // Mandatory spacing.
-			// First matching group.
-			`(\S+\s*\S*\s*\d*)` + // ( ip_addr | * ) [ port ip_port ]
-			`\s+` + // Mandatory spacing.
-			`allow` + // Fixed statement.
-			`\s*\{\s*` + // Opening clause surrounded by optional spacing.
-			// Non-matching group repeated zero or more times.
-			`(?:\s*\S+\s*;\s*)*` + // address_match_list
-			`\s*\}` + // Closing clause prepended by an optional spacing.
-			// Second matching group.
-			`(.*)` + // keys { key_list }; (pattern matched below)
-			`;`, // Trailing semicolon.
-	)
-	match := pattern.FindStringSubmatch(excerpt)
-	if len(match) == 0 {
-		log.Warnf("Cannot parse BIND 9 inet configuration: no match (%+v)", config)
+	parsedExcerpt, err := bind9config.Parse(excerpt)
+	if err != nil {
+		log.WithError(err).Warn("Cannot parse BIND 9 inet configuration")
 		return "", 0, nil
 	}
-
-	inetSpec := regexp.MustCompile(`\s+`).Split(match[1], 3)
-	switch len(inetSpec) {
-	case 1:
-		address = inetSpec[0]
-	case 3:
-		address = inetSpec[0]
-		if inetSpec[1] != "port" {
-			log.Warnf("Cannot parse BIND 9 control port: bad port statement (%+v)", inetSpec)
-			return "", 0, nil
-		}
-
-		iPort, err := strconv.Atoi(inetSpec[2])
-		if err != nil {
-			log.Warnf("Cannot parse BIND 9 control port: %+v (%+v)", inetSpec, err)
-			return "", 0, nil
-		}
-		port = int64(iPort)
-	case 2:
-	default:
-		log.Warnf("Cannot parse BIND 9 inet_spec configuration: no match (%+v)", inetSpec)
+	inet := parsedExcerpt.GetStatement("inet")
+	if inet == nil {
+		log.Warnf("Cannot parse BIND 9 inet configuration: no match (%+v)", excerpt)
 		return "", 0, nil
 	}
-
-	if len(match) == 3 {
-		// Find a key clause. This pattern is build up like this:
-		// keys\s*                - keys
-		// \{\s*                  - {
-		// \"(.*?)\"\s*;\s*       - key_list (first)
-		// (?:\s*\".*?\"\s*;\s*)* - key_list (remainder)
-		// \}                     - }
-		pattern = regexp.MustCompile(`(?s)keys\s*\{\s*\"(.*?)\"\s*;\s*(?:\s*\".*?\"\s*;\s*)*\}`)
-
-		keyName := pattern.FindStringSubmatch(match[2])
-		if len(keyName) > 1 {
-			key = getRndcKey(config, keyName[1])
-			if key == nil {
-				log.WithField("key", keyName[1]).Warn("Cannot find key details")
-			}
-		}
+	parsedConfig, err := bind9config.Parse(config)
+	if err != nil {
+		log.WithError(err).Warn("Cannot parse BIND 9 configuration")
+		return "", 0, nil
 	}
+	return getInetSpec(parsedConfig, inet)
+}
 
-	if address == "*" {
-		address = "localhost"
+// Returns the address, port and the first key of the inet statement. The
+// key details are taken from the configuration.
+func getInetSpec(config *bind9config.Config, statement *bind9config.Statement) (address string, port int64, key *Bind9RndcKey) {
+	inet, err := bind9config.NewInet(statement)
+	if err != nil {
+		log.WithError(err).Warn("Cannot parse BIND 9 inet configuration")
+		return "", 0, nil
 	}
-
-	return address, port, key
+	if len(inet.Keys) > 0 {
+		key = getRndcKeyFromConfig(config, inet.Keys[0])
+		if key == nil {
+			log.WithField("key", inet.Keys[0]).Warn("Cannot find key details")
+		}
+	}
+	return inet.Address, inet.Port, key
 }
 
 // getCtrlAddressFromBind9Config retrieves the rndc control access address,
@@ -287,7 +258,8 @@ func parseInetSpec(config, excerpt string) (address string, port int64, key *Bin
 // Multiple controls clauses may be configured but currently this function
 // only matches the first one.  Multiple access points may be listed inside
 // a single controls clause, but this function currently only matches the
-// first in the list.  A controls clause may look like this:
+// first inet access point in the list.  A controls clause may look like
+// this:
 //
 //		controls {
 //			inet 127.0.0.1 allow {localhost;};
@@ -299,33 +271,37 @@ func parseInetSpec(config, excerpt string) (address string, port int64, key *Bin
 // clauses.
 //
 // Finding the key is done by looking if the control access point has a
-// keys parameter and if so, it looks in `path` for a key clause with the
-// same name.
+// keys parameter and if so, it looks in the configuration for a key clause
+// with the same name.
 func getCtrlAddressFromBind9Config(text string) (controlAddress string, controlPort int64, controlKey *Bind9RndcKey) {
-	// Match the following clause:
-	//     controls {
-	//         inet inet_spec [inet_spec] ;
-	//     };
-	// or
-	//     controls { /maybe some whitespace chars here/ };
-	pattern := regexp.MustCompile(`(?s)controls\s*\{\s*(.*)\s*\}\s*;`)
-	controls := pattern.FindStringSubmatch(text)
-	if len(controls) == 0 {
+	config, err := bind9config.Parse(text)
+	if err != nil {
+		log.WithError(err).Warn("Cannot parse BIND 9 configuration to find the control access point")
+		return "", 0, nil
+	}
+
+	controls := config.GetStatement("controls")
+	if controls == nil {
 		log.Debugf("BIND9 has no `controls` clause, assuming defaults (127.0.0.1, port 953)")
 		return "127.0.0.1", 953, nil
 	}
 
-	// See if there's any non-whitespace characters in the controls clause.
-	// If not, there's `controls {};`, which means: disable control socket.
-	txt := strings.TrimSpace(controls[1])
-	if len(txt) == 0 {
+	// If there are no statements in the controls clause, there's
+	// `controls {};`, which means: disable control socket.
+	block := controls.GetBlock()
+	if block == nil || len(block.Statements) == 0 {
 		log.Debugf("BIND9 has rndc support disabled (empty 'controls' found)")
 		return "", 0, nil
 	}
 
 	// We only pick the first match, but the controls clause
 	// can list multiple control access points.
-	controlAddress, controlPort, controlKey = parseInetSpec(text, controls[1])
+	inet := block.GetStatement("inet")
+	if inet == nil {
+		log.Warn("BIND9 has no inet control access point configured")
+		return "", 0, nil
+	}
+	controlAddress, controlPort, controlKey = getInetSpec(config, inet)
 	if controlAddress != "" {
 		// If no port was provided, use the default rndc port.
 		if controlPort == 0 {
@@ -350,19 +326,24 @@ func getCtrlAddressFromBind9Config(text string) (controlAddress string, controlP
 //
 // In this example, "stats-clients" refers to an acl clause.
 func getStatisticsChannelFromBind9Config(text string) (statsAddress string, statsPort int64) {
-	// Match the following clause:
-	//     statistics-channels {
-	//         inet inet_spec [inet_spec] ;
-	//     };
-	pattern := regexp.MustCompile(`(?s)statistics-channels\s*\{\s*(.*)\s*\}\s*;`)
-	channels := pattern.FindStringSubmatch(text)
-	if len(channels) == 0 {
+	config, err := bind9config.Parse(text)
+	if err != nil {
+		log.WithError(err).Warn("Cannot parse BIND 9 configuration to find the statistics channel")
+		return "", 0
+	}
+
+	channels := config.GetStatement("statistics-channels")
+	if channels == nil || channels.GetBlock() == nil {
 		return "", 0
 	}
 
 	// We only pick the first match, but the statistics-channels clause
 	// can list multiple control access points.
-	statsAddress, statsPort, _ = parseInetSpec(text, channels[1])
+	inet := channels.GetBlock().GetStatement("inet")
+	if inet == nil {
+		return "", 0
+	}
+	statsAddress, statsPort, _ = getInetSpec(config, inet)
 	if statsAddress != "" {
 		// If no port was provided, use the default statistics channel port.
 		if statsPort == 0 {
@@ -636,6 +617,8 @@ func detectBind9App(match []string, cwd string, executor storkutil.CommandExecut
 			AccessPoints: accessPoints,
 		},
 		RndcClient: rndcClient,
+		ConfigPath: bind9ConfPath,
+		RootPrefix: rootPrefix,
 	}
 
 	return bind9App
@@ -645,3 +628,17 @@ func detectBind9App(match []string, cwd string, executor storkutil.CommandExecut
 func (ba *Bind9App) sendCommand(command []string) (output []byte, err error) {
 	return ba.RndcClient.SendCommand(command)
 }
+
+// Parses the named configuration file and the files it includes. The
+// secrets of the keys are hidden in the returned configuration.
+func (ba *Bind9App) getConfig() (*bind9config.Config, error) {
+	if ba.ConfigPath == "" {
+		return nil, errors.New("path to the BIND 9 configuration file is unknown")
+	}
+	config, err := bind9config.ParseFile(ba.ConfigPath, ba.RootPrefix)
+	if err != nil {
+		return nil, err
+	}
+	config.HideSecrets()
+	return config, nil
+}
@@ -182,6 +182,52 @@ func TestGetCtrlAddressFromBind9Config(t *testing.T) {
 	}
 }
 
+// Test that the control and statistics channel access points are found
+// regardless of the comments and the statement order, which confused the
+// regular expressions used before.
+func TestGetAccessPointsFromBind9ConfigWithComments(t *testing.T) {
+	config := `
+		// controls { };
+		/* statistics-channels { inet 192.0.2.2 port 80 allow { any; }; }; */
+		key "foo" { algorithm hmac-sha256; secret "abcd"; };
+		statistics-channels {
+			# The first channel is selected.
+			inet 127.0.0.1 port 8053 allow { localhost; };
+			inet 192.0.2.1 port 8054 allow { any; };
+		};
+		controls {
+			unix "/run/named/rndc.sock" perm 0600 owner 0 group 0;
+			inet * port 7766 allow { "rndc-users"; } keys { foo; };
+		};`
+
+	address, port, key := getCtrlAddressFromBind9Config(config)
+	require.Equal(t, "localhost", address)
+	require.EqualValues(t, 7766, port)
+	require.NotNil(t, key)
+	require.Equal(t, "foo:hmac-sha256:abcd", key.String())
+
+	address, port = getStatisticsChannelFromBind9Config(config)
+	require.Equal(t, "127.0.0.1", address)
+	require.EqualValues(t, 8053, port)
+}
+
+// Test that no access points are returned when the configuration cannot
+// be parsed or the control access point is not an inet socket.
+func TestGetAccessPointsFromInvalidBind9Config(t *testing.T) {
+	address, port, key := getCtrlAddressFromBind9Config(`controls { inet 127.0.0.1 allow { localhost; }`)
+	require.Empty(t, address)
+	require.Zero(t, port)
+	require.Nil(t, key)
+
+	address, port, _ = getCtrlAddressFromBind9Config(`controls { unix "/run/named/rndc.sock" perm 0600 owner 0 group 0; };`)
+	require.Empty(t, address)
+	require.Zero(t, port)
+
+	address, port = getStatisticsChannelFromBind9Config(`statistics-channels { inet 127.0.0.1 port http allow { any; }; };`)
+	require.Empty(t, address)
+	require.Zero(t, port)
+}
+
 // The command executor implementation for the testing purposes.
 // It implements the builder pattern for the configuration methods.
 type testCommandExecutor struct {
@@ -347,6 +393,8 @@ func TestDetectBind9ChrootStep1ProcessCmdLine(t *testing.T) {
 	require.Equal(t, "1.1.1.1", point.Address)
 	require.EqualValues(t, 1111, point.Port)
 	require.EqualValues(t, "foo:hmac-sha256:abcd", point.Key)
+	require.Equal(t, config1Path, app.(*Bind9App).ConfigPath)
+	require.Equal(t, chrootPath, app.(*Bind9App).RootPrefix)
 }
 
 // Checks detection STEP 2: if BIND9 detection takes STORK_BIND9_CONFIG env var into account.
@@ -0,0 +1,468 @@
+package bind9config
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The value replacing the secrets of the keys when they are hidden.
+const HiddenSecret = "????????"
+
+// The parsed named configuration. It holds the top-level statements, e.g.,
+// options, acl, key, view or zone.
+type Config struct {
+	Block
+}
+
+// A block of the statements enclosed in braces. The blocks are nested in
+// the statements, e.g., the options or zone statement.
+type Block struct {
+	Statements []*Statement `json:"statements"`
+}
+
+// A statement of the named configuration. It consists of the keyword and
+// the arguments terminated with a semicolon. An argument is a value or a
+// block. For example, the following statement:
+//
+//	inet 127.0.0.1 port 953 allow { localhost; } keys { "rndc-key"; };
+//
+// has the inet keyword, the 127.0.0.1, port, 953, allow and keys value
+// arguments and the two block arguments. The elements of the address
+// match lists, e.g., localhost, are the statements without arguments.
+type Statement struct {
+	Keyword string `json:"keyword"`
+	// Indicates if the keyword is a quoted string.
+	Quoted bool   `json:"quoted,omitempty"`
+	Args   []*Arg `json:"args,omitempty"`
+}
+
+// An argument of the statement. It holds a value or a block.
+type Arg struct {
+	Value string `json:"value,omitempty"`
+	// Indicates if the value is a quoted string.
+	Quoted bool   `json:"quoted,omitempty"`
+	Block  *Block `json:"block,omitempty"`
+}
+
+// An access control list defined with the acl statement.
+type ACL struct {
+	Name string
+	// The elements of the address match list, e.g., 192.0.2.0/24,
+	// !192.0.2.1 or key "rndc-key".
+	Elements []string
+}
+
+// A key defined with the key statement.
+type Key struct {
+	Name      string
+	Algorithm string
+	Secret    string
+}
+
+// A view defined with the view statement.
+type View struct {
+	Name         string
+	Class        string
+	MatchClients []string
+	Zones        []*Zone
+}
+
+// A zone defined with the zone statement.
+type Zone struct {
+	Name  string
+	Class string
+	// The view the zone belongs to. It is empty for the zones defined
+	// outside of the views.
+	View string
+	Type string
+	File string
+	// The primary servers of a secondary zone.
+	Primaries []string
+}
+
+// An inet clause of the controls or statistics-channels statement.
+type Inet struct {
+	Address string
+	// The port or zero if it is not specified.
+	Port  int64
+	Allow []string
+	// The names of the keys.
+	Keys []string
+}
+
+// Returns the values of the arguments preceding the first block.
+func (s *Statement) GetValues() []string {
+	values := []string{}
+	for _, arg := range s.Args {
+		if arg.Block != nil {
+			break
+		}
+		values = append(values, arg.Value)
+	}
+	return values
+}
+
+// Returns the first block of the statement or nil if the statement has no
+// blocks.
+func (s *Statement) GetBlock() *Block {
+	for _, arg := range s.Args {
+		if arg.Block != nil {
+			return arg.Block
+		}
+	}
+	return nil
+}
+
+// Returns the block following the value argument, e.g., the block of the
+// allow clause of the inet statement. It returns nil if there is no such
+// block.
+func (s *Statement) GetBlockAfter(value string) *Block {
+	for i, arg := range s.Args {
+		if arg.Block == nil && arg.Value == value && i+1 < len(s.Args) {
+			return s.Args[i+1].Block
+		}
+	}
+	return nil
+}
+
+// Returns the value following the value argument, e.g., the port number
+// following the port keyword. It returns an empty string if there is no
+// such value.
+func (s *Statement) GetValueAfter(value string) string {
+	for i, arg := range s.Args {
+		if arg.Block == nil && arg.Value == value && i+1 < len(s.Args) && s.Args[i+1].Block == nil {
+			return s.Args[i+1].Value
+		}
+	}
+	return ""
+}
+
+// Returns the first statement with the keyword or nil if there is no
+// such statement in the block.
+func (b *Block) GetStatement(keyword string) *Statement {
+	for _, statement := range b.Statements {
+		if statement.Keyword == keyword {
+			return statement
+		}
+	}
+	return nil
+}
+
+// Returns all statements with the keyword.
+func (b *Block) GetStatements(keyword string) []*Statement {
+	statements := []*Statement{}
+	for _, statement := range b.Statements {
+		if statement.Keyword == keyword {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+// Returns the first value of the statement with the keyword, e.g., the
+// file name of the file statement. It returns an empty string if there is
+// no such statement.
+func (b *Block) GetValue(keyword string) string {
+	if statement := b.GetStatement(keyword); statement != nil {
+		if values := statement.GetValues(); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// Returns the elements of the list enclosed in the block, e.g., an address
+// match list. The elements with the nested blocks are converted to text.
+func (b *Block) GetElements() []string {
+	if b == nil {
+		return nil
+	}
+	elements := []string{}
+	for _, statement := range b.Statements {
+		elements = append(elements, strings.TrimSuffix(statement.String(), ";"))
+	}
+	return elements
+}
+
+// Returns the options statement block or nil if there is no options
+// statement.
+func (c *Config) GetOptions() *Block {
+	if options := c.GetStatement("options"); options != nil {
+		return options.GetBlock()
+	}
+	return nil
+}
+
+// Returns the access control lists.
+func (c *Config) GetACLs() []*ACL {
+	acls := []*ACL{}
+	for _, statement := range c.GetStatements("acl") {
+		values := statement.GetValues()
+		if len(values) == 0 {
+			continue
+		}
+		acls = append(acls, &ACL{
+			Name:     values[0],
+			Elements: statement.GetBlock().GetElements(),
+		})
+	}
+	return acls
+}
+
+// Converts the key statement to the key. It returns nil if the statement
+// lacks the key name or block.
+func newKey(statement *Statement) *Key {
+	values := statement.GetValues()
+	block := statement.GetBlock()
+	if len(values) == 0 || block == nil {
+		return nil
+	}
+	return &Key{
+		Name:      values[0],
+		Algorithm: block.GetValue("algorithm"),
+		Secret:    block.GetValue("secret"),
+	}
+}
+
+// Returns the keys defined at the top level of the configuration.
+func (c *Config) GetKeys() []*Key {
+	keys := []*Key{}
+	for _, statement := range c.GetStatements("key") {
+		if key := newKey(statement); key != nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Returns the key with the name defined at the top level of the
+// configuration or nil if there is no such key.
+func (c *Config) GetKey(name string) *Key {
+	for _, key := range c.GetKeys() {
+		if key.Name == name {
+			return key
+		}
+	}
+	return nil
+}
+
+// Converts the zone statement to the zone. It returns nil if the statement
+// lacks the zone name.
+func newZone(statement *Statement, view string) *Zone {
+	values := statement.GetValues()
+	if len(values) == 0 {
+		return nil
+	}
+	zone := &Zone{
+		Name:  values[0],
+		Class: "IN",
+		View:  view,
+	}
+	if len(values) > 1 {
+		zone.Class = values[1]
+	}
+	if block := statement.GetBlock(); block != nil {
+		zone.Type = block.GetValue("type")
+		zone.File = block.GetValue("file")
+		for _, keyword := range []string{"primaries", "masters"} {
+			if primaries := block.GetStatement(keyword); primaries != nil {
+				zone.Primaries = primaries.GetBlock().GetElements()
+				break
+			}
+		}
+	}
+	return zone
+}
+
+// Converts the zone statements of the block to the zones.
+func getZones(block *Block, view string) []*Zone {
+	zones := []*Zone{}
+	if block == nil {
+		return zones
+	}
+	for _, statement := range block.GetStatements("zone") {
+		if zone := newZone(statement, view); zone != nil {
+			zones = append(zones, zone)
+		}
+	}
+	return zones
+}
+
+// Returns the zones defined outside of the views.
+func (c *Config) GetZones() []*Zone {
+	return getZones(&c.Block, "")
+}
+
+// Returns the views with their zones.
+func (c *Config) GetViews() []*View {
+	views := []*View{}
+	for _, statement := range c.GetStatements("view") {
+		values := statement.GetValues()
+		block := statement.GetBlock()
+		if len(values) == 0 {
+			continue
+		}
+		view := &View{
+			Name:  values[0],
+			Class: "IN",
+			Zones: getZones(block, values[0]),
+		}
+		if len(values) > 1 {
+			view.Class = values[1]
+		}
+		if block != nil {
+			if matchClients := block.GetStatement("match-clients"); matchClients != nil {
+				view.MatchClients = matchClients.GetBlock().GetElements()
+			}
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+// Replaces the secrets of all keys with the HiddenSecret value, including
+// the keys defined within the views.
+func (c *Config) HideSecrets() {
+	var hide func(block *Block, inKey bool)
+	hide = func(block *Block, inKey bool) {
+		for _, statement := range block.Statements {
+			if inKey && statement.Keyword == "secret" {
+				for _, arg := range statement.Args {
+					if arg.Block == nil {
+						arg.Value = HiddenSecret
+					}
+				}
+				continue
+			}
+			for _, arg := range statement.Args {
+				if arg.Block != nil {
+					hide(arg.Block, inKey || statement.Keyword == "key")
+				}
+			}
+		}
+	}
+	hide(&c.Block, false)
+}
+
+// Converts the inet clause of the controls or statistics-channels
+// statement. The inet clause has the following format:
+//
+//	inet ( ip_addr | * ) [ port ip_port ] allow { address_match_list } [ keys { key_list } ];
+//
+// The asterisk address is converted to localhost.
+func NewInet(statement *Statement) (*Inet, error) {
+	if statement.Keyword != "inet" {
+		return nil, errors.Errorf("expected inet clause but got %s", statement.Keyword)
+	}
+	values := statement.GetValues()
+	if len(values) == 0 {
+		return nil, errors.New("inet clause lacks the address")
+	}
+	inet := &Inet{
+		Address: values[0],
+		Allow:   statement.GetBlockAfter("allow").GetElements(),
+	}
+	if keys := statement.GetBlockAfter("keys"); keys != nil {
+		for _, key := range keys.Statements {
+			inet.Keys = append(inet.Keys, key.Keyword)
+		}
+	}
+	if inet.Address == "*" {
+		inet.Address = "localhost"
+	}
+	if len(values) > 1 && values[1] == "port" {
+		value := statement.GetValueAfter("port")
+		port, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid port number %s in the inet clause", value)
+		}
+		inet.Port = port
+	}
+	return inet, nil
+}
+
+// Quotes the string and escapes the quotes and backslashes it contains.
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// Writes the block in the text format with the given indentation. The
+// simple lists, e.g., { localhost; any; }, are written in a single line.
+func (b *Block) write(sb *strings.Builder, indent int) {
+	if len(b.Statements) == 0 {
+		sb.WriteString("{ }")
+		return
+	}
+	inline := true
+	for _, statement := range b.Statements {
+		if len(statement.Args) > 0 || statement.Keyword == "" {
+			inline = false
+			break
+		}
+	}
+	if inline {
+		sb.WriteString("{ ")
+		for _, statement := range b.Statements {
+			statement.write(sb, indent)
+			sb.WriteString(" ")
+		}
+		sb.WriteString("}")
+		return
+	}
+	sb.WriteString("{\n")
+	for _, statement := range b.Statements {
+		sb.WriteString(strings.Repeat("\t", indent+1))
+		statement.write(sb, indent+1)
+		sb.WriteString("\n")
+	}
+	sb.WriteString(strings.Repeat("\t", indent))
+	sb.WriteString("}")
+}
+
+// Writes the statement in the text format with the given indentation.
+func (s *Statement) write(sb *strings.Builder, indent int) {
+	parts := 0
+	if s.Keyword != "" || s.Quoted {
+		if s.Quoted {
+			sb.WriteString(quote(s.Keyword))
+		} else {
+			sb.WriteString(s.Keyword)
+		}
+		parts++
+	}
+	for _, arg := range s.Args {
+		if parts > 0 {
+			sb.WriteString(" ")
+		}
+		switch {
+		case arg.Block != nil:
+			arg.Block.write(sb, indent)
+		case arg.Quoted:
+			sb.WriteString(quote(arg.Value))
+		default:
+			sb.WriteString(arg.Value)
+		}
+		parts++
+	}
+	sb.WriteString(";")
+}
+
+// Returns the statement in the text format.
+func (s *Statement) String() string {
+	var sb strings.Builder
+	s.write(&sb, 0)
+	return sb.String()
+}
+
+// Returns the configuration in the text format. The comments and the
+// original formatting are not preserved.
+func (c *Config) String() string {
+	var sb strings.Builder
+	for _, statement := range c.Statements {
+		statement.write(&sb, 0)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,196 @@
+package bind9config
+
+import (
+	"encoding/json"
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+)
+
+// Sample configuration used in the tests.
+const testConfig = `
+options {
+	directory "/var/cache/bind";
+	listen-on port 53 { any; };
+};
+acl trusted { 192.0.2.0/24; !192.0.2.1; key "rndc-key"; };
+key "rndc-key" {
+	algorithm hmac-sha256;
+	secret "OmItW1lOyLVUEuvv+Fme+Q==";
+};
+controls {
+	inet * port 7766 allow { trusted; } keys { "rndc-key"; };
+};
+zone "example.org" {
+	type primary;
+	file "db.example.org";
+};
+view "external" IN {
+	match-clients { !trusted; any; };
+	key "transfer" {
+		algorithm hmac-sha512;
+		secret "c2VjcmV0";
+	};
+	zone "example.com" IN {
+		type secondary;
+		primaries { 192.0.2.2 key "transfer"; };
+	};
+	zone "example.net" CH { type slave; masters { 192.0.2.3; }; };
+};
+`
+
+// Test getting the options.
+func TestGetOptions(t *testing.T) {
+	config, err := Parse(testConfig)
+	require.NoError(t, err)
+	options := config.GetOptions()
+	require.NotNil(t, options)
+	require.Equal(t, "/var/cache/bind", options.GetValue("directory"))
+	require.Equal(t, "port", options.GetValue("listen-on"))
+	require.Empty(t, options.GetValue("recursion"))
+
+	config, err = Parse("")
+	require.NoError(t, err)
+	require.Nil(t, config.GetOptions())
+}
+
+// Test getting the access control lists.
+func TestGetACLs(t *testing.T) {
+	config, err := Parse(testConfig)
+	require.NoError(t, err)
+	acls := config.GetACLs()
+	require.Len(t, acls, 1)
+	require.Equal(t, "trusted", acls[0].Name)
+	require.Equal(t, []string{"192.0.2.0/24", "!192.0.2.1", `key "rndc-key"`}, acls[0].Elements)
+}
+
+// Test getting the keys.
+func TestGetKeys(t *testing.T) {
+	config, err := Parse(testConfig)
+	require.NoError(t, err)
+	keys := config.GetKeys()
+	require.Len(t, keys, 1)
+	require.Equal(t, &Key{Name: "rndc-key", Algorithm: "hmac-sha256", Secret: "OmItW1lOyLVUEuvv+Fme+Q=="}, keys[0])
+
+	require.Equal(t, keys[0], config.GetKey("rndc-key"))
+	// The keys defined in the views are not returned.
+	require.Nil(t, config.GetKey("transfer"))
+}
+
+// Test getting the zones and views.
+func TestGetViewsAndZones(t *testing.T) {
+	config, err := Parse(testConfig)
+	require.NoError(t, err)
+
+	zones := config.GetZones()
+	require.Len(t, zones, 1)
+	require.Equal(t, &Zone{Name: "example.org", Class: "IN", Type: "primary", File: "db.example.org"}, zones[0])
+
+	views := config.GetViews()
+	require.Len(t, views, 1)
+	require.Equal(t, "external", views[0].Name)
+	require.Equal(t, "IN", views[0].Class)
+	require.Equal(t, []string{"!trusted", "any"}, views[0].MatchClients)
+	require.Len(t, views[0].Zones, 2)
+	require.Equal(t, &Zone{
+		Name:      "example.com",
+		Class:     "IN",
+		View:      "external",
+		Type:      "secondary",
+		Primaries: []string{`192.0.2.2 key "transfer"`},
+	}, views[0].Zones[0])
+	require.Equal(t, "CH", views[0].Zones[1].Class)
+	require.Equal(t, []string{"192.0.2.3"}, views[0].Zones[1].Primaries)
+}
+
+// Test that the secrets of all keys are hidden.
+func TestHideSecrets(t *testing.T) {
+	config, err := Parse(testConfig)
+	require.NoError(t, err)
+	config.HideSecrets()
+
+	require.Equal(t, HiddenSecret, config.GetKey("rndc-key").Secret)
+	require.NotContains(t, config.String(), "OmItW1lOyLVUEuvv+Fme+Q==")
+	require.NotContains(t, config.String(), "c2VjcmV0")
+	// The other statements are not modified.
+	require.Equal(t, "hmac-sha256", config.GetKey("rndc-key").Algorithm)
+}
+
+// Test converting the inet clauses.
+func TestNewInet(t *testing.T) {
+	config, err := Parse(`
+		inet * port 7766 allow { trusted; } keys { "rndc-key"; "foo"; };
+		inet 192.0.2.1 allow { };
+		inet 192.0.2.1 port foo allow { };
+		inet;
+		unix "/run/named/rndc.sock" perm 0600 owner 0 group 0;
+	`)
+	require.NoError(t, err)
+
+	inet, err := NewInet(config.Statements[0])
+	require.NoError(t, err)
+	require.Equal(t, &Inet{
+		Address: "localhost",
+		Port:    7766,
+		Allow:   []string{"trusted"},
+		Keys:    []string{"rndc-key", "foo"},
+	}, inet)
+
+	inet, err = NewInet(config.Statements[1])
+	require.NoError(t, err)
+	require.Equal(t, "192.0.2.1", inet.Address)
+	require.Zero(t, inet.Port)
+	require.Empty(t, inet.Allow)
+	require.Nil(t, inet.Keys)
+
+	_, err = NewInet(config.Statements[2])
+	require.ErrorContains(t, err, "invalid port number foo")
+
+	_, err = NewInet(config.Statements[3])
+	require.ErrorContains(t, err, "inet clause lacks the address")
+
+	_, err = NewInet(config.Statements[4])
+	require.ErrorContains(t, err, "expected inet clause but got unix")
+}
+
+// Test that the configuration converted to text can be parsed again.
+func TestConfigString(t *testing.T) {
+	config, err := Parse(testConfig)
+	require.NoError(t, err)
+
+	text := config.String()
+	require.Contains(t, text, "zone \"example.org\" {\n\ttype primary;\n\tfile \"db.example.org\";\n};\n")
+	require.Contains(t, text, "allow { trusted; } keys { \"rndc-key\"; }")
+
+	reparsed, err := Parse(text)
+	require.NoError(t, err)
+	require.Equal(t, config, reparsed)
+
+	require.Equal(t, `file "a \"quoted\" \\ name";`, (&Statement{
+		Keyword: "file",
+		Args:    []*Arg{{Value: `a "quoted" \ name`, Quoted: true}},
+	}).String())
+	require.Equal(t, "controls { };", (&Statement{
+		Keyword: "controls",
+		Args:    []*Arg{{Block: &Block{Statements: []*Statement{}}}},
+	}).String())
+}
+
+// Test that the configuration survives the conversion to JSON and back.
+// It is sent from the agent to the server in JSON.
+func TestConfigJSON(t *testing.T) {
+	config, err := Parse(testConfig + "controls { };")
+	require.NoError(t, err)
+
+	data, err := json.Marshal(config)
+	require.NoError(t, err)
+
+	var unmarshalled Config
+	require.NoError(t, json.Unmarshal(data, &unmarshalled))
+	require.Equal(t, config.String(), unmarshalled.String())
+	// The empty block is preserved.
+	controls := unmarshalled.GetStatements("controls")
+	require.Len(t, controls, 2)
+	require.NotNil(t, controls[1].GetBlock())
+	require.Empty(t, controls[1].GetBlock().Statements)
+}
@@ -0,0 +1,272 @@
+// Package bind9config implements functions to parse and manage the BIND 9
+// configuration (named.conf).
+package bind9config
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Maximum nesting level of the included files. It protects against the
+// include loops.
+const maxIncludeDepth = 16
+
+// Kinds of the tokens of the named configuration.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOpenBrace
+	tokenCloseBrace
+	tokenSemicolon
+)
+
+// A token of the named configuration with the line it was found in.
+type token struct {
+	kind  tokenKind
+	value string
+	line  int
+}
+
+// Splits the named configuration into tokens. It skips the comments in
+// the C (/* */), C++ (//) and shell (#) styles.
+type lexer struct {
+	text []rune
+	pos  int
+	line int
+}
+
+// Checks if the text at the current position starts with the prefix.
+func (l *lexer) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(string(l.text[l.pos:min(len(l.text), l.pos+len(prefix))]), prefix)
+}
+
+// Skips the white spaces and comments before the next token.
+func (l *lexer) skipSpaceAndComments() error {
+	for l.pos < len(l.text) {
+		switch c := l.text[l.pos]; {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+		case c == '#' || l.hasPrefix("//"):
+			for l.pos < len(l.text) && l.text[l.pos] != '\n' {
+				l.pos++
+			}
+		case l.hasPrefix("/*"):
+			start := l.line
+			l.pos += 2
+			for !l.hasPrefix("*/") {
+				if l.pos >= len(l.text) {
+					return errors.Errorf("line %d: unterminated comment", start)
+				}
+				if l.text[l.pos] == '\n' {
+					l.line++
+				}
+				l.pos++
+			}
+			l.pos += 2
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// Returns the next token. It returns the token of the tokenEOF kind at
+// the end of the text.
+func (l *lexer) next() (token, error) {
+	if err := l.skipSpaceAndComments(); err != nil {
+		return token{}, err
+	}
+	if l.pos >= len(l.text) {
+		return token{kind: tokenEOF, line: l.line}, nil
+	}
+	line := l.line
+	switch l.text[l.pos] {
+	case '{':
+		l.pos++
+		return token{kind: tokenOpenBrace, value: "{", line: line}, nil
+	case '}':
+		l.pos++
+		return token{kind: tokenCloseBrace, value: "}", line: line}, nil
+	case ';':
+		l.pos++
+		return token{kind: tokenSemicolon, value: ";", line: line}, nil
+	case '"':
+		l.pos++
+		var value strings.Builder
+		for {
+			if l.pos >= len(l.text) {
+				return token{}, errors.Errorf("line %d: unterminated string", line)
+			}
+			c := l.text[l.pos]
+			l.pos++
+			switch c {
+			case '"':
+				return token{kind: tokenString, value: value.String(), line: line}, nil
+			case '\\':
+				if l.pos < len(l.text) {
+					c = l.text[l.pos]
+					l.pos++
+				}
+			case '\n':
+				l.line++
+			}
+			value.WriteRune(c)
+		}
+	default:
+		start := l.pos
+		for l.pos < len(l.text) {
+			c := l.text[l.pos]
+			if strings.ContainsRune(" \t\r\n{};\"", c) || l.hasPrefix("//") || l.hasPrefix("/*") {
+				break
+			}
+			l.pos++
+		}
+		return token{kind: tokenWord, value: string(l.text[start:l.pos]), line: line}, nil
+	}
+}
+
+// Builds the statements from the tokens and resolves the include
+// statements.
+type parser struct {
+	lexer *lexer
+	// Reads the included files. It is nil if the includes are not
+	// resolved.
+	include func(path string) ([]*Statement, error)
+}
+
+// Parses the statements until the end of the text or the end of the
+// block.
+func (p *parser) parseStatements(inBlock bool) ([]*Statement, error) {
+	statements := []*Statement{}
+	for {
+		tok, err := p.lexer.next()
+		if err != nil {
+			return nil, err
+		}
+		var statement *Statement
+		switch tok.kind {
+		case tokenEOF:
+			if inBlock {
+				return nil, errors.Errorf("line %d: unexpected end of the configuration; missing '}'", tok.line)
+			}
+			return statements, nil
+		case tokenCloseBrace:
+			if !inBlock {
+				return nil, errors.Errorf("line %d: unexpected '}'", tok.line)
+			}
+			return statements, nil
+		case tokenSemicolon:
+			// Empty statement.
+			continue
+		case tokenOpenBrace:
+			// Nested address match list without a keyword.
+			block, err := p.parseStatements(true)
+			if err != nil {
+				return nil, err
+			}
+			statement = &Statement{Args: []*Arg{{Block: &Block{Statements: block}}}}
+		default:
+			statement = &Statement{Keyword: tok.value, Quoted: tok.kind == tokenString}
+		}
+		if err := p.parseArgs(statement); err != nil {
+			return nil, err
+		}
+		if statement.Keyword == "include" && p.include != nil {
+			if len(statement.Args) != 1 || statement.Args[0].Block != nil {
+				return nil, errors.Errorf("line %d: include statement requires a file name", tok.line)
+			}
+			included, err := p.include(statement.Args[0].Value)
+			if err != nil {
+				return nil, err
+			}
+			statements = append(statements, included...)
+			continue
+		}
+		statements = append(statements, statement)
+	}
+}
+
+// Parses the arguments of the statement until the terminating semicolon.
+func (p *parser) parseArgs(statement *Statement) error {
+	for {
+		tok, err := p.lexer.next()
+		if err != nil {
+			return err
+		}
+		switch tok.kind {
+		case tokenSemicolon:
+			return nil
+		case tokenWord, tokenString:
+			statement.Args = append(statement.Args, &Arg{Value: tok.value, Quoted: tok.kind == tokenString})
+		case tokenOpenBrace:
+			block, err := p.parseStatements(true)
+			if err != nil {
+				return err
+			}
+			statement.Args = append(statement.Args, &Arg{Block: &Block{Statements: block}})
+		default:
+			return errors.Errorf("line %d: missing ';' after the %s statement", tok.line, statement.Keyword)
+		}
+	}
+}
+
+// Parses the named configuration text. The include statements are
+// preserved as is.
+func Parse(text string) (*Config, error) {
+	p := &parser{lexer: &lexer{text: []rune(text), line: 1}}
+	statements, err := p.parseStatements(false)
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot parse the BIND 9 configuration")
+	}
+	return &Config{Block: Block{Statements: statements}}, nil
+}
+
+// Parses the named configuration file and the files it includes. The
+// include statements are replaced with the statements of the included
+// files. The rootDir is the chroot directory of named or an empty string
+// if named is not chrooted. The configPath and the paths of the included
+// files are relative to the rootDir. The relative paths of the included
+// files are resolved against the directory of the main configuration
+// file.
+func ParseFile(configPath, rootDir string) (*Config, error) {
+	baseDir := path.Dir(configPath)
+	var parseFile func(filePath string, depth int) ([]*Statement, error)
+	parseFile = func(filePath string, depth int) ([]*Statement, error) {
+		if depth > maxIncludeDepth {
+			return nil, errors.Errorf("too many nested includes in %s", filePath)
+		}
+		if !path.IsAbs(filePath) {
+			filePath = path.Join(baseDir, filePath)
+		}
+		content, err := os.ReadFile(filepath.Join(rootDir, filePath))
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read the BIND 9 configuration file %s", filePath)
+		}
+		p := &parser{
+			lexer: &lexer{text: []rune(string(content)), line: 1},
+			include: func(includePath string) ([]*Statement, error) {
+				return parseFile(includePath, depth+1)
+			},
+		}
+		statements, err := p.parseStatements(false)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "cannot parse the BIND 9 configuration file %s", filePath)
+		}
+		return statements, nil
+	}
+	statements, err := parseFile(configPath, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &Config{Block: Block{Statements: statements}}, nil
+}
@@ -0,0 +1,148 @@
+package bind9config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+)
+
+// Test that the tokens are recognized and the comments are skipped.
+func TestLexer(t *testing.T) {
+	l := &lexer{text: []rune(`# shell comment
+	options{ // C++ comment
+		/* C comment
+		   spanning lines */ directory "/var/cache/\"bind\"";
+		allow-query { 10.0.0.0/8; };
+	};`), line: 1}
+
+	expected := []token{
+		{tokenWord, "options", 2},
+		{tokenOpenBrace, "{", 2},
+		{tokenWord, "directory", 4},
+		{tokenString, `/var/cache/"bind"`, 4},
+		{tokenSemicolon, ";", 4},
+		{tokenWord, "allow-query", 5},
+		{tokenOpenBrace, "{", 5},
+		{tokenWord, "10.0.0.0/8", 5},
+		{tokenSemicolon, ";", 5},
+		{tokenCloseBrace, "}", 5},
+		{tokenSemicolon, ";", 5},
+		{tokenCloseBrace, "}", 6},
+		{tokenSemicolon, ";", 6},
+		{tokenEOF, "", 6},
+	}
+	for _, exp := range expected {
+		tok, err := l.next()
+		require.NoError(t, err)
+		require.Equal(t, exp, tok)
+	}
+}
+
+// Test that the unterminated strings and comments are reported.
+func TestLexerUnterminated(t *testing.T) {
+	_, err := (&lexer{text: []rune(`"foo`), line: 1}).next()
+	require.ErrorContains(t, err, "line 1: unterminated string")
+
+	_, err = (&lexer{text: []rune("\n/* foo"), line: 1}).next()
+	require.ErrorContains(t, err, "line 2: unterminated comment")
+}
+
+// Test parsing the configuration with different statement types.
+func TestParse(t *testing.T) {
+	config, err := Parse(`
+		include "/etc/bind/named.conf.options";
+		controls {
+			inet 127.0.0.1 port 953 allow { localhost; } keys { "rndc-key"; };
+		};
+		acl trusted { 192.0.2.0/24; !192.0.2.1; { 10/8; }; };
+		zone "example.org" IN { type primary; file "db.example.org"; };
+	`)
+	require.NoError(t, err)
+	require.Len(t, config.Statements, 4)
+
+	// The include statement is preserved.
+	require.Equal(t, "include", config.Statements[0].Keyword)
+	require.Equal(t, []string{"/etc/bind/named.conf.options"}, config.Statements[0].GetValues())
+
+	inet := config.Statements[1].GetBlock().GetStatement("inet")
+	require.NotNil(t, inet)
+	require.Equal(t, []string{"127.0.0.1", "port", "953", "allow"}, inet.GetValues())
+	require.Equal(t, []string{"localhost"}, inet.GetBlockAfter("allow").GetElements())
+	require.Equal(t, []string{`"rndc-key"`}, inet.GetBlockAfter("keys").GetElements())
+
+	acl := config.Statements[2].GetBlock()
+	require.Len(t, acl.Statements, 3)
+	require.Equal(t, "!192.0.2.1", acl.Statements[1].Keyword)
+	require.Empty(t, acl.Statements[2].Keyword)
+	require.NotNil(t, acl.Statements[2].GetBlock())
+
+	zone := config.Statements[3]
+	require.Equal(t, []string{"example.org", "IN"}, zone.GetValues())
+	require.True(t, zone.Args[0].Quoted)
+	require.False(t, zone.Args[1].Quoted)
+	require.Equal(t, "primary", zone.GetBlock().GetValue("type"))
+}
+
+// Test that the syntax errors are reported with the line numbers.
+func TestParseErrors(t *testing.T) {
+	_, err := Parse("options {\n directory \"/var/cache/bind\";\n")
+	require.ErrorContains(t, err, "line 3: unexpected end of the configuration; missing '}'")
+
+	_, err = Parse("options { };\n};")
+	require.ErrorContains(t, err, "line 2: unexpected '}'")
+
+	_, err = Parse("options { directory \"/var/cache/bind\" }")
+	require.ErrorContains(t, err, "line 1: missing ';' after the directory statement")
+
+	_, err = Parse("zone \"example.org\" { type primary; }")
+	require.ErrorContains(t, err, "missing ';' after the zone statement")
+}
+
+// Test parsing the configuration file with the included files.
+func TestParseFile(t *testing.T) {
+	rootDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(rootDir, "etc/bind/zones"), 0o755))
+	files := map[string]string{
+		"etc/bind/named.conf": `
+			include "named.conf.options";
+			view internal {
+				include "/etc/bind/zones/internal.conf";
+			};`,
+		"etc/bind/named.conf.options": `options { directory "/var/cache/bind"; };`,
+		"etc/bind/zones/internal.conf": `
+			// Internal zones.
+			zone "example.org" { type primary; file "db.example.org"; };`,
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(rootDir, name), []byte(content), 0o600))
+	}
+
+	config, err := ParseFile("/etc/bind/named.conf", rootDir)
+	require.NoError(t, err)
+	require.Len(t, config.Statements, 2)
+	require.Equal(t, "/var/cache/bind", config.GetOptions().GetValue("directory"))
+	views := config.GetViews()
+	require.Len(t, views, 1)
+	require.Len(t, views[0].Zones, 1)
+	require.Equal(t, "example.org", views[0].Zones[0].Name)
+}
+
+// Test that the errors in the included files are reported.
+func TestParseFileErrors(t *testing.T) {
+	rootDir := t.TempDir()
+
+	_, err := ParseFile("/named.conf", rootDir)
+	require.ErrorContains(t, err, "cannot read the BIND 9 configuration file /named.conf")
+
+	require.NoError(t, os.WriteFile(filepath.Join(rootDir, "named.conf"), []byte(`include "broken.conf";`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(rootDir, "broken.conf"), []byte(`options {`), 0o600))
+	_, err = ParseFile("/named.conf", rootDir)
+	require.ErrorContains(t, err, "cannot parse the BIND 9 configuration file /broken.conf")
+
+	// The file including itself.
+	require.NoError(t, os.WriteFile(filepath.Join(rootDir, "named.conf"), []byte(`include "named.conf";`), 0o600))
+	_, err = ParseFile("/named.conf", rootDir)
+	require.ErrorContains(t, err, "too many nested includes")
+}
@@ -1,9 +1,200 @@
 package keaconfig
 
-// Represents a client class in Kea configuration.
-// todo: it currently only contains class name because it is
-// all we need for current use cases. It will have extra fields
-// when we need them.
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Represents a DHCP option specified within a client class. It differs
+// from the SingleOptionData in that the boolean flags are optional. It
+// guarantees that the class sent back to Kea contains the same option
+// parameters as it was fetched from the configuration.
+type ClientClassOptionData struct {
+	AlwaysSend *bool  `json:"always-send,omitempty"`
+	Code       uint16 `json:"code,omitempty"`
+	CSVFormat  *bool  `json:"csv-format,omitempty"`
+	Data       string `json:"data,omitempty"`
+	Name       string `json:"name,omitempty"`
+	NeverSend  *bool  `json:"never-send,omitempty"`
+	Space      string `json:"space,omitempty"`
+}
+
+// Represents a client class in Kea configuration. The structure contains
+// the class parameters supported in the DHCPv4 and DHCPv6 servers. The
+// parameters not recognized by Stork (e.g., option definitions) are held
+// in the Unrecognized map. They are preserved when the class is converted
+// back to JSON, so the class can be safely sent to Kea after modifying
+// the recognized parameters.
 type ClientClass struct {
-	Name string `json:"name"`
+	Name                 string                  `json:"name"`
+	Test                 string                  `json:"test,omitempty"`
+	TemplateTest         string                  `json:"template-test,omitempty"`
+	OnlyIfRequired       *bool                   `json:"only-if-required,omitempty"`
+	NextServer           string                  `json:"next-server,omitempty"`
+	ServerHostname       string                  `json:"server-hostname,omitempty"`
+	BootFileName         string                  `json:"boot-file-name,omitempty"`
+	ValidLifetime        *int64                  `json:"valid-lifetime,omitempty"`
+	MinValidLifetime     *int64                  `json:"min-valid-lifetime,omitempty"`
+	MaxValidLifetime     *int64                  `json:"max-valid-lifetime,omitempty"`
+	PreferredLifetime    *int64                  `json:"preferred-lifetime,omitempty"`
+	MinPreferredLifetime *int64                  `json:"min-preferred-lifetime,omitempty"`
+	MaxPreferredLifetime *int64                  `json:"max-preferred-lifetime,omitempty"`
+	OptionData           []ClientClassOptionData `json:"option-data,omitempty"`
+	UserContext          map[string]any          `json:"user-context,omitempty"`
+	Unrecognized         map[string]any          `json:"-"`
+}
+
+// Returns the set of JSON keys corresponding to the ClientClass fields.
+func getClientClassRecognizedKeys() map[string]bool {
+	keys := make(map[string]bool)
+	classType := reflect.TypeOf(ClientClass{})
+	for i := 0; i < classType.NumField(); i++ {
+		tag := classType.Field(i).Tag.Get("json")
+		key, _, _ := strings.Cut(tag, ",")
+		if key != "" && key != "-" {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// A custom unmarshaller for the client class. It parses the recognized
+// parameters into the structure fields and stores remaining parameters
+// in the Unrecognized map.
+func (c *ClientClass) UnmarshalJSON(data []byte) error {
+	type t ClientClass
+	if err := json.Unmarshal(data, (*t)(c)); err != nil {
+		return err
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	c.Unrecognized = nil
+	recognized := getClientClassRecognizedKeys()
+	for key, value := range raw {
+		if recognized[key] {
+			continue
+		}
+		if c.Unrecognized == nil {
+			c.Unrecognized = make(map[string]any)
+		}
+		c.Unrecognized[key] = value
+	}
+	return nil
+}
+
+// A custom marshaller for the client class. It outputs the recognized
+// parameters and the parameters held in the Unrecognized map. The
+// recognized parameters take precedence over the unrecognized ones with
+// the same names.
+func (c ClientClass) MarshalJSON() ([]byte, error) {
+	type t ClientClass
+	data, err := json.Marshal(t(c))
+	if err != nil || len(c.Unrecognized) == 0 {
+		return data, err
+	}
+	var raw map[string]any
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	recognized := getClientClassRecognizedKeys()
+	for key, value := range c.Unrecognized {
+		if recognized[key] {
+			continue
+		}
+		raw[key] = value
+	}
+	return json.Marshal(raw)
+}
+
+// Creates a new configuration with the client classes modified by the
+// specified function. The function receives the classes in the raw form
+// and returns the modified classes. The original configuration is not
+// modified. It returns an error if the configuration does not belong to
+// a DHCP server.
+func (c *Config) withClientClasses(modify func(classes []any) ([]any, error)) (*Config, error) {
+	rootName, ok := c.getDHCPRootName()
+	if !ok {
+		return nil, errors.New("client classes can only be set for a DHCP server")
+	}
+	// Copy the configuration to avoid modifying the original one.
+	rawConfig, err := toRawConfigValue(c.Raw)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := rawConfig.(map[string]any)[rootName].(map[string]any)
+	if !ok {
+		return nil, errors.Errorf("invalid %s configuration", rootName)
+	}
+	classes, _ := root["client-classes"].([]any)
+	if classes, err = modify(classes); err != nil {
+		return nil, err
+	}
+	root["client-classes"] = classes
+	data, err := json.Marshal(rawConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem marshalling the updated configuration")
+	}
+	return NewConfig(string(data))
+}
+
+// Returns the index of the raw client class with the specified name or -1
+// if the class does not exist.
+func findRawClientClass(classes []any, name string) int {
+	for i, class := range classes {
+		if rawClass, ok := class.(map[string]any); ok && rawClass["name"] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Creates a new configuration with the client class appended to the
+// existing classes, i.e., in the same place where the class-add command
+// puts it. It returns an error if the class already exists.
+func (c *Config) WithClientClassAdded(class *ClientClass) (*Config, error) {
+	return c.withClientClasses(func(classes []any) ([]any, error) {
+		if findRawClientClass(classes, class.Name) >= 0 {
+			return nil, errors.Errorf("client class %s already exists", class.Name)
+		}
+		rawClass, err := toRawConfigValue(class)
+		if err != nil {
+			return nil, err
+		}
+		return append(classes, rawClass), nil
+	})
+}
+
+// Creates a new configuration with the client class of the same name
+// replaced with the specified class. It returns an error if the class
+// does not exist.
+func (c *Config) WithClientClassUpdated(class *ClientClass) (*Config, error) {
+	return c.withClientClasses(func(classes []any) ([]any, error) {
+		index := findRawClientClass(classes, class.Name)
+		if index < 0 {
+			return nil, errors.Errorf("client class %s does not exist", class.Name)
+		}
+		rawClass, err := toRawConfigValue(class)
+		if err != nil {
+			return nil, err
+		}
+		classes[index] = rawClass
+		return classes, nil
+	})
+}
+
+// Creates a new configuration without the client class with the specified
+// name. It returns an error if the class does not exist.
+func (c *Config) WithClientClassDeleted(name string) (*Config, error) {
+	return c.withClientClasses(func(classes []any) ([]any, error) {
+		index := findRawClientClass(classes, name)
+		if index < 0 {
+			return nil, errors.Errorf("client class %s does not exist", name)
+		}
+		return append(classes[:index], classes[index+1:]...), nil
+	})
 }
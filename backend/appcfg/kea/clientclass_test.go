@@ -0,0 +1,227 @@
+package keaconfig
+
+import (
+	"encoding/json"
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+	storkutil "isc.org/stork/util"
+)
+
+// Test that the client class is parsed from JSON and the unrecognized
+// parameters are preserved.
+func TestClientClassUnmarshal(t *testing.T) {
+	// Arrange
+	data := `{
+		"name": "foo",
+		"test": "substring(option[61].hex,0,3) == 'foo'",
+		"only-if-required": true,
+		"next-server": "192.0.2.1",
+		"valid-lifetime": 3600,
+		"option-data": [
+			{
+				"name": "domain-name-servers",
+				"data": "192.0.2.2",
+				"always-send": true
+			}
+		],
+		"user-context": {
+			"comment": "bar"
+		},
+		"option-def": [
+			{
+				"name": "baz",
+				"code": 224,
+				"type": "uint32"
+			}
+		]
+	}`
+
+	// Act
+	var class ClientClass
+	err := json.Unmarshal([]byte(data), &class)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, "foo", class.Name)
+	require.Equal(t, "substring(option[61].hex,0,3) == 'foo'", class.Test)
+	require.True(t, *class.OnlyIfRequired)
+	require.Equal(t, "192.0.2.1", class.NextServer)
+	require.EqualValues(t, 3600, *class.ValidLifetime)
+	require.Nil(t, class.PreferredLifetime)
+	require.Len(t, class.OptionData, 1)
+	require.Equal(t, "domain-name-servers", class.OptionData[0].Name)
+	require.True(t, *class.OptionData[0].AlwaysSend)
+	require.Nil(t, class.OptionData[0].CSVFormat)
+	require.Equal(t, "bar", class.UserContext["comment"])
+	require.Len(t, class.Unrecognized, 1)
+	require.Contains(t, class.Unrecognized, "option-def")
+}
+
+// Test that the client class is converted to JSON with the unrecognized
+// parameters.
+func TestClientClassMarshal(t *testing.T) {
+	// Arrange
+	class := ClientClass{
+		Name:          "foo",
+		Test:          "member('bar')",
+		ValidLifetime: storkutil.Ptr(int64(1800)),
+		OptionData: []ClientClassOptionData{
+			{
+				Code:      6,
+				Data:      "192.0.2.2",
+				CSVFormat: storkutil.Ptr(true),
+			},
+		},
+		Unrecognized: map[string]any{
+			"option-def": []any{},
+			// The recognized parameter must not be overridden.
+			"name": "bar",
+		},
+	}
+
+	// Act
+	data, err := json.Marshal(class)
+
+	// Assert
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"name": "foo",
+		"test": "member('bar')",
+		"valid-lifetime": 1800,
+		"option-data": [
+			{
+				"code": 6,
+				"data": "192.0.2.2",
+				"csv-format": true
+			}
+		],
+		"option-def": []
+	}`, string(data))
+}
+
+// Test that the client class without the unrecognized parameters is
+// converted to JSON.
+func TestClientClassMarshalNoUnrecognized(t *testing.T) {
+	data, err := json.Marshal(&ClientClass{Name: "foo"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name": "foo"}`, string(data))
+}
+
+// Returns a test DHCPv4 server configuration with two client classes.
+func getTestClientClassesConfig(t *testing.T) *Config {
+	config, err := NewConfig(`{
+		"Dhcp4": {
+			"client-classes": [
+				{
+					"name": "foo",
+					"test": "member('ALL')"
+				},
+				{
+					"name": "bar",
+					"test": "member('foo')",
+					"option-def": [
+						{
+							"name": "opt",
+							"code": 222,
+							"type": "uint8"
+						}
+					]
+				}
+			]
+		},
+		"hash": "1234"
+	}`)
+	require.NoError(t, err)
+	return config
+}
+
+// Test that the client class is appended to the configuration.
+func TestWithClientClassAdded(t *testing.T) {
+	config := getTestClientClassesConfig(t)
+
+	updated, err := config.WithClientClassAdded(&ClientClass{
+		Name:         "baz",
+		Test:         "member('bar')",
+		Unrecognized: map[string]any{"option-def": []any{}},
+	})
+	require.NoError(t, err)
+
+	classes := updated.GetClientClasses()
+	require.Len(t, classes, 3)
+	require.Equal(t, "foo", classes[0].Name)
+	require.Equal(t, "bar", classes[1].Name)
+	require.Contains(t, classes[1].Unrecognized, "option-def")
+	require.Equal(t, "baz", classes[2].Name)
+	require.Equal(t, "member('bar')", classes[2].Test)
+	require.Contains(t, classes[2].Unrecognized, "option-def")
+
+	// The original configuration is not modified.
+	require.Len(t, config.GetClientClasses(), 2)
+
+	// The class must not be added twice.
+	_, err = config.WithClientClassAdded(&ClientClass{Name: "foo"})
+	require.ErrorContains(t, err, "client class foo already exists")
+}
+
+// Test that the client class is added to the configuration without classes.
+func TestWithClientClassAddedFirst(t *testing.T) {
+	config, err := NewConfig(`{"Dhcp6": {}}`)
+	require.NoError(t, err)
+
+	updated, err := config.WithClientClassAdded(&ClientClass{Name: "foo"})
+	require.NoError(t, err)
+	require.Len(t, updated.GetClientClasses(), 1)
+	require.Equal(t, "foo", updated.GetClientClasses()[0].Name)
+}
+
+// Test that the client class is replaced in the configuration.
+func TestWithClientClassUpdated(t *testing.T) {
+	config := getTestClientClassesConfig(t)
+
+	updated, err := config.WithClientClassUpdated(&ClientClass{
+		Name: "foo",
+		Test: "member('KNOWN')",
+	})
+	require.NoError(t, err)
+
+	classes := updated.GetClientClasses()
+	require.Len(t, classes, 2)
+	require.Equal(t, "foo", classes[0].Name)
+	require.Equal(t, "member('KNOWN')", classes[0].Test)
+	require.Equal(t, "bar", classes[1].Name)
+
+	// The original configuration is not modified.
+	require.Equal(t, "member('ALL')", config.GetClientClasses()[0].Test)
+
+	_, err = config.WithClientClassUpdated(&ClientClass{Name: "baz"})
+	require.ErrorContains(t, err, "client class baz does not exist")
+}
+
+// Test that the client class is removed from the configuration.
+func TestWithClientClassDeleted(t *testing.T) {
+	config := getTestClientClassesConfig(t)
+
+	updated, err := config.WithClientClassDeleted("foo")
+	require.NoError(t, err)
+
+	classes := updated.GetClientClasses()
+	require.Len(t, classes, 1)
+	require.Equal(t, "bar", classes[0].Name)
+
+	// The original configuration is not modified.
+	require.Len(t, config.GetClientClasses(), 2)
+
+	_, err = config.WithClientClassDeleted("baz")
+	require.ErrorContains(t, err, "client class baz does not exist")
+}
+
+// Test that the client classes can only be modified in a DHCP server
+// configuration.
+func TestWithClientClassNonDHCP(t *testing.T) {
+	config, err := NewConfig(`{"DhcpDdns": {}}`)
+	require.NoError(t, err)
+
+	_, err = config.WithClientClassAdded(&ClientClass{Name: "foo"})
+	require.ErrorContains(t, err, "client classes can only be set for a DHCP server")
+}
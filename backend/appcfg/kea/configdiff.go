@@ -0,0 +1,452 @@
+package keaconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	dhcpmodel "isc.org/stork/datamodel/dhcp"
+	storkutil "isc.org/stork/util"
+)
+
+// Type of a difference between two configurations.
+type ConfigDiffOperation string
+
+// Supported types of the differences.
+const (
+	ConfigDiffAdded   ConfigDiffOperation = "added"
+	ConfigDiffRemoved ConfigDiffOperation = "removed"
+	ConfigDiffChanged ConfigDiffOperation = "changed"
+)
+
+// Represents a single difference between two configurations. The path
+// locates the differing value in the configuration, e.g.,
+// Dhcp4.subnet4[192.0.2.0/24].pools[192.0.2.10-192.0.2.20].option-data[3].
+// The list elements are identified by their keys (e.g., subnet prefixes)
+// or, if the elements have no keys, by their indexes. The before value is
+// nil for the added values and the after value is nil for the removed
+// values.
+type ConfigDiffEntry struct {
+	Path      string
+	Operation ConfigDiffOperation
+	Before    any
+	After     any
+}
+
+// Standard option definitions used to find the codes of the options
+// specified by names.
+var stdOptionDefinitionLookup = NewStdDHCPOptionDefinitionLookup()
+
+// A function returning the key identifying a list element in the
+// configuration and the names of the element's parameters holding
+// this key. The parameters holding the key are not compared because
+// they may differ in format for the equal keys. It returns false if
+// the key cannot be determined.
+type configDiffKeyFunc func(element map[string]any) (string, []string, bool)
+
+// Functions returning the keys of the list elements by the list names.
+// The elements of the lists not included here and in the
+// getConfigDiffKeyFunc function are compared by indexes.
+var configDiffKeyFuncs = map[string]configDiffKeyFunc{
+	"subnet4":         getSubnetDiffKey,
+	"subnet6":         getSubnetDiffKey,
+	"pools":           getPoolDiffKey,
+	"pd-pools":        getPDPoolDiffKey,
+	"reservations":    getReservationDiffKey,
+	"shared-networks": getNamedElementDiffKey("name"),
+	"client-classes":  getNamedElementDiffKey("name"),
+	"hooks-libraries": getNamedElementDiffKey("library"),
+	"loggers":         getNamedElementDiffKey("name"),
+	"peers":           getNamedElementDiffKey("name"),
+	"control-sockets": getNamedElementDiffKey("socket-type"),
+	"forward-ddns":    getNamedElementDiffKey("name"),
+	"reverse-ddns":    getNamedElementDiffKey("name"),
+	"ddns-domains":    getNamedElementDiffKey("name"),
+	"dns-servers":     getNamedElementDiffKey("ip-address"),
+	"tsig-keys":       getNamedElementDiffKey("name"),
+}
+
+// Returns the semantic differences between two configurations. The maps
+// are compared key by key. The lists of subnets, pools, reservations and
+// other elements having well-known keys are compared regardless of the
+// elements' order by matching the elements with the same keys. The lists
+// of scalar values are compared as sets. The remaining lists are compared
+// element by element. The passwords, secrets and tokens are not compared
+// because Kea may return them in a hashed or redacted form. The hash
+// returned by the config-get command is not compared because it differs
+// for all distinct configurations. The differences are sorted by path.
+func DiffConfigs(before, after *Config) []ConfigDiffEntry {
+	var beforeRaw, afterRaw map[string]any
+	if before != nil {
+		beforeRaw = before.Raw
+	}
+	if after != nil {
+		afterRaw = after.Raw
+	}
+	diff := []ConfigDiffEntry{}
+	for _, key := range getSortedDiffKeys(beforeRaw, afterRaw) {
+		if key == "hash" || isSensitiveDataKey(key) {
+			continue
+		}
+		beforeValue, beforeOk := beforeRaw[key]
+		afterValue, afterOk := afterRaw[key]
+		diffValues(key, key, beforeValue, beforeOk, afterValue, afterOk, getDiffUniverse(key), &diff)
+	}
+	sort.SliceStable(diff, func(i, j int) bool {
+		return diff[i].Path < diff[j].Path
+	})
+	return diff
+}
+
+// Returns the universe of the daemon's configuration by its top-level key.
+// It returns zero for the daemons other than the DHCP servers.
+func getDiffUniverse(key string) storkutil.IPType {
+	switch key {
+	case "Dhcp4":
+		return storkutil.IPv4
+	case "Dhcp6":
+		return storkutil.IPv6
+	}
+	return 0
+}
+
+// Returns the sorted union of the keys of two maps.
+func getSortedDiffKeys(before, after map[string]any) []string {
+	keys := []string{}
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Compares two values recursively and appends the differences to the
+// list. The name is the name of the compared parameter. The beforeOk and
+// afterOk flags indicate if the respective values exist. The universe is
+// the universe of the compared configurations.
+func diffValues(path, name string, before any, beforeOk bool, after any, afterOk bool, universe storkutil.IPType, diff *[]ConfigDiffEntry) {
+	switch {
+	case !beforeOk && !afterOk:
+		return
+	case !beforeOk:
+		*diff = append(*diff, ConfigDiffEntry{Path: path, Operation: ConfigDiffAdded, After: after})
+		return
+	case !afterOk:
+		*diff = append(*diff, ConfigDiffEntry{Path: path, Operation: ConfigDiffRemoved, Before: before})
+		return
+	}
+	beforeMap, beforeIsMap := before.(map[string]any)
+	afterMap, afterIsMap := after.(map[string]any)
+	if beforeIsMap && afterIsMap {
+		diffMaps(path, beforeMap, afterMap, nil, universe, diff)
+		return
+	}
+	beforeList, beforeIsList := before.([]any)
+	afterList, afterIsList := after.([]any)
+	if beforeIsList && afterIsList {
+		diffLists(path, name, beforeList, afterList, universe, diff)
+		return
+	}
+	if !reflect.DeepEqual(before, after) {
+		*diff = append(*diff, ConfigDiffEntry{Path: path, Operation: ConfigDiffChanged, Before: before, After: after})
+	}
+}
+
+// Compares two maps key by key. The sensitive data and the specified
+// parameters are skipped.
+func diffMaps(path string, before, after map[string]any, skipped []string, universe storkutil.IPType, diff *[]ConfigDiffEntry) {
+	for _, key := range getSortedDiffKeys(before, after) {
+		if isSensitiveDataKey(key) || isSkippedDiffKey(key, skipped) {
+			continue
+		}
+		beforeValue, beforeOk := before[key]
+		afterValue, afterOk := after[key]
+		diffValues(fmt.Sprintf("%s.%s", path, key), key, beforeValue, beforeOk, afterValue, afterOk, universe, diff)
+	}
+}
+
+// Checks if the parameter is on the list of the skipped parameters.
+func isSkippedDiffKey(key string, skipped []string) bool {
+	for _, s := range skipped {
+		if key == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Compares two lists. The lists of scalar values are compared as sets.
+// The lists of maps having known keys are compared by matching the
+// elements with the same keys. Other lists are compared by indexes.
+func diffLists(path, name string, before, after []any, universe storkutil.IPType, diff *[]ConfigDiffEntry) {
+	if isScalarList(before) && isScalarList(after) {
+		diffScalarLists(path, before, after, diff)
+		return
+	}
+	if keyFunc := getConfigDiffKeyFunc(name, universe); keyFunc != nil {
+		beforeKeyed, beforeOk := getKeyedDiffElements(before, keyFunc)
+		afterKeyed, afterOk := getKeyedDiffElements(after, keyFunc)
+		if beforeOk && afterOk {
+			diffKeyedLists(path, beforeKeyed, afterKeyed, universe, diff)
+			return
+		}
+	}
+	for i := 0; i < len(before) || i < len(after); i++ {
+		var beforeValue, afterValue any
+		if i < len(before) {
+			beforeValue = before[i]
+		}
+		if i < len(after) {
+			afterValue = after[i]
+		}
+		diffValues(fmt.Sprintf("%s[%d]", path, i), name, beforeValue, i < len(before), afterValue, i < len(after), universe, diff)
+	}
+}
+
+// Returns the function returning the keys of the elements of the specified
+// list. The keys of the options depend on the universe. It returns nil if
+// the list elements have no keys.
+func getConfigDiffKeyFunc(name string, universe storkutil.IPType) configDiffKeyFunc {
+	switch name {
+	case "option-data":
+		return func(element map[string]any) (string, []string, bool) {
+			return getOptionDiffKey(element, universe, false)
+		}
+	case "option-def":
+		return func(element map[string]any) (string, []string, bool) {
+			return getOptionDiffKey(element, universe, true)
+		}
+	}
+	return configDiffKeyFuncs[name]
+}
+
+// Checks if the list contains no maps and no lists.
+func isScalarList(list []any) bool {
+	for _, element := range list {
+		switch element.(type) {
+		case map[string]any, []any:
+			return false
+		}
+	}
+	return true
+}
+
+// Compares two lists of scalar values regardless of their order. The
+// values present only in one of the lists are reported as added or
+// removed.
+func diffScalarLists(path string, before, after []any, diff *[]ConfigDiffEntry) {
+	counts := make(map[string]int)
+	for _, value := range before {
+		counts[fmt.Sprint(value)]--
+	}
+	for _, value := range after {
+		counts[fmt.Sprint(value)]++
+	}
+	for _, value := range before {
+		key := fmt.Sprint(value)
+		if counts[key] < 0 {
+			counts[key]++
+			*diff = append(*diff, ConfigDiffEntry{Path: fmt.Sprintf("%s[%s]", path, key), Operation: ConfigDiffRemoved, Before: value})
+		}
+	}
+	for _, value := range after {
+		key := fmt.Sprint(value)
+		if counts[key] > 0 {
+			counts[key]--
+			*diff = append(*diff, ConfigDiffEntry{Path: fmt.Sprintf("%s[%s]", path, key), Operation: ConfigDiffAdded, After: value})
+		}
+	}
+}
+
+// A list element identified by a key.
+type keyedDiffElement struct {
+	value   map[string]any
+	skipped []string
+}
+
+// Returns the list elements by their keys. It returns false if any of
+// the elements is not a map, its key cannot be determined or the keys
+// are not unique.
+func getKeyedDiffElements(list []any, keyFunc configDiffKeyFunc) (map[string]keyedDiffElement, bool) {
+	elements := make(map[string]keyedDiffElement)
+	for _, element := range list {
+		elementMap, ok := element.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		key, skipped, ok := keyFunc(elementMap)
+		if !ok {
+			return nil, false
+		}
+		if _, exists := elements[key]; exists {
+			return nil, false
+		}
+		elements[key] = keyedDiffElement{value: elementMap, skipped: skipped}
+	}
+	return elements, true
+}
+
+// Compares the list elements with the same keys. The elements present
+// only in one of the lists are reported as added or removed.
+func diffKeyedLists(path string, before, after map[string]keyedDiffElement, universe storkutil.IPType, diff *[]ConfigDiffEntry) {
+	keys := []string{}
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		elementPath := fmt.Sprintf("%s[%s]", path, key)
+		beforeElement, beforeOk := before[key]
+		afterElement, afterOk := after[key]
+		switch {
+		case !beforeOk:
+			*diff = append(*diff, ConfigDiffEntry{Path: elementPath, Operation: ConfigDiffAdded, After: afterElement.value})
+		case !afterOk:
+			*diff = append(*diff, ConfigDiffEntry{Path: elementPath, Operation: ConfigDiffRemoved, Before: beforeElement.value})
+		default:
+			diffMaps(elementPath, beforeElement.value, afterElement.value, beforeElement.skipped, universe, diff)
+		}
+	}
+}
+
+// Converts the list element to the specified configuration type.
+func decodeDiffElement(element map[string]any, target any) bool {
+	data, err := json.Marshal(element)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, target) == nil
+}
+
+// Returns the canonical prefix of a subnet as its key.
+func getSubnetDiffKey(element map[string]any) (string, []string, bool) {
+	var subnet MandatorySubnetParameters
+	if !decodeDiffElement(element, &subnet) {
+		return "", nil, false
+	}
+	prefix, err := subnet.GetCanonicalPrefix()
+	if err != nil {
+		return "", nil, false
+	}
+	return prefix, []string{"subnet"}, true
+}
+
+// Returns the address range of a pool as its key. The pools specified
+// as a prefix and as a range are equal if they cover the same addresses.
+func getPoolDiffKey(element map[string]any) (string, []string, bool) {
+	var pool Pool
+	if !decodeDiffElement(element, &pool) {
+		return "", nil, false
+	}
+	lb, ub, err := pool.GetBoundaries()
+	if err != nil {
+		return "", nil, false
+	}
+	return fmt.Sprintf("%s-%s", lb, ub), []string{"pool"}, true
+}
+
+// Returns the canonical prefix of a delegated prefix pool as its key.
+func getPDPoolDiffKey(element map[string]any) (string, []string, bool) {
+	var pool PDPool
+	if !decodeDiffElement(element, &pool) {
+		return "", nil, false
+	}
+	parsed := storkutil.ParseIP(pool.GetCanonicalPrefix())
+	if parsed == nil {
+		return "", nil, false
+	}
+	return parsed.GetNetworkPrefixWithLength(), []string{"prefix", "prefix-len"}, true
+}
+
+// Returns the host identifier of a reservation as its key. The
+// identifiers are compared case-insensitively.
+func getReservationDiffKey(element map[string]any) (string, []string, bool) {
+	var reservation Reservation
+	if !decodeDiffElement(element, &reservation) {
+		return "", nil, false
+	}
+	for _, identifier := range []struct {
+		name  string
+		value string
+	}{
+		{"hw-address", reservation.HWAddress},
+		{"duid", reservation.DUID},
+		{"circuit-id", reservation.CircuitID},
+		{"client-id", reservation.ClientID},
+		{"flex-id", reservation.FlexID},
+	} {
+		if identifier.value != "" {
+			return fmt.Sprintf("%s=%s", identifier.name, strings.ToLower(identifier.value)), []string{identifier.name}, true
+		}
+	}
+	return "", nil, false
+}
+
+// Returns the option space and code of an option or option definition as
+// its key. If the space is not specified, the default space of the
+// universe is used, i.e., dhcp4 or dhcp6. The code of the standard option
+// specified by name is taken from the standard option definitions. The
+// name is used instead of the code if the code is unknown. The parameters
+// holding the key are not compared, so the same option specified with and
+// without the default space or by name and by code is not reported as
+// changed. The names of the option definitions are compared because they
+// are not a part of the key.
+func getOptionDiffKey(element map[string]any, universe storkutil.IPType, isDefinition bool) (string, []string, bool) {
+	var option SingleOptionData
+	if !decodeDiffElement(element, &option) {
+		return "", nil, false
+	}
+	space := option.Space
+	if space == "" {
+		switch universe {
+		case storkutil.IPv4:
+			space = dhcpmodel.DHCPv4OptionSpace
+		case storkutil.IPv6:
+			space = dhcpmodel.DHCPv6OptionSpace
+		}
+	}
+	code := option.Code
+	if code == 0 && option.Name != "" && space != "" {
+		if def := stdOptionDefinitionLookup.FindByNameSpace(option.Name, space, universe); def != nil {
+			code = def.GetCode()
+		}
+	}
+	key := option.Name
+	if code != 0 {
+		key = fmt.Sprint(code)
+	}
+	if key == "" {
+		return "", nil, false
+	}
+	if space != "" {
+		key = fmt.Sprintf("%s:%s", space, key)
+	}
+	skipped := []string{"space", "code"}
+	if !isDefinition {
+		skipped = append(skipped, "name")
+	}
+	return key, skipped, true
+}
+
+// Returns a function using the specified string parameter as the key.
+func getNamedElementDiffKey(parameter string) configDiffKeyFunc {
+	return func(element map[string]any) (string, []string, bool) {
+		name, ok := element[parameter].(string)
+		if !ok || name == "" {
+			return "", nil, false
+		}
+		return name, nil, true
+	}
+}
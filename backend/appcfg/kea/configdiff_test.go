@@ -0,0 +1,324 @@
+package keaconfig
+
+import (
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+)
+
+// Test that the differences between two configurations are found.
+func TestDiffConfigs(t *testing.T) {
+	before, err := NewConfig(`{
+		"Dhcp4": {
+			"valid-lifetime": 4000,
+			"renew-timer": 1000,
+			"subnet4": [
+				{
+					"id": 1,
+					"subnet": "192.0.2.0/24",
+					"pools": [
+						{ "pool": "192.0.2.10-192.0.2.20" }
+					]
+				}
+			]
+		},
+		"hash": "1234"
+	}`)
+	require.NoError(t, err)
+
+	after, err := NewConfig(`{
+		"Dhcp4": {
+			"valid-lifetime": 3000,
+			"rebind-timer": 2000,
+			"subnet4": [
+				{
+					"id": 1,
+					"subnet": "192.0.2.0/24",
+					"pools": [
+						{ "pool": "192.0.2.10-192.0.2.30" },
+						{ "pool": "192.0.2.100-192.0.2.110" }
+					]
+				}
+			]
+		},
+		"hash": "5678"
+	}`)
+	require.NoError(t, err)
+
+	diff := DiffConfigs(before, after)
+	require.Len(t, diff, 6)
+
+	require.Equal(t, "Dhcp4.rebind-timer", diff[0].Path)
+	require.Equal(t, ConfigDiffAdded, diff[0].Operation)
+	require.Nil(t, diff[0].Before)
+	require.EqualValues(t, 2000, diff[0].After)
+
+	require.Equal(t, "Dhcp4.renew-timer", diff[1].Path)
+	require.Equal(t, ConfigDiffRemoved, diff[1].Operation)
+	require.EqualValues(t, 1000, diff[1].Before)
+	require.Nil(t, diff[1].After)
+
+	// The pools are identified by their address ranges.
+	require.Equal(t, "Dhcp4.subnet4[192.0.2.0/24].pools[192.0.2.10-192.0.2.20]", diff[2].Path)
+	require.Equal(t, ConfigDiffRemoved, diff[2].Operation)
+	require.Equal(t, map[string]any{"pool": "192.0.2.10-192.0.2.20"}, diff[2].Before)
+
+	require.Equal(t, "Dhcp4.subnet4[192.0.2.0/24].pools[192.0.2.10-192.0.2.30]", diff[3].Path)
+	require.Equal(t, ConfigDiffAdded, diff[3].Operation)
+	require.Equal(t, map[string]any{"pool": "192.0.2.10-192.0.2.30"}, diff[3].After)
+
+	require.Equal(t, "Dhcp4.subnet4[192.0.2.0/24].pools[192.0.2.100-192.0.2.110]", diff[4].Path)
+	require.Equal(t, ConfigDiffAdded, diff[4].Operation)
+
+	require.Equal(t, "Dhcp4.valid-lifetime", diff[5].Path)
+	require.Equal(t, ConfigDiffChanged, diff[5].Operation)
+	require.EqualValues(t, 4000, diff[5].Before)
+	require.EqualValues(t, 3000, diff[5].After)
+}
+
+// Test that the order of the subnets, pools, reservations, options and
+// scalar values does not matter and that the equal values specified in
+// different formats are not reported.
+func TestDiffConfigsIgnoreOrder(t *testing.T) {
+	before, err := NewConfig(`{
+		"Dhcp4": {
+			"interfaces-config": { "interfaces": [ "eth0", "eth1" ] },
+			"subnet4": [
+				{
+					"id": 1,
+					"subnet": "192.0.2.0/24",
+					"pools": [
+						{ "pool": "192.0.2.0/28" },
+						{ "pool": "192.0.2.100 - 192.0.2.110" }
+					],
+					"reservations": [
+						{ "hw-address": "01:02:03:04:05:06", "ip-address": "192.0.2.50" },
+						{ "client-id": "01:aa", "ip-address": "192.0.2.51" }
+					],
+					"option-data": [
+						{ "code": 3, "data": "192.0.2.1" },
+						{ "name": "domain-name-servers", "data": "192.0.2.2" }
+					]
+				},
+				{ "id": 2, "subnet": "198.51.100.0/24" }
+			]
+		}
+	}`)
+	require.NoError(t, err)
+
+	after, err := NewConfig(`{
+		"Dhcp4": {
+			"interfaces-config": { "interfaces": [ "eth1", "eth0" ] },
+			"subnet4": [
+				{ "id": 2, "subnet": "198.51.100.0/24" },
+				{
+					"id": 1,
+					"subnet": "192.0.2.0/24",
+					"pools": [
+						{ "pool": "192.0.2.100-192.0.2.110" },
+						{ "pool": "192.0.2.0-192.0.2.15" }
+					],
+					"reservations": [
+						{ "client-id": "01:AA", "ip-address": "192.0.2.51" },
+						{ "hw-address": "01:02:03:04:05:06", "ip-address": "192.0.2.52" }
+					],
+					"option-data": [
+						{ "name": "domain-name-servers", "data": "192.0.2.2" },
+						{ "code": 3, "data": "192.0.2.1" }
+					]
+				}
+			]
+		}
+	}`)
+	require.NoError(t, err)
+
+	diff := DiffConfigs(before, after)
+	require.Len(t, diff, 1)
+	require.Equal(t, "Dhcp4.subnet4[192.0.2.0/24].reservations[hw-address=01:02:03:04:05:06].ip-address", diff[0].Path)
+	require.Equal(t, ConfigDiffChanged, diff[0].Operation)
+	require.Equal(t, "192.0.2.50", diff[0].Before)
+	require.Equal(t, "192.0.2.52", diff[0].After)
+}
+
+// Test that the differences between the lists of scalar values are
+// reported as added and removed values.
+func TestDiffConfigsScalarLists(t *testing.T) {
+	before, err := NewConfig(`{
+		"Dhcp6": {
+			"interfaces-config": { "interfaces": [ "eth0", "eth1" ] }
+		}
+	}`)
+	require.NoError(t, err)
+
+	after, err := NewConfig(`{
+		"Dhcp6": {
+			"interfaces-config": { "interfaces": [ "eth2", "eth0" ] }
+		}
+	}`)
+	require.NoError(t, err)
+
+	diff := DiffConfigs(before, after)
+	require.Len(t, diff, 2)
+	require.Equal(t, "Dhcp6.interfaces-config.interfaces[eth1]", diff[0].Path)
+	require.Equal(t, ConfigDiffRemoved, diff[0].Operation)
+	require.Equal(t, "eth1", diff[0].Before)
+	require.Equal(t, "Dhcp6.interfaces-config.interfaces[eth2]", diff[1].Path)
+	require.Equal(t, ConfigDiffAdded, diff[1].Operation)
+	require.Equal(t, "eth2", diff[1].After)
+}
+
+// Test that the passwords, secrets and tokens are not compared.
+func TestDiffConfigsIgnoreSecrets(t *testing.T) {
+	before, err := NewConfig(`{
+		"Dhcp4": {
+			"lease-database": { "type": "mysql", "user": "kea", "password": "secret1" },
+			"hosts-databases": [
+				{ "type": "postgresql", "name": "hosts", "password": "secret2" }
+			]
+		}
+	}`)
+	require.NoError(t, err)
+
+	after, err := NewConfig(`{
+		"Dhcp4": {
+			"lease-database": { "type": "mysql", "user": "kea", "password": "*****" },
+			"hosts-databases": [
+				{ "type": "postgresql", "name": "hosts", "password": "*****" }
+			]
+		}
+	}`)
+	require.NoError(t, err)
+
+	require.Empty(t, DiffConfigs(before, after))
+}
+
+// Test that the lists of elements lacking the keys are compared by
+// indexes.
+func TestDiffConfigsIndexedLists(t *testing.T) {
+	before, err := NewConfig(`{
+		"Dhcp4": {
+			"pools": [ { "pool": "192.0.2.1-192.0.2.10" } ],
+			"reservations": [ { "ip-address": "192.0.2.5" } ]
+		}
+	}`)
+	require.NoError(t, err)
+
+	after, err := NewConfig(`{
+		"Dhcp4": {
+			"pools": [ { "pool": "192.0.2.1-192.0.2.10" } ],
+			"reservations": [ { "ip-address": "192.0.2.6" } ]
+		}
+	}`)
+	require.NoError(t, err)
+
+	diff := DiffConfigs(before, after)
+	require.Len(t, diff, 1)
+	require.Equal(t, "Dhcp4.reservations[0].ip-address", diff[0].Path)
+	require.Equal(t, ConfigDiffChanged, diff[0].Operation)
+}
+
+// Test that no differences are returned for the same configurations.
+func TestDiffConfigsSame(t *testing.T) {
+	config, err := NewConfig(`{
+		"Dhcp6": {
+			"subnet6": [
+				{ "id": 1, "subnet": "2001:db8:1::/64" }
+			]
+		}
+	}`)
+	require.NoError(t, err)
+	require.Empty(t, DiffConfigs(config, config))
+}
+
+// Test that the whole configuration is reported as added or removed when
+// one of the configurations is nil.
+func TestDiffConfigsNil(t *testing.T) {
+	config, err := NewConfig(`{ "Dhcp4": { "valid-lifetime": 4000 } }`)
+	require.NoError(t, err)
+
+	diff := DiffConfigs(nil, config)
+	require.Len(t, diff, 1)
+	require.Equal(t, "Dhcp4", diff[0].Path)
+	require.Equal(t, ConfigDiffAdded, diff[0].Operation)
+
+	diff = DiffConfigs(config, nil)
+	require.Len(t, diff, 1)
+	require.Equal(t, ConfigDiffRemoved, diff[0].Operation)
+
+	require.Empty(t, DiffConfigs(nil, nil))
+}
+
+// Test that the option specified without the space and the option in the
+// default space of the daemon are not reported as different.
+func TestDiffConfigsOptionDefaultSpace(t *testing.T) {
+	before, err := NewConfig(`{
+		"Dhcp4": {
+			"option-data": [
+				{ "code": 3, "data": "192.0.2.1" },
+				{ "code": 222, "space": "isc", "data": "foo" }
+			]
+		}
+	}`)
+	require.NoError(t, err)
+
+	after, err := NewConfig(`{
+		"Dhcp4": {
+			"option-data": [
+				{ "code": 3, "space": "dhcp4", "data": "192.0.2.2" },
+				{ "code": 222, "data": "foo" }
+			]
+		}
+	}`)
+	require.NoError(t, err)
+
+	diff := DiffConfigs(before, after)
+	require.Len(t, diff, 3)
+	require.Equal(t, "Dhcp4.option-data[dhcp4:222]", diff[0].Path)
+	require.Equal(t, ConfigDiffAdded, diff[0].Operation)
+	require.Equal(t, "Dhcp4.option-data[dhcp4:3].data", diff[1].Path)
+	require.Equal(t, ConfigDiffChanged, diff[1].Operation)
+	require.Equal(t, "192.0.2.1", diff[1].Before)
+	require.Equal(t, "192.0.2.2", diff[1].After)
+	require.Equal(t, "Dhcp4.option-data[isc:222]", diff[2].Path)
+	require.Equal(t, ConfigDiffRemoved, diff[2].Operation)
+}
+
+// Test that the standard option specified by name and by code is not
+// reported as different.
+func TestDiffConfigsOptionNameAndCode(t *testing.T) {
+	before, err := NewConfig(`{
+		"Dhcp6": {
+			"option-data": [
+				{ "name": "dns-servers", "data": "2001:db8:1::1" },
+				{ "name": "foo", "data": "bar" }
+			],
+			"option-def": [
+				{ "name": "foo", "code": 222, "type": "string" }
+			]
+		}
+	}`)
+	require.NoError(t, err)
+
+	after, err := NewConfig(`{
+		"Dhcp6": {
+			"option-data": [
+				{ "code": 23, "space": "dhcp6", "data": "2001:db8:1::1" },
+				{ "name": "foo", "space": "dhcp6", "data": "baz" }
+			],
+			"option-def": [
+				{ "name": "bar", "code": 222, "type": "string", "space": "dhcp6" }
+			]
+		}
+	}`)
+	require.NoError(t, err)
+
+	diff := DiffConfigs(before, after)
+	require.Len(t, diff, 2)
+	require.Equal(t, "Dhcp6.option-data[dhcp6:foo].data", diff[0].Path)
+	require.Equal(t, "bar", diff[0].Before)
+	require.Equal(t, "baz", diff[0].After)
+	// The option definition names are compared.
+	require.Equal(t, "Dhcp6.option-def[dhcp6:222].name", diff[1].Path)
+	require.Equal(t, "foo", diff[1].Before)
+	require.Equal(t, "bar", diff[1].After)
+}
@@ -4,10 +4,42 @@ var _ commonConfigAccessor = (*D2Config)(nil)
 
 // Represents a D2 (DHCP-DDNS) Kea configuration.
 type D2Config struct {
+	ForwardDDNS   *DDNSDomains  `json:"forward-ddns"`
+	ReverseDDNS   *DDNSDomains  `json:"reverse-ddns"`
+	TSIGKeys      []TSIGKey     `json:"tsig-keys"`
 	HookLibraries []HookLibrary `json:"hooks-libraries"`
 	Loggers       []Logger      `json:"loggers"`
 }
 
+// Represents the forward-ddns or reverse-ddns structure of the D2 server.
+type DDNSDomains struct {
+	DDNSDomains []DDNSDomain `json:"ddns-domains"`
+}
+
+// Represents a DDNS domain in the D2 server. The D2 server sends the DNS
+// updates for the FQDNs belonging to the domain to its DNS servers.
+type DDNSDomain struct {
+	Name       string      `json:"name"`
+	KeyName    string      `json:"key-name"`
+	DNSServers []DNSServer `json:"dns-servers"`
+}
+
+// Represents a DNS server of the DDNS domain.
+type DNSServer struct {
+	HostName  string `json:"hostname"`
+	IPAddress string `json:"ip-address"`
+	Port      int64  `json:"port"`
+	KeyName   string `json:"key-name"`
+}
+
+// Represents a TSIG key used by the D2 server to sign the DNS updates.
+// The secret is deliberately not parsed, so it cannot be exposed.
+type TSIGKey struct {
+	Name       string `json:"name"`
+	Algorithm  string `json:"algorithm"`
+	DigestBits int64  `json:"digest-bits"`
+}
+
 // Returns the hook libraries configured in the D2 server.
 func (c *D2Config) GetHookLibraries() HookLibraries {
 	return c.HookLibraries
@@ -17,3 +49,24 @@ func (c *D2Config) GetHookLibraries() HookLibraries {
 func (c *D2Config) GetLoggers() []Logger {
 	return c.Loggers
 }
+
+// Returns the forward DDNS domains configured in the D2 server.
+func (c *D2Config) GetForwardDDNSDomains() []DDNSDomain {
+	if c.ForwardDDNS == nil {
+		return nil
+	}
+	return c.ForwardDDNS.DDNSDomains
+}
+
+// Returns the reverse DDNS domains configured in the D2 server.
+func (c *D2Config) GetReverseDDNSDomains() []DDNSDomain {
+	if c.ReverseDDNS == nil {
+		return nil
+	}
+	return c.ReverseDDNS.DDNSDomains
+}
+
+// Returns the TSIG keys configured in the D2 server.
+func (c *D2Config) GetTSIGKeys() []TSIGKey {
+	return c.TSIGKeys
+}
@@ -40,3 +40,84 @@ func TestGetD2Loggers(t *testing.T) {
 	require.Equal(t, "DEBUG", libraries[0].Severity)
 	require.EqualValues(t, 99, libraries[0].DebugLevel)
 }
+
+// Test parsing the DDNS domains and TSIG keys of the D2 server.
+func TestGetD2DDNSDomainsAndKeys(t *testing.T) {
+	configStr := `{
+		"DhcpDdns": {
+			"tsig-keys": [
+				{
+					"name": "d2.md5.key",
+					"algorithm": "HMAC-MD5",
+					"secret": "LSWXnfkKZjdPJI5QxlpnfQ=="
+				},
+				{
+					"name": "d2.sha256.key",
+					"algorithm": "HMAC-SHA256",
+					"digest-bits": 128,
+					"secret": "LSWXnfkKZjdPJI5QxlpnfQ=="
+				}
+			],
+			"forward-ddns": {
+				"ddns-domains": [
+					{
+						"name": "example.com.",
+						"key-name": "d2.md5.key",
+						"dns-servers": [
+							{
+								"ip-address": "192.0.2.1",
+								"port": 53
+							},
+							{
+								"hostname": "ns2.example.com.",
+								"key-name": "d2.sha256.key"
+							}
+						]
+					}
+				]
+			},
+			"reverse-ddns": {
+				"ddns-domains": [
+					{
+						"name": "2.0.192.in-addr.arpa."
+					}
+				]
+			}
+		}
+	}`
+	config, err := NewConfig(configStr)
+	require.NoError(t, err)
+	require.True(t, config.IsD2())
+
+	keys := config.GetTSIGKeys()
+	require.Len(t, keys, 2)
+	require.Equal(t, "d2.md5.key", keys[0].Name)
+	require.Equal(t, "HMAC-MD5", keys[0].Algorithm)
+	require.Zero(t, keys[0].DigestBits)
+	require.Equal(t, "d2.sha256.key", keys[1].Name)
+	require.EqualValues(t, 128, keys[1].DigestBits)
+
+	forward := config.GetForwardDDNSDomains()
+	require.Len(t, forward, 1)
+	require.Equal(t, "example.com.", forward[0].Name)
+	require.Equal(t, "d2.md5.key", forward[0].KeyName)
+	require.Len(t, forward[0].DNSServers, 2)
+	require.Equal(t, "192.0.2.1", forward[0].DNSServers[0].IPAddress)
+	require.EqualValues(t, 53, forward[0].DNSServers[0].Port)
+	require.Equal(t, "ns2.example.com.", forward[0].DNSServers[1].HostName)
+	require.Equal(t, "d2.sha256.key", forward[0].DNSServers[1].KeyName)
+
+	reverse := config.GetReverseDDNSDomains()
+	require.Len(t, reverse, 1)
+	require.Equal(t, "2.0.192.in-addr.arpa.", reverse[0].Name)
+	require.Empty(t, reverse[0].DNSServers)
+}
+
+// Test that no DDNS domains are returned when the forward-ddns and
+// reverse-ddns structures are not specified.
+func TestGetD2DDNSDomainsNotSpecified(t *testing.T) {
+	cfg := &D2Config{}
+	require.Nil(t, cfg.GetForwardDDNSDomains())
+	require.Nil(t, cfg.GetReverseDDNSDomains())
+	require.Nil(t, cfg.GetTSIGKeys())
+}
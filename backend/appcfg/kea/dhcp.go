@@ -66,18 +66,28 @@ type CommonDHCPConfig struct {
 	ReservationParameters
 	TimerParameters
 	ValidLifetimeParameters
-	Allocator         *string         `json:"allocator"`
-	ClientClasses     []ClientClass   `json:"client-classes"`
-	ConfigControl     *ConfigControl  `json:"config-control"`
-	ControlSocket     *ControlSocket  `json:"control-socket"`
-	HostsDatabase     *Database       `json:"hosts-database"`
-	HostsDatabases    []Database      `json:"hosts-databases"`
-	HookLibraries     []HookLibrary   `json:"hooks-libraries"`
-	LeaseDatabase     *Database       `json:"lease-database"`
-	Loggers           []Logger        `json:"loggers"`
-	MultiThreading    *MultiThreading `json:"multi-threading"`
-	Reservations      []Reservation   `json:"reservations"`
-	StoreExtendedInfo *bool           `json:"store-extended-info"`
+	Allocator               *string                  `json:"allocator"`
+	ClientClasses           []ClientClass            `json:"client-classes"`
+	ConfigControl           *ConfigControl           `json:"config-control"`
+	ControlSocket           *ControlSocket           `json:"control-socket"`
+	DHCPDDNS                *DHCPDDNS                `json:"dhcp-ddns"`
+	ExpiredLeasesProcessing *ExpiredLeasesProcessing `json:"expired-leases-processing"`
+	HostsDatabase           *Database                `json:"hosts-database"`
+	HostsDatabases          []Database               `json:"hosts-databases"`
+	HookLibraries           []HookLibrary            `json:"hooks-libraries"`
+	LeaseDatabase           *Database                `json:"lease-database"`
+	Loggers                 []Logger                 `json:"loggers"`
+	MultiThreading          *MultiThreading          `json:"multi-threading"`
+	Reservations            []Reservation            `json:"reservations"`
+	StoreExtendedInfo       *bool                    `json:"store-extended-info"`
+}
+
+// Represents the parameters of the connection between the DHCP server
+// and the D2 server sending the DNS updates.
+type DHCPDDNS struct {
+	EnableUpdates *bool   `json:"enable-updates"`
+	ServerIP      *string `json:"server-ip"`
+	ServerPort    *int64  `json:"server-port"`
 }
 
 // Represents the global DHCP multi-threading parameters.
@@ -0,0 +1,143 @@
+package keaconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Represents the expired leases processing parameters in Kea.
+type ExpiredLeasesProcessing struct {
+	FlushReclaimedTimerWaitTime *int64 `json:"flush-reclaimed-timer-wait-time,omitempty"`
+	HoldReclaimedTime           *int64 `json:"hold-reclaimed-time,omitempty"`
+	MaxReclaimLeases            *int64 `json:"max-reclaim-leases,omitempty"`
+	MaxReclaimTime              *int64 `json:"max-reclaim-time,omitempty"`
+	ReclaimTimerWaitTime        *int64 `json:"reclaim-timer-wait-time,omitempty"`
+	UnwarnedReclaimCycles       *int64 `json:"unwarned-reclaim-cycles,omitempty"`
+}
+
+// Represents the global DHCP parameters that can be modified in Stork.
+// A nil value means that the parameter is left unchanged. Similarly, the
+// nil expired leases processing parameters are left unchanged, and the
+// specified ones are merged into the existing configuration. The option
+// data replace the global options when the slice is non-nil. An empty
+// slice removes all global options.
+type GlobalParameters struct {
+	DDNSParameters
+	PreferredLifetimeParameters
+	TimerParameters
+	ValidLifetimeParameters
+	ExpiredLeasesProcessing *ExpiredLeasesProcessing `json:"expired-leases-processing,omitempty"`
+	OptionData              []SingleOptionData       `json:"option-data"`
+}
+
+// Describes a change of a global parameter value. The name of a parameter
+// in the nested structure is prefixed with the name of the structure and
+// a dot, e.g., expired-leases-processing.max-reclaim-time. The nil value
+// means that the parameter is not set.
+type GlobalParameterChange struct {
+	Name   string `json:"name"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// Returns the global parameters that can be modified in Stork. It returns
+// nil if the configuration does not belong to a DHCP server.
+func (c *Config) GetGlobalParameters() *GlobalParameters {
+	rootName, ok := c.getDHCPRootName()
+	if !ok {
+		return nil
+	}
+	data, err := json.Marshal(c.Raw[rootName])
+	if err != nil {
+		return nil
+	}
+	params := &GlobalParameters{}
+	if err = json.Unmarshal(data, params); err != nil {
+		return nil
+	}
+	return params
+}
+
+// Creates a new configuration with the specified global parameters set.
+// The original configuration is not modified. It returns the new
+// configuration and the list of the parameters whose values have changed,
+// sorted by name. It returns an error if the configuration does not belong
+// to a DHCP server or the preferred lifetimes are specified for a DHCPv4
+// server.
+func (c *Config) WithGlobalParameters(params *GlobalParameters) (*Config, []GlobalParameterChange, error) {
+	rootName, ok := c.getDHCPRootName()
+	if !ok {
+		return nil, nil, errors.New("global parameters can only be set for a DHCP server")
+	}
+	if c.IsDHCPv4() && params.PreferredLifetimeParameters != (PreferredLifetimeParameters{}) {
+		return nil, nil, errors.New("preferred lifetimes cannot be set for a DHCPv4 server")
+	}
+	// Copy the configuration to avoid modifying the original one.
+	rawConfig, err := toRawConfigValue(c.Raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	root, ok := rawConfig.(map[string]any)[rootName].(map[string]any)
+	if !ok {
+		return nil, nil, errors.Errorf("invalid %s configuration", rootName)
+	}
+	rawParams, err := toRawConfigValue(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	var changes []GlobalParameterChange
+	setValue := func(entry map[string]any, name, key string, value any) {
+		if !reflect.DeepEqual(entry[key], value) {
+			changes = append(changes, GlobalParameterChange{
+				Name:   name,
+				Before: entry[key],
+				After:  value,
+			})
+			entry[key] = value
+		}
+	}
+	paramsMap := rawParams.(map[string]any)
+	keys := make([]string, 0, len(paramsMap))
+	for key := range paramsMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := paramsMap[key]
+		if value == nil {
+			continue
+		}
+		nestedValue, isNested := value.(map[string]any)
+		if !isNested {
+			setValue(root, key, key, value)
+			continue
+		}
+		// Merge the nested structure into the existing one.
+		nestedEntry, ok := root[key].(map[string]any)
+		if !ok {
+			nestedEntry = map[string]any{}
+			root[key] = nestedEntry
+		}
+		nestedKeys := make([]string, 0, len(nestedValue))
+		for nestedKey := range nestedValue {
+			nestedKeys = append(nestedKeys, nestedKey)
+		}
+		sort.Strings(nestedKeys)
+		for _, nestedKey := range nestedKeys {
+			setValue(nestedEntry, fmt.Sprintf("%s.%s", key, nestedKey), nestedKey, nestedValue[nestedKey])
+		}
+	}
+	data, err := json.Marshal(rawConfig)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "problem marshalling the updated configuration")
+	}
+	config, err := NewConfig(string(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	return config, changes, nil
+}
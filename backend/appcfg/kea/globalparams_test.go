@@ -0,0 +1,174 @@
+package keaconfig
+
+import (
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+	storkutil "isc.org/stork/util"
+)
+
+// Test getting the global parameters from the configuration.
+func TestGetGlobalParameters(t *testing.T) {
+	config, err := NewConfig(`{
+		"Dhcp6": {
+			"valid-lifetime": 4000,
+			"preferred-lifetime": 3000,
+			"renew-timer": 1000,
+			"ddns-send-updates": true,
+			"expired-leases-processing": {
+				"max-reclaim-time": 250
+			},
+			"option-data": [
+				{
+					"name": "dns-servers",
+					"data": "2001:db8:1::1"
+				}
+			]
+		}
+	}`)
+	require.NoError(t, err)
+
+	params := config.GetGlobalParameters()
+	require.NotNil(t, params)
+	require.EqualValues(t, 4000, *params.ValidLifetime)
+	require.EqualValues(t, 3000, *params.PreferredLifetime)
+	require.EqualValues(t, 1000, *params.RenewTimer)
+	require.Nil(t, params.RebindTimer)
+	require.True(t, *params.DDNSSendUpdates)
+	require.NotNil(t, params.ExpiredLeasesProcessing)
+	require.EqualValues(t, 250, *params.ExpiredLeasesProcessing.MaxReclaimTime)
+	require.Len(t, params.OptionData, 1)
+	require.Equal(t, "dns-servers", params.OptionData[0].Name)
+
+	require.EqualValues(t, 250, *config.GetExpiredLeasesProcessing().MaxReclaimTime)
+}
+
+// Test that the global parameters are not returned for a non-DHCP server.
+func TestGetGlobalParametersNonDHCP(t *testing.T) {
+	config, err := NewConfig(`{
+		"Control-agent": {}
+	}`)
+	require.NoError(t, err)
+	require.Nil(t, config.GetGlobalParameters())
+	require.Nil(t, config.GetSettableConfig())
+}
+
+// Test setting the global parameters in the configuration.
+func TestWithGlobalParameters(t *testing.T) {
+	config, err := NewConfig(`{
+		"Dhcp4": {
+			"valid-lifetime": 4000,
+			"renew-timer": 1000,
+			"expired-leases-processing": {
+				"max-reclaim-time": 250,
+				"max-reclaim-leases": 100
+			},
+			"option-data": [
+				{
+					"name": "domain-name-servers",
+					"data": "192.0.2.1"
+				}
+			],
+			"subnet4": [
+				{
+					"id": 1,
+					"subnet": "192.0.2.0/24"
+				}
+			]
+		},
+		"hash": "1234"
+	}`)
+	require.NoError(t, err)
+
+	updated, changes, err := config.WithGlobalParameters(&GlobalParameters{
+		ValidLifetimeParameters: ValidLifetimeParameters{
+			ValidLifetime:    storkutil.Ptr(int64(7200)),
+			MaxValidLifetime: storkutil.Ptr(int64(9000)),
+		},
+		TimerParameters: TimerParameters{
+			// Unchanged value.
+			RenewTimer: storkutil.Ptr(int64(1000)),
+		},
+		DDNSParameters: DDNSParameters{
+			DDNSSendUpdates: storkutil.Ptr(false),
+		},
+		ExpiredLeasesProcessing: &ExpiredLeasesProcessing{
+			MaxReclaimTime: storkutil.Ptr(int64(500)),
+		},
+		OptionData: []SingleOptionData{},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, updated)
+
+	require.Equal(t, []GlobalParameterChange{
+		{Name: "ddns-send-updates", Before: nil, After: false},
+		{Name: "expired-leases-processing.max-reclaim-time", Before: float64(250), After: float64(500)},
+		{Name: "max-valid-lifetime", Before: nil, After: float64(9000)},
+		{Name: "option-data", Before: []any{map[string]any{"name": "domain-name-servers", "data": "192.0.2.1"}}, After: []any{}},
+		{Name: "valid-lifetime", Before: float64(4000), After: float64(7200)},
+	}, changes)
+
+	// The updated configuration should be parsed.
+	require.EqualValues(t, 7200, *updated.GetValidLifetimeParameters().ValidLifetime)
+	require.EqualValues(t, 9000, *updated.GetValidLifetimeParameters().MaxValidLifetime)
+	require.False(t, *updated.GetDDNSParameters().DDNSSendUpdates)
+	require.EqualValues(t, 500, *updated.GetExpiredLeasesProcessing().MaxReclaimTime)
+	require.EqualValues(t, 100, *updated.GetExpiredLeasesProcessing().MaxReclaimLeases)
+	require.Empty(t, updated.GetDHCPOptions())
+	require.Len(t, updated.GetSubnets(), 1)
+
+	// The original configuration should be unchanged.
+	require.EqualValues(t, 4000, *config.GetValidLifetimeParameters().ValidLifetime)
+	require.EqualValues(t, 250, *config.GetExpiredLeasesProcessing().MaxReclaimTime)
+	require.Len(t, config.GetDHCPOptions(), 1)
+
+	// The settable configuration should lack the hash.
+	settable := updated.GetSettableConfig()
+	require.Len(t, settable, 1)
+	require.Contains(t, settable, "Dhcp4")
+}
+
+// Test that no changes are returned when the parameters are unchanged.
+func TestWithGlobalParametersNoChanges(t *testing.T) {
+	config, err := NewConfig(`{
+		"Dhcp6": {
+			"preferred-lifetime": 3000
+		}
+	}`)
+	require.NoError(t, err)
+
+	updated, changes, err := config.WithGlobalParameters(&GlobalParameters{
+		PreferredLifetimeParameters: PreferredLifetimeParameters{
+			PreferredLifetime: storkutil.Ptr(int64(3000)),
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, updated)
+	require.Empty(t, changes)
+}
+
+// Test that setting the preferred lifetime for a DHCPv4 server fails.
+func TestWithGlobalParametersPreferredLifetimeDHCPv4(t *testing.T) {
+	config, err := NewConfig(`{
+		"Dhcp4": {}
+	}`)
+	require.NoError(t, err)
+
+	_, _, err = config.WithGlobalParameters(&GlobalParameters{
+		PreferredLifetimeParameters: PreferredLifetimeParameters{
+			PreferredLifetime: storkutil.Ptr(int64(3000)),
+		},
+	})
+	require.Error(t, err)
+}
+
+// Test that setting the global parameters for a non-DHCP server fails.
+func TestWithGlobalParametersNonDHCP(t *testing.T) {
+	config, err := NewConfig(`{
+		"DhcpDdns": {}
+	}`)
+	require.NoError(t, err)
+
+	_, _, err = config.WithGlobalParameters(&GlobalParameters{})
+	require.Error(t, err)
+}
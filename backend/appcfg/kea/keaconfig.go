@@ -140,6 +140,62 @@ func (c *Config) IsDHCPv6() bool {
 	return c.DHCPv6Config != nil
 }
 
+// Returns the name of the top-level configuration entry for a DHCP server.
+func (c *Config) getDHCPRootName() (string, bool) {
+	switch {
+	case c.IsDHCPv4():
+		return "Dhcp4", true
+	case c.IsDHCPv6():
+		return "Dhcp6", true
+	default:
+		return "", false
+	}
+}
+
+// Returns the configuration suitable for the config-set command. It
+// comprises the top-level DHCP server entry only, excluding other entries
+// returned by config-get (e.g., the hash). It returns nil if the
+// configuration does not belong to a DHCP server.
+func (c *Config) GetSettableConfig() map[string]any {
+	rootName, ok := c.getDHCPRootName()
+	if !ok {
+		return nil
+	}
+	return map[string]any{
+		rootName: c.Raw[rootName],
+	}
+}
+
+// Converts the value to the form used in the raw configuration, i.e.,
+// composed of the maps, slices, strings, booleans and float64 numbers.
+func toRawConfigValue(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem marshalling the configuration value")
+	}
+	var rawValue any
+	if err = json.Unmarshal(data, &rawValue); err != nil {
+		return nil, errors.Wrap(err, "problem unmarshalling the configuration value")
+	}
+	return rawValue, nil
+}
+
+// Returns the parameters of the connection with the D2 server configured
+// in a DHCP server.
+func (c *Config) GetDHCPDDNS() (dhcpDDNS *DHCPDDNS) {
+	if accessor := c.getDHCPConfigAccessor(); accessor != nil {
+		dhcpDDNS = accessor.GetCommonDHCPConfig().DHCPDDNS
+	}
+	return
+}
+
+// Checks if the DHCP server sends the name change requests to the D2
+// server. The updates are disabled by default.
+func (c *Config) IsDDNSUpdatesEnabled() bool {
+	dhcpDDNS := c.GetDHCPDDNS()
+	return dhcpDDNS != nil && dhcpDDNS.EnableUpdates != nil && *dhcpDDNS.EnableUpdates
+}
+
 // Returns multi-threading configuration for a DHCP server.
 func (c *Config) GetMultiThreading() (mt *MultiThreading) {
 	if accessor := c.getDHCPConfigAccessor(); accessor != nil {
@@ -218,6 +274,14 @@ func (c *Config) GetDDNSParameters() (parameters DDNSParameters) {
 	return
 }
 
+// Returns DHCP expired leases processing parameters.
+func (c *Config) GetExpiredLeasesProcessing() (parameters *ExpiredLeasesProcessing) {
+	if accessor := c.getDHCPConfigAccessor(); accessor != nil {
+		parameters = accessor.GetCommonDHCPConfig().ExpiredLeasesProcessing
+	}
+	return
+}
+
 // Returns DHCP hostname char parameters.
 func (c *Config) GetHostnameCharParameters() (parameters HostnameCharParameters) {
 	if accessor := c.getDHCPConfigAccessor(); accessor != nil {
@@ -412,14 +476,20 @@ func (c *Config) HideSensitiveData() {
 	hideSensitiveData((*map[string]any)(&c.Raw))
 }
 
+// Checks if the configuration parameter with the specified name holds
+// sensitive data, i.e., password, secret or token.
+func isSensitiveDataKey(key string) bool {
+	key = strings.ToLower(key)
+	return key == "password" || key == "secret" || key == "token"
+}
+
 // Hides the sensitive data in the configuration map. It traverses the raw
 // configuration and nullifies the values for the following keys: password,
 // secret, token.
 func hideSensitiveData(obj *map[string]any) {
 	for entryKey, entryValue := range *obj {
 		// Check if the value holds sensitive data.
-		entryKeyNormalized := strings.ToLower(entryKey)
-		if entryKeyNormalized == "password" || entryKeyNormalized == "secret" || entryKeyNormalized == "token" {
+		if isSensitiveDataKey(entryKey) {
 			(*obj)[entryKey] = nil
 			continue
 		}
@@ -1434,6 +1434,53 @@ func TestGetMultiThreadingEntry(t *testing.T) {
 	require.EqualValues(t, 16, *multiThreading.PacketQueueSize)
 }
 
+// Test getting the parameters of the connection with the D2 server.
+func TestGetDHCPDDNS(t *testing.T) {
+	// Arrange
+	configStr := `{
+		"Dhcp6": {
+			"dhcp-ddns": {
+				"enable-updates": true,
+				"server-ip": "192.0.2.1",
+				"server-port": 53001
+			}
+		}
+	}`
+	config, err := NewConfig(configStr)
+	require.NoError(t, err)
+
+	// Act
+	dhcpDDNS := config.GetDHCPDDNS()
+
+	// Assert
+	require.NotNil(t, dhcpDDNS)
+	require.NotNil(t, dhcpDDNS.EnableUpdates)
+	require.True(t, *dhcpDDNS.EnableUpdates)
+	require.NotNil(t, dhcpDDNS.ServerIP)
+	require.Equal(t, "192.0.2.1", *dhcpDDNS.ServerIP)
+	require.NotNil(t, dhcpDDNS.ServerPort)
+	require.EqualValues(t, 53001, *dhcpDDNS.ServerPort)
+	require.True(t, config.IsDDNSUpdatesEnabled())
+}
+
+// Test that the DDNS updates are disabled by default and that the
+// dhcp-ddns structure is not returned for the non-DHCP servers.
+func TestIsDDNSUpdatesEnabledDefault(t *testing.T) {
+	for _, configStr := range []string{
+		`{ "Dhcp4": { } }`,
+		`{ "Dhcp4": { "dhcp-ddns": { } } }`,
+		`{ "Dhcp4": { "dhcp-ddns": { "enable-updates": false } } }`,
+		`{ "DhcpDdns": { } }`,
+	} {
+		config, err := NewConfig(configStr)
+		require.NoError(t, err, configStr)
+		require.False(t, config.IsDDNSUpdatesEnabled(), configStr)
+	}
+
+	config, _ := NewConfig(`{ "DhcpDdns": { } }`)
+	require.Nil(t, config.GetDHCPDDNS())
+}
+
 // Test that the top-level multi-threading structure is returned even if it
 // includes no parameters.
 func TestGetMultiThreadingEntryMissingParameters(t *testing.T) {
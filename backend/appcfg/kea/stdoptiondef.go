@@ -17,6 +17,8 @@ type dhcpStdOptionDefinitionLookup struct {
 type DHCPStdOptionDefinitionLookup interface {
 	// Finds DHCP option definition by code and space.
 	FindByCodeSpace(code uint16, space string, universe storkutil.IPType) DHCPOptionDefinition
+	// Finds DHCP option definition by name and space.
+	FindByNameSpace(name string, space string, universe storkutil.IPType) DHCPOptionDefinition
 }
 
 // Creates standard DHCP option definition lookup instance. It prepares
@@ -32,18 +34,33 @@ func NewStdDHCPOptionDefinitionLookup() DHCPStdOptionDefinitionLookup {
 // Finds a DHCP option definition by option code and space. The last argument
 // specifies whether it should look for a DHCPv4 or DHCPv6 option.
 func (lookup dhcpStdOptionDefinitionLookup) FindByCodeSpace(code uint16, space string, universe storkutil.IPType) DHCPOptionDefinition {
-	var defs []dhcpOptionDefinition
-	switch universe {
-	case storkutil.IPv4:
-		defs = lookup.v4Defs
-	case storkutil.IPv6:
-		defs = lookup.v6Defs
-	}
 	// todo: add indexing to this search.
-	for _, def := range defs {
+	for _, def := range lookup.getDefs(universe) {
 		if def.Code == code && def.Space == space {
 			return def
 		}
 	}
 	return nil
 }
+
+// Finds a DHCP option definition by option name and space. The last argument
+// specifies whether it should look for a DHCPv4 or DHCPv6 option.
+func (lookup dhcpStdOptionDefinitionLookup) FindByNameSpace(name string, space string, universe storkutil.IPType) DHCPOptionDefinition {
+	for _, def := range lookup.getDefs(universe) {
+		if def.Name == name && def.Space == space {
+			return def
+		}
+	}
+	return nil
+}
+
+// Returns the DHCPv4 or DHCPv6 option definitions.
+func (lookup dhcpStdOptionDefinitionLookup) getDefs(universe storkutil.IPType) []dhcpOptionDefinition {
+	switch universe {
+	case storkutil.IPv4:
+		return lookup.v4Defs
+	case storkutil.IPv6:
+		return lookup.v6Defs
+	}
+	return nil
+}
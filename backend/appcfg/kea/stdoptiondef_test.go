@@ -50,3 +50,20 @@ func TestFindDHCPv6OptionDefinitionNotExists(t *testing.T) {
 	def := lookup.FindByCodeSpace(11, "foo", storkutil.IPv6)
 	require.Nil(t, def)
 }
+
+// Test that the DHCPv4 and DHCPv6 option definitions can be found by
+// name and space.
+func TestFindOptionDefinitionByNameSpace(t *testing.T) {
+	lookup := NewStdDHCPOptionDefinitionLookup()
+	def := lookup.FindByNameSpace("domain-name-servers", "dhcp4", storkutil.IPv4)
+	require.NotNil(t, def)
+	require.EqualValues(t, 6, def.GetCode())
+
+	def = lookup.FindByNameSpace("dns-servers", "dhcp6", storkutil.IPv6)
+	require.NotNil(t, def)
+	require.EqualValues(t, 23, def.GetCode())
+
+	require.Nil(t, lookup.FindByNameSpace("dns-servers", "dhcp4", storkutil.IPv4))
+	require.Nil(t, lookup.FindByNameSpace("domain-name-servers", "dhcp4", storkutil.IPv6))
+	require.Nil(t, lookup.FindByNameSpace("foo", "dhcp4", storkutil.IPv4))
+}
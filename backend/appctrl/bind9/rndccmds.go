@@ -0,0 +1,98 @@
+package bind9ctrl
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Name of an rndc command.
+type RndcCommandName string
+
+// The rndc commands allowed to be sent to named by Stork. The other
+// commands are rejected by both the server and the agent.
+const (
+	RndcStatus     RndcCommandName = "status"
+	RndcZoneStatus RndcCommandName = "zonestatus"
+	RndcReload     RndcCommandName = "reload"
+	RndcFlush      RndcCommandName = "flush"
+	RndcFlushName  RndcCommandName = "flushname"
+	RndcRetransfer RndcCommandName = "retransfer"
+	RndcNotify     RndcCommandName = "notify"
+	RndcFreeze     RndcCommandName = "freeze"
+	RndcThaw       RndcCommandName = "thaw"
+)
+
+// The minimum and maximum number of the arguments of an rndc command.
+type rndcCommandArgs struct {
+	min int
+	max int
+}
+
+// The allowed rndc commands with their arguments. The zone commands take
+// the zone name followed by the optional class and view. The flush command
+// takes the optional view, and the flushname command takes the name
+// followed by the optional view.
+var allowedRndcCommands = map[RndcCommandName]rndcCommandArgs{
+	RndcStatus:     {0, 0},
+	RndcZoneStatus: {1, 3},
+	RndcReload:     {1, 3},
+	RndcFlush:      {0, 1},
+	RndcFlushName:  {1, 2},
+	RndcRetransfer: {1, 3},
+	RndcNotify:     {1, 3},
+	RndcFreeze:     {1, 3},
+	RndcThaw:       {1, 3},
+}
+
+// Pattern of the rndc command arguments, i.e., the zone, class, view and
+// domain names. An argument must not start with a hyphen to avoid
+// passing options to rndc.
+var rndcArgumentPattern = regexp.MustCompile(`^[A-Za-z0-9_./][A-Za-z0-9_./-]*$`)
+
+// Returns the names of the allowed rndc commands.
+func GetAllowedRndcCommands() []RndcCommandName {
+	return []RndcCommandName{
+		RndcStatus, RndcZoneStatus, RndcReload, RndcFlush, RndcFlushName,
+		RndcRetransfer, RndcNotify, RndcFreeze, RndcThaw,
+	}
+}
+
+// Checks if the rndc command split into the command name and arguments is
+// allowed. It returns an error if the command is not in the allow-list,
+// it has a wrong number of arguments or an argument contains unexpected
+// characters.
+func ValidateRndcCommand(command []string) error {
+	if len(command) == 0 {
+		return errors.New("no rndc command specified")
+	}
+	name := RndcCommandName(command[0])
+	args, ok := allowedRndcCommands[name]
+	if !ok {
+		return errors.Errorf("rndc command %s is not allowed", command[0])
+	}
+	argc := len(command) - 1
+	switch {
+	case argc < args.min:
+		return errors.Errorf("rndc command %s requires at least %d argument(s)", name, args.min)
+	case argc > args.max:
+		return errors.Errorf("rndc command %s accepts at most %d argument(s)", name, args.max)
+	}
+	for _, arg := range command[1:] {
+		if !rndcArgumentPattern.MatchString(arg) {
+			return errors.Errorf("invalid argument %q of the rndc command %s", arg, name)
+		}
+	}
+	return nil
+}
+
+// Splits the rndc command into the command name and arguments and checks
+// if it is allowed.
+func ParseRndcCommand(command string) ([]string, error) {
+	fields := strings.Fields(command)
+	if err := ValidateRndcCommand(fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
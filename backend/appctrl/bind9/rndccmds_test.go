@@ -0,0 +1,73 @@
+package bind9ctrl
+
+import (
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+)
+
+// Test that the allowed rndc commands are accepted.
+func TestValidateRndcCommandAllowed(t *testing.T) {
+	for _, command := range [][]string{
+		{"status"},
+		{"zonestatus", "example.org"},
+		{"zonestatus", "example.org", "IN", "internal"},
+		{"reload", "example.org"},
+		{"flush"},
+		{"flush", "_default"},
+		{"flushname", "www.example.org"},
+		{"flushname", "www.example.org", "external"},
+		{"retransfer", "0/25.2.0.192.in-addr.arpa"},
+		{"notify", "example.org", "IN"},
+		{"freeze", "example.org"},
+		{"thaw", "example.org", "IN", "_default"},
+	} {
+		require.NoError(t, ValidateRndcCommand(command), command)
+	}
+}
+
+// Test that the rndc commands not in the allow-list, with a wrong number
+// of arguments or with invalid arguments are rejected.
+func TestValidateRndcCommandRejected(t *testing.T) {
+	for _, command := range [][]string{
+		{},
+		{"stop"},
+		{"halt"},
+		{"addzone", "example.org"},
+		{"STATUS"},
+		{"status", "example.org"},
+		{"reload"},
+		{"freeze"},
+		{"flushname"},
+		{"flush", "a", "b"},
+		{"zonestatus", "example.org", "IN", "internal", "extra"},
+		{"zonestatus", "-s"},
+		{"flushname", "example.org;", "external"},
+		{"retransfer", "$(reboot)"},
+	} {
+		require.Error(t, ValidateRndcCommand(command), command)
+	}
+}
+
+// Test parsing the rndc command.
+func TestParseRndcCommand(t *testing.T) {
+	command, err := ParseRndcCommand("  flushname   www.example.org ")
+	require.NoError(t, err)
+	require.Equal(t, []string{"flushname", "www.example.org"}, command)
+
+	command, err = ParseRndcCommand("stop")
+	require.ErrorContains(t, err, "rndc command stop is not allowed")
+	require.Nil(t, command)
+
+	_, err = ParseRndcCommand("")
+	require.ErrorContains(t, err, "no rndc command specified")
+}
+
+// Test that all allowed commands are returned.
+func TestGetAllowedRndcCommands(t *testing.T) {
+	commands := GetAllowedRndcCommands()
+	require.Len(t, commands, len(allowedRndcCommands))
+	for _, command := range commands {
+		require.Contains(t, allowedRndcCommands, command)
+	}
+}
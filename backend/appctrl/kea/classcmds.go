@@ -0,0 +1,24 @@
+package keactrl
+
+import keaconfig "isc.org/stork/appcfg/kea"
+
+const (
+	ClassAdd    CommandName = "class-add"
+	ClassDel    CommandName = "class-del"
+	ClassUpdate CommandName = "class-update"
+)
+
+// Creates class-add command.
+func NewCommandClassAdd(class *keaconfig.ClientClass, daemonNames ...DaemonName) *Command {
+	return NewCommandBase(ClassAdd, daemonNames...).WithArrayArgument("client-classes", class)
+}
+
+// Creates class-del command.
+func NewCommandClassDel(name string, daemonNames ...DaemonName) *Command {
+	return NewCommandBase(ClassDel, daemonNames...).WithArgument("name", name)
+}
+
+// Creates class-update command.
+func NewCommandClassUpdate(class *keaconfig.ClientClass, daemonNames ...DaemonName) *Command {
+	return NewCommandBase(ClassUpdate, daemonNames...).WithArrayArgument("client-classes", class)
+}
@@ -0,0 +1,70 @@
+package keactrl
+
+import (
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+	keaconfig "isc.org/stork/appcfg/kea"
+	storkutil "isc.org/stork/util"
+)
+
+// Tests class-add command.
+func TestNewCommandClassAdd(t *testing.T) {
+	command := NewCommandClassAdd(&keaconfig.ClientClass{
+		Name:           "foo",
+		Test:           "member('bar')",
+		OnlyIfRequired: storkutil.Ptr(true),
+	}, DHCPv4)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "class-add",
+		"service": ["dhcp4"],
+		"arguments": {
+			"client-classes": [
+				{
+					"name": "foo",
+					"test": "member('bar')",
+					"only-if-required": true
+				}
+			]
+		}
+	}`, command.Marshal())
+}
+
+// Tests class-del command.
+func TestNewCommandClassDel(t *testing.T) {
+	command := NewCommandClassDel("foo", DHCPv6)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "class-del",
+		"service": ["dhcp6"],
+		"arguments": {
+			"name": "foo"
+		}
+	}`, command.Marshal())
+}
+
+// Tests class-update command.
+func TestNewCommandClassUpdate(t *testing.T) {
+	command := NewCommandClassUpdate(&keaconfig.ClientClass{
+		Name:              "foo",
+		PreferredLifetime: storkutil.Ptr(int64(1000)),
+		Unrecognized: map[string]any{
+			"option-def": []any{},
+		},
+	}, DHCPv6)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "class-update",
+		"service": ["dhcp6"],
+		"arguments": {
+			"client-classes": [
+				{
+					"name": "foo",
+					"preferred-lifetime": 1000,
+					"option-def": []
+				}
+			]
+		}
+	}`, command.Marshal())
+}
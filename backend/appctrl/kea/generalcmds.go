@@ -1,11 +1,19 @@
 package keactrl
 
 const (
-	ConfigGet    CommandName = "config-get"
-	ConfigReload CommandName = "config-reload"
-	ConfigWrite  CommandName = "config-write"
-	ListCommands CommandName = "list-commands"
-	StatisticGet CommandName = "statistic-get"
-	StatusGet    CommandName = "status-get"
-	VersionGet   CommandName = "version-get"
+	ConfigGet       CommandName = "config-get"
+	ConfigReload    CommandName = "config-reload"
+	ConfigSet       CommandName = "config-set"
+	ConfigWrite     CommandName = "config-write"
+	ListCommands    CommandName = "list-commands"
+	StatisticGet    CommandName = "statistic-get"
+	StatisticGetAll CommandName = "statistic-get-all"
+	StatusGet       CommandName = "status-get"
+	VersionGet      CommandName = "version-get"
 )
+
+// Creates config-set command. The configuration must comprise the top-level
+// entry of the configured daemon, e.g., Dhcp4.
+func NewCommandConfigSet(config map[string]any, daemonNames ...DaemonName) *Command {
+	return NewCommandBase(ConfigSet, daemonNames...).WithArguments(config)
+}
@@ -0,0 +1,26 @@
+package keactrl
+
+import (
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+)
+
+// Tests config-set command.
+func TestNewCommandConfigSet(t *testing.T) {
+	command := NewCommandConfigSet(map[string]any{
+		"Dhcp4": map[string]any{
+			"valid-lifetime": 3600,
+		},
+	}, DHCPv4)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "config-set",
+		"service": ["dhcp4"],
+		"arguments": {
+			"Dhcp4": {
+				"valid-lifetime": 3600
+			}
+		}
+	}`, command.Marshal())
+}
@@ -0,0 +1,64 @@
+package keactrl
+
+const (
+	HAContinue          CommandName = "ha-continue"
+	HAMaintenanceCancel CommandName = "ha-maintenance-cancel"
+	HAMaintenanceStart  CommandName = "ha-maintenance-start"
+	HAReset             CommandName = "ha-reset"
+	HAScopes            CommandName = "ha-scopes"
+	HASync              CommandName = "ha-sync"
+)
+
+// Creates a command controlling the HA state machine. The server-name
+// argument is only included when it is non-empty. It selects the HA
+// relationship when the server participates in more than one (i.e., in
+// the hub-and-spoke configuration).
+func newCommandHA(commandName CommandName, serverName string, daemons ...DaemonName) *Command {
+	command := NewCommandBase(commandName, daemons...)
+	if serverName != "" {
+		command = command.WithArgument("server-name", serverName)
+	}
+	return command
+}
+
+// Creates ha-maintenance-start command. It is sent to the server remaining
+// operational while its partner is in maintenance.
+func NewCommandHAMaintenanceStart(serverName string, daemons ...DaemonName) *Command {
+	return newCommandHA(HAMaintenanceStart, serverName, daemons...)
+}
+
+// Creates ha-maintenance-cancel command.
+func NewCommandHAMaintenanceCancel(serverName string, daemons ...DaemonName) *Command {
+	return newCommandHA(HAMaintenanceCancel, serverName, daemons...)
+}
+
+// Creates ha-continue command resuming the paused HA state machine.
+func NewCommandHAContinue(serverName string, daemons ...DaemonName) *Command {
+	return newCommandHA(HAContinue, serverName, daemons...)
+}
+
+// Creates ha-reset command moving the server to the waiting state.
+func NewCommandHAReset(serverName string, daemons ...DaemonName) *Command {
+	return newCommandHA(HAReset, serverName, daemons...)
+}
+
+// Creates ha-sync command. The server name is the name of the partner from
+// which the leases are synchronized. The max-period argument is only
+// included when it is greater than 0.
+func NewCommandHASync(serverName string, maxPeriod int64, daemons ...DaemonName) *Command {
+	command := newCommandHA(HASync, serverName, daemons...)
+	if maxPeriod > 0 {
+		command = command.WithArgument("max-period", maxPeriod)
+	}
+	return command
+}
+
+// Creates ha-scopes command. The scopes are the names of the servers
+// whose clients should be served by the server receiving the command.
+// An empty list disables serving the clients.
+func NewCommandHAScopes(scopes []string, serverName string, daemons ...DaemonName) *Command {
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return newCommandHA(HAScopes, serverName, daemons...).WithArgument("scopes", scopes)
+}
@@ -0,0 +1,94 @@
+package keactrl
+
+import (
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+)
+
+// Tests ha-maintenance-start and ha-maintenance-cancel commands.
+func TestNewCommandHAMaintenance(t *testing.T) {
+	command := NewCommandHAMaintenanceStart("", DHCPv4)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "ha-maintenance-start",
+		"service": ["dhcp4"]
+	}`, command.Marshal())
+
+	command = NewCommandHAMaintenanceCancel("server2", DHCPv6)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "ha-maintenance-cancel",
+		"service": ["dhcp6"],
+		"arguments": {
+			"server-name": "server2"
+		}
+	}`, command.Marshal())
+}
+
+// Tests ha-continue and ha-reset commands.
+func TestNewCommandHAContinueAndReset(t *testing.T) {
+	command := NewCommandHAContinue("server1", DHCPv4)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "ha-continue",
+		"service": ["dhcp4"],
+		"arguments": {
+			"server-name": "server1"
+		}
+	}`, command.Marshal())
+
+	command = NewCommandHAReset("", DHCPv4)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "ha-reset",
+		"service": ["dhcp4"]
+	}`, command.Marshal())
+}
+
+// Tests ha-sync command.
+func TestNewCommandHASync(t *testing.T) {
+	command := NewCommandHASync("server2", 60, DHCPv4)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "ha-sync",
+		"service": ["dhcp4"],
+		"arguments": {
+			"server-name": "server2",
+			"max-period": 60
+		}
+	}`, command.Marshal())
+
+	command = NewCommandHASync("server2", 0, DHCPv4)
+	require.JSONEq(t, `{
+		"command": "ha-sync",
+		"service": ["dhcp4"],
+		"arguments": {
+			"server-name": "server2"
+		}
+	}`, command.Marshal())
+}
+
+// Tests ha-scopes command.
+func TestNewCommandHAScopes(t *testing.T) {
+	command := NewCommandHAScopes([]string{"server1", "server2"}, "", DHCPv4)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "ha-scopes",
+		"service": ["dhcp4"],
+		"arguments": {
+			"scopes": ["server1", "server2"]
+		}
+	}`, command.Marshal())
+
+	// Empty scopes disable serving the clients.
+	command = NewCommandHAScopes(nil, "server1", DHCPv4)
+	require.JSONEq(t, `{
+		"command": "ha-scopes",
+		"service": ["dhcp4"],
+		"arguments": {
+			"scopes": [],
+			"server-name": "server1"
+		}
+	}`, command.Marshal())
+}
@@ -16,10 +16,50 @@ const (
 	Lease4GetByHostname  CommandName = "lease4-get-by-hostname"
 	Lease6GetByHostname  CommandName = "lease6-get-by-hostname"
 	Lease4GetByHWAddress CommandName = "lease4-get-by-hw-address"
+	Lease4GetAll         CommandName = "lease4-get-all"
+	Lease6GetAll         CommandName = "lease6-get-all"
+	Lease4GetPage        CommandName = "lease4-get-page"
+	Lease6GetPage        CommandName = "lease6-get-page"
+	Lease4Add            CommandName = "lease4-add"
+	Lease6Add            CommandName = "lease6-add"
+	Lease4Update         CommandName = "lease4-update"
+	Lease6Update         CommandName = "lease6-update"
+	Lease4Del            CommandName = "lease4-del"
+	Lease6Del            CommandName = "lease6-del"
+	Lease4Wipe           CommandName = "lease4-wipe"
+	Lease6Wipe           CommandName = "lease6-wipe"
+	Lease4ResendDDNS     CommandName = "lease4-resend-ddns"
+	Lease6ResendDDNS     CommandName = "lease6-resend-ddns"
 	StatLease4Get        CommandName = "stat-lease4-get"
 	StatLease6Get        CommandName = "stat-lease4-get"
 )
 
+// Lease parameters sent in the commands adding and updating the leases.
+// The nil values and empty strings are not sent, so Kea uses the defaults
+// for them (e.g., the valid lifetime configured for the subnet). The
+// expiration time is the Unix timestamp of the lease expiration. The
+// ForceCreate flag is only used in the update commands to add the lease
+// if it does not exist.
+type LeaseParameters struct {
+	IPAddress         string         `json:"ip-address"`
+	Type              LeaseType      `json:"type,omitempty"`
+	PrefixLength      *int64         `json:"prefix-len,omitempty"`
+	SubnetID          *int64         `json:"subnet-id,omitempty"`
+	HWAddress         string         `json:"hw-address,omitempty"`
+	ClientID          string         `json:"client-id,omitempty"`
+	DUID              string         `json:"duid,omitempty"`
+	IAID              *int64         `json:"iaid,omitempty"`
+	ValidLifetime     *int64         `json:"valid-lft,omitempty"`
+	PreferredLifetime *int64         `json:"preferred-lft,omitempty"`
+	Expire            *int64         `json:"expire,omitempty"`
+	FqdnFwd           *bool          `json:"fqdn-fwd,omitempty"`
+	FqdnRev           *bool          `json:"fqdn-rev,omitempty"`
+	Hostname          string         `json:"hostname,omitempty"`
+	State             *int64         `json:"state,omitempty"`
+	UserContext       map[string]any `json:"user-context,omitempty"`
+	ForceCreate       bool           `json:"force-create,omitempty"`
+}
+
 // Creates lease4-get command.
 func NewCommandLease4Get(ipAddress string, daemons ...DaemonName) *Command {
 	return NewCommandBase(Lease4Get, daemons...).WithArgument("ip-address", ipAddress)
@@ -31,3 +71,91 @@ func NewCommandLease6Get(leaseType LeaseType, ipAddress string, daemons ...Daemo
 		WithArgument("type", leaseType).
 		WithArgument("ip-address", ipAddress)
 }
+
+// Creates lease4-get-all command returning the leases in the subnets with
+// the specified local subnet IDs.
+func NewCommandLease4GetAll(subnetIDs []int64, daemons ...DaemonName) *Command {
+	return NewCommandBase(Lease4GetAll, daemons...).WithArgument("subnets", subnetIDs)
+}
+
+// Creates lease6-get-all command returning the leases in the subnets with
+// the specified local subnet IDs.
+func NewCommandLease6GetAll(subnetIDs []int64, daemons ...DaemonName) *Command {
+	return NewCommandBase(Lease6GetAll, daemons...).WithArgument("subnets", subnetIDs)
+}
+
+// Creates lease4-get-page command. The from argument is the address of the
+// last lease returned on the previous page. The first page is fetched when
+// it is empty.
+func NewCommandLease4GetPage(from string, limit int64, daemons ...DaemonName) *Command {
+	if from == "" {
+		from = "start"
+	}
+	return NewCommandBase(Lease4GetPage, daemons...).
+		WithArgument("from", from).
+		WithArgument("limit", limit)
+}
+
+// Creates lease6-get-page command. The from argument is the address of the
+// last lease returned on the previous page. The first page is fetched when
+// it is empty.
+func NewCommandLease6GetPage(from string, limit int64, daemons ...DaemonName) *Command {
+	if from == "" {
+		from = "start"
+	}
+	return NewCommandBase(Lease6GetPage, daemons...).
+		WithArgument("from", from).
+		WithArgument("limit", limit)
+}
+
+// Creates lease4-add command.
+func NewCommandLease4Add(lease *LeaseParameters, daemons ...DaemonName) *Command {
+	return NewCommandBase(Lease4Add, daemons...).WithArguments(lease)
+}
+
+// Creates lease6-add command.
+func NewCommandLease6Add(lease *LeaseParameters, daemons ...DaemonName) *Command {
+	return NewCommandBase(Lease6Add, daemons...).WithArguments(lease)
+}
+
+// Creates lease4-update command.
+func NewCommandLease4Update(lease *LeaseParameters, daemons ...DaemonName) *Command {
+	return NewCommandBase(Lease4Update, daemons...).WithArguments(lease)
+}
+
+// Creates lease6-update command.
+func NewCommandLease6Update(lease *LeaseParameters, daemons ...DaemonName) *Command {
+	return NewCommandBase(Lease6Update, daemons...).WithArguments(lease)
+}
+
+// Creates lease4-del command.
+func NewCommandLease4Del(ipAddress string, daemons ...DaemonName) *Command {
+	return NewCommandBase(Lease4Del, daemons...).WithArgument("ip-address", ipAddress)
+}
+
+// Creates lease6-del command.
+func NewCommandLease6Del(leaseType LeaseType, ipAddress string, daemons ...DaemonName) *Command {
+	return NewCommandBase(Lease6Del, daemons...).
+		WithArgument("type", leaseType).
+		WithArgument("ip-address", ipAddress)
+}
+
+// Creates lease4-wipe command removing all leases from the specified subnet.
+func NewCommandLease4Wipe(subnetID int64, daemons ...DaemonName) *Command {
+	return NewCommandBase(Lease4Wipe, daemons...).WithArgument("subnet-id", subnetID)
+}
+
+// Creates lease6-wipe command removing all leases from the specified subnet.
+func NewCommandLease6Wipe(subnetID int64, daemons ...DaemonName) *Command {
+	return NewCommandBase(Lease6Wipe, daemons...).WithArgument("subnet-id", subnetID)
+}
+
+// Creates lease4-resend-ddns command.
+func NewCommandLease4ResendDDNS(ipAddress string, daemons ...DaemonName) *Command {
+	return NewCommandBase(Lease4ResendDDNS, daemons...).WithArgument("ip-address", ipAddress)
+}
+
+// Creates lease6-resend-ddns command.
+func NewCommandLease6ResendDDNS(ipAddress string, daemons ...DaemonName) *Command {
+	return NewCommandBase(Lease6ResendDDNS, daemons...).WithArgument("ip-address", ipAddress)
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	require "github.com/stretchr/testify/require"
+	storkutil "isc.org/stork/util"
 )
 
 // Tests lease4-get command.
@@ -34,3 +35,210 @@ func TestNewCommandLease6Get(t *testing.T) {
 
 	}`, command.Marshal())
 }
+
+// Tests lease4-add command.
+func TestNewCommandLease4Add(t *testing.T) {
+	command := NewCommandLease4Add(&LeaseParameters{
+		IPAddress:     "192.0.2.1",
+		SubnetID:      storkutil.Ptr(int64(1)),
+		HWAddress:     "01:02:03:04:05:06",
+		ValidLifetime: storkutil.Ptr(int64(3600)),
+		FqdnFwd:       storkutil.Ptr(false),
+		Hostname:      "host.example.org",
+	}, DHCPv4)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "lease4-add",
+		"service": ["dhcp4"],
+		"arguments": {
+			"ip-address": "192.0.2.1",
+			"subnet-id": 1,
+			"hw-address": "01:02:03:04:05:06",
+			"valid-lft": 3600,
+			"fqdn-fwd": false,
+			"hostname": "host.example.org"
+		}
+	}`, command.Marshal())
+}
+
+// Tests lease6-add command.
+func TestNewCommandLease6Add(t *testing.T) {
+	command := NewCommandLease6Add(&LeaseParameters{
+		IPAddress:    "2001:db8:1::",
+		Type:         LeaseTypePD,
+		PrefixLength: storkutil.Ptr(int64(64)),
+		DUID:         "01:02:03",
+		IAID:         storkutil.Ptr(int64(0)),
+	}, DHCPv6)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "lease6-add",
+		"service": ["dhcp6"],
+		"arguments": {
+			"ip-address": "2001:db8:1::",
+			"type": "IA_PD",
+			"prefix-len": 64,
+			"duid": "01:02:03",
+			"iaid": 0
+		}
+	}`, command.Marshal())
+}
+
+// Tests lease4-update command.
+func TestNewCommandLease4Update(t *testing.T) {
+	command := NewCommandLease4Update(&LeaseParameters{
+		IPAddress:   "192.0.2.1",
+		State:       storkutil.Ptr(int64(0)),
+		ForceCreate: true,
+	}, DHCPv4)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "lease4-update",
+		"service": ["dhcp4"],
+		"arguments": {
+			"ip-address": "192.0.2.1",
+			"state": 0,
+			"force-create": true
+		}
+	}`, command.Marshal())
+}
+
+// Tests lease6-update command.
+func TestNewCommandLease6Update(t *testing.T) {
+	command := NewCommandLease6Update(&LeaseParameters{
+		IPAddress: "2001:db8:1::1",
+		DUID:      "01:02:03",
+		IAID:      storkutil.Ptr(int64(1)),
+	}, DHCPv6)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "lease6-update",
+		"service": ["dhcp6"],
+		"arguments": {
+			"ip-address": "2001:db8:1::1",
+			"duid": "01:02:03",
+			"iaid": 1
+		}
+	}`, command.Marshal())
+}
+
+// Tests lease4-del command.
+func TestNewCommandLease4Del(t *testing.T) {
+	command := NewCommandLease4Del("192.0.2.1", DHCPv4)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "lease4-del",
+		"service": ["dhcp4"],
+		"arguments": {
+			"ip-address": "192.0.2.1"
+		}
+	}`, command.Marshal())
+}
+
+// Tests lease6-del command.
+func TestNewCommandLease6Del(t *testing.T) {
+	command := NewCommandLease6Del(LeaseTypeNA, "2001:db8:1::1", DHCPv6)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "lease6-del",
+		"service": ["dhcp6"],
+		"arguments": {
+			"type": "IA_NA",
+			"ip-address": "2001:db8:1::1"
+		}
+	}`, command.Marshal())
+}
+
+// Tests lease4-get-all and lease6-get-all commands.
+func TestNewCommandLeaseGetAll(t *testing.T) {
+	command := NewCommandLease4GetAll([]int64{1, 2}, DHCPv4)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "lease4-get-all",
+		"service": ["dhcp4"],
+		"arguments": {
+			"subnets": [1, 2]
+		}
+	}`, command.Marshal())
+
+	command = NewCommandLease6GetAll([]int64{3}, DHCPv6)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "lease6-get-all",
+		"service": ["dhcp6"],
+		"arguments": {
+			"subnets": [3]
+		}
+	}`, command.Marshal())
+}
+
+// Tests lease4-get-page and lease6-get-page commands.
+func TestNewCommandLeaseGetPage(t *testing.T) {
+	command := NewCommandLease4GetPage("", 100, DHCPv4)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "lease4-get-page",
+		"service": ["dhcp4"],
+		"arguments": {
+			"from": "start",
+			"limit": 100
+		}
+	}`, command.Marshal())
+
+	command = NewCommandLease6GetPage("2001:db8:1::5", 10, DHCPv6)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "lease6-get-page",
+		"service": ["dhcp6"],
+		"arguments": {
+			"from": "2001:db8:1::5",
+			"limit": 10
+		}
+	}`, command.Marshal())
+}
+
+// Tests lease4-wipe and lease6-wipe commands.
+func TestNewCommandLeaseWipe(t *testing.T) {
+	command := NewCommandLease4Wipe(3, DHCPv4)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "lease4-wipe",
+		"service": ["dhcp4"],
+		"arguments": {
+			"subnet-id": 3
+		}
+	}`, command.Marshal())
+
+	command = NewCommandLease6Wipe(4, DHCPv6)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "lease6-wipe",
+		"service": ["dhcp6"],
+		"arguments": {
+			"subnet-id": 4
+		}
+	}`, command.Marshal())
+}
+
+// Tests lease4-resend-ddns and lease6-resend-ddns commands.
+func TestNewCommandLeaseResendDDNS(t *testing.T) {
+	command := NewCommandLease4ResendDDNS("192.0.2.1", DHCPv4)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "lease4-resend-ddns",
+		"service": ["dhcp4"],
+		"arguments": {
+			"ip-address": "192.0.2.1"
+		}
+	}`, command.Marshal())
+
+	command = NewCommandLease6ResendDDNS("2001:db8:1::1", DHCPv6)
+	require.NotNil(t, command)
+	require.JSONEq(t, `{
+		"command": "lease6-resend-ddns",
+		"service": ["dhcp6"],
+		"arguments": {
+			"ip-address": "2001:db8:1::1"
+		}
+	}`, command.Marshal())
+}
@@ -22,9 +22,8 @@ type AuthenticationMetadata interface {
 }
 
 // The metadata of the authentication method that uses the data from the login
-// form. Currently, we expect that all methods implement this interface.
-// The non-form flow (e.g.: OpenID Connect, Basic Auth, Bearer Token, JWT) are
-// not supported.
+// form. The methods redirecting to an external identity provider should
+// implement the AuthenticationMetadataRedirect interface instead.
 type AuthenticationMetadataForm interface {
 	// Returns a label for the identifier field in the login form on UI.
 	GetIdentifierFormLabel() string
@@ -32,6 +31,16 @@ type AuthenticationMetadataForm interface {
 	GetSecretFormLabel() string
 }
 
+// The metadata of the authentication method that redirects the user to an
+// external identity provider (e.g.: OpenID Connect, SAML) instead of using
+// the login form. The callout carrier providing such a method must implement
+// the AuthenticationRedirectCallouts interface.
+type AuthenticationMetadataRedirect interface {
+	// Returns a label of the button on UI that redirects the user to the
+	// identity provider.
+	GetRedirectButtonLabel() string
+}
+
 // User group ID enum.
 type UserGroupID int
 
@@ -39,6 +48,7 @@ type UserGroupID int
 const (
 	UserGroupIDSuperAdmin UserGroupID = 1
 	UserGroupIDAdmin      UserGroupID = 2
+	UserGroupIDReadOnly   UserGroupID = 3
 )
 
 // The logged user metadata. It's a data transfer object (DTO) to avoid using
@@ -68,9 +78,32 @@ type AuthenticationCallouts interface {
 	// Returns authentication metadata used to list the authentication methods
 	// on the UI. The metadata object should also implement the interface
 	// specific to the flow of providing credentials
-	// (e.g.: "AuthenticationMetadataForm" - for form-based).
-	// Note: Other flows are unsupported but expected in the future as
-	// redirecting to external authentication provider, Single Sign On, Basic
-	// Auth, Token-based authentication, or Multi-Factor authentication
+	// (e.g.: "AuthenticationMetadataForm" - for form-based or
+	// "AuthenticationMetadataRedirect" - for redirect-based).
+	// Note: Other flows are unsupported but expected in the future as Basic
+	// Auth or Multi-Factor authentication
 	GetMetadata() AuthenticationMetadata
 }
+
+// Set of callouts used to perform the redirect-based authentication, e.g.,
+// OpenID Connect authorization code flow. The server redirects the user to
+// the URL returned by the GetAuthorizationURL callout. The identity provider
+// authenticates the user and redirects back to the callback URL. The server
+// validates the state parameter and calls the HandleCallback callout to
+// obtain the user metadata.
+type AuthenticationRedirectCallouts interface {
+	AuthenticationCallouts
+	// Returns the URL of the identity provider the user should be redirected
+	// to. The state and nonce are random values generated by the server for
+	// each login attempt. The hook must include them in the authorization
+	// request. The callback URL is the server location the identity provider
+	// should redirect the user back to.
+	GetAuthorizationURL(ctx context.Context, state, nonce, callbackURL string) (string, error)
+	// Called when the identity provider redirects the user back to the
+	// server. The request contains the authorization response (e.g., the
+	// authorization code). The server has already validated the state
+	// parameter. The hook must verify the response (e.g., exchange the code
+	// for the tokens and check that the ID token contains the specified
+	// nonce). Returns a user metadata or error if the authentication failed.
+	HandleCallback(ctx context.Context, request *http.Request, nonce, callbackURL string) (*User, error)
+}
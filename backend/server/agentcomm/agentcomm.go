@@ -16,6 +16,7 @@ import (
 	"google.golang.org/grpc/security/advancedtls"
 
 	agentapi "isc.org/stork/api"
+	bind9config "isc.org/stork/appcfg/bind9"
 	keactrl "isc.org/stork/appctrl/kea"
 	dbmodel "isc.org/stork/server/database/model"
 	"isc.org/stork/server/eventcenter"
@@ -133,6 +134,7 @@ type ConnectedAgents interface {
 	ForwardToNamedStats(ctx context.Context, app ControlledApp, statsAddress string, statsPort int64, path string, statsOutput interface{}) error
 	ForwardToKeaOverHTTP(ctx context.Context, app ControlledApp, commands []keactrl.SerializableCommand, cmdResponses ...interface{}) (*KeaCmdsResult, error)
 	TailTextFile(ctx context.Context, machine dbmodel.MachineTag, path string, offset int64) ([]string, error)
+	GetBind9Config(ctx context.Context, app ControlledApp) (*bind9config.Config, error)
 }
 
 // Agents management map. It tracks Agents currently connected to the Server.
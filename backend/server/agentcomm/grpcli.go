@@ -2,6 +2,7 @@ package agentcomm
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"strconv"
@@ -12,6 +13,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	agentapi "isc.org/stork/api"
+	bind9config "isc.org/stork/appcfg/bind9"
 	keactrl "isc.org/stork/appctrl/kea"
 	dbmodel "isc.org/stork/server/database/model"
 	storkutil "isc.org/stork/util"
@@ -902,3 +904,72 @@ func (agents *connectedAgentsData) TailTextFile(ctx context.Context, machine dbm
 	// All ok.
 	return response.Lines, nil
 }
+
+// Fetches the parsed configuration of the BIND 9 server from the Stork
+// agent. The secrets of the keys are hidden by the agent.
+func (agents *connectedAgentsData) GetBind9Config(ctx context.Context, app ControlledApp) (*bind9config.Config, error) {
+	agentAddress := app.GetMachineTag().GetAddress()
+	agentPort := app.GetMachineTag().GetAgentPort()
+	addrPort := net.JoinHostPort(agentAddress, strconv.FormatInt(agentPort, 10))
+
+	// The agent identifies the BIND 9 server by its control access point.
+	ctrlAddress, ctrlPort, _, _, err := app.GetControlAccessPoint()
+	if err != nil {
+		return nil, err
+	}
+	req := &agentapi.GetBind9ConfigReq{
+		ControlAddress: ctrlAddress,
+		ControlPort:    ctrlPort,
+	}
+
+	// Send the request via queue.
+	agentResponse, err := agents.sendAndRecvViaQueue(addrPort, req)
+
+	stats := agents.getConnectedAgentStats(agentAddress, agentPort)
+	if stats == nil {
+		return nil, errors.Errorf("failed to get statistics for the non-existing agent %s", addrPort)
+	}
+
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	// Check connectivity with the Stork agent by examining the returned error.
+	commIssue, details := agents.checkAgentCommState(stats, req, err)
+	switch commIssue {
+	case CommErrorNew:
+		log.WithFields(log.Fields{
+			"agent": addrPort,
+		}).Warn("Failed to get the BIND 9 configuration via the Stork agent")
+		agents.EventCenter.AddErrorEvent("communication with Stork agent on {machine} to get the BIND 9 configuration failed", app.GetMachineTag(), dbmodel.SSEConnectivity, details)
+
+	case CommErrorReset:
+		agents.EventCenter.AddWarningEvent("communication with Stork agent on {machine} to get the BIND 9 configuration succeeded", app.GetMachineTag(), dbmodel.SSEConnectivity, details)
+
+	case CommErrorContinued:
+		log.WithFields(log.Fields{
+			"agent": addrPort,
+		}).Warn("Failed to get the BIND 9 configuration via the Stork agent; the agent is still not responding")
+	default:
+		// Communication with the agent was ok and is still ok.
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the BIND 9 configuration from Stork agent %s", addrPort)
+	}
+
+	response, ok := agentResponse.(*agentapi.GetBind9ConfigRsp)
+	if !ok || response == nil {
+		return nil, errors.Errorf("wrong response to getting the BIND 9 configuration from the Stork agent %s", addrPort)
+	}
+
+	// Check the status code.
+	if response.Status.Code != agentapi.Status_OK {
+		return nil, errors.New(response.Status.Message)
+	}
+
+	config := &bind9config.Config{}
+	if err := json.Unmarshal([]byte(response.Config), config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the BIND 9 configuration returned by the Stork agent %s", addrPort)
+	}
+	return config, nil
+}
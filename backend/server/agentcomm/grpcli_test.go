@@ -638,6 +638,78 @@ func TestTailTextFileError(t *testing.T) {
 	require.EqualValues(t, 1, agent.Stats.GetTotalErrorCount())
 }
 
+// Returns the BIND 9 app used in the tests of fetching the configuration.
+func newTestBind9ConfigApp() *dbmodel.App {
+	return &dbmodel.App{
+		Machine: &dbmodel.Machine{
+			Address:   "127.0.0.1",
+			AgentPort: 8080,
+		},
+		AccessPoints: []*dbmodel.AccessPoint{{
+			Type:    dbmodel.AccessPointControl,
+			Address: "127.0.0.1",
+			Port:    953,
+		}},
+	}
+}
+
+// Test the gRPC call fetching the BIND 9 configuration.
+func TestGetBind9Config(t *testing.T) {
+	mockAgentClient, agents, teardown := setupGrpcliTestCase(t)
+	defer teardown()
+
+	rsp := agentapi.GetBind9ConfigRsp{
+		Status: &agentapi.Status{
+			Code: 0,
+		},
+		Config: `{"statements": [{"keyword": "options", "args": [{"block": {"statements": [{"keyword": "directory", "args": [{"value": "/var/cache/bind", "quoted": true}]}]}}]}]}`,
+	}
+
+	mockAgentClient.EXPECT().
+		GetBind9Config(gomock.Any(), &agentapi.GetBind9ConfigReq{
+			ControlAddress: "127.0.0.1",
+			ControlPort:    953,
+		}, newGZIPMatcher()).
+		Return(&rsp, nil)
+
+	config, err := agents.GetBind9Config(context.Background(), newTestBind9ConfigApp())
+	require.NoError(t, err)
+	require.NotNil(t, config)
+	require.Equal(t, "/var/cache/bind", config.GetOptions().GetValue("directory"))
+}
+
+// Test that the error status returned by the agent and the communication
+// errors are returned when fetching the BIND 9 configuration.
+func TestGetBind9ConfigError(t *testing.T) {
+	mockAgentClient, agents, teardown := setupGrpcliTestCase(t)
+	defer teardown()
+
+	rsp := agentapi.GetBind9ConfigRsp{
+		Status: &agentapi.Status{
+			Code:    agentapi.Status_ERROR,
+			Message: "Cannot find BIND 9 app",
+		},
+	}
+	gomock.InOrder(
+		mockAgentClient.EXPECT().
+			GetBind9Config(gomock.Any(), gomock.Any(), newGZIPMatcher()).
+			Return(&rsp, nil),
+		mockAgentClient.EXPECT().
+			GetBind9Config(gomock.Any(), gomock.Any(), newGZIPMatcher()).
+			Return(nil, pkgerrors.New("config error")),
+	)
+
+	_, err := agents.GetBind9Config(context.Background(), newTestBind9ConfigApp())
+	require.ErrorContains(t, err, "Cannot find BIND 9 app")
+
+	_, err = agents.GetBind9Config(context.Background(), newTestBind9ConfigApp())
+	require.Error(t, err)
+
+	agent, err := agents.GetConnectedAgent("127.0.0.1:8080")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, agent.Stats.GetTotalErrorCount())
+}
+
 // Check MakeAccessPoint.
 func TestMakeAccessPoint(t *testing.T) {
 	aps := MakeAccessPoint(dbmodel.AccessPointControl, "1.2.3.4", "abcd", 124)
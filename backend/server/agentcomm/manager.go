@@ -80,6 +80,8 @@ func doCall(ctx context.Context, agent *Agent, in interface{}) (interface{}, err
 		response, err = agent.Client.ForwardToKeaOverHTTP(ctx, inData, bigMessageOptions...)
 	case *agentapi.TailTextFileReq:
 		response, err = agent.Client.TailTextFile(ctx, inData, bigMessageOptions...)
+	case *agentapi.GetBind9ConfigReq:
+		response, err = agent.Client.GetBind9Config(ctx, inData, bigMessageOptions...)
 	default:
 		err = errors.New("doCall: unsupported request type")
 	}
@@ -3,6 +3,8 @@ package agentcommtest
 import (
 	"context"
 
+	"github.com/pkg/errors"
+	bind9config "isc.org/stork/appcfg/bind9"
 	keactrl "isc.org/stork/appctrl/kea"
 	"isc.org/stork/server/agentcomm"
 	dbmodel "isc.org/stork/server/database/model"
@@ -22,13 +24,19 @@ type FakeAgents struct {
 	RecordedPort    int64
 	RecordedKey     string
 	RecordedCommand string
-	mockRndcOutput  string
+	// All rndc commands forwarded to the agents.
+	RecordedRndcCommands []string
+	mockRndcOutput       string
+	mockRndcFunc         func(string) string
 
 	RecordedStatsURL string
 	mockNamedFunc    func(int, interface{})
 
 	MachineState   *agentcomm.State
 	GetStateCalled bool
+
+	// The BIND 9 configuration returned by GetBind9Config.
+	Bind9Config *bind9config.Config
 }
 
 // mockRndcOutput returns some mocked named response.
@@ -76,6 +84,17 @@ func NewKeaFakeAgents(fnsKea ...func(int, []interface{})) *FakeAgents {
 	return fa
 }
 
+// Create new instance of the FakeAgents structure with the functions
+// returning the named statistics and the responses to the rndc commands.
+// The rndc function receives the forwarded command.
+func NewBind9FakeAgents(fnNamed func(int, interface{}), fnRndc func(string) string) *FakeAgents {
+	fa := &FakeAgents{
+		mockNamedFunc: fnNamed,
+		mockRndcFunc:  fnRndc,
+	}
+	return fa
+}
+
 // Do nothing. Always returns nil.
 func (fa *FakeAgents) Ping(ctx context.Context, machine dbmodel.MachineTag) error {
 	return nil
@@ -171,6 +190,14 @@ func (fa *FakeAgents) ForwardToNamedStats(ctx context.Context, app agentcomm.Con
 func (fa *FakeAgents) ForwardRndcCommand(ctx context.Context, app agentcomm.ControlledApp, command string) (*agentcomm.RndcOutput, error) {
 	fa.RecordedAddress, fa.RecordedPort, fa.RecordedKey, _, _ = app.GetControlAccessPoint()
 	fa.RecordedCommand = command
+	fa.RecordedRndcCommands = append(fa.RecordedRndcCommands, command)
+
+	if fa.mockRndcFunc != nil {
+		output := &agentcomm.RndcOutput{
+			Output: fa.mockRndcFunc(command),
+		}
+		return output, nil
+	}
 
 	if fa.mockRndcOutput != "" {
 		output := &agentcomm.RndcOutput{
@@ -186,3 +213,13 @@ func (fa *FakeAgents) ForwardRndcCommand(ctx context.Context, app agentcomm.Cont
 func (fa *FakeAgents) TailTextFile(ctx context.Context, machine dbmodel.MachineTag, path string, offset int64) ([]string, error) {
 	return []string{"lorem ipsum"}, nil
 }
+
+// Returns the BIND 9 configuration set in the Bind9Config field. It
+// returns an error if the configuration is not set.
+func (fa *FakeAgents) GetBind9Config(ctx context.Context, app agentcomm.ControlledApp) (*bind9config.Config, error) {
+	fa.RecordedAddress, fa.RecordedPort, fa.RecordedKey, _, _ = app.GetControlAccessPoint()
+	if fa.Bind9Config == nil {
+		return nil, errors.New("BIND 9 configuration not found")
+	}
+	return fa.Bind9Config, nil
+}
@@ -11,6 +11,7 @@ import (
 	dbops "isc.org/stork/server/database"
 	dbmodel "isc.org/stork/server/database/model"
 	"isc.org/stork/server/eventcenter"
+	storkutil "isc.org/stork/util"
 )
 
 // Provide example date format how named returns dates.
@@ -31,7 +32,8 @@ type ResolverData struct {
 
 // The view statistics data JSON structure.
 type ViewStatsData struct {
-	Resolver ResolverData `json:"resolver"`
+	Resolver ResolverData    `json:"resolver"`
+	Zones    []ZoneStatsData `json:"zones"`
 }
 
 // JSON Structure of response returned by the named Bind 9 daemon on fetching
@@ -105,6 +107,20 @@ func GetAppState(ctx context.Context, agents agentcomm.ConnectedAgents, dbApp *d
 	}
 
 	bind9Daemon := dbmodel.NewBind9Daemon(false)
+	if oldDaemon := dbApp.GetDaemonByName(dbmodel.DaemonNameBind9); oldDaemon != nil {
+		// Keep the identity of the existing daemon, so the zones, config
+		// reports and other data referencing it survive the update.
+		bind9Daemon.ID = oldDaemon.ID
+		bind9Daemon.CreatedAt = oldDaemon.CreatedAt
+		bind9Daemon.Monitored = oldDaemon.Monitored
+		bind9Daemon.LogTargets = oldDaemon.LogTargets
+		bind9Daemon.ConfigReview = oldDaemon.ConfigReview
+		if oldDaemon.Bind9Daemon != nil {
+			bind9Daemon.Bind9Daemon.ID = oldDaemon.Bind9Daemon.ID
+			bind9Daemon.Bind9Daemon.Config = oldDaemon.Bind9Daemon.Config
+			bind9Daemon.Bind9Daemon.ConfigHash = oldDaemon.Bind9Daemon.ConfigHash
+		}
+	}
 
 	// Get version
 	pattern := regexp.MustCompile(`version:\s+(.+)\n`)
@@ -177,6 +193,27 @@ func GetAppState(ctx context.Context, agents agentcomm.ConnectedAgents, dbApp *d
 
 	// Get statistics
 	GetAppStatistics(ctx, agents, dbApp)
+
+	// Get configuration
+	GetAppConfig(ctx, agents, dbApp)
+}
+
+// Fetches the configuration of the named daemon from the agent and stores
+// it in the app's daemon with its hash. The previously fetched
+// configuration is preserved when the agent fails to return it.
+func GetAppConfig(ctx context.Context, agents agentcomm.ConnectedAgents, dbApp *dbmodel.App) {
+	daemon := dbApp.GetDaemonByName(dbmodel.DaemonNameBind9)
+	if daemon == nil || daemon.Bind9Daemon == nil {
+		return
+	}
+	daemon.App = dbApp
+	config, err := GetConfig(ctx, agents, daemon)
+	if err != nil {
+		log.Warnf("Problem getting BIND 9 configuration: %s", err)
+		return
+	}
+	daemon.Bind9Daemon.Config = config
+	daemon.Bind9Daemon.ConfigHash = storkutil.Fnv128(config.String())
 }
 
 // Inserts or updates information about BIND 9 app in the database.
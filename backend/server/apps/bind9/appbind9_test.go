@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	bind9config "isc.org/stork/appcfg/bind9"
 	agentcommtest "isc.org/stork/server/agentcomm/test"
 	dbmodel "isc.org/stork/server/database/model"
 	dbtest "isc.org/stork/server/database/test"
@@ -86,6 +87,50 @@ func TestGetAppState(t *testing.T) {
 	require.EqualValues(t, 30, daemon.Bind9Daemon.Stats.NamedStats.Views["_default"].Resolver.CacheStats["QueryMisses"])
 }
 
+// Test that the configuration is fetched with the state and that the
+// existing daemon keeps its identity.
+func TestGetAppStateConfig(t *testing.T) {
+	fa := agentcommtest.NewFakeAgents(nil, mockNamed)
+	var err error
+	fa.Bind9Config, err = bind9config.Parse(`options { recursion no; };`)
+	require.NoError(t, err)
+
+	var accessPoints []*dbmodel.AccessPoint
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "127.0.0.1", "abcd", 953, false)
+	oldDaemon := dbmodel.NewBind9Daemon(true)
+	oldDaemon.ID = 5
+	oldDaemon.Monitored = false
+	oldDaemon.Bind9Daemon.ID = 6
+	oldDaemon.ConfigReview = &dbmodel.ConfigReview{ConfigHash: "abc"}
+	dbApp := dbmodel.App{
+		AccessPoints: accessPoints,
+		Machine: &dbmodel.Machine{
+			Address:   "192.0.2.0",
+			AgentPort: 1111,
+		},
+		Daemons: []*dbmodel.Daemon{oldDaemon},
+	}
+
+	GetAppState(context.Background(), fa, &dbApp, &storktest.FakeEventCenter{})
+
+	require.Len(t, dbApp.Daemons, 1)
+	daemon := dbApp.Daemons[0]
+	require.EqualValues(t, 5, daemon.ID)
+	require.False(t, daemon.Monitored)
+	require.EqualValues(t, 6, daemon.Bind9Daemon.ID)
+	require.Equal(t, "abc", daemon.ConfigReview.ConfigHash)
+	require.NotNil(t, daemon.Bind9Daemon.Config)
+	require.Equal(t, "no", daemon.Bind9Daemon.Config.GetOptions().GetValue("recursion"))
+	require.NotEmpty(t, daemon.Bind9Daemon.ConfigHash)
+
+	// The configuration is preserved when it cannot be fetched.
+	hash := daemon.Bind9Daemon.ConfigHash
+	fa.Bind9Config = nil
+	GetAppState(context.Background(), fa, &dbApp, &storktest.FakeEventCenter{})
+	require.NotNil(t, dbApp.Daemons[0].Bind9Daemon.Config)
+	require.Equal(t, hash, dbApp.Daemons[0].Bind9Daemon.ConfigHash)
+}
+
 // Tests that BIND 9 can be added and then updated in the database.
 func TestCommitAppIntoDB(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
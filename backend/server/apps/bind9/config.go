@@ -0,0 +1,36 @@
+package bind9
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	bind9config "isc.org/stork/appcfg/bind9"
+	"isc.org/stork/server/agentcomm"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Timeout of fetching the BIND 9 configuration from the agent. The agent
+// reads and parses named.conf with all included files.
+const configFetchTimeout = 10 * time.Second
+
+// Fetches the configuration of the BIND 9 daemon from the agent. The
+// agent parses the configuration and hides the secrets of the keys before
+// sending it.
+func GetConfig(ctx context.Context, agents agentcomm.ConnectedAgents, daemon *dbmodel.Daemon) (*bind9config.Config, error) {
+	if daemon.Name != dbmodel.DaemonNameBind9 {
+		return nil, errors.Errorf("daemon %d is not a BIND 9 daemon", daemon.ID)
+	}
+	if daemon.App == nil {
+		return nil, errors.Errorf("daemon %d lacks the app", daemon.ID)
+	}
+
+	ctx2, cancel := context.WithTimeout(ctx, configFetchTimeout)
+	defer cancel()
+
+	config, err := agents.GetBind9Config(ctx2, daemon.App)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "cannot get the configuration of %s", daemon.App.GetName())
+	}
+	return config, nil
+}
@@ -0,0 +1,44 @@
+package bind9
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	bind9config "isc.org/stork/appcfg/bind9"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Test that the configuration is fetched from the agent.
+func TestGetConfig(t *testing.T) {
+	fa := agentcommtest.NewBind9FakeAgents(nil, nil)
+	var err error
+	fa.Bind9Config, err = bind9config.Parse(`options { directory "/var/cache/bind"; };`)
+	require.NoError(t, err)
+
+	config, err := GetConfig(context.Background(), fa, newTestRndcDaemon())
+	require.NoError(t, err)
+	require.Equal(t, "/var/cache/bind", config.GetOptions().GetValue("directory"))
+	require.Equal(t, "127.0.0.1", fa.RecordedAddress)
+	require.EqualValues(t, 953, fa.RecordedPort)
+}
+
+// Test that the errors are returned when the configuration cannot be
+// fetched or the daemon is not a BIND 9 daemon.
+func TestGetConfigError(t *testing.T) {
+	fa := agentcommtest.NewBind9FakeAgents(nil, nil)
+
+	_, err := GetConfig(context.Background(), fa, newTestRndcDaemon())
+	require.ErrorContains(t, err, "cannot get the configuration of dns1")
+
+	daemon := newTestRndcDaemon()
+	daemon.Name = dbmodel.DaemonNameDHCPv4
+	_, err = GetConfig(context.Background(), fa, daemon)
+	require.ErrorContains(t, err, "not a BIND 9 daemon")
+
+	daemon = newTestRndcDaemon()
+	daemon.App = nil
+	_, err = GetConfig(context.Background(), fa, daemon)
+	require.ErrorContains(t, err, "lacks the app")
+}
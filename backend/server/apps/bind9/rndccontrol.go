@@ -0,0 +1,46 @@
+package bind9
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	bind9ctrl "isc.org/stork/appctrl/bind9"
+	"isc.org/stork/server/agentcomm"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Timeout of the rndc commands run on the users' request. It is longer
+// than the timeout of the commands run by the pullers because some
+// commands, e.g., reloading a large zone, take a while.
+const rndcCommandTimeout = 10 * time.Second
+
+// Sends the rndc command to the BIND 9 daemon and returns its output. The
+// command must be one of the commands allowed by the bind9ctrl package.
+// It is validated before sending it, and it is validated again by the
+// agent.
+func RunRndcCommand(ctx context.Context, agents agentcomm.ConnectedAgents, daemon *dbmodel.Daemon, command string) (string, error) {
+	if daemon.Name != dbmodel.DaemonNameBind9 {
+		return "", errors.Errorf("daemon %d is not a BIND 9 daemon", daemon.ID)
+	}
+	if daemon.App == nil {
+		return "", errors.Errorf("daemon %d lacks the app", daemon.ID)
+	}
+	fields, err := bind9ctrl.ParseRndcCommand(command)
+	if err != nil {
+		return "", err
+	}
+
+	ctx2, cancel := context.WithTimeout(ctx, rndcCommandTimeout)
+	defer cancel()
+
+	out, err := agents.ForwardRndcCommand(ctx2, daemon.App, strings.Join(fields, " "))
+	if err != nil {
+		return "", errors.WithMessagef(err, "rndc %s command to %s failed", fields[0], daemon.App.GetName())
+	}
+	if out == nil {
+		return "", nil
+	}
+	return out.Output, nil
+}
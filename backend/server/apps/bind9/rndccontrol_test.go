@@ -0,0 +1,69 @@
+package bind9
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Creates the BIND 9 daemon with the app for testing the rndc commands.
+func newTestRndcDaemon() *dbmodel.Daemon {
+	var accessPoints []*dbmodel.AccessPoint
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "127.0.0.1", "abcd", 953, false)
+	daemon := dbmodel.NewBind9Daemon(true)
+	daemon.ID = 1
+	daemon.App = &dbmodel.App{
+		ID:           2,
+		Name:         "dns1",
+		Type:         dbmodel.AppTypeBind9,
+		AccessPoints: accessPoints,
+		Machine: &dbmodel.Machine{
+			Address:   "192.0.2.1",
+			AgentPort: 8080,
+		},
+	}
+	return daemon
+}
+
+// Test that the allowed rndc command is sent to the daemon.
+func TestRunRndcCommand(t *testing.T) {
+	fa := agentcommtest.NewBind9FakeAgents(nil, func(command string) string {
+		return "zone reload queued"
+	})
+	output, err := RunRndcCommand(context.Background(), fa, newTestRndcDaemon(), "  reload example.org  IN ")
+	require.NoError(t, err)
+	require.Equal(t, "zone reload queued", output)
+	require.Equal(t, []string{"reload example.org IN"}, fa.RecordedRndcCommands)
+	require.Equal(t, "127.0.0.1", fa.RecordedAddress)
+	require.EqualValues(t, 953, fa.RecordedPort)
+}
+
+// Test that the rndc commands not in the allow-list are not sent.
+func TestRunRndcCommandNotAllowed(t *testing.T) {
+	fa := agentcommtest.NewBind9FakeAgents(nil, nil)
+	for _, command := range []string{"", "stop", "reload", "addzone example.org {}"} {
+		_, err := RunRndcCommand(context.Background(), fa, newTestRndcDaemon(), command)
+		require.Error(t, err, command)
+	}
+	require.Empty(t, fa.RecordedRndcCommands)
+}
+
+// Test that the rndc commands are only sent to the BIND 9 daemons.
+func TestRunRndcCommandWrongDaemon(t *testing.T) {
+	fa := agentcommtest.NewBind9FakeAgents(nil, nil)
+
+	daemon := newTestRndcDaemon()
+	daemon.Name = dbmodel.DaemonNameDHCPv4
+	_, err := RunRndcCommand(context.Background(), fa, daemon, "flush")
+	require.ErrorContains(t, err, "daemon 1 is not a BIND 9 daemon")
+
+	daemon = newTestRndcDaemon()
+	daemon.App = nil
+	_, err = RunRndcCommand(context.Background(), fa, daemon, "flush")
+	require.ErrorContains(t, err, "daemon 1 lacks the app")
+
+	require.Empty(t, fa.RecordedRndcCommands)
+}
@@ -14,6 +14,9 @@ import (
 type StatsPuller struct {
 	*agentcomm.PeriodicPuller
 	EventCenter eventcenter.EventCenter
+	// The zones whose details should be fetched with the rndc zonestatus
+	// command, by daemon ID.
+	pendingZoneDetails map[int64]map[zoneKey]bool
 }
 
 // Create a StatsPuller object that in background pulls BIND 9 statistics.
@@ -21,7 +24,8 @@ type StatsPuller struct {
 // statistics-channel.
 func NewStatsPuller(db *pg.DB, agents agentcomm.ConnectedAgents, eventCenter eventcenter.EventCenter) (*StatsPuller, error) {
 	statsPuller := &StatsPuller{
-		EventCenter: eventCenter,
+		EventCenter:        eventCenter,
+		pendingZoneDetails: make(map[int64]map[zoneKey]bool),
 	}
 	periodicPuller, err := agentcomm.NewPeriodicPuller(db, agents, "BIND 9 stats puller", "bind9_stats_puller_interval",
 		statsPuller.pullStats)
@@ -60,6 +64,21 @@ func (statsPuller *StatsPuller) pullStats() error {
 		}
 	}
 	log.Printf("Completed pulling stats from BIND 9 apps: %d/%d succeeded", appsOkCnt, len(dbApps))
+
+	// Fetch the details of the new zones with rndc when the stats of all
+	// apps have been pulled, so slow rndc commands don't delay the stats.
+	for i := range dbApps {
+		if len(dbApps[i].Daemons) > 0 && dbApps[i].Daemons[0].Active {
+			statsPuller.updateZoneDetails(context.Background(), &dbApps[i])
+		}
+	}
+
+	// Compare the secondary zones with their primaries using the zones
+	// pulled from all apps.
+	if err := statsPuller.checkZoneReplication(); err != nil {
+		lastErr = err
+		log.WithError(err).Error("Error occurred while checking zone replication")
+	}
 	return lastErr
 }
 
@@ -114,5 +133,9 @@ func (statsPuller *StatsPuller) getStatsFromApp(dbApp *dbmodel.App) error {
 	}
 
 	dbApp.Daemons[0].Bind9Daemon.Stats.NamedStats = namedStats
-	return dbmodel.UpdateDaemon(statsPuller.DB, dbApp.Daemons[0])
+	if err = dbmodel.UpdateDaemon(statsPuller.DB, dbApp.Daemons[0]); err != nil {
+		return err
+	}
+
+	return statsPuller.updateZoneInventory(dbApp, statsOutput.Views)
 }
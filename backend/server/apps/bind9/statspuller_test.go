@@ -127,13 +127,8 @@ func TestStatsPullerPullStats(t *testing.T) {
 	err = CommitAppIntoDB(db, &dbApp2, fec)
 	require.NoError(t, err)
 
-	// set one setting that is needed by puller
-	setting := dbmodel.Setting{
-		Name:    "bind9_stats_puller_interval",
-		ValType: dbmodel.SettingValTypeInt,
-		Value:   "60",
-	}
-	_, err = db.Model(&setting).Insert()
+	// initialize the settings needed by the puller
+	err = dbmodel.InitializeSettings(db, 0)
 	require.NoError(t, err)
 
 	// prepare stats puller
@@ -218,13 +213,8 @@ func TestStatsPullerEmptyResponse(t *testing.T) {
 	err = CommitAppIntoDB(db, &dbApp, fec)
 	require.NoError(t, err)
 
-	// set one setting that is needed by puller
-	setting := dbmodel.Setting{
-		Name:    "bind9_stats_puller_interval",
-		ValType: dbmodel.SettingValTypeInt,
-		Value:   "60",
-	}
-	_, err = db.Model(&setting).Insert()
+	// initialize the settings needed by the puller
+	err = dbmodel.InitializeSettings(db, 0)
 	require.NoError(t, err)
 
 	// prepare stats puller
@@ -284,13 +274,8 @@ func TestStatsPullerPullStatsForPartiallyDetectedDaemon(t *testing.T) {
 	err = CommitAppIntoDB(db, &dbApp, fec)
 	require.NoError(t, err)
 
-	// Set one setting that is needed by puller.
-	setting := dbmodel.Setting{
-		Name:    "bind9_stats_puller_interval",
-		ValType: dbmodel.SettingValTypeInt,
-		Value:   "60",
-	}
-	_, err = db.Model(&setting).Insert()
+	// Initialize the settings needed by the puller.
+	err = dbmodel.InitializeSettings(db, 0)
 	require.NoError(t, err)
 
 	// Prepare stats puller.
@@ -0,0 +1,266 @@
+package bind9
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"isc.org/stork/server/agentcomm"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// The zone entry of the view statistics JSON structure. The times are
+// in the ISO 8601 format. The serial is a string if the zone is not
+// loaded, so it is parsed separately.
+type ZoneStatsData struct {
+	Name    string          `json:"name"`
+	Class   string          `json:"class"`
+	Serial  json.RawMessage `json:"serial"`
+	Type    string          `json:"type"`
+	Loaded  string          `json:"loaded"`
+	Expires string          `json:"expires"`
+	Refresh string          `json:"refresh"`
+}
+
+// Identifies the zone served by a daemon.
+type zoneKey struct {
+	view  string
+	name  string
+	class string
+}
+
+// Converts the zone type reported by BIND 9 to the type stored in the
+// database. The older BIND 9 versions use the master and slave terms.
+func normalizeZoneType(zoneType string) string {
+	switch strings.ToLower(zoneType) {
+	case "master", dbmodel.ZoneTypePrimary:
+		return dbmodel.ZoneTypePrimary
+	case "slave", dbmodel.ZoneTypeSecondary:
+		return dbmodel.ZoneTypeSecondary
+	default:
+		return strings.ToLower(zoneType)
+	}
+}
+
+// Parses the time returned by named in the ISO 8601 or the long format.
+// It returns the zero time if the time is empty or malformed.
+func parseNamedTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC()
+	}
+	if t, err := time.Parse(namedLongDateFormat, value); err == nil {
+		return t.UTC()
+	}
+	return time.Time{}
+}
+
+// Converts the zones from the statistics channel to the database zones.
+// The built-in zones and the zones of the _bind view are skipped because
+// they are created automatically by named. The zones are sorted by view
+// and name.
+func newZonesFromStats(views map[string]*ViewStatsData) []dbmodel.Zone {
+	zones := []dbmodel.Zone{}
+	for viewName, view := range views {
+		if view == nil || viewName == "_bind" {
+			continue
+		}
+		for _, zoneStats := range view.Zones {
+			if zoneStats.Name == "" || strings.EqualFold(zoneStats.Type, "builtin") {
+				continue
+			}
+			serial, _ := strconv.ParseInt(string(zoneStats.Serial), 10, 64)
+			if serial < 0 {
+				serial = 0
+			}
+			class := zoneStats.Class
+			if class == "" {
+				class = "IN"
+			}
+			zones = append(zones, dbmodel.Zone{
+				View:      viewName,
+				Name:      zoneStats.Name,
+				Class:     class,
+				ZoneType:  normalizeZoneType(zoneStats.Type),
+				Serial:    serial,
+				LoadedAt:  parseNamedTime(zoneStats.Loaded),
+				RefreshAt: parseNamedTime(zoneStats.Refresh),
+				ExpiresAt: parseNamedTime(zoneStats.Expires),
+			})
+		}
+	}
+	sort.Slice(zones, func(i, j int) bool {
+		if zones[i].View != zones[j].View {
+			return zones[i].View < zones[j].View
+		}
+		return zones[i].Name < zones[j].Name
+	})
+	return zones
+}
+
+// Parses the output of the rndc zonestatus command and updates the zone
+// with the details missing in the statistics channel. The output consists
+// of the "key: value" lines.
+func parseZoneStatus(output string, zone *dbmodel.Zone) {
+	for _, line := range strings.Split(output, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "type":
+			zone.ZoneType = normalizeZoneType(value)
+		case "files":
+			// The included files follow the main zone file.
+			zone.File, _, _ = strings.Cut(value, ",")
+		case "serial":
+			if serial, err := strconv.ParseInt(value, 10, 64); err == nil {
+				zone.Serial = serial
+			}
+		case "dynamic":
+			zone.Dynamic = value == "yes"
+		case "last loaded":
+			if t := parseNamedTime(value); !t.IsZero() {
+				zone.LoadedAt = t
+			}
+		case "next refresh":
+			if t := parseNamedTime(value); !t.IsZero() {
+				zone.RefreshAt = t
+			}
+		case "expires":
+			if t := parseNamedTime(value); !t.IsZero() {
+				zone.ExpiresAt = t
+			}
+		}
+	}
+}
+
+// Maximum number of the rndc zonestatus commands sent to a BIND 9 server
+// in a single pull. The details of the remaining zones are fetched in the
+// next pulls.
+const maxZoneStatusPerPull = 10
+
+// Fetches the details of the zone from named using the rndc zonestatus
+// command.
+func getZoneStatus(ctx context.Context, agents agentcomm.ConnectedAgents, dbApp *dbmodel.App, zone *dbmodel.Zone) error {
+	ctx2, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	command := fmt.Sprintf("zonestatus %s %s %s", zone.Name, zone.Class, zone.View)
+	out, err := agents.ForwardRndcCommand(ctx2, dbApp, command)
+	if err != nil {
+		return err
+	}
+	if out != nil {
+		parseZoneStatus(out.Output, zone)
+	}
+	return nil
+}
+
+// Updates the zone inventory of the BIND 9 app with the zones returned
+// over the statistics channel. The statistics channel provides the zone
+// serials and timers, so no rndc commands are sent here. The details
+// available only with the rndc zonestatus command, i.e., the zone file
+// and the dynamic flag, are copied from the database. The new zones and
+// the zones whose type has changed are remembered, so their details are
+// fetched by the updateZoneDetails function after the statistics of all
+// apps are pulled.
+func (statsPuller *StatsPuller) updateZoneInventory(dbApp *dbmodel.App, views map[string]*ViewStatsData) error {
+	daemon := dbApp.Daemons[0]
+	existingZones, err := dbmodel.GetZonesByDaemonID(statsPuller.DB, daemon.ID)
+	if err != nil {
+		return err
+	}
+	existing := make(map[zoneKey]*dbmodel.Zone)
+	for i := range existingZones {
+		zone := &existingZones[i]
+		existing[zoneKey{zone.View, zone.Name, zone.Class}] = zone
+	}
+
+	pending, ok := statsPuller.pendingZoneDetails[daemon.ID]
+	if !ok {
+		// The details of the zones without a file could have not been
+		// fetched before the server restarted. Try to fetch them once.
+		pending = make(map[zoneKey]bool)
+		for key, zone := range existing {
+			if zone.File == "" {
+				pending[key] = true
+			}
+		}
+	}
+
+	zones := newZonesFromStats(views)
+	current := make(map[zoneKey]bool)
+	for i := range zones {
+		zone := &zones[i]
+		key := zoneKey{zone.View, zone.Name, zone.Class}
+		prev, ok := existing[key]
+		if ok {
+			zone.File = prev.File
+			zone.Dynamic = prev.Dynamic
+			if zone.LoadedAt.IsZero() {
+				zone.LoadedAt = prev.LoadedAt
+			}
+		}
+		current[key] = !ok || prev.ZoneType != zone.ZoneType || pending[key]
+	}
+	// Forget the zones no longer served by the daemon.
+	pending = make(map[zoneKey]bool)
+	for key, isPending := range current {
+		if isPending {
+			pending[key] = true
+		}
+	}
+	statsPuller.pendingZoneDetails[daemon.ID] = pending
+
+	return dbmodel.CommitZonesIntoDB(statsPuller.DB, daemon.ID, zones)
+}
+
+// Fetches the details of the zones remembered by the updateZoneInventory
+// function using the rndc zonestatus command and stores them in the
+// database. At most maxZoneStatusPerPull commands are sent to the app in
+// a single call. The zones are processed in the order of views and names.
+func (statsPuller *StatsPuller) updateZoneDetails(ctx context.Context, dbApp *dbmodel.App) {
+	daemon := dbApp.Daemons[0]
+	pending := statsPuller.pendingZoneDetails[daemon.ID]
+	keys := make([]zoneKey, 0, len(pending))
+	for key := range pending {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].view != keys[j].view {
+			return keys[i].view < keys[j].view
+		}
+		return keys[i].name < keys[j].name
+	})
+	if len(keys) > maxZoneStatusPerPull {
+		keys = keys[:maxZoneStatusPerPull]
+	}
+	for _, key := range keys {
+		// The zone is not retried when fetching its details fails, so
+		// the failing zones don't take the place of the other zones.
+		delete(pending, key)
+		zone := &dbmodel.Zone{
+			DaemonID: daemon.ID,
+			View:     key.view,
+			Name:     key.name,
+			Class:    key.class,
+		}
+		if err := getZoneStatus(ctx, statsPuller.Agents, dbApp, zone); err != nil {
+			log.WithError(err).Warnf("Cannot get the status of zone %s in view %s from BIND 9 app %d", zone.Name, zone.View, dbApp.ID)
+			continue
+		}
+		if err := dbmodel.UpdateZoneDetails(statsPuller.DB, zone); err != nil {
+			log.WithError(err).Warnf("Cannot update the details of zone %s in view %s from BIND 9 app %d", zone.Name, zone.View, dbApp.ID)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package bind9
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"isc.org/stork/server/agentcomm"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	storktest "isc.org/stork/server/test/dbmodel"
+)
+
+// Test that the zone types are converted to the types stored in the
+// database.
+func TestNormalizeZoneType(t *testing.T) {
+	require.Equal(t, dbmodel.ZoneTypePrimary, normalizeZoneType("master"))
+	require.Equal(t, dbmodel.ZoneTypePrimary, normalizeZoneType("primary"))
+	require.Equal(t, dbmodel.ZoneTypeSecondary, normalizeZoneType("slave"))
+	require.Equal(t, dbmodel.ZoneTypeSecondary, normalizeZoneType("Secondary"))
+	require.Equal(t, "stub", normalizeZoneType("stub"))
+}
+
+// Test parsing the times returned by named.
+func TestParseNamedTime(t *testing.T) {
+	expected := time.Date(2024, 2, 5, 13, 39, 36, 0, time.UTC)
+	require.Equal(t, expected, parseNamedTime("2024-02-05T13:39:36Z"))
+	require.Equal(t, expected, parseNamedTime("2024-02-05T13:39:36.000Z"))
+	require.Equal(t, expected, parseNamedTime("Mon, 05 Feb 2024 13:39:36 GMT"))
+	require.Zero(t, parseNamedTime(""))
+	require.Zero(t, parseNamedTime("never"))
+}
+
+// Test that the zones from the statistics channel are converted to the
+// database zones.
+func TestNewZonesFromStats(t *testing.T) {
+	views := map[string]*ViewStatsData{
+		"_default": {
+			Zones: []ZoneStatsData{
+				{Name: "example.org", Class: "IN", Serial: []byte("2024010101"), Type: "master", Loaded: "2024-02-05T13:39:36Z"},
+				{Name: "example.com", Class: "IN", Serial: []byte("7"), Type: "slave", Refresh: "2024-02-05T14:00:00Z", Expires: "2024-02-12T13:00:00Z"},
+				{Name: "10.in-addr.arpa", Class: "IN", Serial: []byte("0"), Type: "builtin"},
+				{Name: "broken.org", Class: "IN", Serial: []byte(`"-"`), Type: "master"},
+			},
+		},
+		"internal": {
+			Zones: []ZoneStatsData{
+				{Name: "example.org", Serial: []byte("5"), Type: "primary"},
+			},
+		},
+		"_bind": {
+			Zones: []ZoneStatsData{
+				{Name: "version.bind", Class: "CH", Serial: []byte("0"), Type: "primary"},
+			},
+		},
+	}
+	zones := newZonesFromStats(views)
+	require.Len(t, zones, 4)
+
+	require.Equal(t, "_default", zones[0].View)
+	require.Equal(t, "broken.org", zones[0].Name)
+	require.Zero(t, zones[0].Serial)
+
+	require.Equal(t, "example.com", zones[1].Name)
+	require.Equal(t, dbmodel.ZoneTypeSecondary, zones[1].ZoneType)
+	require.EqualValues(t, 7, zones[1].Serial)
+	require.Equal(t, time.Date(2024, 2, 5, 14, 0, 0, 0, time.UTC), zones[1].RefreshAt)
+	require.Equal(t, time.Date(2024, 2, 12, 13, 0, 0, 0, time.UTC), zones[1].ExpiresAt)
+
+	require.Equal(t, "example.org", zones[2].Name)
+	require.Equal(t, dbmodel.ZoneTypePrimary, zones[2].ZoneType)
+	require.EqualValues(t, 2024010101, zones[2].Serial)
+	require.Equal(t, time.Date(2024, 2, 5, 13, 39, 36, 0, time.UTC), zones[2].LoadedAt)
+	require.Zero(t, zones[2].RefreshAt)
+
+	require.Equal(t, "internal", zones[3].View)
+	require.Equal(t, "IN", zones[3].Class)
+}
+
+// Test parsing the output of the rndc zonestatus command.
+func TestParseZoneStatus(t *testing.T) {
+	output := `name: example.com
+type: slave
+files: example.com.db, example.com.inc
+serial: 2024010102
+nodes: 4
+last loaded: Mon, 05 Feb 2024 13:39:36 GMT
+next refresh: Mon, 05 Feb 2024 14:39:36 GMT
+expires: Mon, 12 Feb 2024 13:39:36 GMT
+secure: no
+dynamic: yes
+reconfigurable via modzone: no`
+
+	zone := &dbmodel.Zone{Name: "example.com", ZoneType: "unknown"}
+	parseZoneStatus(output, zone)
+	require.Equal(t, dbmodel.ZoneTypeSecondary, zone.ZoneType)
+	require.Equal(t, "example.com.db", zone.File)
+	require.EqualValues(t, 2024010102, zone.Serial)
+	require.True(t, zone.Dynamic)
+	require.Equal(t, time.Date(2024, 2, 5, 13, 39, 36, 0, time.UTC), zone.LoadedAt)
+	require.Equal(t, time.Date(2024, 2, 5, 14, 39, 36, 0, time.UTC), zone.RefreshAt)
+	require.Equal(t, time.Date(2024, 2, 12, 13, 39, 36, 0, time.UTC), zone.ExpiresAt)
+}
+
+// Test that the zone inventory is updated when the stats are pulled and
+// that the rndc zonestatus command is only sent for the new zones and
+// the zones whose type has changed.
+func TestStatsPullerUpdateZoneInventory(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	serial := "1"
+	zoneType := "primary"
+	bind9Mock := func(callNo int, statsOutput interface{}) {
+		json := `{
+		    "views":{
+		        "_default":{
+		            "zones":[
+		                {"name":"example.org","class":"IN","serial":` + serial + `,"type":"` + zoneType + `"},
+		                {"name":"example.com","class":"IN","serial":10,"type":"secondary"}
+		            ]
+		        }
+		    }
+		}`
+		agentcomm.UnmarshalNamedStatsResponse(json, statsOutput)
+	}
+	rndcMock := func(command string) string {
+		fields := strings.Fields(command)
+		return "name: " + fields[1] + "\nfiles: " + fields[1] + ".db\nlast loaded: Mon, 05 Feb 2024 13:39:36 GMT\n"
+	}
+	fa := agentcommtest.NewBind9FakeAgents(bind9Mock, rndcMock)
+	fec := &storktest.FakeEventCenter{}
+
+	var accessPoints []*dbmodel.AccessPoint
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "127.0.0.1", "abcd", 953, false)
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointStatistics, "127.0.0.1", "abcd", 8000, false)
+	machine := &dbmodel.Machine{
+		Address:   "192.0.1.0",
+		AgentPort: 1111,
+	}
+	err := dbmodel.AddMachine(db, machine)
+	require.NoError(t, err)
+	dbApp := dbmodel.App{
+		Type:         dbmodel.AppTypeBind9,
+		AccessPoints: accessPoints,
+		MachineID:    machine.ID,
+		Machine:      machine,
+		Daemons: []*dbmodel.Daemon{
+			dbmodel.NewBind9Daemon(true),
+		},
+	}
+	err = CommitAppIntoDB(db, &dbApp, fec)
+	require.NoError(t, err)
+
+	// initialize the settings needed by the puller
+	err = dbmodel.InitializeSettings(db, 0)
+	require.NoError(t, err)
+
+	sp, err := NewStatsPuller(db, fa, fec)
+	require.NoError(t, err)
+	defer sp.Shutdown()
+
+	// The zone status should be fetched for both new zones.
+	require.NoError(t, sp.pullStats())
+	require.ElementsMatch(t, []string{
+		"zonestatus example.com IN _default",
+		"zonestatus example.org IN _default",
+	}, fa.RecordedRndcCommands)
+
+	zones, err := dbmodel.GetZonesByDaemonID(db, dbApp.Daemons[0].ID)
+	require.NoError(t, err)
+	require.Len(t, zones, 2)
+	require.Equal(t, "example.com", zones[0].Name)
+	require.Equal(t, dbmodel.ZoneTypeSecondary, zones[0].ZoneType)
+	require.EqualValues(t, 10, zones[0].Serial)
+	require.Equal(t, "example.com.db", zones[0].File)
+	require.Equal(t, time.Date(2024, 2, 5, 13, 39, 36, 0, time.UTC), zones[0].LoadedAt)
+	require.Equal(t, "example.org", zones[1].Name)
+	require.EqualValues(t, 1, zones[1].Serial)
+
+	// The serial is provided by the statistics channel, so the zone status
+	// should not be fetched for the zone with the new serial. The details
+	// of the zones should be preserved.
+	fa.RecordedRndcCommands = nil
+	serial = "2"
+	require.NoError(t, sp.pullStats())
+	require.Empty(t, fa.RecordedRndcCommands)
+
+	zones, err = dbmodel.GetZonesByDaemonID(db, dbApp.Daemons[0].ID)
+	require.NoError(t, err)
+	require.Len(t, zones, 2)
+	require.Equal(t, "example.com.db", zones[0].File)
+	require.Equal(t, time.Date(2024, 2, 5, 13, 39, 36, 0, time.UTC), zones[0].LoadedAt)
+	require.EqualValues(t, 2, zones[1].Serial)
+	require.Equal(t, "example.org.db", zones[1].File)
+
+	// The zone status should be fetched for the zone whose type has changed.
+	zoneType = "secondary"
+	require.NoError(t, sp.pullStats())
+	require.Equal(t, []string{"zonestatus example.org IN _default"}, fa.RecordedRndcCommands)
+}
+
+// Test that the number of the rndc zonestatus commands sent in a single
+// pull is limited and the details of the remaining zones are fetched in
+// the next pulls.
+func TestStatsPullerUpdateZoneDetailsLimit(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	zoneCount := maxZoneStatusPerPull + 5
+	bind9Mock := func(callNo int, statsOutput interface{}) {
+		var zones []string
+		for i := 0; i < zoneCount; i++ {
+			zones = append(zones, fmt.Sprintf(`{"name":"zone%02d.example.org","class":"IN","serial":1,"type":"primary"}`, i))
+		}
+		json := `{"views":{"_default":{"zones":[` + strings.Join(zones, ",") + `]}}}`
+		agentcomm.UnmarshalNamedStatsResponse(json, statsOutput)
+	}
+	rndcMock := func(command string) string {
+		fields := strings.Fields(command)
+		return "name: " + fields[1] + "\nfiles: " + fields[1] + ".db\n"
+	}
+	fa := agentcommtest.NewBind9FakeAgents(bind9Mock, rndcMock)
+	fec := &storktest.FakeEventCenter{}
+
+	var accessPoints []*dbmodel.AccessPoint
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "127.0.0.1", "abcd", 953, false)
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointStatistics, "127.0.0.1", "abcd", 8000, false)
+	machine := &dbmodel.Machine{
+		Address:   "192.0.1.0",
+		AgentPort: 1111,
+	}
+	err := dbmodel.AddMachine(db, machine)
+	require.NoError(t, err)
+	dbApp := dbmodel.App{
+		Type:         dbmodel.AppTypeBind9,
+		AccessPoints: accessPoints,
+		MachineID:    machine.ID,
+		Machine:      machine,
+		Daemons: []*dbmodel.Daemon{
+			dbmodel.NewBind9Daemon(true),
+		},
+	}
+	err = CommitAppIntoDB(db, &dbApp, fec)
+	require.NoError(t, err)
+
+	err = dbmodel.InitializeSettings(db, 0)
+	require.NoError(t, err)
+
+	sp, err := NewStatsPuller(db, fa, fec)
+	require.NoError(t, err)
+	defer sp.Shutdown()
+
+	// All zones should be stored but only some of them have the details.
+	require.NoError(t, sp.pullStats())
+	require.Len(t, fa.RecordedRndcCommands, maxZoneStatusPerPull)
+	zones, err := dbmodel.GetZonesByDaemonID(db, dbApp.Daemons[0].ID)
+	require.NoError(t, err)
+	require.Len(t, zones, zoneCount)
+	require.Equal(t, "zone00.example.org.db", zones[0].File)
+	require.Empty(t, zones[zoneCount-1].File)
+
+	// The details of the remaining zones should be fetched in the next pull.
+	fa.RecordedRndcCommands = nil
+	require.NoError(t, sp.pullStats())
+	require.Len(t, fa.RecordedRndcCommands, zoneCount-maxZoneStatusPerPull)
+	zones, err = dbmodel.GetZonesByDaemonID(db, dbApp.Daemons[0].ID)
+	require.NoError(t, err)
+	for _, zone := range zones {
+		require.Equal(t, zone.Name+".db", zone.File)
+	}
+
+	// No more zone status commands should be sent.
+	fa.RecordedRndcCommands = nil
+	require.NoError(t, sp.pullStats())
+	require.Empty(t, fa.RecordedRndcCommands)
+}
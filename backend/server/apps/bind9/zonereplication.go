@@ -0,0 +1,194 @@
+package bind9
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	dbops "isc.org/stork/server/database"
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/eventcenter"
+	storkutil "isc.org/stork/util"
+)
+
+// Identifies the zone regardless of the daemon and view serving it.
+type zoneNameKey struct {
+	name  string
+	class string
+}
+
+// Finds the primary zone of the secondary zone among the monitored primary
+// zones with the same name and class. If there are several candidates,
+// e.g., the zone is served in several views, the primary zone in the view
+// with the same name is selected. It returns nil if the primary zone is
+// not monitored or it cannot be determined unambiguously.
+func findPrimaryZone(secondary *dbmodel.Zone, primaries map[zoneNameKey][]*dbmodel.Zone) *dbmodel.Zone {
+	candidates := primaries[zoneNameKey{strings.ToLower(secondary.Name), secondary.Class}]
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+	var found *dbmodel.Zone
+	for _, candidate := range candidates {
+		if candidate.View != secondary.View {
+			continue
+		}
+		if found != nil {
+			return nil
+		}
+		found = candidate
+	}
+	return found
+}
+
+// Evaluates the replication status of the secondary zone compared with its
+// primary zone. The secondary zone is lagging if its serial has differed
+// from the primary serial for longer than the threshold. The transfer has
+// failed if the secondary zone is not loaded, it has expired, or it is
+// still out of sync long after the first refresh scheduled since it went
+// out of sync. This refresh is remembered because named reschedules the
+// refresh after each failed attempt. The secondary zone may be pulled
+// before its refresh, so the failure is only flagged when the refresh is
+// late by more than the pull interval plus the threshold. It returns the
+// status, the time since when the zone has been out of sync and the time
+// of the first refresh scheduled since then.
+func evaluateZoneReplication(secondary, primary *dbmodel.Zone, threshold, pullInterval time.Duration, now time.Time) (string, time.Time, time.Time) {
+	if primary == nil {
+		return dbmodel.ZoneReplicationStatusUnknown, time.Time{}, time.Time{}
+	}
+
+	outOfSyncSince := secondary.OutOfSyncSince
+	outOfSyncRefreshAt := secondary.OutOfSyncRefreshAt
+	if secondary.Serial == primary.Serial {
+		outOfSyncSince = time.Time{}
+		outOfSyncRefreshAt = time.Time{}
+	} else {
+		if outOfSyncSince.IsZero() {
+			outOfSyncSince = now
+		}
+		if outOfSyncRefreshAt.IsZero() && !secondary.RefreshAt.IsZero() {
+			// The refresh time pulled before the zone went out of sync
+			// can't reflect the new primary serial.
+			outOfSyncRefreshAt = secondary.RefreshAt
+			if outOfSyncRefreshAt.Before(outOfSyncSince) {
+				outOfSyncRefreshAt = outOfSyncSince
+			}
+		}
+	}
+
+	switch {
+	case secondary.Serial == 0 && secondary.LoadedAt.IsZero(),
+		!secondary.ExpiresAt.IsZero() && secondary.ExpiresAt.Before(now),
+		!outOfSyncRefreshAt.IsZero() && now.Sub(outOfSyncRefreshAt) > pullInterval+threshold:
+		return dbmodel.ZoneReplicationStatusTransferFailed, outOfSyncSince, outOfSyncRefreshAt
+	case !outOfSyncSince.IsZero() && now.Sub(outOfSyncSince) > threshold:
+		return dbmodel.ZoneReplicationStatusLagging, outOfSyncSince, outOfSyncRefreshAt
+	default:
+		return dbmodel.ZoneReplicationStatusInSync, outOfSyncSince, outOfSyncRefreshAt
+	}
+}
+
+// Raises an event when the replication status of the secondary zone
+// changes to lagging or transfer-failed, and when the zone is back in sync.
+func addZoneReplicationEvent(eventCenter eventcenter.EventCenter, zone, primary *dbmodel.Zone, previousStatus string, now time.Time) {
+	if zone.ReplicationStatus == previousStatus {
+		return
+	}
+	subject := fmt.Sprintf("secondary zone %s in view %s on {daemon}", zone.Name, zone.View)
+	primaryApp := ""
+	if primary != nil && primary.Daemon != nil && primary.Daemon.App != nil {
+		primaryApp = fmt.Sprintf(" on app %s", primary.Daemon.App.Name)
+	}
+	switch zone.ReplicationStatus {
+	case dbmodel.ZoneReplicationStatusLagging:
+		text := fmt.Sprintf("The %s lags its primary%s since %s: the serial is %d and the primary serial is %d",
+			subject, primaryApp, zone.OutOfSyncSince.Format(time.RFC3339), zone.Serial, primary.Serial)
+		eventCenter.AddWarningEvent(text, zone.Daemon)
+	case dbmodel.ZoneReplicationStatusTransferFailed:
+		reason := "the zone is not loaded"
+		switch {
+		case !zone.ExpiresAt.IsZero() && zone.ExpiresAt.Before(now):
+			reason = fmt.Sprintf("the zone expired at %s", zone.ExpiresAt.Format(time.RFC3339))
+		case !zone.OutOfSyncRefreshAt.IsZero():
+			reason = fmt.Sprintf("the zone is out of sync since the refresh at %s: the serial is %d and the primary serial is %d",
+				zone.OutOfSyncRefreshAt.Format(time.RFC3339), zone.Serial, primary.Serial)
+		}
+		text := fmt.Sprintf("Transfer of the %s from its primary%s has failed; %s",
+			subject, primaryApp, reason)
+		eventCenter.AddWarningEvent(text, zone.Daemon)
+	case dbmodel.ZoneReplicationStatusInSync:
+		if previousStatus != dbmodel.ZoneReplicationStatusLagging &&
+			previousStatus != dbmodel.ZoneReplicationStatusTransferFailed {
+			return
+		}
+		text := fmt.Sprintf("The %s is in sync with its primary%s again", subject, primaryApp)
+		eventCenter.AddInfoEvent(text, zone.Daemon)
+	}
+}
+
+// Compares the secondary zones of all monitored BIND 9 daemons with their
+// primary zones, stores their replication status and raises the events
+// when the status changes. The pull interval is the interval of pulling
+// the zones from the daemons. It returns the last error.
+func checkZoneReplication(db dbops.DBI, eventCenter eventcenter.EventCenter, threshold, pullInterval time.Duration, now time.Time) error {
+	zones, err := dbmodel.GetAllZones(db)
+	if err != nil {
+		return err
+	}
+
+	primaries := make(map[zoneNameKey][]*dbmodel.Zone)
+	for i := range zones {
+		if zones[i].ZoneType == dbmodel.ZoneTypePrimary {
+			key := zoneNameKey{strings.ToLower(zones[i].Name), zones[i].Class}
+			primaries[key] = append(primaries[key], &zones[i])
+		}
+	}
+
+	var lastErr error
+	for i := range zones {
+		zone := &zones[i]
+		previous := *zone
+		if zone.ZoneType != dbmodel.ZoneTypeSecondary {
+			// The zone may have been converted from a secondary zone.
+			zone.PrimaryZoneID = 0
+			zone.ReplicationStatus = ""
+			zone.OutOfSyncSince = time.Time{}
+			zone.OutOfSyncRefreshAt = time.Time{}
+		} else {
+			primary := findPrimaryZone(zone, primaries)
+			zone.PrimaryZoneID = 0
+			if primary != nil {
+				zone.PrimaryZoneID = primary.ID
+			}
+			zone.ReplicationStatus, zone.OutOfSyncSince, zone.OutOfSyncRefreshAt = evaluateZoneReplication(zone, primary, threshold, pullInterval, now)
+			addZoneReplicationEvent(eventCenter, zone, primary, previous.ReplicationStatus, now)
+		}
+		if zone.PrimaryZoneID == previous.PrimaryZoneID &&
+			zone.ReplicationStatus == previous.ReplicationStatus &&
+			zone.OutOfSyncSince.Equal(previous.OutOfSyncSince) &&
+			zone.OutOfSyncRefreshAt.Equal(previous.OutOfSyncRefreshAt) {
+			continue
+		}
+		if err := dbmodel.UpdateZoneReplication(db, zone); err != nil {
+			lastErr = err
+			log.WithError(err).Errorf("Cannot update replication status of zone %s in view %s", zone.Name, zone.View)
+		}
+	}
+	return lastErr
+}
+
+// Checks the replication of the secondary zones using the lag threshold
+// and the pull interval from the settings.
+func (statsPuller *StatsPuller) checkZoneReplication() error {
+	threshold, err := dbmodel.GetSettingInt(statsPuller.DB, "zone_serial_lag_threshold")
+	if err != nil {
+		return errors.WithMessage(err, "cannot check zone replication")
+	}
+	pullInterval, err := dbmodel.GetSettingInt(statsPuller.DB, statsPuller.GetIntervalSettingName())
+	if err != nil {
+		return errors.WithMessage(err, "cannot check zone replication")
+	}
+	return checkZoneReplication(statsPuller.DB, statsPuller.EventCenter, time.Duration(threshold)*time.Minute,
+		time.Duration(pullInterval)*time.Second, storkutil.UTCNow())
+}
@@ -0,0 +1,294 @@
+package bind9
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	storktest "isc.org/stork/server/test/dbmodel"
+)
+
+// Test finding the primary zone of the secondary zone.
+func TestFindPrimaryZone(t *testing.T) {
+	primaryOrg := &dbmodel.Zone{ID: 1, View: "_default", Name: "example.org", Class: "IN"}
+	primaryComDefault := &dbmodel.Zone{ID: 2, View: "_default", Name: "example.com", Class: "IN"}
+	primaryComInternal := &dbmodel.Zone{ID: 3, View: "internal", Name: "example.com", Class: "IN"}
+	primaries := map[zoneNameKey][]*dbmodel.Zone{
+		{"example.org", "IN"}: {primaryOrg},
+		{"example.com", "IN"}: {primaryComDefault, primaryComInternal},
+	}
+
+	// Single candidate in any view.
+	require.Equal(t, primaryOrg, findPrimaryZone(&dbmodel.Zone{View: "external", Name: "Example.ORG", Class: "IN"}, primaries))
+	// The candidate in the same view.
+	require.Equal(t, primaryComInternal, findPrimaryZone(&dbmodel.Zone{View: "internal", Name: "example.com", Class: "IN"}, primaries))
+	// Ambiguous candidates.
+	require.Nil(t, findPrimaryZone(&dbmodel.Zone{View: "external", Name: "example.com", Class: "IN"}, primaries))
+	// No candidates.
+	require.Nil(t, findPrimaryZone(&dbmodel.Zone{View: "_default", Name: "example.net", Class: "IN"}, primaries))
+	require.Nil(t, findPrimaryZone(&dbmodel.Zone{View: "_default", Name: "example.org", Class: "CH"}, primaries))
+}
+
+// Test evaluating the replication status of the secondary zone.
+func TestEvaluateZoneReplication(t *testing.T) {
+	now := time.Date(2024, 2, 5, 12, 0, 0, 0, time.UTC)
+	loadedAt := now.Add(-24 * time.Hour)
+	pullInterval := 10 * time.Minute
+	primary := &dbmodel.Zone{Serial: 10}
+
+	t.Run("unknown primary", func(t *testing.T) {
+		status, since, refreshAt := evaluateZoneReplication(&dbmodel.Zone{Serial: 9, LoadedAt: loadedAt}, nil, time.Hour, pullInterval, now)
+		require.Equal(t, dbmodel.ZoneReplicationStatusUnknown, status)
+		require.Zero(t, since)
+		require.Zero(t, refreshAt)
+	})
+
+	t.Run("in sync", func(t *testing.T) {
+		secondary := &dbmodel.Zone{
+			Serial: 10, LoadedAt: loadedAt, RefreshAt: now.Add(time.Minute),
+			OutOfSyncSince: now.Add(-2 * time.Hour), OutOfSyncRefreshAt: now.Add(-time.Hour),
+		}
+		status, since, refreshAt := evaluateZoneReplication(secondary, primary, time.Hour, pullInterval, now)
+		require.Equal(t, dbmodel.ZoneReplicationStatusInSync, status)
+		require.Zero(t, since)
+		require.Zero(t, refreshAt)
+	})
+
+	t.Run("out of sync within threshold", func(t *testing.T) {
+		secondary := &dbmodel.Zone{Serial: 9, LoadedAt: loadedAt}
+		status, since, refreshAt := evaluateZoneReplication(secondary, primary, time.Hour, pullInterval, now)
+		require.Equal(t, dbmodel.ZoneReplicationStatusInSync, status)
+		require.Equal(t, now, since)
+		require.Zero(t, refreshAt)
+	})
+
+	t.Run("lagging", func(t *testing.T) {
+		secondary := &dbmodel.Zone{Serial: 9, LoadedAt: loadedAt, OutOfSyncSince: now.Add(-2 * time.Hour)}
+		status, since, refreshAt := evaluateZoneReplication(secondary, primary, time.Hour, pullInterval, now)
+		require.Equal(t, dbmodel.ZoneReplicationStatusLagging, status)
+		require.Equal(t, now.Add(-2*time.Hour), since)
+		require.Zero(t, refreshAt)
+	})
+
+	t.Run("not loaded", func(t *testing.T) {
+		status, since, _ := evaluateZoneReplication(&dbmodel.Zone{}, primary, time.Hour, pullInterval, now)
+		require.Equal(t, dbmodel.ZoneReplicationStatusTransferFailed, status)
+		require.Equal(t, now, since)
+	})
+
+	t.Run("old secondary pull", func(t *testing.T) {
+		// The secondary zone was pulled long before the primary serial
+		// changed, so its refresh time has passed.
+		secondary := &dbmodel.Zone{Serial: 9, LoadedAt: loadedAt, RefreshAt: now.Add(-3 * time.Hour), ExpiresAt: now.Add(time.Hour)}
+		status, since, refreshAt := evaluateZoneReplication(secondary, primary, time.Hour, pullInterval, now)
+		require.Equal(t, dbmodel.ZoneReplicationStatusInSync, status)
+		require.Equal(t, now, since)
+		require.Equal(t, now, refreshAt)
+	})
+
+	t.Run("refresh late within pull interval and threshold", func(t *testing.T) {
+		secondary := &dbmodel.Zone{
+			Serial: 9, LoadedAt: loadedAt, RefreshAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour),
+			OutOfSyncSince: now.Add(-2 * time.Hour),
+		}
+		status, since, refreshAt := evaluateZoneReplication(secondary, primary, time.Hour, pullInterval, now)
+		require.Equal(t, dbmodel.ZoneReplicationStatusLagging, status)
+		require.Equal(t, now.Add(-2*time.Hour), since)
+		require.Equal(t, now.Add(-time.Hour), refreshAt)
+	})
+
+	t.Run("refresh late", func(t *testing.T) {
+		secondary := &dbmodel.Zone{
+			Serial: 9, LoadedAt: loadedAt, RefreshAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(time.Hour),
+			OutOfSyncSince: now.Add(-3 * time.Hour),
+		}
+		status, since, refreshAt := evaluateZoneReplication(secondary, primary, time.Hour, pullInterval, now)
+		require.Equal(t, dbmodel.ZoneReplicationStatusTransferFailed, status)
+		require.Equal(t, now.Add(-3*time.Hour), since)
+		require.Equal(t, now.Add(-2*time.Hour), refreshAt)
+	})
+
+	t.Run("refresh pending", func(t *testing.T) {
+		secondary := &dbmodel.Zone{Serial: 9, LoadedAt: loadedAt, RefreshAt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour)}
+		status, since, refreshAt := evaluateZoneReplication(secondary, primary, time.Hour, pullInterval, now)
+		require.Equal(t, dbmodel.ZoneReplicationStatusInSync, status)
+		require.Equal(t, now, since)
+		require.Equal(t, now.Add(time.Minute), refreshAt)
+	})
+
+	t.Run("retry keeps failing", func(t *testing.T) {
+		// named reschedules the refresh after each failed attempt, so the
+		// refresh time of the secondary zone is always in the future.
+		secondary := &dbmodel.Zone{Serial: 9, LoadedAt: loadedAt, ExpiresAt: now.Add(24 * time.Hour)}
+		var statuses []string
+		for i := 0; i < 12; i++ {
+			pulledAt := now.Add(time.Duration(i) * pullInterval)
+			secondary.RefreshAt = pulledAt.Add(5 * time.Minute)
+			secondary.ReplicationStatus, secondary.OutOfSyncSince, secondary.OutOfSyncRefreshAt = evaluateZoneReplication(secondary, primary, time.Hour, pullInterval, pulledAt)
+			require.Equal(t, now, secondary.OutOfSyncSince)
+			require.Equal(t, now.Add(5*time.Minute), secondary.OutOfSyncRefreshAt)
+			statuses = append(statuses, secondary.ReplicationStatus)
+		}
+		require.Equal(t, dbmodel.ZoneReplicationStatusInSync, statuses[6])
+		require.Equal(t, dbmodel.ZoneReplicationStatusLagging, statuses[7])
+		require.Equal(t, dbmodel.ZoneReplicationStatusTransferFailed, statuses[8])
+		require.Equal(t, dbmodel.ZoneReplicationStatusTransferFailed, statuses[11])
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		secondary := &dbmodel.Zone{Serial: 10, LoadedAt: loadedAt, ExpiresAt: now.Add(-time.Minute)}
+		status, since, refreshAt := evaluateZoneReplication(secondary, primary, time.Hour, pullInterval, now)
+		require.Equal(t, dbmodel.ZoneReplicationStatusTransferFailed, status)
+		require.Zero(t, since)
+		require.Zero(t, refreshAt)
+	})
+}
+
+// Test that the events are raised when the replication status changes.
+func TestAddZoneReplicationEvent(t *testing.T) {
+	now := time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)
+	daemon := dbmodel.NewBind9Daemon(true)
+	primary := &dbmodel.Zone{
+		Serial: 10,
+		Daemon: &dbmodel.Daemon{App: &dbmodel.App{Name: "dns1"}},
+	}
+	zone := &dbmodel.Zone{
+		View:              "_default",
+		Name:              "example.org",
+		Serial:            9,
+		Daemon:            daemon,
+		ReplicationStatus: dbmodel.ZoneReplicationStatusLagging,
+		OutOfSyncSince:    time.Date(2024, 2, 5, 12, 0, 0, 0, time.UTC),
+	}
+	fec := &storktest.FakeEventCenter{}
+
+	// Unchanged status.
+	addZoneReplicationEvent(fec, zone, primary, dbmodel.ZoneReplicationStatusLagging, now)
+	require.Empty(t, fec.Events)
+
+	// Lagging.
+	addZoneReplicationEvent(fec, zone, primary, dbmodel.ZoneReplicationStatusInSync, now)
+	require.Len(t, fec.Events, 1)
+	require.Equal(t, dbmodel.EvWarning, fec.Events[0].Level)
+	require.Contains(t, fec.Events[0].Text, "secondary zone example.org in view _default")
+	require.Contains(t, fec.Events[0].Text, "on app dns1 since 2024-02-05T12:00:00Z")
+	require.Contains(t, fec.Events[0].Text, "the serial is 9 and the primary serial is 10")
+
+	// Transfer failed.
+	zone.ReplicationStatus = dbmodel.ZoneReplicationStatusTransferFailed
+	zone.ExpiresAt = time.Date(2024, 2, 6, 12, 0, 0, 0, time.UTC)
+	addZoneReplicationEvent(fec, zone, primary, dbmodel.ZoneReplicationStatusLagging, now)
+	require.Len(t, fec.Events, 2)
+	require.Equal(t, dbmodel.EvWarning, fec.Events[1].Level)
+	require.Contains(t, fec.Events[1].Text, "has failed; the zone expired at 2024-02-06T12:00:00Z")
+
+	// Transfer failed before the zone expiry.
+	zone.ExpiresAt = time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC)
+	zone.OutOfSyncRefreshAt = time.Date(2024, 2, 7, 10, 0, 0, 0, time.UTC)
+	addZoneReplicationEvent(fec, zone, primary, dbmodel.ZoneReplicationStatusInSync, now)
+	require.Len(t, fec.Events, 3)
+	require.Contains(t, fec.Events[2].Text, "has failed; the zone is out of sync since the refresh at 2024-02-07T10:00:00Z")
+	require.Contains(t, fec.Events[2].Text, "the serial is 9 and the primary serial is 10")
+
+	// Back in sync.
+	zone.ReplicationStatus = dbmodel.ZoneReplicationStatusInSync
+	addZoneReplicationEvent(fec, zone, primary, dbmodel.ZoneReplicationStatusTransferFailed, now)
+	require.Len(t, fec.Events, 4)
+	require.Equal(t, dbmodel.EvInfo, fec.Events[3].Level)
+	require.Contains(t, fec.Events[3].Text, "is in sync with its primary on app dns1 again")
+
+	// No event when the primary becomes known.
+	addZoneReplicationEvent(fec, zone, primary, dbmodel.ZoneReplicationStatusUnknown, now)
+	require.Len(t, fec.Events, 4)
+}
+
+// Test that the replication status of the secondary zones is stored in
+// the database and the events are raised when it changes.
+func TestCheckZoneReplication(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fec := &storktest.FakeEventCenter{}
+	var apps []*dbmodel.App
+	for _, address := range []string{"192.0.2.1", "192.0.2.2"} {
+		machine := &dbmodel.Machine{
+			Address:   address,
+			AgentPort: 8080,
+		}
+		require.NoError(t, dbmodel.AddMachine(db, machine))
+		app := &dbmodel.App{
+			Type:      dbmodel.AppTypeBind9,
+			MachineID: machine.ID,
+			Daemons: []*dbmodel.Daemon{
+				dbmodel.NewBind9Daemon(true),
+			},
+		}
+		_, err := dbmodel.AddApp(db, app)
+		require.NoError(t, err)
+		apps = append(apps, app)
+	}
+
+	now := time.Date(2024, 2, 5, 12, 0, 0, 0, time.UTC)
+	loadedAt := now.Add(-24 * time.Hour)
+	require.NoError(t, dbmodel.CommitZonesIntoDB(db, apps[0].Daemons[0].ID, []dbmodel.Zone{
+		{View: "_default", Name: "example.org", Class: "IN", ZoneType: dbmodel.ZoneTypePrimary, Serial: 10, LoadedAt: loadedAt},
+	}))
+	require.NoError(t, dbmodel.CommitZonesIntoDB(db, apps[1].Daemons[0].ID, []dbmodel.Zone{
+		{View: "_default", Name: "example.org", Class: "IN", ZoneType: dbmodel.ZoneTypeSecondary, Serial: 9, LoadedAt: loadedAt},
+		{View: "_default", Name: "example.net", Class: "IN", ZoneType: dbmodel.ZoneTypeSecondary, Serial: 1, LoadedAt: loadedAt},
+	}))
+
+	getZone := func(name string) *dbmodel.Zone {
+		zones, err := dbmodel.GetZonesByDaemonID(db, apps[1].Daemons[0].ID)
+		require.NoError(t, err)
+		for i := range zones {
+			if zones[i].Name == name {
+				return &zones[i]
+			}
+		}
+		require.FailNow(t, "zone not found", name)
+		return nil
+	}
+
+	// The secondary zone is out of sync but within the threshold.
+	require.NoError(t, checkZoneReplication(db, fec, time.Hour, 10*time.Minute, now))
+	zone := getZone("example.org")
+	require.Equal(t, dbmodel.ZoneReplicationStatusInSync, zone.ReplicationStatus)
+	require.NotZero(t, zone.PrimaryZoneID)
+	require.Equal(t, now, zone.OutOfSyncSince)
+	require.Equal(t, dbmodel.ZoneReplicationStatusUnknown, getZone("example.net").ReplicationStatus)
+	require.Empty(t, fec.Events)
+
+	// The threshold is exceeded.
+	require.NoError(t, checkZoneReplication(db, fec, time.Hour, 10*time.Minute, now.Add(2*time.Hour)))
+	zone = getZone("example.org")
+	require.Equal(t, dbmodel.ZoneReplicationStatusLagging, zone.ReplicationStatus)
+	require.Equal(t, now, zone.OutOfSyncSince)
+	require.Len(t, fec.Events, 1)
+	require.Equal(t, dbmodel.EvWarning, fec.Events[0].Level)
+
+	// No new event when the status doesn't change.
+	require.NoError(t, checkZoneReplication(db, fec, time.Hour, 10*time.Minute, now.Add(3*time.Hour)))
+	require.Len(t, fec.Events, 1)
+
+	// The secondary catches up.
+	require.NoError(t, dbmodel.CommitZonesIntoDB(db, apps[1].Daemons[0].ID, []dbmodel.Zone{
+		{View: "_default", Name: "example.org", Class: "IN", ZoneType: dbmodel.ZoneTypeSecondary, Serial: 10, LoadedAt: loadedAt},
+	}))
+	require.NoError(t, checkZoneReplication(db, fec, time.Hour, 10*time.Minute, now.Add(4*time.Hour)))
+	zone = getZone("example.org")
+	require.Equal(t, dbmodel.ZoneReplicationStatusInSync, zone.ReplicationStatus)
+	require.Zero(t, zone.OutOfSyncSince)
+	require.Len(t, fec.Events, 2)
+	require.Equal(t, dbmodel.EvInfo, fec.Events[1].Level)
+
+	// The primary is no longer monitored.
+	require.NoError(t, dbmodel.CommitZonesIntoDB(db, apps[0].Daemons[0].ID, nil))
+	require.NoError(t, checkZoneReplication(db, fec, time.Hour, 10*time.Minute, now.Add(5*time.Hour)))
+	zone = getZone("example.org")
+	require.Equal(t, dbmodel.ZoneReplicationStatusUnknown, zone.ReplicationStatus)
+	require.Zero(t, zone.PrimaryZoneID)
+	require.Len(t, fec.Events, 2)
+}
@@ -664,6 +664,23 @@ func CommitAppIntoDB(db *dbops.PgDB, app *dbmodel.App, eventCenter eventcenter.E
 					return err
 				}
 			}
+
+			// Replace the client classes of the daemon with the classes from
+			// its current configuration.
+			if daemon.KeaDaemon != nil && daemon.KeaDaemon.Config != nil {
+				err = dbmodel.CommitClientClassesIntoDB(tx, daemon.ID, daemon.KeaDaemon.Config.GetClientClasses())
+				if err != nil {
+					err = errors.Wrapf(err, "unable to commit client classes for Kea daemon %d", daemon.ID)
+					return err
+				}
+			}
+
+			// Store the changed configuration in the daemon's configuration
+			// history.
+			if err = commitConfigSnapshot(tx, daemon); err != nil {
+				err = errors.WithMessagef(err, "unable to store configuration snapshot for Kea daemon %d", daemon.ID)
+				return err
+			}
 		}
 
 		// Add events to the database.
@@ -0,0 +1,271 @@
+package kea
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	keaconfig "isc.org/stork/appcfg/kea"
+	keactrl "isc.org/stork/appctrl/kea"
+	"isc.org/stork/server/config"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Converts the entity to JSON for the audit trail. It returns nil if the
+// entity cannot be serialized.
+func marshalAuditSnapshot(entity any) json.RawMessage {
+	data, err := json.Marshal(entity)
+	if err != nil {
+		log.WithError(err).Warn("Cannot serialize the entity for the audit trail")
+		return nil
+	}
+	return data
+}
+
+// Returns a JSON representation of the command sent to Kea for the audit
+// trail. The arguments of the config-set command are not stored because
+// they hold the entire configuration, including the database passwords and
+// the TSIG secrets. The changed parameters are recorded in the entity
+// snapshots instead. The sensitive data in the arguments of the other
+// commands are hidden like in the configurations returned over the REST API.
+func newAuditCommandData(command keactrl.SerializableCommand) json.RawMessage {
+	var data map[string]any
+	if err := json.Unmarshal([]byte(command.Marshal()), &data); err != nil {
+		log.WithError(err).Warn("Cannot serialize the command for the audit trail")
+		return nil
+	}
+	if command.GetCommand() == keactrl.ConfigSet {
+		delete(data, "arguments")
+	} else if arguments, ok := data["arguments"].(map[string]any); ok {
+		(&keaconfig.Config{Raw: arguments}).HideSensitiveData()
+	}
+	return marshalAuditSnapshot(data)
+}
+
+// Returns a JSON representation of the host for the audit trail. The
+// daemons and other related objects are stripped from the copy to keep
+// the entry focused on the modified entity.
+func newAuditHostSnapshot(host *dbmodel.Host) json.RawMessage {
+	if host == nil {
+		return nil
+	}
+	snapshot := *host
+	snapshot.Subnet = nil
+	snapshot.LocalHosts = make([]dbmodel.LocalHost, len(host.LocalHosts))
+	for i, lh := range host.LocalHosts {
+		lh.Daemon = nil
+		lh.Host = nil
+		snapshot.LocalHosts[i] = lh
+	}
+	return marshalAuditSnapshot(snapshot)
+}
+
+// Returns a copy of the subnet stripped from the daemons and other
+// related objects.
+func stripSubnetForAudit(subnet dbmodel.Subnet) dbmodel.Subnet {
+	subnet.SharedNetwork = nil
+	subnet.Hosts = nil
+	localSubnets := make([]*dbmodel.LocalSubnet, len(subnet.LocalSubnets))
+	for i, ls := range subnet.LocalSubnets {
+		localSubnet := *ls
+		localSubnet.Daemon = nil
+		localSubnet.Subnet = nil
+		localSubnet.AddressPools = make([]dbmodel.AddressPool, len(ls.AddressPools))
+		for j, pool := range ls.AddressPools {
+			pool.LocalSubnet = nil
+			localSubnet.AddressPools[j] = pool
+		}
+		localSubnet.PrefixPools = make([]dbmodel.PrefixPool, len(ls.PrefixPools))
+		for j, pool := range ls.PrefixPools {
+			pool.LocalSubnet = nil
+			localSubnet.PrefixPools[j] = pool
+		}
+		localSubnets[i] = &localSubnet
+	}
+	subnet.LocalSubnets = localSubnets
+	return subnet
+}
+
+// Returns a JSON representation of the subnet for the audit trail.
+func newAuditSubnetSnapshot(subnet *dbmodel.Subnet) json.RawMessage {
+	if subnet == nil {
+		return nil
+	}
+	return marshalAuditSnapshot(stripSubnetForAudit(*subnet))
+}
+
+// Returns a JSON representation of the shared network for the audit trail.
+func newAuditSharedNetworkSnapshot(sharedNetwork *dbmodel.SharedNetwork) json.RawMessage {
+	if sharedNetwork == nil {
+		return nil
+	}
+	snapshot := *sharedNetwork
+	snapshot.Subnets = make([]dbmodel.Subnet, len(sharedNetwork.Subnets))
+	for i, subnet := range sharedNetwork.Subnets {
+		snapshot.Subnets[i] = stripSubnetForAudit(subnet)
+	}
+	snapshot.LocalSharedNetworks = make([]*dbmodel.LocalSharedNetwork, len(sharedNetwork.LocalSharedNetworks))
+	for i, lsn := range sharedNetwork.LocalSharedNetworks {
+		localSharedNetwork := *lsn
+		localSharedNetwork.Daemon = nil
+		localSharedNetwork.SharedNetwork = nil
+		snapshot.LocalSharedNetworks[i] = &localSharedNetwork
+	}
+	return marshalAuditSnapshot(snapshot)
+}
+
+// Returns a JSON representation of the client class for the audit trail.
+func newAuditClientClassSnapshot(class *dbmodel.ClientClass) json.RawMessage {
+	if class == nil {
+		return nil
+	}
+	snapshot := *class
+	snapshot.Daemon = nil
+	return marshalAuditSnapshot(snapshot)
+}
+
+// Returns a JSON representation of the global parameters updates for the
+// audit trail. It includes the changed parameters in each daemon with their
+// values before and after the update.
+func newAuditGlobalParametersSnapshot(updates []DaemonGlobalParametersUpdate) json.RawMessage {
+	if len(updates) == 0 {
+		return nil
+	}
+	return marshalAuditSnapshot(updates)
+}
+
+// Returns the first non-nil ID from the list.
+func firstAuditEntityID(ids ...*int64) int64 {
+	for _, id := range ids {
+		if id != nil && *id != 0 {
+			return *id
+		}
+	}
+	return 0
+}
+
+// Creates an audit entry describing the committed config update. The
+// commitErr is the error returned by the commit, if any.
+func newAuditEntry(update *config.Update[ConfigRecipe], commitErr error) *dbmodel.AuditEntry {
+	entry := &dbmodel.AuditEntry{
+		Target:    string(update.Target),
+		Operation: update.Operation,
+		DaemonIDs: update.DaemonIDs,
+		Commands:  update.Recipe.CommandResults,
+	}
+	if index := strings.LastIndex(update.Operation, "_"); index > 0 {
+		entry.EntityType = update.Operation[:index]
+	} else {
+		entry.EntityType = update.Operation
+	}
+	if commitErr != nil {
+		entry.Error = commitErr.Error()
+	}
+	recipe := update.Recipe
+	switch entry.EntityType {
+	case "host":
+		var beforeID, afterID *int64
+		if recipe.HostBeforeUpdate != nil {
+			beforeID = &recipe.HostBeforeUpdate.ID
+		}
+		if recipe.HostAfterUpdate != nil {
+			afterID = &recipe.HostAfterUpdate.ID
+		}
+		entry.EntityID = firstAuditEntityID(recipe.HostID, afterID, beforeID)
+		entry.EntityBefore = newAuditHostSnapshot(recipe.HostBeforeUpdate)
+		entry.EntityAfter = newAuditHostSnapshot(recipe.HostAfterUpdate)
+	case "shared_network":
+		var beforeID, afterID *int64
+		if recipe.SharedNetworkBeforeUpdate != nil {
+			beforeID = &recipe.SharedNetworkBeforeUpdate.ID
+		}
+		if recipe.SharedNetworkAfterUpdate != nil {
+			afterID = &recipe.SharedNetworkAfterUpdate.ID
+		}
+		entry.EntityID = firstAuditEntityID(recipe.SharedNetworkID, afterID, beforeID)
+		entry.EntityBefore = newAuditSharedNetworkSnapshot(recipe.SharedNetworkBeforeUpdate)
+		entry.EntityAfter = newAuditSharedNetworkSnapshot(recipe.SharedNetworkAfterUpdate)
+	case "subnet":
+		var beforeID, afterID *int64
+		if recipe.SubnetBeforeUpdate != nil {
+			beforeID = &recipe.SubnetBeforeUpdate.ID
+		}
+		if recipe.SubnetAfterUpdate != nil {
+			afterID = &recipe.SubnetAfterUpdate.ID
+		}
+		entry.EntityID = firstAuditEntityID(recipe.SubnetID, afterID, beforeID)
+		entry.EntityBefore = newAuditSubnetSnapshot(recipe.SubnetBeforeUpdate)
+		entry.EntityAfter = newAuditSubnetSnapshot(recipe.SubnetAfterUpdate)
+	case "client_class":
+		var beforeID, afterID *int64
+		if recipe.ClientClassBeforeUpdate != nil {
+			beforeID = &recipe.ClientClassBeforeUpdate.ID
+		}
+		if recipe.ClientClassAfterUpdate != nil {
+			afterID = &recipe.ClientClassAfterUpdate.ID
+		}
+		entry.EntityID = firstAuditEntityID(recipe.ClientClassID, afterID, beforeID)
+		entry.EntityBefore = newAuditClientClassSnapshot(recipe.ClientClassBeforeUpdate)
+		entry.EntityAfter = newAuditClientClassSnapshot(recipe.ClientClassAfterUpdate)
+	case "global_parameters":
+		entry.EntityAfter = newAuditGlobalParametersSnapshot(recipe.GlobalParametersUpdates)
+	}
+	return entry
+}
+
+// Records the committed config update in the audit trail. The user is
+// taken from the context. Failing to record the entry doesn't affect the
+// commit result; the error is only logged.
+func (module *ConfigModule) recordAuditEntry(ctx context.Context, update *config.Update[ConfigRecipe], scheduled bool, commitErr error) {
+	db := module.manager.GetDB()
+	if db == nil {
+		return
+	}
+	entry := newAuditEntry(update, commitErr)
+	entry.Scheduled = scheduled
+	if userID, ok := config.GetValueAsInt64(ctx, config.UserContextKey); ok && userID > 0 {
+		user, err := dbmodel.GetUserByID(db, int(userID))
+		if err != nil {
+			log.WithError(err).WithField("user", userID).Warn("Cannot get the user for the audit trail")
+		}
+		if user != nil {
+			entry.UserID = int64(user.ID)
+			entry.UserLogin = user.Login
+			if entry.UserLogin == "" {
+				entry.UserLogin = user.Email
+			}
+		}
+	}
+	if err := dbmodel.AddAuditEntry(db, entry); err != nil {
+		log.WithError(err).WithField("operation", update.Operation).Error("Cannot record the config change in the audit trail")
+	}
+}
+
+// Creates an audit entry describing a lease modification, e.g., lease_delete.
+// Leases are not stored in the Stork database, so the entity ID is zero and
+// the lease is identified by the snapshots. The before and after values are
+// the lease before and after the change, respectively. They are nil when not
+// applicable. The command is the command sent to the daemon and its result.
+// It is nil if the command was not sent.
+func NewLeaseAuditEntry(operation string, daemon *dbmodel.Daemon, before, after any, command *dbmodel.AuditCommand, err error) *dbmodel.AuditEntry {
+	entry := &dbmodel.AuditEntry{
+		Target:     "kea",
+		Operation:  operation,
+		EntityType: "lease",
+		DaemonIDs:  []int64{daemon.ID},
+	}
+	if before != nil {
+		entry.EntityBefore = marshalAuditSnapshot(before)
+	}
+	if after != nil {
+		entry.EntityAfter = marshalAuditSnapshot(after)
+	}
+	if command != nil {
+		entry.Commands = []dbmodel.AuditCommand{*command}
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	return entry
+}
@@ -0,0 +1,272 @@
+package kea
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	keaconfig "isc.org/stork/appcfg/kea"
+	keactrl "isc.org/stork/appctrl/kea"
+	"isc.org/stork/datamodel"
+	"isc.org/stork/server/config"
+	dbmodel "isc.org/stork/server/database/model"
+	storkutil "isc.org/stork/util"
+)
+
+// Test that the host snapshot doesn't include the daemons.
+func TestNewAuditHostSnapshot(t *testing.T) {
+	// Arrange
+	host := &dbmodel.Host{
+		ID: 5,
+		LocalHosts: []dbmodel.LocalHost{
+			{
+				DaemonID: 1,
+				Hostname: "foo.example.org",
+				Daemon: &dbmodel.Daemon{
+					Name: "dhcp4",
+				},
+			},
+		},
+	}
+
+	// Act
+	snapshot := newAuditHostSnapshot(host)
+
+	// Assert
+	var decoded dbmodel.Host
+	require.NoError(t, json.Unmarshal(snapshot, &decoded))
+	require.EqualValues(t, 5, decoded.ID)
+	require.Len(t, decoded.LocalHosts, 1)
+	require.Equal(t, "foo.example.org", decoded.LocalHosts[0].Hostname)
+	require.Nil(t, decoded.LocalHosts[0].Daemon)
+	// The original host must not be modified.
+	require.NotNil(t, host.LocalHosts[0].Daemon)
+	require.Nil(t, newAuditHostSnapshot(nil))
+}
+
+// Test that the shared network snapshot doesn't include the daemons.
+func TestNewAuditSharedNetworkSnapshot(t *testing.T) {
+	// Arrange
+	sharedNetwork := &dbmodel.SharedNetwork{
+		ID:   3,
+		Name: "foo",
+		Subnets: []dbmodel.Subnet{
+			{
+				Prefix: "192.0.2.0/24",
+				LocalSubnets: []*dbmodel.LocalSubnet{
+					{
+						DaemonID: 1,
+						Daemon:   &dbmodel.Daemon{Name: "dhcp4"},
+					},
+				},
+			},
+		},
+		LocalSharedNetworks: []*dbmodel.LocalSharedNetwork{
+			{
+				DaemonID: 1,
+				Daemon:   &dbmodel.Daemon{Name: "dhcp4"},
+			},
+		},
+	}
+
+	// Act
+	snapshot := newAuditSharedNetworkSnapshot(sharedNetwork)
+
+	// Assert
+	var decoded dbmodel.SharedNetwork
+	require.NoError(t, json.Unmarshal(snapshot, &decoded))
+	require.Equal(t, "foo", decoded.Name)
+	require.Len(t, decoded.Subnets, 1)
+	require.Nil(t, decoded.Subnets[0].LocalSubnets[0].Daemon)
+	require.Nil(t, decoded.LocalSharedNetworks[0].Daemon)
+	require.NotNil(t, sharedNetwork.Subnets[0].LocalSubnets[0].Daemon)
+	require.NotNil(t, sharedNetwork.LocalSharedNetworks[0].Daemon)
+}
+
+// Test creating an audit entry for a subnet update.
+func TestNewAuditEntrySubnetUpdate(t *testing.T) {
+	// Arrange
+	update := config.NewUpdate[ConfigRecipe](datamodel.AppTypeKea, "subnet_update", 1, 2)
+	update.Recipe.SubnetBeforeUpdate = &dbmodel.Subnet{ID: 7, Prefix: "192.0.2.0/24"}
+	update.Recipe.SubnetAfterUpdate = &dbmodel.Subnet{ID: 7, Prefix: "192.0.2.0/24", ClientClass: "foo"}
+	update.Recipe.CommandResults = []dbmodel.AuditCommand{
+		{AppID: 1, AppName: "kea@192.0.2.1", Result: 0},
+	}
+
+	// Act
+	entry := newAuditEntry(update, nil)
+
+	// Assert
+	require.Equal(t, "kea", entry.Target)
+	require.Equal(t, "subnet_update", entry.Operation)
+	require.Equal(t, "subnet", entry.EntityType)
+	require.EqualValues(t, 7, entry.EntityID)
+	require.Equal(t, []int64{1, 2}, entry.DaemonIDs)
+	require.Contains(t, string(entry.EntityBefore), "192.0.2.0/24")
+	require.Contains(t, string(entry.EntityAfter), "foo")
+	require.Len(t, entry.Commands, 1)
+	require.Empty(t, entry.Error)
+}
+
+// Test creating an audit entry for a failed shared network deletion.
+func TestNewAuditEntrySharedNetworkDeleteError(t *testing.T) {
+	// Arrange
+	update := config.NewUpdate[ConfigRecipe](datamodel.AppTypeKea, "shared_network_delete", 1)
+	update.Recipe.SharedNetworkID = storkutil.Ptr(int64(3))
+	update.Recipe.SharedNetworkBeforeUpdate = &dbmodel.SharedNetwork{ID: 3, Name: "foo"}
+
+	// Act
+	entry := newAuditEntry(update, errors.New("network4-del failed"))
+
+	// Assert
+	require.Equal(t, "shared_network", entry.EntityType)
+	require.EqualValues(t, 3, entry.EntityID)
+	require.NotNil(t, entry.EntityBefore)
+	require.Nil(t, entry.EntityAfter)
+	require.Equal(t, "network4-del failed", entry.Error)
+}
+
+// Test creating an audit entry for a client class update.
+func TestNewAuditEntryClientClassUpdate(t *testing.T) {
+	// Arrange
+	update := config.NewUpdate[ConfigRecipe](datamodel.AppTypeKea, "client_class_update", 1)
+	update.Recipe.ClientClassBeforeUpdate = &dbmodel.ClientClass{
+		ID:            4,
+		Name:          "foo",
+		Daemon:        &dbmodel.Daemon{Name: "dhcp4"},
+		KeaParameters: &keaconfig.ClientClass{Name: "foo", Test: "member('ALL')"},
+	}
+	update.Recipe.ClientClassAfterUpdate = &dbmodel.ClientClass{
+		ID:            4,
+		Name:          "foo",
+		Daemon:        &dbmodel.Daemon{Name: "dhcp4"},
+		KeaParameters: &keaconfig.ClientClass{Name: "foo", Test: "member('KNOWN')"},
+	}
+
+	// Act
+	entry := newAuditEntry(update, nil)
+
+	// Assert
+	require.Equal(t, "client_class", entry.EntityType)
+	require.EqualValues(t, 4, entry.EntityID)
+	require.Contains(t, string(entry.EntityBefore), "member('ALL')")
+	require.Contains(t, string(entry.EntityAfter), "member('KNOWN')")
+	require.NotContains(t, string(entry.EntityAfter), "dhcp4")
+	// The original class must not be modified.
+	require.NotNil(t, update.Recipe.ClientClassAfterUpdate.Daemon)
+}
+
+// Test that the global parameters changes are recorded in the audit entry.
+func TestNewAuditEntryGlobalParametersUpdate(t *testing.T) {
+	// Arrange
+	update := config.NewUpdate[ConfigRecipe](datamodel.AppTypeKea, "global_parameters_update", 1, 2)
+	update.Recipe.GlobalParametersUpdates = []DaemonGlobalParametersUpdate{
+		{
+			DaemonID:   1,
+			DaemonName: "dhcp4",
+			Changes: []keaconfig.GlobalParameterChange{
+				{Name: "valid-lifetime", Before: float64(3600), After: float64(7200)},
+			},
+		},
+		{
+			DaemonID:   2,
+			DaemonName: "dhcp6",
+		},
+	}
+
+	// Act
+	entry := newAuditEntry(update, nil)
+
+	// Assert
+	require.Equal(t, "global_parameters", entry.EntityType)
+	require.Zero(t, entry.EntityID)
+	require.Nil(t, entry.EntityBefore)
+	require.Contains(t, string(entry.EntityAfter), "valid-lifetime")
+	require.Contains(t, string(entry.EntityAfter), "7200")
+	require.ElementsMatch(t, []int64{1, 2}, entry.DaemonIDs)
+}
+
+// Test creating an audit entry for a lease deletion.
+func TestNewLeaseAuditEntry(t *testing.T) {
+	// Arrange
+	daemon := &dbmodel.Daemon{ID: 5}
+	before := &dbmodel.Lease{}
+	before.IPAddress = "192.0.2.1"
+	command := &dbmodel.AuditCommand{AppID: 1, AppName: "kea@192.0.2.1", Result: 0}
+
+	// Act
+	entry := NewLeaseAuditEntry("lease_delete", daemon, before, nil, command, nil)
+
+	// Assert
+	require.Equal(t, "kea", entry.Target)
+	require.Equal(t, "lease_delete", entry.Operation)
+	require.Equal(t, "lease", entry.EntityType)
+	require.Zero(t, entry.EntityID)
+	require.Equal(t, []int64{5}, entry.DaemonIDs)
+	require.Contains(t, string(entry.EntityBefore), "192.0.2.1")
+	require.Nil(t, entry.EntityAfter)
+	require.Len(t, entry.Commands, 1)
+	require.Empty(t, entry.Error)
+}
+
+// Test creating an audit entry for a lease modification that failed
+// before sending the command.
+func TestNewLeaseAuditEntryError(t *testing.T) {
+	daemon := &dbmodel.Daemon{ID: 5}
+	entry := NewLeaseAuditEntry("lease_add", daemon, nil, map[string]any{"ip-address": "192.0.2.1"}, nil, errors.New("lease4-add failed"))
+	require.Nil(t, entry.EntityBefore)
+	require.NotNil(t, entry.EntityAfter)
+	require.Empty(t, entry.Commands)
+	require.Equal(t, "lease4-add failed", entry.Error)
+}
+
+// Test that the arguments of the config-set command are not stored in the
+// audit trail.
+func TestNewAuditCommandDataConfigSet(t *testing.T) {
+	command := keactrl.NewCommandConfigSet(map[string]any{
+		"Dhcp4": map[string]any{
+			"lease-database": map[string]any{
+				"type":     "mysql",
+				"password": "secret-password",
+			},
+		},
+	}, keactrl.DHCPv4)
+
+	data := newAuditCommandData(command)
+	require.JSONEq(t, `{
+		"command": "config-set",
+		"service": [ "dhcp4" ]
+	}`, string(data))
+}
+
+// Test that the sensitive data are hidden in the arguments of the commands
+// stored in the audit trail.
+func TestNewAuditCommandDataHideSensitiveData(t *testing.T) {
+	command := keactrl.NewCommandBase(keactrl.CommandName("remote-server4-set"), keactrl.DHCPv4).
+		WithArguments(map[string]any{
+			"servers": []any{
+				map[string]any{
+					"server-tag": "foo",
+				},
+			},
+			"remote": map[string]any{
+				"type":     "mysql",
+				"password": "secret-password",
+			},
+		})
+
+	data := newAuditCommandData(command)
+	require.NotContains(t, string(data), "secret-password")
+	require.JSONEq(t, `{
+		"command": "remote-server4-set",
+		"service": [ "dhcp4" ],
+		"arguments": {
+			"servers": [ { "server-tag": "foo" } ],
+			"remote": {
+				"type": "mysql",
+				"password": null
+			}
+		}
+	}`, string(data))
+}
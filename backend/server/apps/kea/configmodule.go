@@ -8,6 +8,7 @@ import (
 	"github.com/pkg/errors"
 	keaconfig "isc.org/stork/appcfg/kea"
 	keactrl "isc.org/stork/appctrl/kea"
+	"isc.org/stork/server/agentcomm"
 	config "isc.org/stork/server/config"
 	dbmodel "isc.org/stork/server/database/model"
 	storkutil "isc.org/stork/util"
@@ -74,6 +75,48 @@ type SubnetConfigRecipeParams struct {
 	SubnetID *int64
 }
 
+// A structure embedded in the ConfigRecipe grouping parameters used
+// in transactions adding, updating and deleting client classes.
+type ClientClassConfigRecipeParams struct {
+	// An instance of the client class before an update. It is typically
+	// fetched at the beginning of the client class update (e.g., when a
+	// user clicks the client class edit button).
+	ClientClassBeforeUpdate *dbmodel.ClientClass
+	// An instance of the client class after it has been added or updated.
+	// This instance is held in the context until it is committed or
+	// scheduled for committing later.
+	ClientClassAfterUpdate *dbmodel.ClientClass
+	// Edited or deleted client class ID.
+	ClientClassID *int64
+	// Indicates that the daemon lacks the libdhcp_class_cmds hook library
+	// and the change is sent in the config-set command.
+	ClientClassConfigSet bool
+}
+
+// Describes an update of the global parameters in a single daemon.
+type DaemonGlobalParametersUpdate struct {
+	DaemonID   int64
+	DaemonName string
+	AppID      int64
+	AppName    string
+	// Global parameters set in the daemon's configuration. They include
+	// the options converted to the Kea format for this daemon.
+	Parameters *keaconfig.GlobalParameters
+	// Parameters whose values differ from the current configuration.
+	Changes []keaconfig.GlobalParameterChange
+}
+
+// A structure embedded in the ConfigRecipe grouping parameters used
+// in transactions updating the global parameters.
+type GlobalParametersConfigRecipeParams struct {
+	// Daemons whose global parameters are updated, along with their
+	// configurations. They are fetched at the beginning of the update.
+	GlobalParametersDaemons []*dbmodel.Daemon
+	// Updates of the global parameters, one per daemon. They are set when
+	// the updated parameters are applied.
+	GlobalParametersUpdates []DaemonGlobalParametersUpdate
+}
+
 // Represents a Kea config change recipe. A recipe is associated with
 // each config update and may comprise several commands sent to different
 // Kea servers. Other data stored in the recipe structure are used in the
@@ -93,6 +136,15 @@ type ConfigRecipe struct {
 	// Embedded structure holding the parameters appropriate for the
 	// subnet management.
 	SubnetConfigRecipeParams
+	// Embedded structure holding the parameters appropriate for the
+	// client class management.
+	ClientClassConfigRecipeParams
+	// Embedded structure holding the parameters appropriate for the
+	// global parameters management.
+	GlobalParametersConfigRecipeParams
+	// Results of the commands sent to the Kea servers. They are collected
+	// during the commit and recorded in the audit trail.
+	CommandResults []dbmodel.AuditCommand `json:"-"`
 }
 
 // A configuration manager module responsible for the Kea configuration.
@@ -132,6 +184,7 @@ func (module *ConfigModule) Commit(ctx context.Context) (context.Context, error)
 		return ctx, errors.Errorf("context lacks state")
 	}
 	for _, pu := range state.Updates {
+		pu.Recipe.CommandResults = nil
 		switch pu.Operation {
 		case "host_add":
 			ctx, err = module.commitHostAdd(ctx)
@@ -151,9 +204,18 @@ func (module *ConfigModule) Commit(ctx context.Context) (context.Context, error)
 			ctx, err = module.commitSubnetUpdate(ctx)
 		case "subnet_delete":
 			ctx, err = module.commitSubnetDelete(ctx)
+		case "client_class_add":
+			ctx, err = module.commitClientClassAdd(ctx)
+		case "client_class_update":
+			ctx, err = module.commitClientClassUpdate(ctx)
+		case "client_class_delete":
+			ctx, err = module.commitClientClassDelete(ctx)
+		case "global_parameters_update":
+			ctx, err = module.commitGlobalParametersUpdate(ctx)
 		default:
 			err = errors.Errorf("unknown operation %s when called Commit()", pu.Operation)
 		}
+		module.recordAuditEntry(ctx, pu, state.Scheduled, err)
 		if err != nil {
 			return ctx, err
 		}
@@ -422,7 +484,8 @@ func (module *ConfigModule) ApplyHostDelete(ctx context.Context, host *dbmodel.H
 	recipe := ConfigRecipe{
 		Commands: commands,
 		HostConfigRecipeParams: HostConfigRecipeParams{
-			HostID: &host.ID,
+			HostBeforeUpdate: host,
+			HostID:           &host.ID,
 		},
 	}
 	if err := state.SetRecipeForUpdate(0, &recipe); err != nil {
@@ -514,6 +577,21 @@ func (module *ConfigModule) commitChanges(ctx context.Context) (context.Context,
 					}
 				}
 			}
+			// Remember the command and its result for the audit trail.
+			commandResult := dbmodel.AuditCommand{
+				AppID:   acs.App.ID,
+				AppName: acs.App.GetName(),
+				Command: newAuditCommandData(acs.Command),
+			}
+			switch {
+			case len(response) > 0:
+				commandResult.Result = response[0].Result
+				commandResult.Text = response[0].Text
+			case err != nil:
+				commandResult.Result = -1
+				commandResult.Text = err.Error()
+			}
+			update.Recipe.CommandResults = append(update.Recipe.CommandResults, commandResult)
 			if err != nil {
 				err = errors.WithMessagef(err, "%s command to %s failed", acs.Command.GetCommand(), acs.App.GetName())
 				return ctx, err
@@ -872,7 +950,8 @@ func (module *ConfigModule) ApplySharedNetworkDelete(ctx context.Context, shared
 	recipe := ConfigRecipe{
 		Commands: commands,
 		SharedNetworkConfigRecipeParams: SharedNetworkConfigRecipeParams{
-			SharedNetworkID: &sharedNetwork.ID,
+			SharedNetworkBeforeUpdate: sharedNetwork,
+			SharedNetworkID:           &sharedNetwork.ID,
 		},
 	}
 	if err := state.SetRecipeForUpdate(0, &recipe); err != nil {
@@ -1335,7 +1414,8 @@ func (module *ConfigModule) ApplySubnetDelete(ctx context.Context, subnet *dbmod
 	recipe := ConfigRecipe{
 		Commands: commands,
 		SubnetConfigRecipeParams: SubnetConfigRecipeParams{
-			SubnetID: &subnet.ID,
+			SubnetBeforeUpdate: subnet,
+			SubnetID:           &subnet.ID,
 		},
 	}
 	if err := state.SetRecipeForUpdate(0, &recipe); err != nil {
@@ -1367,3 +1447,546 @@ func (module *ConfigModule) commitSubnetDelete(ctx context.Context) (context.Con
 	}
 	return ctx, nil
 }
+
+// Checks that the client class is associated with a daemon and an app, and
+// that the daemon's configuration is available.
+func checkClientClassDaemon(class *dbmodel.ClientClass) error {
+	if class.Daemon == nil {
+		return errors.Errorf("client class %s is associated with nil daemon", class.Name)
+	}
+	if class.Daemon.App == nil {
+		return errors.Errorf("client class %s is associated with nil app", class.Name)
+	}
+	if class.Daemon.KeaDaemon == nil || class.Daemon.KeaDaemon.Config == nil {
+		return errors.Errorf("configuration not found for daemon %d", class.DaemonID)
+	}
+	return nil
+}
+
+// Returns the configuration with the client class added, updated or
+// deleted, depending on the transaction operation.
+func withClientClassChange(cfg *keaconfig.Config, operation string, class *dbmodel.ClientClass) (*keaconfig.Config, error) {
+	switch operation {
+	case "client_class_add":
+		return cfg.WithClientClassAdded(class.KeaParameters)
+	case "client_class_update":
+		return cfg.WithClientClassUpdated(class.KeaParameters)
+	case "client_class_delete":
+		return cfg.WithClientClassDeleted(class.Name)
+	default:
+		return nil, errors.Errorf("unsupported client class operation %s", operation)
+	}
+}
+
+// Returns the commands applying the client class change in the daemon and
+// persisting the daemon's configuration. If the daemon has the
+// libdhcp_class_cmds hook library, the change is applied with the specified
+// class command. Otherwise, the daemon's configuration with the change
+// applied is sent in the config-set command, and the returned flag is true.
+func newClientClassCommands(class *dbmodel.ClientClass, operation string, command *keactrl.Command) ([]ConfigCommand, bool, error) {
+	configSet := false
+	if _, _, exists := class.Daemon.KeaDaemon.Config.GetHookLibrary("libdhcp_class_cmds"); !exists {
+		updatedConfig, err := withClientClassChange(class.Daemon.KeaDaemon.Config.Config, operation, class)
+		if err != nil {
+			return nil, false, errors.WithMessagef(err, "problem applying client class %s to the configuration of %s", class.Name, class.Daemon.Name)
+		}
+		command = keactrl.NewCommandConfigSet(updatedConfig.GetSettableConfig(), class.Daemon.Name)
+		configSet = true
+	}
+	return []ConfigCommand{
+		{
+			Command: command,
+			App:     class.Daemon.App,
+		},
+		{
+			Command: keactrl.NewCommandBase(keactrl.ConfigWrite, class.Daemon.Name),
+			App:     class.Daemon.App,
+		},
+	}, configSet, nil
+}
+
+// Verifies that the daemon's configuration has not been modified since
+// it was fetched using the agents of the configuration manager.
+func (module *ConfigModule) verifyConfigHash(ctx context.Context, daemon *dbmodel.Daemon) error {
+	return verifyDaemonConfigHash(ctx, module.manager.GetConnectedAgents(), daemon)
+}
+
+// Fetches the current configuration of the daemon with the config-get
+// command and compares its hash with the hash of the configuration cached
+// in the database. The config-set command is built from the cached
+// configuration, so it must not be sent when the hashes differ. Otherwise,
+// the changes made in the server since the last configuration pull would
+// be lost. The configuration without the hash, e.g., updated by Stork and
+// not pulled yet, is also considered modified.
+func verifyDaemonConfigHash(ctx context.Context, agents agentcomm.ConnectedAgents, daemon *dbmodel.Daemon) error {
+	if daemon.KeaDaemon.ConfigHash == "" {
+		return errors.WithStack(config.NewConfigModifiedError(daemon.Name, daemon.App.GetName()))
+	}
+	command := keactrl.NewCommandBase(keactrl.ConfigGet, daemon.Name)
+	response := []keactrl.HashedResponse{}
+	result, err := agents.ForwardToKeaOverHTTP(ctx, daemon.App, []keactrl.SerializableCommand{command}, &response)
+	if err == nil {
+		err = result.GetFirstError()
+	}
+	if err == nil && len(response) == 0 {
+		err = errors.New("empty response")
+	}
+	if err == nil {
+		err = response[0].GetError()
+	}
+	if err != nil {
+		return errors.WithMessagef(err, "%s command to %s failed", keactrl.ConfigGet, daemon.App.GetName())
+	}
+	if response[0].ArgumentsHash != daemon.KeaDaemon.ConfigHash {
+		return errors.WithStack(config.NewConfigModifiedError(daemon.Name, daemon.App.GetName()))
+	}
+	return nil
+}
+
+// Stores the daemon's configuration with the client class change applied
+// in the database after the change has been sent in the config-set command.
+// The configuration hash is cleared, so the next configuration pull updates
+// the remaining configuration information in the database.
+func (module *ConfigModule) updateClientClassDaemonConfig(update *config.Update[ConfigRecipe], class *dbmodel.ClientClass) error {
+	if !update.Recipe.ClientClassConfigSet {
+		return nil
+	}
+	db := module.manager.GetDB()
+	daemon, err := dbmodel.GetDaemonByID(db, class.DaemonID)
+	if err != nil {
+		return err
+	}
+	if daemon == nil || daemon.KeaDaemon == nil || daemon.KeaDaemon.Config == nil {
+		// The daemon has been deleted in the meantime.
+		return nil
+	}
+	updatedConfig, err := withClientClassChange(daemon.KeaDaemon.Config.Config, update.Operation, class)
+	if err == nil {
+		err = daemon.SetConfig(&dbmodel.KeaConfig{Config: updatedConfig})
+	}
+	if err == nil {
+		err = dbmodel.UpdateDaemon(db, daemon)
+	}
+	return err
+}
+
+// Begins adding a new client class. It initializes transaction state.
+func (module *ConfigModule) BeginClientClassAdd(ctx context.Context) (context.Context, error) {
+	// Create transaction state.
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe]("kea", "client_class_add")
+	ctx = context.WithValue(ctx, config.StateContextKey, *state)
+	return ctx, nil
+}
+
+// Applies new client class. It prepares necessary commands to be sent to
+// Kea upon commit.
+func (module *ConfigModule) ApplyClientClassAdd(ctx context.Context, class *dbmodel.ClientClass) (context.Context, error) {
+	if class.KeaParameters == nil {
+		return ctx, errors.Errorf("applied client class %s has no parameters", class.Name)
+	}
+	if err := checkClientClassDaemon(class); err != nil {
+		return ctx, err
+	}
+	class.KeaParameters.Name = class.Name
+	commands, configSet, err := newClientClassCommands(class, "client_class_add", keactrl.NewCommandClassAdd(class.KeaParameters, class.Daemon.Name))
+	if err != nil {
+		return ctx, err
+	}
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	if !ok || len(state.Updates) == 0 {
+		return ctx, errors.New("context lacks state")
+	}
+	// The daemon is selected by the user in the form, so it is known
+	// only now.
+	state.Updates[0].DaemonIDs = []int64{class.DaemonID}
+	ctx = context.WithValue(ctx, config.StateContextKey, state)
+	recipe := &ConfigRecipe{
+		ClientClassConfigRecipeParams: ClientClassConfigRecipeParams{
+			ClientClassAfterUpdate: class,
+			ClientClassConfigSet:   configSet,
+		},
+		Commands: commands,
+	}
+	return config.SetRecipeForUpdate(ctx, 0, recipe)
+}
+
+// Create the client class in the Kea server.
+func (module *ConfigModule) commitClientClassAdd(ctx context.Context) (context.Context, error) {
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	if !ok {
+		return ctx, errors.New("context lacks state")
+	}
+	for _, update := range state.Updates {
+		if update.Recipe.ClientClassConfigSet && update.Recipe.ClientClassAfterUpdate != nil {
+			if err := module.verifyConfigHash(ctx, update.Recipe.ClientClassAfterUpdate.Daemon); err != nil {
+				return ctx, err
+			}
+		}
+	}
+	var err error
+	ctx, err = module.commitChanges(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	for i, update := range state.Updates {
+		if update.Recipe.ClientClassAfterUpdate == nil {
+			return ctx, errors.New("server logic error: the update.Recipe.ClientClassAfterUpdate cannot be nil when committing the client class creation")
+		}
+		err = dbmodel.AddClientClass(module.manager.GetDB(), update.Recipe.ClientClassAfterUpdate)
+		if err == nil {
+			err = module.updateClientClassDaemonConfig(update, update.Recipe.ClientClassAfterUpdate)
+		}
+		if err != nil {
+			return ctx, errors.WithMessagef(err, "client class has been successfully added to Kea but adding to the Stork database failed")
+		}
+		recipe, err := config.GetRecipeForUpdate[ConfigRecipe](ctx, i)
+		if err != nil {
+			return ctx, err
+		}
+		recipe.ClientClassID = storkutil.Ptr(update.Recipe.ClientClassAfterUpdate.ID)
+		if ctx, err = config.SetRecipeForUpdate(ctx, i, recipe); err != nil {
+			return ctx, err
+		}
+	}
+	return ctx, nil
+}
+
+// Begins a client class update. It fetches the specified client class from
+// the database and stores it in the context state. Then, it locks the daemon
+// owning the class for updates.
+func (module *ConfigModule) BeginClientClassUpdate(ctx context.Context, clientClassID int64) (context.Context, error) {
+	// Try to get the client class to be updated from the database.
+	class, err := dbmodel.GetClientClass(module.manager.GetDB(), clientClassID)
+	if err != nil {
+		// Internal database error.
+		return ctx, err
+	}
+	// Client class does not exist.
+	if class == nil {
+		return ctx, errors.WithStack(config.NewClientClassNotFoundError(clientClassID))
+	}
+	if err = checkClientClassDaemon(class); err != nil {
+		return ctx, err
+	}
+	// Try to lock the configuration.
+	ctx, err = module.manager.Lock(ctx, class.DaemonID)
+	if err != nil {
+		return ctx, errors.WithStack(config.NewLockError())
+	}
+	// Create transaction state.
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe]("kea", "client_class_update", class.DaemonID)
+	recipe := &ConfigRecipe{
+		ClientClassConfigRecipeParams: ClientClassConfigRecipeParams{
+			ClientClassBeforeUpdate: class,
+		},
+	}
+	if err := state.SetRecipeForUpdate(0, recipe); err != nil {
+		return ctx, err
+	}
+	ctx = context.WithValue(ctx, config.StateContextKey, *state)
+	return ctx, nil
+}
+
+// Applies updated client class. It prepares necessary commands to be sent
+// to Kea upon commit. The class-update command identifies the class by name,
+// so the class can't be renamed or moved to another daemon.
+func (module *ConfigModule) ApplyClientClassUpdate(ctx context.Context, class *dbmodel.ClientClass) (context.Context, error) {
+	if class.KeaParameters == nil {
+		return ctx, errors.Errorf("applied client class %s has no parameters", class.Name)
+	}
+	recipe, err := config.GetRecipeForUpdate[ConfigRecipe](ctx, 0)
+	if err != nil {
+		return ctx, err
+	}
+	existingClass := recipe.ClientClassBeforeUpdate
+	if existingClass == nil {
+		return ctx, errors.New("internal server error: client class instance cannot be nil when committing client class update")
+	}
+	if class.Name != existingClass.Name {
+		return ctx, errors.Errorf("client class %s cannot be renamed to %s", existingClass.Name, class.Name)
+	}
+	if class.DaemonID != existingClass.DaemonID {
+		return ctx, errors.Errorf("client class %s cannot be moved to another daemon", class.Name)
+	}
+	if err = checkClientClassDaemon(class); err != nil {
+		return ctx, err
+	}
+	class.KeaParameters.Name = class.Name
+	commands, configSet, err := newClientClassCommands(class, "client_class_update", keactrl.NewCommandClassUpdate(class.KeaParameters, class.Daemon.Name))
+	if err != nil {
+		return ctx, err
+	}
+	// Store the data in the existing recipe.
+	recipe.ClientClassAfterUpdate = class
+	recipe.ClientClassConfigSet = configSet
+	recipe.Commands = commands
+	return config.SetRecipeForUpdate(ctx, 0, recipe)
+}
+
+// Update the client class in the Kea server.
+func (module *ConfigModule) commitClientClassUpdate(ctx context.Context) (context.Context, error) {
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	if !ok {
+		return ctx, errors.New("context lacks state")
+	}
+	for _, update := range state.Updates {
+		if update.Recipe.ClientClassConfigSet && update.Recipe.ClientClassAfterUpdate != nil {
+			if err := module.verifyConfigHash(ctx, update.Recipe.ClientClassAfterUpdate.Daemon); err != nil {
+				return ctx, err
+			}
+		}
+	}
+	var err error
+	ctx, err = module.commitChanges(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	for _, update := range state.Updates {
+		if update.Recipe.ClientClassAfterUpdate == nil {
+			return ctx, errors.New("server logic error: the update.Recipe.ClientClassAfterUpdate cannot be nil when committing the client class update")
+		}
+		err = dbmodel.UpdateClientClass(module.manager.GetDB(), update.Recipe.ClientClassAfterUpdate)
+		if err == nil {
+			err = module.updateClientClassDaemonConfig(update, update.Recipe.ClientClassAfterUpdate)
+		}
+		if err != nil {
+			return ctx, errors.WithMessagef(err, "client class has been successfully updated in Kea but updating it in the Stork database failed")
+		}
+	}
+	return ctx, nil
+}
+
+// Creates requests to delete a client class. It prepares necessary commands
+// to be sent to Kea upon commit.
+func (module *ConfigModule) ApplyClientClassDelete(ctx context.Context, class *dbmodel.ClientClass) (context.Context, error) {
+	if err := checkClientClassDaemon(class); err != nil {
+		return ctx, err
+	}
+	commands, configSet, err := newClientClassCommands(class, "client_class_delete", keactrl.NewCommandClassDel(class.Name, class.Daemon.Name))
+	if err != nil {
+		return ctx, err
+	}
+	// Create transaction state.
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe]("kea", "client_class_delete", class.DaemonID)
+	recipe := ConfigRecipe{
+		Commands: commands,
+		ClientClassConfigRecipeParams: ClientClassConfigRecipeParams{
+			ClientClassBeforeUpdate: class,
+			ClientClassID:           &class.ID,
+			ClientClassConfigSet:    configSet,
+		},
+	}
+	if err := state.SetRecipeForUpdate(0, &recipe); err != nil {
+		return ctx, err
+	}
+	ctx = context.WithValue(ctx, config.StateContextKey, *state)
+	return ctx, nil
+}
+
+// Delete the client class from the Kea server.
+func (module *ConfigModule) commitClientClassDelete(ctx context.Context) (context.Context, error) {
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	if !ok {
+		return ctx, errors.New("context lacks state")
+	}
+	for _, update := range state.Updates {
+		if update.Recipe.ClientClassConfigSet && update.Recipe.ClientClassBeforeUpdate != nil {
+			if err := module.verifyConfigHash(ctx, update.Recipe.ClientClassBeforeUpdate.Daemon); err != nil {
+				return ctx, err
+			}
+		}
+	}
+	var err error
+	ctx, err = module.commitChanges(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	for _, update := range state.Updates {
+		if update.Recipe.ClientClassID == nil {
+			return ctx, errors.New("server logic error: the client class ID cannot be nil when committing client class deletion")
+		}
+		err = dbmodel.DeleteClientClass(module.manager.GetDB(), *update.Recipe.ClientClassID)
+		if err == nil && update.Recipe.ClientClassBeforeUpdate != nil {
+			err = module.updateClientClassDaemonConfig(update, update.Recipe.ClientClassBeforeUpdate)
+		}
+		if err != nil {
+			return ctx, errors.WithMessagef(err, "client class has been successfully deleted in Kea but deleting in the Stork database failed")
+		}
+	}
+	return ctx, nil
+}
+
+// Begins an update of the global parameters in the specified daemons. It
+// fetches the daemons from the database and stores them in the context
+// state. Then, it locks the daemons for updates.
+func (module *ConfigModule) BeginGlobalParametersUpdate(ctx context.Context, daemonIDs []int64) (context.Context, error) {
+	if len(daemonIDs) == 0 {
+		return ctx, errors.New("no daemons specified for the global parameters update")
+	}
+	var daemons []*dbmodel.Daemon
+	for _, daemonID := range daemonIDs {
+		daemon, err := dbmodel.GetDaemonByID(module.manager.GetDB(), daemonID)
+		if err != nil {
+			// Internal database error.
+			return ctx, err
+		}
+		// Daemon does not exist.
+		if daemon == nil {
+			return ctx, errors.WithStack(config.NewDaemonNotFoundError(daemonID))
+		}
+		if daemon.KeaDaemon == nil || daemon.KeaDaemon.Config == nil {
+			return ctx, errors.Errorf("configuration not found for daemon %d", daemonID)
+		}
+		if !daemon.KeaDaemon.Config.IsDHCPv4() && !daemon.KeaDaemon.Config.IsDHCPv6() {
+			return ctx, errors.Errorf("daemon %d is not a DHCP server", daemonID)
+		}
+		daemons = append(daemons, daemon)
+	}
+	// Try to lock configurations.
+	ctx, err := module.manager.Lock(ctx, daemonIDs...)
+	if err != nil {
+		return ctx, errors.WithStack(config.NewLockError())
+	}
+	// Create transaction state.
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe]("kea", "global_parameters_update", daemonIDs...)
+	recipe := &ConfigRecipe{
+		GlobalParametersConfigRecipeParams: GlobalParametersConfigRecipeParams{
+			GlobalParametersDaemons: daemons,
+		},
+	}
+	if err := state.SetRecipeForUpdate(0, recipe); err != nil {
+		return ctx, err
+	}
+	ctx = context.WithValue(ctx, config.StateContextKey, *state)
+	return ctx, nil
+}
+
+// Applies updated global parameters. The options replace the global options
+// in all daemons unless they are nil. It prepares the config-set and
+// config-write commands for the daemons whose configurations change.
+// The daemons in which the parameters are already set are left intact.
+// The changes in each daemon are stored in the recipe, so they can be
+// presented to the user before committing the transaction.
+func (module *ConfigModule) ApplyGlobalParametersUpdate(ctx context.Context, params *keaconfig.GlobalParameters, options []dbmodel.DHCPOption) (context.Context, error) {
+	recipe, err := config.GetRecipeForUpdate[ConfigRecipe](ctx, 0)
+	if err != nil {
+		return ctx, err
+	}
+	if len(recipe.GlobalParametersDaemons) == 0 {
+		return ctx, errors.New("internal server error: daemons cannot be empty when committing global parameters update")
+	}
+	lookup := module.manager.GetDHCPOptionDefinitionLookup()
+
+	var (
+		commands []ConfigCommand
+		updates  []DaemonGlobalParametersUpdate
+	)
+	for _, daemon := range recipe.GlobalParametersDaemons {
+		if daemon.App == nil {
+			return ctx, errors.Errorf("daemon %d is associated with nil app", daemon.ID)
+		}
+		daemonParams := *params
+		if options != nil {
+			daemonParams.OptionData = []keaconfig.SingleOptionData{}
+			for _, option := range options {
+				optionData, err := keaconfig.CreateSingleOptionData(daemon.ID, lookup, option)
+				if err != nil {
+					return ctx, err
+				}
+				daemonParams.OptionData = append(daemonParams.OptionData, *optionData)
+			}
+		}
+		updatedConfig, changes, err := daemon.KeaDaemon.Config.WithGlobalParameters(&daemonParams)
+		if err != nil {
+			return ctx, errors.WithMessagef(err, "problem setting global parameters for %s", daemon.Name)
+		}
+		updates = append(updates, DaemonGlobalParametersUpdate{
+			DaemonID:   daemon.ID,
+			DaemonName: daemon.Name,
+			AppID:      daemon.App.ID,
+			AppName:    daemon.App.Name,
+			Parameters: &daemonParams,
+			Changes:    changes,
+		})
+		if len(changes) == 0 {
+			continue
+		}
+		commands = append(commands,
+			ConfigCommand{
+				Command: keactrl.NewCommandConfigSet(updatedConfig.GetSettableConfig(), daemon.Name),
+				App:     daemon.App,
+			},
+			ConfigCommand{
+				Command: keactrl.NewCommandBase(keactrl.ConfigWrite, daemon.Name),
+				App:     daemon.App,
+			},
+		)
+	}
+	// Store the data in the existing recipe.
+	recipe.GlobalParametersUpdates = updates
+	recipe.Commands = commands
+	return config.SetRecipeForUpdate(ctx, 0, recipe)
+}
+
+// Updates the global parameters in the Kea servers and stores the updated
+// configurations in the database. Before sending any config-set command,
+// it verifies that the configurations of the updated daemons haven't been
+// modified since they were fetched. The configuration hashes are cleared,
+// so the next configuration pull updates the remaining configuration
+// information in the database.
+func (module *ConfigModule) commitGlobalParametersUpdate(ctx context.Context) (context.Context, error) {
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	if !ok {
+		return ctx, errors.New("context lacks state")
+	}
+	for _, update := range state.Updates {
+		for _, daemonUpdate := range update.Recipe.GlobalParametersUpdates {
+			if len(daemonUpdate.Changes) == 0 {
+				continue
+			}
+			for _, daemon := range update.Recipe.GlobalParametersDaemons {
+				if daemon.ID != daemonUpdate.DaemonID {
+					continue
+				}
+				if err := module.verifyConfigHash(ctx, daemon); err != nil {
+					return ctx, err
+				}
+			}
+		}
+	}
+	var err error
+	ctx, err = module.commitChanges(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	db := module.manager.GetDB()
+	for _, update := range state.Updates {
+		for _, daemonUpdate := range update.Recipe.GlobalParametersUpdates {
+			if len(daemonUpdate.Changes) == 0 {
+				continue
+			}
+			if daemonUpdate.Parameters == nil {
+				return ctx, errors.New("server logic error: the global parameters cannot be nil when committing the global parameters update")
+			}
+			daemon, err := dbmodel.GetDaemonByID(db, daemonUpdate.DaemonID)
+			if err != nil {
+				return ctx, errors.WithMessagef(err, "global parameters have been successfully updated in Kea but updating them in the Stork database failed")
+			}
+			if daemon == nil || daemon.KeaDaemon == nil || daemon.KeaDaemon.Config == nil {
+				// The daemon has been deleted in the meantime.
+				continue
+			}
+			updatedConfig, _, err := daemon.KeaDaemon.Config.WithGlobalParameters(daemonUpdate.Parameters)
+			if err == nil {
+				err = daemon.SetConfig(&dbmodel.KeaConfig{Config: updatedConfig})
+			}
+			if err == nil {
+				err = dbmodel.UpdateDaemon(db, daemon)
+			}
+			if err != nil {
+				return ctx, errors.WithMessagef(err, "global parameters have been successfully updated in Kea but updating them in the Stork database failed")
+			}
+		}
+	}
+	return ctx, nil
+}
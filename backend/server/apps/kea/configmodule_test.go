@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -379,6 +380,20 @@ func TestCommitHostAdd(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, newHost)
 	require.Len(t, newHost.LocalHosts, 2)
+
+	// Make sure that the change has been recorded in the audit trail.
+	entries, total, err := dbmodel.GetAuditEntriesByPage(db, 0, 10, nil, dbmodel.SortDirAsc)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Equal(t, "host_add", entries[0].Operation)
+	require.Equal(t, "host", entries[0].EntityType)
+	require.EqualValues(t, 1001, entries[0].EntityID)
+	require.Nil(t, entries[0].EntityBefore)
+	require.NotNil(t, entries[0].EntityAfter)
+	require.Len(t, entries[0].Commands, 2)
+	require.Contains(t, string(entries[0].Commands[0].Command), "reservation-add")
+	require.False(t, entries[0].Scheduled)
+	require.Empty(t, entries[0].Error)
 }
 
 // Test that error is returned when Kea response contains error status code.
@@ -575,6 +590,15 @@ func TestCommitScheduledHostAdd(t *testing.T) {
 	newHost, err := dbmodel.GetHost(db, host.ID)
 	require.NoError(t, err)
 	require.NotNil(t, newHost)
+
+	// Make sure that the audit entry identifies the user and the scheduled
+	// change.
+	entries, _, err := dbmodel.GetAuditEntriesByPage(db, 0, 10, nil, dbmodel.SortDirAsc)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.EqualValues(t, user.ID, entries[0].UserID)
+	require.Equal(t, user.Login, entries[0].UserLogin)
+	require.True(t, entries[0].Scheduled)
 }
 
 // Test the first stage of updating a host. It checks that the host information
@@ -4787,3 +4811,928 @@ func TestCommitSubnetDelete(t *testing.T) {
 	require.NoError(t, err)
 	require.Nil(t, returnedSubnet)
 }
+
+// Returns a test Kea configuration with the client classes and the
+// libdhcp_class_cmds hook library.
+func getTestClientClassesConfig() string {
+	return `{
+		"Dhcp4": {
+			"client-classes": [
+				{
+					"name": "foo",
+					"test": "member('ALL')"
+				},
+				{
+					"name": "bar",
+					"next-server": "192.0.2.1",
+					"option-def": [
+						{
+							"name": "baz",
+							"code": 224,
+							"type": "uint32"
+						}
+					]
+				}
+			],
+			"hooks-libraries": [
+				{
+					"library": "libdhcp_class_cmds.so"
+				}
+			]
+		}
+	}`
+}
+
+// Test the first stage of adding a client class.
+func TestBeginClientClassAdd(t *testing.T) {
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{})
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	ctx, err := module.BeginClientClassAdd(context.Background())
+	require.NoError(t, err)
+
+	// There should be no locks on any daemons.
+	require.Empty(t, manager.locks)
+
+	// Make sure that the transaction state has been created.
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	require.Len(t, state.Updates, 1)
+	require.Equal(t, datamodel.AppTypeKea, state.Updates[0].Target)
+	require.Equal(t, "client_class_add", state.Updates[0].Operation)
+}
+
+// Test that the client class is applied with the config-set command when
+// the daemon lacks the libdhcp_class_cmds hook library, and that the
+// daemon is recorded in the transaction state.
+func TestApplyClientClassAddConfigSet(t *testing.T) {
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{})
+	module := NewConfigModule(manager)
+
+	ctx, err := module.BeginClientClassAdd(context.Background())
+	require.NoError(t, err)
+
+	cfg, err := dbmodel.NewKeaConfigFromJSON(`{"Dhcp4": {"client-classes": [{"name": "bar"}]}}`)
+	require.NoError(t, err)
+
+	class := &dbmodel.ClientClass{
+		DaemonID: 1,
+		Name:     "foo",
+		Daemon: &dbmodel.Daemon{
+			Name: "dhcp4",
+			App:  &dbmodel.App{},
+			KeaDaemon: &dbmodel.KeaDaemon{
+				Config: cfg,
+			},
+		},
+		KeaParameters: &keaconfig.ClientClass{
+			Test: "member('bar')",
+		},
+	}
+	ctx, err = module.ApplyClientClassAdd(ctx, class)
+	require.NoError(t, err)
+
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	require.Len(t, state.Updates, 1)
+	require.Equal(t, []int64{1}, state.Updates[0].DaemonIDs)
+
+	recipe := state.Updates[0].Recipe
+	require.True(t, recipe.ClientClassConfigSet)
+	require.Len(t, recipe.Commands, 2)
+	require.JSONEq(t, `{
+		"command": "config-set",
+		"service": [ "dhcp4" ],
+		"arguments": {
+			"Dhcp4": {
+				"client-classes": [
+					{
+						"name": "bar"
+					},
+					{
+						"name": "foo",
+						"test": "member('bar')"
+					}
+				]
+			}
+		}
+	}`, recipe.Commands[0].Command.Marshal())
+	require.Equal(t, keactrl.ConfigWrite, recipe.Commands[1].Command.GetCommand())
+}
+
+// Test that the client class can't be renamed during the update.
+func TestApplyClientClassUpdateRename(t *testing.T) {
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{})
+	module := NewConfigModule(manager)
+
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe](datamodel.AppTypeKea, "client_class_update", 1)
+	recipe := &ConfigRecipe{
+		ClientClassConfigRecipeParams: ClientClassConfigRecipeParams{
+			ClientClassBeforeUpdate: &dbmodel.ClientClass{
+				ID:       1,
+				DaemonID: 1,
+				Name:     "foo",
+			},
+		},
+	}
+	require.NoError(t, state.SetRecipeForUpdate(0, recipe))
+	ctx := context.WithValue(context.Background(), config.StateContextKey, *state)
+
+	_, err := module.ApplyClientClassUpdate(ctx, &dbmodel.ClientClass{
+		ID:            1,
+		DaemonID:      1,
+		Name:          "bar",
+		KeaParameters: &keaconfig.ClientClass{},
+	})
+	require.ErrorContains(t, err, "cannot be renamed")
+}
+
+// Test committing added client class, i.e. actually sending control commands
+// to Kea and adding the class to the database.
+func TestCommitClientClassAdd(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewKeaFakeAgents()
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:     db,
+		Agents: agents,
+	})
+	module := NewConfigModule(manager)
+
+	server, err := dbmodeltest.NewKeaDHCPv4Server(db)
+	require.NoError(t, err)
+	require.NoError(t, server.Configure(getTestClientClassesConfig()))
+	app, err := server.GetKea()
+	require.NoError(t, err)
+	err = CommitAppIntoDB(db, app, &storktest.FakeEventCenter{}, nil, dbmodel.NewDHCPOptionDefinitionLookup())
+	require.NoError(t, err)
+
+	daemon, err := dbmodel.GetDaemonByID(db, app.Daemons[0].ID)
+	require.NoError(t, err)
+
+	ctx, err := module.BeginClientClassAdd(context.Background())
+	require.NoError(t, err)
+
+	ctx, err = module.ApplyClientClassAdd(ctx, &dbmodel.ClientClass{
+		DaemonID: daemon.ID,
+		Daemon:   daemon,
+		Name:     "qux",
+		KeaParameters: &keaconfig.ClientClass{
+			Test: "member('foo')",
+		},
+	})
+	require.NoError(t, err)
+
+	ctx, err = module.Commit(ctx)
+	require.NoError(t, err)
+
+	// The class-add and config-write commands should have been sent.
+	require.Len(t, agents.RecordedCommands, 2)
+	require.JSONEq(t, `{
+		"command": "class-add",
+		"service": [ "dhcp4" ],
+		"arguments": {
+			"client-classes": [
+				{
+					"name": "qux",
+					"test": "member('foo')"
+				}
+			]
+		}
+	}`, agents.RecordedCommands[0].Marshal())
+	require.Equal(t, keactrl.ConfigWrite, agents.RecordedCommands[1].GetCommand())
+
+	// The class should have been added to the database after the existing
+	// classes.
+	classes, err := dbmodel.GetClientClassesByDaemonID(db, daemon.ID)
+	require.NoError(t, err)
+	require.Len(t, classes, 3)
+	require.Equal(t, "qux", classes[2].Name)
+	require.EqualValues(t, 2, classes[2].Position)
+
+	// The new class ID should be stored in the recipe.
+	recipe, err := config.GetRecipeForUpdate[ConfigRecipe](ctx, 0)
+	require.NoError(t, err)
+	require.NotNil(t, recipe.ClientClassID)
+	require.Equal(t, classes[2].ID, *recipe.ClientClassID)
+
+	// The change should have been recorded in the audit trail.
+	entries, err := dbmodel.GetAuditEntries(db, nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "client_class", entries[0].EntityType)
+	require.Equal(t, classes[2].ID, entries[0].EntityID)
+	require.Equal(t, []int64{daemon.ID}, entries[0].DaemonIDs)
+}
+
+// Returns a function generating the response to the config-get command
+// with the specified configuration. The same response is returned to
+// the config-set and config-write commands.
+func mockConfigGet(configJSON string) func(int, []interface{}) {
+	return func(callNo int, cmdResponses []interface{}) {
+		json := []byte(fmt.Sprintf(`[{"result": 0, "arguments": %s}]`, configJSON))
+		command := keactrl.NewCommandBase(keactrl.ConfigGet, keactrl.DHCPv4)
+		_ = keactrl.UnmarshalResponseList(command, json, cmdResponses[0])
+	}
+}
+
+// Test committing added client class in the daemon lacking the
+// libdhcp_class_cmds hook library. The configuration should be verified
+// with config-get and then sent with config-set.
+func TestCommitClientClassAddConfigSet(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	serverConfig := strings.ReplaceAll(getTestClientClassesConfig(), "libdhcp_class_cmds.so", "libdhcp_subnet_cmds.so")
+
+	agents := agentcommtest.NewKeaFakeAgents(mockConfigGet(serverConfig))
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:     db,
+		Agents: agents,
+	})
+	module := NewConfigModule(manager)
+
+	server, err := dbmodeltest.NewKeaDHCPv4Server(db)
+	require.NoError(t, err)
+	require.NoError(t, server.Configure(serverConfig))
+	app, err := server.GetKea()
+	require.NoError(t, err)
+	app.Daemons[0].KeaDaemon.ConfigHash = keaconfig.NewHasher().Hash([]byte(serverConfig))
+	err = CommitAppIntoDB(db, app, &storktest.FakeEventCenter{}, nil, dbmodel.NewDHCPOptionDefinitionLookup())
+	require.NoError(t, err)
+
+	daemon, err := dbmodel.GetDaemonByID(db, app.Daemons[0].ID)
+	require.NoError(t, err)
+
+	ctx, err := module.BeginClientClassAdd(context.Background())
+	require.NoError(t, err)
+
+	ctx, err = module.ApplyClientClassAdd(ctx, &dbmodel.ClientClass{
+		DaemonID: daemon.ID,
+		Daemon:   daemon,
+		Name:     "qux",
+		KeaParameters: &keaconfig.ClientClass{
+			Test: "member('foo')",
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = module.Commit(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, agents.RecordedCommands, 3)
+	require.Equal(t, keactrl.ConfigGet, agents.RecordedCommands[0].GetCommand())
+	require.Equal(t, keactrl.ConfigSet, agents.RecordedCommands[1].GetCommand())
+	require.Equal(t, keactrl.ConfigWrite, agents.RecordedCommands[2].GetCommand())
+
+	// The class should have been added to the database.
+	classes, err := dbmodel.GetClientClassesByDaemonID(db, daemon.ID)
+	require.NoError(t, err)
+	require.Len(t, classes, 3)
+	require.Equal(t, "qux", classes[2].Name)
+
+	// The daemon's configuration should include the new class.
+	daemon, err = dbmodel.GetDaemonByID(db, daemon.ID)
+	require.NoError(t, err)
+	configClasses := daemon.KeaDaemon.Config.GetClientClasses()
+	require.Len(t, configClasses, 3)
+	require.Equal(t, "qux", configClasses[2].Name)
+	require.Empty(t, daemon.KeaDaemon.ConfigHash)
+}
+
+// Test that the client class is not added with config-set when the
+// configuration in the server differs from the cached configuration.
+func TestCommitClientClassAddConfigSetModified(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	serverConfig := strings.ReplaceAll(getTestClientClassesConfig(), "libdhcp_class_cmds.so", "libdhcp_subnet_cmds.so")
+
+	agents := agentcommtest.NewKeaFakeAgents(mockConfigGet(`{"Dhcp4": {}}`))
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:     db,
+		Agents: agents,
+	})
+	module := NewConfigModule(manager)
+
+	server, err := dbmodeltest.NewKeaDHCPv4Server(db)
+	require.NoError(t, err)
+	require.NoError(t, server.Configure(serverConfig))
+	app, err := server.GetKea()
+	require.NoError(t, err)
+	app.Daemons[0].KeaDaemon.ConfigHash = keaconfig.NewHasher().Hash([]byte(serverConfig))
+	err = CommitAppIntoDB(db, app, &storktest.FakeEventCenter{}, nil, dbmodel.NewDHCPOptionDefinitionLookup())
+	require.NoError(t, err)
+
+	daemon, err := dbmodel.GetDaemonByID(db, app.Daemons[0].ID)
+	require.NoError(t, err)
+
+	ctx, err := module.BeginClientClassAdd(context.Background())
+	require.NoError(t, err)
+
+	ctx, err = module.ApplyClientClassAdd(ctx, &dbmodel.ClientClass{
+		DaemonID:      daemon.ID,
+		Daemon:        daemon,
+		Name:          "qux",
+		KeaParameters: &keaconfig.ClientClass{},
+	})
+	require.NoError(t, err)
+
+	_, err = module.Commit(ctx)
+	var modifiedErr *config.ConfigModifiedError
+	require.ErrorAs(t, err, &modifiedErr)
+
+	// Only the config-get command should be sent.
+	require.Len(t, agents.RecordedCommands, 1)
+
+	classes, err := dbmodel.GetClientClassesByDaemonID(db, daemon.ID)
+	require.NoError(t, err)
+	require.Len(t, classes, 2)
+}
+
+// Test the first stage of updating a client class and committing the
+// update.
+func TestCommitClientClassUpdate(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewKeaFakeAgents()
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:     db,
+		Agents: agents,
+	})
+	module := NewConfigModule(manager)
+
+	server, err := dbmodeltest.NewKeaDHCPv4Server(db)
+	require.NoError(t, err)
+	require.NoError(t, server.Configure(getTestClientClassesConfig()))
+	app, err := server.GetKea()
+	require.NoError(t, err)
+	err = CommitAppIntoDB(db, app, &storktest.FakeEventCenter{}, nil, dbmodel.NewDHCPOptionDefinitionLookup())
+	require.NoError(t, err)
+
+	classes, err := dbmodel.GetClientClassesByDaemonID(db, app.Daemons[0].ID)
+	require.NoError(t, err)
+	require.Len(t, classes, 2)
+
+	ctx, err := module.BeginClientClassUpdate(context.Background(), classes[1].ID)
+	require.NoError(t, err)
+
+	// The daemon should be locked.
+	require.Contains(t, manager.locks, app.Daemons[0].ID)
+
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	require.Equal(t, "client_class_update", state.Updates[0].Operation)
+	class := state.Updates[0].Recipe.ClientClassBeforeUpdate
+	require.NotNil(t, class)
+	require.Equal(t, "bar", class.Name)
+
+	// Modify a copy of the class.
+	updatedParameters := *class.KeaParameters
+	updatedParameters.NextServer = "192.0.2.2"
+	updatedClass := *class
+	updatedClass.KeaParameters = &updatedParameters
+
+	ctx, err = module.ApplyClientClassUpdate(ctx, &updatedClass)
+	require.NoError(t, err)
+
+	_, err = module.Commit(ctx)
+	require.NoError(t, err)
+
+	// The unrecognized parameters should be sent back to Kea.
+	require.Len(t, agents.RecordedCommands, 2)
+	require.JSONEq(t, `{
+		"command": "class-update",
+		"service": [ "dhcp4" ],
+		"arguments": {
+			"client-classes": [
+				{
+					"name": "bar",
+					"next-server": "192.0.2.2",
+					"option-def": [
+						{
+							"name": "baz",
+							"code": 224,
+							"type": "uint32"
+						}
+					]
+				}
+			]
+		}
+	}`, agents.RecordedCommands[0].Marshal())
+
+	returned, err := dbmodel.GetClientClass(db, classes[1].ID)
+	require.NoError(t, err)
+	require.Equal(t, "192.0.2.2", returned.KeaParameters.NextServer)
+}
+
+// Test that updating a non-existing client class fails.
+func TestBeginClientClassUpdateNotFound(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB: db,
+	})
+	module := NewConfigModule(manager)
+
+	_, err := module.BeginClientClassUpdate(context.Background(), 123)
+	var notFoundErr *config.ClientClassNotFoundError
+	require.ErrorAs(t, err, &notFoundErr)
+	require.Empty(t, manager.locks)
+}
+
+// Test committing the client class deletion.
+func TestCommitClientClassDelete(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewKeaFakeAgents()
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:     db,
+		Agents: agents,
+	})
+	module := NewConfigModule(manager)
+
+	server, err := dbmodeltest.NewKeaDHCPv4Server(db)
+	require.NoError(t, err)
+	require.NoError(t, server.Configure(getTestClientClassesConfig()))
+	app, err := server.GetKea()
+	require.NoError(t, err)
+	err = CommitAppIntoDB(db, app, &storktest.FakeEventCenter{}, nil, dbmodel.NewDHCPOptionDefinitionLookup())
+	require.NoError(t, err)
+
+	classes, err := dbmodel.GetClientClassesByDaemonID(db, app.Daemons[0].ID)
+	require.NoError(t, err)
+	class, err := dbmodel.GetClientClass(db, classes[0].ID)
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), config.DaemonsContextKey, []int64{class.DaemonID})
+	ctx, err = module.ApplyClientClassDelete(ctx, class)
+	require.NoError(t, err)
+
+	_, err = module.Commit(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, agents.RecordedCommands, 2)
+	require.JSONEq(t, `{
+		"command": "class-del",
+		"service": [ "dhcp4" ],
+		"arguments": {
+			"name": "foo"
+		}
+	}`, agents.RecordedCommands[0].Marshal())
+
+	classes, err = dbmodel.GetClientClassesByDaemonID(db, app.Daemons[0].ID)
+	require.NoError(t, err)
+	require.Len(t, classes, 1)
+	require.Equal(t, "bar", classes[0].Name)
+}
+
+// Returns test daemons with the DHCPv4 and DHCPv6 configurations for
+// the global parameters update.
+func getTestGlobalParametersDaemons(t *testing.T) []*dbmodel.Daemon {
+	config4, err := dbmodel.NewKeaConfigFromJSON(`{
+		"Dhcp4": {
+			"valid-lifetime": 3600,
+			"option-data": [
+				{
+					"name": "domain-name-servers",
+					"data": "192.0.2.1"
+				}
+			]
+		}
+	}`)
+	require.NoError(t, err)
+	config6, err := dbmodel.NewKeaConfigFromJSON(`{
+		"Dhcp6": {
+			"valid-lifetime": 7200
+		}
+	}`)
+	require.NoError(t, err)
+
+	app := &dbmodel.App{
+		ID:   1,
+		Name: "kea@192.0.2.1",
+		AccessPoints: []*dbmodel.AccessPoint{
+			{
+				Type:    dbmodel.AccessPointControl,
+				Address: "192.0.2.1",
+				Port:    1234,
+			},
+		},
+	}
+	return []*dbmodel.Daemon{
+		{
+			ID:   1,
+			Name: "dhcp4",
+			App:  app,
+			KeaDaemon: &dbmodel.KeaDaemon{
+				Config: config4,
+			},
+		},
+		{
+			ID:   2,
+			Name: "dhcp6",
+			App:  app,
+			KeaDaemon: &dbmodel.KeaDaemon{
+				Config: config6,
+			},
+		},
+	}
+}
+
+// Test that the global parameters update requires daemons.
+func TestBeginGlobalParametersUpdateNoDaemons(t *testing.T) {
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{})
+	module := NewConfigModule(manager)
+
+	_, err := module.BeginGlobalParametersUpdate(context.Background(), []int64{})
+	require.Error(t, err)
+	require.Empty(t, manager.locks)
+}
+
+// Test applying the global parameters. The config-set commands should only
+// be sent to the daemons whose configurations change.
+func TestApplyGlobalParametersUpdate(t *testing.T) {
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe](datamodel.AppTypeKea, "global_parameters_update", 1, 2)
+	err := state.SetRecipeForUpdate(0, &ConfigRecipe{
+		GlobalParametersConfigRecipeParams: GlobalParametersConfigRecipeParams{
+			GlobalParametersDaemons: getTestGlobalParametersDaemons(t),
+		},
+	})
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), config.StateContextKey, *state)
+
+	ctx, err = module.ApplyGlobalParametersUpdate(ctx, &keaconfig.GlobalParameters{
+		ValidLifetimeParameters: keaconfig.ValidLifetimeParameters{
+			ValidLifetime: storkutil.Ptr(int64(7200)),
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	recipe, err := config.GetRecipeForUpdate[ConfigRecipe](ctx, 0)
+	require.NoError(t, err)
+
+	// The DHCPv4 server's valid lifetime has changed. The DHCPv6 server
+	// already has the specified lifetime.
+	require.Len(t, recipe.GlobalParametersUpdates, 2)
+	require.EqualValues(t, 1, recipe.GlobalParametersUpdates[0].DaemonID)
+	require.Equal(t, "kea@192.0.2.1", recipe.GlobalParametersUpdates[0].AppName)
+	require.Equal(t, []keaconfig.GlobalParameterChange{
+		{Name: "valid-lifetime", Before: float64(3600), After: float64(7200)},
+	}, recipe.GlobalParametersUpdates[0].Changes)
+	require.EqualValues(t, 2, recipe.GlobalParametersUpdates[1].DaemonID)
+	require.Empty(t, recipe.GlobalParametersUpdates[1].Changes)
+
+	require.Len(t, recipe.Commands, 2)
+	require.JSONEq(t, `{
+		"command": "config-set",
+		"service": [ "dhcp4" ],
+		"arguments": {
+			"Dhcp4": {
+				"valid-lifetime": 7200,
+				"option-data": [
+					{
+						"name": "domain-name-servers",
+						"data": "192.0.2.1"
+					}
+				]
+			}
+		}
+	}`, recipe.Commands[0].Command.Marshal())
+	require.JSONEq(t, `{
+		"command": "config-write",
+		"service": [ "dhcp4" ]
+	}`, recipe.Commands[1].Command.Marshal())
+}
+
+// Test that the global options are converted to the Kea format and
+// replace the existing options in all daemons.
+func TestApplyGlobalParametersUpdateOptions(t *testing.T) {
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+
+	daemons := getTestGlobalParametersDaemons(t)
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe](datamodel.AppTypeKea, "global_parameters_update", 1)
+	err := state.SetRecipeForUpdate(0, &ConfigRecipe{
+		GlobalParametersConfigRecipeParams: GlobalParametersConfigRecipeParams{
+			GlobalParametersDaemons: daemons[:1],
+		},
+	})
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), config.StateContextKey, *state)
+
+	ctx, err = module.ApplyGlobalParametersUpdate(ctx, &keaconfig.GlobalParameters{}, []dbmodel.DHCPOption{
+		{
+			Code:     6,
+			Space:    "dhcp4",
+			Universe: storkutil.IPv4,
+			Fields: []dbmodel.DHCPOptionField{
+				{
+					FieldType: "ipv4-address",
+					Values:    []any{"192.0.2.2"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	recipe, err := config.GetRecipeForUpdate[ConfigRecipe](ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, recipe.GlobalParametersUpdates, 1)
+	require.Len(t, recipe.GlobalParametersUpdates[0].Changes, 1)
+	require.Equal(t, "option-data", recipe.GlobalParametersUpdates[0].Changes[0].Name)
+
+	require.Len(t, recipe.Commands, 2)
+	require.JSONEq(t, `{
+		"command": "config-set",
+		"service": [ "dhcp4" ],
+		"arguments": {
+			"Dhcp4": {
+				"valid-lifetime": 3600,
+				"option-data": [
+					{
+						"code": 6,
+						"csv-format": true,
+						"data": "192.0.2.2",
+						"space": "dhcp4"
+					}
+				]
+			}
+		}
+	}`, recipe.Commands[0].Command.Marshal())
+}
+
+// Test that the preferred lifetime can't be set for the DHCPv4 server.
+func TestApplyGlobalParametersUpdatePreferredLifetime(t *testing.T) {
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{})
+	module := NewConfigModule(manager)
+
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe](datamodel.AppTypeKea, "global_parameters_update", 1, 2)
+	err := state.SetRecipeForUpdate(0, &ConfigRecipe{
+		GlobalParametersConfigRecipeParams: GlobalParametersConfigRecipeParams{
+			GlobalParametersDaemons: getTestGlobalParametersDaemons(t),
+		},
+	})
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), config.StateContextKey, *state)
+
+	_, err = module.ApplyGlobalParametersUpdate(ctx, &keaconfig.GlobalParameters{
+		PreferredLifetimeParameters: keaconfig.PreferredLifetimeParameters{
+			PreferredLifetime: storkutil.Ptr(int64(3000)),
+		},
+	}, nil)
+	require.ErrorContains(t, err, "dhcp4")
+}
+
+// Test DHCPv4 server configuration for the global parameters update.
+const testGlobalParametersConfig = `{
+	"Dhcp4": {
+		"valid-lifetime": 3600,
+		"expired-leases-processing": {
+			"max-reclaim-leases": 100
+		}
+	}
+}`
+
+// Test updating the global parameters in multiple daemons and storing the
+// updated configurations in the database.
+func TestCommitGlobalParametersUpdate(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewKeaFakeAgents(mockConfigGet(testGlobalParametersConfig))
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    agents,
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+
+	var daemonIDs []int64
+	for i := 0; i < 2; i++ {
+		server, err := dbmodeltest.NewKeaDHCPv4Server(db)
+		require.NoError(t, err)
+		require.NoError(t, server.Configure(testGlobalParametersConfig))
+		app, err := server.GetKea()
+		require.NoError(t, err)
+		app.Daemons[0].KeaDaemon.ConfigHash = keaconfig.NewHasher().Hash([]byte(testGlobalParametersConfig))
+		err = CommitAppIntoDB(db, app, &storktest.FakeEventCenter{}, nil, dbmodel.NewDHCPOptionDefinitionLookup())
+		require.NoError(t, err)
+		daemonIDs = append(daemonIDs, app.Daemons[0].ID)
+	}
+
+	ctx, err := module.BeginGlobalParametersUpdate(context.Background(), daemonIDs)
+	require.NoError(t, err)
+
+	// The daemons should be locked.
+	require.Contains(t, manager.locks, daemonIDs[0])
+	require.Contains(t, manager.locks, daemonIDs[1])
+
+	ctx, err = module.ApplyGlobalParametersUpdate(ctx, &keaconfig.GlobalParameters{
+		ValidLifetimeParameters: keaconfig.ValidLifetimeParameters{
+			ValidLifetime: storkutil.Ptr(int64(7200)),
+		},
+		ExpiredLeasesProcessing: &keaconfig.ExpiredLeasesProcessing{
+			MaxReclaimTime: storkutil.Ptr(int64(250)),
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	_, err = module.Commit(ctx)
+	require.NoError(t, err)
+
+	// The configurations should be verified with the config-get commands
+	// before the config-set and config-write commands are sent to both servers.
+	require.Len(t, agents.RecordedCommands, 6)
+	require.Equal(t, keactrl.ConfigGet, agents.RecordedCommands[0].GetCommand())
+	require.Equal(t, keactrl.ConfigGet, agents.RecordedCommands[1].GetCommand())
+	for i, command := range agents.RecordedCommands[2:] {
+		if i%2 == 0 {
+			require.Equal(t, keactrl.ConfigSet, command.GetCommand())
+		} else {
+			require.Equal(t, keactrl.ConfigWrite, command.GetCommand())
+		}
+	}
+
+	// The configurations should be updated in the database.
+	for _, daemonID := range daemonIDs {
+		daemon, err := dbmodel.GetDaemonByID(db, daemonID)
+		require.NoError(t, err)
+		require.EqualValues(t, 7200, *daemon.KeaDaemon.Config.GetValidLifetimeParameters().ValidLifetime)
+		require.EqualValues(t, 250, *daemon.KeaDaemon.Config.GetExpiredLeasesProcessing().MaxReclaimTime)
+		require.EqualValues(t, 100, *daemon.KeaDaemon.Config.GetExpiredLeasesProcessing().MaxReclaimLeases)
+		require.Empty(t, daemon.KeaDaemon.ConfigHash)
+	}
+}
+
+// Test that the global parameters are not updated when the configuration
+// in the server differs from the configuration cached in the database.
+func TestCommitGlobalParametersUpdateConfigModified(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	// The server returns the configuration modified outside of Stork.
+	agents := agentcommtest.NewKeaFakeAgents(mockConfigGet(`{"Dhcp4": {"valid-lifetime": 4000}}`))
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    agents,
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+
+	server, err := dbmodeltest.NewKeaDHCPv4Server(db)
+	require.NoError(t, err)
+	require.NoError(t, server.Configure(testGlobalParametersConfig))
+	app, err := server.GetKea()
+	require.NoError(t, err)
+	app.Daemons[0].KeaDaemon.ConfigHash = keaconfig.NewHasher().Hash([]byte(testGlobalParametersConfig))
+	err = CommitAppIntoDB(db, app, &storktest.FakeEventCenter{}, nil, dbmodel.NewDHCPOptionDefinitionLookup())
+	require.NoError(t, err)
+
+	ctx, err := module.BeginGlobalParametersUpdate(context.Background(), []int64{app.Daemons[0].ID})
+	require.NoError(t, err)
+	ctx, err = module.ApplyGlobalParametersUpdate(ctx, &keaconfig.GlobalParameters{
+		ValidLifetimeParameters: keaconfig.ValidLifetimeParameters{
+			ValidLifetime: storkutil.Ptr(int64(7200)),
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	_, err = module.Commit(ctx)
+	var modifiedErr *config.ConfigModifiedError
+	require.ErrorAs(t, err, &modifiedErr)
+
+	// Only the config-get command should be sent.
+	require.Len(t, agents.RecordedCommands, 1)
+	require.Equal(t, keactrl.ConfigGet, agents.RecordedCommands[0].GetCommand())
+
+	// The configuration in the database should be unchanged.
+	daemon, err := dbmodel.GetDaemonByID(db, app.Daemons[0].ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 3600, *daemon.KeaDaemon.Config.GetValidLifetimeParameters().ValidLifetime)
+}
+
+// Test that the global parameters are not updated when the configuration
+// cached in the database has no hash, e.g., because it has been updated
+// by Stork and not pulled from the server yet.
+func TestCommitGlobalParametersUpdateNoConfigHash(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewKeaFakeAgents(mockConfigGet(testGlobalParametersConfig))
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    agents,
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+
+	server, err := dbmodeltest.NewKeaDHCPv4Server(db)
+	require.NoError(t, err)
+	require.NoError(t, server.Configure(testGlobalParametersConfig))
+	app, err := server.GetKea()
+	require.NoError(t, err)
+	err = CommitAppIntoDB(db, app, &storktest.FakeEventCenter{}, nil, dbmodel.NewDHCPOptionDefinitionLookup())
+	require.NoError(t, err)
+
+	ctx, err := module.BeginGlobalParametersUpdate(context.Background(), []int64{app.Daemons[0].ID})
+	require.NoError(t, err)
+	ctx, err = module.ApplyGlobalParametersUpdate(ctx, &keaconfig.GlobalParameters{
+		ValidLifetimeParameters: keaconfig.ValidLifetimeParameters{
+			ValidLifetime: storkutil.Ptr(int64(7200)),
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	_, err = module.Commit(ctx)
+	var modifiedErr *config.ConfigModifiedError
+	require.ErrorAs(t, err, &modifiedErr)
+	require.Empty(t, agents.RecordedCommands)
+}
+
+// Test that the secrets sent in the config-set command are not stored in
+// the audit trail.
+func TestCommitGlobalParametersUpdateAuditHidesSecrets(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	configJSON := `{
+		"Dhcp4": {
+			"valid-lifetime": 3600,
+			"lease-database": {
+				"type": "mysql",
+				"name": "kea",
+				"user": "kea",
+				"password": "secret-password"
+			}
+		}
+	}`
+	agents := agentcommtest.NewKeaFakeAgents(mockConfigGet(configJSON))
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    agents,
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+
+	server, err := dbmodeltest.NewKeaDHCPv4Server(db)
+	require.NoError(t, err)
+	require.NoError(t, server.Configure(configJSON))
+	app, err := server.GetKea()
+	require.NoError(t, err)
+	app.Daemons[0].KeaDaemon.ConfigHash = keaconfig.NewHasher().Hash([]byte(configJSON))
+	err = CommitAppIntoDB(db, app, &storktest.FakeEventCenter{}, nil, dbmodel.NewDHCPOptionDefinitionLookup())
+	require.NoError(t, err)
+
+	ctx, err := module.BeginGlobalParametersUpdate(context.Background(), []int64{app.Daemons[0].ID})
+	require.NoError(t, err)
+	ctx, err = module.ApplyGlobalParametersUpdate(ctx, &keaconfig.GlobalParameters{
+		ValidLifetimeParameters: keaconfig.ValidLifetimeParameters{
+			ValidLifetime: storkutil.Ptr(int64(7200)),
+		},
+	}, nil)
+	require.NoError(t, err)
+	_, err = module.Commit(ctx)
+	require.NoError(t, err)
+
+	// The password should be sent to Kea.
+	require.Len(t, agents.RecordedCommands, 3)
+	require.Contains(t, agents.RecordedCommands[1].Marshal(), "secret-password")
+
+	// The password should not be stored in the audit trail.
+	entries, err := dbmodel.GetAuditEntries(db, nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.NotEmpty(t, entries[0].Commands)
+	data, err := json.Marshal(entries)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "secret-password")
+}
+
+// Test that updating the global parameters of a non-existing daemon fails.
+func TestBeginGlobalParametersUpdateNotFound(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB: db,
+	})
+	module := NewConfigModule(manager)
+
+	_, err := module.BeginGlobalParametersUpdate(context.Background(), []int64{1234})
+	var notFoundErr *config.DaemonNotFoundError
+	require.ErrorAs(t, err, &notFoundErr)
+	require.Empty(t, manager.locks)
+}
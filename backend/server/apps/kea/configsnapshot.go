@@ -0,0 +1,169 @@
+package kea
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	keactrl "isc.org/stork/appctrl/kea"
+	"isc.org/stork/server/agentcomm"
+	config "isc.org/stork/server/config"
+	dbops "isc.org/stork/server/database"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Maximum number of the recent audit entries examined to find the change
+// that caused a new configuration of a daemon.
+const configChangeAuditEntriesLimit = 20
+
+// Finds the most recent successful audit entry describing a configuration
+// change of the daemon made after the previous snapshot. The lease changes
+// are skipped because they don't modify the configuration. It returns nil
+// if the configuration was presumably changed outside of Stork.
+func findConfigChangeAuditEntry(dbi pg.DBI, daemonID int64, previous *dbmodel.KeaConfigSnapshot) (*dbmodel.AuditEntry, error) {
+	filters := &dbmodel.AuditEntriesByPageFilters{
+		DaemonID: &daemonID,
+	}
+	if previous != nil {
+		filters.From = &previous.CreatedAt
+	}
+	entries, _, err := dbmodel.GetAuditEntriesByPage(dbi, 0, configChangeAuditEntriesLimit, filters, dbmodel.SortDirDesc)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].Error != "" || entries[i].EntityType == "lease" {
+			continue
+		}
+		return &entries[i], nil
+	}
+	return nil, nil
+}
+
+// Stores the daemon's configuration in the history if it differs from the
+// most recent snapshot. The snapshot is associated with the audit entry
+// describing the change made through Stork, if any.
+func commitConfigSnapshot(tx *pg.Tx, daemon *dbmodel.Daemon) error {
+	if daemon.ID == 0 || daemon.KeaDaemon == nil || daemon.KeaDaemon.Config == nil {
+		return nil
+	}
+	latest, err := dbmodel.GetLatestKeaConfigSnapshot(tx, daemon.ID)
+	if err != nil {
+		return err
+	}
+	if latest != nil && latest.ConfigHash == daemon.KeaDaemon.ConfigHash {
+		return nil
+	}
+	snapshot := &dbmodel.KeaConfigSnapshot{
+		DaemonID:   daemon.ID,
+		Config:     daemon.KeaDaemon.Config,
+		ConfigHash: daemon.KeaDaemon.ConfigHash,
+		Source:     dbmodel.KeaConfigSnapshotSourceFetched,
+	}
+	// The first snapshot holds the configuration found when the daemon
+	// was added to Stork.
+	if latest != nil {
+		entry, err := findConfigChangeAuditEntry(tx, daemon.ID, latest)
+		if err != nil {
+			return err
+		}
+		if entry != nil {
+			snapshot.UserID = entry.UserID
+			snapshot.UserLogin = entry.UserLogin
+			snapshot.AuditEntryID = entry.ID
+			snapshot.Operation = entry.Operation
+		}
+	}
+	return dbmodel.AddKeaConfigSnapshot(tx, snapshot)
+}
+
+// Sends the configuration stored in the snapshot to the daemon using the
+// config-set command and persists it with the config-write command. The
+// daemon's configuration is locked for the time of the rollback, so it
+// cannot be edited concurrently by another user. The rollback is rejected
+// if the daemon's configuration has been modified since the daemon was
+// fetched from the database. The rollback is recorded in the audit trail
+// and, if it succeeds, as a new snapshot in the configuration history. It
+// returns the new snapshot. Only the Kea DHCP servers' configurations can
+// be rolled back.
+func RollbackConfig(ctx context.Context, db *dbops.PgDB, agents agentcomm.ConnectedAgents, locker config.ManagerLocker, daemon *dbmodel.Daemon, snapshot *dbmodel.KeaConfigSnapshot, user *dbmodel.SystemUser) (*dbmodel.KeaConfigSnapshot, error) {
+	if daemon.App == nil {
+		return nil, errors.Errorf("daemon %d lacks the app", daemon.ID)
+	}
+	if daemon.KeaDaemon == nil {
+		return nil, errors.Errorf("daemon %d is not a Kea daemon", daemon.ID)
+	}
+	if snapshot.DaemonID != daemon.ID {
+		return nil, errors.Errorf("configuration snapshot %d does not belong to daemon %d", snapshot.ID, daemon.ID)
+	}
+	if snapshot.Config == nil {
+		return nil, errors.Errorf("configuration snapshot %d is empty", snapshot.ID)
+	}
+	settable := snapshot.Config.GetSettableConfig()
+	if settable == nil {
+		return nil, errors.Errorf("configuration of %s cannot be rolled back; only the Kea DHCP servers are supported", daemon.Name)
+	}
+	ctx, err := locker.Lock(ctx, daemon.ID)
+	if err != nil {
+		return nil, errors.WithStack(config.NewLockError())
+	}
+	defer locker.Unlock(ctx)
+	if err := verifyDaemonConfigHash(ctx, agents, daemon); err != nil {
+		return nil, err
+	}
+	entry := &dbmodel.AuditEntry{
+		Target:     "kea",
+		Operation:  "config_rollback",
+		EntityType: "config",
+		EntityID:   snapshot.ID,
+		DaemonIDs:  []int64{daemon.ID},
+	}
+	if user != nil {
+		entry.UserID = int64(user.ID)
+		entry.UserLogin = user.Login
+		if entry.UserLogin == "" {
+			entry.UserLogin = user.Email
+		}
+	}
+	daemonName := keactrl.DaemonName(daemon.Name)
+	var rollbackErr error
+	for _, command := range []*keactrl.Command{
+		keactrl.NewCommandConfigSet(settable, daemonName),
+		keactrl.NewCommandBase(keactrl.ConfigWrite, daemonName),
+	} {
+		var result *dbmodel.AuditCommand
+		result, rollbackErr = sendDaemonCommand(agents, daemon, command)
+		if result != nil {
+			entry.Commands = append(entry.Commands, *result)
+		}
+		if rollbackErr != nil {
+			break
+		}
+	}
+	if rollbackErr != nil {
+		entry.Error = rollbackErr.Error()
+	}
+	if err := dbmodel.AddAuditEntry(db, entry); err != nil {
+		log.WithError(err).WithField("operation", entry.Operation).Error("Cannot record the configuration rollback in the audit trail")
+	}
+	if rollbackErr != nil {
+		return nil, rollbackErr
+	}
+	restored := &dbmodel.KeaConfigSnapshot{
+		DaemonID:     daemon.ID,
+		Config:       snapshot.Config,
+		ConfigHash:   snapshot.ConfigHash,
+		Source:       dbmodel.KeaConfigSnapshotSourceRollback,
+		UserID:       entry.UserID,
+		UserLogin:    entry.UserLogin,
+		AuditEntryID: entry.ID,
+		Operation:    entry.Operation,
+		Description:  fmt.Sprintf("rolled back to configuration snapshot %d", snapshot.ID),
+	}
+	if err := dbmodel.AddKeaConfigSnapshot(db, restored); err != nil {
+		return nil, errors.WithMessage(err, "configuration was rolled back but it could not be stored in the history")
+	}
+	return restored, nil
+}
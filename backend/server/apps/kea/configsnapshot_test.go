@@ -0,0 +1,307 @@
+package kea
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	require "github.com/stretchr/testify/require"
+
+	keaconfig "isc.org/stork/appcfg/kea"
+	keactrl "isc.org/stork/appctrl/kea"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	appstest "isc.org/stork/server/apps/test"
+	"isc.org/stork/server/config"
+	dbops "isc.org/stork/server/database"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	storktest "isc.org/stork/server/test/dbmodel"
+)
+
+// Adds a Kea app with a DHCPv4 server having the specified configuration
+// to the database.
+func addTestConfigSnapshotApp(t *testing.T, db *dbops.PgDB, config string, configHash string) *dbmodel.App {
+	machine := &dbmodel.Machine{
+		Address:   "localhost",
+		AgentPort: 8080,
+	}
+	err := dbmodel.AddMachine(db, machine)
+	require.NoError(t, err)
+
+	var accessPoints []*dbmodel.AccessPoint
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "localhost", "", 8000, false)
+	daemon := dbmodel.NewKeaDaemon(dbmodel.DaemonNameDHCPv4, true)
+	err = daemon.SetConfigFromJSON(config)
+	require.NoError(t, err)
+	daemon.KeaDaemon.ConfigHash = configHash
+	app := &dbmodel.App{
+		MachineID:    machine.ID,
+		Machine:      machine,
+		Type:         dbmodel.AppTypeKea,
+		Active:       true,
+		AccessPoints: accessPoints,
+		Daemons:      []*dbmodel.Daemon{daemon},
+	}
+	err = CommitAppIntoDB(db, app, &storktest.FakeEventCenter{}, nil, dbmodel.NewDHCPOptionDefinitionLookup())
+	require.NoError(t, err)
+	return app
+}
+
+// Test that the changed configurations are stored in the history and that
+// they are attributed to the changes made through Stork.
+func TestCommitConfigSnapshot(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestConfigSnapshotApp(t, db, `{ "Dhcp4": { "valid-lifetime": 4000 } }`, "1234")
+	daemon := app.Daemons[0]
+
+	snapshots, total, err := dbmodel.GetKeaConfigSnapshotsByPage(db, daemon.ID, 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Equal(t, dbmodel.KeaConfigSnapshotSourceFetched, snapshots[0].Source)
+	require.Equal(t, "1234", snapshots[0].ConfigHash)
+	require.Zero(t, snapshots[0].AuditEntryID)
+
+	// Committing the same configuration should not create a new snapshot.
+	lookup := dbmodel.NewDHCPOptionDefinitionLookup()
+	err = CommitAppIntoDB(db, app, &storktest.FakeEventCenter{}, nil, lookup)
+	require.NoError(t, err)
+	_, total, err = dbmodel.GetKeaConfigSnapshotsByPage(db, daemon.ID, 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+
+	// Simulate the configuration change made through Stork followed by
+	// the lease change.
+	user := &dbmodel.SystemUser{Login: "jdoe", Name: "John", Lastname: "Doe"}
+	_, err = dbmodel.CreateUser(db, user)
+	require.NoError(t, err)
+	change := &dbmodel.AuditEntry{
+		UserID:     int64(user.ID),
+		UserLogin:  user.Login,
+		Target:     "kea",
+		Operation:  "global_parameters_update",
+		EntityType: "global_parameters",
+		DaemonIDs:  []int64{daemon.ID},
+	}
+	err = dbmodel.AddAuditEntry(db, change)
+	require.NoError(t, err)
+	err = dbmodel.AddAuditEntry(db, &dbmodel.AuditEntry{
+		UserID:     int64(user.ID),
+		UserLogin:  user.Login,
+		Target:     "kea",
+		Operation:  "lease_delete",
+		EntityType: "lease",
+		DaemonIDs:  []int64{daemon.ID},
+	})
+	require.NoError(t, err)
+
+	err = daemon.SetConfigFromJSON(`{ "Dhcp4": { "valid-lifetime": 3000 } }`)
+	require.NoError(t, err)
+	daemon.KeaDaemon.ConfigHash = "5678"
+	err = CommitAppIntoDB(db, app, &storktest.FakeEventCenter{}, nil, lookup)
+	require.NoError(t, err)
+
+	snapshots, total, err = dbmodel.GetKeaConfigSnapshotsByPage(db, daemon.ID, 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	require.Equal(t, "5678", snapshots[0].ConfigHash)
+	require.Equal(t, dbmodel.KeaConfigSnapshotSourceFetched, snapshots[0].Source)
+	require.Equal(t, change.ID, snapshots[0].AuditEntryID)
+	require.Equal(t, "global_parameters_update", snapshots[0].Operation)
+	require.Equal(t, "jdoe", snapshots[0].UserLogin)
+	require.EqualValues(t, user.ID, snapshots[0].UserID)
+}
+
+// Config manager refusing to lock the daemons, e.g., because they are
+// edited by another user.
+type lockedTestManager struct {
+	*testManager
+}
+
+// Always fails to lock the daemons.
+func (tm *lockedTestManager) Lock(ctx context.Context, daemonIDs ...int64) (context.Context, error) {
+	return ctx, errors.New("daemons are locked")
+}
+
+// Test rolling back the daemon's configuration to a stored snapshot.
+func TestRollbackConfig(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	serverConfig := `{ "Dhcp4": { "valid-lifetime": 4000 }, "hash": "1234" }`
+	configHash := keaconfig.NewHasher().Hash([]byte(serverConfig))
+	app := addTestConfigSnapshotApp(t, db, serverConfig, configHash)
+	daemon := app.Daemons[0]
+	daemon.App = app
+
+	snapshot, err := dbmodel.GetLatestKeaConfigSnapshot(db, daemon.ID)
+	require.NoError(t, err)
+	require.NotNil(t, snapshot)
+
+	user := &dbmodel.SystemUser{Login: "jdoe", Name: "John", Lastname: "Doe"}
+	_, err = dbmodel.CreateUser(db, user)
+	require.NoError(t, err)
+
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{})
+	lockedDuringSet := false
+	agents := agentcommtest.NewKeaFakeAgents(
+		mockConfigGet(serverConfig),
+		func(callNo int, cmdResponses []interface{}) {
+			lockedDuringSet = manager.locks[daemon.ID]
+			mockLeaseCommandResult(0, "Configuration successfully set.")(callNo, cmdResponses)
+		},
+		mockLeaseCommandResult(0, "Configuration written."),
+	)
+	restored, err := RollbackConfig(context.Background(), db, agents, manager, daemon, snapshot, user)
+	require.NoError(t, err)
+	require.NotNil(t, restored)
+	require.NotZero(t, restored.ID)
+	require.Equal(t, dbmodel.KeaConfigSnapshotSourceRollback, restored.Source)
+	require.Equal(t, configHash, restored.ConfigHash)
+	require.Equal(t, "jdoe", restored.UserLogin)
+	require.Contains(t, restored.Description, "rolled back to configuration snapshot")
+
+	// The daemon should be locked while the configuration is set and
+	// unlocked afterwards.
+	require.True(t, lockedDuringSet)
+	require.Empty(t, manager.locks)
+
+	// The current configuration should be verified, then the configuration
+	// should be set without the hash and persisted.
+	require.Len(t, agents.RecordedCommands, 3)
+	require.EqualValues(t, keactrl.ConfigGet, agents.RecordedCommands[0].GetCommand())
+	require.JSONEq(t, `{
+		"command": "config-set",
+		"service": ["dhcp4"],
+		"arguments": {
+			"Dhcp4": {
+				"valid-lifetime": 4000
+			}
+		}
+	}`, agents.RecordedCommands[1].Marshal())
+	require.EqualValues(t, keactrl.ConfigWrite, agents.RecordedCommands[2].GetCommand())
+
+	// The rollback should be recorded in the audit trail.
+	entries, err := dbmodel.GetAuditEntries(db, nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "config_rollback", entries[0].Operation)
+	require.Equal(t, snapshot.ID, entries[0].EntityID)
+	require.Equal(t, "jdoe", entries[0].UserLogin)
+	require.Len(t, entries[0].Commands, 2)
+	require.Empty(t, entries[0].Error)
+	require.Equal(t, entries[0].ID, restored.AuditEntryID)
+}
+
+// Test that a failed rollback is recorded in the audit trail and it is
+// not stored in the configuration history.
+func TestRollbackConfigError(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	serverConfig := `{ "Dhcp4": { "valid-lifetime": 4000 } }`
+	app := addTestConfigSnapshotApp(t, db, serverConfig, keaconfig.NewHasher().Hash([]byte(serverConfig)))
+	daemon := app.Daemons[0]
+	daemon.App = app
+
+	snapshot, err := dbmodel.GetLatestKeaConfigSnapshot(db, daemon.ID)
+	require.NoError(t, err)
+
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{})
+	agents := agentcommtest.NewKeaFakeAgents(mockConfigGet(serverConfig), mockLeaseCommandResult(1, "Configuration rejected."))
+	restored, err := RollbackConfig(context.Background(), db, agents, manager, daemon, snapshot, nil)
+	require.ErrorContains(t, err, "Configuration rejected.")
+	require.Nil(t, restored)
+	require.Empty(t, manager.locks)
+
+	// The config-write command should not be sent.
+	require.Len(t, agents.RecordedCommands, 2)
+
+	entries, err := dbmodel.GetAuditEntries(db, nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Contains(t, entries[0].Error, "Configuration rejected.")
+
+	_, total, err := dbmodel.GetKeaConfigSnapshotsByPage(db, daemon.ID, 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+}
+
+// Test that the rollback is rejected when the daemon's configuration has
+// been modified since it was fetched from the database.
+func TestRollbackConfigModified(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	serverConfig := `{ "Dhcp4": { "valid-lifetime": 4000 } }`
+	app := addTestConfigSnapshotApp(t, db, serverConfig, keaconfig.NewHasher().Hash([]byte(serverConfig)))
+	daemon := app.Daemons[0]
+	daemon.App = app
+
+	snapshot, err := dbmodel.GetLatestKeaConfigSnapshot(db, daemon.ID)
+	require.NoError(t, err)
+
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{})
+	agents := agentcommtest.NewKeaFakeAgents(mockConfigGet(`{ "Dhcp4": { "valid-lifetime": 5000 } }`))
+	restored, err := RollbackConfig(context.Background(), db, agents, manager, daemon, snapshot, nil)
+	var modifiedErr *config.ConfigModifiedError
+	require.ErrorAs(t, err, &modifiedErr)
+	require.Nil(t, restored)
+	require.Empty(t, manager.locks)
+
+	// Only the config-get command should be sent.
+	require.Len(t, agents.RecordedCommands, 1)
+	require.EqualValues(t, keactrl.ConfigGet, agents.RecordedCommands[0].GetCommand())
+
+	entries, err := dbmodel.GetAuditEntries(db, nil)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+// Test that the rollback is rejected when the daemon's configuration is
+// locked by another user.
+func TestRollbackConfigLocked(t *testing.T) {
+	agents := agentcommtest.NewKeaFakeAgents()
+	daemon := newTestLeaseDaemon(t, dbmodel.DaemonNameDHCPv4, false)
+
+	snapshot := &dbmodel.KeaConfigSnapshot{
+		ID:       1,
+		DaemonID: daemon.ID,
+		Config:   daemon.KeaDaemon.Config,
+	}
+	manager := &lockedTestManager{newTestManager(&appstest.ManagerAccessorsWrapper{})}
+	_, err := RollbackConfig(context.Background(), nil, agents, manager, daemon, snapshot, nil)
+	var lockErr *config.LockError
+	require.ErrorAs(t, err, &lockErr)
+	require.Empty(t, agents.RecordedCommands)
+}
+
+// Test that the rollback is rejected when the snapshot belongs to another
+// daemon or the daemon is not a DHCP server.
+func TestRollbackConfigInvalid(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(nil, nil)
+	daemon := newTestLeaseDaemon(t, dbmodel.DaemonNameDHCPv4, false)
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{})
+
+	snapshot := &dbmodel.KeaConfigSnapshot{
+		ID:       1,
+		DaemonID: daemon.ID + 1,
+		Config:   daemon.KeaDaemon.Config,
+	}
+	_, err := RollbackConfig(context.Background(), nil, agents, manager, daemon, snapshot, nil)
+	require.ErrorContains(t, err, "does not belong to daemon")
+
+	caConfig, err := dbmodel.NewKeaConfigFromJSON(`{ "Control-agent": { } }`)
+	require.NoError(t, err)
+	snapshot = &dbmodel.KeaConfigSnapshot{
+		ID:       1,
+		DaemonID: daemon.ID,
+		Config:   caConfig,
+	}
+	_, err = RollbackConfig(context.Background(), nil, agents, manager, daemon, snapshot, nil)
+	require.ErrorContains(t, err, "only the Kea DHCP servers are supported")
+
+	require.Empty(t, agents.RecordedCommands)
+	require.Empty(t, manager.locks)
+}
@@ -0,0 +1,140 @@
+package kea
+
+import (
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	keactrl "isc.org/stork/appctrl/kea"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Matches the names of the D2 statistics collected per TSIG key, e.g.,
+// key[d2.md5.key].update-sent.
+var d2KeyStatNamePattern = regexp.MustCompile(`^key\[(.+)\]\.([a-z-]+)$`)
+
+// Represents a response from the D2 server to the statistic-get-all
+// command:
+//
+//	{
+//		"result": 0,
+//		"arguments": {
+//			"ncr-received": [ [ 10, "2024-01-02 03:04:05.123456" ] ],
+//			"key[d2.md5.key].update-sent": [ [ 8, "2024-01-02 03:04:05.123456" ] ],
+//			...
+//		}
+//	}
+//
+// Each statistic holds a list of samples. The first sample is the most
+// recent one.
+type StatGetAllResponse struct {
+	keactrl.ResponseHeader
+	Arguments map[string][][]any `json:"arguments,omitempty"`
+}
+
+// Returns the most recent value of the statistic sample list. It returns
+// false if the list is empty or the value is not a number.
+func getLatestStatValue(samples [][]any) (int64, bool) {
+	if len(samples) == 0 || len(samples[0]) == 0 {
+		return 0, false
+	}
+	value, ok := samples[0][0].(float64)
+	if !ok {
+		return 0, false
+	}
+	return int64(value), true
+}
+
+// Sets the counter corresponding to the D2 statistic name. The statistics
+// not stored by Stork are ignored.
+func setD2StatCounter(stats *dbmodel.KeaD2DaemonStats, key *dbmodel.KeaD2KeyStats, name string, value int64) {
+	if key != nil {
+		switch name {
+		case "update-sent":
+			key.UpdateSent = value
+		case "update-success":
+			key.UpdateSuccess = value
+		case "update-timeout":
+			key.UpdateTimeout = value
+		case "update-error":
+			key.UpdateError = value
+		}
+		return
+	}
+	switch name {
+	case "ncr-received":
+		stats.NCRReceived = value
+	case "ncr-invalid":
+		stats.NCRInvalid = value
+	case "ncr-error":
+		stats.NCRError = value
+	case "update-sent":
+		stats.UpdateSent = value
+	case "update-success":
+		stats.UpdateSuccess = value
+	case "update-timeout":
+		stats.UpdateTimeout = value
+	case "update-error":
+		stats.UpdateError = value
+	}
+}
+
+// Converts the statistic-get-all response from the D2 server to the
+// statistics stored in the database. The per-key statistics are sorted
+// by the key name.
+func newD2DaemonStats(response []StatGetAllResponse, collectedAt time.Time) (*dbmodel.KeaD2DaemonStats, error) {
+	if len(response) == 0 {
+		return nil, errors.New("empty D2 statistics response")
+	}
+	if err := response[0].GetError(); err != nil {
+		return nil, errors.WithMessage(err, "error result in D2 statistics response")
+	}
+	if response[0].Arguments == nil {
+		return nil, errors.Errorf("missing arguments from D2 statistics response %+v", response[0])
+	}
+
+	stats := &dbmodel.KeaD2DaemonStats{
+		CollectedAt: collectedAt,
+	}
+	keys := make(map[string]*dbmodel.KeaD2KeyStats)
+	for name, samples := range response[0].Arguments {
+		value, ok := getLatestStatValue(samples)
+		if !ok {
+			continue
+		}
+		var key *dbmodel.KeaD2KeyStats
+		if match := d2KeyStatNamePattern.FindStringSubmatch(name); match != nil {
+			if key, ok = keys[match[1]]; !ok {
+				key = &dbmodel.KeaD2KeyStats{Name: match[1]}
+				keys[match[1]] = key
+			}
+			name = match[2]
+		}
+		setD2StatCounter(stats, key, name, value)
+	}
+	for _, key := range keys {
+		stats.Keys = append(stats.Keys, *key)
+	}
+	sort.Slice(stats.Keys, func(i, j int) bool {
+		return stats.Keys[i].Name < stats.Keys[j].Name
+	})
+	return stats, nil
+}
+
+// Processes the statistic-get-all command response from the D2 server and
+// stores the statistics in the database.
+func (statsPuller *StatsPuller) storeD2DaemonStats(daemon *dbmodel.Daemon, response interface{}, collectedAt time.Time) error {
+	statsResp, ok := response.(*[]StatGetAllResponse)
+	if !ok {
+		return errors.Errorf("response type is invalid: %+v", response)
+	}
+	if daemon.KeaDaemon == nil {
+		return errors.Errorf("daemon %d is not a Kea daemon", daemon.ID)
+	}
+	stats, err := newD2DaemonStats(*statsResp, collectedAt)
+	if err != nil {
+		return err
+	}
+	return dbmodel.UpdateKeaD2DaemonStats(statsPuller.DB, daemon.KeaDaemon.ID, *stats)
+}
@@ -0,0 +1,144 @@
+package kea
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	keactrl "isc.org/stork/appctrl/kea"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	storktest "isc.org/stork/server/test/dbmodel"
+)
+
+// Sample response to the statistic-get-all command sent to the D2 server.
+const d2StatGetAllResponse = `[{
+	"result": 0,
+	"text": "Everything is fine",
+	"arguments": {
+		"ncr-received": [ [ 12, "2024-01-02 03:04:05.123456" ], [ 11, "2024-01-02 03:04:00.123456" ] ],
+		"ncr-invalid": [ [ 1, "2024-01-02 03:04:05.123456" ] ],
+		"ncr-error": [ [ 0, "2024-01-02 03:04:05.123456" ] ],
+		"update-sent": [ [ 10, "2024-01-02 03:04:05.123456" ] ],
+		"update-success": [ [ 7, "2024-01-02 03:04:05.123456" ] ],
+		"update-timeout": [ [ 1, "2024-01-02 03:04:05.123456" ] ],
+		"update-error": [ [ 2, "2024-01-02 03:04:05.123456" ] ],
+		"update-signed": [ [ 10, "2024-01-02 03:04:05.123456" ] ],
+		"queue-mgr-queue-full": [ [ 0, "2024-01-02 03:04:05.123456" ] ],
+		"key[d2.sha256.key].update-sent": [ [ 6, "2024-01-02 03:04:05.123456" ] ],
+		"key[d2.sha256.key].update-error": [ [ 2, "2024-01-02 03:04:05.123456" ] ],
+		"key[d2.md5.key].update-sent": [ [ 4, "2024-01-02 03:04:05.123456" ] ],
+		"key[d2.md5.key].update-success": [ [ 4, "2024-01-02 03:04:05.123456" ] ],
+		"key[d2.md5.key].update-timeout": [ ]
+	}
+}]`
+
+// Test that the statistic-get-all response from the D2 server is converted
+// to the statistics stored in the database.
+func TestNewD2DaemonStats(t *testing.T) {
+	var response []StatGetAllResponse
+	command := keactrl.NewCommandBase(keactrl.StatisticGetAll, keactrl.D2)
+	err := keactrl.UnmarshalResponseList(command, []byte(d2StatGetAllResponse), &response)
+	require.NoError(t, err)
+
+	collectedAt := time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC)
+	stats, err := newD2DaemonStats(response, collectedAt)
+	require.NoError(t, err)
+	require.NotNil(t, stats)
+
+	require.EqualValues(t, 12, stats.NCRReceived)
+	require.EqualValues(t, 1, stats.NCRInvalid)
+	require.Zero(t, stats.NCRError)
+	require.EqualValues(t, 10, stats.UpdateSent)
+	require.EqualValues(t, 7, stats.UpdateSuccess)
+	require.EqualValues(t, 1, stats.UpdateTimeout)
+	require.EqualValues(t, 2, stats.UpdateError)
+	require.Equal(t, collectedAt, stats.CollectedAt)
+
+	require.Equal(t, []dbmodel.KeaD2KeyStats{
+		{
+			Name:          "d2.md5.key",
+			UpdateSent:    4,
+			UpdateSuccess: 4,
+		},
+		{
+			Name:        "d2.sha256.key",
+			UpdateSent:  6,
+			UpdateError: 2,
+		},
+	}, stats.Keys)
+}
+
+// Test that the invalid statistic-get-all responses are rejected.
+func TestNewD2DaemonStatsInvalidResponse(t *testing.T) {
+	_, err := newD2DaemonStats([]StatGetAllResponse{}, time.Now())
+	require.ErrorContains(t, err, "empty D2 statistics response")
+
+	_, err = newD2DaemonStats([]StatGetAllResponse{
+		{
+			ResponseHeader: keactrl.ResponseHeader{
+				Result: keactrl.ResponseCommandUnsupported,
+				Text:   "'statistic-get-all' command not supported.",
+			},
+		},
+	}, time.Now())
+	require.ErrorContains(t, err, "error result in D2 statistics response")
+
+	_, err = newD2DaemonStats([]StatGetAllResponse{{}}, time.Now())
+	require.ErrorContains(t, err, "missing arguments")
+}
+
+// Test that the D2 statistics are pulled and stored in the database.
+func TestStatsPullerPullD2Stats(t *testing.T) {
+	// Arrange
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+	_ = dbmodel.InitializeSettings(db, 0)
+	_ = dbmodel.InitializeStats(db)
+
+	machine := &dbmodel.Machine{
+		Address:   "localhost",
+		AgentPort: 8080,
+	}
+	err := dbmodel.AddMachine(db, machine)
+	require.NoError(t, err)
+
+	accessPoints := []*dbmodel.AccessPoint{}
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "localhost", "", 8000, false)
+	app := &dbmodel.App{
+		MachineID:    machine.ID,
+		Type:         dbmodel.AppTypeKea,
+		AccessPoints: accessPoints,
+		Daemons: []*dbmodel.Daemon{
+			dbmodel.NewKeaDaemon(dbmodel.DaemonNameD2, true),
+		},
+	}
+	_, err = dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+
+	fa := agentcommtest.NewFakeAgents(func(callNo int, cmdResponses []interface{}) {
+		command := keactrl.NewCommandBase(keactrl.StatisticGetAll, keactrl.D2)
+		_ = keactrl.UnmarshalResponseList(command, []byte(d2StatGetAllResponse), cmdResponses[0])
+	}, nil)
+
+	sp, _ := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
+	defer sp.Shutdown()
+
+	// Act
+	err = sp.pullStats()
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, fa.RecordedCommands, 1)
+	require.EqualValues(t, keactrl.StatisticGetAll, fa.RecordedCommands[0].GetCommand())
+
+	daemon, err := dbmodel.GetDaemonByID(db, app.Daemons[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, daemon.KeaDaemon.KeaD2Daemon)
+	stats := daemon.KeaDaemon.KeaD2Daemon.Stats
+	require.EqualValues(t, 12, stats.NCRReceived)
+	require.EqualValues(t, 2, stats.UpdateError)
+	require.Len(t, stats.Keys, 2)
+	require.NotZero(t, stats.CollectedAt)
+}
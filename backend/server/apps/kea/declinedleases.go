@@ -0,0 +1,503 @@
+package kea
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	errors "github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	keactrl "isc.org/stork/appctrl/kea"
+	keadata "isc.org/stork/appdata/kea"
+	"isc.org/stork/server/agentcomm"
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/eventcenter"
+	storkutil "isc.org/stork/util"
+)
+
+// Specifies how the declined leases are released.
+type DeclinedLeasesReleaseMode string
+
+// The declined leases are either deleted from the lease database or
+// moved to the expired-reclaimed state. In both cases, the addresses
+// become available for allocation.
+const (
+	DeclinedLeasesReleaseDelete  DeclinedLeasesReleaseMode = "delete"
+	DeclinedLeasesReleaseReclaim DeclinedLeasesReleaseMode = "reclaim"
+)
+
+const (
+	// Maximum number of the error messages remembered for a daemon. The
+	// remaining errors are only counted.
+	maxDeclinedLeasesReleaseErrors = 10
+	// Maximum number of the jobs remembered by the releaser. The oldest
+	// finished jobs are forgotten when this number is exceeded.
+	maxDeclinedLeasesReleaseJobs = 20
+	// Number of the progress events issued during a job.
+	declinedLeasesReleaseProgressEvents = 10
+)
+
+// Selects the declined leases to be released. The zero values of the
+// fields match all leases.
+type DeclinedLeasesFilter struct {
+	// ID of the app the leases belong to.
+	AppID int64
+	// ID of the subnet in the Stork database.
+	SubnetID int64
+	// Minimum time elapsed since the lease was declined.
+	MinAge time.Duration
+	// Subnet IDs in the Kea configurations of the daemons owning the
+	// subnet selected by SubnetID. The keys are returned by the
+	// getDeclinedLeaseDaemonKey function.
+	localSubnetIDs map[string]int64
+}
+
+// Maps the selected Stork subnet to the subnet IDs used by the daemons
+// owning the subnet. The leases do not hold the Stork subnet ID, so the
+// filter must be resolved before it can match any lease by subnet.
+func (filter *DeclinedLeasesFilter) setSubnet(subnet *dbmodel.Subnet) {
+	filter.localSubnetIDs = make(map[string]int64)
+	for _, ls := range subnet.LocalSubnets {
+		if ls.Daemon == nil {
+			continue
+		}
+		key := fmt.Sprintf("%d:%s", ls.Daemon.AppID, ls.Daemon.Name)
+		filter.localSubnetIDs[key] = ls.LocalSubnetID
+	}
+}
+
+// Checks if the declined lease matches the filter. The lease age is
+// calculated from the client last transaction time relative to now.
+func (filter *DeclinedLeasesFilter) Matches(lease *dbmodel.Lease, now time.Time) bool {
+	if filter.AppID != 0 && lease.AppID != filter.AppID {
+		return false
+	}
+	if filter.SubnetID != 0 {
+		localSubnetID, ok := filter.localSubnetIDs[getDeclinedLeaseDaemonKey(lease)]
+		if !ok || int64(lease.SubnetID) != localSubnetID {
+			return false
+		}
+	}
+	if filter.MinAge > 0 && now.Sub(time.Unix(int64(lease.CLTT), 0)) < filter.MinAge {
+		return false
+	}
+	return true
+}
+
+// Result of releasing the declined leases in a single daemon.
+type DeclinedLeasesReleaseResult struct {
+	// ID of the daemon. It is zero if the daemon could not be found.
+	DaemonID   int64
+	DaemonName string
+	AppID      int64
+	AppName    string
+	// Number of successfully released leases.
+	Released int64
+	// Number of leases that could not be released.
+	Failed int64
+	// Error messages for the leases that could not be released. It holds
+	// at most maxDeclinedLeasesReleaseErrors messages.
+	Errors []string
+}
+
+// Remembers the error message unless there are too many of them already.
+func (result *DeclinedLeasesReleaseResult) addError(err error) {
+	result.Failed++
+	if len(result.Errors) < maxDeclinedLeasesReleaseErrors {
+		result.Errors = append(result.Errors, err.Error())
+	}
+}
+
+// State of the background job releasing the declined leases. It is
+// returned by the job as a copy, so it is safe to use it while the job
+// is running.
+type DeclinedLeasesReleaseStatus struct {
+	ID     int64
+	Mode   DeclinedLeasesReleaseMode
+	Filter DeclinedLeasesFilter
+	// Number of the declined leases matching the filter.
+	Total int64
+	// Number of the leases processed so far.
+	Processed  int64
+	StartedAt  time.Time
+	FinishedAt *time.Time
+	// Names of the apps from which the declined leases could not be fetched.
+	ErredApps []string
+	// Error terminating the job.
+	Error   string
+	Results []DeclinedLeasesReleaseResult
+}
+
+// Background job releasing the declined leases.
+type DeclinedLeasesReleaseJob struct {
+	mutex  sync.RWMutex
+	status DeclinedLeasesReleaseStatus
+	done   chan struct{}
+}
+
+// Returns a copy of the current job state.
+func (job *DeclinedLeasesReleaseJob) GetStatus() DeclinedLeasesReleaseStatus {
+	job.mutex.RLock()
+	defer job.mutex.RUnlock()
+	status := job.status
+	status.ErredApps = append([]string{}, job.status.ErredApps...)
+	status.Results = make([]DeclinedLeasesReleaseResult, len(job.status.Results))
+	for i, result := range job.status.Results {
+		status.Results[i] = result
+		status.Results[i].Errors = append([]string{}, result.Errors...)
+	}
+	return status
+}
+
+// Checks if the job has finished.
+func (job *DeclinedLeasesReleaseJob) IsFinished() bool {
+	select {
+	case <-job.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Blocks until the job finishes.
+func (job *DeclinedLeasesReleaseJob) Wait() {
+	<-job.done
+}
+
+// Runs the jobs releasing the declined leases in the background and
+// remembers their state, so it can be polled by the users. The progress
+// and the results are also reported via the event center.
+type DeclinedLeasesReleaser struct {
+	db          *pg.DB
+	agents      agentcomm.ConnectedAgents
+	eventCenter eventcenter.EventCenter
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+	mutex       sync.Mutex
+	jobs        []*DeclinedLeasesReleaseJob
+	lastJobID   int64
+}
+
+// Creates new instance of the releaser.
+func NewDeclinedLeasesReleaser(db *pg.DB, agents agentcomm.ConnectedAgents, eventCenter eventcenter.EventCenter) *DeclinedLeasesReleaser {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &DeclinedLeasesReleaser{
+		db:          db,
+		agents:      agents,
+		eventCenter: eventCenter,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Starts the background job releasing the declined leases matching the
+// filter. The user is the one who requested the operation; it is used in
+// the events and the audit trail.
+func (releaser *DeclinedLeasesReleaser) Start(filter DeclinedLeasesFilter, mode DeclinedLeasesReleaseMode, user *dbmodel.SystemUser) (*DeclinedLeasesReleaseJob, error) {
+	if mode != DeclinedLeasesReleaseDelete && mode != DeclinedLeasesReleaseReclaim {
+		return nil, errors.Errorf("invalid mode %s of releasing the declined leases", mode)
+	}
+	releaser.mutex.Lock()
+	defer releaser.mutex.Unlock()
+	if releaser.ctx.Err() != nil {
+		return nil, errors.New("releasing the declined leases has been shut down")
+	}
+	releaser.lastJobID++
+	job := &DeclinedLeasesReleaseJob{
+		status: DeclinedLeasesReleaseStatus{
+			ID:        releaser.lastJobID,
+			Mode:      mode,
+			Filter:    filter,
+			StartedAt: storkutil.UTCNow(),
+		},
+		done: make(chan struct{}),
+	}
+	releaser.jobs = append(releaser.jobs, job)
+	releaser.pruneJobs()
+
+	releaser.wg.Add(1)
+	go func() {
+		defer releaser.wg.Done()
+		defer close(job.done)
+		releaser.run(job, user)
+	}()
+	return job, nil
+}
+
+// Removes the oldest finished jobs when there are too many of them.
+// It must be called with the mutex locked.
+func (releaser *DeclinedLeasesReleaser) pruneJobs() {
+	for i := 0; len(releaser.jobs) > maxDeclinedLeasesReleaseJobs && i < len(releaser.jobs); {
+		if releaser.jobs[i].IsFinished() {
+			releaser.jobs = append(releaser.jobs[:i], releaser.jobs[i+1:]...)
+			continue
+		}
+		i++
+	}
+}
+
+// Returns the job with the specified ID or nil if it does not exist.
+func (releaser *DeclinedLeasesReleaser) GetJob(id int64) *DeclinedLeasesReleaseJob {
+	releaser.mutex.Lock()
+	defer releaser.mutex.Unlock()
+	for _, job := range releaser.jobs {
+		if job.status.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// Interrupts the running jobs and waits for them to finish.
+func (releaser *DeclinedLeasesReleaser) Shutdown() {
+	releaser.mutex.Lock()
+	releaser.cancel()
+	releaser.mutex.Unlock()
+	releaser.wg.Wait()
+}
+
+// Adds an event with the specified level if the event center is
+// available.
+func (releaser *DeclinedLeasesReleaser) addEvent(level dbmodel.EventLevel, text string, objects ...any) {
+	if releaser.eventCenter == nil {
+		return
+	}
+	switch level {
+	case dbmodel.EvError:
+		releaser.eventCenter.AddErrorEvent(text, objects...)
+	case dbmodel.EvWarning:
+		releaser.eventCenter.AddWarningEvent(text, objects...)
+	default:
+		releaser.eventCenter.AddInfoEvent(text, objects...)
+	}
+}
+
+// Fetches the declined leases, releases those matching the filter and
+// reports the results.
+func (releaser *DeclinedLeasesReleaser) run(job *DeclinedLeasesReleaseJob, user *dbmodel.SystemUser) {
+	status := job.GetStatus()
+	releaser.addEvent(dbmodel.EvInfo, fmt.Sprintf("{user} started releasing declined leases (job %d)", status.ID), user)
+
+	if status.Filter.SubnetID != 0 {
+		subnet, err := dbmodel.GetSubnet(releaser.db, status.Filter.SubnetID)
+		if err == nil && subnet == nil {
+			err = errors.Errorf("subnet with ID %d not found", status.Filter.SubnetID)
+		}
+		if err != nil {
+			releaser.finish(job, err)
+			releaser.addEvent(dbmodel.EvError, fmt.Sprintf("failed to release declined leases (job %d)", status.ID), user, err)
+			return
+		}
+		status.Filter.setSubnet(subnet)
+	}
+
+	leases, erredApps, err := FindDeclinedLeases(releaser.db, releaser.agents)
+	if err != nil {
+		releaser.finish(job, err)
+		releaser.addEvent(dbmodel.EvError, fmt.Sprintf("failed to release declined leases (job %d)", status.ID), user, err)
+		return
+	}
+	now := time.Now()
+	var selected []dbmodel.Lease
+	for i := range leases {
+		if status.Filter.Matches(&leases[i], now) {
+			selected = append(selected, leases[i])
+		}
+	}
+	job.mutex.Lock()
+	job.status.Total = int64(len(selected))
+	for _, app := range erredApps {
+		job.status.ErredApps = append(job.status.ErredApps, app.GetName())
+	}
+	job.mutex.Unlock()
+
+	for _, app := range erredApps {
+		if status.Filter.AppID == 0 || status.Filter.AppID == app.ID {
+			releaser.addEvent(dbmodel.EvWarning, fmt.Sprintf("failed to fetch declined leases from {app} (job %d)", status.ID), app)
+		}
+	}
+
+	step := len(selected) / declinedLeasesReleaseProgressEvents
+	if step == 0 {
+		step = 1
+	}
+	results := make(map[string]*DeclinedLeasesReleaseResult)
+	daemons := make(map[string]*dbmodel.Daemon)
+	for i := range selected {
+		if releaser.ctx.Err() != nil {
+			releaser.finish(job, errors.New("interrupted by the server shutdown"))
+			return
+		}
+		lease := &selected[i]
+		daemon, result := getDeclinedLeaseDaemon(lease, results, daemons)
+		var err error
+		if daemon == nil {
+			err = errors.Errorf("DHCP server for lease %s not found", lease.IPAddress)
+		} else {
+			err = releaseDeclinedLease(releaser.agents, daemon, lease, status.Mode)
+		}
+
+		job.mutex.Lock()
+		if err != nil {
+			result.addError(err)
+		} else {
+			result.Released++
+		}
+		job.status.Processed++
+		job.status.Results = nil
+		for _, r := range sortedDeclinedLeasesResults(results) {
+			job.status.Results = append(job.status.Results, *r)
+		}
+		job.mutex.Unlock()
+
+		if (i+1)%step == 0 && i+1 < len(selected) {
+			releaser.addEvent(dbmodel.EvInfo, fmt.Sprintf("processed %d of %d declined leases (job %d)", i+1, len(selected), status.ID))
+		}
+	}
+	releaser.finish(job, nil)
+
+	for key, result := range results {
+		releaser.recordResult(user, daemons[key], result, status)
+	}
+	releaser.addEvent(dbmodel.EvInfo, fmt.Sprintf("{user} finished releasing %d declined leases (job %d)", len(selected), status.ID), user)
+}
+
+// Marks the job as finished.
+func (releaser *DeclinedLeasesReleaser) finish(job *DeclinedLeasesReleaseJob, err error) {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	job.status.FinishedAt = storkutil.Ptr(storkutil.UTCNow())
+	if err != nil {
+		job.status.Error = err.Error()
+		log.WithError(err).WithField("job", job.status.ID).Error("Failed to release the declined leases")
+	}
+}
+
+// Records the result of releasing the leases in a daemon in the audit
+// trail and in the events.
+func (releaser *DeclinedLeasesReleaser) recordResult(user *dbmodel.SystemUser, daemon *dbmodel.Daemon, result *DeclinedLeasesReleaseResult, status DeclinedLeasesReleaseStatus) {
+	if daemon == nil {
+		releaser.addEvent(dbmodel.EvWarning, fmt.Sprintf("failed to release %d declined leases in %s (job %d): DHCP server not found", result.Failed, result.AppName, status.ID), user)
+		return
+	}
+	var resultErr error
+	if result.Failed > 0 {
+		resultErr = errors.Errorf("failed to release %d declined leases", result.Failed)
+	}
+	entry := NewLeaseAuditEntry("lease_release_declined", daemon, nil, result, nil, resultErr)
+	if user != nil {
+		entry.UserID = int64(user.ID)
+		entry.UserLogin = user.Login
+		if entry.UserLogin == "" {
+			entry.UserLogin = user.Email
+		}
+	}
+	if releaser.db != nil {
+		if err := dbmodel.AddAuditEntry(releaser.db, entry); err != nil {
+			log.WithError(err).Error("Cannot record releasing the declined leases in the audit trail")
+		}
+	}
+	text := fmt.Sprintf("{user} released %d declined leases in {daemon} (job %d)", result.Released, status.ID)
+	if result.Failed > 0 {
+		text = fmt.Sprintf("{user} released %d declined leases in {daemon}, %d failed (job %d)", result.Released, result.Failed, status.ID)
+		releaser.addEvent(dbmodel.EvWarning, text, user, daemon, resultErr)
+		return
+	}
+	releaser.addEvent(dbmodel.EvInfo, text, user, daemon)
+}
+
+// Returns the DHCP server holding the lease and the result structure for
+// this server. The daemon is nil if it cannot be found. The results and
+// daemons maps are updated.
+func getDeclinedLeaseDaemon(lease *dbmodel.Lease, results map[string]*DeclinedLeasesReleaseResult, daemons map[string]*dbmodel.Daemon) (*dbmodel.Daemon, *DeclinedLeasesReleaseResult) {
+	daemonName := getDeclinedLeaseDaemonName(lease)
+	key := getDeclinedLeaseDaemonKey(lease)
+	if result, ok := results[key]; ok {
+		return daemons[key], result
+	}
+	result := &DeclinedLeasesReleaseResult{
+		DaemonName: daemonName,
+		AppID:      lease.AppID,
+	}
+	results[key] = result
+	if lease.App == nil {
+		return nil, result
+	}
+	result.AppName = lease.App.GetName()
+	daemon := lease.App.GetDaemonByName(daemonName)
+	if daemon == nil {
+		return nil, result
+	}
+	// The daemons fetched with the apps lack the reference to the app.
+	daemonCopy := *daemon
+	daemonCopy.App = lease.App
+	daemons[key] = &daemonCopy
+	result.DaemonID = daemon.ID
+	return &daemonCopy, result
+}
+
+// Returns the name of the DHCP server owning the lease. It is selected by
+// the lease address family.
+func getDeclinedLeaseDaemonName(lease *dbmodel.Lease) string {
+	if ip := net.ParseIP(lease.IPAddress); ip != nil && ip.To4() != nil {
+		return dbmodel.DaemonNameDHCPv4
+	}
+	return dbmodel.DaemonNameDHCPv6
+}
+
+// Returns the key identifying the DHCP server owning the lease. It consists
+// of the app ID and the daemon name.
+func getDeclinedLeaseDaemonKey(lease *dbmodel.Lease) string {
+	return fmt.Sprintf("%d:%s", lease.AppID, getDeclinedLeaseDaemonName(lease))
+}
+
+// Returns the results ordered by the app ID and the daemon name.
+func sortedDeclinedLeasesResults(results map[string]*DeclinedLeasesReleaseResult) []*DeclinedLeasesReleaseResult {
+	sorted := make([]*DeclinedLeasesReleaseResult, 0, len(results))
+	for _, result := range results {
+		sorted = append(sorted, result)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].AppID != sorted[j].AppID {
+			return sorted[i].AppID < sorted[j].AppID
+		}
+		return sorted[i].DaemonName < sorted[j].DaemonName
+	})
+	return sorted
+}
+
+// Releases a single declined lease in the daemon. The lease is deleted
+// or updated to the expired-reclaimed state depending on the mode. The
+// identifiers of the lease are preserved in the latter case because
+// they are mandatory in the update commands.
+func releaseDeclinedLease(agents agentcomm.ConnectedAgents, daemon *dbmodel.Daemon, lease *dbmodel.Lease, mode DeclinedLeasesReleaseMode) error {
+	var err error
+	switch mode {
+	case DeclinedLeasesReleaseDelete:
+		_, err = DeleteLease(agents, daemon, keactrl.LeaseType(lease.Type), lease.IPAddress)
+	default:
+		params := &keactrl.LeaseParameters{
+			IPAddress: lease.IPAddress,
+			Type:      keactrl.LeaseType(lease.Type),
+			SubnetID:  storkutil.Ptr(int64(lease.SubnetID)),
+			HWAddress: lease.HWAddress,
+			ClientID:  lease.ClientID,
+			DUID:      lease.DUID,
+			State:     storkutil.Ptr(int64(keadata.LeaseStateExpiredReclaimed)),
+		}
+		if daemon.Name == dbmodel.DaemonNameDHCPv6 {
+			params.IAID = storkutil.Ptr(int64(lease.IAID))
+			if lease.PrefixLength > 0 {
+				params.PrefixLength = storkutil.Ptr(int64(lease.PrefixLength))
+			}
+		}
+		_, err = UpdateLease(agents, daemon, params)
+	}
+	return err
+}
@@ -0,0 +1,378 @@
+package kea
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	require "github.com/stretchr/testify/require"
+
+	keactrl "isc.org/stork/appctrl/kea"
+	keadata "isc.org/stork/appdata/kea"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	storktest "isc.org/stork/server/test/dbmodel"
+)
+
+// Test that the declined leases are filtered by app, subnet and age.
+func TestDeclinedLeasesFilterMatches(t *testing.T) {
+	now := time.Unix(100000, 0)
+	lease := &dbmodel.Lease{
+		AppID: 1,
+		Lease: keadata.Lease{
+			IPAddress: "192.0.2.1",
+			SubnetID:  3,
+			CLTT:      100000 - 3600,
+			State:     keadata.LeaseStateDeclined,
+		},
+	}
+
+	require.True(t, (&DeclinedLeasesFilter{}).Matches(lease, now))
+	require.False(t, (&DeclinedLeasesFilter{AppID: 2}).Matches(lease, now))
+	require.False(t, (&DeclinedLeasesFilter{MinAge: 2 * time.Hour}).Matches(lease, now))
+
+	// The subnet ID in the filter is the Stork subnet ID. The filter
+	// matches nothing by subnet until it is resolved to the subnet IDs
+	// in the Kea configurations.
+	filter := &DeclinedLeasesFilter{AppID: 1, SubnetID: 10, MinAge: time.Hour}
+	require.False(t, filter.Matches(lease, now))
+
+	filter.setSubnet(&dbmodel.Subnet{
+		ID: 10,
+		LocalSubnets: []*dbmodel.LocalSubnet{
+			{
+				LocalSubnetID: 3,
+				Daemon: &dbmodel.Daemon{
+					AppID: 1,
+					Name:  dbmodel.DaemonNameDHCPv4,
+				},
+			},
+			{
+				LocalSubnetID: 4,
+				Daemon: &dbmodel.Daemon{
+					AppID: 2,
+					Name:  dbmodel.DaemonNameDHCPv4,
+				},
+			},
+		},
+	})
+	require.True(t, filter.Matches(lease, now))
+
+	// The lease in another app having the same local subnet ID.
+	otherLease := *lease
+	otherLease.AppID = 2
+	require.False(t, (&DeclinedLeasesFilter{SubnetID: 10, localSubnetIDs: filter.localSubnetIDs}).Matches(&otherLease, now))
+
+	// The lease with the matching local subnet ID in another app.
+	otherLease.SubnetID = 4
+	require.True(t, (&DeclinedLeasesFilter{SubnetID: 10, localSubnetIDs: filter.localSubnetIDs}).Matches(&otherLease, now))
+
+	// The DHCPv6 lease having the same local subnet ID.
+	otherLease = *lease
+	otherLease.IPAddress = "2001:db8:1::1"
+	require.False(t, filter.Matches(&otherLease, now))
+}
+
+// Test that the DHCP server is selected by the lease address family and
+// that the results are grouped by the daemons.
+func TestGetDeclinedLeaseDaemon(t *testing.T) {
+	app := &dbmodel.App{
+		ID:   1,
+		Name: "kea@localhost",
+		Daemons: []*dbmodel.Daemon{
+			{ID: 4, Name: dbmodel.DaemonNameDHCPv4},
+		},
+	}
+	results := make(map[string]*DeclinedLeasesReleaseResult)
+	daemons := make(map[string]*dbmodel.Daemon)
+
+	lease4 := &dbmodel.Lease{AppID: 1, App: app, Lease: keadata.Lease{IPAddress: "192.0.2.1"}}
+	daemon, result := getDeclinedLeaseDaemon(lease4, results, daemons)
+	require.NotNil(t, daemon)
+	require.EqualValues(t, 4, daemon.ID)
+	require.Equal(t, app, daemon.App)
+	require.EqualValues(t, 4, result.DaemonID)
+	require.Equal(t, "kea@localhost", result.AppName)
+
+	// The same result should be returned for another lease in this daemon.
+	daemon2, result2 := getDeclinedLeaseDaemon(lease4, results, daemons)
+	require.Same(t, daemon, daemon2)
+	require.Same(t, result, result2)
+
+	// The DHCPv6 server does not exist in the app.
+	lease6 := &dbmodel.Lease{AppID: 1, App: app, Lease: keadata.Lease{IPAddress: "2001:db8:1::1"}}
+	daemon, result = getDeclinedLeaseDaemon(lease6, results, daemons)
+	require.Nil(t, daemon)
+	require.Zero(t, result.DaemonID)
+	require.Equal(t, dbmodel.DaemonNameDHCPv6, result.DaemonName)
+
+	sorted := sortedDeclinedLeasesResults(results)
+	require.Len(t, sorted, 2)
+	require.Equal(t, dbmodel.DaemonNameDHCPv4, sorted[0].DaemonName)
+	require.Equal(t, dbmodel.DaemonNameDHCPv6, sorted[1].DaemonName)
+}
+
+// Test that the declined DHCPv6 lease is moved to the expired-reclaimed
+// state in the reclaim mode.
+func TestReleaseDeclinedLeaseReclaim(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(mockLeaseCommandResult(0, "IPv6 lease updated."), nil)
+	daemon := newTestLeaseDaemon(t, dbmodel.DaemonNameDHCPv6, true)
+	lease := &dbmodel.Lease{
+		Lease: keadata.Lease{
+			IPAddress: "2001:db8:1::1",
+			Type:      "IA_NA",
+			DUID:      "00:00:00",
+			IAID:      1,
+			SubnetID:  3,
+			State:     keadata.LeaseStateDeclined,
+		},
+	}
+	err := releaseDeclinedLease(agents, daemon, lease, DeclinedLeasesReleaseReclaim)
+	require.NoError(t, err)
+	require.Len(t, agents.RecordedCommands, 1)
+	require.JSONEq(t, `{
+		"command": "lease6-update",
+		"service": ["dhcp6"],
+		"arguments": {
+			"ip-address": "2001:db8:1::1",
+			"type": "IA_NA",
+			"duid": "00:00:00",
+			"iaid": 1,
+			"subnet-id": 3,
+			"state": 2
+		}
+	}`, agents.RecordedCommands[0].Marshal())
+}
+
+// Test that the declined DHCPv4 lease is deleted in the delete mode.
+func TestReleaseDeclinedLeaseDelete(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(mockLeaseCommandResult(0, "IPv4 lease deleted."), nil)
+	daemon := newTestLeaseDaemon(t, dbmodel.DaemonNameDHCPv4, true)
+	lease := &dbmodel.Lease{
+		Lease: keadata.Lease{
+			IPAddress: "192.0.2.1",
+			State:     keadata.LeaseStateDeclined,
+		},
+	}
+	err := releaseDeclinedLease(agents, daemon, lease, DeclinedLeasesReleaseDelete)
+	require.NoError(t, err)
+	require.Len(t, agents.RecordedCommands, 1)
+	require.EqualValues(t, keactrl.Lease4Del, agents.RecordedCommands[0].GetCommand())
+}
+
+// Test that an invalid release mode is rejected.
+func TestDeclinedLeasesReleaserInvalidMode(t *testing.T) {
+	releaser := NewDeclinedLeasesReleaser(nil, nil, nil)
+	defer releaser.Shutdown()
+
+	job, err := releaser.Start(DeclinedLeasesFilter{}, "purge", nil)
+	require.Error(t, err)
+	require.Nil(t, job)
+}
+
+// Test that no new jobs are started after the releaser is shut down.
+func TestDeclinedLeasesReleaserShutdown(t *testing.T) {
+	releaser := NewDeclinedLeasesReleaser(nil, nil, nil)
+	releaser.Shutdown()
+
+	job, err := releaser.Start(DeclinedLeasesFilter{}, DeclinedLeasesReleaseDelete, nil)
+	require.Error(t, err)
+	require.Nil(t, job)
+}
+
+// Adds a Kea app with the DHCPv4 and DHCPv6 servers using the lease_cmds
+// hook library.
+func addTestDeclinedLeasesApp(t *testing.T, db *pg.DB) *dbmodel.App {
+	machine := &dbmodel.Machine{
+		Address:   "machine",
+		AgentPort: 8080,
+	}
+	err := dbmodel.AddMachine(db, machine)
+	require.NoError(t, err)
+
+	accessPoints := []*dbmodel.AccessPoint{}
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "localhost", "", 8000, true)
+	app := &dbmodel.App{
+		MachineID:    machine.ID,
+		Type:         dbmodel.AppTypeKea,
+		AccessPoints: accessPoints,
+		Daemons: []*dbmodel.Daemon{
+			{
+				Name: dbmodel.DaemonNameDHCPv4,
+				KeaDaemon: &dbmodel.KeaDaemon{
+					Config: dbmodel.NewKeaConfig(&map[string]interface{}{
+						"Dhcp4": map[string]interface{}{
+							"hooks-libraries": []interface{}{
+								map[string]interface{}{
+									"library": "libdhcp_lease_cmds.so",
+								},
+							},
+						},
+					}),
+				},
+			},
+			{
+				Name: dbmodel.DaemonNameDHCPv6,
+				KeaDaemon: &dbmodel.KeaDaemon{
+					Config: dbmodel.NewKeaConfig(&map[string]interface{}{
+						"Dhcp6": map[string]interface{}{
+							"hooks-libraries": []interface{}{
+								map[string]interface{}{
+									"library": "libdhcp_lease_cmds.so",
+								},
+							},
+						},
+					}),
+				},
+			},
+		},
+	}
+	_, err = dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+	return app
+}
+
+// Test the background job deleting the declined leases from the DHCPv4
+// and DHCPv6 servers.
+func TestDeclinedLeasesReleaserDelete(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	addTestDeclinedLeasesApp(t, db)
+
+	// The first call finds the declined leases. Subsequent calls delete
+	// them. The deletion of the second DHCPv6 lease fails.
+	agents := agentcommtest.NewFakeAgents(func(callNo int, responses []interface{}) {
+		switch callNo {
+		case 0:
+			mockLeasesGetDeclined(callNo, responses)
+		case 3:
+			mockLeaseCommandResult(1, "unable to delete lease")(callNo, responses)
+		default:
+			mockLeaseCommandResult(0, "lease deleted")(callNo, responses)
+		}
+	}, nil)
+	fec := &storktest.FakeEventCenter{}
+	releaser := NewDeclinedLeasesReleaser(db, agents, fec)
+	defer releaser.Shutdown()
+
+	user := &dbmodel.SystemUser{ID: 1, Login: "admin"}
+	job, err := releaser.Start(DeclinedLeasesFilter{}, DeclinedLeasesReleaseDelete, user)
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	job.Wait()
+
+	require.Same(t, job, releaser.GetJob(job.GetStatus().ID))
+	require.Nil(t, releaser.GetJob(job.GetStatus().ID+1))
+
+	status := job.GetStatus()
+	require.Empty(t, status.Error)
+	require.NotNil(t, status.FinishedAt)
+	require.EqualValues(t, 3, status.Total)
+	require.EqualValues(t, 3, status.Processed)
+	require.Len(t, status.Results, 2)
+
+	require.Equal(t, dbmodel.DaemonNameDHCPv4, status.Results[0].DaemonName)
+	require.EqualValues(t, 1, status.Results[0].Released)
+	require.Zero(t, status.Results[0].Failed)
+
+	require.Equal(t, dbmodel.DaemonNameDHCPv6, status.Results[1].DaemonName)
+	require.EqualValues(t, 1, status.Results[1].Released)
+	require.EqualValues(t, 1, status.Results[1].Failed)
+	require.Len(t, status.Results[1].Errors, 1)
+	require.Contains(t, status.Results[1].Errors[0], "unable to delete lease")
+
+	// One search command and three delete commands should have been sent.
+	require.Len(t, agents.RecordedCommands, 4)
+	require.EqualValues(t, keactrl.Lease4Del, agents.RecordedCommands[1].GetCommand())
+	require.EqualValues(t, keactrl.Lease6Del, agents.RecordedCommands[2].GetCommand())
+	require.EqualValues(t, keactrl.Lease6Del, agents.RecordedCommands[3].GetCommand())
+
+	// The results should be recorded in the audit trail for each daemon.
+	entries, err := dbmodel.GetAuditEntries(db, &dbmodel.AuditEntriesByPageFilters{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		require.Equal(t, "lease_release_declined", entry.Operation)
+		require.Equal(t, "admin", entry.UserLogin)
+	}
+
+	// The start, progress and results should be reported as events.
+	require.NotEmpty(t, fec.Events)
+	require.Contains(t, fec.Events[0].Text, "started releasing declined leases")
+	require.Contains(t, fec.Events[len(fec.Events)-1].Text, "finished releasing 3 declined leases")
+	var warnings int
+	for _, event := range fec.Events {
+		if event.Level == dbmodel.EvWarning {
+			warnings++
+			require.Contains(t, event.Text, "1 failed")
+		}
+	}
+	require.Equal(t, 1, warnings)
+}
+
+// Test that the background job selects the declined leases by the subnet
+// ID in the Stork database. The leases in other subnets having the same
+// subnet ID in the Kea configuration must not be released.
+func TestDeclinedLeasesReleaserSubnet(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestDeclinedLeasesApp(t, db)
+	subnet := &dbmodel.Subnet{
+		Prefix: "2001:db8:2::/64",
+	}
+	err := dbmodel.AddSubnet(db, subnet)
+	require.NoError(t, err)
+	subnet.SetLocalSubnet(&dbmodel.LocalSubnet{
+		DaemonID:      app.GetDaemonByName(dbmodel.DaemonNameDHCPv6).ID,
+		LocalSubnetID: 44,
+	})
+	err = dbmodel.AddLocalSubnets(db, subnet)
+	require.NoError(t, err)
+
+	agents := agentcommtest.NewFakeAgents(func(callNo int, responses []interface{}) {
+		if callNo == 0 {
+			mockLeasesGetDeclined(callNo, responses)
+			return
+		}
+		mockLeaseCommandResult(0, "lease deleted")(callNo, responses)
+	}, nil)
+	releaser := NewDeclinedLeasesReleaser(db, agents, nil)
+	defer releaser.Shutdown()
+
+	job, err := releaser.Start(DeclinedLeasesFilter{SubnetID: subnet.ID}, DeclinedLeasesReleaseDelete, nil)
+	require.NoError(t, err)
+	job.Wait()
+
+	// Only the DHCPv6 leases belong to the subnet.
+	status := job.GetStatus()
+	require.Empty(t, status.Error)
+	require.EqualValues(t, 2, status.Total)
+	require.Len(t, status.Results, 1)
+	require.Equal(t, dbmodel.DaemonNameDHCPv6, status.Results[0].DaemonName)
+	require.EqualValues(t, 2, status.Results[0].Released)
+	require.Len(t, agents.RecordedCommands, 3)
+	require.EqualValues(t, keactrl.Lease6Del, agents.RecordedCommands[1].GetCommand())
+	require.EqualValues(t, keactrl.Lease6Del, agents.RecordedCommands[2].GetCommand())
+}
+
+// Test that the background job fails when the selected subnet does not
+// exist.
+func TestDeclinedLeasesReleaserSubnetNotFound(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewFakeAgents(nil, nil)
+	releaser := NewDeclinedLeasesReleaser(db, agents, nil)
+	defer releaser.Shutdown()
+
+	job, err := releaser.Start(DeclinedLeasesFilter{SubnetID: 12345}, DeclinedLeasesReleaseDelete, nil)
+	require.NoError(t, err)
+	job.Wait()
+
+	status := job.GetStatus()
+	require.Contains(t, status.Error, "subnet with ID 12345 not found")
+	require.Empty(t, agents.RecordedCommands)
+}
@@ -0,0 +1,270 @@
+package kea
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	dbops "isc.org/stork/server/database"
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/eventcenter"
+	storkutil "isc.org/stork/util"
+)
+
+const (
+	// Time range of the hourly utilization history used to compute the
+	// utilization trends.
+	exhaustionTrendRange = 14 * 24 * time.Hour
+	// Minimum number of the hourly periods required to compute the trend.
+	exhaustionTrendMinPeriods = 24
+	// The exhaustion forecast later than this time from now is dropped.
+	exhaustionForecastHorizon = 365 * 24 * time.Hour
+)
+
+// Forecasts the exhaustion time assuming the utilization keeps growing at
+// the current rate. The utilization is expressed in permille and the slope
+// in permille per second. It returns the zero time if the utilization is
+// not growing or the exhaustion is beyond the forecast horizon.
+func forecastExhaustion(utilization int16, slope float64, now time.Time) time.Time {
+	if utilization >= 1000 {
+		return now
+	}
+	if slope <= 0 || math.IsNaN(slope) {
+		return time.Time{}
+	}
+	seconds := float64(1000-utilization) / slope
+	if seconds > exhaustionForecastHorizon.Seconds() {
+		return time.Time{}
+	}
+	return now.Add(time.Duration(seconds * float64(time.Second)))
+}
+
+// Computes the exhaustion forecasts of the subnets, shared networks and
+// prefix pools and raises or clears the alerts when the forecast
+// exhaustion enters or leaves the configured window. The alert is cleared
+// when the forecast exhaustion is later than the window extended by a
+// quarter to avoid a flood of events when the forecast oscillates around
+// the window boundary.
+type exhaustionForecaster struct {
+	db          dbops.DBI
+	eventCenter eventcenter.EventCenter
+	now         time.Time
+	window      time.Duration
+	trends      map[utilizationAlertKey]*dbmodel.UtilizationTrend
+	alerts      map[utilizationAlertKey]*dbmodel.UtilizationAlert
+}
+
+// Creates the forecaster. It fetches the forecast window, the utilization
+// trends and the currently raised exhaustion alerts from the database.
+func newExhaustionForecaster(db *dbops.PgDB, eventCenter eventcenter.EventCenter, now time.Time) (*exhaustionForecaster, error) {
+	window, err := dbmodel.GetSettingInt(db, "exhaustion_forecast_window")
+	if err != nil {
+		return nil, err
+	}
+	forecaster := &exhaustionForecaster{
+		db:          db,
+		eventCenter: eventCenter,
+		now:         now,
+		window:      time.Duration(window) * 24 * time.Hour,
+		trends:      make(map[utilizationAlertKey]*dbmodel.UtilizationTrend),
+		alerts:      make(map[utilizationAlertKey]*dbmodel.UtilizationAlert),
+	}
+
+	trends, err := dbmodel.GetUtilizationTrends(db, now.Add(-exhaustionTrendRange), exhaustionTrendMinPeriods)
+	if err != nil {
+		return nil, err
+	}
+	for i := range trends {
+		key := utilizationAlertKey{
+			subnetID:        trends[i].SubnetID,
+			sharedNetworkID: trends[i].SharedNetworkID,
+			prefixPoolID:    trends[i].PrefixPoolID,
+		}
+		forecaster.trends[key] = &trends[i]
+	}
+
+	alerts, err := dbmodel.GetAllUtilizationAlerts(db)
+	if err != nil {
+		return nil, err
+	}
+	for i := range alerts {
+		if alerts[i].Kind != dbmodel.UtilizationAlertKindAddressExhaustion &&
+			alerts[i].Kind != dbmodel.UtilizationAlertKindDelegatedPrefixExhaustion {
+			continue
+		}
+		key := utilizationAlertKey{alerts[i].SubnetID, alerts[i].SharedNetworkID, alerts[i].PrefixPoolID, alerts[i].Kind}
+		forecaster.alerts[key] = &alerts[i]
+	}
+	return forecaster, nil
+}
+
+// Returns the address and delegated prefix exhaustion forecasts for the
+// utilizations of the subnet or shared network.
+func (f *exhaustionForecaster) forecast(subnetID, sharedNetworkID int64, addrUtilization, pdUtilization int16) (time.Time, time.Time) {
+	var addrSlope, pdSlope float64
+	if trend, ok := f.trends[utilizationAlertKey{subnetID: subnetID, sharedNetworkID: sharedNetworkID}]; ok {
+		addrSlope = trend.AddrSlope
+		pdSlope = trend.PdSlope
+	}
+	return forecastExhaustion(addrUtilization, addrSlope, f.now), forecastExhaustion(pdUtilization, pdSlope, f.now)
+}
+
+// Forecasts the exhaustion of the subnet, stores the forecast and updates
+// the exhaustion alerts.
+func (f *exhaustionForecaster) forecastSubnet(subnet *dbmodel.Subnet) error {
+	addrExhaustionAt, pdExhaustionAt := f.forecast(subnet.ID, 0, subnet.AddrUtilization, subnet.PdUtilization)
+	if err := subnet.UpdateExhaustionForecast(f.db, addrExhaustionAt, pdExhaustionAt); err != nil {
+		return err
+	}
+	describe := func() (string, []any) {
+		return "{subnet}", []any{subnet}
+	}
+	err := f.evaluate(utilizationAlertKey{subnetID: subnet.ID, kind: dbmodel.UtilizationAlertKindAddressExhaustion},
+		addrExhaustionAt, subnet.AddrUtilization, describe)
+	if err != nil {
+		return err
+	}
+	return f.evaluate(utilizationAlertKey{subnetID: subnet.ID, kind: dbmodel.UtilizationAlertKindDelegatedPrefixExhaustion},
+		pdExhaustionAt, subnet.PdUtilization, describe)
+}
+
+// Forecasts the exhaustion of the shared network, stores the forecast and
+// updates the exhaustion alerts. The utilizations are expressed in
+// permille.
+func (f *exhaustionForecaster) forecastSharedNetwork(sharedNetworkID int64, addrUtilization, pdUtilization int16) error {
+	addrExhaustionAt, pdExhaustionAt := f.forecast(0, sharedNetworkID, addrUtilization, pdUtilization)
+	if err := dbmodel.UpdateExhaustionForecastInSharedNetwork(f.db, sharedNetworkID, addrExhaustionAt, pdExhaustionAt); err != nil {
+		return err
+	}
+	describe := func() (string, []any) {
+		sharedNetwork, err := dbmodel.GetSharedNetwork(f.db, sharedNetworkID)
+		if err != nil || sharedNetwork == nil {
+			return fmt.Sprintf("shared network with ID %d", sharedNetworkID), nil
+		}
+		return fmt.Sprintf("shared network %s", sharedNetwork.Name), nil
+	}
+	err := f.evaluate(utilizationAlertKey{sharedNetworkID: sharedNetworkID, kind: dbmodel.UtilizationAlertKindAddressExhaustion},
+		addrExhaustionAt, addrUtilization, describe)
+	if err != nil {
+		return err
+	}
+	return f.evaluate(utilizationAlertKey{sharedNetworkID: sharedNetworkID, kind: dbmodel.UtilizationAlertKindDelegatedPrefixExhaustion},
+		pdExhaustionAt, pdUtilization, describe)
+}
+
+// Forecasts the delegated prefix exhaustion of the prefix pool, stores the
+// forecast and updates the exhaustion alert.
+func (f *exhaustionForecaster) forecastPrefixPool(pool *dbmodel.PrefixPool) error {
+	var pdSlope float64
+	if trend, ok := f.trends[utilizationAlertKey{prefixPoolID: pool.ID}]; ok {
+		pdSlope = trend.PdSlope
+	}
+	pdExhaustionAt := forecastExhaustion(pool.PdUtilization, pdSlope, f.now)
+	if err := pool.UpdateExhaustionForecast(f.db, pdExhaustionAt); err != nil {
+		return err
+	}
+	describe := func() (string, []any) {
+		if pool.LocalSubnet == nil || pool.LocalSubnet.Subnet == nil || pool.LocalSubnet.Daemon == nil {
+			return fmt.Sprintf("PD pool %s", pool.Prefix), nil
+		}
+		return fmt.Sprintf("PD pool %s of {subnet} on {daemon}", pool.Prefix),
+			[]any{pool.LocalSubnet.Subnet, pool.LocalSubnet.Daemon}
+	}
+	return f.evaluate(utilizationAlertKey{prefixPoolID: pool.ID, kind: dbmodel.UtilizationAlertKindDelegatedPrefixExhaustion},
+		pdExhaustionAt, pool.PdUtilization, describe)
+}
+
+// Compares the forecast exhaustion with the window and raises or clears
+// the alert. The describe function returns the subject of the event text
+// and the objects passed to the event center. It is called only if the
+// alert is raised or cleared.
+func (f *exhaustionForecaster) evaluate(key utilizationAlertKey, exhaustionAt time.Time, utilization int16, describe func() (string, []any)) error {
+	alert := f.alerts[key]
+	window := f.window
+	if alert != nil {
+		window += window / 4
+	}
+	within := f.window > 0 && !exhaustionAt.IsZero() && !exhaustionAt.After(f.now.Add(window))
+
+	resource := "Addresses"
+	if key.kind == dbmodel.UtilizationAlertKindDelegatedPrefixExhaustion {
+		resource = "Delegated prefixes"
+	}
+
+	switch {
+	case within && alert == nil:
+		alert = &dbmodel.UtilizationAlert{
+			SubnetID:        key.subnetID,
+			SharedNetworkID: key.sharedNetworkID,
+			PrefixPoolID:    key.prefixPoolID,
+			Kind:            key.kind,
+			Level:           dbmodel.EvWarning,
+			Utilization:     utilization,
+		}
+		if err := dbmodel.SetUtilizationAlert(f.db, alert); err != nil {
+			return err
+		}
+		f.alerts[key] = alert
+
+		subject, objects := describe()
+		days := int(exhaustionAt.Sub(f.now).Hours() / 24)
+		var text string
+		if days < 1 {
+			text = fmt.Sprintf("%s in %s are forecast to run out within a day at the current utilization growth; the utilization is %.1f%%",
+				resource, subject, float64(utilization)/10)
+		} else {
+			text = fmt.Sprintf("%s in %s are forecast to run out in %d days, on %s, at the current utilization growth; the utilization is %.1f%%",
+				resource, subject, days, exhaustionAt.Format(time.DateOnly), float64(utilization)/10)
+		}
+		f.eventCenter.AddWarningEvent(text, objects...)
+
+	case within && alert.Utilization != utilization:
+		alert.Utilization = utilization
+		return dbmodel.SetUtilizationAlert(f.db, alert)
+
+	case !within && alert != nil:
+		if err := dbmodel.DeleteUtilizationAlert(f.db, alert.ID); err != nil {
+			return err
+		}
+		delete(f.alerts, key)
+
+		subject, objects := describe()
+		text := fmt.Sprintf("%s in %s are no longer forecast to run out within the exhaustion forecast window",
+			resource, subject)
+		f.eventCenter.AddInfoEvent(text, objects...)
+	}
+	return nil
+}
+
+// Forecasts the exhaustion of the subnets, shared networks and prefix
+// pools whose statistics have been updated. It returns the last error.
+func (statsPuller *StatsPuller) forecastExhaustion(subnets []*dbmodel.Subnet, prefixPools []*dbmodel.PrefixPool, sharedNetworks map[int64]*sharedNetworkStats) error {
+	forecaster, err := newExhaustionForecaster(statsPuller.DB, statsPuller.EventCenter, storkutil.UTCNow())
+	if err != nil {
+		return errors.WithMessage(err, "cannot forecast exhaustion")
+	}
+	var lastErr error
+	for _, sn := range subnets {
+		if err := forecaster.forecastSubnet(sn); err != nil {
+			lastErr = err
+			log.WithError(err).Errorf("Cannot forecast exhaustion for subnet %d", sn.ID)
+		}
+	}
+	for id, stats := range sharedNetworks {
+		addrUtilization := toPermille(stats.GetAddressUtilization())
+		pdUtilization := toPermille(stats.GetDelegatedPrefixUtilization())
+		if err := forecaster.forecastSharedNetwork(id, addrUtilization, pdUtilization); err != nil {
+			lastErr = err
+			log.WithError(err).Errorf("Cannot forecast exhaustion for shared network %d", id)
+		}
+	}
+	for _, pool := range prefixPools {
+		if err := forecaster.forecastPrefixPool(pool); err != nil {
+			lastErr = err
+			log.WithError(err).Errorf("Cannot forecast exhaustion for prefix pool %d", pool.ID)
+		}
+	}
+	return lastErr
+}
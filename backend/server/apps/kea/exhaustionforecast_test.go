@@ -0,0 +1,307 @@
+package kea
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	dbops "isc.org/stork/server/database"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	storktest "isc.org/stork/server/test/dbmodel"
+)
+
+// Test that the exhaustion is forecast from the current utilization and
+// its growth rate.
+func TestForecastExhaustion(t *testing.T) {
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	// 10 permille per day.
+	slope := 10.0 / (24 * 3600)
+
+	require.Equal(t, now.Add(50*24*time.Hour), forecastExhaustion(500, slope, now))
+	require.Equal(t, now, forecastExhaustion(1000, 0, now))
+	require.Zero(t, forecastExhaustion(500, 0, now))
+	require.Zero(t, forecastExhaustion(500, -slope, now))
+	// Beyond the horizon.
+	require.Zero(t, forecastExhaustion(0, slope/10, now))
+}
+
+// Adds the hourly utilization history of the subnet growing by the
+// specified number of permille per hour and ending at the specified time.
+func addGrowingUtilizationHistory(t *testing.T, db dbops.DBI, subnetID int64, end time.Time, start, growth int16, hours int) {
+	for i := 0; i < hours; i++ {
+		err := dbmodel.AddUtilizationSamples(db, []dbmodel.UtilizationSample{{
+			SubnetID:        subnetID,
+			Family:          4,
+			AddrUtilization: start + int16(i)*growth,
+		}}, end.Add(-time.Duration(hours-i)*time.Hour))
+		require.NoError(t, err)
+	}
+}
+
+// Test that the exhaustion alert is raised when the exhaustion is forecast
+// within the window and cleared when the utilization stops growing.
+func TestForecastSubnetExhaustion(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	err := dbmodel.InitializeSettings(db, 0)
+	require.NoError(t, err)
+
+	subnet := &dbmodel.Subnet{
+		Prefix: "192.0.2.0/24",
+	}
+	err = dbmodel.AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	// The utilization grows by 1 permille per hour, so it takes 400 hours
+	// to exhaust the remaining 40%, i.e., less than 17 days.
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	addGrowingUtilizationHistory(t, db, subnet.ID, now, 552, 1, 48)
+	subnet.AddrUtilization = 600
+
+	fec := &storktest.FakeEventCenter{}
+	forecaster, err := newExhaustionForecaster(db, fec, now)
+	require.NoError(t, err)
+	err = forecaster.forecastSubnet(subnet)
+	require.NoError(t, err)
+
+	require.WithinDuration(t, now.Add(400*time.Hour), subnet.AddrExhaustionAt, time.Minute)
+	require.Zero(t, subnet.PdExhaustionAt)
+
+	alerts, err := dbmodel.GetAllUtilizationAlerts(db)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	require.Equal(t, dbmodel.UtilizationAlertKindAddressExhaustion, alerts[0].Kind)
+	require.Equal(t, dbmodel.EvWarning, alerts[0].Level)
+	require.NotNil(t, alerts[0].Subnet)
+	require.WithinDuration(t, now.Add(400*time.Hour), alerts[0].Subnet.AddrExhaustionAt, time.Minute)
+
+	require.Len(t, fec.Events, 1)
+	require.Equal(t, dbmodel.EvWarning, fec.Events[0].Level)
+	require.Contains(t, fec.Events[0].Text, "Addresses in <subnet")
+	require.Contains(t, fec.Events[0].Text, "are forecast to run out in 16 days, on 2024-05-17")
+	require.Equal(t, subnet.ID, fec.Events[0].Relations.SubnetID)
+
+	// The forecast is repeated but no new event is expected.
+	forecaster, err = newExhaustionForecaster(db, fec, now)
+	require.NoError(t, err)
+	err = forecaster.forecastSubnet(subnet)
+	require.NoError(t, err)
+	require.Len(t, fec.Events, 1)
+
+	// Shrinking the window below the forecast keeps the alert within the
+	// hysteresis.
+	err = dbmodel.SetSettingInt(db, "exhaustion_forecast_window", 14)
+	require.NoError(t, err)
+	forecaster, err = newExhaustionForecaster(db, fec, now)
+	require.NoError(t, err)
+	err = forecaster.forecastSubnet(subnet)
+	require.NoError(t, err)
+	require.Len(t, fec.Events, 1)
+
+	// The alert is cleared when the forecast is far beyond the window.
+	err = dbmodel.SetSettingInt(db, "exhaustion_forecast_window", 7)
+	require.NoError(t, err)
+	forecaster, err = newExhaustionForecaster(db, fec, now)
+	require.NoError(t, err)
+	err = forecaster.forecastSubnet(subnet)
+	require.NoError(t, err)
+
+	alerts, err = dbmodel.GetAllUtilizationAlerts(db)
+	require.NoError(t, err)
+	require.Empty(t, alerts)
+	require.Len(t, fec.Events, 2)
+	require.Equal(t, dbmodel.EvInfo, fec.Events[1].Level)
+	require.Contains(t, fec.Events[1].Text, "are no longer forecast to run out")
+}
+
+// Test that no exhaustion is forecast without enough history.
+func TestForecastSubnetExhaustionShortHistory(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	err := dbmodel.InitializeSettings(db, 0)
+	require.NoError(t, err)
+
+	subnet := &dbmodel.Subnet{
+		Prefix: "192.0.2.0/24",
+	}
+	err = dbmodel.AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	addGrowingUtilizationHistory(t, db, subnet.ID, now, 500, 10, exhaustionTrendMinPeriods-1)
+	subnet.AddrUtilization = 800
+
+	fec := &storktest.FakeEventCenter{}
+	forecaster, err := newExhaustionForecaster(db, fec, now)
+	require.NoError(t, err)
+	err = forecaster.forecastSubnet(subnet)
+	require.NoError(t, err)
+
+	require.Zero(t, subnet.AddrExhaustionAt)
+	require.Empty(t, fec.Events)
+}
+
+// Test that the exhaustion of the shared network is forecast.
+func TestForecastSharedNetworkExhaustion(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	err := dbmodel.InitializeSettings(db, 0)
+	require.NoError(t, err)
+
+	sharedNetwork := &dbmodel.SharedNetwork{
+		Name:   "frog",
+		Family: 6,
+	}
+	err = dbmodel.AddSharedNetwork(db, sharedNetwork)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 48; i++ {
+		err = dbmodel.AddUtilizationSamples(db, []dbmodel.UtilizationSample{{
+			SharedNetworkID: sharedNetwork.ID,
+			Family:          6,
+			PdUtilization:   int16(700 + i),
+		}}, now.Add(-time.Duration(48-i)*time.Hour))
+		require.NoError(t, err)
+	}
+
+	fec := &storktest.FakeEventCenter{}
+	forecaster, err := newExhaustionForecaster(db, fec, now)
+	require.NoError(t, err)
+	err = forecaster.forecastSharedNetwork(sharedNetwork.ID, 0, 748)
+	require.NoError(t, err)
+
+	sharedNetwork, err = dbmodel.GetSharedNetwork(db, sharedNetwork.ID)
+	require.NoError(t, err)
+	require.Zero(t, sharedNetwork.AddrExhaustionAt)
+	require.WithinDuration(t, now.Add(252*time.Hour), sharedNetwork.PdExhaustionAt, time.Minute)
+
+	require.Len(t, fec.Events, 1)
+	require.Contains(t, fec.Events[0].Text, "Delegated prefixes in shared network frog are forecast to run out in 10 days")
+}
+
+// Test that the exhaustion of the prefix pool is forecast from its own
+// utilization history and the alert is raised for the pool.
+func TestForecastPrefixPoolExhaustion(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	err := dbmodel.InitializeSettings(db, 0)
+	require.NoError(t, err)
+
+	machine := &dbmodel.Machine{Address: "localhost", AgentPort: 8080}
+	err = dbmodel.AddMachine(db, machine)
+	require.NoError(t, err)
+	app := &dbmodel.App{
+		MachineID: machine.ID,
+		Type:      dbmodel.AppTypeKea,
+		Active:    true,
+		Daemons: []*dbmodel.Daemon{
+			dbmodel.NewKeaDaemon(dbmodel.DaemonNameDHCPv6, true),
+		},
+	}
+	daemons, err := dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+
+	subnet := &dbmodel.Subnet{
+		Prefix: "2001:db8:1::/48",
+		LocalSubnets: []*dbmodel.LocalSubnet{
+			{
+				DaemonID:      daemons[0].ID,
+				LocalSubnetID: 1,
+			},
+		},
+	}
+	err = dbmodel.AddSubnet(db, subnet)
+	require.NoError(t, err)
+	err = dbmodel.AddLocalSubnets(db, subnet)
+	require.NoError(t, err)
+
+	// The subnet has two pools but only one of them is running out.
+	for _, prefix := range []string{"2001:db8:1:8000::/64", "2001:db8:1:9000::/64"} {
+		pool := &dbmodel.PrefixPool{
+			Prefix:        prefix,
+			DelegatedLen:  80,
+			LocalSubnetID: subnet.LocalSubnets[0].ID,
+		}
+		err = dbmodel.AddPrefixPool(db, pool)
+		require.NoError(t, err)
+	}
+	pools, err := dbmodel.GetAppPrefixPools(db, app.ID)
+	require.NoError(t, err)
+	require.Len(t, pools, 2)
+
+	// The utilization of the first pool grows by 2 permille per hour, so it
+	// takes 100 hours to exhaust the remaining 20%. The second pool is
+	// stable.
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 48; i++ {
+		err = dbmodel.AddUtilizationSamples(db, []dbmodel.UtilizationSample{
+			{PrefixPoolID: pools[0].ID, Family: 6, PdUtilization: int16(704 + 2*i)},
+			{PrefixPoolID: pools[1].ID, Family: 6, PdUtilization: 800},
+			{SubnetID: subnet.ID, Family: 6, PdUtilization: 800},
+		}, now.Add(-time.Duration(48-i)*time.Hour))
+		require.NoError(t, err)
+	}
+	for _, pool := range pools {
+		err = pool.UpdateUtilization(db, 800, now)
+		require.NoError(t, err)
+	}
+
+	pools, err = dbmodel.GetPrefixPoolsWithUtilization(db)
+	require.NoError(t, err)
+	require.Len(t, pools, 2)
+
+	fec := &storktest.FakeEventCenter{}
+	forecaster, err := newExhaustionForecaster(db, fec, now)
+	require.NoError(t, err)
+	err = forecaster.forecastSubnet(subnet)
+	require.NoError(t, err)
+	for _, pool := range pools {
+		err = forecaster.forecastPrefixPool(pool)
+		require.NoError(t, err)
+	}
+
+	// The subnet utilization doesn't grow.
+	require.Zero(t, subnet.PdExhaustionAt)
+	require.WithinDuration(t, now.Add(100*time.Hour), pools[0].PdExhaustionAt, time.Minute)
+	require.Zero(t, pools[1].PdExhaustionAt)
+
+	alerts, err := dbmodel.GetAllUtilizationAlerts(db)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	require.Equal(t, dbmodel.UtilizationAlertKindDelegatedPrefixExhaustion, alerts[0].Kind)
+	require.Equal(t, pools[0].ID, alerts[0].PrefixPoolID)
+	require.Zero(t, alerts[0].SubnetID)
+	require.NotNil(t, alerts[0].PrefixPool)
+	require.WithinDuration(t, now.Add(100*time.Hour), alerts[0].PrefixPool.PdExhaustionAt, time.Minute)
+	require.NotNil(t, alerts[0].PrefixPool.LocalSubnet)
+	require.NotNil(t, alerts[0].PrefixPool.LocalSubnet.Subnet)
+	require.Equal(t, subnet.Prefix, alerts[0].PrefixPool.LocalSubnet.Subnet.Prefix)
+
+	require.Len(t, fec.Events, 1)
+	require.Equal(t, dbmodel.EvWarning, fec.Events[0].Level)
+	require.Contains(t, fec.Events[0].Text, "Delegated prefixes in PD pool 2001:db8:1:8000::/64 of <subnet")
+	require.Contains(t, fec.Events[0].Text, "are forecast to run out in 4 days")
+	require.Equal(t, subnet.ID, fec.Events[0].Relations.SubnetID)
+	require.Equal(t, daemons[0].ID, fec.Events[0].Relations.DaemonID)
+
+	// The alert is cleared when the forecast is far beyond the window.
+	err = dbmodel.SetSettingInt(db, "exhaustion_forecast_window", 2)
+	require.NoError(t, err)
+	forecaster, err = newExhaustionForecaster(db, fec, now)
+	require.NoError(t, err)
+	err = forecaster.forecastPrefixPool(pools[0])
+	require.NoError(t, err)
+
+	alerts, err = dbmodel.GetAllUtilizationAlerts(db)
+	require.NoError(t, err)
+	require.Empty(t, alerts)
+	require.Len(t, fec.Events, 2)
+	require.Equal(t, dbmodel.EvInfo, fec.Events[1].Level)
+	require.Contains(t, fec.Events[1].Text, "Delegated prefixes in PD pool 2001:db8:1:8000::/64")
+}
@@ -0,0 +1,171 @@
+package kea
+
+import (
+	"github.com/pkg/errors"
+	keaconfig "isc.org/stork/appcfg/kea"
+	keactrl "isc.org/stork/appctrl/kea"
+	"isc.org/stork/server/agentcomm"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// An action controlling the HA state machine of a Kea server.
+type HAAction string
+
+// Supported HA actions.
+const (
+	HAActionMaintenanceStart  HAAction = "maintenance-start"
+	HAActionMaintenanceCancel HAAction = "maintenance-cancel"
+	HAActionSync              HAAction = "sync"
+	HAActionContinue          HAAction = "continue"
+	HAActionScopes            HAAction = "scopes"
+	HAActionReset             HAAction = "reset"
+)
+
+// Checks if the HA action is supported.
+func (action HAAction) IsValid() bool {
+	switch action {
+	case HAActionMaintenanceStart, HAActionMaintenanceCancel, HAActionSync,
+		HAActionContinue, HAActionScopes, HAActionReset:
+		return true
+	default:
+		return false
+	}
+}
+
+// Optional parameters of the HA actions. The scopes are only used by the
+// scopes action and the max period by the sync action.
+type HAActionParams struct {
+	Scopes    []string
+	MaxPeriod int64
+}
+
+// Checks if the daemon belongs to the HA service, i.e., it is the primary,
+// secondary or one of the backup servers.
+func IsHAServiceDaemon(service *dbmodel.Service, daemonID int64) bool {
+	if service == nil || service.HAService == nil || daemonID == 0 {
+		return false
+	}
+	ha := service.HAService
+	if ha.PrimaryID == daemonID || ha.SecondaryID == daemonID {
+		return true
+	}
+	for _, id := range ha.BackupID {
+		if id == daemonID {
+			return true
+		}
+	}
+	return false
+}
+
+// Finds the HA relationship configured in the daemon matching the HA
+// service. It returns the relationship and the total number of the
+// relationships in which the daemon participates.
+func findHARelationship(daemon *dbmodel.Daemon, service *dbmodel.Service) (*keaconfig.HA, int, error) {
+	if daemon.KeaDaemon == nil || daemon.KeaDaemon.Config == nil {
+		return nil, 0, errors.Errorf("configuration of daemon %d not found", daemon.ID)
+	}
+	_, params, ok := daemon.KeaDaemon.Config.GetHookLibraries().GetHAHookLibrary()
+	if !ok {
+		return nil, 0, errors.Errorf("daemon %d lacks the HA hooks library", daemon.ID)
+	}
+	relationships := params.GetAllRelationships()
+	for i := range relationships {
+		if !relationships[i].IsValid() {
+			continue
+		}
+		for _, peer := range relationships[i].Peers {
+			if *peer.Name == service.HAService.Relationship {
+				return &relationships[i], len(relationships), nil
+			}
+		}
+	}
+	return nil, 0, errors.Errorf("HA relationship %s not found in the configuration of daemon %d", service.HAService.Relationship, daemon.ID)
+}
+
+// Returns the name of the partner of the server within the HA
+// relationship. The partner of the primary server is the secondary or
+// standby server and vice versa. The partner of a backup server is the
+// primary server.
+func getHAPartnerName(relationship *keaconfig.HA) string {
+	var thisRole string
+	for _, peer := range relationship.Peers {
+		if *peer.Name == *relationship.ThisServerName {
+			thisRole = *peer.Role
+			break
+		}
+	}
+	for _, peer := range relationship.Peers {
+		if *peer.Name == *relationship.ThisServerName {
+			continue
+		}
+		switch *peer.Role {
+		case "primary":
+			return *peer.Name
+		case "secondary", "standby":
+			if thisRole == "primary" {
+				return *peer.Name
+			}
+		}
+	}
+	return ""
+}
+
+// Creates the command for the HA action. The server name is included in
+// the commands only when the daemon participates in several relationships
+// (i.e., in the hub-and-spoke configuration). The ha-sync command always
+// includes the name of the partner from which the leases are fetched.
+func newHAActionCommand(daemon *dbmodel.Daemon, service *dbmodel.Service, action HAAction, params *HAActionParams) (*keactrl.Command, error) {
+	if params == nil {
+		params = &HAActionParams{}
+	}
+	relationship, count, err := findHARelationship(daemon, service)
+	if err != nil {
+		return nil, err
+	}
+	serverName := ""
+	if count > 1 {
+		serverName = *relationship.ThisServerName
+	}
+	daemonName := keactrl.DaemonName(daemon.Name)
+	switch action {
+	case HAActionMaintenanceStart:
+		return keactrl.NewCommandHAMaintenanceStart(serverName, daemonName), nil
+	case HAActionMaintenanceCancel:
+		return keactrl.NewCommandHAMaintenanceCancel(serverName, daemonName), nil
+	case HAActionContinue:
+		return keactrl.NewCommandHAContinue(serverName, daemonName), nil
+	case HAActionReset:
+		return keactrl.NewCommandHAReset(serverName, daemonName), nil
+	case HAActionScopes:
+		return keactrl.NewCommandHAScopes(params.Scopes, serverName, daemonName), nil
+	case HAActionSync:
+		partnerName := getHAPartnerName(relationship)
+		if partnerName == "" {
+			return nil, errors.Errorf("partner of %s not found in HA relationship", *relationship.ThisServerName)
+		}
+		return keactrl.NewCommandHASync(partnerName, params.MaxPeriod, daemonName), nil
+	default:
+		return nil, errors.Errorf("unsupported HA action %s", action)
+	}
+}
+
+// Sends the command controlling the HA state machine to the daemon
+// belonging to the HA service. It returns the sent command and its result.
+// The returned result is non-nil when the command was sent, even if an
+// error is returned.
+func RunHAAction(agents agentcomm.ConnectedAgents, service *dbmodel.Service, daemon *dbmodel.Daemon, action HAAction, params *HAActionParams) (*dbmodel.AuditCommand, error) {
+	if service == nil || service.HAService == nil {
+		return nil, errors.New("HA actions can only be run for an HA service")
+	}
+	if daemon.App == nil {
+		return nil, errors.Errorf("daemon %d lacks the app", daemon.ID)
+	}
+	if !IsHAServiceDaemon(service, daemon.ID) {
+		return nil, errors.Errorf("daemon %d does not belong to HA service %d", daemon.ID, service.ID)
+	}
+	command, err := newHAActionCommand(daemon, service, action, params)
+	if err != nil {
+		return nil, err
+	}
+	return sendDaemonCommand(agents, daemon, command)
+}
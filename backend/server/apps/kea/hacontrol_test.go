@@ -0,0 +1,221 @@
+package kea
+
+import (
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+
+	keactrl "isc.org/stork/appctrl/kea"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Creates a DHCPv4 daemon with the HA hooks library configured. The
+// daemon is the server1 in the relationship with server2 and optionally
+// in another relationship with server3.
+func newTestHADaemon(t *testing.T, hubAndSpoke bool) *dbmodel.Daemon {
+	accessPoints := []*dbmodel.AccessPoint{}
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "localhost", "", 8000, false)
+	daemon := dbmodel.NewKeaDaemon(dbmodel.DaemonNameDHCPv4, true)
+	daemon.ID = 2
+	daemon.App = &dbmodel.App{
+		ID:           1,
+		Name:         "kea@localhost",
+		AccessPoints: accessPoints,
+	}
+	relationships := `{
+		"this-server-name": "server1",
+		"mode": "hot-standby",
+		"peers": [
+			{ "name": "server1", "url": "http://192.0.2.1:8000", "role": "primary" },
+			{ "name": "server2", "url": "http://192.0.2.2:8000", "role": "standby" },
+			{ "name": "server4", "url": "http://192.0.2.4:8000", "role": "backup" }
+		]
+	}`
+	if hubAndSpoke {
+		relationships += `, {
+			"this-server-name": "server1",
+			"mode": "hot-standby",
+			"peers": [
+				{ "name": "server3", "url": "http://192.0.2.3:8000", "role": "primary" },
+				{ "name": "server1", "url": "http://192.0.2.1:8000", "role": "standby" }
+			]
+		}`
+	}
+	err := daemon.SetConfigFromJSON(`{
+		"Dhcp4": {
+			"hooks-libraries": [
+				{
+					"library": "/usr/lib/kea/libdhcp_ha.so",
+					"parameters": {
+						"high-availability": [` + relationships + `]
+					}
+				}
+			]
+		}
+	}`)
+	require.NoError(t, err)
+	return daemon
+}
+
+// Creates an HA service with the specified relationship and the primary
+// server being the test daemon.
+func newTestHAService(relationship string) *dbmodel.Service {
+	return &dbmodel.Service{
+		BaseService: dbmodel.BaseService{
+			ID: 5,
+		},
+		HAService: &dbmodel.BaseHAService{
+			HAType:       dbmodel.DaemonNameDHCPv4,
+			Relationship: relationship,
+			PrimaryID:    2,
+			SecondaryID:  3,
+			BackupID:     []int64{7},
+		},
+	}
+}
+
+// Test that the HA actions are validated.
+func TestHAActionIsValid(t *testing.T) {
+	require.True(t, HAActionMaintenanceStart.IsValid())
+	require.True(t, HAActionMaintenanceCancel.IsValid())
+	require.True(t, HAActionSync.IsValid())
+	require.True(t, HAActionContinue.IsValid())
+	require.True(t, HAActionScopes.IsValid())
+	require.True(t, HAActionReset.IsValid())
+	require.False(t, HAAction("restart").IsValid())
+}
+
+// Test checking if the daemon belongs to the HA service.
+func TestIsHAServiceDaemon(t *testing.T) {
+	service := newTestHAService("server1")
+	require.True(t, IsHAServiceDaemon(service, 2))
+	require.True(t, IsHAServiceDaemon(service, 3))
+	require.True(t, IsHAServiceDaemon(service, 7))
+	require.False(t, IsHAServiceDaemon(service, 4))
+	require.False(t, IsHAServiceDaemon(service, 0))
+	require.False(t, IsHAServiceDaemon(&dbmodel.Service{}, 2))
+	require.False(t, IsHAServiceDaemon(nil, 2))
+}
+
+// Test that the server name is not included in the commands when the
+// daemon participates in a single relationship, except the ha-sync
+// command which always includes the partner's name.
+func TestNewHAActionCommandSingleRelationship(t *testing.T) {
+	daemon := newTestHADaemon(t, false)
+	service := newTestHAService("server2")
+
+	command, err := newHAActionCommand(daemon, service, HAActionMaintenanceStart, nil)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"command": "ha-maintenance-start",
+		"service": ["dhcp4"]
+	}`, command.Marshal())
+
+	command, err = newHAActionCommand(daemon, service, HAActionSync, &HAActionParams{MaxPeriod: 60})
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"command": "ha-sync",
+		"service": ["dhcp4"],
+		"arguments": {
+			"server-name": "server2",
+			"max-period": 60
+		}
+	}`, command.Marshal())
+
+	command, err = newHAActionCommand(daemon, service, HAActionScopes, &HAActionParams{Scopes: []string{"server1", "server2"}})
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"command": "ha-scopes",
+		"service": ["dhcp4"],
+		"arguments": {
+			"scopes": ["server1", "server2"]
+		}
+	}`, command.Marshal())
+
+	_, err = newHAActionCommand(daemon, service, HAAction("restart"), nil)
+	require.Error(t, err)
+}
+
+// Test that the server name is included in the commands when the daemon
+// participates in several relationships.
+func TestNewHAActionCommandHubAndSpoke(t *testing.T) {
+	daemon := newTestHADaemon(t, true)
+	service := newTestHAService("server3")
+
+	command, err := newHAActionCommand(daemon, service, HAActionContinue, nil)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"command": "ha-continue",
+		"service": ["dhcp4"],
+		"arguments": {
+			"server-name": "server1"
+		}
+	}`, command.Marshal())
+
+	// The server1 is the standby server in this relationship, so the
+	// leases are fetched from the primary.
+	command, err = newHAActionCommand(daemon, service, HAActionSync, nil)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"command": "ha-sync",
+		"service": ["dhcp4"],
+		"arguments": {
+			"server-name": "server3"
+		}
+	}`, command.Marshal())
+
+	// The relationship does not exist in the daemon's configuration.
+	_, err = newHAActionCommand(daemon, newTestHAService("server5"), HAActionReset, nil)
+	require.Error(t, err)
+}
+
+// Test that an error is returned when the daemon lacks the HA hooks
+// library.
+func TestNewHAActionCommandNoHAHook(t *testing.T) {
+	daemon := newTestLeaseDaemon(t, dbmodel.DaemonNameDHCPv4, true)
+	_, err := newHAActionCommand(daemon, newTestHAService("server1"), HAActionReset, nil)
+	require.ErrorContains(t, err, "lacks the HA hooks library")
+}
+
+// Test sending the HA action to the daemon.
+func TestRunHAAction(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(mockLeaseCommandResult(0, "Server transitioned to the in-maintenance state."), nil)
+	daemon := newTestHADaemon(t, false)
+
+	result, err := RunHAAction(agents, newTestHAService("server1"), daemon, HAActionMaintenanceStart, nil)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	require.Zero(t, result.Result)
+	require.Equal(t, "Server transitioned to the in-maintenance state.", result.Text)
+	require.Len(t, agents.RecordedCommands, 1)
+	require.EqualValues(t, keactrl.HAMaintenanceStart, agents.RecordedCommands[0].GetCommand())
+}
+
+// Test that the error returned by Kea is propagated to the caller.
+func TestRunHAActionError(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(mockLeaseCommandResult(1, "Unable to transition the server."), nil)
+	daemon := newTestHADaemon(t, false)
+
+	result, err := RunHAAction(agents, newTestHAService("server1"), daemon, HAActionReset, nil)
+	require.ErrorContains(t, err, "Unable to transition the server.")
+	require.NotNil(t, result)
+	require.EqualValues(t, 1, result.Result)
+}
+
+// Test that the HA action is not sent to the daemon which does not belong
+// to the service.
+func TestRunHAActionWrongDaemon(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(nil, nil)
+	daemon := newTestHADaemon(t, false)
+	daemon.ID = 4
+
+	result, err := RunHAAction(agents, newTestHAService("server1"), daemon, HAActionReset, nil)
+	require.Error(t, err)
+	require.Nil(t, result)
+	require.Empty(t, agents.RecordedCommands)
+
+	_, err = RunHAAction(agents, &dbmodel.Service{}, daemon, HAActionReset, nil)
+	require.Error(t, err)
+	require.Empty(t, agents.RecordedCommands)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net"
 	"reflect"
+	"time"
 
 	errors "github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -270,12 +271,7 @@ func GetLeases6ByHostname(agents agentcomm.ConnectedAgents, dbApp *dbmodel.App,
 // has the libdhcp_lease_cmds hooks library configured.
 func hasLeaseCmdsHook(app *dbmodel.App, daemonName string) bool {
 	daemon := app.GetDaemonByName(daemonName)
-	if daemon != nil && daemon.KeaDaemon != nil && daemon.KeaDaemon.Config != nil {
-		if _, _, ok := daemon.KeaDaemon.Config.GetHookLibrary("libdhcp_lease_cmds"); ok {
-			return true
-		}
-	}
-	return false
+	return daemon != nil && daemonHasLeaseCmdsHook(daemon)
 }
 
 // Attempts to find a lease on the Kea servers by specified text.
@@ -459,6 +455,217 @@ func FindDeclinedLeases(db *dbops.PgDB, agents agentcomm.ConnectedAgents) (lease
 	return leases, erredApps, nil
 }
 
+// Number of leases fetched in a single lease4-get-page or lease6-get-page
+// command.
+const leasesPageSize = 1000
+
+// Criteria for searching the leases on the Kea servers. Kea can only
+// select the leases by the local subnet IDs, so the leases are also
+// filtered by the Stork server. The zero values of the fields match all
+// leases.
+type LeasesFilter struct {
+	// Prefix to which the leased address or delegated prefix belongs.
+	Subnet *net.IPNet
+	// Lease state, e.g., keadata.LeaseStateDeclined.
+	State *int
+	// If positive, only the leases expiring within this time from now
+	// are matched. The expired leases are not matched.
+	ExpiresWithin time.Duration
+}
+
+// Checks if the lease matches the filter. The lease expiration time is
+// calculated from the client last transaction time and the valid lifetime.
+func (filter *LeasesFilter) Matches(lease *dbmodel.Lease, now time.Time) bool {
+	if filter.Subnet != nil {
+		ip := net.ParseIP(lease.IPAddress)
+		if ip == nil || !filter.Subnet.Contains(ip) {
+			return false
+		}
+	}
+	if filter.State != nil && lease.State != *filter.State {
+		return false
+	}
+	if filter.ExpiresWithin > 0 {
+		expire := time.Unix(int64(lease.CLTT)+int64(lease.ValidLifetime), 0)
+		if expire.Before(now) || expire.After(now.Add(filter.ExpiresWithin)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Fetches all leases from the DHCPv4 or DHCPv6 server page by page and
+// returns those matching the filter. The command name must be
+// lease4-get-page or lease6-get-page.
+func getLeasesPageByPage(agents agentcomm.ConnectedAgents, dbApp *dbmodel.App, commandName keactrl.CommandName, filter *LeasesFilter, now time.Time) (leases []dbmodel.Lease, err error) {
+	from := ""
+	for {
+		var command *keactrl.Command
+		if commandName == keactrl.Lease4GetPage {
+			command = keactrl.NewCommandLease4GetPage(from, leasesPageSize, keactrl.DHCPv4)
+		} else {
+			command = keactrl.NewCommandLease6GetPage(from, leasesPageSize, keactrl.DHCPv6)
+		}
+		response := make([]LeaseGetMultipleResponse, 1)
+		respResult, err := agents.ForwardToKeaOverHTTP(context.Background(), dbApp, []keactrl.SerializableCommand{command}, &response)
+		if err != nil {
+			return nil, err
+		}
+		if respResult.Error != nil {
+			return nil, respResult.Error
+		}
+		if len(response) == 0 {
+			return nil, errors.Errorf("invalid response to %s command received", commandName)
+		}
+		// There are no more leases.
+		if response[0].Result == keactrl.ResponseEmpty {
+			break
+		}
+		if err = validateGetLeasesResponse(commandName, response[0].Result, response[0].Arguments); err != nil {
+			return nil, err
+		}
+		page := response[0].Arguments.Leases
+		for i := range page {
+			page[i].AppID = dbApp.ID
+			page[i].App = dbApp
+			if filter.Matches(&page[i], now) {
+				leases = append(leases, page[i])
+			}
+		}
+		if len(page) < leasesPageSize {
+			break
+		}
+		from = page[len(page)-1].IPAddress
+	}
+	return leases, nil
+}
+
+// Fetches the leases belonging to the subnets with the specified local
+// subnet IDs from the DHCPv4 or DHCPv6 server and returns those matching
+// the filter. The command name must be lease4-get-all or lease6-get-all.
+func getSubnetLeases(agents agentcomm.ConnectedAgents, dbApp *dbmodel.App, commandName keactrl.CommandName, subnetIDs []int64, filter *LeasesFilter, now time.Time) (leases []dbmodel.Lease, err error) {
+	var command *keactrl.Command
+	if commandName == keactrl.Lease4GetAll {
+		command = keactrl.NewCommandLease4GetAll(subnetIDs, keactrl.DHCPv4)
+	} else {
+		command = keactrl.NewCommandLease6GetAll(subnetIDs, keactrl.DHCPv6)
+	}
+	response := make([]LeaseGetMultipleResponse, 1)
+	respResult, err := agents.ForwardToKeaOverHTTP(context.Background(), dbApp, []keactrl.SerializableCommand{command}, &response)
+	if err != nil {
+		return nil, err
+	}
+	if respResult.Error != nil {
+		return nil, respResult.Error
+	}
+	if len(response) == 0 {
+		return nil, errors.Errorf("invalid response to %s command received", commandName)
+	}
+	// There are no leases in the subnets.
+	if response[0].Result == keactrl.ResponseEmpty {
+		return nil, nil
+	}
+	if err = validateGetLeasesResponse(commandName, response[0].Result, response[0].Arguments); err != nil {
+		return nil, err
+	}
+	for _, lease := range response[0].Arguments.Leases {
+		lease.AppID = dbApp.ID
+		lease.App = dbApp
+		if filter.Matches(&lease, now) {
+			leases = append(leases, lease)
+		}
+	}
+	return leases, nil
+}
+
+// Returns the local subnet IDs of the subnets overlapping with the prefix,
+// i.e., the subnets within the prefix and the subnet containing it. The IDs
+// are grouped by the ID of the daemon owning the subnets.
+func getLocalSubnetIDsByPrefix(db *dbops.PgDB, prefix *net.IPNet) (map[int64][]int64, error) {
+	family := 6
+	if prefix.IP.To4() != nil {
+		family = 4
+	}
+	subnets, err := dbmodel.GetAllSubnets(db, family)
+	if err != nil {
+		return nil, err
+	}
+	localSubnetIDs := make(map[int64][]int64)
+	for _, subnet := range subnets {
+		_, subnetPrefix, err := net.ParseCIDR(subnet.Prefix)
+		if err != nil {
+			continue
+		}
+		if !prefix.Contains(subnetPrefix.IP) && !subnetPrefix.Contains(prefix.IP) {
+			continue
+		}
+		for _, localSubnet := range subnet.LocalSubnets {
+			localSubnetIDs[localSubnet.DaemonID] = append(localSubnetIDs[localSubnet.DaemonID], localSubnet.LocalSubnetID)
+		}
+	}
+	return localSubnetIDs, nil
+}
+
+// Attempts to find the leases matching the filter on the Kea servers
+// having the libdhcp_lease_cmds hooks library. When the filter specifies
+// a prefix, the function finds the subnets overlapping with it in the
+// database and fetches their leases using the lease4-get-all or
+// lease6-get-all command with the local subnet IDs. Only the servers
+// owning these subnets are queried. Kea provides no API to search the
+// leases by state or expiration time, so without the prefix all leases
+// are fetched using the lease4-get-page and lease6-get-page commands.
+// In both cases the returned leases are filtered by the Stork server.
+// The Kea servers that returned an error are returned in the second value.
+func FindLeasesByFilter(db *dbops.PgDB, agents agentcomm.ConnectedAgents, filter *LeasesFilter) (leases []dbmodel.Lease, erredApps []*dbmodel.App, err error) {
+	apps, err := dbmodel.GetAppsByType(db, dbmodel.AppTypeKea)
+	if err != nil {
+		err = errors.WithMessagef(err, "failed to fetch Kea apps while searching for leases")
+		return leases, erredApps, err
+	}
+	var localSubnetIDs map[int64][]int64
+	if filter.Subnet != nil {
+		localSubnetIDs, err = getLocalSubnetIDsByPrefix(db, filter.Subnet)
+		if err != nil {
+			err = errors.WithMessagef(err, "failed to fetch subnets in %s while searching for leases", filter.Subnet)
+			return leases, erredApps, err
+		}
+	}
+	now := time.Now()
+	for i := range apps {
+		appError := false
+		for _, daemonName := range []string{dbmodel.DaemonNameDHCPv4, dbmodel.DaemonNameDHCPv6} {
+			daemon := apps[i].GetDaemonByName(daemonName)
+			if daemon == nil || !daemonHasLeaseCmdsHook(daemon) {
+				continue
+			}
+			var daemonLeases []dbmodel.Lease
+			switch {
+			case filter.Subnet == nil && daemonName == dbmodel.DaemonNameDHCPv4:
+				daemonLeases, err = getLeasesPageByPage(agents, &apps[i], keactrl.Lease4GetPage, filter, now)
+			case filter.Subnet == nil:
+				daemonLeases, err = getLeasesPageByPage(agents, &apps[i], keactrl.Lease6GetPage, filter, now)
+			case len(localSubnetIDs[daemon.ID]) == 0:
+				// The daemon has no subnets in the prefix.
+				continue
+			case daemonName == dbmodel.DaemonNameDHCPv4:
+				daemonLeases, err = getSubnetLeases(agents, &apps[i], keactrl.Lease4GetAll, localSubnetIDs[daemon.ID], filter, now)
+			default:
+				daemonLeases, err = getSubnetLeases(agents, &apps[i], keactrl.Lease6GetAll, localSubnetIDs[daemon.ID], filter, now)
+			}
+			if err != nil {
+				appError = true
+				log.WithError(err).Warnf("Failed to fetch leases from %s", apps[i].GetName())
+				continue
+			}
+			leases = append(leases, daemonLeases...)
+		}
+		if appError {
+			erredApps = append(erredApps, &apps[i])
+		}
+	}
+	return leases, erredApps, nil
+}
+
 // Selects leases not matching specified host reservation. It compares DHCP
 // identifiers in the lease with host identifiers. If match is not found,
 // the lease is considered in conflict with the host and returned in the
@@ -1,7 +1,11 @@
 package kea
 
 import (
+	"encoding/json"
+	"fmt"
+	"net"
 	"testing"
+	"time"
 
 	require "github.com/stretchr/testify/require"
 
@@ -11,6 +15,7 @@ import (
 	agentcommtest "isc.org/stork/server/agentcomm/test"
 	dbmodel "isc.org/stork/server/database/model"
 	dbtest "isc.org/stork/server/database/test"
+	storkutil "isc.org/stork/util"
 )
 
 // Generates a success mock response to commands fetching a single
@@ -1475,6 +1480,276 @@ func TestFindDeclinedLeasesNoLeaseCmds(t *testing.T) {
 	require.Empty(t, leases)
 }
 
+// Test that the leases are filtered by subnet, state and expiration time.
+func TestLeasesFilterMatches(t *testing.T) {
+	now := time.Unix(100000, 0)
+	lease := &dbmodel.Lease{
+		Lease: keadata.Lease{
+			IPAddress:     "10.20.1.1",
+			CLTT:          100000 - 3000,
+			ValidLifetime: 3600,
+			State:         keadata.LeaseStateDefault,
+		},
+	}
+	_, subnet, _ := net.ParseCIDR("10.20.0.0/16")
+	_, otherSubnet, _ := net.ParseCIDR("10.30.0.0/16")
+	_, subnet6, _ := net.ParseCIDR("2001:db8:1::/64")
+
+	require.True(t, (&LeasesFilter{}).Matches(lease, now))
+	require.True(t, (&LeasesFilter{
+		Subnet:        subnet,
+		State:         storkutil.Ptr(keadata.LeaseStateDefault),
+		ExpiresWithin: time.Hour,
+	}).Matches(lease, now))
+	require.False(t, (&LeasesFilter{Subnet: otherSubnet}).Matches(lease, now))
+	require.False(t, (&LeasesFilter{Subnet: subnet6}).Matches(lease, now))
+	require.False(t, (&LeasesFilter{State: storkutil.Ptr(keadata.LeaseStateDeclined)}).Matches(lease, now))
+	// The lease expires in 10 minutes.
+	require.False(t, (&LeasesFilter{ExpiresWithin: 5 * time.Minute}).Matches(lease, now))
+	// The expired lease is not matched.
+	require.False(t, (&LeasesFilter{ExpiresWithin: time.Hour}).Matches(lease, now.Add(time.Hour)))
+}
+
+// Generates the responses to the lease4-get-page commands. The first page
+// is full and holds the leases from two subnets. The second page holds
+// one lease. The next response indicates that there are no more leases.
+func mockLease4GetPage(callNo int, responses []interface{}) {
+	var leases []map[string]any
+	switch callNo {
+	case 0:
+		for i := 0; i < leasesPageSize; i++ {
+			leases = append(leases, map[string]any{
+				"ip-address": fmt.Sprintf("10.%d.%d.%d", 20+i%2, i/256, i%256),
+				"cltt":       12345678,
+				"valid-lft":  3600,
+				"subnet-id":  1 + i%2,
+				"state":      0,
+			})
+		}
+	case 1:
+		leases = append(leases, map[string]any{
+			"ip-address": "10.20.200.1",
+			"cltt":       12345678,
+			"valid-lft":  3600,
+			"subnet-id":  1,
+			"state":      1,
+		})
+	}
+	response := []map[string]any{
+		{
+			"result": keactrl.ResponseEmpty,
+			"text":   "0 IPv4 lease(s) found.",
+		},
+	}
+	if len(leases) > 0 {
+		response[0]["result"] = keactrl.ResponseSuccess
+		response[0]["text"] = fmt.Sprintf("%d IPv4 lease(s) found.", len(leases))
+		response[0]["arguments"] = map[string]any{
+			"leases": leases,
+			"count":  len(leases),
+		}
+	}
+	data, _ := json.Marshal(response)
+	command := keactrl.NewCommandBase(keactrl.Lease4GetPage, keactrl.DHCPv4)
+	_ = keactrl.UnmarshalResponseList(command, data, responses[0])
+}
+
+// Test fetching the leases page by page and filtering them.
+func TestGetLeasesPageByPage(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(mockLease4GetPage, nil)
+	app := &dbmodel.App{
+		ID: 1,
+	}
+	_, subnet, _ := net.ParseCIDR("10.20.0.0/16")
+	leases, err := getLeasesPageByPage(agents, app, keactrl.Lease4GetPage, &LeasesFilter{Subnet: subnet}, time.Now())
+	require.NoError(t, err)
+	require.Len(t, leases, leasesPageSize/2+1)
+	for _, lease := range leases {
+		require.EqualValues(t, 1, lease.SubnetID)
+		require.EqualValues(t, 1, lease.AppID)
+		require.Equal(t, app, lease.App)
+	}
+
+	// The next page should start from the last lease on the previous page.
+	require.Len(t, agents.RecordedCommands, 2)
+	require.JSONEq(t, `{
+		"command": "lease4-get-page",
+		"service": ["dhcp4"],
+		"arguments": {
+			"from": "start",
+			"limit": 1000
+		}
+	}`, agents.RecordedCommands[0].Marshal())
+	require.JSONEq(t, `{
+		"command": "lease4-get-page",
+		"service": ["dhcp4"],
+		"arguments": {
+			"from": "10.21.3.231",
+			"limit": 1000
+		}
+	}`, agents.RecordedCommands[1].Marshal())
+}
+
+// Test that an error is returned when Kea fails to return a page of leases.
+func TestGetLeasesPageByPageError(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(func(callNo int, responses []interface{}) {
+		json := []byte(`[
+            {
+                "result": 1,
+                "text": "unable to fetch leases"
+            }
+        ]`)
+		command := keactrl.NewCommandBase(keactrl.Lease6GetPage, keactrl.DHCPv6)
+		_ = keactrl.UnmarshalResponseList(command, json, responses[0])
+	}, nil)
+	leases, err := getLeasesPageByPage(agents, &dbmodel.App{}, keactrl.Lease6GetPage, &LeasesFilter{}, time.Now())
+	require.Error(t, err)
+	require.Empty(t, leases)
+	require.Len(t, agents.RecordedCommands, 1)
+}
+
+// Test searching the leases by subnet and state on the Kea servers. Only
+// the DHCPv4 server owning the subnets within the prefix should be queried.
+func TestFindLeasesByFilter(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	machine := &dbmodel.Machine{
+		Address:   "machine",
+		AgentPort: 8080,
+	}
+	err := dbmodel.AddMachine(db, machine)
+	require.NoError(t, err)
+
+	accessPoints := []*dbmodel.AccessPoint{}
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "localhost", "", 8000, true)
+	app := &dbmodel.App{
+		MachineID:    machine.ID,
+		Type:         dbmodel.AppTypeKea,
+		AccessPoints: accessPoints,
+		Daemons: []*dbmodel.Daemon{
+			{
+				Name: dbmodel.DaemonNameDHCPv4,
+				KeaDaemon: &dbmodel.KeaDaemon{
+					Config: dbmodel.NewKeaConfig(&map[string]interface{}{
+						"Dhcp4": map[string]interface{}{
+							"hooks-libraries": []interface{}{
+								map[string]interface{}{
+									"library": "libdhcp_lease_cmds.so",
+								},
+							},
+						},
+					}),
+				},
+			},
+			{
+				Name: dbmodel.DaemonNameDHCPv6,
+				KeaDaemon: &dbmodel.KeaDaemon{
+					Config: dbmodel.NewKeaConfig(&map[string]interface{}{
+						"Dhcp6": map[string]interface{}{
+							"hooks-libraries": []interface{}{
+								map[string]interface{}{
+									"library": "libdhcp_lease_cmds.so",
+								},
+							},
+						},
+					}),
+				},
+			},
+		},
+	}
+	_, err = dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+
+	// The first subnet has ID 1 in the Kea configuration. The second
+	// subnet is outside of the searched prefix.
+	for i, prefix := range []string{"10.20.0.0/16", "10.30.0.0/16"} {
+		subnet := &dbmodel.Subnet{
+			Prefix: prefix,
+		}
+		err = dbmodel.AddSubnet(db, subnet)
+		require.NoError(t, err)
+		subnet.SetLocalSubnet(&dbmodel.LocalSubnet{
+			DaemonID:      app.Daemons[0].ID,
+			LocalSubnetID: int64(i + 1),
+		})
+		err = dbmodel.AddLocalSubnets(db, subnet)
+		require.NoError(t, err)
+	}
+
+	agents := agentcommtest.NewFakeAgents(func(callNo int, responses []interface{}) {
+		json := []byte(`[
+            {
+                "result": 0,
+                "text": "2 IPv4 lease(s) found.",
+                "arguments": {
+                    "leases": [
+                        {
+                            "ip-address": "10.20.0.1",
+                            "cltt": 12345678,
+                            "valid-lft": 3600,
+                            "subnet-id": 1,
+                            "state": 0
+                        },
+                        {
+                            "ip-address": "10.20.200.1",
+                            "cltt": 12345678,
+                            "valid-lft": 3600,
+                            "subnet-id": 1,
+                            "state": 1
+                        }
+                    ],
+                    "count": 2
+                }
+            }
+        ]`)
+		command := keactrl.NewCommandBase(keactrl.Lease4GetAll, keactrl.DHCPv4)
+		_ = keactrl.UnmarshalResponseList(command, json, responses[0])
+	}, nil)
+
+	_, prefix, _ := net.ParseCIDR("10.20.0.0/16")
+	leases, erredApps, err := FindLeasesByFilter(db, agents, &LeasesFilter{
+		Subnet: prefix,
+		State:  storkutil.Ptr(keadata.LeaseStateDeclined),
+	})
+	require.NoError(t, err)
+	require.Empty(t, erredApps)
+	require.Len(t, leases, 1)
+	require.Equal(t, "10.20.200.1", leases[0].IPAddress)
+	require.EqualValues(t, app.ID, leases[0].AppID)
+
+	// Only the leases in the subnet within the prefix should be fetched.
+	require.Len(t, agents.RecordedCommands, 1)
+	require.JSONEq(t, `{
+		"command": "lease4-get-all",
+		"service": ["dhcp4"],
+		"arguments": {
+			"subnets": [1]
+		}
+	}`, agents.RecordedCommands[0].Marshal())
+
+	// The prefix within the subnet should select this subnet too.
+	_, prefix, _ = net.ParseCIDR("10.20.200.0/24")
+	leases, erredApps, err = FindLeasesByFilter(db, agents, &LeasesFilter{
+		Subnet: prefix,
+	})
+	require.NoError(t, err)
+	require.Empty(t, erredApps)
+	require.Len(t, leases, 1)
+	require.Equal(t, "10.20.200.1", leases[0].IPAddress)
+	require.Len(t, agents.RecordedCommands, 2)
+
+	// No servers should be queried when there are no subnets in the prefix.
+	_, prefix, _ = net.ParseCIDR("192.0.2.0/24")
+	leases, erredApps, err = FindLeasesByFilter(db, agents, &LeasesFilter{
+		Subnet: prefix,
+	})
+	require.NoError(t, err)
+	require.Empty(t, erredApps)
+	require.Empty(t, leases)
+	require.Len(t, agents.RecordedCommands, 2)
+}
+
 // Test searching leases associated with a host reservation.
 func TestFindLeasesByHostID(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
@@ -0,0 +1,179 @@
+package kea
+
+import (
+	"context"
+
+	errors "github.com/pkg/errors"
+
+	keactrl "isc.org/stork/appctrl/kea"
+	"isc.org/stork/server/agentcomm"
+	"isc.org/stork/server/config"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Checks if the daemon has the libdhcp_lease_cmds hooks library configured.
+func daemonHasLeaseCmdsHook(daemon *dbmodel.Daemon) bool {
+	if daemon.KeaDaemon != nil && daemon.KeaDaemon.Config != nil {
+		if _, _, ok := daemon.KeaDaemon.Config.GetHookLibrary("libdhcp_lease_cmds"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks that the leases can be modified in the daemon, i.e., it is a Kea
+// DHCP server belonging to an app and it has the libdhcp_lease_cmds hooks
+// library configured.
+func validateLeaseDaemon(daemon *dbmodel.Daemon) error {
+	if daemon.App == nil {
+		return errors.Errorf("daemon %d lacks the app", daemon.ID)
+	}
+	if daemon.Name != dbmodel.DaemonNameDHCPv4 && daemon.Name != dbmodel.DaemonNameDHCPv6 {
+		return errors.Errorf("leases can only be modified in a Kea DHCP server, not in %s", daemon.Name)
+	}
+	if !daemonHasLeaseCmdsHook(daemon) {
+		return config.NewNoLeaseCmdsHookError()
+	}
+	return nil
+}
+
+// Sends a command to the daemon. It returns the sent command and its
+// result for the audit trail. The returned result is non-nil when the
+// command was sent, even if an error is returned.
+func sendDaemonCommand(agents agentcomm.ConnectedAgents, daemon *dbmodel.Daemon, command *keactrl.Command) (*dbmodel.AuditCommand, error) {
+	var response keactrl.ResponseList
+	result, err := agents.ForwardToKeaOverHTTP(context.Background(), daemon.App, []keactrl.SerializableCommand{command}, &response)
+	if err == nil {
+		if err = result.GetFirstError(); err == nil {
+			if len(response) == 0 {
+				err = errors.Errorf("invalid response to %s command received", command.GetCommand())
+			} else {
+				err = keactrl.GetResponseError(response[0])
+			}
+		}
+	}
+	commandResult := &dbmodel.AuditCommand{
+		AppID:   daemon.App.ID,
+		AppName: daemon.App.GetName(),
+		Command: newAuditCommandData(command),
+	}
+	switch {
+	case len(response) > 0:
+		commandResult.Result = response[0].Result
+		commandResult.Text = response[0].Text
+	case err != nil:
+		commandResult.Result = -1
+		commandResult.Text = err.Error()
+	}
+	if err != nil {
+		return commandResult, errors.WithMessagef(err, "%s command to %s failed", command.GetCommand(), daemon.App.GetName())
+	}
+	return commandResult, nil
+}
+
+// Sends a command modifying the leases to the daemon. It returns the sent
+// command and its result for the audit trail. The returned result is
+// non-nil when the command was sent, even if an error is returned. If the
+// notFoundAddress is non-empty, the empty response from Kea is converted
+// to the LeaseNotFoundError for this address.
+func sendLeaseCommand(agents agentcomm.ConnectedAgents, daemon *dbmodel.Daemon, command *keactrl.Command, notFoundAddress string) (*dbmodel.AuditCommand, error) {
+	commandResult, err := sendDaemonCommand(agents, daemon, command)
+	if err != nil {
+		return commandResult, err
+	}
+	if notFoundAddress != "" && commandResult.Result == keactrl.ResponseEmpty {
+		return commandResult, config.NewLeaseNotFoundError(notFoundAddress)
+	}
+	return commandResult, nil
+}
+
+// Adds a lease to the Kea DHCP server using the lease4-add or lease6-add
+// command. It returns the sent command and its result, and an error if
+// the lease could not be added.
+func AddLease(agents agentcomm.ConnectedAgents, daemon *dbmodel.Daemon, lease *keactrl.LeaseParameters) (*dbmodel.AuditCommand, error) {
+	if err := validateLeaseDaemon(daemon); err != nil {
+		return nil, err
+	}
+	// The force-create flag is not accepted by the add commands.
+	params := *lease
+	params.ForceCreate = false
+	var command *keactrl.Command
+	if daemon.Name == dbmodel.DaemonNameDHCPv4 {
+		command = keactrl.NewCommandLease4Add(&params, keactrl.DHCPv4)
+	} else {
+		command = keactrl.NewCommandLease6Add(&params, keactrl.DHCPv6)
+	}
+	return sendLeaseCommand(agents, daemon, command, "")
+}
+
+// Updates a lease in the Kea DHCP server using the lease4-update or
+// lease6-update command. The lease is added if it does not exist and the
+// ForceCreate flag is set. It returns the sent command and its result,
+// and an error if the lease could not be updated.
+func UpdateLease(agents agentcomm.ConnectedAgents, daemon *dbmodel.Daemon, lease *keactrl.LeaseParameters) (*dbmodel.AuditCommand, error) {
+	if err := validateLeaseDaemon(daemon); err != nil {
+		return nil, err
+	}
+	var command *keactrl.Command
+	if daemon.Name == dbmodel.DaemonNameDHCPv4 {
+		command = keactrl.NewCommandLease4Update(lease, keactrl.DHCPv4)
+	} else {
+		command = keactrl.NewCommandLease6Update(lease, keactrl.DHCPv6)
+	}
+	return sendLeaseCommand(agents, daemon, command, "")
+}
+
+// Deletes a lease from the Kea DHCP server using the lease4-del or
+// lease6-del command. The lease type is only used for the DHCPv6 server;
+// it defaults to IA_NA when empty. It returns the LeaseNotFoundError if
+// the lease does not exist.
+func DeleteLease(agents agentcomm.ConnectedAgents, daemon *dbmodel.Daemon, leaseType keactrl.LeaseType, ipAddress string) (*dbmodel.AuditCommand, error) {
+	if err := validateLeaseDaemon(daemon); err != nil {
+		return nil, err
+	}
+	var command *keactrl.Command
+	if daemon.Name == dbmodel.DaemonNameDHCPv4 {
+		command = keactrl.NewCommandLease4Del(ipAddress, keactrl.DHCPv4)
+	} else {
+		if leaseType == "" {
+			leaseType = keactrl.LeaseTypeNA
+		}
+		command = keactrl.NewCommandLease6Del(leaseType, ipAddress, keactrl.DHCPv6)
+	}
+	return sendLeaseCommand(agents, daemon, command, ipAddress)
+}
+
+// Deletes all leases belonging to the subnet from the Kea DHCP server
+// using the lease4-wipe or lease6-wipe command. The subnet ID is the
+// identifier of the subnet in the Kea configuration.
+func WipeLeases(agents agentcomm.ConnectedAgents, daemon *dbmodel.Daemon, subnetID int64) (*dbmodel.AuditCommand, error) {
+	if err := validateLeaseDaemon(daemon); err != nil {
+		return nil, err
+	}
+	if subnetID <= 0 {
+		return nil, errors.Errorf("invalid subnet ID %d for wiping the leases", subnetID)
+	}
+	var command *keactrl.Command
+	if daemon.Name == dbmodel.DaemonNameDHCPv4 {
+		command = keactrl.NewCommandLease4Wipe(subnetID, keactrl.DHCPv4)
+	} else {
+		command = keactrl.NewCommandLease6Wipe(subnetID, keactrl.DHCPv6)
+	}
+	return sendLeaseCommand(agents, daemon, command, "")
+}
+
+// Requests the Kea DHCP server to resend the DNS update for the lease
+// using the lease4-resend-ddns or lease6-resend-ddns command. It returns
+// the LeaseNotFoundError if the lease does not exist.
+func ResendLeaseDDNS(agents agentcomm.ConnectedAgents, daemon *dbmodel.Daemon, ipAddress string) (*dbmodel.AuditCommand, error) {
+	if err := validateLeaseDaemon(daemon); err != nil {
+		return nil, err
+	}
+	var command *keactrl.Command
+	if daemon.Name == dbmodel.DaemonNameDHCPv4 {
+		command = keactrl.NewCommandLease4ResendDDNS(ipAddress, keactrl.DHCPv4)
+	} else {
+		command = keactrl.NewCommandLease6ResendDDNS(ipAddress, keactrl.DHCPv6)
+	}
+	return sendLeaseCommand(agents, daemon, command, ipAddress)
+}
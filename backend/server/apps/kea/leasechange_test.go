@@ -0,0 +1,188 @@
+package kea
+
+import (
+	"fmt"
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+
+	keactrl "isc.org/stork/appctrl/kea"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	"isc.org/stork/server/config"
+	dbmodel "isc.org/stork/server/database/model"
+	storkutil "isc.org/stork/util"
+)
+
+// Creates a test daemon, optionally with the lease_cmds hooks library.
+func newTestLeaseDaemon(t *testing.T, name string, leaseCmds bool) *dbmodel.Daemon {
+	accessPoints := []*dbmodel.AccessPoint{}
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "localhost", "", 8000, false)
+	daemon := dbmodel.NewKeaDaemon(name, true)
+	daemon.ID = 2
+	daemon.App = &dbmodel.App{
+		ID:           1,
+		Name:         "kea@localhost",
+		AccessPoints: accessPoints,
+	}
+	root := "Dhcp4"
+	if name == dbmodel.DaemonNameDHCPv6 {
+		root = "Dhcp6"
+	}
+	hooks := "[]"
+	if leaseCmds {
+		hooks = `[{ "library": "/usr/lib/kea/libdhcp_lease_cmds.so" }]`
+	}
+	err := daemon.SetConfigFromJSON(`{ "` + root + `": { "hooks-libraries": ` + hooks + ` } }`)
+	require.NoError(t, err)
+	return daemon
+}
+
+// Returns a function generating the mock response with the specified
+// result and text.
+func mockLeaseCommandResult(result int, text string) func(int, []any) {
+	return func(callNo int, responses []any) {
+		json := []byte(`[
+			{
+				"result": ` + fmt.Sprint(result) + `,
+				"text": "` + text + `"
+			}
+		]`)
+		command := keactrl.NewCommandBase(keactrl.Lease4Del, keactrl.DHCPv4)
+		_ = keactrl.UnmarshalResponseList(command, json, responses[0])
+	}
+}
+
+// Test adding a DHCPv4 lease.
+func TestAddLease4(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(mockLeaseCommandResult(0, "Lease for address 192.0.2.1, subnet-id 1 added."), nil)
+	daemon := newTestLeaseDaemon(t, dbmodel.DaemonNameDHCPv4, true)
+
+	command, err := AddLease(agents, daemon, &keactrl.LeaseParameters{
+		IPAddress:   "192.0.2.1",
+		HWAddress:   "01:02:03:04:05:06",
+		ForceCreate: true,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, command)
+	require.EqualValues(t, 1, command.AppID)
+	require.Equal(t, "kea@localhost", command.AppName)
+	require.Zero(t, command.Result)
+	require.Contains(t, command.Text, "added")
+
+	require.Len(t, agents.RecordedCommands, 1)
+	require.JSONEq(t, `{
+		"command": "lease4-add",
+		"service": ["dhcp4"],
+		"arguments": {
+			"ip-address": "192.0.2.1",
+			"hw-address": "01:02:03:04:05:06"
+		}
+	}`, agents.RecordedCommands[0].Marshal())
+	require.JSONEq(t, agents.RecordedCommands[0].Marshal(), string(command.Command))
+}
+
+// Test updating a DHCPv6 lease.
+func TestUpdateLease6(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(mockLeaseCommandResult(0, "IPv6 lease updated."), nil)
+	daemon := newTestLeaseDaemon(t, dbmodel.DaemonNameDHCPv6, true)
+
+	_, err := UpdateLease(agents, daemon, &keactrl.LeaseParameters{
+		IPAddress:   "2001:db8:1::1",
+		DUID:        "01:02:03",
+		IAID:        storkutil.Ptr(int64(1)),
+		ForceCreate: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, agents.RecordedCommands, 1)
+	require.EqualValues(t, keactrl.Lease6Update, agents.RecordedCommands[0].GetCommand())
+	require.Contains(t, agents.RecordedCommands[0].Marshal(), `"force-create":true`)
+}
+
+// Test deleting a DHCPv6 lease. The lease type should default to IA_NA.
+func TestDeleteLease6(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(mockLeaseCommandResult(0, "IPv6 lease deleted."), nil)
+	daemon := newTestLeaseDaemon(t, dbmodel.DaemonNameDHCPv6, true)
+
+	_, err := DeleteLease(agents, daemon, "", "2001:db8:1::1")
+	require.NoError(t, err)
+	require.Len(t, agents.RecordedCommands, 1)
+	require.JSONEq(t, `{
+		"command": "lease6-del",
+		"service": ["dhcp6"],
+		"arguments": {
+			"type": "IA_NA",
+			"ip-address": "2001:db8:1::1"
+		}
+	}`, agents.RecordedCommands[0].Marshal())
+}
+
+// Test that the LeaseNotFoundError is returned when the deleted lease
+// does not exist.
+func TestDeleteLeaseNotFound(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(mockLeaseCommandResult(3, "IPv4 lease not found."), nil)
+	daemon := newTestLeaseDaemon(t, dbmodel.DaemonNameDHCPv4, true)
+
+	command, err := DeleteLease(agents, daemon, "", "192.0.2.1")
+	require.ErrorAs(t, err, new(*config.LeaseNotFoundError))
+	require.NotNil(t, command)
+	require.EqualValues(t, 3, command.Result)
+}
+
+// Test that an error returned by Kea is reported.
+func TestAddLeaseKeaError(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(mockLeaseCommandResult(1, "lease already exists"), nil)
+	daemon := newTestLeaseDaemon(t, dbmodel.DaemonNameDHCPv4, true)
+
+	command, err := AddLease(agents, daemon, &keactrl.LeaseParameters{
+		IPAddress: "192.0.2.1",
+	})
+	require.ErrorContains(t, err, "lease already exists")
+	require.NotNil(t, command)
+	require.EqualValues(t, 1, command.Result)
+}
+
+// Test wiping the leases from a subnet.
+func TestWipeLeases(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(mockLeaseCommandResult(0, "Deleted 2 IPv4 lease(s) from subnet(s) 3"), nil)
+	daemon := newTestLeaseDaemon(t, dbmodel.DaemonNameDHCPv4, true)
+
+	command, err := WipeLeases(agents, daemon, 3)
+	require.NoError(t, err)
+	require.Contains(t, command.Text, "Deleted 2")
+	require.Len(t, agents.RecordedCommands, 1)
+	require.EqualValues(t, keactrl.Lease4Wipe, agents.RecordedCommands[0].GetCommand())
+
+	// The subnet ID is mandatory.
+	_, err = WipeLeases(agents, daemon, 0)
+	require.Error(t, err)
+	require.Len(t, agents.RecordedCommands, 1)
+}
+
+// Test resending the DNS update for a lease.
+func TestResendLeaseDDNS(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(mockLeaseCommandResult(0, "NCR generated for: 192.0.2.1"), nil)
+	daemon := newTestLeaseDaemon(t, dbmodel.DaemonNameDHCPv4, true)
+
+	_, err := ResendLeaseDDNS(agents, daemon, "192.0.2.1")
+	require.NoError(t, err)
+	require.Len(t, agents.RecordedCommands, 1)
+	require.EqualValues(t, keactrl.Lease4ResendDDNS, agents.RecordedCommands[0].GetCommand())
+}
+
+// Test that the commands are not sent to a daemon lacking the lease_cmds
+// hooks library or to a daemon not being a DHCP server.
+func TestLeaseChangeInvalidDaemon(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(mockLeaseCommandResult(0, ""), nil)
+
+	daemon := newTestLeaseDaemon(t, dbmodel.DaemonNameDHCPv4, false)
+	command, err := DeleteLease(agents, daemon, "", "192.0.2.1")
+	require.ErrorAs(t, err, new(*config.NoLeaseCmdsHookError))
+	require.Nil(t, command)
+
+	daemon = dbmodel.NewKeaDaemon(dbmodel.DaemonNameD2, true)
+	daemon.App = &dbmodel.App{}
+	_, err = ResendLeaseDDNS(agents, daemon, "192.0.2.1")
+	require.Error(t, err)
+
+	require.Empty(t, agents.RecordedCommands)
+}
@@ -0,0 +1,146 @@
+package kea
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Matches the names of the DHCPv6 statistics collected per prefix pool,
+// e.g., subnet[1].pd-pool[0].assigned-pds.
+var prefixPoolStatNamePattern = regexp.MustCompile(`^subnet\[(\d+)\]\.pd-pool\[(\d+)\]\.(total-pds|assigned-pds)$`)
+
+// Identifies the prefix pool in the per-pool statistics. The local subnet
+// ID is the subnet ID in the Kea configuration.
+type prefixPoolStatKey struct {
+	localSubnetID int64
+	poolID        int64
+}
+
+// Delegated prefix counters of a prefix pool. They are stored as floats
+// because the total number of the delegated prefixes may exceed the
+// range of the integers.
+type prefixPoolStats struct {
+	totalPDs    float64
+	assignedPDs float64
+}
+
+// Returns the delegated prefix utilization of the prefix pool as a
+// fraction. It returns zero if the pool has no prefixes.
+func (s *prefixPoolStats) getUtilization() float64 {
+	if s.totalPDs == 0 {
+		return 0
+	}
+	return s.assignedPDs / s.totalPDs
+}
+
+// Returns the ID identifying the prefix pool in the per-pool statistics.
+// It is the pool ID from the configuration or, if it is not specified,
+// the index of the pool in its subnet.
+func getPrefixPoolStatID(pool *dbmodel.PrefixPool, index int) int64 {
+	if pool.KeaParameters != nil && pool.KeaParameters.PoolID != nil {
+		return *pool.KeaParameters.PoolID
+	}
+	return int64(index)
+}
+
+// Converts the statistic-get-all response from the DHCPv6 server to the
+// delegated prefix counters of the prefix pools. The other statistics are
+// ignored.
+func newPrefixPoolStats(response []StatGetAllResponse) (map[prefixPoolStatKey]*prefixPoolStats, error) {
+	if len(response) == 0 {
+		return nil, errors.New("empty DHCPv6 statistics response")
+	}
+	if err := response[0].GetError(); err != nil {
+		return nil, errors.WithMessage(err, "error result in DHCPv6 statistics response")
+	}
+
+	stats := make(map[prefixPoolStatKey]*prefixPoolStats)
+	for name, samples := range response[0].Arguments {
+		match := prefixPoolStatNamePattern.FindStringSubmatch(name)
+		if match == nil || len(samples) == 0 || len(samples[0]) == 0 {
+			continue
+		}
+		value, ok := samples[0][0].(float64)
+		if !ok {
+			continue
+		}
+		localSubnetID, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		poolID, err := strconv.ParseInt(match[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		key := prefixPoolStatKey{localSubnetID, poolID}
+		poolStats, ok := stats[key]
+		if !ok {
+			poolStats = &prefixPoolStats{}
+			stats[key] = poolStats
+		}
+		if match[3] == "total-pds" {
+			poolStats.totalPDs = value
+		} else {
+			poolStats.assignedPDs = value
+		}
+	}
+	return stats, nil
+}
+
+// Processes the statistic-get-all command response from the DHCPv6 server
+// and stores the delegated prefix utilization in its prefix pools. The
+// pools are expected to be ordered like in the configuration. The pools
+// without statistics are left unchanged.
+func (statsPuller *StatsPuller) storePrefixPoolStats(daemon *dbmodel.Daemon, pools []*dbmodel.PrefixPool, response interface{}, collectedAt time.Time) error {
+	statsResp, ok := response.(*[]StatGetAllResponse)
+	if !ok {
+		return errors.Errorf("response type is invalid: %+v", response)
+	}
+	stats, err := newPrefixPoolStats(*statsResp)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	indexes := make(map[int64]int)
+	for _, pool := range pools {
+		if pool.LocalSubnet == nil || pool.LocalSubnet.DaemonID != daemon.ID {
+			continue
+		}
+		index := indexes[pool.LocalSubnetID]
+		indexes[pool.LocalSubnetID]++
+
+		poolStats, ok := stats[prefixPoolStatKey{pool.LocalSubnet.LocalSubnetID, getPrefixPoolStatID(pool, index)}]
+		if !ok {
+			continue
+		}
+		if err := pool.UpdateUtilization(statsPuller.DB, toPermille(poolStats.getUtilization()), collectedAt); err != nil {
+			lastErr = err
+			log.WithError(err).Errorf("Cannot update utilization in prefix pool %s", pool.Prefix)
+		}
+	}
+	return lastErr
+}
+
+// Returns the prefix pools except the pools of the excluded daemons,
+// e.g., the passive HA daemons sharing the lease database with the active
+// daemons.
+func excludeDaemonPrefixPools(pools []*dbmodel.PrefixPool, excludedDaemons []int64) []*dbmodel.PrefixPool {
+	excluded := make(map[int64]bool, len(excludedDaemons))
+	for _, id := range excludedDaemons {
+		excluded[id] = true
+	}
+	var filtered []*dbmodel.PrefixPool
+	for _, pool := range pools {
+		if pool.LocalSubnet != nil && excluded[pool.LocalSubnet.DaemonID] {
+			continue
+		}
+		filtered = append(filtered, pool)
+	}
+	return filtered
+}
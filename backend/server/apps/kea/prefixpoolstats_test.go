@@ -0,0 +1,102 @@
+package kea
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	keaconfig "isc.org/stork/appcfg/kea"
+	keactrl "isc.org/stork/appctrl/kea"
+	dbmodel "isc.org/stork/server/database/model"
+	storkutil "isc.org/stork/util"
+)
+
+// Test that the delegated prefix counters of the prefix pools are read
+// from the statistic-get-all response and the other statistics are
+// ignored.
+func TestNewPrefixPoolStats(t *testing.T) {
+	response := `[{
+		"result": 0,
+		"arguments": {
+			"pkt6-received": [ [ 100, "2024-01-02 03:04:05.123456" ] ],
+			"subnet[1].assigned-pds": [ [ 30, "2024-01-02 03:04:05.123456" ] ],
+			"subnet[1].pd-pool[0].total-pds": [ [ 256, "2024-01-02 03:04:05.123456" ], [ 128, "2024-01-02 03:04:00.123456" ] ],
+			"subnet[1].pd-pool[0].assigned-pds": [ [ 64, "2024-01-02 03:04:05.123456" ] ],
+			"subnet[1].pd-pool[5].total-pds": [ [ 1.8446744073709552e+19, "2024-01-02 03:04:05.123456" ] ],
+			"subnet[1].pd-pool[5].assigned-pds": [ [ 0, "2024-01-02 03:04:05.123456" ] ],
+			"subnet[2].pd-pool[1].assigned-pds": [ [ 10, "2024-01-02 03:04:05.123456" ] ],
+			"subnet[2].pd-pool[1].declined-addresses": [ [ 1, "2024-01-02 03:04:05.123456" ] ]
+		}
+	}]`
+	var statsResponse []StatGetAllResponse
+	command := keactrl.NewCommandBase(keactrl.StatisticGetAll, keactrl.DHCPv6)
+	err := keactrl.UnmarshalResponseList(command, []byte(response), &statsResponse)
+	require.NoError(t, err)
+
+	stats, err := newPrefixPoolStats(statsResponse)
+	require.NoError(t, err)
+	require.Len(t, stats, 3)
+
+	require.Contains(t, stats, prefixPoolStatKey{1, 0})
+	require.EqualValues(t, 256, stats[prefixPoolStatKey{1, 0}].totalPDs)
+	require.EqualValues(t, 64, stats[prefixPoolStatKey{1, 0}].assignedPDs)
+	require.EqualValues(t, 0.25, stats[prefixPoolStatKey{1, 0}].getUtilization())
+
+	require.Contains(t, stats, prefixPoolStatKey{1, 5})
+	require.Zero(t, stats[prefixPoolStatKey{1, 5}].getUtilization())
+
+	// The pool without the total counter has zero utilization.
+	require.Contains(t, stats, prefixPoolStatKey{2, 1})
+	require.EqualValues(t, 10, stats[prefixPoolStatKey{2, 1}].assignedPDs)
+	require.Zero(t, stats[prefixPoolStatKey{2, 1}].getUtilization())
+}
+
+// Test that an error is returned for an empty or failed statistic-get-all
+// response.
+func TestNewPrefixPoolStatsError(t *testing.T) {
+	_, err := newPrefixPoolStats([]StatGetAllResponse{})
+	require.Error(t, err)
+
+	_, err = newPrefixPoolStats([]StatGetAllResponse{
+		{
+			ResponseHeader: keactrl.ResponseHeader{
+				Result: keactrl.ResponseError,
+				Text:   "unable to get statistics",
+			},
+		},
+	})
+	require.ErrorContains(t, err, "unable to get statistics")
+
+	// The response without the per-pool statistics is fine.
+	stats, err := newPrefixPoolStats([]StatGetAllResponse{{}})
+	require.NoError(t, err)
+	require.Empty(t, stats)
+}
+
+// Test that the prefix pool is identified by the configured pool ID or
+// its index in the subnet.
+func TestGetPrefixPoolStatID(t *testing.T) {
+	pool := &dbmodel.PrefixPool{}
+	require.EqualValues(t, 2, getPrefixPoolStatID(pool, 2))
+
+	pool.KeaParameters = &keaconfig.PoolParameters{}
+	require.EqualValues(t, 2, getPrefixPoolStatID(pool, 2))
+
+	pool.KeaParameters.PoolID = storkutil.Ptr(int64(7))
+	require.EqualValues(t, 7, getPrefixPoolStatID(pool, 2))
+}
+
+// Test that the prefix pools of the excluded daemons are filtered out.
+func TestExcludeDaemonPrefixPools(t *testing.T) {
+	pools := []*dbmodel.PrefixPool{
+		{ID: 1, LocalSubnet: &dbmodel.LocalSubnet{DaemonID: 10}},
+		{ID: 2, LocalSubnet: &dbmodel.LocalSubnet{DaemonID: 11}},
+		{ID: 3, LocalSubnet: &dbmodel.LocalSubnet{DaemonID: 12}},
+	}
+
+	filtered := excludeDaemonPrefixPools(pools, []int64{11})
+	require.Len(t, filtered, 2)
+	require.EqualValues(t, 1, filtered[0].ID)
+	require.EqualValues(t, 3, filtered[1].ID)
+
+	require.Len(t, excludeDaemonPrefixPools(pools, nil), 3)
+}
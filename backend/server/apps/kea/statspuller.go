@@ -12,6 +12,7 @@ import (
 	keactrl "isc.org/stork/appctrl/kea"
 	"isc.org/stork/server/agentcomm"
 	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/eventcenter"
 	storkutil "isc.org/stork/util"
 )
 
@@ -20,12 +21,16 @@ import (
 type StatsPuller struct {
 	*agentcomm.PeriodicPuller
 	*RpsWorker
+	EventCenter eventcenter.EventCenter
 }
 
 // Create a StatsPuller object that in background pulls Kea stats about leases.
 // Beneath it spawns a goroutine that pulls stats periodically from Kea apps (that are stored in database).
-func NewStatsPuller(db *pg.DB, agents agentcomm.ConnectedAgents) (*StatsPuller, error) {
-	statsPuller := &StatsPuller{}
+// The event center receives the events about the utilization alerts.
+func NewStatsPuller(db *pg.DB, agents agentcomm.ConnectedAgents, eventCenter eventcenter.EventCenter) (*StatsPuller, error) {
+	statsPuller := &StatsPuller{
+		EventCenter: eventCenter,
+	}
 	periodicPuller, err := agentcomm.NewPeriodicPuller(db, agents, "Kea Stats puller", "kea_stats_puller_interval",
 		statsPuller.pullStats)
 	if err != nil {
@@ -124,6 +129,7 @@ func (statsPuller *StatsPuller) pullStats() error {
 	// go through all Subnets and:
 	// 1) estimate utilization per Subnet and per SharedNetwork
 	// 2) estimate global stats
+	var updatedSubnets []*dbmodel.Subnet
 	for _, sn := range subnets {
 		su := counter.add(sn)
 		err = sn.UpdateStatistics(
@@ -136,9 +142,11 @@ func (statsPuller *StatsPuller) pullStats() error {
 				su.GetAddressUtilization(), su.GetDelegatedPrefixUtilization(), sn.ID, err)
 			continue
 		}
+		updatedSubnets = append(updatedSubnets, sn)
 	}
 
 	// shared network utilization
+	updatedSharedNetworks := make(map[int64]*sharedNetworkStats)
 	for sharedNetworkID, u := range counter.sharedNetworks {
 		err = dbmodel.UpdateStatisticsInSharedNetwork(
 			statsPuller.DB, sharedNetworkID, u,
@@ -149,6 +157,34 @@ func (statsPuller *StatsPuller) pullStats() error {
 				u.GetAddressUtilization(), u.GetDelegatedPrefixUtilization(), sharedNetworkID, err)
 			continue
 		}
+		updatedSharedNetworks[sharedNetworkID] = u
+	}
+
+	// raise or clear the alerts according to the new utilization
+	err = statsPuller.evaluateUtilizationAlerts(updatedSubnets, updatedSharedNetworks)
+	if err != nil {
+		lastErr = err
+	}
+
+	// prefix pool utilization stored from the per-pool statistics
+	prefixPools, err := dbmodel.GetPrefixPoolsWithUtilization(statsPuller.DB)
+	if err != nil {
+		log.WithError(err).Error("Cannot get utilization of the prefix pools")
+		lastErr = err
+	}
+	prefixPools = excludeDaemonPrefixPools(prefixPools, excludedDaemons)
+
+	// record the utilization in the history
+	err = statsPuller.storeUtilizationHistory(updatedSubnets, prefixPools, updatedSharedNetworks, counter.global)
+	if err != nil {
+		log.WithError(err).Error("Cannot store utilization history")
+		lastErr = err
+	}
+
+	// forecast the exhaustion using the utilization history
+	err = statsPuller.forecastExhaustion(updatedSubnets, prefixPools, updatedSharedNetworks)
+	if err != nil {
+		lastErr = err
 	}
 
 	// global stats to collect
@@ -271,8 +307,9 @@ func (statsPuller *StatsPuller) storeDaemonStats(response interface{}, subnetsMa
 }
 
 func (statsPuller *StatsPuller) getStatsFromApp(dbApp *dbmodel.App) error {
-	// If no dhcp daemons found then exit.
-	if len(dbApp.GetActiveDHCPDaemonNames()) == 0 {
+	// If no dhcp or d2 daemons found then exit.
+	d2Daemon := dbApp.GetDaemonByName(d2)
+	if len(dbApp.GetActiveDHCPDaemonNames()) == 0 && (d2Daemon == nil || !d2Daemon.Active) {
 		return nil
 	}
 
@@ -287,9 +324,17 @@ func (statsPuller *StatsPuller) getStatsFromApp(dbApp *dbmodel.App) error {
 	responses := []interface{}{}
 
 	// Iterate over active daemons, adding commands and response containers
-	// for dhcp4 and dhcp6 daemons.
+	// for dhcp4, dhcp6 and d2 daemons.
 	for _, d := range dbApp.Daemons {
 		if d.KeaDaemon != nil && d.Active {
+			if d.Name == d2 {
+				// The D2 statistics are built-in and don't require the
+				// statistic hook.
+				cmdDaemons = append(cmdDaemons, d)
+				cmds = append(cmds, keactrl.NewCommandBase(keactrl.StatisticGetAll, d2))
+				responses = append(responses, &[]StatGetAllResponse{})
+				continue
+			}
 			if d.KeaDaemon.Config != nil {
 				// Ignore the daemons without the statistic hook to avoid
 				// confusing error messages.
@@ -325,6 +370,12 @@ func (statsPuller *StatsPuller) getStatsFromApp(dbApp *dbmodel.App) error {
 					cmdDaemons = append(cmdDaemons, d)
 					responses = append(responses, RpsAddCmd6(&cmds, dhcp6Daemons))
 				}
+
+				// Add daemon, cmd and response for DHCP6 per-pool stats
+				// used to compute the prefix pool utilization.
+				cmdDaemons = append(cmdDaemons, d)
+				cmds = append(cmds, keactrl.NewCommandBase(keactrl.StatisticGetAll, dhcp6Daemons...))
+				responses = append(responses, &[]StatGetAllResponse{})
 			}
 		}
 	}
@@ -371,9 +422,25 @@ func (statsPuller *StatsPuller) processAppResponses(dbApp *dbmodel.App, cmds []*
 		subnetsMap[localSubnetKey{sn.LocalSubnetID, family}] = sn
 	}
 
+	// Per-pool statistic processing needs app's prefix pools
+	prefixPools, err := dbmodel.GetAppPrefixPools(statsPuller.DB, dbApp.ID)
+	if err != nil {
+		return err
+	}
+
 	var lastErr error
+	collectedAt := storkutil.UTCNow()
 	for idx := 0; idx < len(cmds); idx++ {
 		switch cmdDaemons[idx].Name {
+		case d2:
+			if cmds[idx].Command == keactrl.StatisticGetAll {
+				err = statsPuller.storeD2DaemonStats(cmdDaemons[idx], responses[idx], collectedAt)
+				if err != nil {
+					log.Errorf("Error handling statistic-get-all (d2) response: %+v", err)
+					lastErr = err
+				}
+			}
+
 		case dhcp4:
 			switch cmds[idx].Command {
 			case keactrl.StatLease4Get:
@@ -406,6 +473,12 @@ func (statsPuller *StatsPuller) processAppResponses(dbApp *dbmodel.App, cmds []*
 					log.Errorf("Error handling statistic-get (v6) response: %+v", err)
 					lastErr = err
 				}
+			case keactrl.StatisticGetAll:
+				err = statsPuller.storePrefixPoolStats(cmdDaemons[idx], prefixPools, responses[idx], collectedAt)
+				if err != nil {
+					log.Errorf("Error handling statistic-get-all (v6) response: %+v", err)
+					lastErr = err
+				}
 			default:
 				// Impossible case.
 			}
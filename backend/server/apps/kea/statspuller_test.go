@@ -14,6 +14,7 @@ import (
 	agentcommtest "isc.org/stork/server/agentcomm/test"
 	dbmodel "isc.org/stork/server/database/model"
 	dbtest "isc.org/stork/server/database/test"
+	storktest "isc.org/stork/server/test/dbmodel"
 	storkutil "isc.org/stork/util"
 )
 
@@ -21,7 +22,8 @@ import (
 // 1. DHCPv4
 // 2. DHCPv4 RSP
 // 3. DHCPv6
-// 4. DHCPv6 RSP.
+// 4. DHCPv6 RSP
+// 5. DHCPv6 per-pool statistics (optional).
 func createKeaMock(jsonFactory func(callNo int) (jsons []string)) func(callNo int, cmdResponses []interface{}) {
 	return func(callNo int, cmdResponses []interface{}) {
 		jsons := jsonFactory(callNo)
@@ -48,6 +50,18 @@ func createKeaMock(jsonFactory func(callNo int) (jsons []string)) func(callNo in
 		rpsCmd = []*keactrl.Command{}
 		_ = RpsAddCmd6(&rpsCmd, daemons)
 		keactrl.UnmarshalResponseList(rpsCmd[0], []byte(jsons[3]), cmdResponses[3])
+
+		if len(cmdResponses) < 5 {
+			return
+		}
+
+		// DHCPv6 per-pool statistics
+		poolStatsJSON := `[{ "result": 0, "arguments": {} }]`
+		if len(jsons) > 4 {
+			poolStatsJSON = jsons[4]
+		}
+		command = keactrl.NewCommandBase(keactrl.StatisticGetAll, daemons...)
+		keactrl.UnmarshalResponseList(command, []byte(poolStatsJSON), cmdResponses[4])
 	}
 }
 
@@ -143,6 +157,19 @@ func createStandardKeaMock(oldStatsFormat bool) func(callNo int, cmdResponses []
 					},
 				},
 			},
+			[]StatGetAllResponse{
+				{
+					ResponseHeader: keactrl.ResponseHeader{
+						Result: 0,
+						Text:   "Everything is fine",
+					},
+					Arguments: map[string][][]any{
+						"subnet[50].pd-pool[0].total-pds":    {{1024 + totalShift, "2019-07-30 10:13:00.000000"}},
+						"subnet[50].pd-pool[0].assigned-pds": {{256 + shift, "2019-07-30 10:13:00.000000"}},
+						"subnet[50].assigned-pds":            {{15 + shift, "2019-07-30 10:13:00.000000"}},
+					},
+				},
+			},
 		}
 
 		var jsons []string
@@ -369,7 +396,7 @@ func TestStatsPullerBasic(t *testing.T) {
 	fa := agentcommtest.NewFakeAgents(nil, nil)
 
 	// Act
-	sp, err := NewStatsPuller(db, fa)
+	sp, err := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
 	defer sp.Shutdown()
 
 	// Assert
@@ -419,7 +446,7 @@ func TestStatsPullerEmptyResponse(t *testing.T) {
 	fa := agentcommtest.NewFakeAgents(keaMock, nil)
 
 	// prepare stats puller
-	sp, _ := NewStatsPuller(db, fa)
+	sp, _ := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
 	defer sp.Shutdown()
 
 	// Act
@@ -462,7 +489,7 @@ func checkStatsPullerPullStats(t *testing.T, statsFormat string) {
 	}
 
 	// prepare stats puller
-	sp, _ := NewStatsPuller(db, fa)
+	sp, _ := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
 	defer sp.Shutdown()
 
 	// Act
@@ -511,6 +538,13 @@ func checkStatsPullerPullStats(t *testing.T, statsFormat string) {
 		}
 	}
 
+	// Check the prefix pool utilization from the per-pool statistics.
+	prefixPools, err := dbmodel.GetPrefixPoolsWithUtilization(db)
+	require.NoError(t, err)
+	require.Len(t, prefixPools, 1)
+	require.Equal(t, "2001:db8:3:8000::/64", prefixPools[0].Prefix)
+	require.EqualValues(t, 250, prefixPools[0].PdUtilization)
+
 	// Check global statistics
 	globals, err := dbmodel.GetAllStats(db)
 	require.NoError(t, err)
@@ -575,7 +609,7 @@ func TestGetStatsFromAppWithoutStatCmd(t *testing.T) {
 		},
 	}
 
-	sp, _ := NewStatsPuller(db, fa)
+	sp, _ := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
 
 	// Act
 	err := sp.getStatsFromApp(app)
@@ -839,6 +873,13 @@ func verifyCountingStatisticsFromPrimary(t *testing.T, db *pg.DB) {
 		}
 	}
 
+	// Check the prefix pool utilization from the per-pool statistics.
+	prefixPools, err := dbmodel.GetPrefixPoolsWithUtilization(db)
+	require.NoError(t, err)
+	require.Len(t, prefixPools, 1)
+	require.Equal(t, "2001:db8:3:8000::/64", prefixPools[0].Prefix)
+	require.EqualValues(t, 250, prefixPools[0].PdUtilization)
+
 	// Check global statistics
 	globals, err := dbmodel.GetAllStats(db)
 	require.NoError(t, err)
@@ -891,6 +932,13 @@ func verifyCountingStatisticsFromSecondary(t *testing.T, db *pg.DB) {
 		}
 	}
 
+	// Check the prefix pool utilization from the per-pool statistics.
+	prefixPools, err := dbmodel.GetPrefixPoolsWithUtilization(db)
+	require.NoError(t, err)
+	require.Len(t, prefixPools, 1)
+	require.Equal(t, "2001:db8:3:8000::/64", prefixPools[0].Prefix)
+	require.EqualValues(t, 250, prefixPools[0].PdUtilization)
+
 	// Check global statistics
 	globals, err := dbmodel.GetAllStats(db)
 	require.NoError(t, err)
@@ -938,7 +986,7 @@ func TestPrepareHAEnvironment(t *testing.T) {
 	keaMock := createKeaMock(func(callNo int) (jsons []string) { return []string{} })
 
 	fa := agentcommtest.NewFakeAgents(keaMock, nil)
-	sp, err := NewStatsPuller(db, fa)
+	sp, err := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
 
 	// Assert
 	require.NoError(t, err)
@@ -961,7 +1009,7 @@ func TestStatsPullerPullStatsHAPairNotInitializedYet(t *testing.T) {
 	fa := agentcommtest.NewFakeAgents(keaMock, nil)
 
 	// prepare stats puller
-	sp, err := NewStatsPuller(db, fa)
+	sp, err := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
 	require.NoError(t, err)
 	defer sp.Shutdown()
 
@@ -1004,7 +1052,7 @@ func TestStatsPullerPullStatsHAPairHealthy(t *testing.T) {
 	fa := agentcommtest.NewFakeAgents(keaMock, nil)
 
 	// prepare stats puller
-	sp, err := NewStatsPuller(db, fa)
+	sp, err := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
 	require.NoError(t, err)
 	defer sp.Shutdown()
 
@@ -1043,7 +1091,7 @@ func TestStatsPullerPullStatsHAPairPrimaryIsDownSecondaryIsReady(t *testing.T) {
 	fa := agentcommtest.NewFakeAgents(keaMock, nil)
 
 	// prepare stats puller
-	sp, err := NewStatsPuller(db, fa)
+	sp, err := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
 	require.NoError(t, err)
 	defer sp.Shutdown()
 
@@ -1082,7 +1130,7 @@ func TestStatsPullerPullStatsHAPairPrimaryIsDownSecondaryIsDown(t *testing.T) {
 	fa := agentcommtest.NewFakeAgents(keaMock, nil)
 
 	// prepare stats puller
-	sp, err := NewStatsPuller(db, fa)
+	sp, err := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
 	require.NoError(t, err)
 	defer sp.Shutdown()
 
@@ -1136,7 +1184,7 @@ func TestProcessAppResponsesForResponseWithBigNumbers(t *testing.T) {
 
 	_ = dbmodel.InitializeSettings(db, 0)
 	fa := agentcommtest.NewFakeAgents(nil, nil)
-	puller, _ := NewStatsPuller(db, fa)
+	puller, _ := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
 
 	var response []StatLeaseGetResponse
 	_ = json.Unmarshal(statisticGetAllBigNumbersJSON, &response)
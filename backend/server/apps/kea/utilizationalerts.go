@@ -0,0 +1,319 @@
+package kea
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	dbops "isc.org/stork/server/database"
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/eventcenter"
+)
+
+// Level of the utilization alert.
+type utilizationAlertLevel int
+
+// Utilization alert levels ordered by severity.
+const (
+	utilizationAlertNone utilizationAlertLevel = iota
+	utilizationAlertWarning
+	utilizationAlertError
+)
+
+// Returns the event level corresponding to the alert level.
+func (l utilizationAlertLevel) toEventLevel() dbmodel.EventLevel {
+	switch l {
+	case utilizationAlertError:
+		return dbmodel.EvError
+	case utilizationAlertWarning:
+		return dbmodel.EvWarning
+	default:
+		return dbmodel.EvInfo
+	}
+}
+
+// Returns the alert level corresponding to the level of the raised alert.
+func newUtilizationAlertLevel(alert *dbmodel.UtilizationAlert) utilizationAlertLevel {
+	switch {
+	case alert == nil:
+		return utilizationAlertNone
+	case alert.Level == dbmodel.EvError:
+		return utilizationAlertError
+	default:
+		return utilizationAlertWarning
+	}
+}
+
+// Returns the alert level name used in the event texts.
+func (l utilizationAlertLevel) String() string {
+	switch l {
+	case utilizationAlertError:
+		return "error"
+	case utilizationAlertWarning:
+		return "warning"
+	default:
+		return "none"
+	}
+}
+
+// Utilization thresholds in percent. A zero threshold disables the alerts
+// of the respective level.
+type utilizationThresholds struct {
+	warningThreshold int16
+	errorThreshold   int16
+	hysteresis       int16
+}
+
+// Returns the alert level for the utilization expressed in permille. The
+// current level is the level of the alert raised in the previous pull.
+// The raised alert is kept until the utilization drops below its threshold
+// decreased by the hysteresis. It prevents the alerts from flapping when
+// the utilization oscillates around the threshold.
+func (t utilizationThresholds) getAlertLevel(utilization int16, current utilizationAlertLevel) utilizationAlertLevel {
+	if t.errorThreshold > 0 {
+		if utilization >= t.errorThreshold*10 ||
+			(current == utilizationAlertError && utilization >= (t.errorThreshold-t.hysteresis)*10) {
+			return utilizationAlertError
+		}
+	}
+	if t.warningThreshold > 0 {
+		if utilization >= t.warningThreshold*10 ||
+			(current >= utilizationAlertWarning && utilization >= (t.warningThreshold-t.hysteresis)*10) {
+			return utilizationAlertWarning
+		}
+	}
+	return utilizationAlertNone
+}
+
+// Returns the threshold of the alert level.
+func (t utilizationThresholds) getThreshold(level utilizationAlertLevel) int16 {
+	if level == utilizationAlertError {
+		return t.errorThreshold
+	}
+	return t.warningThreshold
+}
+
+// Identifies the alert raised for a subnet, a shared network or a prefix
+// pool.
+type utilizationAlertKey struct {
+	subnetID        int64
+	sharedNetworkID int64
+	prefixPoolID    int64
+	kind            dbmodel.UtilizationAlertKind
+}
+
+// Compares the subnet and shared network utilizations with the thresholds
+// and raises or clears the alerts. The thresholds are taken from the
+// subnet's rule, the shared network's rule or the global settings, in this
+// order. Each change of the alert level is reported in an event.
+type utilizationAlertEvaluator struct {
+	db                 dbops.DBI
+	eventCenter        eventcenter.EventCenter
+	global             utilizationThresholds
+	subnetRules        map[int64]*dbmodel.UtilizationAlertRule
+	sharedNetworkRules map[int64]*dbmodel.UtilizationAlertRule
+	alerts             map[utilizationAlertKey]*dbmodel.UtilizationAlert
+}
+
+// Creates the evaluator. It fetches the global thresholds, the alert rules
+// and the currently raised alerts from the database.
+func newUtilizationAlertEvaluator(db *dbops.PgDB, eventCenter eventcenter.EventCenter) (*utilizationAlertEvaluator, error) {
+	evaluator := &utilizationAlertEvaluator{
+		db:                 db,
+		eventCenter:        eventCenter,
+		subnetRules:        make(map[int64]*dbmodel.UtilizationAlertRule),
+		sharedNetworkRules: make(map[int64]*dbmodel.UtilizationAlertRule),
+		alerts:             make(map[utilizationAlertKey]*dbmodel.UtilizationAlert),
+	}
+	for name, threshold := range map[string]*int16{
+		"utilization_warning_threshold": &evaluator.global.warningThreshold,
+		"utilization_error_threshold":   &evaluator.global.errorThreshold,
+		"utilization_alert_hysteresis":  &evaluator.global.hysteresis,
+	} {
+		value, err := dbmodel.GetSettingInt(db, name)
+		if err != nil {
+			return nil, err
+		}
+		*threshold = int16(value)
+	}
+
+	rules, err := dbmodel.GetAllUtilizationAlertRules(db)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		if rules[i].SubnetID != 0 {
+			evaluator.subnetRules[rules[i].SubnetID] = &rules[i]
+		} else {
+			evaluator.sharedNetworkRules[rules[i].SharedNetworkID] = &rules[i]
+		}
+	}
+
+	alerts, err := dbmodel.GetAllUtilizationAlerts(db)
+	if err != nil {
+		return nil, err
+	}
+	for i := range alerts {
+		key := utilizationAlertKey{alerts[i].SubnetID, alerts[i].SharedNetworkID, alerts[i].PrefixPoolID, alerts[i].Kind}
+		evaluator.alerts[key] = &alerts[i]
+	}
+	return evaluator, nil
+}
+
+// Returns the thresholds specified in the rule. The disabled rule
+// disables all alerts.
+func (e *utilizationAlertEvaluator) getRuleThresholds(rule *dbmodel.UtilizationAlertRule) utilizationThresholds {
+	if !rule.Enabled {
+		return utilizationThresholds{}
+	}
+	return utilizationThresholds{
+		warningThreshold: rule.WarningThreshold,
+		errorThreshold:   rule.ErrorThreshold,
+		hysteresis:       e.global.hysteresis,
+	}
+}
+
+// Returns the thresholds applicable to the subnet. The subnet's rule takes
+// precedence over the rule of its shared network.
+func (e *utilizationAlertEvaluator) getSubnetThresholds(subnet *dbmodel.Subnet) utilizationThresholds {
+	if rule, ok := e.subnetRules[subnet.ID]; ok {
+		return e.getRuleThresholds(rule)
+	}
+	return e.getSharedNetworkThresholds(subnet.SharedNetworkID)
+}
+
+// Returns the thresholds applicable to the shared network.
+func (e *utilizationAlertEvaluator) getSharedNetworkThresholds(sharedNetworkID int64) utilizationThresholds {
+	if rule, ok := e.sharedNetworkRules[sharedNetworkID]; ok {
+		return e.getRuleThresholds(rule)
+	}
+	return e.global
+}
+
+// Evaluates the address and delegated prefix utilization of the subnet.
+func (e *utilizationAlertEvaluator) evaluateSubnet(subnet *dbmodel.Subnet) error {
+	thresholds := e.getSubnetThresholds(subnet)
+	describe := func() (string, []any) {
+		return "{subnet}", []any{subnet}
+	}
+	err := e.evaluate(utilizationAlertKey{subnetID: subnet.ID, kind: dbmodel.UtilizationAlertKindAddress},
+		thresholds, subnet.AddrUtilization, describe)
+	if err != nil {
+		return err
+	}
+	return e.evaluate(utilizationAlertKey{subnetID: subnet.ID, kind: dbmodel.UtilizationAlertKindDelegatedPrefix},
+		thresholds, subnet.PdUtilization, describe)
+}
+
+// Evaluates the address and delegated prefix utilization of the shared
+// network. The utilizations are expressed in permille.
+func (e *utilizationAlertEvaluator) evaluateSharedNetwork(sharedNetworkID int64, addrUtilization, pdUtilization int16) error {
+	thresholds := e.getSharedNetworkThresholds(sharedNetworkID)
+	// The shared network name is only needed in the event text, so it is
+	// fetched when the alert level changes.
+	describe := func() (string, []any) {
+		sharedNetwork, err := dbmodel.GetSharedNetwork(e.db, sharedNetworkID)
+		if err != nil || sharedNetwork == nil {
+			return fmt.Sprintf("shared network with ID %d", sharedNetworkID), nil
+		}
+		return fmt.Sprintf("shared network %s", sharedNetwork.Name), nil
+	}
+	err := e.evaluate(utilizationAlertKey{sharedNetworkID: sharedNetworkID, kind: dbmodel.UtilizationAlertKindAddress},
+		thresholds, addrUtilization, describe)
+	if err != nil {
+		return err
+	}
+	return e.evaluate(utilizationAlertKey{sharedNetworkID: sharedNetworkID, kind: dbmodel.UtilizationAlertKindDelegatedPrefix},
+		thresholds, pdUtilization, describe)
+}
+
+// Compares the utilization with the thresholds and updates the alert. The
+// describe function returns the subject of the event text and the objects
+// passed to the event center. It is called only if the alert level changes.
+func (e *utilizationAlertEvaluator) evaluate(key utilizationAlertKey, thresholds utilizationThresholds, utilization int16, describe func() (string, []any)) error {
+	alert := e.alerts[key]
+	current := newUtilizationAlertLevel(alert)
+	level := thresholds.getAlertLevel(utilization, current)
+
+	if level == utilizationAlertNone {
+		if alert == nil {
+			return nil
+		}
+		if err := dbmodel.DeleteUtilizationAlert(e.db, alert.ID); err != nil {
+			return err
+		}
+		delete(e.alerts, key)
+	} else {
+		if alert == nil {
+			alert = &dbmodel.UtilizationAlert{
+				SubnetID:        key.subnetID,
+				SharedNetworkID: key.sharedNetworkID,
+				Kind:            key.kind,
+			}
+		}
+		if level == current && alert.Utilization == utilization {
+			return nil
+		}
+		alert.Level = level.toEventLevel()
+		alert.Utilization = utilization
+		if err := dbmodel.SetUtilizationAlert(e.db, alert); err != nil {
+			return err
+		}
+		e.alerts[key] = alert
+	}
+	if level == current {
+		return nil
+	}
+
+	subject, objects := describe()
+	kind := "Address"
+	if key.kind == dbmodel.UtilizationAlertKindDelegatedPrefix {
+		kind = "Delegated prefix"
+	}
+	percent := float64(utilization) / 10
+
+	switch {
+	case level > current:
+		text := fmt.Sprintf("%s utilization in %s reached %.1f%%, exceeding the %s threshold of %d%%",
+			kind, subject, percent, level, thresholds.getThreshold(level))
+		if level == utilizationAlertError {
+			e.eventCenter.AddErrorEvent(text, objects...)
+		} else {
+			e.eventCenter.AddWarningEvent(text, objects...)
+		}
+	case level == utilizationAlertNone:
+		text := fmt.Sprintf("%s utilization in %s dropped to %.1f%%; the %s alert has been cleared",
+			kind, subject, percent, current)
+		e.eventCenter.AddInfoEvent(text, objects...)
+	default:
+		text := fmt.Sprintf("%s utilization in %s dropped to %.1f%%; the %s alert has been lowered to %s",
+			kind, subject, percent, current, level)
+		e.eventCenter.AddInfoEvent(text, objects...)
+	}
+	return nil
+}
+
+// Raises and clears the utilization alerts for the subnets and shared
+// networks whose statistics have been updated. It returns the last error.
+func (statsPuller *StatsPuller) evaluateUtilizationAlerts(subnets []*dbmodel.Subnet, sharedNetworks map[int64]*sharedNetworkStats) error {
+	evaluator, err := newUtilizationAlertEvaluator(statsPuller.DB, statsPuller.EventCenter)
+	if err != nil {
+		return errors.WithMessage(err, "cannot evaluate utilization alerts")
+	}
+	var lastErr error
+	for _, sn := range subnets {
+		if err := evaluator.evaluateSubnet(sn); err != nil {
+			lastErr = err
+			log.WithError(err).Errorf("Cannot evaluate utilization alerts for subnet %d", sn.ID)
+		}
+	}
+	for id, stats := range sharedNetworks {
+		addrUtilization := toPermille(stats.GetAddressUtilization())
+		pdUtilization := toPermille(stats.GetDelegatedPrefixUtilization())
+		if err := evaluator.evaluateSharedNetwork(id, addrUtilization, pdUtilization); err != nil {
+			lastErr = err
+			log.WithError(err).Errorf("Cannot evaluate utilization alerts for shared network %d", id)
+		}
+	}
+	return lastErr
+}
@@ -0,0 +1,245 @@
+package kea
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	storktest "isc.org/stork/server/test/dbmodel"
+)
+
+// Test that the alert level is computed from the utilization taking the
+// hysteresis into account.
+func TestGetUtilizationAlertLevel(t *testing.T) {
+	thresholds := utilizationThresholds{
+		warningThreshold: 80,
+		errorThreshold:   90,
+		hysteresis:       5,
+	}
+
+	testCases := []struct {
+		name        string
+		utilization int16
+		current     utilizationAlertLevel
+		expected    utilizationAlertLevel
+	}{
+		{"below warning", 799, utilizationAlertNone, utilizationAlertNone},
+		{"at warning", 800, utilizationAlertNone, utilizationAlertWarning},
+		{"at error", 900, utilizationAlertNone, utilizationAlertError},
+		{"escalate to error", 950, utilizationAlertWarning, utilizationAlertError},
+		{"keep warning within hysteresis", 750, utilizationAlertWarning, utilizationAlertWarning},
+		{"clear warning below hysteresis", 749, utilizationAlertWarning, utilizationAlertNone},
+		{"keep error within hysteresis", 850, utilizationAlertError, utilizationAlertError},
+		{"lower error to warning", 849, utilizationAlertError, utilizationAlertWarning},
+		{"keep warning after error within hysteresis", 760, utilizationAlertError, utilizationAlertWarning},
+		{"clear error", 100, utilizationAlertError, utilizationAlertNone},
+		{"no error raised within hysteresis", 870, utilizationAlertNone, utilizationAlertWarning},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(t, testCase.expected, thresholds.getAlertLevel(testCase.utilization, testCase.current))
+		})
+	}
+}
+
+// Test that the zero thresholds disable the alerts of the respective level.
+func TestGetUtilizationAlertLevelDisabled(t *testing.T) {
+	thresholds := utilizationThresholds{
+		warningThreshold: 0,
+		errorThreshold:   90,
+		hysteresis:       5,
+	}
+	require.Equal(t, utilizationAlertNone, thresholds.getAlertLevel(899, utilizationAlertNone))
+	require.Equal(t, utilizationAlertError, thresholds.getAlertLevel(900, utilizationAlertNone))
+	require.Equal(t, utilizationAlertNone, thresholds.getAlertLevel(849, utilizationAlertError))
+
+	thresholds = utilizationThresholds{}
+	require.Equal(t, utilizationAlertNone, thresholds.getAlertLevel(1000, utilizationAlertError))
+}
+
+// Test that the alerts are raised, escalated, lowered and cleared as the
+// subnet utilization changes and that each change is reported in an event.
+func TestEvaluateSubnetUtilizationAlerts(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	err := dbmodel.InitializeSettings(db, 0)
+	require.NoError(t, err)
+
+	subnet := &dbmodel.Subnet{
+		Prefix: "192.0.2.0/24",
+	}
+	err = dbmodel.AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	fec := &storktest.FakeEventCenter{}
+
+	// Evaluates the utilization and returns the current alerts.
+	evaluate := func(utilization int16) []dbmodel.UtilizationAlert {
+		evaluator, err := newUtilizationAlertEvaluator(db, fec)
+		require.NoError(t, err)
+		subnet.AddrUtilization = utilization
+		err = evaluator.evaluateSubnet(subnet)
+		require.NoError(t, err)
+		alerts, err := dbmodel.GetAllUtilizationAlerts(db)
+		require.NoError(t, err)
+		return alerts
+	}
+
+	// Below the thresholds.
+	alerts := evaluate(500)
+	require.Empty(t, alerts)
+	require.Empty(t, fec.Events)
+
+	// Warning.
+	alerts = evaluate(820)
+	require.Len(t, alerts, 1)
+	require.Equal(t, dbmodel.EvWarning, alerts[0].Level)
+	require.Equal(t, dbmodel.UtilizationAlertKindAddress, alerts[0].Kind)
+	require.EqualValues(t, 820, alerts[0].Utilization)
+	require.Len(t, fec.Events, 1)
+	require.Equal(t, dbmodel.EvWarning, fec.Events[0].Level)
+	require.Contains(t, fec.Events[0].Text, "Address utilization in <subnet")
+	require.Contains(t, fec.Events[0].Text, "reached 82.0%, exceeding the warning threshold of 80%")
+	require.Equal(t, subnet.ID, fec.Events[0].Relations.SubnetID)
+
+	// The utilization changes but the level remains. No event is expected.
+	alerts = evaluate(830)
+	require.Len(t, alerts, 1)
+	require.EqualValues(t, 830, alerts[0].Utilization)
+	require.Len(t, fec.Events, 1)
+
+	// Error.
+	alerts = evaluate(910)
+	require.Len(t, alerts, 1)
+	require.Equal(t, dbmodel.EvError, alerts[0].Level)
+	require.Len(t, fec.Events, 2)
+	require.Equal(t, dbmodel.EvError, fec.Events[1].Level)
+	require.Contains(t, fec.Events[1].Text, "exceeding the error threshold of 90%")
+
+	// Within the hysteresis.
+	alerts = evaluate(860)
+	require.Len(t, alerts, 1)
+	require.Equal(t, dbmodel.EvError, alerts[0].Level)
+	require.Len(t, fec.Events, 2)
+
+	// Lowered to warning.
+	alerts = evaluate(840)
+	require.Len(t, alerts, 1)
+	require.Equal(t, dbmodel.EvWarning, alerts[0].Level)
+	require.Len(t, fec.Events, 3)
+	require.Equal(t, dbmodel.EvInfo, fec.Events[2].Level)
+	require.Contains(t, fec.Events[2].Text, "dropped to 84.0%; the error alert has been lowered to warning")
+
+	// Cleared.
+	alerts = evaluate(700)
+	require.Empty(t, alerts)
+	require.Len(t, fec.Events, 4)
+	require.Equal(t, dbmodel.EvInfo, fec.Events[3].Level)
+	require.Contains(t, fec.Events[3].Text, "dropped to 70.0%; the warning alert has been cleared")
+}
+
+// Test that the subnet rule overrides the shared network rule which
+// overrides the global thresholds.
+func TestEvaluateUtilizationAlertRules(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	err := dbmodel.InitializeSettings(db, 0)
+	require.NoError(t, err)
+
+	sharedNetwork := &dbmodel.SharedNetwork{
+		Name:   "frog",
+		Family: 4,
+	}
+	err = dbmodel.AddSharedNetwork(db, sharedNetwork)
+	require.NoError(t, err)
+
+	subnets := []*dbmodel.Subnet{
+		{Prefix: "192.0.2.0/24", SharedNetworkID: sharedNetwork.ID, AddrUtilization: 600},
+		{Prefix: "192.0.3.0/24", SharedNetworkID: sharedNetwork.ID, AddrUtilization: 600},
+		{Prefix: "192.0.4.0/24", AddrUtilization: 600},
+	}
+	for _, subnet := range subnets {
+		err = dbmodel.AddSubnet(db, subnet)
+		require.NoError(t, err)
+	}
+
+	// The first subnet has its own rule.
+	err = dbmodel.SetUtilizationAlertRule(db, &dbmodel.UtilizationAlertRule{
+		SubnetID:         subnets[0].ID,
+		Enabled:          true,
+		WarningThreshold: 40,
+		ErrorThreshold:   50,
+	})
+	require.NoError(t, err)
+
+	// The second subnet inherits the shared network rule.
+	err = dbmodel.SetUtilizationAlertRule(db, &dbmodel.UtilizationAlertRule{
+		SharedNetworkID:  sharedNetwork.ID,
+		Enabled:          true,
+		WarningThreshold: 55,
+		ErrorThreshold:   0,
+	})
+	require.NoError(t, err)
+
+	fec := &storktest.FakeEventCenter{}
+	evaluator, err := newUtilizationAlertEvaluator(db, fec)
+	require.NoError(t, err)
+
+	for _, subnet := range subnets {
+		err = evaluator.evaluateSubnet(subnet)
+		require.NoError(t, err)
+	}
+	err = evaluator.evaluateSharedNetwork(sharedNetwork.ID, 600, 0)
+	require.NoError(t, err)
+
+	alerts, err := dbmodel.GetAllUtilizationAlerts(db)
+	require.NoError(t, err)
+	require.Len(t, alerts, 3)
+
+	// The subnet rule.
+	require.Equal(t, subnets[0].ID, alerts[0].SubnetID)
+	require.Equal(t, dbmodel.EvError, alerts[0].Level)
+
+	// The shared network rule applies to the second subnet and the
+	// shared network. The third subnet is below the global thresholds.
+	require.Equal(t, dbmodel.EvWarning, alerts[1].Level)
+	require.Equal(t, dbmodel.EvWarning, alerts[2].Level)
+	ids := []int64{alerts[1].SubnetID, alerts[2].SubnetID}
+	require.Contains(t, ids, subnets[1].ID)
+	require.Contains(t, ids, int64(0))
+
+	require.Len(t, fec.Events, 3)
+	var sharedNetworkEvent *dbmodel.Event
+	for _, event := range fec.Events {
+		if event.Relations.SubnetID == 0 {
+			sharedNetworkEvent = event
+		}
+	}
+	require.NotNil(t, sharedNetworkEvent)
+	require.Contains(t, sharedNetworkEvent.Text, "Address utilization in shared network frog reached 60.0%")
+
+	// Disabling the subnet rule clears the subnet alert.
+	err = dbmodel.SetUtilizationAlertRule(db, &dbmodel.UtilizationAlertRule{
+		SubnetID:         subnets[0].ID,
+		Enabled:          false,
+		WarningThreshold: 40,
+		ErrorThreshold:   50,
+	})
+	require.NoError(t, err)
+
+	evaluator, err = newUtilizationAlertEvaluator(db, fec)
+	require.NoError(t, err)
+	err = evaluator.evaluateSubnet(subnets[0])
+	require.NoError(t, err)
+
+	alerts, err = dbmodel.GetAllUtilizationAlerts(db)
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+	require.Len(t, fec.Events, 4)
+	require.Contains(t, fec.Events[3].Text, "the error alert has been cleared")
+}
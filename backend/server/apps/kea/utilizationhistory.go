@@ -0,0 +1,87 @@
+package kea
+
+import (
+	"github.com/pkg/errors"
+	dbmodel "isc.org/stork/server/database/model"
+	storkutil "isc.org/stork/util"
+)
+
+// Converts the utilization fraction to permille like it is stored in the
+// database.
+func toPermille(utilization float64) int16 {
+	return int16(utilization * 1000)
+}
+
+// Creates the utilization history samples of the subnets, prefix pools,
+// shared networks and the global utilization of each family having at
+// least one subnet. The family of a shared network is taken from its
+// subnets.
+func newUtilizationSamples(subnets []*dbmodel.Subnet, prefixPools []*dbmodel.PrefixPool, sharedNetworks map[int64]*sharedNetworkStats, global *globalStats) []dbmodel.UtilizationSample {
+	var samples []dbmodel.UtilizationSample
+	families := make(map[int]bool)
+	sharedNetworkFamilies := make(map[int64]int)
+	for _, sn := range subnets {
+		family := sn.GetFamily()
+		families[family] = true
+		if sn.SharedNetworkID != 0 {
+			sharedNetworkFamilies[sn.SharedNetworkID] = family
+		}
+		samples = append(samples, dbmodel.UtilizationSample{
+			SubnetID:        sn.ID,
+			Family:          family,
+			AddrUtilization: sn.AddrUtilization,
+			PdUtilization:   sn.PdUtilization,
+		})
+	}
+
+	for _, pool := range prefixPools {
+		samples = append(samples, dbmodel.UtilizationSample{
+			PrefixPoolID:  pool.ID,
+			Family:        6,
+			PdUtilization: pool.PdUtilization,
+		})
+	}
+
+	for id, stats := range sharedNetworks {
+		family, ok := sharedNetworkFamilies[id]
+		if !ok {
+			continue
+		}
+		samples = append(samples, dbmodel.UtilizationSample{
+			SharedNetworkID: id,
+			Family:          family,
+			AddrUtilization: toPermille(stats.GetAddressUtilization()),
+			PdUtilization:   toPermille(stats.GetDelegatedPrefixUtilization()),
+		})
+	}
+
+	if families[4] {
+		samples = append(samples, dbmodel.UtilizationSample{
+			Family:          4,
+			AddrUtilization: toPermille(global.totalAssignedIPv4Addresses.DivideSafeBy(global.totalIPv4Addresses)),
+		})
+	}
+	if families[6] {
+		samples = append(samples, dbmodel.UtilizationSample{
+			Family:          6,
+			AddrUtilization: toPermille(global.totalAssignedIPv6Addresses.DivideSafeBy(global.totalIPv6Addresses)),
+			PdUtilization:   toPermille(global.totalAssignedDelegatedPrefixes.DivideSafeBy(global.totalDelegatedPrefixes)),
+		})
+	}
+	return samples
+}
+
+// Adds the current utilization of the subnets, prefix pools, shared
+// networks and the global utilization to the history and deletes the
+// history periods older than their retention.
+func (statsPuller *StatsPuller) storeUtilizationHistory(subnets []*dbmodel.Subnet, prefixPools []*dbmodel.PrefixPool, sharedNetworks map[int64]*sharedNetworkStats, global *globalStats) error {
+	now := storkutil.UTCNow()
+	samples := newUtilizationSamples(subnets, prefixPools, sharedNetworks, global)
+	if err := dbmodel.AddUtilizationSamples(statsPuller.DB, samples, now); err != nil {
+		return errors.WithMessage(err, "cannot store utilization history")
+	}
+	if err := dbmodel.AgeOffUtilizationHistory(statsPuller.DB, now); err != nil {
+		return errors.WithMessage(err, "cannot age off utilization history")
+	}
+	return nil
+}
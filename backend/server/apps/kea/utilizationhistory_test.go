@@ -0,0 +1,63 @@
+package kea
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Test that the utilization samples are created for the subnets, shared
+// networks and the families having at least one subnet.
+func TestNewUtilizationSamples(t *testing.T) {
+	subnets := []*dbmodel.Subnet{
+		{ID: 1, Prefix: "192.0.2.0/24", SharedNetworkID: 10, AddrUtilization: 500},
+		{ID: 2, Prefix: "192.0.3.0/24", AddrUtilization: 250},
+	}
+
+	sharedNetwork := newSharedNetworkStats()
+	sharedNetwork.totalAddresses.AddUint64(100)
+	sharedNetwork.totalAssignedAddresses.AddUint64(40)
+	sharedNetworks := map[int64]*sharedNetworkStats{
+		10: sharedNetwork,
+		// The shared network without updated subnets has unknown family.
+		11: newSharedNetworkStats(),
+	}
+
+	global := newGlobalStats()
+	global.totalIPv4Addresses.AddUint64(200)
+	global.totalAssignedIPv4Addresses.AddUint64(50)
+
+	samples := newUtilizationSamples(subnets, nil, sharedNetworks, global)
+	require.ElementsMatch(t, []dbmodel.UtilizationSample{
+		{SubnetID: 1, Family: 4, AddrUtilization: 500},
+		{SubnetID: 2, Family: 4, AddrUtilization: 250},
+		{SharedNetworkID: 10, Family: 4, AddrUtilization: 400},
+		{Family: 4, AddrUtilization: 250},
+	}, samples)
+}
+
+// Test that the global IPv6 sample includes the delegated prefix
+// utilization and the prefix pools have their own samples.
+func TestNewUtilizationSamplesIPv6(t *testing.T) {
+	subnets := []*dbmodel.Subnet{
+		{ID: 1, Prefix: "2001:db8:1::/64", AddrUtilization: 100, PdUtilization: 300},
+	}
+
+	global := newGlobalStats()
+	global.totalIPv6Addresses.AddUint64(1000)
+	global.totalAssignedIPv6Addresses.AddUint64(100)
+	global.totalDelegatedPrefixes.AddUint64(10)
+	global.totalAssignedDelegatedPrefixes.AddUint64(3)
+
+	prefixPools := []*dbmodel.PrefixPool{
+		{ID: 5, Prefix: "2001:db8:1:8000::/64", PdUtilization: 700},
+	}
+
+	samples := newUtilizationSamples(subnets, prefixPools, map[int64]*sharedNetworkStats{}, global)
+	require.ElementsMatch(t, []dbmodel.UtilizationSample{
+		{SubnetID: 1, Family: 6, AddrUtilization: 100, PdUtilization: 300},
+		{PrefixPoolID: 5, Family: 6, PdUtilization: 700},
+		{Family: 6, AddrUtilization: 100, PdUtilization: 300},
+	}, samples)
+}
@@ -288,6 +288,9 @@ func GetMachineAndAppsState(ctx context.Context, db *dbops.PgDB, dbMachine *dbmo
 		case dbmodel.AppTypeBind9:
 			bind9.GetAppState(ctx2, agents, dbApp, eventCenter)
 			err = bind9.CommitAppIntoDB(db, dbApp, eventCenter)
+			if err == nil {
+				conditionallyBeginBind9ConfigReviews(dbApp, reviewDispatcher)
+			}
 		default:
 			err = nil
 		}
@@ -341,3 +344,22 @@ func conditionallyBeginKeaConfigReviews(dbApp *dbmodel.App, state *kea.AppStateM
 		}
 	}
 }
+
+// This function checks if a new config review should be performed for the
+// BIND 9 daemon. It is performed when the hash of the daemon's
+// configuration differs from the hash of the reviewed configuration or
+// the dispatcher's signature has changed.
+func conditionallyBeginBind9ConfigReviews(dbApp *dbmodel.App, reviewDispatcher configreview.Dispatcher) {
+	for i, daemon := range dbApp.Daemons {
+		// The configuration is nil when it couldn't be fetched from the agent.
+		if daemon.Bind9Daemon == nil || daemon.Bind9Daemon.Config == nil {
+			continue
+		}
+		if daemon.ConfigReview != nil &&
+			daemon.ConfigReview.ConfigHash == daemon.Bind9Daemon.ConfigHash &&
+			daemon.ConfigReview.Signature == reviewDispatcher.GetSignature() {
+			continue
+		}
+		_ = reviewDispatcher.BeginReview(dbApp.Daemons[i], configreview.Triggers{configreview.ConfigModified}, nil)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	bind9config "isc.org/stork/appcfg/bind9"
 	"isc.org/stork/datamodel"
 	"isc.org/stork/server/agentcomm"
 	agentcommtest "isc.org/stork/server/agentcomm/test"
@@ -225,3 +226,52 @@ func TestConditionallyBeginKeaConfigReviews(t *testing.T) {
 	require.Equal(t, configreview.StorkAgentConfigModified, dispatcher.CallLog[6].Triggers[0])
 	require.Equal(t, configreview.ConfigModified, dispatcher.CallLog[6].Triggers[1])
 }
+
+// Test that new configuration review is scheduled for the BIND 9 daemon
+// when its configuration has changed or when review dispatcher's checkers
+// have changed.
+func TestConditionallyBeginBind9ConfigReviews(t *testing.T) {
+	config, err := bind9config.Parse(`options { recursion no; };`)
+	require.NoError(t, err)
+
+	daemon := dbmodel.NewBind9Daemon(true)
+	app := &dbmodel.App{
+		Daemons: []*dbmodel.Daemon{daemon},
+	}
+	dispatcher := &storktest.FakeDispatcher{}
+
+	// The configuration hasn't been fetched. The review should not be
+	// performed.
+	conditionallyBeginBind9ConfigReviews(app, dispatcher)
+	require.Empty(t, dispatcher.CallLog)
+
+	// The configuration hasn't been reviewed yet.
+	daemon.Bind9Daemon.Config = config
+	daemon.Bind9Daemon.ConfigHash = "hash"
+	conditionallyBeginBind9ConfigReviews(app, dispatcher)
+	require.Len(t, dispatcher.CallLog, 1)
+	require.Equal(t, "BeginReview", dispatcher.CallLog[0].CallName)
+	require.Equal(t, configreview.Triggers{configreview.ConfigModified}, dispatcher.CallLog[0].Triggers)
+
+	// The configuration has been reviewed. The review should not be
+	// performed.
+	daemon.ConfigReview = &dbmodel.ConfigReview{
+		ConfigHash: "hash",
+	}
+	conditionallyBeginBind9ConfigReviews(app, dispatcher)
+	require.Len(t, dispatcher.CallLog, 2)
+	require.Equal(t, "GetSignature", dispatcher.CallLog[1].CallName)
+
+	// Modify the dispatcher's signature.
+	dispatcher.Signature = "new signature"
+	conditionallyBeginBind9ConfigReviews(app, dispatcher)
+	require.Len(t, dispatcher.CallLog, 4)
+	require.Equal(t, "BeginReview", dispatcher.CallLog[3].CallName)
+
+	// Modify the configuration.
+	daemon.ConfigReview.Signature = "new signature"
+	daemon.Bind9Daemon.ConfigHash = "new hash"
+	conditionallyBeginBind9ConfigReviews(app, dispatcher)
+	require.Len(t, dispatcher.CallLog, 5)
+	require.Equal(t, "BeginReview", dispatcher.CallLog[4].CallName)
+}
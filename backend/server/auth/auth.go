@@ -4,15 +4,103 @@ import (
 	"fmt"
 	"net/http"
 	"path"
+	"regexp"
 	"strings"
 
 	dbmodel "isc.org/stork/server/database/model"
 )
 
-// Checks if the given user is permitted to access a resource. Currently the
-// access pattern is very simple, the super-admin user can access all
-// resources. The admin-user can access all resources except those related
-// to users management.
+// Associates the REST API requests with the permission required to
+// access them by the users belonging to the custom groups. The method
+// is an HTTP method or an empty string matching any method. The pattern
+// is matched against the cleaned URL path ending with a slash. An empty
+// permission denies the access to the resource for all custom groups.
+type accessRule struct {
+	method     string
+	pattern    *regexp.Regexp
+	permission dbmodel.Permission
+}
+
+// The access rules for the custom groups. The first matching rule is
+// applied. The requests not matching any rule are rejected.
+var accessRules = []accessRule{
+	// Sensitive data.
+	{"GET", regexp.MustCompile(`^/api/app/\d+/access-points/`), ""},
+	{"GET", regexp.MustCompile(`^/api/machines/\d+/dump/$`), ""},
+	{"GET", regexp.MustCompile(`^/api/audit/`), ""},
+	{"", regexp.MustCompile(`^/api/notification-channels/`), ""},
+	// Machines and apps.
+	{"", regexp.MustCompile(`^/api/machines-server-token/$`), dbmodel.PermissionManageMachines},
+	{"PUT", regexp.MustCompile(`^/api/machines/\d+/$`), dbmodel.PermissionManageMachines},
+	{"DELETE", regexp.MustCompile(`^/api/machines/\d+/$`), dbmodel.PermissionManageMachines},
+	{"PUT", regexp.MustCompile(`^/api/apps/\d+/name/$`), dbmodel.PermissionManageMachines},
+	{"POST", regexp.MustCompile(`^/api/services/\d+/ha-actions/$`), dbmodel.PermissionManageMachines},
+	{"POST", regexp.MustCompile(`^/api/daemons/\d+/rndc-commands/$`), dbmodel.PermissionManageMachines},
+	// Host reservations.
+	{"POST", regexp.MustCompile(`^/api/hosts/`), dbmodel.PermissionManageHosts},
+	{"DELETE", regexp.MustCompile(`^/api/hosts/`), dbmodel.PermissionManageHosts},
+	// Subnets, shared networks, client classes, global parameters,
+	// configuration rollbacks and utilization alert rules.
+	{"POST", regexp.MustCompile(`^/api/subnets/`), dbmodel.PermissionManageSubnets},
+	{"DELETE", regexp.MustCompile(`^/api/subnets/`), dbmodel.PermissionManageSubnets},
+	{"POST", regexp.MustCompile(`^/api/shared-networks/`), dbmodel.PermissionManageSubnets},
+	{"DELETE", regexp.MustCompile(`^/api/shared-networks/`), dbmodel.PermissionManageSubnets},
+	{"POST", regexp.MustCompile(`^/api/client-classes/`), dbmodel.PermissionManageSubnets},
+	{"DELETE", regexp.MustCompile(`^/api/client-classes/`), dbmodel.PermissionManageSubnets},
+	{"POST", regexp.MustCompile(`^/api/kea-global-parameters/`), dbmodel.PermissionManageSubnets},
+	{"DELETE", regexp.MustCompile(`^/api/kea-global-parameters/`), dbmodel.PermissionManageSubnets},
+	{"POST", regexp.MustCompile(`^/api/config-snapshots/\d+/rollback/$`), dbmodel.PermissionManageSubnets},
+	{"PUT", regexp.MustCompile(`^/api/(subnets|shared-networks)/\d+/utilization-alert-rule/$`), dbmodel.PermissionManageSubnets},
+	// Leases.
+	{"POST", regexp.MustCompile(`^/api/leases/`), dbmodel.PermissionManageLeases},
+	{"PUT", regexp.MustCompile(`^/api/leases/`), dbmodel.PermissionManageLeases},
+	// All other information can be viewed.
+	{"GET", regexp.MustCompile(`^/api/`), dbmodel.PermissionView},
+}
+
+// The resources that can be viewed by the users whose view permission is
+// limited to specific apps, daemons or subnets. The REST API handlers serving
+// these resources return only the objects covered by the permission scopes.
+// The other resources require the view permission that is not scoped.
+var scopedViewPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^/api/subnets/(\d+/)?$`),
+	regexp.MustCompile(`^/api/hosts/(\d+/)?$`),
+}
+
+// Checks if the resource can be viewed by the users whose view permission
+// is scoped.
+func isScopedViewResource(urlPath string) bool {
+	for _, pattern := range scopedViewPatterns {
+		if pattern.MatchString(urlPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the permission required to access the resource by the users
+// belonging to the custom groups. It returns an empty string if the
+// resource cannot be accessed by these users.
+func getRequiredPermission(method, urlPath string) dbmodel.Permission {
+	for _, rule := range accessRules {
+		if (rule.method == "" || rule.method == method) && rule.pattern.MatchString(urlPath) {
+			return rule.permission
+		}
+	}
+	return ""
+}
+
+// Checks if the given user is permitted to access a resource. The
+// super-admin user can access all resources. The admin-user can access
+// all resources except those related to users management. The read-only
+// user can view all resources except the sensitive ones but cannot modify
+// anything. The users belonging to the custom groups can access the
+// resources according to the permissions granted to these groups. The
+// permissions must be populated in the user's groups before calling this
+// function. The permission scopes are not checked here because the objects
+// being accessed are not known yet. See AuthorizeTargets and AuthorizeView.
+// However, the users whose view permission is scoped can only view the
+// resources which handlers filter the objects by the scopes.
 func Authorize(user *dbmodel.SystemUser, req *http.Request) (ok bool, err error) {
 	// If there is no user (possibly the user has not signed in) or the
 	// request is nil, reject access to the resource.
@@ -35,6 +123,9 @@ func Authorize(user *dbmodel.SystemUser, req *http.Request) (ok bool, err error)
 		// access the user specific information, check if the data the user
 		// is trying to access belong to this user. If not, reject access.
 		return strings.HasPrefix(urlPath, fmt.Sprintf("/api/users/%d/", user.ID)), nil
+	} else if strings.HasPrefix(urlPath, "/api/groups/") && req.Method != "GET" {
+		// Managing the groups is a part of the users management.
+		return false, nil
 	} else if strings.HasPrefix(urlPath, "/api/sessions/") && req.Method == "DELETE" {
 		// Log out is available for all users.
 		return true, nil
@@ -45,7 +136,100 @@ func Authorize(user *dbmodel.SystemUser, req *http.Request) (ok bool, err error)
 		return true, err
 	}
 
-	// User who doesn't belong to any group is not allowed to access
-	// system resources.
-	return false, nil
+	permission := getRequiredPermission(req.Method, urlPath)
+	if permission == "" {
+		return false, nil
+	}
+
+	// The read-only user can access the same resources as a custom group
+	// with the view permission.
+	if permission == dbmodel.PermissionView && user.InGroup(&dbmodel.SystemGroup{ID: dbmodel.ReadOnlyGroupID}) {
+		return true, nil
+	}
+
+	// The scoped view permission only grants access to the resources which
+	// handlers filter the returned objects by the permission scopes.
+	if permission == dbmodel.PermissionView && !user.HasUnscopedPermission(permission) {
+		return user.HasPermission(permission) && isScopedViewResource(urlPath), nil
+	}
+
+	// Check if the user's custom groups grant the access. User who doesn't
+	// belong to any group is not allowed to access system resources.
+	return user.HasPermission(permission), nil
+}
+
+// Checks if the user's access to the viewed objects is limited by the
+// scopes of the view permission. It is the case for the users belonging
+// only to the custom groups that grant the view permission scoped to
+// specific apps, daemons or subnets.
+func IsViewScoped(user *dbmodel.SystemUser) bool {
+	if user == nil {
+		return false
+	}
+	if user.InGroup(&dbmodel.SystemGroup{ID: dbmodel.SuperAdminGroupID}) ||
+		user.InGroup(&dbmodel.SystemGroup{ID: dbmodel.AdminGroupID}) ||
+		user.InGroup(&dbmodel.SystemGroup{ID: dbmodel.ReadOnlyGroupID}) {
+		return false
+	}
+	return user.HasCustomGroups() && !user.HasUnscopedPermission(dbmodel.PermissionView)
+}
+
+// Checks if the given user is permitted to view an object associated with
+// the specified targets (e.g., the daemons owning a subnet). Contrary to
+// AuthorizeTargets, it is sufficient that the view permission covers any
+// of the targets. The users belonging to the built-in groups and the users
+// whose view permission is not scoped can view all objects. The users
+// without the view permission cannot view any objects.
+func AuthorizeView(user *dbmodel.SystemUser, targets ...dbmodel.PermissionTarget) bool {
+	if user == nil {
+		return false
+	}
+	if user.InGroup(&dbmodel.SystemGroup{ID: dbmodel.SuperAdminGroupID}) ||
+		user.InGroup(&dbmodel.SystemGroup{ID: dbmodel.AdminGroupID}) ||
+		user.InGroup(&dbmodel.SystemGroup{ID: dbmodel.ReadOnlyGroupID}) {
+		return true
+	}
+	if !user.HasPermission(dbmodel.PermissionView) {
+		return false
+	}
+	if user.HasUnscopedPermission(dbmodel.PermissionView) {
+		return true
+	}
+	for _, target := range targets {
+		if user.HasPermission(dbmodel.PermissionView, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks if the given user is permitted to perform an operation requiring
+// the specified permission on all specified targets (e.g., the daemons
+// owning a host reservation). This function is meant to be called by the
+// REST API handlers after Authorize has admitted the request. It narrows
+// down the access of the users belonging to the custom groups with the
+// permissions scoped to specific apps, daemons or subnets. The read-only
+// user is permitted to view all targets but it cannot modify them. The
+// access of the users belonging only to the other built-in groups has been
+// fully decided by Authorize, so they are not restricted by this function.
+func AuthorizeTargets(user *dbmodel.SystemUser, permission dbmodel.Permission, targets ...dbmodel.PermissionTarget) bool {
+	if user == nil {
+		return false
+	}
+	if user.InGroup(&dbmodel.SystemGroup{ID: dbmodel.SuperAdminGroupID}) ||
+		user.InGroup(&dbmodel.SystemGroup{ID: dbmodel.AdminGroupID}) {
+		return true
+	}
+	if user.InGroup(&dbmodel.SystemGroup{ID: dbmodel.ReadOnlyGroupID}) {
+		if permission == dbmodel.PermissionView {
+			return true
+		}
+		// The read-only group doesn't grant any management permissions.
+		// They may be only granted by the custom groups.
+		return user.HasCustomGroups() && user.HasPermission(permission, targets...)
+	}
+	if !user.HasCustomGroups() {
+		return true
+	}
+	return user.HasPermission(permission, targets...)
 }
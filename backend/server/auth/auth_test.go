@@ -75,3 +75,289 @@ func TestAuthorize(t *testing.T) {
 	require.False(t, authorizeAccept(t, noneGroupID, "/users/4", "GET"))
 	require.False(t, authorizeAccept(t, noneGroupID, "/users/4/password", "GET"))
 }
+
+// Helper function checking if the user belonging to a custom group with
+// the specified permissions has access to the resource.
+func authorizeAcceptCustom(t *testing.T, path, method string, permissions ...dbmodel.Permission) bool {
+	group := &dbmodel.SystemGroup{
+		ID: 100,
+	}
+	for _, p := range permissions {
+		group.Permissions = append(group.Permissions, &dbmodel.SystemGroupPermission{
+			Permission: p,
+		})
+	}
+	user := &dbmodel.SystemUser{
+		ID:     5,
+		Groups: []*dbmodel.SystemGroup{group},
+	}
+
+	req, _ := http.NewRequestWithContext(context.Background(), method, "http://example.org/api"+path, nil)
+	ok, err := Authorize(user, req)
+	require.NoError(t, err)
+
+	return ok
+}
+
+// Verify that the users belonging to the custom groups have access
+// privileges matching the permissions granted to the groups.
+func TestAuthorizeCustomGroup(t *testing.T) {
+	// A group without permissions grants no access.
+	require.False(t, authorizeAcceptCustom(t, "/machines/1/", "GET"))
+
+	// The view permission allows for reading the data.
+	require.True(t, authorizeAcceptCustom(t, "/machines/1/", "GET", dbmodel.PermissionView))
+	require.True(t, authorizeAcceptCustom(t, "/hosts", "GET", dbmodel.PermissionView))
+	require.True(t, authorizeAcceptCustom(t, "/groups", "GET", dbmodel.PermissionView))
+	require.False(t, authorizeAcceptCustom(t, "/hosts", "POST", dbmodel.PermissionView))
+	require.False(t, authorizeAcceptCustom(t, "/hosts/1", "DELETE", dbmodel.PermissionView))
+	require.False(t, authorizeAcceptCustom(t, "/machines/1", "PUT", dbmodel.PermissionView))
+
+	// The sensitive data are not available to the custom groups.
+	require.False(t, authorizeAcceptCustom(t, "/app/1/access-points/control/key", "GET", dbmodel.GetAllPermissions()...))
+	require.False(t, authorizeAcceptCustom(t, "/machines/1/dump", "GET", dbmodel.GetAllPermissions()...))
+
+	// The management permissions imply the view permission.
+	require.True(t, authorizeAcceptCustom(t, "/subnets", "GET", dbmodel.PermissionManageHosts))
+	require.True(t, authorizeAcceptCustom(t, "/hosts/new/transaction", "POST", dbmodel.PermissionManageHosts))
+	require.True(t, authorizeAcceptCustom(t, "/hosts/1", "DELETE", dbmodel.PermissionManageHosts))
+	require.False(t, authorizeAcceptCustom(t, "/subnets/1", "DELETE", dbmodel.PermissionManageHosts))
+
+	require.True(t, authorizeAcceptCustom(t, "/subnets/1", "DELETE", dbmodel.PermissionManageSubnets))
+	require.True(t, authorizeAcceptCustom(t, "/shared-networks/new/transaction", "POST", dbmodel.PermissionManageSubnets))
+	require.True(t, authorizeAcceptCustom(t, "/client-classes/1/transaction", "POST", dbmodel.PermissionManageSubnets))
+	require.False(t, authorizeAcceptCustom(t, "/client-classes/1", "DELETE", dbmodel.PermissionManageHosts))
+	require.True(t, authorizeAcceptCustom(t, "/kea-global-parameters/transaction/1/submit", "POST", dbmodel.PermissionManageSubnets))
+	require.False(t, authorizeAcceptCustom(t, "/kea-global-parameters/transaction", "POST", dbmodel.PermissionManageHosts))
+	require.False(t, authorizeAcceptCustom(t, "/hosts/1", "DELETE", dbmodel.PermissionManageSubnets))
+
+	require.True(t, authorizeAcceptCustom(t, "/leases", "POST", dbmodel.PermissionManageLeases))
+	require.True(t, authorizeAcceptCustom(t, "/leases/delete", "POST", dbmodel.PermissionManageLeases))
+	require.True(t, authorizeAcceptCustom(t, "/leases", "PUT", dbmodel.PermissionManageLeases))
+	require.True(t, authorizeAcceptCustom(t, "/leases", "GET", dbmodel.PermissionManageLeases))
+	require.False(t, authorizeAcceptCustom(t, "/leases/wipe", "POST", dbmodel.PermissionManageSubnets))
+	require.True(t, authorizeAcceptCustom(t, "/leases/declined/release", "POST", dbmodel.PermissionManageLeases))
+	require.False(t, authorizeAcceptCustom(t, "/leases/declined/release", "POST", dbmodel.PermissionManageHosts))
+
+	require.True(t, authorizeAcceptCustom(t, "/machines/1", "PUT", dbmodel.PermissionManageMachines))
+	require.True(t, authorizeAcceptCustom(t, "/machines/1", "DELETE", dbmodel.PermissionManageMachines))
+	require.True(t, authorizeAcceptCustom(t, "/machines-server-token", "PUT", dbmodel.PermissionManageMachines))
+	require.True(t, authorizeAcceptCustom(t, "/apps/1/name", "PUT", dbmodel.PermissionManageMachines))
+	require.False(t, authorizeAcceptCustom(t, "/machines/1", "PUT", dbmodel.PermissionManageHosts))
+	require.True(t, authorizeAcceptCustom(t, "/services/1/ha-actions", "POST", dbmodel.PermissionManageMachines))
+	require.True(t, authorizeAcceptCustom(t, "/config-snapshots/1/rollback", "POST", dbmodel.PermissionManageSubnets))
+	require.False(t, authorizeAcceptCustom(t, "/config-snapshots/1/rollback", "POST", dbmodel.PermissionManageMachines))
+	require.True(t, authorizeAcceptCustom(t, "/config-snapshots/1", "GET", dbmodel.PermissionView))
+	require.False(t, authorizeAcceptCustom(t, "/services/1/ha-actions", "POST", dbmodel.PermissionManageLeases))
+	require.True(t, authorizeAcceptCustom(t, "/daemons/1/rndc-commands", "POST", dbmodel.PermissionManageMachines))
+	require.False(t, authorizeAcceptCustom(t, "/daemons/1/rndc-commands", "POST", dbmodel.PermissionManageHosts))
+
+	// The users' and groups' management is not available.
+	require.False(t, authorizeAcceptCustom(t, "/users", "GET", dbmodel.GetAllPermissions()...))
+	require.False(t, authorizeAcceptCustom(t, "/groups", "POST", dbmodel.GetAllPermissions()...))
+	require.False(t, authorizeAcceptCustom(t, "/groups/100", "DELETE", dbmodel.GetAllPermissions()...))
+	require.True(t, authorizeAcceptCustom(t, "/users/5", "GET"))
+
+	// The admin can't manage the groups either.
+	require.False(t, authorizeAccept(t, dbmodel.AdminGroupID, "/groups", "POST"))
+	require.True(t, authorizeAccept(t, dbmodel.AdminGroupID, "/groups", "GET"))
+	require.True(t, authorizeAccept(t, dbmodel.SuperAdminGroupID, "/groups/100", "PUT"))
+}
+
+// Verify that the permissions scoped to the particular daemons are
+// respected when checking access to the specific targets.
+func TestAuthorizeTargets(t *testing.T) {
+	user := &dbmodel.SystemUser{
+		ID: 5,
+		Groups: []*dbmodel.SystemGroup{
+			{
+				ID: 100,
+				Permissions: []*dbmodel.SystemGroupPermission{
+					{Permission: dbmodel.PermissionManageHosts, DaemonID: 1},
+					{Permission: dbmodel.PermissionManageHosts, AppID: 2},
+				},
+			},
+		},
+	}
+
+	require.True(t, AuthorizeTargets(user, dbmodel.PermissionManageHosts, dbmodel.PermissionTarget{DaemonID: 1}))
+	require.True(t, AuthorizeTargets(user, dbmodel.PermissionManageHosts, dbmodel.PermissionTarget{AppID: 2, DaemonID: 3}))
+	require.True(t, AuthorizeTargets(user, dbmodel.PermissionManageHosts,
+		dbmodel.PermissionTarget{AppID: 1, DaemonID: 1},
+		dbmodel.PermissionTarget{AppID: 2, DaemonID: 3}))
+	// All targets must be covered.
+	require.False(t, AuthorizeTargets(user, dbmodel.PermissionManageHosts,
+		dbmodel.PermissionTarget{AppID: 1, DaemonID: 1},
+		dbmodel.PermissionTarget{AppID: 3, DaemonID: 4}))
+	require.False(t, AuthorizeTargets(user, dbmodel.PermissionManageSubnets, dbmodel.PermissionTarget{DaemonID: 1}))
+	// The scoped management permission implies viewing the same targets.
+	require.True(t, AuthorizeTargets(user, dbmodel.PermissionView, dbmodel.PermissionTarget{DaemonID: 1}))
+
+	// The built-in groups are not restricted.
+	admin := &dbmodel.SystemUser{ID: 6, Groups: []*dbmodel.SystemGroup{{ID: dbmodel.AdminGroupID}}}
+	require.True(t, AuthorizeTargets(admin, dbmodel.PermissionManageSubnets, dbmodel.PermissionTarget{DaemonID: 5}))
+	superAdmin := &dbmodel.SystemUser{ID: 7, Groups: []*dbmodel.SystemGroup{{ID: dbmodel.SuperAdminGroupID}}}
+	require.True(t, AuthorizeTargets(superAdmin, dbmodel.PermissionManageSubnets, dbmodel.PermissionTarget{DaemonID: 5}))
+
+	require.False(t, AuthorizeTargets(nil, dbmodel.PermissionView))
+}
+
+// Verify that the users belonging to the read-only group can view all
+// resources except the sensitive ones but cannot modify anything.
+func TestAuthorizeReadOnly(t *testing.T) {
+	require.True(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/machines/1/", "GET"))
+	require.True(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/hosts", "GET"))
+	require.True(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/leases?text=192.0.2.1", "GET"))
+	require.True(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/settings", "GET"))
+	require.True(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/users/5", "GET"))
+	require.True(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/sessions", "DELETE"))
+
+	require.False(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/users", "GET"))
+	require.False(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/machines-server-token", "GET"))
+	require.False(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/machines/1/dump", "GET"))
+	require.False(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/app/1/access-points/control/key", "GET"))
+
+	require.False(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/hosts/new/transaction", "POST"))
+	require.False(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/hosts/1", "DELETE"))
+	require.False(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/subnets/1/transaction", "POST"))
+	require.False(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/shared-networks/1", "DELETE"))
+	require.False(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/machines/1", "PUT"))
+	require.False(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/machines/1", "DELETE"))
+	require.False(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/settings", "PUT"))
+	require.False(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/machines", "POST"))
+	require.False(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/groups", "POST"))
+}
+
+// Verify that the audit trail is available only to the admin and
+// super-admin users.
+func TestAuthorizeAudit(t *testing.T) {
+	require.True(t, authorizeAccept(t, dbmodel.SuperAdminGroupID, "/audit", "GET"))
+	require.True(t, authorizeAccept(t, dbmodel.AdminGroupID, "/audit", "GET"))
+	require.True(t, authorizeAccept(t, dbmodel.AdminGroupID, "/audit/export?format=csv", "GET"))
+	require.False(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/audit", "GET"))
+	require.False(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/audit/export", "GET"))
+}
+
+// Verify that the notification channels are available only to the admin
+// and super-admin users. The webhook URLs often include the credentials.
+func TestAuthorizeNotificationChannels(t *testing.T) {
+	require.True(t, authorizeAccept(t, dbmodel.SuperAdminGroupID, "/notification-channels", "GET"))
+	require.True(t, authorizeAccept(t, dbmodel.AdminGroupID, "/notification-channels", "POST"))
+	require.True(t, authorizeAccept(t, dbmodel.AdminGroupID, "/notification-channels/1", "PUT"))
+	require.False(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/notification-channels", "GET"))
+	require.False(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/notification-channels/1", "GET"))
+	require.False(t, authorizeAcceptCustom(t, "/notification-channels", "GET", dbmodel.GetAllPermissions()...))
+	require.False(t, authorizeAcceptCustom(t, "/notification-channels/1", "DELETE", dbmodel.GetAllPermissions()...))
+}
+
+// Verify that the utilization alert rules can be viewed by the read-only
+// users and modified by the users permitted to manage the subnets.
+func TestAuthorizeUtilizationAlertRules(t *testing.T) {
+	require.True(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/utilization-alert-rules", "GET"))
+	require.True(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/utilization-alerts", "GET"))
+	require.False(t, authorizeAccept(t, dbmodel.ReadOnlyGroupID, "/subnets/1/utilization-alert-rule", "PUT"))
+	require.True(t, authorizeAccept(t, dbmodel.AdminGroupID, "/subnets/1/utilization-alert-rule", "PUT"))
+	require.True(t, authorizeAcceptCustom(t, "/subnets/1/utilization-alert-rule", "PUT", dbmodel.PermissionManageSubnets))
+	require.True(t, authorizeAcceptCustom(t, "/subnets/1/utilization-alert-rule", "DELETE", dbmodel.PermissionManageSubnets))
+	require.True(t, authorizeAcceptCustom(t, "/shared-networks/1/utilization-alert-rule", "PUT", dbmodel.PermissionManageSubnets))
+	require.True(t, authorizeAcceptCustom(t, "/shared-networks/1/utilization-alert-rule", "DELETE", dbmodel.PermissionManageSubnets))
+	require.False(t, authorizeAcceptCustom(t, "/subnets/1/utilization-alert-rule", "PUT", dbmodel.PermissionManageHosts))
+	require.False(t, authorizeAcceptCustom(t, "/shared-networks/1/utilization-alert-rule", "PUT", dbmodel.PermissionView))
+}
+
+// Verify that the read-only users are not permitted to modify any targets.
+func TestAuthorizeTargetsReadOnly(t *testing.T) {
+	user := &dbmodel.SystemUser{
+		ID:     5,
+		Groups: []*dbmodel.SystemGroup{{ID: dbmodel.ReadOnlyGroupID}},
+	}
+	require.True(t, AuthorizeTargets(user, dbmodel.PermissionView, dbmodel.PermissionTarget{DaemonID: 1}))
+	require.False(t, AuthorizeTargets(user, dbmodel.PermissionManageHosts, dbmodel.PermissionTarget{DaemonID: 1}))
+	require.False(t, AuthorizeTargets(user, dbmodel.PermissionManageSubnets))
+
+	// The management permissions can be granted by a custom group.
+	user.Groups = append(user.Groups, &dbmodel.SystemGroup{
+		ID: 100,
+		Permissions: []*dbmodel.SystemGroupPermission{
+			{Permission: dbmodel.PermissionManageHosts, DaemonID: 1},
+		},
+	})
+	require.True(t, AuthorizeTargets(user, dbmodel.PermissionManageHosts, dbmodel.PermissionTarget{DaemonID: 1}))
+	require.False(t, AuthorizeTargets(user, dbmodel.PermissionManageHosts, dbmodel.PermissionTarget{DaemonID: 2}))
+	require.True(t, AuthorizeTargets(user, dbmodel.PermissionView, dbmodel.PermissionTarget{DaemonID: 2}))
+}
+
+// Helper function returning a user belonging to a custom group with the
+// view permission scoped to the specified daemon.
+func newScopedViewer(daemonID int64) *dbmodel.SystemUser {
+	return &dbmodel.SystemUser{
+		ID: 5,
+		Groups: []*dbmodel.SystemGroup{
+			{
+				ID: 100,
+				Permissions: []*dbmodel.SystemGroupPermission{
+					{Permission: dbmodel.PermissionView, DaemonID: daemonID},
+				},
+			},
+		},
+	}
+}
+
+// Verify that the users with the scoped view permission can only view the
+// resources filtered by the permission scopes.
+func TestAuthorizeScopedViewer(t *testing.T) {
+	user := newScopedViewer(1)
+	authorize := func(path, method string) bool {
+		req, _ := http.NewRequestWithContext(context.Background(), method, "http://example.org/api"+path, nil)
+		ok, err := Authorize(user, req)
+		require.NoError(t, err)
+		return ok
+	}
+	require.True(t, authorize("/subnets", "GET"))
+	require.True(t, authorize("/subnets/1", "GET"))
+	require.True(t, authorize("/hosts", "GET"))
+	require.True(t, authorize("/hosts/1", "GET"))
+	require.True(t, authorize("/users/5", "GET"))
+	require.False(t, authorize("/subnets/1/utilization-alert-rule", "GET"))
+	require.False(t, authorize("/machines/1", "GET"))
+	require.False(t, authorize("/leases", "GET"))
+	require.False(t, authorize("/events", "GET"))
+	require.False(t, authorize("/hosts/1", "DELETE"))
+
+	// The unscoped view permission granted by another group gives access
+	// to all resources.
+	user.Groups = append(user.Groups, &dbmodel.SystemGroup{
+		ID: 101,
+		Permissions: []*dbmodel.SystemGroupPermission{
+			{Permission: dbmodel.PermissionView},
+		},
+	})
+	require.True(t, authorize("/machines/1", "GET"))
+	require.True(t, authorize("/leases", "GET"))
+}
+
+// Verify that the users with the scoped view permission can only view the
+// objects covered by the scopes.
+func TestAuthorizeView(t *testing.T) {
+	require.False(t, AuthorizeView(nil))
+
+	// The user without the view permission cannot view anything.
+	require.False(t, AuthorizeView(&dbmodel.SystemUser{ID: 5}, dbmodel.PermissionTarget{DaemonID: 1}))
+	require.False(t, AuthorizeView(&dbmodel.SystemUser{ID: 5, Groups: []*dbmodel.SystemGroup{{ID: 100}}}))
+
+	user := newScopedViewer(1)
+	require.True(t, IsViewScoped(user))
+	require.True(t, AuthorizeView(user, dbmodel.PermissionTarget{DaemonID: 1}))
+	require.True(t, AuthorizeView(user, dbmodel.PermissionTarget{DaemonID: 2}, dbmodel.PermissionTarget{DaemonID: 1}))
+	require.False(t, AuthorizeView(user, dbmodel.PermissionTarget{DaemonID: 2}))
+	require.False(t, AuthorizeView(user))
+
+	// The built-in groups are not limited by the scopes.
+	for _, groupID := range []int{dbmodel.SuperAdminGroupID, dbmodel.AdminGroupID, dbmodel.ReadOnlyGroupID} {
+		user := newScopedViewer(1)
+		user.Groups = append(user.Groups, &dbmodel.SystemGroup{ID: groupID})
+		require.False(t, IsViewScoped(user))
+		require.True(t, AuthorizeView(user, dbmodel.PermissionTarget{DaemonID: 2}))
+		require.True(t, AuthorizeView(user))
+	}
+}
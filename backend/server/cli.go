@@ -11,6 +11,7 @@ import (
 	"isc.org/stork/hooksutil"
 	"isc.org/stork/server/agentcomm"
 	dbops "isc.org/stork/server/database"
+	"isc.org/stork/server/notifications"
 	"isc.org/stork/server/restservice"
 	storkutil "isc.org/stork/util"
 )
@@ -59,6 +60,7 @@ type Settings struct {
 	AgentsSettings   *agentcomm.AgentsSettings
 	HooksSettings    map[string]hooks.HookSettings
 	DatabaseSettings *dbops.DatabaseSettings
+	SMTPSettings     *notifications.SMTPSettings
 }
 
 // Constructs a new settings instance.
@@ -71,6 +73,7 @@ func newSettings() *Settings {
 		AgentsSettings:   &agentcomm.AgentsSettings{},
 		HooksSettings:    make(map[string]hooks.HookSettings),
 		DatabaseSettings: &dbops.DatabaseSettings{},
+		SMTPSettings:     &notifications.SMTPSettings{},
 	}
 }
 
@@ -297,6 +300,13 @@ func (p *CLIParser) parseSettings(allHooksCLIFlags map[string]hooks.HookSettings
 		return nil, err
 	}
 
+	// Process SMTP specific args.
+	_, err = parser.AddGroup("Email Notifications Flags", "", settings.SMTPSettings)
+	if err != nil {
+		err = errors.Wrap(err, "cannot add the SMTP group")
+		return nil, err
+	}
+
 	// Append hook flags.
 	for hookName, cliFlags := range allHooksCLIFlags {
 		if cliFlags == nil {
@@ -74,6 +74,13 @@ type KeaModule interface {
 	BeginSubnetUpdate(context.Context, int64) (context.Context, error)
 	ApplySubnetUpdate(context.Context, *dbmodel.Subnet) (context.Context, error)
 	ApplySubnetDelete(context.Context, *dbmodel.Subnet) (context.Context, error)
+	BeginClientClassAdd(context.Context) (context.Context, error)
+	ApplyClientClassAdd(context.Context, *dbmodel.ClientClass) (context.Context, error)
+	BeginClientClassUpdate(context.Context, int64) (context.Context, error)
+	ApplyClientClassUpdate(context.Context, *dbmodel.ClientClass) (context.Context, error)
+	ApplyClientClassDelete(context.Context, *dbmodel.ClientClass) (context.Context, error)
+	BeginGlobalParametersUpdate(context.Context, []int64) (context.Context, error)
+	ApplyGlobalParametersUpdate(context.Context, *keaconfig.GlobalParameters, []dbmodel.DHCPOption) (context.Context, error)
 }
 
 // Interface of the Kea configuration module used by the manager to
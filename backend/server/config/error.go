@@ -67,6 +67,72 @@ func (e NoSubnetCmdsHookError) Error() string {
 	return "libdhcp_subnet_cmds hook library not configured for some of the daemons"
 }
 
+// An error returned when specified client class is not found in the database.
+type ClientClassNotFoundError struct {
+	clientClassID int64
+}
+
+// Create new instance of the ClientClassNotFoundError.
+func NewClientClassNotFoundError(clientClassID int64) error {
+	return &ClientClassNotFoundError{
+		clientClassID: clientClassID,
+	}
+}
+
+// Returns error string.
+func (e ClientClassNotFoundError) Error() string {
+	return fmt.Sprintf("client class with ID %d not found", e.clientClassID)
+}
+
+// An error returned when specified daemon is not found in the database.
+type DaemonNotFoundError struct {
+	daemonID int64
+}
+
+// Create new instance of the DaemonNotFoundError.
+func NewDaemonNotFoundError(daemonID int64) error {
+	return &DaemonNotFoundError{
+		daemonID: daemonID,
+	}
+}
+
+// Returns error string.
+func (e DaemonNotFoundError) Error() string {
+	return fmt.Sprintf("daemon with ID %d not found", e.daemonID)
+}
+
+// An error returned when the daemon has no libdhcp_lease_cmds hook library
+// configured.
+type NoLeaseCmdsHookError struct{}
+
+// Create new instance of the NoLeaseCmdsHookError.
+func NewNoLeaseCmdsHookError() error {
+	return &NoLeaseCmdsHookError{}
+}
+
+// Returns error string.
+func (e NoLeaseCmdsHookError) Error() string {
+	return "libdhcp_lease_cmds hook library not configured for the daemon"
+}
+
+// An error returned when the lease to be modified is not found in the
+// DHCP server.
+type LeaseNotFoundError struct {
+	ipAddress string
+}
+
+// Create new instance of the LeaseNotFoundError.
+func NewLeaseNotFoundError(ipAddress string) error {
+	return &LeaseNotFoundError{
+		ipAddress: ipAddress,
+	}
+}
+
+// Returns error string.
+func (e LeaseNotFoundError) Error() string {
+	return fmt.Sprintf("lease for %s not found", e.ipAddress)
+}
+
 // An error returned when it was not possible to lock daemons' configuration.
 type LockError struct{}
 
@@ -79,3 +145,23 @@ func NewLockError() error {
 func (e LockError) Error() string {
 	return "problem with locking daemons configuration"
 }
+
+// An error returned when the daemon's configuration has been modified
+// since it was fetched by the Stork server.
+type ConfigModifiedError struct {
+	daemonName string
+	appName    string
+}
+
+// Creates new instance of the ConfigModifiedError.
+func NewConfigModifiedError(daemonName, appName string) error {
+	return &ConfigModifiedError{
+		daemonName: daemonName,
+		appName:    appName,
+	}
+}
+
+// Returns error string.
+func (e ConfigModifiedError) Error() string {
+	return fmt.Sprintf("configuration of %s in %s may have been modified since it was fetched by Stork; try again after the next configuration pull", e.daemonName, e.appName)
+}
@@ -30,9 +30,41 @@ func TestNoSubnetCmdsHookError(t *testing.T) {
 	require.EqualError(t, err, "libdhcp_subnet_cmds hook library not configured for some of the daemons")
 }
 
+// Test creation of an error which indicates that client class was not found.
+func TestClientClassNotFoundError(t *testing.T) {
+	err := NewClientClassNotFoundError(345)
+	require.EqualError(t, err, "client class with ID 345 not found")
+}
+
+// Test creation of an error which indicates that the lease_cmds hook
+// library is not configured.
+func TestNoLeaseCmdsHookError(t *testing.T) {
+	err := NewNoLeaseCmdsHookError()
+	require.EqualError(t, err, "libdhcp_lease_cmds hook library not configured for the daemon")
+}
+
+// Test creation of an error which indicates that a lease was not found.
+func TestLeaseNotFoundError(t *testing.T) {
+	err := NewLeaseNotFoundError("192.0.2.1")
+	require.EqualError(t, err, "lease for 192.0.2.1 not found")
+}
+
+// Test creation of an error which indicates that daemon was not found.
+func TestDaemonNotFoundError(t *testing.T) {
+	err := NewDaemonNotFoundError(567)
+	require.EqualError(t, err, "daemon with ID 567 not found")
+}
+
 // Test creation of an error which indicates a problem with locking
 // configuration.
 func TestLockError(t *testing.T) {
 	err := NewLockError()
 	require.EqualError(t, err, "problem with locking daemons configuration")
 }
+
+// Test creation of an error which indicates that the daemon's configuration
+// has been modified since it was fetched.
+func TestConfigModifiedError(t *testing.T) {
+	err := NewConfigModifiedError("dhcp4", "kea@192.0.2.1")
+	require.ErrorContains(t, err, "configuration of dhcp4 in kea@192.0.2.1 may have been modified")
+}
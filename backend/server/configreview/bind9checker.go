@@ -0,0 +1,392 @@
+package configreview
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+	bind9config "isc.org/stork/appcfg/bind9"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Maximum number of the zones listed in the report about the unrestricted
+// zone transfers. The remaining zones are only counted.
+const maxReportedZones = 10
+
+// A scope of the BIND 9 configuration in which the options apply. It is
+// a view or the global options when the configuration has no views. The
+// statements specified in the view take precedence over the ones in the
+// global options.
+type bind9Scope struct {
+	// View name or an empty string for the global options.
+	view    string
+	block   *bind9config.Block
+	options *bind9config.Block
+}
+
+// Returns the scope description used in the reports.
+func (s *bind9Scope) String() string {
+	if s.view == "" {
+		return "global options"
+	}
+	return fmt.Sprintf("view %s", s.view)
+}
+
+// Returns the statement with the keyword from the view or the global
+// options. It returns nil if the statement is not specified.
+func (s *bind9Scope) getStatement(keyword string) *bind9config.Statement {
+	for _, block := range []*bind9config.Block{s.block, s.options} {
+		if block == nil {
+			continue
+		}
+		if statement := block.GetStatement(keyword); statement != nil {
+			return statement
+		}
+	}
+	return nil
+}
+
+// Returns the first value of the statement with the keyword from the view
+// or the global options. It returns an empty string if the statement is
+// not specified.
+func (s *bind9Scope) getValue(keyword string) string {
+	if statement := s.getStatement(keyword); statement != nil {
+		if values := statement.GetValues(); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// Returns the elements of the address match list specified with the
+// keyword in the view or the global options. It returns nil if the list
+// is not specified.
+func (s *bind9Scope) getAddressMatchList(keyword string) []string {
+	if statement := s.getStatement(keyword); statement != nil {
+		return statement.GetBlock().GetElements()
+	}
+	return nil
+}
+
+// Indicates if the recursion is enabled in the scope. It is enabled by
+// default.
+func (s *bind9Scope) isRecursionEnabled() bool {
+	value := s.getValue("recursion")
+	return value == "" || isBind9True(value)
+}
+
+// Returns the scopes of the BIND 9 configuration: the views or the global
+// options if there are no views.
+func getBind9Scopes(config *bind9config.Config) []*bind9Scope {
+	options := config.GetOptions()
+	var scopes []*bind9Scope
+	for _, view := range config.GetStatements("view") {
+		values := view.GetValues()
+		if len(values) == 0 {
+			continue
+		}
+		scopes = append(scopes, &bind9Scope{
+			view:    values[0],
+			block:   view.GetBlock(),
+			options: options,
+		})
+	}
+	if len(scopes) == 0 {
+		scopes = append(scopes, &bind9Scope{options: options})
+	}
+	return scopes
+}
+
+// Indicates if the BIND 9 boolean value is true.
+func isBind9True(value string) bool {
+	switch strings.ToLower(value) {
+	case "yes", "true", "1":
+		return true
+	}
+	return false
+}
+
+// Indicates if the address match list allows any host. BIND 9 applies
+// the first element matching the client address, so the list allows any
+// host if it contains:
+//
+//   - the "any" element not preceded by the "!any" element, nor by both
+//     "!0.0.0.0/0" and "!::/0" elements,
+//   - the "0.0.0.0/0" element not preceded by the "!any" or "!0.0.0.0/0"
+//     element,
+//   - the "::/0" element not preceded by the "!any" or "!::/0" element.
+//
+// The other negated elements preceding the element matching any host,
+// e.g., "!10.0.0.0/8", exclude only some addresses and are ignored. The
+// list "!10.0.0.0/8; any;" still allows the hosts from all other networks,
+// so it is considered open.
+func isAnyAllowed(elements []string) bool {
+	deniedIPv4, deniedIPv6 := false, false
+	for _, element := range elements {
+		negated := strings.HasPrefix(element, "!")
+		switch strings.TrimSpace(strings.TrimPrefix(element, "!")) {
+		case "any":
+			if negated {
+				return false
+			}
+			return !deniedIPv4 || !deniedIPv6
+		case "0.0.0.0/0":
+			if !negated && !deniedIPv4 {
+				return true
+			}
+			deniedIPv4 = true
+		case "::/0":
+			if !negated && !deniedIPv6 {
+				return true
+			}
+			deniedIPv6 = true
+		}
+	}
+	return false
+}
+
+// Indicates if the address is a loopback address.
+func isLoopback(address string) bool {
+	if address == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(address)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Returns the BIND 9 configuration of the subject daemon. It returns an
+// error if the daemon is not a BIND 9 daemon. The configuration is nil if
+// it hasn't been fetched.
+func getBind9Config(ctx *ReviewContext) (*bind9config.Config, error) {
+	daemon := ctx.subjectDaemon
+	if daemon.Name != dbmodel.DaemonNameBind9 || daemon.Bind9Daemon == nil {
+		return nil, errors.Errorf("unsupported daemon %s", daemon.Name)
+	}
+	return daemon.Bind9Daemon.Config, nil
+}
+
+// Joins the scope descriptions for the report.
+func joinBind9Scopes(scopes []*bind9Scope) string {
+	names := []string{}
+	for _, scope := range scopes {
+		names = append(names, scope.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// The checker verifying that the BIND 9 server is not an open resolver.
+// The recursive queries are allowed by the allow-recursion statement. If
+// it is not specified, the allow-query-cache or the allow-query statement
+// is used. The default, if none of them is specified, allows only the
+// local networks.
+func bind9OpenRecursion(ctx *ReviewContext) (*Report, error) {
+	config, err := getBind9Config(ctx)
+	if err != nil || config == nil {
+		return nil, err
+	}
+	var openScopes []*bind9Scope
+	for _, scope := range getBind9Scopes(config) {
+		if !scope.isRecursionEnabled() {
+			continue
+		}
+		for _, keyword := range []string{"allow-recursion", "allow-query-cache", "allow-query"} {
+			if elements := scope.getAddressMatchList(keyword); elements != nil {
+				if isAnyAllowed(elements) {
+					openScopes = append(openScopes, scope)
+				}
+				break
+			}
+		}
+	}
+	if len(openScopes) == 0 {
+		return nil, nil
+	}
+	return NewReport(ctx, fmt.Sprintf("The BIND 9 server {daemon} allows "+
+		"recursive queries from any host (%s). An open resolver can be "+
+		"abused in the DNS amplification attacks. Restrict the clients "+
+		"with the 'allow-recursion' statement or disable the recursion "+
+		"with 'recursion no' if the server is authoritative only.",
+		joinBind9Scopes(openScopes))).
+		referencingDaemon(ctx.subjectDaemon).
+		create()
+}
+
+// The checker verifying that the transfers of the primary and secondary
+// zones are restricted with the allow-transfer statement. The statement
+// specified for the zone takes precedence over the one in the view or
+// the global options.
+func bind9UnrestrictedZoneTransfer(ctx *ReviewContext) (*Report, error) {
+	config, err := getBind9Config(ctx)
+	if err != nil || config == nil {
+		return nil, err
+	}
+	var zones []string
+	checkZones := func(block *bind9config.Block, scope *bind9Scope) {
+		if block == nil {
+			return
+		}
+		for _, statement := range block.GetStatements("zone") {
+			values := statement.GetValues()
+			zoneBlock := statement.GetBlock()
+			if len(values) == 0 || zoneBlock == nil {
+				continue
+			}
+			switch zoneBlock.GetValue("type") {
+			case "primary", "master", "secondary", "slave":
+			default:
+				continue
+			}
+			elements := scope.getAddressMatchList("allow-transfer")
+			if zoneStatement := zoneBlock.GetStatement("allow-transfer"); zoneStatement != nil {
+				elements = zoneStatement.GetBlock().GetElements()
+			}
+			if elements != nil && !isAnyAllowed(elements) {
+				continue
+			}
+			name := values[0]
+			if scope.view != "" {
+				name = fmt.Sprintf("%s (view %s)", name, scope.view)
+			}
+			zones = append(zones, name)
+		}
+	}
+	options := config.GetOptions()
+	checkZones(&config.Block, &bind9Scope{options: options})
+	for _, scope := range getBind9Scopes(config) {
+		if scope.view != "" {
+			checkZones(scope.block, scope)
+		}
+	}
+	if len(zones) == 0 {
+		return nil, nil
+	}
+	list := strings.Join(zones[:min(len(zones), maxReportedZones)], ", ")
+	if len(zones) > maxReportedZones {
+		list += fmt.Sprintf(" and %d more", len(zones)-maxReportedZones)
+	}
+	return NewReport(ctx, fmt.Sprintf("The BIND 9 server {daemon} does not "+
+		"restrict the zone transfers of %d zone(s): %s. Any host may "+
+		"download the entire contents of these zones. "+
+		"Specify the 'allow-transfer' statement listing the secondary "+
+		"servers or the transfer keys in the zones, views or global options.",
+		len(zones), list)).
+		referencingDaemon(ctx.subjectDaemon).
+		create()
+}
+
+// The checker verifying that the statistics channels of the BIND 9 server
+// listen only on the loopback addresses. The Stork agent fetches the
+// statistics locally, so there is no need to expose them.
+func bind9StatisticsChannelExposed(ctx *ReviewContext) (*Report, error) {
+	config, err := getBind9Config(ctx)
+	if err != nil || config == nil {
+		return nil, err
+	}
+	var addresses []string
+	for _, channels := range config.GetStatements("statistics-channels") {
+		block := channels.GetBlock()
+		if block == nil {
+			continue
+		}
+		for _, inet := range block.GetStatements("inet") {
+			values := inet.GetValues()
+			if len(values) == 0 || isLoopback(values[0]) {
+				continue
+			}
+			addresses = append(addresses, values[0])
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+	return NewReport(ctx, fmt.Sprintf("The statistics channel of the BIND 9 "+
+		"server {daemon} listens on the non-loopback address(es): %s. The "+
+		"statistics reveal the server's configuration and traffic details. "+
+		"The Stork agent fetches the statistics locally, so configure the "+
+		"statistics channel to listen on 127.0.0.1 or ::1 only.",
+		strings.Join(addresses, ", "))).
+		referencingDaemon(ctx.subjectDaemon).
+		create()
+}
+
+// The checker verifying that the keys used by rndc to control the BIND 9
+// server don't use the weak HMAC-MD5 or HMAC-SHA1 algorithms. The keys
+// are specified in the controls statement. The rndc-key is used when the
+// statement or the keys are not specified.
+func bind9RndcKeyWeakAlgorithm(ctx *ReviewContext) (*Report, error) {
+	config, err := getBind9Config(ctx)
+	if err != nil || config == nil {
+		return nil, err
+	}
+	keyNames := []string{}
+	useDefaultKey := true
+	for _, controls := range config.GetStatements("controls") {
+		block := controls.GetBlock()
+		if block == nil {
+			continue
+		}
+		for _, statement := range block.GetStatements("inet") {
+			inet, err := bind9config.NewInet(statement)
+			if err != nil {
+				continue
+			}
+			if len(inet.Keys) > 0 {
+				useDefaultKey = false
+				keyNames = append(keyNames, inet.Keys...)
+			}
+		}
+	}
+	if useDefaultKey {
+		keyNames = append(keyNames, "rndc-key")
+	}
+	var weakKeys []string
+	for _, name := range keyNames {
+		key := config.GetKey(name)
+		if key == nil {
+			continue
+		}
+		algorithm := strings.ToLower(key.Algorithm)
+		if strings.HasPrefix(algorithm, "hmac-md5") || algorithm == "hmac-sha1" {
+			weakKeys = append(weakKeys, fmt.Sprintf("%s (%s)", key.Name, key.Algorithm))
+		}
+	}
+	if len(weakKeys) == 0 {
+		return nil, nil
+	}
+	return NewReport(ctx, fmt.Sprintf("The BIND 9 server {daemon} is "+
+		"controlled with the rndc key(s) using a weak algorithm: %s. "+
+		"Generate a new key with 'rndc-confgen -a -A hmac-sha256' or a "+
+		"stronger algorithm.", strings.Join(weakKeys, ", "))).
+		referencingDaemon(ctx.subjectDaemon).
+		create()
+}
+
+// The checker verifying that the DNSSEC validation is not disabled in the
+// scopes allowing recursion. The validation is enabled by default.
+func bind9DNSSECValidationDisabled(ctx *ReviewContext) (*Report, error) {
+	config, err := getBind9Config(ctx)
+	if err != nil || config == nil {
+		return nil, err
+	}
+	var disabledScopes []*bind9Scope
+	for _, scope := range getBind9Scopes(config) {
+		if !scope.isRecursionEnabled() {
+			continue
+		}
+		value := scope.getValue("dnssec-validation")
+		if value != "" && value != "auto" && !isBind9True(value) {
+			disabledScopes = append(disabledScopes, scope)
+		}
+	}
+	if len(disabledScopes) == 0 {
+		return nil, nil
+	}
+	return NewReport(ctx, fmt.Sprintf("The DNSSEC validation is disabled "+
+		"in the BIND 9 server {daemon} (%s). The clients of the resolver "+
+		"are not protected against the forged responses. Set "+
+		"'dnssec-validation auto' to enable the validation with the "+
+		"built-in trust anchor.", joinBind9Scopes(disabledScopes))).
+		referencingDaemon(ctx.subjectDaemon).
+		create()
+}
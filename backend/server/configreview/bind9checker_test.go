@@ -0,0 +1,272 @@
+package configreview
+
+import (
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+	bind9config "isc.org/stork/appcfg/bind9"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Creates the review context for the BIND 9 daemon with the specified
+// configuration.
+func createBind9ReviewContext(t *testing.T, configStr string) *ReviewContext {
+	config, err := bind9config.Parse(configStr)
+	require.NoError(t, err)
+
+	daemon := dbmodel.NewBind9Daemon(true)
+	daemon.ID = 1
+	daemon.Bind9Daemon.Config = config
+
+	ctx := newReviewContext(nil, daemon, []Trigger{ManualRun}, nil)
+	require.NotNil(t, ctx)
+	return ctx
+}
+
+// Test that the BIND 9 checkers return an error for the non-BIND 9
+// daemons and no report when the configuration is missing.
+func TestBind9CheckersUnsupportedDaemon(t *testing.T) {
+	checkers := []func(*ReviewContext) (*Report, error){
+		bind9OpenRecursion,
+		bind9UnrestrictedZoneTransfer,
+		bind9StatisticsChannelExposed,
+		bind9RndcKeyWeakAlgorithm,
+		bind9DNSSECValidationDisabled,
+	}
+
+	keaCtx := createReviewContext(t, nil, `{ "Dhcp4": { } }`, "2.2.0")
+	noConfigCtx := createBind9ReviewContext(t, "")
+	noConfigCtx.subjectDaemon.Bind9Daemon.Config = nil
+
+	for _, checker := range checkers {
+		report, err := checker(keaCtx)
+		require.ErrorContains(t, err, "unsupported daemon")
+		require.Nil(t, report)
+
+		report, err = checker(noConfigCtx)
+		require.NoError(t, err)
+		require.Nil(t, report)
+	}
+}
+
+// Test that the open recursion is reported when the recursive queries
+// are allowed from any host.
+func TestBind9OpenRecursion(t *testing.T) {
+	ctx := createBind9ReviewContext(t, `options { allow-recursion { any; }; };`)
+	report, err := bind9OpenRecursion(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "allows recursive queries from any host (global options)")
+	require.Equal(t, []int64{1}, report.refDaemonIDs)
+}
+
+// Test that the allow-query-cache and allow-query statements are used
+// when the allow-recursion statement is not specified.
+func TestBind9OpenRecursionFallback(t *testing.T) {
+	ctx := createBind9ReviewContext(t, `options { allow-query { any; }; };`)
+	report, err := bind9OpenRecursion(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+
+	ctx = createBind9ReviewContext(t, `options { allow-query-cache { localhost; }; allow-query { any; }; };`)
+	report, err = bind9OpenRecursion(ctx)
+	require.NoError(t, err)
+	require.Nil(t, report)
+}
+
+// Test that the open recursion is not reported when the recursion is
+// disabled or restricted.
+func TestBind9OpenRecursionRestricted(t *testing.T) {
+	for _, configStr := range []string{
+		``,
+		`options { recursion no; allow-query { any; }; };`,
+		`options { allow-recursion { 192.0.2.0/24; }; allow-query { any; }; };`,
+	} {
+		ctx := createBind9ReviewContext(t, configStr)
+		report, err := bind9OpenRecursion(ctx)
+		require.NoError(t, err, configStr)
+		require.Nil(t, report, configStr)
+	}
+}
+
+// Test that the negated elements of the address match list are taken
+// into account when checking for the open recursion.
+func TestBind9OpenRecursionNegated(t *testing.T) {
+	for _, configStr := range []string{
+		`options { allow-recursion { !10.0.0.0/8; any; }; };`,
+		`options { allow-recursion { !trusted; !192.0.2.1; any; }; };`,
+		`options { allow-recursion { !0.0.0.0/0; any; }; };`,
+		`options { allow-recursion { !::/0; 0.0.0.0/0; }; };`,
+		`options { allow-recursion { any; !any; }; };`,
+	} {
+		ctx := createBind9ReviewContext(t, configStr)
+		report, err := bind9OpenRecursion(ctx)
+		require.NoError(t, err, configStr)
+		require.NotNil(t, report, configStr)
+	}
+
+	for _, configStr := range []string{
+		`options { allow-recursion { !any; any; }; };`,
+		`options { allow-recursion { !0.0.0.0/0; !::/0; any; }; };`,
+		`options { allow-recursion { !0.0.0.0/0; 0.0.0.0/0; }; };`,
+		`options { allow-recursion { !::/0; ::/0; }; };`,
+		`options { allow-recursion { !any; }; };`,
+	} {
+		ctx := createBind9ReviewContext(t, configStr)
+		report, err := bind9OpenRecursion(ctx)
+		require.NoError(t, err, configStr)
+		require.Nil(t, report, configStr)
+	}
+}
+
+// Test that the open recursion is reported for the views overriding the
+// global options.
+func TestBind9OpenRecursionInViews(t *testing.T) {
+	ctx := createBind9ReviewContext(t, `
+		options { allow-recursion { localnets; }; };
+		view internal { };
+		view external { allow-recursion { any; }; };
+		view authoritative { recursion no; allow-recursion { any; }; };
+	`)
+	report, err := bind9OpenRecursion(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "(view external)")
+}
+
+// Test that the primary and secondary zones without the transfer
+// restrictions are reported.
+func TestBind9UnrestrictedZoneTransfer(t *testing.T) {
+	ctx := createBind9ReviewContext(t, `
+		options { allow-transfer { none; }; };
+		zone "restricted.org" { type primary; file "db.restricted.org"; };
+		zone "open.org" { type primary; file "db.open.org"; allow-transfer { any; }; };
+		zone "." { type hint; file "root.hints"; allow-transfer { any; }; };
+		view external {
+			allow-transfer { any; };
+			zone "example.com" { type secondary; primaries { 192.0.2.1; }; };
+			zone "example.net" { type secondary; allow-transfer { key "xfr"; }; };
+		};
+	`)
+	report, err := bind9UnrestrictedZoneTransfer(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "transfers of 2 zone(s): open.org, example.com (view external).")
+}
+
+// Test that the negated elements of the allow-transfer statement are
+// taken into account when checking for the unrestricted zone transfers.
+func TestBind9UnrestrictedZoneTransferNegated(t *testing.T) {
+	ctx := createBind9ReviewContext(t, `
+		options { allow-transfer { !any; any; }; };
+		zone "restricted.org" { type primary; file "db.restricted.org"; };
+		zone "excluded.org" { type primary; file "db.excluded.org"; allow-transfer { !10.0.0.0/8; any; }; };
+		zone "ipv6.org" { type primary; file "db.ipv6.org"; allow-transfer { !0.0.0.0/0; any; }; };
+		zone "none.org" { type primary; file "db.none.org"; allow-transfer { !0.0.0.0/0; !::/0; any; }; };
+		zone "ipv4.org" { type secondary; allow-transfer { !::/0; ::/0; 0.0.0.0/0; }; };
+	`)
+	report, err := bind9UnrestrictedZoneTransfer(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "transfers of 3 zone(s): excluded.org, ipv6.org, ipv4.org.")
+}
+
+// Test that the zone transfers are reported when the allow-transfer
+// statement is not specified and that the list of zones is shortened.
+func TestBind9UnrestrictedZoneTransferDefault(t *testing.T) {
+	configStr := ""
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l"} {
+		configStr += `zone "` + name + `.org" { type master; file "db"; };`
+	}
+	ctx := createBind9ReviewContext(t, configStr)
+	report, err := bind9UnrestrictedZoneTransfer(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "transfers of 12 zone(s): a.org, b.org")
+	require.Contains(t, *report.content, "j.org and 2 more.")
+
+	ctx = createBind9ReviewContext(t, `options { allow-transfer { 192.0.2.2; }; }; zone "a.org" { type master; file "db"; };`)
+	report, err = bind9UnrestrictedZoneTransfer(ctx)
+	require.NoError(t, err)
+	require.Nil(t, report)
+}
+
+// Test that the statistics channels listening on the non-loopback
+// addresses are reported.
+func TestBind9StatisticsChannelExposed(t *testing.T) {
+	ctx := createBind9ReviewContext(t, `
+		statistics-channels {
+			inet 127.0.0.1 port 8053 allow { 127.0.0.1; };
+			inet ::1 port 8053;
+			inet * port 8054;
+			inet 192.0.2.1 port 8053 allow { 192.0.2.2; };
+		};
+	`)
+	report, err := bind9StatisticsChannelExposed(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "non-loopback address(es): *, 192.0.2.1.")
+
+	ctx = createBind9ReviewContext(t, `statistics-channels { inet localhost port 8053; };`)
+	report, err = bind9StatisticsChannelExposed(ctx)
+	require.NoError(t, err)
+	require.Nil(t, report)
+}
+
+// Test that the rndc keys using the weak algorithms are reported.
+func TestBind9RndcKeyWeakAlgorithm(t *testing.T) {
+	ctx := createBind9ReviewContext(t, `
+		key "rndc-key" { algorithm hmac-md5; secret "c2VjcmV0"; };
+		key "ctrl" { algorithm HMAC-SHA1; secret "c2VjcmV0"; };
+		key "strong" { algorithm hmac-sha256; secret "c2VjcmV0"; };
+		controls {
+			inet 127.0.0.1 allow { localhost; } keys { "ctrl"; "strong"; };
+		};
+	`)
+	report, err := bind9RndcKeyWeakAlgorithm(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	// The rndc-key is not used because the keys are specified.
+	require.Contains(t, *report.content, "weak algorithm: ctrl (HMAC-SHA1).")
+}
+
+// Test that the default rndc key is checked when the controls statement
+// lacks the keys.
+func TestBind9RndcKeyWeakAlgorithmDefaultKey(t *testing.T) {
+	ctx := createBind9ReviewContext(t, `key "rndc-key" { algorithm hmac-md5.sig-alg.reg.int; secret "c2VjcmV0"; };`)
+	report, err := bind9RndcKeyWeakAlgorithm(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "rndc-key (hmac-md5.sig-alg.reg.int)")
+
+	ctx = createBind9ReviewContext(t, `key "rndc-key" { algorithm hmac-sha512; secret "c2VjcmV0"; };`)
+	report, err = bind9RndcKeyWeakAlgorithm(ctx)
+	require.NoError(t, err)
+	require.Nil(t, report)
+}
+
+// Test that the disabled DNSSEC validation is reported for the scopes
+// allowing recursion.
+func TestBind9DNSSECValidationDisabled(t *testing.T) {
+	ctx := createBind9ReviewContext(t, `
+		options { dnssec-validation no; };
+		view internal { };
+		view authoritative { recursion no; };
+		view external { dnssec-validation auto; };
+	`)
+	report, err := bind9DNSSECValidationDisabled(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "(view internal)")
+
+	for _, configStr := range []string{
+		``,
+		`options { dnssec-validation yes; };`,
+		`options { dnssec-validation auto; };`,
+	} {
+		ctx = createBind9ReviewContext(t, configStr)
+		report, err = bind9DNSSECValidationDisabled(ctx)
+		require.NoError(t, err, configStr)
+		require.Nil(t, report, configStr)
+	}
+}
@@ -0,0 +1,126 @@
+package configreview
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	keaconfig "isc.org/stork/appcfg/kea"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Default address on which the D2 server receives the name change requests
+// from the DHCP servers.
+const defaultD2ServerIP = "127.0.0.1"
+
+// The checker verifying that the DHCP server sending the name change
+// requests has a running D2 server to receive them. The DHCP server doesn't
+// report the failures to deliver the requests, so the DNS updates are
+// silently dropped. Only the D2 server listening on the same machine can be
+// verified because Stork cannot map the remote addresses to the machines.
+func ddnsUpdatesWithoutD2(ctx *ReviewContext) (*Report, error) {
+	daemon := ctx.subjectDaemon
+	if daemon.Name != dbmodel.DaemonNameDHCPv4 && daemon.Name != dbmodel.DaemonNameDHCPv6 {
+		return nil, errors.Errorf("unsupported daemon %s", daemon.Name)
+	}
+	if daemon.KeaDaemon == nil || daemon.KeaDaemon.Config == nil {
+		return nil, nil
+	}
+	config := daemon.KeaDaemon.Config
+	if !config.IsDDNSUpdatesEnabled() {
+		return nil, nil
+	}
+	serverIP := defaultD2ServerIP
+	if dhcpDDNS := config.GetDHCPDDNS(); dhcpDDNS.ServerIP != nil && *dhcpDDNS.ServerIP != "" {
+		serverIP = *dhcpDDNS.ServerIP
+	}
+
+	// It isn't guaranteed that the subject daemon has the referenced app
+	// member so we need to retrieve the database entry.
+	if daemon.App == nil || daemon.App.Machine == nil {
+		var err error
+		daemon, err = dbmodel.GetDaemonByID(ctx.db, ctx.subjectDaemon.ID)
+		if err != nil {
+			return nil, err
+		}
+		if daemon == nil || daemon.App == nil || daemon.App.Machine == nil {
+			return nil, nil
+		}
+	}
+	machine := daemon.App.Machine
+	if !isLoopback(serverIP) && serverIP != machine.Address {
+		// The D2 server runs on another machine.
+		return nil, nil
+	}
+
+	apps, err := dbmodel.GetAppsByMachine(ctx.db, machine.ID)
+	if err != nil {
+		return nil, err
+	}
+	var inactiveD2Daemons []*dbmodel.Daemon
+	for _, app := range apps {
+		for _, d := range app.Daemons {
+			if d.Name != dbmodel.DaemonNameD2 {
+				continue
+			}
+			if d.Active {
+				return nil, nil
+			}
+			inactiveD2Daemons = append(inactiveD2Daemons, d)
+		}
+	}
+
+	reason := "no Kea DHCP-DDNS (D2) server is monitored on this machine"
+	if len(inactiveD2Daemons) > 0 {
+		reason = "the Kea DHCP-DDNS (D2) server on this machine is not running"
+	}
+	report := NewReport(ctx, fmt.Sprintf("The Kea {daemon} server sends the "+
+		"DNS update requests to %s ('enable-updates' is true in 'dhcp-ddns') "+
+		"but %s. The DHCP server doesn't report the failed deliveries, so "+
+		"the DNS records of the clients are silently not updated. Start the "+
+		"D2 server and make sure Stork monitors it or disable the DDNS updates.",
+		serverIP, reason)).
+		referencingDaemon(ctx.subjectDaemon)
+	for _, d := range inactiveD2Daemons {
+		report = report.referencingDaemon(d)
+	}
+	return report.create()
+}
+
+// The checker verifying that the DDNS domains configured in the D2 server
+// have the DNS servers. The D2 server matches the FQDNs in the name change
+// requests with the domains. If the matching domain has no servers, the
+// update of the forward or reverse zone fails.
+func d2DomainsWithoutServers(ctx *ReviewContext) (*Report, error) {
+	daemon := ctx.subjectDaemon
+	if daemon.Name != dbmodel.DaemonNameD2 {
+		return nil, errors.Errorf("unsupported daemon %s", daemon.Name)
+	}
+	if daemon.KeaDaemon == nil || daemon.KeaDaemon.Config == nil {
+		return nil, nil
+	}
+	config := daemon.KeaDaemon.Config
+	if !config.IsD2() {
+		return nil, nil
+	}
+	var domains []string
+	collect := func(ddnsDomains []keaconfig.DDNSDomain, direction string) {
+		for _, domain := range ddnsDomains {
+			if len(domain.DNSServers) == 0 {
+				domains = append(domains, fmt.Sprintf("%s (%s)", domain.Name, direction))
+			}
+		}
+	}
+	collect(config.GetForwardDDNSDomains(), "forward")
+	collect(config.GetReverseDDNSDomains(), "reverse")
+	if len(domains) == 0 {
+		return nil, nil
+	}
+	return NewReport(ctx, fmt.Sprintf("The Kea {daemon} server has %d DDNS "+
+		"domain(s) without the DNS servers: %s. The DNS updates of the names "+
+		"matching these domains fail. Specify the 'dns-servers' for the domains "+
+		"or remove them from the configuration.",
+		len(domains), strings.Join(domains, ", "))).
+		referencingDaemon(ctx.subjectDaemon).
+		create()
+}
@@ -0,0 +1,200 @@
+package configreview
+
+import (
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+	dbops "isc.org/stork/server/database"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+)
+
+// DHCPv4 configuration sending the name change requests to the local D2
+// server.
+const dhcp4ConfigWithDDNS = `{
+	"Dhcp4": {
+		"dhcp-ddns": {
+			"enable-updates": true
+		}
+	}
+}`
+
+// Adds a machine with the Kea app including the DHCPv4 daemon with the
+// specified configuration and, optionally, the D2 daemon. It returns the
+// DHCPv4 daemon and the D2 daemon.
+func addDDNSTestApp(t *testing.T, db *dbops.PgDB, dhcp4Config string, d2Daemon *dbmodel.Daemon) (*dbmodel.Daemon, *dbmodel.Daemon) {
+	machine := &dbmodel.Machine{
+		Address:   "192.0.2.10",
+		AgentPort: 8080,
+	}
+	require.NoError(t, dbmodel.AddMachine(db, machine))
+
+	dhcp4Daemon := dbmodel.NewKeaDaemon(dbmodel.DaemonNameDHCPv4, true)
+	require.NoError(t, dhcp4Daemon.SetConfigFromJSON(dhcp4Config))
+	app := &dbmodel.App{
+		MachineID: machine.ID,
+		Type:      dbmodel.AppTypeKea,
+		Daemons: []*dbmodel.Daemon{
+			dhcp4Daemon,
+		},
+	}
+	if d2Daemon != nil {
+		app.Daemons = append(app.Daemons, d2Daemon)
+	}
+	_, err := dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+	return dhcp4Daemon, d2Daemon
+}
+
+// Test that the DDNS checkers return an error for the unsupported daemons.
+func TestDDNSCheckersUnsupportedDaemon(t *testing.T) {
+	ctx := createReviewContext(t, nil, `{ "Control-agent": { } }`, "2.2.0")
+
+	report, err := ddnsUpdatesWithoutD2(ctx)
+	require.ErrorContains(t, err, "unsupported daemon")
+	require.Nil(t, report)
+
+	report, err = d2DomainsWithoutServers(ctx)
+	require.ErrorContains(t, err, "unsupported daemon")
+	require.Nil(t, report)
+}
+
+// Test that no report is generated when the DHCP server doesn't send the
+// name change requests.
+func TestDDNSUpdatesWithoutD2Disabled(t *testing.T) {
+	for _, configStr := range []string{
+		`{ "Dhcp4": { } }`,
+		`{ "Dhcp4": { "dhcp-ddns": { "enable-updates": false } } }`,
+		`{ "Dhcp6": { "ddns-send-updates": true } }`,
+	} {
+		// The database is not used when the updates are disabled.
+		ctx := createReviewContext(t, nil, configStr, "2.2.0")
+		report, err := ddnsUpdatesWithoutD2(ctx)
+		require.NoError(t, err, configStr)
+		require.Nil(t, report, configStr)
+	}
+}
+
+// Test that the DHCP server sending the name change requests to the local
+// D2 server is reported when there is no D2 daemon on the machine.
+func TestDDNSUpdatesWithoutD2NoDaemon(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	dhcp4Daemon, _ := addDDNSTestApp(t, db, dhcp4ConfigWithDDNS, nil)
+
+	ctx := newReviewContext(db, dhcp4Daemon, []Trigger{ManualRun}, nil)
+	report, err := ddnsUpdatesWithoutD2(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "sends the DNS update requests to 127.0.0.1")
+	require.Contains(t, *report.content, "no Kea DHCP-DDNS (D2) server is monitored on this machine")
+	require.Equal(t, []int64{dhcp4Daemon.ID}, report.refDaemonIDs)
+}
+
+// Test that the inactive D2 daemon is reported and referenced.
+func TestDDNSUpdatesWithoutD2InactiveDaemon(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	dhcp4Daemon, d2Daemon := addDDNSTestApp(t, db, `{
+		"Dhcp4": {
+			"dhcp-ddns": {
+				"enable-updates": true,
+				"server-ip": "192.0.2.10"
+			}
+		}
+	}`, dbmodel.NewKeaDaemon(dbmodel.DaemonNameD2, false))
+
+	ctx := newReviewContext(db, dhcp4Daemon, []Trigger{ManualRun}, nil)
+	report, err := ddnsUpdatesWithoutD2(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "the Kea DHCP-DDNS (D2) server on this machine is not running")
+	require.ElementsMatch(t, []int64{dhcp4Daemon.ID, d2Daemon.ID}, report.refDaemonIDs)
+}
+
+// Test that no report is generated when the D2 daemon is running on the
+// machine.
+func TestDDNSUpdatesWithoutD2ActiveDaemon(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	dhcp4Daemon, _ := addDDNSTestApp(t, db, dhcp4ConfigWithDDNS, dbmodel.NewKeaDaemon(dbmodel.DaemonNameD2, true))
+
+	ctx := newReviewContext(db, dhcp4Daemon, []Trigger{ManualRun}, nil)
+	report, err := ddnsUpdatesWithoutD2(ctx)
+	require.NoError(t, err)
+	require.Nil(t, report)
+}
+
+// Test that no report is generated when the D2 server runs on another
+// machine.
+func TestDDNSUpdatesWithoutD2RemoteServer(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	dhcp4Daemon, _ := addDDNSTestApp(t, db, `{
+		"Dhcp4": {
+			"dhcp-ddns": {
+				"enable-updates": true,
+				"server-ip": "192.0.2.20"
+			}
+		}
+	}`, nil)
+
+	ctx := newReviewContext(db, dhcp4Daemon, []Trigger{ManualRun}, nil)
+	report, err := ddnsUpdatesWithoutD2(ctx)
+	require.NoError(t, err)
+	require.Nil(t, report)
+}
+
+// Test that the DDNS domains without the DNS servers are reported.
+func TestD2DomainsWithoutServers(t *testing.T) {
+	ctx := createReviewContext(t, nil, `{
+		"DhcpDdns": {
+			"forward-ddns": {
+				"ddns-domains": [
+					{
+						"name": "example.com.",
+						"dns-servers": [ { "ip-address": "192.0.2.1" } ]
+					},
+					{
+						"name": "example.org."
+					}
+				]
+			},
+			"reverse-ddns": {
+				"ddns-domains": [
+					{
+						"name": "2.0.192.in-addr.arpa.",
+						"dns-servers": [ ]
+					}
+				]
+			}
+		}
+	}`, "2.2.0")
+	ctx.subjectDaemon.Name = dbmodel.DaemonNameD2
+
+	report, err := d2DomainsWithoutServers(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "2 DDNS domain(s) without the DNS servers: "+
+		"example.org. (forward), 2.0.192.in-addr.arpa. (reverse).")
+	require.Equal(t, []int64{1}, report.refDaemonIDs)
+}
+
+// Test that no report is generated when all DDNS domains have the DNS
+// servers or there are no domains.
+func TestD2DomainsWithoutServersNoIssue(t *testing.T) {
+	for _, configStr := range []string{
+		`{ "DhcpDdns": { } }`,
+		`{ "DhcpDdns": { "forward-ddns": { "ddns-domains": [ { "name": "example.com.", "dns-servers": [ { "ip-address": "192.0.2.1" } ] } ] } } }`,
+	} {
+		ctx := createReviewContext(t, nil, configStr, "2.2.0")
+		ctx.subjectDaemon.Name = dbmodel.DaemonNameD2
+		report, err := d2DomainsWithoutServers(ctx)
+		require.NoError(t, err, configStr)
+		require.Nil(t, report, configStr)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	dbops "isc.org/stork/server/database"
 	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/notifications"
 	storkutil "isc.org/stork/util"
 )
 
@@ -317,6 +318,9 @@ type dispatcherImpl struct {
 	hasher storkutil.Hasher
 	// Checker controller manages the state of configuration checkers.
 	checkerController checkerController
+	// Notifier sending the new issues over the notification channels.
+	// It may be nil.
+	notifier notifications.Notifier
 }
 
 // Dispatcher interface. The interface is used in the unit tests that
@@ -568,6 +572,18 @@ func (d *dispatcherImpl) populateReports(ctx *ReviewContext) (err error) {
 
 	daemons := append([]*dbmodel.Daemon{ctx.subjectDaemon}, ctx.refDaemons...)
 
+	// Remember the issues found in the previous review to send only the
+	// new issues over the notification channels. The internal runs only
+	// rebuild the reports of the referenced daemons, so they are skipped
+	// to avoid sending the same issues again.
+	var previousIssues map[string]bool
+	if d.notifier != nil && !ctx.triggers.isInternalRun() {
+		previousIssues, err = d.getIssues(ctx.subjectDaemon.ID)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Begin a new transaction for inserting the reports.
 	tx, err := d.db.Begin()
 	if err != nil {
@@ -653,11 +669,16 @@ func (d *dispatcherImpl) populateReports(ctx *ReviewContext) (err error) {
 	}
 
 	// Add configuration review summary.
-	// todo: add config review summary for BIND9. Currently we don't because
-	// BIND 9 does not include a config hash.
-	if ctx.subjectDaemon.KeaDaemon != nil {
+	var configHash *string
+	switch {
+	case ctx.subjectDaemon.KeaDaemon != nil:
+		configHash = &ctx.subjectDaemon.KeaDaemon.ConfigHash
+	case ctx.subjectDaemon.Bind9Daemon != nil && ctx.subjectDaemon.Bind9Daemon.Config != nil:
+		configHash = &ctx.subjectDaemon.Bind9Daemon.ConfigHash
+	}
+	if configHash != nil {
 		configReview := &dbmodel.ConfigReview{
-			ConfigHash: ctx.subjectDaemon.KeaDaemon.ConfigHash,
+			ConfigHash: *configHash,
 			Signature:  d.GetSignature(),
 			DaemonID:   ctx.subjectDaemon.ID,
 		}
@@ -672,6 +693,10 @@ func (d *dispatcherImpl) populateReports(ctx *ReviewContext) (err error) {
 		return err
 	}
 
+	if previousIssues != nil {
+		d.notifyNewIssues(ctx.subjectDaemon, previousIssues)
+	}
+
 	if !ctx.triggers.isInternalRun() {
 		// If the review was scheduled externally, and we deleted configuration
 		// reports for referenced daemons, we have to rebuild the reports for
@@ -688,6 +713,45 @@ func (d *dispatcherImpl) populateReports(ctx *ReviewContext) (err error) {
 	return err
 }
 
+// Returns the issues found for the daemon and stored in the database. The
+// returned map is keyed by the checker name and the report content.
+func (d *dispatcherImpl) getIssues(daemonID int64) (map[string]bool, error) {
+	reports, _, err := dbmodel.GetConfigReportsByDaemonID(d.db, 0, 0, daemonID, true)
+	if err != nil {
+		return nil, err
+	}
+	issues := make(map[string]bool)
+	for _, report := range reports {
+		issues[report.CheckerName+":"+*report.Content] = true
+	}
+	return issues, nil
+}
+
+// Sends the issues found for the daemon and absent in the previous
+// review over the notification channels.
+func (d *dispatcherImpl) notifyNewIssues(daemon *dbmodel.Daemon, previousIssues map[string]bool) {
+	reports, _, err := dbmodel.GetConfigReportsByDaemonID(d.db, 0, 0, daemon.ID, true)
+	if err != nil {
+		log.WithError(err).
+			WithField("daemon", daemon.ID).
+			Error("Problem getting config reports to send the notifications")
+		return
+	}
+	relations := dbmodel.Relations{
+		DaemonID: daemon.ID,
+		AppID:    daemon.AppID,
+	}
+	if daemon.App != nil {
+		relations.MachineID = daemon.App.MachineID
+	}
+	for _, report := range reports {
+		if previousIssues[report.CheckerName+":"+*report.Content] {
+			continue
+		}
+		d.notifier.Notify(notifications.NewConfigReviewNotification(report.CheckerName, *report.Content, relations))
+	}
+}
+
 // Returns dispatch group indicated by the selector or nil when such group
 // does not exist.
 func (d *dispatcherImpl) getGroup(selector DispatchGroupSelector) *dispatchGroup {
@@ -697,8 +761,9 @@ func (d *dispatcherImpl) getGroup(selector DispatchGroupSelector) *dispatchGroup
 	return nil
 }
 
-// Creates new dispatcher instance.
-func NewDispatcher(db *dbops.PgDB) Dispatcher {
+// Creates new dispatcher instance. The notifier is used to send the new
+// issues found during the reviews. It may be nil.
+func NewDispatcher(db *dbops.PgDB, notifier notifications.Notifier) Dispatcher {
 	ctx, cancel := context.WithCancel(context.Background())
 	dispatcher := &dispatcherImpl{
 		db:                db,
@@ -712,6 +777,7 @@ func NewDispatcher(db *dbops.PgDB) Dispatcher {
 		state:             make(map[int64]bool),
 		hasher:            newHasher(),
 		checkerController: newCheckerController(),
+		notifier:          notifier,
 	}
 	return dispatcher
 }
@@ -933,12 +999,20 @@ func RegisterDefaultCheckers(dispatcher Dispatcher) {
 	dispatcher.RegisterChecker(KeaDHCPDaemon, "canonical_prefix", GetDefaultTriggers(), canonicalPrefixes)
 	dispatcher.RegisterChecker(KeaDHCPDaemon, "ha_mt_presence", GetDefaultTriggers(), highAvailabilityMultiThreadingMode)
 	dispatcher.RegisterChecker(KeaDHCPDaemon, "ha_dedicated_ports", GetDefaultTriggers(), highAvailabilityDedicatedPorts)
+	dispatcher.RegisterChecker(KeaDHCPDaemon, "ha_peers_consistency", GetDefaultTriggers(), highAvailabilityPeersConsistency)
 	dispatcher.RegisterChecker(KeaDHCPDaemon, "address_pools_exhausted_by_reservations", ExtendDefaultTriggers(DBHostsModified), addressPoolsExhaustedByReservations)
 	dispatcher.RegisterChecker(KeaDHCPDaemon, "pd_pools_exhausted_by_reservations", ExtendDefaultTriggers(DBHostsModified), delegatedPrefixPoolsExhaustedByReservations)
 	dispatcher.RegisterChecker(KeaDHCPDaemon, "subnet_cmds_and_cb_mutual_exclusion", GetDefaultTriggers(), subnetCmdsAndConfigBackendMutualExclusion)
 	dispatcher.RegisterChecker(KeaDHCPDaemon, "statistics_unavailable_due_to_number_overflow", GetDefaultTriggers(), gatheringStatisticsUnavailableDueToNumberOverflow)
+	dispatcher.RegisterChecker(KeaDHCPDaemon, "ddns_updates_without_d2", GetDefaultTriggers(), ddnsUpdatesWithoutD2)
+	dispatcher.RegisterChecker(KeaD2Daemon, "d2_domains_without_servers", GetDefaultTriggers(), d2DomainsWithoutServers)
 	dispatcher.RegisterChecker(KeaCADaemon, "agent_credentials_over_https", ExtendDefaultTriggers(StorkAgentConfigModified), credentialsOverHTTPS)
 	dispatcher.RegisterChecker(KeaCADaemon, "ca_control_sockets", GetDefaultTriggers(), controlSocketsCA)
+	dispatcher.RegisterChecker(Bind9Daemon, "bind9_open_recursion", GetDefaultTriggers(), bind9OpenRecursion)
+	dispatcher.RegisterChecker(Bind9Daemon, "bind9_unrestricted_zone_transfer", GetDefaultTriggers(), bind9UnrestrictedZoneTransfer)
+	dispatcher.RegisterChecker(Bind9Daemon, "bind9_statistics_channel_exposed", GetDefaultTriggers(), bind9StatisticsChannelExposed)
+	dispatcher.RegisterChecker(Bind9Daemon, "bind9_rndc_key_weak_algorithm", GetDefaultTriggers(), bind9RndcKeyWeakAlgorithm)
+	dispatcher.RegisterChecker(Bind9Daemon, "bind9_dnssec_validation_disabled", GetDefaultTriggers(), bind9DNSSECValidationDisabled)
 }
 
 // Fetches all checker preferences from the database and loads them into
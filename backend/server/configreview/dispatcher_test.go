@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/require"
 	dbmodel "isc.org/stork/server/database/model"
 	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/notifications"
 )
 
 // Test hasher.
@@ -25,7 +26,7 @@ func TestNewDispatcher(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
 	defer teardown()
 
-	dispatcher := NewDispatcher(db).(*dispatcherImpl)
+	dispatcher := NewDispatcher(db, nil).(*dispatcherImpl)
 	require.NotNil(t, dispatcher)
 	require.Equal(t, db, dispatcher.db)
 	require.NotNil(t, dispatcher.groups)
@@ -47,7 +48,7 @@ func TestGracefulShutdown(t *testing.T) {
 	defer teardown()
 
 	// Create new dispatcher.
-	dispatcher := NewDispatcher(db)
+	dispatcher := NewDispatcher(db, nil)
 	require.NotNil(t, dispatcher)
 
 	// We will simulate reviews for all daemon types.
@@ -174,7 +175,7 @@ func TestPopulateKeaReports(t *testing.T) {
 	require.Len(t, daemons, 2)
 
 	// Create review dispatcher.
-	dispatcher := NewDispatcher(db)
+	dispatcher := NewDispatcher(db, nil)
 	require.NotNil(t, dispatcher)
 
 	// Register a different checker for each daemon.
@@ -292,7 +293,7 @@ func TestPopulateBind9Reports(t *testing.T) {
 	require.Len(t, daemons, 1)
 
 	// Create the dispatcher instance.
-	dispatcher := NewDispatcher(db)
+	dispatcher := NewDispatcher(db, nil)
 	require.NotNil(t, dispatcher)
 
 	// Register a test checker for the BIND9 daemon.
@@ -332,6 +333,111 @@ func TestPopulateBind9Reports(t *testing.T) {
 	require.Equal(t, "Bind9 test output", *reports[0].Content)
 }
 
+// Notifier recording the notifications in the unit tests.
+type testNotifier struct {
+	mutex         sync.Mutex
+	notifications []*notifications.Notification
+}
+
+// Records the notification.
+func (n *testNotifier) Notify(notification *notifications.Notification) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.notifications = append(n.notifications, notification)
+}
+
+// Does nothing.
+func (n *testNotifier) SendTestEmail(to string) error {
+	return nil
+}
+
+// Does nothing.
+func (n *testNotifier) Shutdown() {}
+
+// Tests that only the new issues found during the review are sent to
+// the notifier.
+func TestPopulateReportsNotifyNewIssues(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	// Add a machine.
+	machine := &dbmodel.Machine{
+		Address:   "localhost",
+		AgentPort: 8080,
+	}
+	err := dbmodel.AddMachine(db, machine)
+	require.NoError(t, err)
+
+	// Add an app with a BIND9 daemon into the database.
+	app := &dbmodel.App{
+		Type:      dbmodel.AppTypeBind9,
+		MachineID: machine.ID,
+		Daemons: []*dbmodel.Daemon{
+			{
+				Name:   "named",
+				Active: true,
+			},
+		},
+	}
+	daemons, err := dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+	require.Len(t, daemons, 1)
+
+	notifier := &testNotifier{}
+	dispatcher := NewDispatcher(db, notifier)
+
+	// The first checker always reports the same issue. The second checker
+	// reports an issue since the second review.
+	var secondRun atomic.Bool
+	dispatcher.RegisterChecker(Bind9Daemon, "first_checker", GetDefaultTriggers(), func(ctx *ReviewContext) (*Report, error) {
+		return NewReport(ctx, "first issue").create()
+	})
+	dispatcher.RegisterChecker(Bind9Daemon, "second_checker", GetDefaultTriggers(), func(ctx *ReviewContext) (*Report, error) {
+		if !secondRun.Load() {
+			return nil, nil
+		}
+		return NewReport(ctx, "second issue").create()
+	})
+
+	dispatcher.Start()
+	defer dispatcher.Shutdown()
+
+	review := func() {
+		var innerError error
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		ok := dispatcher.BeginReview(daemons[0], Triggers{ConfigModified}, func(daemonID int64, err error) {
+			defer wg.Done()
+			innerError = err
+		})
+		require.True(t, ok)
+		wg.Wait()
+		require.NoError(t, innerError)
+	}
+
+	// The first review finds a new issue.
+	review()
+	require.Len(t, notifier.notifications, 1)
+	require.Equal(t, notifications.NotificationTypeConfigReview, notifier.notifications[0].Type)
+	require.Equal(t, dbmodel.EvWarning, notifier.notifications[0].Level)
+	require.Equal(t, "first_checker", notifier.notifications[0].Checker)
+	require.Equal(t, "first issue", notifier.notifications[0].Text)
+	require.Equal(t, daemons[0].ID, notifier.notifications[0].Relations.DaemonID)
+	require.Equal(t, daemons[0].AppID, notifier.notifications[0].Relations.AppID)
+
+	// The second review finds the same issue and a new one. Only the new
+	// issue should be sent.
+	secondRun.Store(true)
+	review()
+	require.Len(t, notifier.notifications, 2)
+	require.Equal(t, "second_checker", notifier.notifications[1].Checker)
+	require.Equal(t, "second issue", notifier.notifications[1].Text)
+
+	// No new issues.
+	review()
+	require.Len(t, notifier.notifications, 2)
+}
+
 // Tests the scenario when another review for the same daemon is scheduled
 // while the earlier review for this daemon is in progress.
 func TestReviewInProgress(t *testing.T) {
@@ -362,7 +468,7 @@ func TestReviewInProgress(t *testing.T) {
 	require.Len(t, daemons, 1)
 
 	// Create new dispatcher.
-	dispatcher := NewDispatcher(db).(*dispatcherImpl)
+	dispatcher := NewDispatcher(db, nil).(*dispatcherImpl)
 	require.NotNil(t, dispatcher)
 
 	// Register the checker which blocks until it receives a value
@@ -475,7 +581,7 @@ func TestCascadeReview(t *testing.T) {
 	require.Len(t, daemons, 2)
 
 	// Create new dispatcher.
-	dispatcher := NewDispatcher(db)
+	dispatcher := NewDispatcher(db, nil)
 	require.NotNil(t, dispatcher)
 
 	// Register a checker for the first daemon. It fetches the configuration of
@@ -604,7 +710,7 @@ func TestTriggers(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, daemons, 2)
 
-	dispatcher := NewDispatcher(db).(*dispatcherImpl)
+	dispatcher := NewDispatcher(db, nil).(*dispatcherImpl)
 	require.NotNil(t, dispatcher)
 	dispatcher.Start()
 	defer dispatcher.Shutdown()
@@ -670,7 +776,7 @@ func TestRegisterDefaultCheckers(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
 	defer teardown()
 
-	dispatcher := NewDispatcher(db).(*dispatcherImpl)
+	dispatcher := NewDispatcher(db, nil).(*dispatcherImpl)
 	require.NotNil(t, dispatcher)
 
 	RegisterDefaultCheckers(dispatcher)
@@ -688,12 +794,14 @@ func TestRegisterDefaultCheckers(t *testing.T) {
 	require.Contains(t, checkerNames, "out_of_pool_reservation")
 	require.Contains(t, checkerNames, "ha_mt_presence")
 	require.Contains(t, checkerNames, "ha_dedicated_ports")
+	require.Contains(t, checkerNames, "ha_peers_consistency")
 	require.Contains(t, checkerNames, "address_pools_exhausted_by_reservations")
 	require.Contains(t, checkerNames, "pd_pools_exhausted_by_reservations")
 	require.Contains(t, checkerNames, "overlapping_subnet")
 	require.Contains(t, checkerNames, "canonical_prefix")
 	require.Contains(t, checkerNames, "subnet_cmds_and_cb_mutual_exclusion")
 	require.Contains(t, checkerNames, "statistics_unavailable_due_to_number_overflow")
+	require.Contains(t, checkerNames, "ddns_updates_without_d2")
 
 	checkerNames = []string{}
 	for _, p := range dispatcher.groups[KeaCADaemon].checkers {
@@ -703,19 +811,39 @@ func TestRegisterDefaultCheckers(t *testing.T) {
 	require.Contains(t, checkerNames, "agent_credentials_over_https")
 	require.Contains(t, checkerNames, "ca_control_sockets")
 
+	checkerNames = []string{}
+	for _, p := range dispatcher.groups[KeaD2Daemon].checkers {
+		checkerNames = append(checkerNames, p.name)
+	}
+	require.Contains(t, checkerNames, "d2_domains_without_servers")
+
+	checkerNames = []string{}
+	for _, p := range dispatcher.groups[Bind9Daemon].checkers {
+		checkerNames = append(checkerNames, p.name)
+	}
+	require.Contains(t, checkerNames, "bind9_open_recursion")
+	require.Contains(t, checkerNames, "bind9_unrestricted_zone_transfer")
+	require.Contains(t, checkerNames, "bind9_statistics_channel_exposed")
+	require.Contains(t, checkerNames, "bind9_rndc_key_weak_algorithm")
+	require.Contains(t, checkerNames, "bind9_dnssec_validation_disabled")
+
 	// Ensure that the appropriate triggers were registered for the
 	// default checkers.
 	require.Contains(t, dispatcher.groups[KeaDHCPDaemon].triggerRefCounts, ManualRun)
 	require.Contains(t, dispatcher.groups[KeaDHCPDaemon].triggerRefCounts, ConfigModified)
 	require.Contains(t, dispatcher.groups[KeaDHCPDaemon].triggerRefCounts, DBHostsModified)
 
-	require.EqualValues(t, 13, dispatcher.groups[KeaDHCPDaemon].triggerRefCounts[ManualRun])
-	require.EqualValues(t, 13, dispatcher.groups[KeaDHCPDaemon].triggerRefCounts[ConfigModified])
+	require.EqualValues(t, 14, dispatcher.groups[KeaDHCPDaemon].triggerRefCounts[ManualRun])
+	require.EqualValues(t, 14, dispatcher.groups[KeaDHCPDaemon].triggerRefCounts[ConfigModified])
 	require.EqualValues(t, 4, dispatcher.groups[KeaDHCPDaemon].triggerRefCounts[DBHostsModified])
 	require.EqualValues(t, 0, dispatcher.groups[KeaDHCPDaemon].triggerRefCounts[StorkAgentConfigModified])
 	require.EqualValues(t, 2, dispatcher.groups[KeaCADaemon].triggerRefCounts[ManualRun])
 	require.EqualValues(t, 2, dispatcher.groups[KeaCADaemon].triggerRefCounts[ConfigModified])
 	require.EqualValues(t, 0, dispatcher.groups[KeaCADaemon].triggerRefCounts[DBHostsModified])
+	require.EqualValues(t, 1, dispatcher.groups[KeaD2Daemon].triggerRefCounts[ManualRun])
+	require.EqualValues(t, 1, dispatcher.groups[KeaD2Daemon].triggerRefCounts[ConfigModified])
+	require.EqualValues(t, 5, dispatcher.groups[Bind9Daemon].triggerRefCounts[ManualRun])
+	require.EqualValues(t, 5, dispatcher.groups[Bind9Daemon].triggerRefCounts[ConfigModified])
 	require.EqualValues(t, 1, dispatcher.groups[KeaCADaemon].triggerRefCounts[StorkAgentConfigModified])
 }
 
@@ -725,7 +853,7 @@ func TestGetSignature(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
 	defer teardown()
 
-	dispatcher := NewDispatcher(db).(*dispatcherImpl)
+	dispatcher := NewDispatcher(db, nil).(*dispatcherImpl)
 	require.NotNil(t, dispatcher)
 
 	signatures := make([]string, 9)
@@ -804,7 +932,7 @@ func TestGetCheckersMetadata(t *testing.T) {
 	daemon1 := &dbmodel.Daemon{ID: 1, Name: dbmodel.DaemonNameDHCPv4}
 	daemon2 := &dbmodel.Daemon{ID: 2, Name: dbmodel.DaemonNameBind9}
 	daemon3 := &dbmodel.Daemon{ID: 3, Name: "unknown"}
-	dispatcher := NewDispatcher(db)
+	dispatcher := NewDispatcher(db, nil)
 	dispatcher.RegisterChecker(KeaDHCPDaemon, "foo", Triggers{ManualRun, ConfigModified}, nil)
 	dispatcher.RegisterChecker(KeaDHCPDaemon, "bar", Triggers{ManualRun, DBHostsModified}, nil)
 	dispatcher.RegisterChecker(KeaDHCPDaemon, "baz", Triggers{ConfigModified, DBHostsModified}, nil)
@@ -889,7 +1017,7 @@ func TestLoadAndValidateCheckerState(t *testing.T) {
 	daemons, _ := dbmodel.AddApp(db, app)
 	daemon := daemons[0]
 
-	dispatcher := NewDispatcher(db)
+	dispatcher := NewDispatcher(db, nil)
 	dispatcher.RegisterChecker(KeaDHCPDaemon, "foo", Triggers{ManualRun, ConfigModified}, nil)
 	dispatcher.RegisterChecker(KeaDHCPDaemon, "bar", Triggers{ManualRun, DBHostsModified}, nil)
 	dispatcher.RegisterChecker(KeaDHCPDaemon, "baz", Triggers{ManualRun}, nil)
@@ -948,7 +1076,7 @@ func TestBeginReviewForDaemonWithAllCheckersDisabled(t *testing.T) {
 	daemons, _ := dbmodel.AddApp(db, app)
 	daemon := daemons[0]
 
-	dispatcher := NewDispatcher(db)
+	dispatcher := NewDispatcher(db, nil)
 	dispatcher.RegisterChecker(KeaDHCPDaemon, "foo", Triggers{ManualRun, ConfigModified}, func(rc *ReviewContext) (*Report, error) {
 		require.Fail(t, "checker function shouldn't be called")
 		return nil, nil
@@ -991,7 +1119,7 @@ func TestBeginReviewForDaemonWithSomeCheckersDisabled(t *testing.T) {
 	daemon := daemons[0]
 	checkerCallCount := 0
 
-	dispatcher := NewDispatcher(db)
+	dispatcher := NewDispatcher(db, nil)
 	dispatcher.RegisterChecker(KeaDHCPDaemon, "foo", Triggers{ManualRun, ConfigModified}, func(rc *ReviewContext) (*Report, error) {
 		require.Fail(t, "checker function shouldn't be called")
 		return nil, nil
@@ -1024,7 +1152,7 @@ func TestSetCheckerStateToInvalidValue(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
 	defer teardown()
 	daemon := &dbmodel.Daemon{ID: 1, Name: dbmodel.DaemonNameDHCPv4}
-	dispatcher := NewDispatcher(db)
+	dispatcher := NewDispatcher(db, nil)
 	dispatcher.RegisterChecker(KeaDHCPDaemon, "foo", Triggers{ManualRun, ConfigModified}, nil)
 
 	// Act
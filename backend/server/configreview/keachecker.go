@@ -1,6 +1,7 @@
 package configreview
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"net/url"
@@ -878,6 +879,259 @@ func highAvailabilityDedicatedPorts(ctx *ReviewContext) (*Report, error) {
 	return nil, nil
 }
 
+// The parameters compared by the HA peers consistency checker.
+var haPeersComparedParameters = []string{
+	"subnet4", "subnet6", "shared-networks", "reservations", "client-classes", "option-data",
+}
+
+// The parameters which may legitimately differ between the HA peers
+// because they are specific to the host running the server, e.g., the
+// names of the interfaces over which the subnets are reachable, or
+// because they don't affect the lease allocation, e.g., the comments.
+// They are removed at any level of the compared parameters.
+var haPeersIgnoredParameters = map[string]bool{
+	"interface":    true,
+	"user-context": true,
+	"comment":      true,
+}
+
+// The categories of the differences between the HA peers' configurations
+// by the names of the lists holding the differing values. The deepest
+// list found in the path of the differing value determines the category.
+var haPeersDifferenceCategories = []struct {
+	marker   string
+	category string
+}{
+	{"subnet4[", "subnets"},
+	{"subnet6[", "subnets"},
+	{"shared-networks[", "subnets"},
+	{"pools[", "pools"},
+	{"pd-pools[", "pools"},
+	{"reservations[", "reservations"},
+	{"client-classes", "client classes"},
+	{"option-data", "option data"},
+	{"high-availability", "HA peers"},
+}
+
+// The checker verifies that the configurations of the servers in each HA
+// relationship are consistent. It compares the subnets, pools, reservations,
+// client classes, option data and the HA peer lists of the subject daemon
+// with the configurations of its peers monitored by Stork. The servers in
+// the hub-and-spoke configurations serve different sets of subnets, so only
+// their HA peer lists are compared.
+func highAvailabilityPeersConsistency(ctx *ReviewContext) (*Report, error) {
+	config := ctx.subjectDaemon.KeaDaemon.Config
+
+	_, haConfig, ok := config.GetHookLibraries().GetHAHookLibrary()
+	if !ok || ctx.subjectDaemon.AppID == 0 {
+		// There is no HA configured or the daemon is not in the database.
+		return nil, nil
+	}
+	relationships := haConfig.GetAllRelationships()
+
+	services, err := dbmodel.GetDetailedServicesByAppID(ctx.db, ctx.subjectDaemon.AppID)
+	if err != nil {
+		return nil, err
+	}
+
+	const maxExamples = 3
+	var (
+		issues        []string
+		peerDaemons   []*dbmodel.Daemon
+		comparedPeers = make(map[int64]bool)
+	)
+	for i := range relationships {
+		if !relationships[i].IsValid() {
+			continue
+		}
+		for _, peerDaemon := range findHAPeerDaemons(ctx.subjectDaemon, &relationships[i], services) {
+			if comparedPeers[peerDaemon.ID] {
+				continue
+			}
+			comparedPeers[peerDaemon.ID] = true
+
+			peerConfig := peerDaemon.KeaDaemon.Config
+			peerRelationship, peerRelationshipsCount := findHAPeerRelationship(peerConfig, &relationships[i])
+			peersOnly := len(relationships) > 1 || peerRelationshipsCount > 1
+
+			diff := keaconfig.DiffConfigs(
+				getHAPeersComparedConfig(config, &relationships[i], peersOnly),
+				getHAPeersComparedConfig(peerConfig, peerRelationship, peersOnly),
+			)
+			if len(diff) == 0 {
+				continue
+			}
+
+			var (
+				categories      []string
+				foundCategories = make(map[string]bool)
+				examples        []string
+			)
+			for _, entry := range diff {
+				category := getHAPeersDifferenceCategory(entry.Path)
+				if !foundCategories[category] {
+					foundCategories[category] = true
+					categories = append(categories, category)
+				}
+				if len(examples) < maxExamples {
+					examples = append(examples, fmt.Sprintf("%s %s", entry.Path, entry.Operation))
+				}
+			}
+			issue := fmt.Sprintf("{daemon} has %d differences in the %s", len(diff), strings.Join(categories, ", "))
+			if len(diff) > len(examples) {
+				issue += fmt.Sprintf(" (e.g., %s)", strings.Join(examples, "; "))
+			} else {
+				issue += fmt.Sprintf(" (%s)", strings.Join(examples, "; "))
+			}
+			issues = append(issues, issue)
+			peerDaemons = append(peerDaemons, peerDaemon)
+		}
+	}
+
+	if len(issues) == 0 {
+		return nil, nil
+	}
+
+	// The peers are fetched by the checker, so they must be reviewed
+	// again when the subject daemon's configuration changes.
+	ctx.refDaemons = append(ctx.refDaemons, peerDaemons...)
+
+	report := NewReport(ctx, fmt.Sprintf("The configuration of the {daemon} "+
+		"is inconsistent with the configurations of its HA peers. The HA "+
+		"peers must serve the same subnets, pools, reservations, client "+
+		"classes and options to allocate the same leases and to take over "+
+		"the DHCP service reliably after a failover. Differences found "+
+		"in the peers' configurations: %s.", strings.Join(issues, "; "))).
+		referencingDaemon(ctx.subjectDaemon)
+	for _, peerDaemon := range peerDaemons {
+		report = report.referencingDaemon(peerDaemon)
+	}
+	return report.create()
+}
+
+// Returns the daemons belonging to the HA service matching the relationship
+// except the subject daemon. The daemons lacking the configurations are
+// skipped.
+func findHAPeerDaemons(subjectDaemon *dbmodel.Daemon, relationship *keaconfig.HA, services []dbmodel.Service) (peerDaemons []*dbmodel.Daemon) {
+	for _, service := range services {
+		if service.HAService == nil || service.HAService.HAType != subjectDaemon.Name {
+			continue
+		}
+		matched := false
+		for _, peer := range relationship.Peers {
+			if peer.Name != nil && *peer.Name == service.HAService.Relationship {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		for _, daemon := range service.Daemons {
+			if daemon.ID == subjectDaemon.ID || daemon.KeaDaemon == nil || daemon.KeaDaemon.Config == nil {
+				continue
+			}
+			peerDaemons = append(peerDaemons, daemon)
+		}
+	}
+	return peerDaemons
+}
+
+// Finds the relationship in the peer's configuration corresponding to the
+// subject daemon's relationship, i.e., the relationship of the server that
+// belongs to the subject daemon's relationship. It also returns the number
+// of the relationships configured in the peer.
+func findHAPeerRelationship(peerConfig *dbmodel.KeaConfig, relationship *keaconfig.HA) (*keaconfig.HA, int) {
+	_, peerHAConfig, ok := peerConfig.GetHookLibraries().GetHAHookLibrary()
+	if !ok {
+		return nil, 0
+	}
+	peerRelationships := peerHAConfig.GetAllRelationships()
+	for i := range peerRelationships {
+		if peerRelationships[i].ThisServerName == nil {
+			continue
+		}
+		for _, peer := range relationship.Peers {
+			if peer.Name != nil && *peer.Name == *peerRelationships[i].ThisServerName {
+				return &peerRelationships[i], len(peerRelationships)
+			}
+		}
+	}
+	return nil, len(peerRelationships)
+}
+
+// Returns a copy of the raw configuration value without the parameters
+// ignored in the comparison of the HA peers' configurations.
+func withoutHAPeersIgnoredParameters(value any) any {
+	switch typedValue := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(typedValue))
+		for key, item := range typedValue {
+			if haPeersIgnoredParameters[key] {
+				continue
+			}
+			copied[key] = withoutHAPeersIgnoredParameters(item)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(typedValue))
+		for i, item := range typedValue {
+			copied[i] = withoutHAPeersIgnoredParameters(item)
+		}
+		return copied
+	default:
+		return value
+	}
+}
+
+// Returns the configuration holding only the parameters that must be
+// consistent between the HA peers. The host-specific parameters, e.g.,
+// the interface names, are excluded. The HA peers list is included under
+// the high-availability key.
+func getHAPeersComparedConfig(config *dbmodel.KeaConfig, relationship *keaconfig.HA, peersOnly bool) *keaconfig.Config {
+	rootName := "Dhcp6"
+	if config.IsDHCPv4() {
+		rootName = "Dhcp4"
+	}
+	root := make(map[string]any)
+	if !peersOnly {
+		if rawRoot, ok := config.Raw[rootName].(map[string]any); ok {
+			for _, parameter := range haPeersComparedParameters {
+				if value, ok := rawRoot[parameter]; ok {
+					root[parameter] = withoutHAPeersIgnoredParameters(value)
+				}
+			}
+		}
+	}
+	if relationship != nil {
+		// Convert the peers to the raw form for comparison.
+		var peers []any
+		if data, err := json.Marshal(relationship.Peers); err == nil {
+			_ = json.Unmarshal(data, &peers)
+		}
+		root["high-availability"] = map[string]any{
+			"peers": peers,
+		}
+	}
+	return keaconfig.NewConfigFromMap(&map[string]any{
+		rootName: root,
+	})
+}
+
+// Returns the category of the difference between the HA peers'
+// configurations.
+func getHAPeersDifferenceCategory(path string) string {
+	category := "subnets"
+	position := -1
+	for _, c := range haPeersDifferenceCategories {
+		if index := strings.LastIndex(path, c.marker); index > position {
+			position = index
+			category = c.category
+		}
+	}
+	return category
+}
+
 // The checker validates when a size of pool equals to the number of
 // reservations.
 func addressPoolsExhaustedByReservations(ctx *ReviewContext) (*Report, error) {
@@ -3291,6 +3291,358 @@ func TestHighAvailabilityDedicatedPortsCheckerLocalPeer(t *testing.T) {
 			"omitting the dedicated HTTP listener of this peer. ")
 }
 
+// Returns the configuration of a DHCPv4 server in the HA relationship
+// with the specified subnets.
+func getTestHAPeerConfig(thisServerName, subnets string) string {
+	return fmt.Sprintf(`{
+		"Dhcp4": {
+			"subnet4": %s,
+			"hooks-libraries": [
+				{
+					"library": "/usr/lib/kea/libdhcp_ha.so",
+					"parameters": {
+						"high-availability": [{
+							"this-server-name": "%s",
+							"mode": "hot-standby",
+							"peers": [
+								{ "name": "server1", "url": "http://192.0.2.1:8001/", "role": "primary" },
+								{ "name": "server2", "url": "http://192.0.2.2:8001/", "role": "standby" }
+							]
+						}]
+					}
+				}
+			]
+		}
+	}`, subnets, thisServerName)
+}
+
+// Adds a Kea app with a DHCPv4 server having the specified configuration
+// to the database.
+func addTestHAPeerApp(t *testing.T, db *dbops.PgDB, address, configStr string) *dbmodel.Daemon {
+	machine := &dbmodel.Machine{
+		Address:   address,
+		AgentPort: 8080,
+	}
+	err := dbmodel.AddMachine(db, machine)
+	require.NoError(t, err)
+
+	config, err := dbmodel.NewKeaConfigFromJSON(configStr)
+	require.NoError(t, err)
+
+	app := &dbmodel.App{
+		MachineID: machine.ID,
+		Type:      dbmodel.AppTypeKea,
+		Daemons: []*dbmodel.Daemon{
+			{
+				Name:   dbmodel.DaemonNameDHCPv4,
+				Active: true,
+				KeaDaemon: &dbmodel.KeaDaemon{
+					Config:        config,
+					KeaDHCPDaemon: &dbmodel.KeaDHCPDaemon{},
+				},
+			},
+		},
+	}
+	_, err = dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+	return app.Daemons[0]
+}
+
+// Test that the HA peers consistency checker reports the differences
+// between the configurations of the servers in the HA relationship.
+func TestHighAvailabilityPeersConsistencyChecker(t *testing.T) {
+	// Arrange
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	daemon1 := addTestHAPeerApp(t, db, "192.0.2.1", getTestHAPeerConfig("server1", `[
+		{
+			"id": 1,
+			"subnet": "192.0.2.0/24",
+			"pools": [ { "pool": "192.0.2.10-192.0.2.20" } ]
+		},
+		{ "id": 2, "subnet": "198.51.100.0/24" }
+	]`))
+	daemon2 := addTestHAPeerApp(t, db, "192.0.2.2", getTestHAPeerConfig("server2", `[
+		{ "id": 2, "subnet": "198.51.100.0/24" },
+		{
+			"id": 1,
+			"subnet": "192.0.2.0/24",
+			"pools": [ { "pool": "192.0.2.10-192.0.2.30" } ]
+		}
+	]`))
+
+	service := &dbmodel.Service{
+		BaseService: dbmodel.BaseService{
+			Daemons: []*dbmodel.Daemon{daemon1, daemon2},
+		},
+		HAService: &dbmodel.BaseHAService{
+			HAType:       dbmodel.DaemonNameDHCPv4,
+			HAMode:       "hot-standby",
+			Relationship: "server1",
+			PrimaryID:    daemon1.ID,
+			SecondaryID:  daemon2.ID,
+		},
+	}
+	err := dbmodel.AddService(db, service)
+	require.NoError(t, err)
+
+	ctx := newReviewContext(db, daemon1, Triggers{ManualRun}, nil)
+
+	// Act
+	report, err := highAvailabilityPeersConsistency(ctx)
+
+	// Assert
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "{daemon} has 2 differences in the pools")
+	require.Contains(t, *report.content, "Dhcp4.subnet4[192.0.2.0/24].pools[192.0.2.10-192.0.2.20] removed")
+	require.Equal(t, []int64{daemon1.ID, daemon2.ID}, report.refDaemonIDs)
+	require.Len(t, ctx.refDaemons, 1)
+	require.Equal(t, daemon2.ID, ctx.refDaemons[0].ID)
+}
+
+// Test that the HA peers consistency checker produces no report when the
+// configurations of the HA peers are consistent.
+func TestHighAvailabilityPeersConsistencyCheckerConsistent(t *testing.T) {
+	// Arrange
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	subnets := `[ { "id": 1, "subnet": "192.0.2.0/24" } ]`
+	daemon1 := addTestHAPeerApp(t, db, "192.0.2.1", getTestHAPeerConfig("server1", subnets))
+	daemon2 := addTestHAPeerApp(t, db, "192.0.2.2", getTestHAPeerConfig("server2", subnets))
+
+	service := &dbmodel.Service{
+		BaseService: dbmodel.BaseService{
+			Daemons: []*dbmodel.Daemon{daemon1, daemon2},
+		},
+		HAService: &dbmodel.BaseHAService{
+			HAType:       dbmodel.DaemonNameDHCPv4,
+			Relationship: "server1",
+		},
+	}
+	err := dbmodel.AddService(db, service)
+	require.NoError(t, err)
+
+	ctx := newReviewContext(db, daemon2, Triggers{ManualRun}, nil)
+
+	// Act
+	report, err := highAvailabilityPeersConsistency(ctx)
+
+	// Assert
+	require.NoError(t, err)
+	require.Nil(t, report)
+	require.Empty(t, ctx.refDaemons)
+}
+
+// Test that the HA peers consistency checker produces no report if the HA
+// is not configured.
+func TestHighAvailabilityPeersConsistencyCheckerMissingHAHook(t *testing.T) {
+	// Arrange
+	ctx := createReviewContext(t, nil, `{ "Dhcp4": { "hooks-libraries": [ ] } }`, "2.4.0")
+
+	// Act
+	report, err := highAvailabilityPeersConsistency(ctx)
+
+	// Assert
+	require.Nil(t, report)
+	require.NoError(t, err)
+}
+
+// Test that the daemons belonging to the HA service matching the
+// relationship are returned as the HA peers.
+func TestFindHAPeerDaemons(t *testing.T) {
+	config, err := dbmodel.NewKeaConfigFromJSON(getTestHAPeerConfig("server1", "[]"))
+	require.NoError(t, err)
+	_, haConfig, ok := config.GetHookLibraries().GetHAHookLibrary()
+	require.True(t, ok)
+	relationship := haConfig.GetAllRelationships()[0]
+
+	subject := &dbmodel.Daemon{ID: 1, Name: dbmodel.DaemonNameDHCPv4, KeaDaemon: &dbmodel.KeaDaemon{Config: config}}
+	peer := &dbmodel.Daemon{ID: 2, Name: dbmodel.DaemonNameDHCPv4, KeaDaemon: &dbmodel.KeaDaemon{Config: config}}
+	other := &dbmodel.Daemon{ID: 3, Name: dbmodel.DaemonNameDHCPv4, KeaDaemon: &dbmodel.KeaDaemon{Config: config}}
+	unconfigured := &dbmodel.Daemon{ID: 4, Name: dbmodel.DaemonNameDHCPv4, KeaDaemon: &dbmodel.KeaDaemon{}}
+
+	services := []dbmodel.Service{
+		{
+			BaseService: dbmodel.BaseService{
+				Daemons: []*dbmodel.Daemon{subject, peer, unconfigured},
+			},
+			HAService: &dbmodel.BaseHAService{
+				HAType:       dbmodel.DaemonNameDHCPv4,
+				Relationship: "server2",
+			},
+		},
+		{
+			BaseService: dbmodel.BaseService{
+				Daemons: []*dbmodel.Daemon{subject, other},
+			},
+			HAService: &dbmodel.BaseHAService{
+				HAType:       dbmodel.DaemonNameDHCPv4,
+				Relationship: "server3",
+			},
+		},
+		{
+			BaseService: dbmodel.BaseService{
+				Daemons: []*dbmodel.Daemon{subject, other},
+			},
+			HAService: &dbmodel.BaseHAService{
+				HAType:       dbmodel.DaemonNameDHCPv6,
+				Relationship: "server1",
+			},
+		},
+	}
+
+	peers := findHAPeerDaemons(subject, &relationship, services)
+	require.Len(t, peers, 1)
+	require.Equal(t, peer, peers[0])
+}
+
+// Test that the relationship corresponding to the subject daemon's
+// relationship is found in the peer's configuration.
+func TestFindHAPeerRelationship(t *testing.T) {
+	config, err := dbmodel.NewKeaConfigFromJSON(getTestHAPeerConfig("server1", "[]"))
+	require.NoError(t, err)
+	_, haConfig, _ := config.GetHookLibraries().GetHAHookLibrary()
+	relationship := haConfig.GetAllRelationships()[0]
+
+	peerConfig, err := dbmodel.NewKeaConfigFromJSON(getTestHAPeerConfig("server2", "[]"))
+	require.NoError(t, err)
+	peerRelationship, count := findHAPeerRelationship(peerConfig, &relationship)
+	require.NotNil(t, peerRelationship)
+	require.Equal(t, "server2", *peerRelationship.ThisServerName)
+	require.Equal(t, 1, count)
+
+	otherConfig, err := dbmodel.NewKeaConfigFromJSON(strings.ReplaceAll(getTestHAPeerConfig("server2", "[]"), "server", "host"))
+	require.NoError(t, err)
+	peerRelationship, count = findHAPeerRelationship(otherConfig, &relationship)
+	require.Nil(t, peerRelationship)
+	require.Equal(t, 1, count)
+
+	noHAConfig, err := dbmodel.NewKeaConfigFromJSON(`{ "Dhcp4": { } }`)
+	require.NoError(t, err)
+	peerRelationship, count = findHAPeerRelationship(noHAConfig, &relationship)
+	require.Nil(t, peerRelationship)
+	require.Zero(t, count)
+}
+
+// Test that the differences between the HA peers are found in the subnets,
+// pools, reservations, client classes, option data and the HA peer lists,
+// and that the other parameters are not compared.
+func TestHAPeersComparedConfigDiff(t *testing.T) {
+	config1, err := dbmodel.NewKeaConfigFromJSON(`{
+		"Dhcp4": {
+			"valid-lifetime": 4000,
+			"subnet4": [ { "id": 1, "subnet": "192.0.2.0/24" } ],
+			"reservations": [ { "hw-address": "01:02:03:04:05:06", "ip-address": "192.0.2.5" } ],
+			"client-classes": [ { "name": "foo" } ],
+			"option-data": [ { "code": 6, "data": "192.0.2.1" } ]
+		}
+	}`)
+	require.NoError(t, err)
+	config2, err := dbmodel.NewKeaConfigFromJSON(`{
+		"Dhcp4": {
+			"valid-lifetime": 3000,
+			"subnet4": [ { "id": 2, "subnet": "192.0.2.0/24" } ],
+			"reservations": [ { "hw-address": "01:02:03:04:05:06", "ip-address": "192.0.2.6" } ],
+			"client-classes": [ { "name": "bar" } ],
+			"option-data": [ { "code": 6, "data": "192.0.2.2" } ]
+		}
+	}`)
+	require.NoError(t, err)
+
+	relationship := keaconfig.HA{
+		Peers: []keaconfig.Peer{
+			{Name: storkutil.Ptr("server1"), URL: storkutil.Ptr("http://192.0.2.1:8001/")},
+		},
+	}
+
+	diff := keaconfig.DiffConfigs(
+		getHAPeersComparedConfig(config1, &relationship, false),
+		getHAPeersComparedConfig(config2, nil, false),
+	)
+	var categories []string
+	for _, entry := range diff {
+		categories = append(categories, getHAPeersDifferenceCategory(entry.Path))
+	}
+	require.Equal(t, []string{"client classes", "client classes", "HA peers", "option data", "reservations", "subnets"}, categories)
+
+	// Only the HA peers are compared in the hub-and-spoke configurations.
+	diff = keaconfig.DiffConfigs(
+		getHAPeersComparedConfig(config1, &relationship, true),
+		getHAPeersComparedConfig(config2, &relationship, true),
+	)
+	require.Empty(t, diff)
+}
+
+// Test that the HA peers whose configurations differ only in the
+// host-specific parameters, e.g., the interface names, are consistent.
+func TestHAPeersComparedConfigDiffInterfaces(t *testing.T) {
+	config1, err := dbmodel.NewKeaConfigFromJSON(`{
+		"Dhcp4": {
+			"shared-networks": [
+				{
+					"name": "foo",
+					"interface": "eth0",
+					"subnet4": [ { "id": 1, "subnet": "192.0.2.0/24", "interface": "eth0" } ]
+				}
+			],
+			"subnet4": [
+				{
+					"id": 2,
+					"subnet": "192.0.3.0/24",
+					"interface": "eth1",
+					"user-context": { "site": "server1" },
+					"pools": [ { "pool": "192.0.3.10-192.0.3.100", "comment": "server1" } ]
+				}
+			]
+		}
+	}`)
+	require.NoError(t, err)
+	config2, err := dbmodel.NewKeaConfigFromJSON(`{
+		"Dhcp4": {
+			"shared-networks": [
+				{
+					"name": "foo",
+					"interface": "ens3",
+					"subnet4": [ { "id": 1, "subnet": "192.0.2.0/24", "interface": "ens3" } ]
+				}
+			],
+			"subnet4": [
+				{
+					"id": 2,
+					"subnet": "192.0.3.0/24",
+					"pools": [ { "pool": "192.0.3.10-192.0.3.100" } ]
+				}
+			]
+		}
+	}`)
+	require.NoError(t, err)
+
+	diff := keaconfig.DiffConfigs(
+		getHAPeersComparedConfig(config1, nil, false),
+		getHAPeersComparedConfig(config2, nil, false),
+	)
+	require.Empty(t, diff)
+
+	// The original configuration should not be modified.
+	subnets := config1.Raw["Dhcp4"].(map[string]any)["subnet4"].([]any)
+	require.Equal(t, "eth1", subnets[0].(map[string]any)["interface"])
+}
+
+// Test the categories of the differences between the HA peers.
+func TestGetHAPeersDifferenceCategory(t *testing.T) {
+	require.Equal(t, "subnets", getHAPeersDifferenceCategory("Dhcp4.subnet4[192.0.2.0/24].valid-lifetime"))
+	require.Equal(t, "subnets", getHAPeersDifferenceCategory("Dhcp4.shared-networks[foo].subnet4[192.0.2.0/24]"))
+	require.Equal(t, "pools", getHAPeersDifferenceCategory("Dhcp4.subnet4[192.0.2.0/24].pools[192.0.2.1-192.0.2.10]"))
+	require.Equal(t, "pools", getHAPeersDifferenceCategory("Dhcp6.subnet6[2001:db8:1::/64].pd-pools[3000::/48]"))
+	require.Equal(t, "reservations", getHAPeersDifferenceCategory("Dhcp4.subnet4[192.0.2.0/24].reservations[hw-address=01:02:03:04:05:06].hostname"))
+	require.Equal(t, "option data", getHAPeersDifferenceCategory("Dhcp4.subnet4[192.0.2.0/24].pools[192.0.2.1-192.0.2.10].option-data[3].data"))
+	require.Equal(t, "client classes", getHAPeersDifferenceCategory("Dhcp4.client-classes[foo].test"))
+	require.Equal(t, "HA peers", getHAPeersDifferenceCategory("Dhcp4.high-availability.peers[server1].url"))
+}
+
 // Test that the error is returned if the non-DHCP daemon is checking.
 func TestAddressPoolsExhaustedByReservationsForNonDHCPDaemonConfig(t *testing.T) {
 	// Arrange
@@ -0,0 +1,62 @@
+package dbmigs
+
+import "github.com/go-pg/migrations/v8"
+
+// This migration adds a table holding the permissions granted to the
+// custom user groups. A permission may be optionally scoped to a specific
+// app, daemon or subnet. The migration also reserves a range of the group
+// identifiers for the built-in groups.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			-- The group names must be unique.
+			ALTER TABLE system_group
+				ADD CONSTRAINT system_group_name_unique_idx UNIQUE (name);
+
+			-- The identifiers below 100 are reserved for the built-in
+			-- groups. The custom groups get higher identifiers.
+			SELECT setval('system_group_id_seq', GREATEST(100, (SELECT MAX(id) FROM system_group)));
+
+			CREATE TABLE IF NOT EXISTS system_group_permission (
+				id BIGSERIAL NOT NULL,
+				group_id BIGINT NOT NULL,
+				permission TEXT NOT NULL,
+				app_id BIGINT,
+				daemon_id BIGINT,
+				subnet_id BIGINT,
+				CONSTRAINT system_group_permission_pkey PRIMARY KEY (id),
+				CONSTRAINT system_group_permission_group_id_fkey FOREIGN KEY (group_id)
+					REFERENCES system_group (id) MATCH SIMPLE
+					ON UPDATE CASCADE
+					ON DELETE CASCADE,
+				CONSTRAINT system_group_permission_app_id_fkey FOREIGN KEY (app_id)
+					REFERENCES app (id) MATCH SIMPLE
+					ON UPDATE CASCADE
+					ON DELETE CASCADE,
+				CONSTRAINT system_group_permission_daemon_id_fkey FOREIGN KEY (daemon_id)
+					REFERENCES daemon (id) MATCH SIMPLE
+					ON UPDATE CASCADE
+					ON DELETE CASCADE,
+				CONSTRAINT system_group_permission_subnet_id_fkey FOREIGN KEY (subnet_id)
+					REFERENCES subnet (id) MATCH SIMPLE
+					ON UPDATE CASCADE
+					ON DELETE CASCADE,
+				CONSTRAINT system_group_permission_check CHECK (
+					permission IN ('view', 'manage-hosts', 'manage-subnets', 'manage-machines')
+				)
+			);
+
+			CREATE INDEX system_group_permission_group_id_idx
+				ON system_group_permission (group_id);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DROP TABLE IF EXISTS system_group_permission;
+
+			ALTER TABLE system_group
+				DROP CONSTRAINT IF EXISTS system_group_name_unique_idx;
+		`)
+		return err
+	})
+}
@@ -0,0 +1,20 @@
+package dbmigs
+
+import "github.com/go-pg/migrations/v8"
+
+// This migration adds the built-in read-only group. The users belonging to
+// this group can view all monitored data but they cannot modify anything.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			INSERT INTO system_group (id, name, description)
+				VALUES (3, 'read-only', 'This group of users can view the system components but cannot modify them.');
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DELETE FROM system_group WHERE id = 3;
+		`)
+		return err
+	})
+}
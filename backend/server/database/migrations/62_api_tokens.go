@@ -0,0 +1,35 @@
+package dbmigs
+
+import "github.com/go-pg/migrations/v8"
+
+// This migration adds a table holding the personal API tokens of the
+// users. The tokens are used to access the REST API without logging in.
+// Only the hashes of the tokens are stored.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE IF NOT EXISTS api_token (
+				id BIGSERIAL NOT NULL,
+				user_id INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				token_hash TEXT NOT NULL,
+				created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+				expires_at TIMESTAMP WITHOUT TIME ZONE,
+				last_used_at TIMESTAMP WITHOUT TIME ZONE,
+				CONSTRAINT api_token_pkey PRIMARY KEY (id),
+				CONSTRAINT api_token_token_hash_unique_idx UNIQUE (token_hash),
+				CONSTRAINT api_token_user_id_name_unique_idx UNIQUE (user_id, name),
+				CONSTRAINT api_token_user_id_fkey FOREIGN KEY (user_id)
+					REFERENCES system_user (id) MATCH SIMPLE
+					ON UPDATE CASCADE
+					ON DELETE CASCADE
+			);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DROP TABLE IF EXISTS api_token;
+		`)
+		return err
+	})
+}
@@ -0,0 +1,47 @@
+package dbmigs
+
+import "github.com/go-pg/migrations/v8"
+
+// This migration adds a table holding the audit trail of the configuration
+// changes made through Stork. Each entry describes who applied the change,
+// when, which daemons were affected, the entity before and after the change,
+// and the commands sent to the daemons with their results. The login of the
+// user is copied to the entry so the entry remains meaningful after the user
+// is deleted.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE IF NOT EXISTS audit_entry (
+				id BIGSERIAL NOT NULL,
+				created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+				user_id INTEGER,
+				user_login TEXT,
+				target TEXT NOT NULL,
+				operation TEXT NOT NULL,
+				entity_type TEXT NOT NULL,
+				entity_id BIGINT,
+				daemon_ids BIGINT[],
+				entity_before JSONB,
+				entity_after JSONB,
+				commands JSONB,
+				scheduled BOOLEAN NOT NULL DEFAULT FALSE,
+				error TEXT,
+				CONSTRAINT audit_entry_pkey PRIMARY KEY (id),
+				CONSTRAINT audit_entry_user_id_fkey FOREIGN KEY (user_id)
+					REFERENCES system_user (id) MATCH SIMPLE
+					ON UPDATE CASCADE
+					ON DELETE SET NULL
+			);
+			CREATE INDEX IF NOT EXISTS audit_entry_created_at_idx ON audit_entry (created_at);
+			CREATE INDEX IF NOT EXISTS audit_entry_user_id_idx ON audit_entry (user_id);
+			CREATE INDEX IF NOT EXISTS audit_entry_entity_idx ON audit_entry (entity_type, entity_id);
+			CREATE INDEX IF NOT EXISTS audit_entry_daemon_ids_idx ON audit_entry USING GIN (daemon_ids);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DROP TABLE IF EXISTS audit_entry;
+		`)
+		return err
+	})
+}
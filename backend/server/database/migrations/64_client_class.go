@@ -0,0 +1,36 @@
+package dbmigs
+
+import "github.com/go-pg/migrations/v8"
+
+// This migration adds a table holding the client classes configured in
+// the Kea DHCP servers. The classes are stored per daemon because the
+// class definitions (e.g., the test expressions) may differ between the
+// servers. The position column preserves the order of the classes in the
+// configuration, which is significant for the class evaluation.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE IF NOT EXISTS client_class (
+				id BIGSERIAL NOT NULL,
+				created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+				daemon_id BIGINT NOT NULL,
+				name TEXT NOT NULL,
+				position INTEGER NOT NULL DEFAULT 0,
+				kea_parameters JSONB,
+				CONSTRAINT client_class_pkey PRIMARY KEY (id),
+				CONSTRAINT client_class_daemon_id_name_unique UNIQUE (daemon_id, name),
+				CONSTRAINT client_class_daemon_id_fkey FOREIGN KEY (daemon_id)
+					REFERENCES daemon (id) MATCH SIMPLE
+					ON UPDATE CASCADE
+					ON DELETE CASCADE
+			);
+			CREATE INDEX IF NOT EXISTS client_class_name_idx ON client_class (name);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DROP TABLE IF EXISTS client_class;
+		`)
+		return err
+	})
+}
@@ -0,0 +1,31 @@
+package dbmigs
+
+import "github.com/go-pg/migrations/v8"
+
+// This migration adds the manage-leases permission to the list of the
+// permissions that can be granted to the custom user groups.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE system_group_permission
+				DROP CONSTRAINT IF EXISTS system_group_permission_check;
+			ALTER TABLE system_group_permission
+				ADD CONSTRAINT system_group_permission_check CHECK (
+					permission IN ('view', 'manage-hosts', 'manage-subnets', 'manage-machines', 'manage-leases')
+				);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DELETE FROM system_group_permission
+				WHERE permission = 'manage-leases';
+			ALTER TABLE system_group_permission
+				DROP CONSTRAINT IF EXISTS system_group_permission_check;
+			ALTER TABLE system_group_permission
+				ADD CONSTRAINT system_group_permission_check CHECK (
+					permission IN ('view', 'manage-hosts', 'manage-subnets', 'manage-machines')
+				);
+		`)
+		return err
+	})
+}
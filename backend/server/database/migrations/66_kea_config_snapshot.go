@@ -0,0 +1,53 @@
+package dbmigs
+
+import "github.com/go-pg/migrations/v8"
+
+// This migration adds a table holding the history of the Kea daemons'
+// configurations. A new snapshot is stored whenever a changed configuration
+// is fetched from a daemon and when a daemon is rolled back to a stored
+// configuration. The snapshot records who or what caused the change. The
+// fetched snapshots are associated with the audit entry describing the
+// change made through Stork, if any.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE IF NOT EXISTS kea_config_snapshot (
+				id BIGSERIAL NOT NULL,
+				created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+				daemon_id BIGINT NOT NULL,
+				config JSONB NOT NULL,
+				config_hash TEXT,
+				source TEXT NOT NULL,
+				user_id INTEGER,
+				user_login TEXT,
+				audit_entry_id BIGINT,
+				operation TEXT,
+				description TEXT,
+				CONSTRAINT kea_config_snapshot_pkey PRIMARY KEY (id),
+				CONSTRAINT kea_config_snapshot_source_check CHECK (
+					source IN ('fetched', 'rollback')
+				),
+				CONSTRAINT kea_config_snapshot_daemon_id_fkey FOREIGN KEY (daemon_id)
+					REFERENCES daemon (id) MATCH SIMPLE
+					ON UPDATE CASCADE
+					ON DELETE CASCADE,
+				CONSTRAINT kea_config_snapshot_user_id_fkey FOREIGN KEY (user_id)
+					REFERENCES system_user (id) MATCH SIMPLE
+					ON UPDATE CASCADE
+					ON DELETE SET NULL,
+				CONSTRAINT kea_config_snapshot_audit_entry_id_fkey FOREIGN KEY (audit_entry_id)
+					REFERENCES audit_entry (id) MATCH SIMPLE
+					ON UPDATE CASCADE
+					ON DELETE SET NULL
+			);
+			CREATE INDEX IF NOT EXISTS kea_config_snapshot_daemon_id_created_at_idx
+				ON kea_config_snapshot (daemon_id, created_at);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DROP TABLE IF EXISTS kea_config_snapshot;
+		`)
+		return err
+	})
+}
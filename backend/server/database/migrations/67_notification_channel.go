@@ -0,0 +1,44 @@
+package dbmigs
+
+import "github.com/go-pg/migrations/v8"
+
+// This migration adds a table holding the outbound notification channels.
+// Each channel receives the events and the new configuration review issues
+// matching its minimum level and object filters. The webhook channels
+// deliver them as HTTP POST requests to the specified URL.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE IF NOT EXISTS notification_channel (
+				id BIGSERIAL NOT NULL,
+				created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+				name TEXT NOT NULL,
+				type TEXT NOT NULL,
+				enabled BOOLEAN NOT NULL DEFAULT TRUE,
+				url TEXT NOT NULL,
+				body_template TEXT,
+				content_type TEXT,
+				level INTEGER NOT NULL DEFAULT 0,
+				machine_ids BIGINT[],
+				app_ids BIGINT[],
+				daemon_ids BIGINT[],
+				subnet_ids BIGINT[],
+				max_retries INTEGER NOT NULL DEFAULT 5,
+				CONSTRAINT notification_channel_pkey PRIMARY KEY (id),
+				CONSTRAINT notification_channel_name_unique UNIQUE (name),
+				CONSTRAINT notification_channel_type_check CHECK (
+					type IN ('webhook')
+				),
+				CONSTRAINT notification_channel_max_retries_check CHECK (
+					max_retries >= 0
+				)
+			);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DROP TABLE IF EXISTS notification_channel;
+		`)
+		return err
+	})
+}
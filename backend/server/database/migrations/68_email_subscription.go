@@ -0,0 +1,37 @@
+package dbmigs
+
+import "github.com/go-pg/migrations/v8"
+
+// This migration adds a table holding the users' subscriptions to the
+// email notifications. Each subscription specifies the address receiving
+// the notifications and the minimum level and object filters selecting
+// the notifications. The notifications are sent in digests.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE IF NOT EXISTS email_subscription (
+				id BIGSERIAL NOT NULL,
+				user_id INTEGER NOT NULL,
+				created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+				email TEXT NOT NULL,
+				enabled BOOLEAN NOT NULL DEFAULT TRUE,
+				level INTEGER NOT NULL DEFAULT 2,
+				app_ids BIGINT[],
+				daemon_ids BIGINT[],
+				subnet_ids BIGINT[],
+				CONSTRAINT email_subscription_pkey PRIMARY KEY (id),
+				CONSTRAINT email_subscription_user_id_fkey FOREIGN KEY (user_id)
+					REFERENCES system_user (id) MATCH SIMPLE
+					ON UPDATE CASCADE
+					ON DELETE CASCADE
+			);
+			CREATE INDEX IF NOT EXISTS email_subscription_user_id_idx ON email_subscription (user_id);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DROP TABLE IF EXISTS email_subscription;
+		`)
+		return err
+	})
+}
@@ -0,0 +1,67 @@
+package dbmigs
+
+import "github.com/go-pg/migrations/v8"
+
+// This migration adds the tables supporting the alerts raised when the
+// address or delegated prefix utilization crosses the thresholds. The
+// first table holds the rules overriding the global thresholds for the
+// selected subnets and shared networks. The second table holds the alerts
+// currently raised for the subnets and shared networks.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE IF NOT EXISTS utilization_alert_rule (
+				id BIGSERIAL NOT NULL,
+				subnet_id BIGINT,
+				shared_network_id BIGINT,
+				enabled BOOLEAN NOT NULL DEFAULT TRUE,
+				warning_threshold SMALLINT NOT NULL,
+				error_threshold SMALLINT NOT NULL,
+				CONSTRAINT utilization_alert_rule_pkey PRIMARY KEY (id),
+				CONSTRAINT utilization_alert_rule_subnet_id_key UNIQUE (subnet_id),
+				CONSTRAINT utilization_alert_rule_shared_network_id_key UNIQUE (shared_network_id),
+				CONSTRAINT utilization_alert_rule_subnet_id_fkey FOREIGN KEY (subnet_id)
+					REFERENCES subnet (id) MATCH SIMPLE
+					ON UPDATE CASCADE
+					ON DELETE CASCADE,
+				CONSTRAINT utilization_alert_rule_shared_network_id_fkey FOREIGN KEY (shared_network_id)
+					REFERENCES shared_network (id) MATCH SIMPLE
+					ON UPDATE CASCADE
+					ON DELETE CASCADE,
+				CONSTRAINT utilization_alert_rule_target_check CHECK ((subnet_id IS NULL) <> (shared_network_id IS NULL)),
+				CONSTRAINT utilization_alert_rule_warning_threshold_check CHECK (warning_threshold BETWEEN 0 AND 100),
+				CONSTRAINT utilization_alert_rule_error_threshold_check CHECK (error_threshold BETWEEN 0 AND 100)
+			);
+
+			CREATE TABLE IF NOT EXISTS utilization_alert (
+				id BIGSERIAL NOT NULL,
+				created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+				subnet_id BIGINT,
+				shared_network_id BIGINT,
+				kind TEXT NOT NULL,
+				level INTEGER NOT NULL,
+				utilization SMALLINT NOT NULL,
+				CONSTRAINT utilization_alert_pkey PRIMARY KEY (id),
+				CONSTRAINT utilization_alert_subnet_id_kind_key UNIQUE (subnet_id, kind),
+				CONSTRAINT utilization_alert_shared_network_id_kind_key UNIQUE (shared_network_id, kind),
+				CONSTRAINT utilization_alert_subnet_id_fkey FOREIGN KEY (subnet_id)
+					REFERENCES subnet (id) MATCH SIMPLE
+					ON UPDATE CASCADE
+					ON DELETE CASCADE,
+				CONSTRAINT utilization_alert_shared_network_id_fkey FOREIGN KEY (shared_network_id)
+					REFERENCES shared_network (id) MATCH SIMPLE
+					ON UPDATE CASCADE
+					ON DELETE CASCADE,
+				CONSTRAINT utilization_alert_target_check CHECK ((subnet_id IS NULL) <> (shared_network_id IS NULL)),
+				CONSTRAINT utilization_alert_kind_check CHECK (kind IN ('address', 'delegated-prefix'))
+			);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DROP TABLE IF EXISTS utilization_alert;
+			DROP TABLE IF EXISTS utilization_alert_rule;
+		`)
+		return err
+	})
+}
@@ -0,0 +1,50 @@
+package dbmigs
+
+import "github.com/go-pg/migrations/v8"
+
+// This migration adds a table holding the history of the address and
+// delegated prefix utilization of the subnets, shared networks and the
+// global utilization per family. The samples collected by the statistics
+// puller are downsampled to the fixed resolutions. Each row aggregates
+// the samples collected within a single period of a resolution.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE IF NOT EXISTS utilization_history (
+				id BIGSERIAL NOT NULL,
+				resolution INTEGER NOT NULL,
+				collected_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+				subnet_id BIGINT,
+				shared_network_id BIGINT,
+				family SMALLINT NOT NULL,
+				samples INTEGER NOT NULL,
+				addr_utilization_sum BIGINT NOT NULL,
+				addr_utilization_max SMALLINT NOT NULL,
+				pd_utilization_sum BIGINT NOT NULL,
+				pd_utilization_max SMALLINT NOT NULL,
+				CONSTRAINT utilization_history_pkey PRIMARY KEY (id),
+				CONSTRAINT utilization_history_subnet_id_fkey FOREIGN KEY (subnet_id)
+					REFERENCES subnet (id) MATCH SIMPLE
+					ON UPDATE CASCADE
+					ON DELETE CASCADE,
+				CONSTRAINT utilization_history_shared_network_id_fkey FOREIGN KEY (shared_network_id)
+					REFERENCES shared_network (id) MATCH SIMPLE
+					ON UPDATE CASCADE
+					ON DELETE CASCADE,
+				CONSTRAINT utilization_history_target_check CHECK (subnet_id IS NULL OR shared_network_id IS NULL),
+				CONSTRAINT utilization_history_family_check CHECK (family IN (4, 6))
+			);
+			CREATE UNIQUE INDEX IF NOT EXISTS utilization_history_period_idx ON utilization_history
+				(resolution, COALESCE(subnet_id, 0), COALESCE(shared_network_id, 0), family, collected_at);
+			CREATE INDEX IF NOT EXISTS utilization_history_collected_at_idx ON utilization_history (resolution, collected_at);
+			CREATE INDEX IF NOT EXISTS utilization_history_subnet_id_idx ON utilization_history (subnet_id, resolution, collected_at);
+			CREATE INDEX IF NOT EXISTS utilization_history_shared_network_id_idx ON utilization_history (shared_network_id, resolution, collected_at);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DROP TABLE IF EXISTS utilization_history;
+		`)
+		return err
+	})
+}
@@ -0,0 +1,36 @@
+package dbmigs
+
+import "github.com/go-pg/migrations/v8"
+
+// This migration adds the columns holding the forecast time of the address
+// and delegated prefix exhaustion of the subnets and shared networks. It
+// also allows the utilization alerts raised when the exhaustion is
+// forecast within the configured window.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE subnet ADD COLUMN IF NOT EXISTS addr_exhaustion_at TIMESTAMP WITHOUT TIME ZONE;
+			ALTER TABLE subnet ADD COLUMN IF NOT EXISTS pd_exhaustion_at TIMESTAMP WITHOUT TIME ZONE;
+			ALTER TABLE shared_network ADD COLUMN IF NOT EXISTS addr_exhaustion_at TIMESTAMP WITHOUT TIME ZONE;
+			ALTER TABLE shared_network ADD COLUMN IF NOT EXISTS pd_exhaustion_at TIMESTAMP WITHOUT TIME ZONE;
+
+			ALTER TABLE utilization_alert DROP CONSTRAINT IF EXISTS utilization_alert_kind_check;
+			ALTER TABLE utilization_alert ADD CONSTRAINT utilization_alert_kind_check
+				CHECK (kind IN ('address', 'delegated-prefix', 'address-exhaustion', 'delegated-prefix-exhaustion'));
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DELETE FROM utilization_alert WHERE kind IN ('address-exhaustion', 'delegated-prefix-exhaustion');
+			ALTER TABLE utilization_alert DROP CONSTRAINT IF EXISTS utilization_alert_kind_check;
+			ALTER TABLE utilization_alert ADD CONSTRAINT utilization_alert_kind_check
+				CHECK (kind IN ('address', 'delegated-prefix'));
+
+			ALTER TABLE shared_network DROP COLUMN IF EXISTS pd_exhaustion_at;
+			ALTER TABLE shared_network DROP COLUMN IF EXISTS addr_exhaustion_at;
+			ALTER TABLE subnet DROP COLUMN IF EXISTS pd_exhaustion_at;
+			ALTER TABLE subnet DROP COLUMN IF EXISTS addr_exhaustion_at;
+		`)
+		return err
+	})
+}
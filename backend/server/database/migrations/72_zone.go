@@ -0,0 +1,42 @@
+package dbmigs
+
+import "github.com/go-pg/migrations/v8"
+
+// This migration adds a table holding the DNS zones served by the BIND 9
+// daemons. The zones are associated with the daemons and views because
+// the same zone may be served by several daemons, e.g., a primary and its
+// secondaries, and in several views of the same daemon.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE IF NOT EXISTS zone (
+				id BIGSERIAL NOT NULL,
+				created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+				daemon_id BIGINT NOT NULL,
+				view TEXT NOT NULL,
+				name TEXT NOT NULL,
+				class TEXT NOT NULL,
+				zone_type TEXT NOT NULL,
+				serial BIGINT NOT NULL,
+				file TEXT,
+				dynamic BOOLEAN NOT NULL DEFAULT FALSE,
+				loaded_at TIMESTAMP WITHOUT TIME ZONE,
+				refresh_at TIMESTAMP WITHOUT TIME ZONE,
+				expires_at TIMESTAMP WITHOUT TIME ZONE,
+				CONSTRAINT zone_pkey PRIMARY KEY (id),
+				CONSTRAINT zone_daemon_id_view_name_class_key UNIQUE (daemon_id, view, name, class),
+				CONSTRAINT zone_daemon_id_fkey FOREIGN KEY (daemon_id)
+					REFERENCES daemon (id) MATCH SIMPLE
+					ON UPDATE CASCADE
+					ON DELETE CASCADE
+			);
+			CREATE INDEX IF NOT EXISTS zone_name_idx ON zone (name);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DROP TABLE IF EXISTS zone;
+		`)
+		return err
+	})
+}
@@ -0,0 +1,33 @@
+package dbmigs
+
+import "github.com/go-pg/migrations/v8"
+
+// This migration adds the replication status of the secondary zones. The
+// secondary zone refers to the primary zone it is compared with. The
+// reference is cleared when the primary zone is deleted.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE zone ADD COLUMN IF NOT EXISTS primary_zone_id BIGINT;
+			ALTER TABLE zone ADD COLUMN IF NOT EXISTS replication_status TEXT;
+			ALTER TABLE zone ADD COLUMN IF NOT EXISTS out_of_sync_since TIMESTAMP WITHOUT TIME ZONE;
+			ALTER TABLE zone ADD CONSTRAINT zone_primary_zone_id_fkey FOREIGN KEY (primary_zone_id)
+				REFERENCES zone (id) MATCH SIMPLE
+				ON UPDATE CASCADE
+				ON DELETE SET NULL;
+			ALTER TABLE zone ADD CONSTRAINT zone_replication_status_check CHECK (
+				replication_status IN ('in-sync', 'lagging', 'transfer-failed', 'unknown')
+			);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE zone DROP CONSTRAINT IF EXISTS zone_replication_status_check;
+			ALTER TABLE zone DROP CONSTRAINT IF EXISTS zone_primary_zone_id_fkey;
+			ALTER TABLE zone DROP COLUMN IF EXISTS out_of_sync_since;
+			ALTER TABLE zone DROP COLUMN IF EXISTS replication_status;
+			ALTER TABLE zone DROP COLUMN IF EXISTS primary_zone_id;
+		`)
+		return err
+	})
+}
@@ -0,0 +1,23 @@
+package dbmigs
+
+import "github.com/go-pg/migrations/v8"
+
+// This migration adds the parsed configuration of the BIND 9 daemons and
+// its hash. The configuration is reviewed by the config review checkers.
+// The secrets of the keys are hidden by the agent before sending the
+// configuration, so they are not stored in the database.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE bind9_daemon ADD COLUMN IF NOT EXISTS config JSONB;
+			ALTER TABLE bind9_daemon ADD COLUMN IF NOT EXISTS config_hash TEXT;
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE bind9_daemon DROP COLUMN IF EXISTS config_hash;
+			ALTER TABLE bind9_daemon DROP COLUMN IF EXISTS config;
+		`)
+		return err
+	})
+}
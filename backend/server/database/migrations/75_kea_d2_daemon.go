@@ -0,0 +1,30 @@
+package dbmigs
+
+import "github.com/go-pg/migrations/v8"
+
+// This migration adds the table holding the Kea D2 daemon-specific
+// information, i.e., the statistics of the name change requests and the
+// DNS updates sent by the daemon.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE IF NOT EXISTS kea_d2_daemon (
+				id BIGSERIAL NOT NULL,
+				kea_daemon_id BIGINT NOT NULL,
+				stats JSONB,
+				CONSTRAINT kea_d2_daemon_pkey PRIMARY KEY (id),
+				CONSTRAINT kea_d2_daemon_id_unique UNIQUE (kea_daemon_id),
+				CONSTRAINT kea_d2_daemon_id_fkey FOREIGN KEY (kea_daemon_id)
+					REFERENCES kea_daemon (id) MATCH SIMPLE
+					ON UPDATE CASCADE
+					ON DELETE CASCADE
+			);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DROP TABLE IF EXISTS kea_d2_daemon;
+		`)
+		return err
+	})
+}
@@ -0,0 +1,62 @@
+package dbmigs
+
+import "github.com/go-pg/migrations/v8"
+
+// This migration adds the columns holding the delegated prefix utilization
+// and its exhaustion forecast of the prefix pools. It also allows the
+// utilization history samples and the exhaustion alerts of the prefix
+// pools.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE prefix_pool ADD COLUMN IF NOT EXISTS pd_utilization SMALLINT;
+			ALTER TABLE prefix_pool ADD COLUMN IF NOT EXISTS stats_collected_at TIMESTAMP WITHOUT TIME ZONE;
+			ALTER TABLE prefix_pool ADD COLUMN IF NOT EXISTS pd_exhaustion_at TIMESTAMP WITHOUT TIME ZONE;
+
+			ALTER TABLE utilization_history ADD COLUMN IF NOT EXISTS prefix_pool_id BIGINT;
+			ALTER TABLE utilization_history ADD CONSTRAINT utilization_history_prefix_pool_id_fkey FOREIGN KEY (prefix_pool_id)
+				REFERENCES prefix_pool (id) MATCH SIMPLE
+				ON UPDATE CASCADE
+				ON DELETE CASCADE;
+			ALTER TABLE utilization_history DROP CONSTRAINT IF EXISTS utilization_history_target_check;
+			ALTER TABLE utilization_history ADD CONSTRAINT utilization_history_target_check
+				CHECK (num_nonnulls(subnet_id, shared_network_id, prefix_pool_id) <= 1);
+			DROP INDEX IF EXISTS utilization_history_period_idx;
+			CREATE UNIQUE INDEX IF NOT EXISTS utilization_history_period_idx ON utilization_history
+				(resolution, COALESCE(subnet_id, 0), COALESCE(shared_network_id, 0), COALESCE(prefix_pool_id, 0), family, collected_at);
+
+			ALTER TABLE utilization_alert ADD COLUMN IF NOT EXISTS prefix_pool_id BIGINT;
+			ALTER TABLE utilization_alert ADD CONSTRAINT utilization_alert_prefix_pool_id_kind_key UNIQUE (prefix_pool_id, kind);
+			ALTER TABLE utilization_alert ADD CONSTRAINT utilization_alert_prefix_pool_id_fkey FOREIGN KEY (prefix_pool_id)
+				REFERENCES prefix_pool (id) MATCH SIMPLE
+				ON UPDATE CASCADE
+				ON DELETE CASCADE;
+			ALTER TABLE utilization_alert DROP CONSTRAINT IF EXISTS utilization_alert_target_check;
+			ALTER TABLE utilization_alert ADD CONSTRAINT utilization_alert_target_check
+				CHECK (num_nonnulls(subnet_id, shared_network_id, prefix_pool_id) = 1);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DELETE FROM utilization_alert WHERE prefix_pool_id IS NOT NULL;
+			ALTER TABLE utilization_alert DROP CONSTRAINT IF EXISTS utilization_alert_target_check;
+			ALTER TABLE utilization_alert ADD CONSTRAINT utilization_alert_target_check
+				CHECK ((subnet_id IS NULL) <> (shared_network_id IS NULL));
+			ALTER TABLE utilization_alert DROP COLUMN IF EXISTS prefix_pool_id;
+
+			DELETE FROM utilization_history WHERE prefix_pool_id IS NOT NULL;
+			DROP INDEX IF EXISTS utilization_history_period_idx;
+			ALTER TABLE utilization_history DROP CONSTRAINT IF EXISTS utilization_history_target_check;
+			ALTER TABLE utilization_history ADD CONSTRAINT utilization_history_target_check
+				CHECK (subnet_id IS NULL OR shared_network_id IS NULL);
+			ALTER TABLE utilization_history DROP COLUMN IF EXISTS prefix_pool_id;
+			CREATE UNIQUE INDEX IF NOT EXISTS utilization_history_period_idx ON utilization_history
+				(resolution, COALESCE(subnet_id, 0), COALESCE(shared_network_id, 0), family, collected_at);
+
+			ALTER TABLE prefix_pool DROP COLUMN IF EXISTS pd_exhaustion_at;
+			ALTER TABLE prefix_pool DROP COLUMN IF EXISTS stats_collected_at;
+			ALTER TABLE prefix_pool DROP COLUMN IF EXISTS pd_utilization;
+		`)
+		return err
+	})
+}
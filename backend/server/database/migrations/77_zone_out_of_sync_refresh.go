@@ -0,0 +1,19 @@
+package dbmigs
+
+import "github.com/go-pg/migrations/v8"
+
+// This migration adds the time of the first refresh of the secondary zone
+// scheduled after it went out of sync with its primary zone.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE zone ADD COLUMN IF NOT EXISTS out_of_sync_refresh_at TIMESTAMP WITHOUT TIME ZONE;
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE zone DROP COLUMN IF EXISTS out_of_sync_refresh_at;
+		`)
+		return err
+	})
+}
@@ -0,0 +1,139 @@
+package dbmodel
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	pkgerrors "github.com/pkg/errors"
+	dbops "isc.org/stork/server/database"
+	storkutil "isc.org/stork/util"
+)
+
+// The prefix of the generated API tokens. It makes the tokens easy to
+// recognize, e.g., by the secret scanners.
+const apiTokenPrefix = "stork_"
+
+// The number of random bytes in the generated API tokens.
+const apiTokenRandomLength = 32
+
+// Represents a personal API token of a user. The token allows for accessing
+// the REST API on behalf of the user without logging in. The token value is
+// returned to the user only once, when the token is created. The database
+// holds only the hash of the token.
+type APIToken struct {
+	ID         int64
+	UserID     int
+	User       *SystemUser `pg:"rel:has-one"`
+	Name       string
+	TokenHash  string `json:"-"`
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+}
+
+// Returns the hash of the API token stored in the database. The tokens are
+// long random strings, so the fast hash function is sufficient.
+func HashAPIToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// Generates a new random API token for the user. It returns the token
+// instance to be stored in the database and the token value to be
+// returned to the user. The zero value of the expiresAt means that the
+// token never expires.
+func NewAPIToken(userID int, name string, expiresAt time.Time) (*APIToken, string, error) {
+	random, err := storkutil.Base64Random(apiTokenRandomLength)
+	if err != nil {
+		return nil, "", pkgerrors.Wrap(err, "problem generating API token")
+	}
+	token := apiTokenPrefix + random
+	return &APIToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: HashAPIToken(token),
+		ExpiresAt: expiresAt,
+	}, token, nil
+}
+
+// Checks if the token is expired at the specified time.
+func (t *APIToken) IsExpired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !now.Before(t.ExpiresAt)
+}
+
+// Inserts the API token into the database. The returned conflict value
+// indicates if the user already has a token with the same name.
+func AddAPIToken(dbi dbops.DBI, token *APIToken) (conflict bool, err error) {
+	_, err = dbi.Model(token).Insert()
+	if err != nil {
+		var pgError pg.Error
+		if errors.As(err, &pgError) {
+			conflict = pgError.IntegrityViolation()
+		}
+		err = pkgerrors.Wrapf(err, "problem inserting API token %s for user %d", token.Name, token.UserID)
+	}
+	return
+}
+
+// Fetches all API tokens of the user ordered by ID.
+func GetAPITokensByUserID(dbi dbops.DBI, userID int) ([]APIToken, error) {
+	var tokens []APIToken
+	err := dbi.Model(&tokens).
+		Where("user_id = ?", userID).
+		OrderExpr("id ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrapf(err, "problem fetching API tokens of user %d", userID)
+	}
+	return tokens, nil
+}
+
+// Fetches the API token matching the specified token value along with
+// the token owner and its groups. It returns nil if there is no such token.
+func GetAPITokenByValue(dbi dbops.DBI, token string) (*APIToken, error) {
+	apiToken := &APIToken{}
+	err := dbi.Model(apiToken).
+		Where("token_hash = ?", HashAPIToken(token)).
+		Select()
+	if errors.Is(err, pg.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, pkgerrors.Wrap(err, "problem fetching API token")
+	}
+	apiToken.User = &SystemUser{ID: apiToken.UserID}
+	err = dbi.Model(apiToken.User).
+		Relation("Groups").
+		WherePK().
+		Select()
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "problem fetching groups of user %d", apiToken.UserID)
+	}
+	return apiToken, nil
+}
+
+// Sets the time when the API token was last used.
+func UpdateAPITokenLastUsed(dbi dbops.DBI, id int64, lastUsedAt time.Time) error {
+	_, err := dbi.Model(&APIToken{ID: id, LastUsedAt: lastUsedAt}).
+		Column("last_used_at").
+		WherePK().
+		Update()
+	return pkgerrors.Wrapf(err, "problem updating last use time of API token %d", id)
+}
+
+// Deletes (revokes) the API token of the user. It returns an error
+// wrapping ErrNotExists if the user has no such token.
+func DeleteAPIToken(dbi dbops.DBI, userID int, id int64) error {
+	result, err := dbi.Model(&APIToken{}).
+		Where("id = ?", id).
+		Where("user_id = ?", userID).
+		Delete()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem deleting API token %d of user %d", id, userID)
+	} else if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "API token %d of user %d does not exist", id, userID)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package dbmodel
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	dbtest "isc.org/stork/server/database/test"
+)
+
+// Test that the generated API tokens are random and only their hashes
+// are kept in the token instances.
+func TestNewAPIToken(t *testing.T) {
+	token1, value1, err := NewAPIToken(1, "foo", time.Time{})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(value1, "stork_"))
+	require.Equal(t, HashAPIToken(value1), token1.TokenHash)
+	require.NotContains(t, token1.TokenHash, value1)
+	require.Equal(t, 1, token1.UserID)
+	require.Equal(t, "foo", token1.Name)
+
+	token2, value2, err := NewAPIToken(1, "foo", time.Time{})
+	require.NoError(t, err)
+	require.NotEqual(t, value1, value2)
+	require.NotEqual(t, token1.TokenHash, token2.TokenHash)
+}
+
+// Test the API token expiration check.
+func TestAPITokenIsExpired(t *testing.T) {
+	now := time.Now()
+	require.False(t, (&APIToken{}).IsExpired(now))
+	require.False(t, (&APIToken{ExpiresAt: now.Add(time.Minute)}).IsExpired(now))
+	require.True(t, (&APIToken{ExpiresAt: now}).IsExpired(now))
+	require.True(t, (&APIToken{ExpiresAt: now.Add(-time.Minute)}).IsExpired(now))
+}
+
+// Test that the API tokens can be added, fetched and deleted.
+func TestAddGetDeleteAPIToken(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	expiresAt := time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC)
+	token, value, err := NewAPIToken(1, "ipam-sync", expiresAt)
+	require.NoError(t, err)
+	conflict, err := AddAPIToken(db, token)
+	require.NoError(t, err)
+	require.False(t, conflict)
+	require.NotZero(t, token.ID)
+	require.False(t, token.CreatedAt.IsZero())
+
+	// The token name must be unique for the user.
+	duplicate, _, err := NewAPIToken(1, "ipam-sync", time.Time{})
+	require.NoError(t, err)
+	conflict, err = AddAPIToken(db, duplicate)
+	require.Error(t, err)
+	require.True(t, conflict)
+
+	// The token can be found by its value.
+	returned, err := GetAPITokenByValue(db, value)
+	require.NoError(t, err)
+	require.NotNil(t, returned)
+	require.Equal(t, token.ID, returned.ID)
+	require.Equal(t, expiresAt, returned.ExpiresAt)
+	require.NotNil(t, returned.User)
+	require.Equal(t, "admin", returned.User.Login)
+	require.True(t, returned.User.InGroup(&SystemGroup{ID: SuperAdminGroupID}))
+
+	returned, err = GetAPITokenByValue(db, "stork_unknown")
+	require.NoError(t, err)
+	require.Nil(t, returned)
+
+	// Record the token use.
+	lastUsedAt := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	require.NoError(t, UpdateAPITokenLastUsed(db, token.ID, lastUsedAt))
+
+	tokens, err := GetAPITokensByUserID(db, 1)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	require.Equal(t, lastUsedAt, tokens[0].LastUsedAt)
+
+	// Another user cannot delete the token.
+	require.ErrorIs(t, DeleteAPIToken(db, 2, token.ID), ErrNotExists)
+
+	require.NoError(t, DeleteAPIToken(db, 1, token.ID))
+	tokens, err = GetAPITokensByUserID(db, 1)
+	require.NoError(t, err)
+	require.Empty(t, tokens)
+	require.ErrorIs(t, DeleteAPIToken(db, 1, token.ID), ErrNotExists)
+}
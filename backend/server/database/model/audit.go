@@ -0,0 +1,156 @@
+package dbmodel
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	pkgerrors "github.com/pkg/errors"
+)
+
+// Describes a single command sent to a daemon while applying a configuration
+// change, and its outcome.
+type AuditCommand struct {
+	// ID of the app the command was sent to.
+	AppID int64 `json:"appId"`
+	// Name of the app the command was sent to.
+	AppName string `json:"appName"`
+	// Sent command.
+	Command json.RawMessage `json:"command"`
+	// Result code returned by the daemon or -1 if the command could not
+	// be delivered.
+	Result int `json:"result"`
+	// Text returned by the daemon or an error message.
+	Text string `json:"text,omitempty"`
+}
+
+// Represents an entry of the audit trail held in the audit_entry table.
+// An entry is created for each configuration change committed by Stork,
+// regardless of whether it succeeded or not.
+type AuditEntry struct {
+	ID        int64
+	CreatedAt time.Time
+	// ID of the user who made the change. It is zero if the user has
+	// been deleted.
+	UserID int64
+	// Login (or email if the login is empty) of the user at the time
+	// of the change.
+	UserLogin string
+	// Type of the configured app, e.g. "kea".
+	Target string
+	// Performed operation, e.g. "host_add".
+	Operation string
+	// Type of the modified entity, e.g. "host".
+	EntityType string
+	// ID of the modified entity. It is zero if the entity was not
+	// created due to an error.
+	EntityID int64
+	// IDs of the daemons affected by the change.
+	DaemonIDs []int64 `pg:",array"`
+	// Entity before the change. It is nil for the added entities.
+	EntityBefore json.RawMessage
+	// Entity after the change. It is nil for the deleted entities.
+	EntityAfter json.RawMessage
+	// Commands sent to the daemons and their results.
+	Commands []AuditCommand
+	// Indicates if the change was scheduled rather than committed
+	// instantly.
+	Scheduled bool
+	// Error message if the change failed.
+	Error string
+}
+
+// A structure containing the filters for selecting the audit entries.
+// A nil value of a filter means that it is not applied.
+type AuditEntriesByPageFilters struct {
+	UserID     *int64
+	DaemonID   *int64
+	EntityType *string
+	EntityID   *int64
+	Operation  *string
+	// Selects the entries created at or after this time.
+	From *time.Time
+	// Selects the entries created before this time.
+	To *time.Time
+}
+
+// Adds an audit entry to the database.
+func AddAuditEntry(dbi pg.DBI, entry *AuditEntry) error {
+	_, err := dbi.Model(entry).Insert()
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "problem inserting audit entry for operation %s", entry.Operation)
+	}
+	return err
+}
+
+// Applies the filters to the audit entries query.
+func applyAuditEntriesFilters(q *orm.Query, filters *AuditEntriesByPageFilters) *orm.Query {
+	if filters == nil {
+		return q
+	}
+	if filters.UserID != nil {
+		q = q.Where("audit_entry.user_id = ?", *filters.UserID)
+	}
+	if filters.DaemonID != nil {
+		q = q.Where("? = ANY(audit_entry.daemon_ids)", *filters.DaemonID)
+	}
+	if filters.EntityType != nil {
+		q = q.Where("audit_entry.entity_type = ?", *filters.EntityType)
+	}
+	if filters.EntityID != nil {
+		q = q.Where("audit_entry.entity_id = ?", *filters.EntityID)
+	}
+	if filters.Operation != nil {
+		q = q.Where("audit_entry.operation = ?", *filters.Operation)
+	}
+	if filters.From != nil {
+		q = q.Where("audit_entry.created_at >= ?", *filters.From)
+	}
+	if filters.To != nil {
+		q = q.Where("audit_entry.created_at < ?", *filters.To)
+	}
+	return q
+}
+
+// Fetches a collection of the audit entries from the database. The offset
+// and limit specify the beginning of the page and the maximum size of the
+// page. Limit has to be greater than 0, otherwise an error is returned.
+// The entries are sorted by the creation time in the specified direction.
+// It returns the entries and the total number of entries matching the
+// filters.
+func GetAuditEntriesByPage(db pg.DBI, offset, limit int64, filters *AuditEntriesByPageFilters, sortDir SortDirEnum) ([]AuditEntry, int64, error) {
+	if limit == 0 {
+		return nil, 0, pkgerrors.New("limit should be greater than 0")
+	}
+	entries := []AuditEntry{}
+	q := db.Model(&entries)
+	q = applyAuditEntriesFilters(q, filters)
+	q = q.OrderExpr(prepareOrderExpr("audit_entry", "created_at", sortDir)).
+		OrderExpr(prepareOrderExpr("audit_entry", "id", sortDir)).
+		Offset(int(offset)).
+		Limit(int(limit))
+
+	total, err := q.SelectAndCount()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return []AuditEntry{}, 0, nil
+		}
+		return nil, 0, pkgerrors.Wrapf(err, "problem getting audit entries")
+	}
+	return entries, int64(total), nil
+}
+
+// Fetches all audit entries matching the filters, sorted from the oldest
+// to the newest one.
+func GetAuditEntries(db pg.DBI, filters *AuditEntriesByPageFilters) ([]AuditEntry, error) {
+	entries := []AuditEntry{}
+	q := db.Model(&entries)
+	q = applyAuditEntriesFilters(q, filters)
+	err := q.OrderExpr("audit_entry.created_at ASC, audit_entry.id ASC").Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrapf(err, "problem getting audit entries")
+	}
+	return entries, nil
+}
@@ -0,0 +1,176 @@
+package dbmodel
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/stretchr/testify/require"
+	dbtest "isc.org/stork/server/database/test"
+	storkutil "isc.org/stork/util"
+)
+
+// Adds the audit entries used in the tests.
+func addTestAuditEntries(t *testing.T, db *pg.DB) *SystemUser {
+	user := &SystemUser{
+		Login:    "auditor",
+		Lastname: "Doe",
+		Name:     "John",
+	}
+	_, err := CreateUser(db, user)
+	require.NoError(t, err)
+
+	entries := []AuditEntry{
+		{
+			CreatedAt:   time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC),
+			UserID:      int64(user.ID),
+			UserLogin:   user.Login,
+			Target:      "kea",
+			Operation:   "host_add",
+			EntityType:  "host",
+			EntityID:    1,
+			DaemonIDs:   []int64{1, 2},
+			EntityAfter: json.RawMessage(`{"ID": 1}`),
+			Commands: []AuditCommand{
+				{
+					AppID:   1,
+					AppName: "kea@192.0.2.1",
+					Command: json.RawMessage(`{"command": "reservation-add"}`),
+					Result:  0,
+					Text:    "Host added.",
+				},
+			},
+		},
+		{
+			CreatedAt:    time.Date(2024, 3, 12, 10, 0, 0, 0, time.UTC),
+			UserID:       int64(user.ID),
+			UserLogin:    user.Login,
+			Target:       "kea",
+			Operation:    "host_delete",
+			EntityType:   "host",
+			EntityID:     1,
+			DaemonIDs:    []int64{2},
+			EntityBefore: json.RawMessage(`{"ID": 1}`),
+			Error:        "reservation-del command failed",
+		},
+		{
+			CreatedAt:  time.Date(2024, 3, 13, 10, 0, 0, 0, time.UTC),
+			Target:     "kea",
+			Operation:  "subnet_update",
+			EntityType: "subnet",
+			EntityID:   4,
+			DaemonIDs:  []int64{3},
+			Scheduled:  true,
+		},
+	}
+	for i := range entries {
+		require.NoError(t, AddAuditEntry(db, &entries[i]))
+		require.NotZero(t, entries[i].ID)
+	}
+	return user
+}
+
+// Test that the audit entries are inserted and fetched with the
+// filters applied.
+func TestGetAuditEntriesByPage(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	user := addTestAuditEntries(t, db)
+
+	// Get all entries, the newest first.
+	entries, total, err := GetAuditEntriesByPage(db, 0, 10, nil, SortDirDesc)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, total)
+	require.Len(t, entries, 3)
+	require.Equal(t, "subnet_update", entries[0].Operation)
+	require.Zero(t, entries[0].UserID)
+	require.True(t, entries[0].Scheduled)
+	require.Equal(t, "host_add", entries[2].Operation)
+	require.Equal(t, user.Login, entries[2].UserLogin)
+	require.Equal(t, []int64{1, 2}, entries[2].DaemonIDs)
+	require.JSONEq(t, `{"ID": 1}`, string(entries[2].EntityAfter))
+	require.Nil(t, entries[2].EntityBefore)
+	require.Len(t, entries[2].Commands, 1)
+	require.Equal(t, "Host added.", entries[2].Commands[0].Text)
+
+	// Paging.
+	entries, total, err = GetAuditEntriesByPage(db, 1, 1, nil, SortDirAsc)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, total)
+	require.Len(t, entries, 1)
+	require.Equal(t, "host_delete", entries[0].Operation)
+	require.Equal(t, "reservation-del command failed", entries[0].Error)
+
+	// Filter by user.
+	entries, total, err = GetAuditEntriesByPage(db, 0, 10, &AuditEntriesByPageFilters{
+		UserID: storkutil.Ptr(int64(user.ID)),
+	}, SortDirAsc)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	require.Len(t, entries, 2)
+
+	// Filter by daemon.
+	entries, total, err = GetAuditEntriesByPage(db, 0, 10, &AuditEntriesByPageFilters{
+		DaemonID: storkutil.Ptr(int64(2)),
+	}, SortDirAsc)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	require.Len(t, entries, 2)
+
+	// Filter by entity.
+	entries, total, err = GetAuditEntriesByPage(db, 0, 10, &AuditEntriesByPageFilters{
+		EntityType: storkutil.Ptr("host"),
+		EntityID:   storkutil.Ptr(int64(1)),
+		Operation:  storkutil.Ptr("host_delete"),
+	}, SortDirAsc)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Equal(t, "host_delete", entries[0].Operation)
+
+	// Filter by time range.
+	entries, total, err = GetAuditEntriesByPage(db, 0, 10, &AuditEntriesByPageFilters{
+		From: storkutil.Ptr(time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC)),
+		To:   storkutil.Ptr(time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC)),
+	}, SortDirAsc)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Equal(t, "host_delete", entries[0].Operation)
+
+	// Limit must be positive.
+	_, _, err = GetAuditEntriesByPage(db, 0, 0, nil, SortDirAsc)
+	require.Error(t, err)
+}
+
+// Test that all audit entries matching the filters are returned from the
+// oldest to the newest.
+func TestGetAuditEntries(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	addTestAuditEntries(t, db)
+
+	entries, err := GetAuditEntries(db, &AuditEntriesByPageFilters{
+		EntityType: storkutil.Ptr("host"),
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "host_add", entries[0].Operation)
+	require.Equal(t, "host_delete", entries[1].Operation)
+}
+
+// Test that the audit entries are preserved when the user is deleted.
+func TestAuditEntryUserDeleted(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	user := addTestAuditEntries(t, db)
+	require.NoError(t, DeleteUser(db, user))
+
+	entries, err := GetAuditEntries(db, nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Zero(t, entries[0].UserID)
+	require.Equal(t, "auditor", entries[0].UserLogin)
+}
@@ -0,0 +1,199 @@
+package dbmodel
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	pkgerrors "github.com/pkg/errors"
+	keaconfig "isc.org/stork/appcfg/kea"
+	dbops "isc.org/stork/server/database"
+)
+
+// A structure reflecting the client_class SQL table. It holds a client
+// class configured in a Kea DHCP server. The classes are associated with
+// the daemons rather than shared between them because the same class may
+// be defined differently in different servers.
+type ClientClass struct {
+	ID        int64
+	CreatedAt time.Time
+	DaemonID  int64
+	Daemon    *Daemon `pg:"rel:has-one"`
+	Name      string
+	// Position of the class in the daemon's configuration. The order of
+	// the classes is significant because a class may only depend on the
+	// classes defined before it.
+	Position int `pg:",use_zero"`
+
+	KeaParameters *keaconfig.ClientClass
+}
+
+// A structure containing the filters for selecting the client classes.
+// A nil value of a filter means that it is not applied.
+type ClientClassesByPageFilters struct {
+	AppID    *int64
+	DaemonID *int64
+	// Matches the class name or the test expression.
+	Text *string
+}
+
+// Adds a client class to the database. The class is appended at the end
+// of the daemon's classes, i.e., in the same place where the class-add
+// command puts it in the Kea configuration.
+func AddClientClass(dbi dbops.DBI, class *ClientClass) error {
+	count, err := dbi.Model((*ClientClass)(nil)).
+		Where("daemon_id = ?", class.DaemonID).
+		Count()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem counting client classes of the daemon with ID %d", class.DaemonID)
+	}
+	class.Position = count
+	_, err = dbi.Model(class).Insert()
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "problem inserting client class %s for the daemon with ID %d", class.Name, class.DaemonID)
+	}
+	return err
+}
+
+// Updates the Kea parameters of the client class in the database. The
+// name, daemon and position of the class are not updated.
+func UpdateClientClass(dbi dbops.DBI, class *ClientClass) error {
+	result, err := dbi.Model(class).
+		Column("kea_parameters").
+		WherePK().
+		Update()
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "problem updating the client class with ID %d", class.ID)
+	} else if result.RowsAffected() <= 0 {
+		err = pkgerrors.Wrapf(ErrNotExists, "client class with ID %d does not exist", class.ID)
+	}
+	return err
+}
+
+// Deletes the client class from the database.
+func DeleteClientClass(dbi dbops.DBI, classID int64) error {
+	class := &ClientClass{
+		ID: classID,
+	}
+	result, err := dbi.Model(class).WherePK().Delete()
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "problem deleting the client class with ID %d", classID)
+	} else if result.RowsAffected() <= 0 {
+		err = pkgerrors.Wrapf(ErrNotExists, "client class with ID %d does not exist", classID)
+	}
+	return err
+}
+
+// Fetches the client class by ID. It includes the daemon and the app the
+// class belongs to. It returns nil if the class does not exist.
+func GetClientClass(dbi dbops.DBI, classID int64) (*ClientClass, error) {
+	class := &ClientClass{}
+	err := dbi.Model(class).
+		Relation("Daemon.KeaDaemon").
+		Relation("Daemon.App.AccessPoints").
+		Relation("Daemon.App.Machine").
+		Where("client_class.id = ?", classID).
+		Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, pkgerrors.Wrapf(err, "problem getting the client class with ID %d", classID)
+	}
+	return class, nil
+}
+
+// Fetches the client classes of the daemon in the configuration order.
+func GetClientClassesByDaemonID(dbi dbops.DBI, daemonID int64) ([]ClientClass, error) {
+	classes := []ClientClass{}
+	err := dbi.Model(&classes).
+		Where("client_class.daemon_id = ?", daemonID).
+		OrderExpr("client_class.position ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrapf(err, "problem getting client classes of the daemon with ID %d", daemonID)
+	}
+	return classes, nil
+}
+
+// Fetches a collection of the client classes from the database. The offset
+// and limit specify the beginning of the page and the maximum size of the
+// page. The sortField allows indicating the sort column and the sortDir
+// allows selecting the order of sorting. If the sortField is empty, the
+// classes are sorted by daemon and their position in the configuration.
+// It returns the classes and the total number of classes matching the
+// filters.
+func GetClientClassesByPage(dbi dbops.DBI, offset, limit int64, filters *ClientClassesByPageFilters, sortField string, sortDir SortDirEnum) ([]ClientClass, int64, error) {
+	classes := []ClientClass{}
+	q := dbi.Model(&classes).
+		Relation("Daemon.App.AccessPoints").
+		Relation("Daemon.App.Machine")
+
+	if filters != nil {
+		if filters.AppID != nil {
+			q = q.Where("daemon.app_id = ?", *filters.AppID)
+		}
+		if filters.DaemonID != nil {
+			q = q.Where("client_class.daemon_id = ?", *filters.DaemonID)
+		}
+		if filters.Text != nil {
+			q = q.WhereGroup(func(q *orm.Query) (*orm.Query, error) {
+				q = q.WhereOr("client_class.name ILIKE ?", "%"+*filters.Text+"%").
+					WhereOr("client_class.kea_parameters->>'test' ILIKE ?", "%"+*filters.Text+"%")
+				return q, nil
+			})
+		}
+	}
+
+	if sortField == "" {
+		q = q.OrderExpr(prepareOrderExpr("client_class", "daemon_id", sortDir)).
+			OrderExpr(prepareOrderExpr("client_class", "position", sortDir))
+	} else {
+		q = q.OrderExpr(prepareOrderExpr("client_class", sortField, sortDir))
+	}
+	q = q.Offset(int(offset)).Limit(int(limit))
+
+	total, err := q.SelectAndCount()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return []ClientClass{}, 0, nil
+		}
+		return nil, 0, pkgerrors.Wrapf(err, "problem getting client classes by page")
+	}
+	return classes, int64(total), nil
+}
+
+// Replaces the client classes of the daemon with the classes fetched from
+// its configuration. The existing classes are updated, the new classes are
+// inserted, and the classes no longer present in the configuration are
+// deleted. Updating rather than re-creating the classes preserves their
+// IDs, so they remain valid in the REST API and the audit trail.
+func CommitClientClassesIntoDB(dbi dbops.DBI, daemonID int64, classes []keaconfig.ClientClass) error {
+	names := []string{}
+	for i := range classes {
+		class := &ClientClass{
+			DaemonID:      daemonID,
+			Name:          classes[i].Name,
+			Position:      i,
+			KeaParameters: &classes[i],
+		}
+		_, err := dbi.Model(class).
+			OnConflict("(daemon_id, name) DO UPDATE").
+			Set("position = EXCLUDED.position").
+			Set("kea_parameters = EXCLUDED.kea_parameters").
+			Insert()
+		if err != nil {
+			return pkgerrors.Wrapf(err, "problem upserting client class %s for the daemon with ID %d", class.Name, daemonID)
+		}
+		names = append(names, class.Name)
+	}
+	q := dbi.Model((*ClientClass)(nil)).Where("daemon_id = ?", daemonID)
+	if len(names) > 0 {
+		q = q.Where("name NOT IN (?)", pg.In(names))
+	}
+	if _, err := q.Delete(); err != nil {
+		return pkgerrors.Wrapf(err, "problem deleting stale client classes of the daemon with ID %d", daemonID)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package dbmodel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	keaconfig "isc.org/stork/appcfg/kea"
+	dbtest "isc.org/stork/server/database/test"
+	storkutil "isc.org/stork/util"
+)
+
+// Test that the client classes of the daemon are inserted, updated and
+// deleted when committing the classes from the configuration.
+func TestCommitClientClassesIntoDB(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	apps := addTestSubnetApps(t, db)
+	daemonID := apps[0].Daemons[0].ID
+
+	// Add the classes.
+	err := CommitClientClassesIntoDB(db, daemonID, []keaconfig.ClientClass{
+		{Name: "foo", Test: "member('ALL')"},
+		{Name: "bar"},
+	})
+	require.NoError(t, err)
+
+	classes, err := GetClientClassesByDaemonID(db, daemonID)
+	require.NoError(t, err)
+	require.Len(t, classes, 2)
+	require.Equal(t, "foo", classes[0].Name)
+	require.Zero(t, classes[0].Position)
+	require.Equal(t, "member('ALL')", classes[0].KeaParameters.Test)
+	require.Equal(t, "bar", classes[1].Name)
+	require.EqualValues(t, 1, classes[1].Position)
+	fooID := classes[0].ID
+
+	// Replace the classes. The foo class should be updated rather than
+	// re-created.
+	err = CommitClientClassesIntoDB(db, daemonID, []keaconfig.ClientClass{
+		{Name: "baz"},
+		{Name: "foo", Test: "member('KNOWN')"},
+	})
+	require.NoError(t, err)
+
+	classes, err = GetClientClassesByDaemonID(db, daemonID)
+	require.NoError(t, err)
+	require.Len(t, classes, 2)
+	require.Equal(t, "baz", classes[0].Name)
+	require.Equal(t, "foo", classes[1].Name)
+	require.Equal(t, fooID, classes[1].ID)
+	require.EqualValues(t, 1, classes[1].Position)
+	require.Equal(t, "member('KNOWN')", classes[1].KeaParameters.Test)
+
+	// Remove all classes.
+	require.NoError(t, CommitClientClassesIntoDB(db, daemonID, nil))
+	classes, err = GetClientClassesByDaemonID(db, daemonID)
+	require.NoError(t, err)
+	require.Empty(t, classes)
+}
+
+// Test adding, getting, updating and deleting a client class.
+func TestAddUpdateDeleteClientClass(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	apps := addTestSubnetApps(t, db)
+	daemonID := apps[0].Daemons[0].ID
+
+	err := CommitClientClassesIntoDB(db, daemonID, []keaconfig.ClientClass{
+		{Name: "foo"},
+	})
+	require.NoError(t, err)
+
+	// The new class is appended at the end.
+	class := &ClientClass{
+		DaemonID: daemonID,
+		Name:     "bar",
+		KeaParameters: &keaconfig.ClientClass{
+			Name:       "bar",
+			NextServer: "192.0.2.1",
+			Unrecognized: map[string]any{
+				"option-def": []any{},
+			},
+		},
+	}
+	require.NoError(t, AddClientClass(db, class))
+	require.NotZero(t, class.ID)
+	require.EqualValues(t, 1, class.Position)
+
+	returned, err := GetClientClass(db, class.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returned)
+	require.Equal(t, "bar", returned.Name)
+	require.NotNil(t, returned.Daemon)
+	require.NotNil(t, returned.Daemon.KeaDaemon)
+	require.NotNil(t, returned.Daemon.App)
+	require.Equal(t, "192.0.2.1", returned.KeaParameters.NextServer)
+	require.Contains(t, returned.KeaParameters.Unrecognized, "option-def")
+
+	// Update the class.
+	returned.KeaParameters.NextServer = "192.0.2.2"
+	require.NoError(t, UpdateClientClass(db, returned))
+	returned, err = GetClientClass(db, class.ID)
+	require.NoError(t, err)
+	require.Equal(t, "192.0.2.2", returned.KeaParameters.NextServer)
+
+	// Delete the class.
+	require.NoError(t, DeleteClientClass(db, class.ID))
+	returned, err = GetClientClass(db, class.ID)
+	require.NoError(t, err)
+	require.Nil(t, returned)
+
+	// Deleting or updating a non-existing class should fail.
+	require.ErrorIs(t, DeleteClientClass(db, class.ID), ErrNotExists)
+	require.ErrorIs(t, UpdateClientClass(db, class), ErrNotExists)
+}
+
+// Test getting the client classes by page with filtering.
+func TestGetClientClassesByPage(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	apps := addTestSubnetApps(t, db)
+	for i, app := range apps {
+		err := CommitClientClassesIntoDB(db, app.Daemons[0].ID, []keaconfig.ClientClass{
+			{Name: "foo", Test: "member('ALL')"},
+			{Name: "bar", OnlyIfRequired: storkutil.Ptr(i == 0)},
+		})
+		require.NoError(t, err)
+	}
+
+	// All classes.
+	classes, total, err := GetClientClassesByPage(db, 0, 10, nil, "", SortDirAsc)
+	require.NoError(t, err)
+	require.EqualValues(t, 4, total)
+	require.Len(t, classes, 4)
+	require.Equal(t, "foo", classes[0].Name)
+	require.Equal(t, "bar", classes[1].Name)
+	require.NotNil(t, classes[0].Daemon)
+	require.NotNil(t, classes[0].Daemon.App)
+
+	// Paging.
+	classes, total, err = GetClientClassesByPage(db, 3, 2, nil, "name", SortDirAsc)
+	require.NoError(t, err)
+	require.EqualValues(t, 4, total)
+	require.Len(t, classes, 1)
+	require.Equal(t, "foo", classes[0].Name)
+
+	// Filter by app.
+	classes, total, err = GetClientClassesByPage(db, 0, 10, &ClientClassesByPageFilters{
+		AppID: storkutil.Ptr(apps[1].ID),
+	}, "", SortDirAsc)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	require.Equal(t, apps[1].Daemons[0].ID, classes[0].DaemonID)
+
+	// Filter by daemon.
+	classes, total, err = GetClientClassesByPage(db, 0, 10, &ClientClassesByPageFilters{
+		DaemonID: storkutil.Ptr(apps[0].Daemons[0].ID),
+	}, "", SortDirAsc)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	require.Equal(t, apps[0].Daemons[0].ID, classes[0].DaemonID)
+
+	// Filter by the test expression.
+	classes, total, err = GetClientClassesByPage(db, 0, 10, &ClientClassesByPageFilters{
+		Text: storkutil.Ptr("all"),
+	}, "", SortDirAsc)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	require.Equal(t, "foo", classes[0].Name)
+	require.Equal(t, "foo", classes[1].Name)
+}
+
+// Test that the client classes are deleted together with the daemon.
+func TestDeleteDaemonWithClientClasses(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	apps := addTestSubnetApps(t, db)
+	daemonID := apps[0].Daemons[0].ID
+	err := CommitClientClassesIntoDB(db, daemonID, []keaconfig.ClientClass{
+		{Name: "foo"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, DeleteApp(db, apps[0]))
+
+	classes, err := GetClientClassesByDaemonID(db, daemonID)
+	require.NoError(t, err)
+	require.Empty(t, classes)
+}
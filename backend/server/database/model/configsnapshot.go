@@ -0,0 +1,117 @@
+package dbmodel
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	pkgerrors "github.com/pkg/errors"
+	dbops "isc.org/stork/server/database"
+)
+
+// Indicates how the configuration snapshot was created.
+type KeaConfigSnapshotSource string
+
+// Supported sources of the configuration snapshots.
+const (
+	// The snapshot holds a changed configuration fetched from the daemon.
+	KeaConfigSnapshotSourceFetched KeaConfigSnapshotSource = "fetched"
+	// The snapshot holds the configuration restored by the rollback.
+	KeaConfigSnapshotSourceRollback KeaConfigSnapshotSource = "rollback"
+)
+
+// A structure reflecting the kea_config_snapshot SQL table. It holds a
+// version of the Kea daemon's configuration. The user and the operation
+// describe who or what changed the configuration. They are empty for the
+// fetched configurations changed outside of Stork.
+type KeaConfigSnapshot struct {
+	ID         int64
+	CreatedAt  time.Time
+	DaemonID   int64
+	Config     *KeaConfig
+	ConfigHash string
+	Source     KeaConfigSnapshotSource
+	// ID of the user who changed the configuration. It is zero if the
+	// user is unknown or has been deleted.
+	UserID int64
+	// Login (or email if the login is empty) of the user at the time
+	// of the change.
+	UserLogin string
+	// ID of the audit entry describing the change made through Stork.
+	// It is zero if the change was made outside of Stork or the entry
+	// has been deleted.
+	AuditEntryID int64
+	// Operation which changed the configuration, e.g. "host_add".
+	Operation string
+	// Free form description of the change.
+	Description string
+}
+
+// Adds a configuration snapshot to the database.
+func AddKeaConfigSnapshot(dbi dbops.DBI, snapshot *KeaConfigSnapshot) error {
+	_, err := dbi.Model(snapshot).Insert()
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "problem inserting configuration snapshot for the daemon with ID %d", snapshot.DaemonID)
+	}
+	return err
+}
+
+// Fetches the configuration snapshot by ID. It returns nil if the
+// snapshot does not exist.
+func GetKeaConfigSnapshot(dbi dbops.DBI, id int64) (*KeaConfigSnapshot, error) {
+	snapshot := &KeaConfigSnapshot{}
+	err := dbi.Model(snapshot).
+		Where("kea_config_snapshot.id = ?", id).
+		Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, pkgerrors.Wrapf(err, "problem getting configuration snapshot with ID %d", id)
+	}
+	return snapshot, nil
+}
+
+// Fetches the most recent configuration snapshot of the daemon. It returns
+// nil if there are no snapshots for the daemon.
+func GetLatestKeaConfigSnapshot(dbi dbops.DBI, daemonID int64) (*KeaConfigSnapshot, error) {
+	snapshot := &KeaConfigSnapshot{}
+	err := dbi.Model(snapshot).
+		Where("kea_config_snapshot.daemon_id = ?", daemonID).
+		OrderExpr("kea_config_snapshot.created_at DESC, kea_config_snapshot.id DESC").
+		Limit(1).
+		Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, pkgerrors.Wrapf(err, "problem getting latest configuration snapshot for the daemon with ID %d", daemonID)
+	}
+	return snapshot, nil
+}
+
+// Fetches a page of the daemon's configuration snapshots from the newest
+// to the oldest. The configurations are not fetched to limit the size of
+// the returned data. Limit has to be greater than 0, otherwise an error
+// is returned. It returns the snapshots and the total number of the
+// daemon's snapshots.
+func GetKeaConfigSnapshotsByPage(dbi dbops.DBI, daemonID, offset, limit int64) ([]KeaConfigSnapshot, int64, error) {
+	if limit == 0 {
+		return nil, 0, pkgerrors.New("limit should be greater than 0")
+	}
+	snapshots := []KeaConfigSnapshot{}
+	total, err := dbi.Model(&snapshots).
+		ExcludeColumn("config").
+		Where("kea_config_snapshot.daemon_id = ?", daemonID).
+		OrderExpr("kea_config_snapshot.created_at DESC, kea_config_snapshot.id DESC").
+		Offset(int(offset)).
+		Limit(int(limit)).
+		SelectAndCount()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return []KeaConfigSnapshot{}, 0, nil
+		}
+		return nil, 0, pkgerrors.Wrapf(err, "problem getting configuration snapshots for the daemon with ID %d", daemonID)
+	}
+	return snapshots, int64(total), nil
+}
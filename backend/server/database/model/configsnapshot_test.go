@@ -0,0 +1,132 @@
+package dbmodel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	dbtest "isc.org/stork/server/database/test"
+)
+
+// Test adding and fetching the configuration snapshots.
+func TestAddGetKeaConfigSnapshot(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	daemon, _, err := addTestDaemons(db)
+	require.NoError(t, err)
+
+	config, err := NewKeaConfigFromJSON(`{ "Dhcp4": { "valid-lifetime": 4000 } }`)
+	require.NoError(t, err)
+
+	snapshot := &KeaConfigSnapshot{
+		DaemonID:   daemon.ID,
+		Config:     config,
+		ConfigHash: "1234",
+		Source:     KeaConfigSnapshotSourceFetched,
+		Operation:  "subnet_update",
+	}
+	err = AddKeaConfigSnapshot(db, snapshot)
+	require.NoError(t, err)
+	require.NotZero(t, snapshot.ID)
+
+	returned, err := GetKeaConfigSnapshot(db, snapshot.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returned)
+	require.Equal(t, daemon.ID, returned.DaemonID)
+	require.Equal(t, "1234", returned.ConfigHash)
+	require.Equal(t, KeaConfigSnapshotSourceFetched, returned.Source)
+	require.Equal(t, "subnet_update", returned.Operation)
+	require.Zero(t, returned.UserID)
+	require.Zero(t, returned.AuditEntryID)
+	require.NotNil(t, returned.Config)
+	require.True(t, returned.Config.IsDHCPv4())
+	require.NotZero(t, returned.CreatedAt)
+
+	returned, err = GetKeaConfigSnapshot(db, snapshot.ID+1)
+	require.NoError(t, err)
+	require.Nil(t, returned)
+}
+
+// Test fetching the latest snapshot and the pages of snapshots.
+func TestGetKeaConfigSnapshotsByPage(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	daemon1, daemon2, err := addTestDaemons(db)
+	require.NoError(t, err)
+
+	latest, err := GetLatestKeaConfigSnapshot(db, daemon1.ID)
+	require.NoError(t, err)
+	require.Nil(t, latest)
+
+	config, err := NewKeaConfigFromJSON(`{ "Dhcp4": { } }`)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		err = AddKeaConfigSnapshot(db, &KeaConfigSnapshot{
+			CreatedAt:  time.Date(2024, 3, 5+i, 10, 0, 0, 0, time.UTC),
+			DaemonID:   daemon1.ID,
+			Config:     config,
+			ConfigHash: string(rune('a' + i)),
+			Source:     KeaConfigSnapshotSourceFetched,
+		})
+		require.NoError(t, err)
+	}
+	err = AddKeaConfigSnapshot(db, &KeaConfigSnapshot{
+		CreatedAt: time.Date(2024, 3, 10, 10, 0, 0, 0, time.UTC),
+		DaemonID:  daemon2.ID,
+		Config:    config,
+		Source:    KeaConfigSnapshotSourceRollback,
+	})
+	require.NoError(t, err)
+
+	latest, err = GetLatestKeaConfigSnapshot(db, daemon1.ID)
+	require.NoError(t, err)
+	require.NotNil(t, latest)
+	require.Equal(t, "c", latest.ConfigHash)
+
+	snapshots, total, err := GetKeaConfigSnapshotsByPage(db, daemon1.ID, 0, 2)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, total)
+	require.Len(t, snapshots, 2)
+	require.Equal(t, "c", snapshots[0].ConfigHash)
+	require.Equal(t, "b", snapshots[1].ConfigHash)
+	// The configurations are not fetched.
+	require.Nil(t, snapshots[0].Config)
+
+	snapshots, total, err = GetKeaConfigSnapshotsByPage(db, daemon1.ID, 2, 2)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, total)
+	require.Len(t, snapshots, 1)
+	require.Equal(t, "a", snapshots[0].ConfigHash)
+
+	_, _, err = GetKeaConfigSnapshotsByPage(db, daemon1.ID, 0, 0)
+	require.Error(t, err)
+}
+
+// Test that the snapshots are deleted together with the daemon.
+func TestDeleteDaemonWithKeaConfigSnapshots(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	daemon, _, err := addTestDaemons(db)
+	require.NoError(t, err)
+
+	config, err := NewKeaConfigFromJSON(`{ "Dhcp4": { } }`)
+	require.NoError(t, err)
+	snapshot := &KeaConfigSnapshot{
+		DaemonID: daemon.ID,
+		Config:   config,
+		Source:   KeaConfigSnapshotSourceFetched,
+	}
+	err = AddKeaConfigSnapshot(db, snapshot)
+	require.NoError(t, err)
+
+	err = DeleteApp(db, daemon.App)
+	require.NoError(t, err)
+
+	returned, err := GetKeaConfigSnapshot(db, snapshot.ID)
+	require.NoError(t, err)
+	require.Nil(t, returned)
+}
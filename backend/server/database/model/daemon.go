@@ -10,6 +10,7 @@ import (
 
 	"github.com/go-pg/pg/v10"
 	pkgerrors "github.com/pkg/errors"
+	bind9config "isc.org/stork/appcfg/bind9"
 	keaconfig "isc.org/stork/appcfg/kea"
 	dbops "isc.org/stork/server/database"
 )
@@ -42,6 +43,41 @@ type KeaDHCPDaemon struct {
 	Stats       KeaDHCPDaemonStats
 }
 
+// A structure reflecting the statistics of the DNS updates signed with
+// a TSIG key by the Kea D2 daemon.
+type KeaD2KeyStats struct {
+	Name          string
+	UpdateSent    int64
+	UpdateSuccess int64
+	UpdateTimeout int64
+	UpdateError   int64
+}
+
+// A structure reflecting Kea D2 stats for daemon. It is stored as a JSONB
+// value in SQL and unmarshaled in this structure. The counters hold the
+// latest values returned by the daemon.
+type KeaD2DaemonStats struct {
+	NCRReceived   int64
+	NCRInvalid    int64
+	NCRError      int64
+	UpdateSent    int64
+	UpdateSuccess int64
+	UpdateTimeout int64
+	UpdateError   int64
+	Keys          []KeaD2KeyStats
+	CollectedAt   time.Time
+}
+
+// A structure holding Kea D2 specific information about a daemon. It
+// reflects the kea_d2_daemon table which extends the daemon and kea_daemon
+// tables with the Kea DHCP-DDNS specific information.
+type KeaD2Daemon struct {
+	tableName   struct{} `pg:"kea_d2_daemon"` //nolint:unused
+	ID          int64
+	KeaDaemonID int64
+	Stats       KeaD2DaemonStats
+}
+
 // A structure holding common information for all Kea daemons. It
 // reflects the information stored in the kea_daemon table.
 type KeaDaemon struct {
@@ -51,6 +87,7 @@ type KeaDaemon struct {
 	DaemonID   int64
 
 	KeaDHCPDaemon *KeaDHCPDaemon `pg:"rel:belongs-to"`
+	KeaD2Daemon   *KeaD2Daemon   `pg:"rel:belongs-to"`
 }
 
 // BIND 9
@@ -169,11 +206,15 @@ type Bind9DaemonStats struct {
 	NamedStats         *Bind9NamedStats
 }
 
-// A structure holding BIND9 daemon specific information.
+// A structure holding BIND9 daemon specific information. The parsed
+// configuration is nil if it could not be fetched from the agent. The
+// secrets of the keys are hidden in the configuration.
 type Bind9Daemon struct {
-	ID       int64
-	DaemonID int64
-	Stats    Bind9DaemonStats
+	ID         int64
+	DaemonID   int64
+	Stats      Bind9DaemonStats
+	Config     *bind9config.Config
+	ConfigHash string
 }
 
 // A structure reflecting all SQL tables holding information about the
@@ -257,7 +298,8 @@ func GetDaemonByID(dbi pg.DBI, id int64) (*Daemon, error) {
 	q := dbi.Model(&daemon)
 	q = q.Relation("App.AccessPoints")
 	q = q.Relation("App.Machine")
-	q = q.Relation("KeaDaemon")
+	q = q.Relation("KeaDaemon.KeaD2Daemon")
+	q = q.Relation("Bind9Daemon")
 	q = q.Where("daemon.id = ?", id)
 	err := q.Select()
 	if errors.Is(err, pg.ErrNoRows) {
@@ -284,6 +326,24 @@ func GetKeaDHCPDaemons(dbi pg.DBI) (daemons []Daemon, err error) {
 	return
 }
 
+// Inserts or updates the statistics of the Kea D2 daemon. The entry
+// holding the statistics is created when they are stored for the first
+// time.
+func UpdateKeaD2DaemonStats(dbi pg.DBI, keaDaemonID int64, stats KeaD2DaemonStats) error {
+	d2Daemon := &KeaD2Daemon{
+		KeaDaemonID: keaDaemonID,
+		Stats:       stats,
+	}
+	_, err := dbi.Model(d2Daemon).
+		OnConflict("(kea_daemon_id) DO UPDATE").
+		Set("stats = EXCLUDED.stats").
+		Insert()
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "problem updating the statistics of the Kea D2 daemon %d", keaDaemonID)
+	}
+	return err
+}
+
 // Select one or more daemons for update. The main use case for this function is
 // to prevent modifications and deletions of the daemons while the server inserts
 // config reports for them. It must be called within a transaction and the selected
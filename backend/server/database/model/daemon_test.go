@@ -7,6 +7,7 @@ import (
 
 	"github.com/go-pg/pg/v10"
 	require "github.com/stretchr/testify/require"
+	bind9config "isc.org/stork/appcfg/bind9"
 	dbtest "isc.org/stork/server/database/test"
 )
 
@@ -147,6 +148,9 @@ func TestUpdateBind9Daemon(t *testing.T) {
 	daemon.Version = "9.20"
 
 	daemon.Bind9Daemon.Stats.ZoneCount = 123
+	daemon.Bind9Daemon.Config, err = bind9config.Parse(`options { recursion no; };`)
+	require.NoError(t, err)
+	daemon.Bind9Daemon.ConfigHash = "hash"
 
 	err = UpdateDaemon(db, daemon)
 	require.NoError(t, err)
@@ -163,6 +167,74 @@ func TestUpdateBind9Daemon(t *testing.T) {
 	require.Equal(t, "9.20", daemon.Version)
 	require.NotNil(t, daemon.Bind9Daemon)
 	require.EqualValues(t, 123, daemon.Bind9Daemon.Stats.ZoneCount)
+	require.NotNil(t, daemon.Bind9Daemon.Config)
+	require.Equal(t, "no", daemon.Bind9Daemon.Config.GetOptions().GetValue("recursion"))
+	require.Equal(t, "hash", daemon.Bind9Daemon.ConfigHash)
+}
+
+// Test that the Kea D2 daemon statistics are inserted and updated.
+func TestUpdateKeaD2DaemonStats(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	m := &Machine{
+		Address:   "localhost",
+		AgentPort: 8080,
+	}
+	err := AddMachine(db, m)
+	require.NoError(t, err)
+
+	app := &App{
+		MachineID: m.ID,
+		Type:      AppTypeKea,
+		Daemons: []*Daemon{
+			NewKeaDaemon(DaemonNameD2, true),
+		},
+	}
+	_, err = AddApp(db, app)
+	require.NoError(t, err)
+	daemon := app.Daemons[0]
+	require.NotZero(t, daemon.KeaDaemon.ID)
+
+	// The statistics haven't been collected yet.
+	daemon, err = GetDaemonByID(db, daemon.ID)
+	require.NoError(t, err)
+	require.NotNil(t, daemon.KeaDaemon)
+	require.Nil(t, daemon.KeaDaemon.KeaD2Daemon)
+
+	collectedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	stats := KeaD2DaemonStats{
+		NCRReceived: 10,
+		UpdateSent:  8,
+		UpdateError: 2,
+		Keys: []KeaD2KeyStats{
+			{
+				Name:        "d2.key",
+				UpdateSent:  8,
+				UpdateError: 2,
+			},
+		},
+		CollectedAt: collectedAt,
+	}
+	err = UpdateKeaD2DaemonStats(db, daemon.KeaDaemon.ID, stats)
+	require.NoError(t, err)
+
+	daemon, err = GetDaemonByID(db, daemon.ID)
+	require.NoError(t, err)
+	require.NotNil(t, daemon.KeaDaemon.KeaD2Daemon)
+	require.Equal(t, stats, daemon.KeaDaemon.KeaD2Daemon.Stats)
+
+	// Update the existing entry.
+	stats.UpdateSent = 20
+	stats.Keys = nil
+	err = UpdateKeaD2DaemonStats(db, daemon.KeaDaemon.ID, stats)
+	require.NoError(t, err)
+
+	daemon, err = GetDaemonByID(db, daemon.ID)
+	require.NoError(t, err)
+	require.NotNil(t, daemon.KeaDaemon.KeaD2Daemon)
+	require.EqualValues(t, 20, daemon.KeaDaemon.KeaD2Daemon.Stats.UpdateSent)
+	require.Empty(t, daemon.KeaDaemon.KeaD2Daemon.Stats.Keys)
 }
 
 // Returns all HA state names to which the daemon belongs and the
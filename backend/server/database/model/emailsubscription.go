@@ -0,0 +1,129 @@
+package dbmodel
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	pkgerrors "github.com/pkg/errors"
+	dbops "isc.org/stork/server/database"
+)
+
+// A structure reflecting the email_subscription SQL table. It describes
+// a user's subscription to the email notifications about the events and
+// the new configuration review issues. A notification is sent to the
+// specified address if its level is not lower than the subscription's
+// level and it relates to the objects specified in the non-empty filters.
+type EmailSubscription struct {
+	ID        int64
+	UserID    int
+	User      *SystemUser `pg:"rel:has-one"`
+	CreatedAt time.Time
+	// Address receiving the notifications.
+	Email   string
+	Enabled bool `pg:",use_zero"`
+	// Minimum level of the notifications sent to the address.
+	Level EventLevel `pg:",use_zero"`
+	// Object filters. The notification must relate to one of the objects
+	// specified in each non-empty filter.
+	AppIDs    []int64 `pg:",array"`
+	DaemonIDs []int64 `pg:",array"`
+	SubnetIDs []int64 `pg:",array"`
+}
+
+// Checks if the notification with the specified level and related to the
+// specified objects should be sent to the subscriber.
+func (s *EmailSubscription) Accepts(level EventLevel, relations *Relations) bool {
+	if !s.Enabled || level < s.Level {
+		return false
+	}
+	if relations == nil {
+		relations = &Relations{}
+	}
+	return matchesNotificationFilters(
+		notificationFilter{s.AppIDs, relations.AppID},
+		notificationFilter{s.DaemonIDs, relations.DaemonID},
+		notificationFilter{s.SubnetIDs, relations.SubnetID},
+	)
+}
+
+// Adds an email subscription of the user to the database.
+func AddEmailSubscription(dbi dbops.DBI, subscription *EmailSubscription) error {
+	_, err := dbi.Model(subscription).Insert()
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "problem inserting email subscription of user %d", subscription.UserID)
+	}
+	return err
+}
+
+// Updates the email subscription of the user in the database.
+func UpdateEmailSubscription(dbi dbops.DBI, subscription *EmailSubscription) error {
+	result, err := dbi.Model(subscription).
+		Where("id = ?", subscription.ID).
+		Where("user_id = ?", subscription.UserID).
+		ExcludeColumn("created_at").
+		Update()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem updating email subscription %d of user %d", subscription.ID, subscription.UserID)
+	} else if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "email subscription %d of user %d does not exist", subscription.ID, subscription.UserID)
+	}
+	return nil
+}
+
+// Fetches the email subscription of the user by ID. It returns nil if the
+// subscription does not exist.
+func GetEmailSubscription(dbi dbops.DBI, userID int, id int64) (*EmailSubscription, error) {
+	subscription := &EmailSubscription{}
+	err := dbi.Model(subscription).
+		Where("id = ?", id).
+		Where("user_id = ?", userID).
+		Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, pkgerrors.Wrapf(err, "problem getting email subscription %d of user %d", id, userID)
+	}
+	return subscription, nil
+}
+
+// Fetches the email subscriptions of the user ordered by ID.
+func GetEmailSubscriptionsByUserID(dbi dbops.DBI, userID int) ([]EmailSubscription, error) {
+	subscriptions := []EmailSubscription{}
+	err := dbi.Model(&subscriptions).
+		Where("user_id = ?", userID).
+		OrderExpr("id ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrapf(err, "problem getting email subscriptions of user %d", userID)
+	}
+	return subscriptions, nil
+}
+
+// Fetches the enabled email subscriptions of all users ordered by ID.
+func GetEnabledEmailSubscriptions(dbi dbops.DBI) ([]EmailSubscription, error) {
+	subscriptions := []EmailSubscription{}
+	err := dbi.Model(&subscriptions).
+		Where("enabled = TRUE").
+		OrderExpr("id ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrap(err, "problem getting enabled email subscriptions")
+	}
+	return subscriptions, nil
+}
+
+// Deletes the email subscription of the user from the database.
+func DeleteEmailSubscription(dbi dbops.DBI, userID int, id int64) error {
+	result, err := dbi.Model(&EmailSubscription{}).
+		Where("id = ?", id).
+		Where("user_id = ?", userID).
+		Delete()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem deleting email subscription %d of user %d", id, userID)
+	} else if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "email subscription %d of user %d does not exist", id, userID)
+	}
+	return nil
+}
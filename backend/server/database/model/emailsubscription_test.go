@@ -0,0 +1,119 @@
+package dbmodel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbtest "isc.org/stork/server/database/test"
+)
+
+// Test that the subscription accepts the notifications matching its level
+// and object filters.
+func TestEmailSubscriptionAccepts(t *testing.T) {
+	subscription := &EmailSubscription{
+		Enabled: true,
+		Level:   EvError,
+	}
+	require.False(t, subscription.Accepts(EvWarning, nil))
+	require.True(t, subscription.Accepts(EvError, nil))
+
+	subscription.AppIDs = []int64{1}
+	subscription.SubnetIDs = []int64{5, 6}
+	require.True(t, subscription.Accepts(EvError, &Relations{AppID: 1, SubnetID: 6}))
+	require.False(t, subscription.Accepts(EvError, &Relations{AppID: 2, SubnetID: 6}))
+	require.False(t, subscription.Accepts(EvError, &Relations{AppID: 1}))
+
+	subscription.Enabled = false
+	require.False(t, subscription.Accepts(EvError, &Relations{AppID: 1, SubnetID: 6}))
+}
+
+// Test that the email subscriptions can be added, updated, fetched and
+// deleted.
+func TestAddUpdateGetDeleteEmailSubscription(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	user := &SystemUser{
+		Login:    "jdoe",
+		Email:    "jdoe@example.org",
+		Lastname: "Doe",
+		Name:     "John",
+	}
+	_, err := CreateUser(db, user)
+	require.NoError(t, err)
+
+	subscription := &EmailSubscription{
+		UserID:    1,
+		Email:     "oncall@example.org",
+		Enabled:   true,
+		Level:     EvError,
+		DaemonIDs: []int64{1, 2},
+	}
+	err = AddEmailSubscription(db, subscription)
+	require.NoError(t, err)
+	require.NotZero(t, subscription.ID)
+
+	err = AddEmailSubscription(db, &EmailSubscription{
+		UserID: user.ID,
+		Email:  "jdoe@example.org",
+		Level:  EvWarning,
+	})
+	require.NoError(t, err)
+
+	returned, err := GetEmailSubscription(db, 1, subscription.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returned)
+	require.Equal(t, "oncall@example.org", returned.Email)
+	require.True(t, returned.Enabled)
+	require.Equal(t, EvError, returned.Level)
+	require.Equal(t, []int64{1, 2}, returned.DaemonIDs)
+	require.Empty(t, returned.AppIDs)
+	require.False(t, returned.CreatedAt.IsZero())
+
+	// The subscription belongs to another user.
+	returned, err = GetEmailSubscription(db, user.ID, subscription.ID)
+	require.NoError(t, err)
+	require.Nil(t, returned)
+
+	subscriptions, err := GetEmailSubscriptionsByUserID(db, 1)
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 1)
+
+	subscriptions, err = GetEnabledEmailSubscriptions(db)
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 1)
+	require.Equal(t, subscription.ID, subscriptions[0].ID)
+
+	subscription.Enabled = false
+	subscription.Email = "noc@example.org"
+	err = UpdateEmailSubscription(db, subscription)
+	require.NoError(t, err)
+
+	returned, err = GetEmailSubscription(db, 1, subscription.ID)
+	require.NoError(t, err)
+	require.Equal(t, "noc@example.org", returned.Email)
+	require.False(t, returned.Enabled)
+
+	subscriptions, err = GetEnabledEmailSubscriptions(db)
+	require.NoError(t, err)
+	require.Empty(t, subscriptions)
+
+	// The user cannot update and delete the subscription of another user.
+	err = UpdateEmailSubscription(db, &EmailSubscription{ID: subscription.ID, UserID: user.ID, Email: "foo@example.org"})
+	require.ErrorIs(t, err, ErrNotExists)
+	err = DeleteEmailSubscription(db, user.ID, subscription.ID)
+	require.ErrorIs(t, err, ErrNotExists)
+
+	err = DeleteEmailSubscription(db, 1, subscription.ID)
+	require.NoError(t, err)
+	subscriptions, err = GetEmailSubscriptionsByUserID(db, 1)
+	require.NoError(t, err)
+	require.Empty(t, subscriptions)
+
+	// Deleting the user deletes the subscriptions.
+	err = DeleteUser(db, user)
+	require.NoError(t, err)
+	subscriptions, err = GetEmailSubscriptionsByUserID(db, user.ID)
+	require.NoError(t, err)
+	require.Empty(t, subscriptions)
+}
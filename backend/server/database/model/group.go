@@ -1,6 +1,7 @@
 package dbmodel
 
 import (
+	"context"
 	"errors"
 
 	"github.com/go-pg/pg/v10"
@@ -13,15 +14,27 @@ import (
 const (
 	SuperAdminGroupID int = 1
 	AdminGroupID      int = 2
+	ReadOnlyGroupID   int = 3
 )
 
+// The highest ID reserved for the built-in groups. The custom groups
+// created by the users have greater IDs.
+const maxBuiltInGroupID int = 99
+
 // Represents a group of users having some specific permissions.
 type SystemGroup struct {
 	ID          int
 	Name        string
 	Description string
 
-	Users []*SystemUser `pg:"many2many:system_user_to_group,fk:group_id,join_fk:user_id"`
+	Users       []*SystemUser            `pg:"many2many:system_user_to_group,fk:group_id,join_fk:user_id"`
+	Permissions []*SystemGroupPermission `pg:"rel:has-many,join_fk:group_id"`
+}
+
+// Checks if the group is one of the groups created by the server. Their
+// access rules are hard-coded and they cannot be modified nor deleted.
+func (group *SystemGroup) IsBuiltIn() bool {
+	return group.ID > 0 && group.ID <= maxBuiltInGroupID
 }
 
 // Fetches a collection of groups from the database. The offset and
@@ -35,7 +48,9 @@ type SystemGroup struct {
 // and error.
 func GetGroupsByPage(db *dbops.PgDB, offset, limit int64, filterText *string, sortField string, sortDir SortDirEnum) ([]SystemGroup, int64, error) {
 	var groups []SystemGroup
-	q := db.Model(&groups)
+	q := db.Model(&groups).Relation("Permissions", func(q *orm.Query) (*orm.Query, error) {
+		return q.Order("system_group_permission.id ASC"), nil
+	})
 
 	if filterText != nil {
 		text := "%" + *filterText + "%"
@@ -62,3 +77,134 @@ func GetGroupsByPage(db *dbops.PgDB, offset, limit int64, filterText *string, so
 
 	return groups, int64(total), err
 }
+
+// Fetches a group with the given ID along with its permissions. If the
+// group doesn't exist the nil value is returned.
+func GetGroupByID(dbi dbops.DBI, id int) (*SystemGroup, error) {
+	group := &SystemGroup{}
+	err := dbi.Model(group).
+		Relation("Permissions", func(q *orm.Query) (*orm.Query, error) {
+			return q.Order("system_group_permission.id ASC"), nil
+		}).
+		Where("system_group.id = ?", id).
+		Select()
+	if errors.Is(err, pg.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, pkgerrors.Wrapf(err, "problem fetching group %d from the database", id)
+	}
+	return group, nil
+}
+
+// Checks if all permissions of the group are valid.
+func validateGroupPermissions(group *SystemGroup) error {
+	for _, permission := range group.Permissions {
+		if !permission.Permission.IsValid() {
+			return pkgerrors.Errorf("unsupported permission %s specified for group %s",
+				permission.Permission, group.Name)
+		}
+	}
+	return nil
+}
+
+// Inserts the permissions of the group into the database.
+func addGroupPermissions(dbi dbops.DBI, group *SystemGroup) error {
+	if len(group.Permissions) == 0 {
+		return nil
+	}
+	for _, permission := range group.Permissions {
+		permission.ID = 0
+		permission.GroupID = group.ID
+	}
+	_, err := dbi.Model(&group.Permissions).Insert()
+	return pkgerrors.Wrapf(err, "problem inserting permissions of group %s", group.Name)
+}
+
+// Internal function adding a group in a transaction.
+func addGroup(dbi dbops.DBI, group *SystemGroup) (conflict bool, err error) {
+	if err = validateGroupPermissions(group); err != nil {
+		return false, err
+	}
+	_, err = dbi.Model(group).Insert()
+	if err != nil {
+		var pgError pg.Error
+		if errors.As(err, &pgError) {
+			conflict = pgError.IntegrityViolation()
+		}
+		return conflict, pkgerrors.Wrapf(err, "problem inserting group %s", group.Name)
+	}
+	return false, addGroupPermissions(dbi, group)
+}
+
+// Adds a custom group of users with its permissions to the database.
+// The returned conflict value indicates if the group name is already
+// used by another group.
+func AddGroup(dbi dbops.DBI, group *SystemGroup) (conflict bool, err error) {
+	if db, ok := dbi.(*pg.DB); ok {
+		err = db.RunInTransaction(context.Background(), func(tx *pg.Tx) error {
+			conflict, err = addGroup(tx, group)
+			return err
+		})
+		return
+	}
+	return addGroup(dbi, group)
+}
+
+// Internal function updating a group in a transaction.
+func updateGroup(dbi dbops.DBI, group *SystemGroup) (conflict bool, err error) {
+	if group.IsBuiltIn() {
+		return false, pkgerrors.Errorf("built-in group %d cannot be modified", group.ID)
+	}
+	if err = validateGroupPermissions(group); err != nil {
+		return false, err
+	}
+	result, err := dbi.Model(group).Column("name", "description").WherePK().Update()
+	if err != nil {
+		var pgError pg.Error
+		if errors.As(err, &pgError) {
+			conflict = pgError.IntegrityViolation()
+		}
+		return conflict, pkgerrors.Wrapf(err, "problem updating group %d", group.ID)
+	} else if result.RowsAffected() <= 0 {
+		return false, pkgerrors.Wrapf(ErrNotExists, "group with ID %d does not exist", group.ID)
+	}
+	// Replace the permissions of the group.
+	_, err = dbi.Model(&SystemGroupPermission{}).
+		Where("group_id = ?", group.ID).
+		Delete()
+	if err != nil {
+		return false, pkgerrors.Wrapf(err, "problem deleting permissions of group %d", group.ID)
+	}
+	return false, addGroupPermissions(dbi, group)
+}
+
+// Updates the name, description and permissions of a custom group in the
+// database. The built-in groups cannot be updated. The returned conflict
+// value indicates if the new group name is already used by another group.
+func UpdateGroup(dbi dbops.DBI, group *SystemGroup) (conflict bool, err error) {
+	if db, ok := dbi.(*pg.DB); ok {
+		err = db.RunInTransaction(context.Background(), func(tx *pg.Tx) error {
+			conflict, err = updateGroup(tx, group)
+			return err
+		})
+		return
+	}
+	return updateGroup(dbi, group)
+}
+
+// Deletes a custom group from the database. The users belonging to this
+// group lose the permissions granted by the group. The built-in groups
+// cannot be deleted.
+func DeleteGroup(dbi dbops.DBI, id int) error {
+	group := &SystemGroup{ID: id}
+	if group.IsBuiltIn() {
+		return pkgerrors.Errorf("built-in group %d cannot be deleted", id)
+	}
+	result, err := dbi.Model(group).WherePK().Delete()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem deleting group %d", id)
+	} else if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "group with ID %d does not exist", id)
+	}
+	return nil
+}
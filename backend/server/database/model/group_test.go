@@ -14,31 +14,38 @@ func TestGetGroups(t *testing.T) {
 
 	groups, total, err := GetGroupsByPage(db, 0, 10, nil, "", SortDirAny)
 	require.NoError(t, err)
-	require.EqualValues(t, 2, total)
-	// There are two predefined groups.
-	require.Len(t, groups, 2)
+	require.EqualValues(t, 3, total)
+	// There are three predefined groups.
+	require.Len(t, groups, 3)
 
 	// Groups are supposed to be ordered by id.
 	require.Equal(t, SuperAdminGroupID, groups[0].ID)
 	require.Equal(t, "super-admin", groups[0].Name)
 	require.Equal(t, AdminGroupID, groups[1].ID)
 	require.Equal(t, "admin", groups[1].Name)
+	require.Equal(t, ReadOnlyGroupID, groups[2].ID)
+	require.Equal(t, "read-only", groups[2].Name)
+	for _, group := range groups {
+		require.True(t, group.IsBuiltIn())
+	}
 
 	// check sorting field and order ascending
 	groups, total, err = GetGroupsByPage(db, 0, 10, nil, "name", SortDirAsc)
 	require.NoError(t, err)
-	require.EqualValues(t, 2, total)
-	require.Len(t, groups, 2)
+	require.EqualValues(t, 3, total)
+	require.Len(t, groups, 3)
 	require.Equal(t, "admin", groups[0].Name)
-	require.Equal(t, "super-admin", groups[1].Name)
+	require.Equal(t, "read-only", groups[1].Name)
+	require.Equal(t, "super-admin", groups[2].Name)
 
 	// check sorting field and order descending
 	groups, total, err = GetGroupsByPage(db, 0, 10, nil, "name", SortDirDesc)
 	require.NoError(t, err)
-	require.EqualValues(t, 2, total)
-	require.Len(t, groups, 2)
+	require.EqualValues(t, 3, total)
+	require.Len(t, groups, 3)
 	require.Equal(t, "super-admin", groups[0].Name)
-	require.Equal(t, "admin", groups[1].Name)
+	require.Equal(t, "read-only", groups[1].Name)
+	require.Equal(t, "admin", groups[2].Name)
 
 	// check filtering by text
 	text := "super"
@@ -48,3 +55,109 @@ func TestGetGroups(t *testing.T) {
 	require.Len(t, groups, 1)
 	require.Equal(t, "super-admin", groups[0].Name)
 }
+
+// Test that a custom group with permissions can be added, updated and
+// deleted.
+func TestAddUpdateDeleteGroup(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	group := &SystemGroup{
+		Name:        "operators",
+		Description: "Host reservations operators",
+		Permissions: []*SystemGroupPermission{
+			{Permission: PermissionView},
+			{Permission: PermissionManageHosts},
+		},
+	}
+	conflict, err := AddGroup(db, group)
+	require.NoError(t, err)
+	require.False(t, conflict)
+	require.Greater(t, group.ID, 99)
+	require.False(t, group.IsBuiltIn())
+
+	returned, err := GetGroupByID(db, group.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returned)
+	require.Equal(t, "operators", returned.Name)
+	require.Len(t, returned.Permissions, 2)
+	require.Equal(t, PermissionView, returned.Permissions[0].Permission)
+	require.Equal(t, PermissionManageHosts, returned.Permissions[1].Permission)
+
+	// The group name must be unique.
+	conflict, err = AddGroup(db, &SystemGroup{Name: "operators"})
+	require.Error(t, err)
+	require.True(t, conflict)
+
+	// Invalid permission is rejected.
+	_, err = AddGroup(db, &SystemGroup{
+		Name:        "invalid",
+		Permissions: []*SystemGroupPermission{{Permission: "manage-users"}},
+	})
+	require.Error(t, err)
+
+	// Replace the permissions.
+	group.Name = "subnet-operators"
+	group.Permissions = []*SystemGroupPermission{
+		{Permission: PermissionManageSubnets},
+	}
+	conflict, err = UpdateGroup(db, group)
+	require.NoError(t, err)
+	require.False(t, conflict)
+
+	returned, err = GetGroupByID(db, group.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returned)
+	require.Equal(t, "subnet-operators", returned.Name)
+	require.Len(t, returned.Permissions, 1)
+	require.Equal(t, PermissionManageSubnets, returned.Permissions[0].Permission)
+
+	// The new name must not conflict with other groups.
+	group.Name = "admin"
+	conflict, err = UpdateGroup(db, group)
+	require.Error(t, err)
+	require.True(t, conflict)
+
+	// Non-existing group.
+	_, err = UpdateGroup(db, &SystemGroup{ID: 1000, Name: "foo"})
+	require.ErrorIs(t, err, ErrNotExists)
+
+	// The built-in groups are immutable.
+	_, err = UpdateGroup(db, &SystemGroup{ID: AdminGroupID, Name: "foo"})
+	require.Error(t, err)
+	require.Error(t, DeleteGroup(db, SuperAdminGroupID))
+
+	// The groups list includes the permissions.
+	groups, total, err := GetGroupsByPage(db, 0, 10, nil, "", SortDirAny)
+	require.NoError(t, err)
+	require.EqualValues(t, 4, total)
+	require.Len(t, groups[3].Permissions, 1)
+
+	require.NoError(t, DeleteGroup(db, group.ID))
+	returned, err = GetGroupByID(db, group.ID)
+	require.NoError(t, err)
+	require.Nil(t, returned)
+	require.ErrorIs(t, DeleteGroup(db, group.ID), ErrNotExists)
+}
+
+// Test that the permissions of the user's custom groups are populated.
+func TestPopulateUserPermissions(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	group := &SystemGroup{
+		Name:        "viewers",
+		Permissions: []*SystemGroupPermission{{Permission: PermissionView, AppID: 3}},
+	}
+	_, err := AddGroup(db, group)
+	require.NoError(t, err)
+
+	user := &SystemUser{
+		Groups: []*SystemGroup{{ID: AdminGroupID}, {ID: group.ID}},
+	}
+	require.NoError(t, PopulateUserPermissions(db, user))
+	require.Empty(t, user.Groups[0].Permissions)
+	require.Len(t, user.Groups[1].Permissions, 1)
+	require.EqualValues(t, 3, user.Groups[1].Permissions[0].AppID)
+	require.True(t, user.HasPermission(PermissionView, PermissionTarget{AppID: 3}))
+}
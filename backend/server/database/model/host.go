@@ -406,6 +406,9 @@ type HostsByPageFilters struct {
 	Global            *bool
 	DHCPDataConflict  *bool
 	DHCPDataDuplicate *bool
+	// If not nil, only the hosts covered by any of these permission
+	// scopes are returned. The empty scopes exclude all hosts.
+	PermissionScopes []*SystemGroupPermission
 }
 
 // Fetches a collection of hosts from the database.
@@ -574,6 +577,9 @@ func GetHostsByPage(dbi dbops.DBI, offset, limit int64, filters HostsByPageFilte
 		q = q.Where("host.subnet_id IS NOT NULL")
 	}
 
+	// Filter by the permission scopes.
+	q = wherePermissionScopes(q, filters.PermissionScopes, "local_host", "host_id", "host.id", "host.subnet_id")
+
 	// Filter by text.
 	if filters.FilterText != nil && len(*filters.FilterText) > 0 {
 		// It is possible that the user is typing a search text with colons
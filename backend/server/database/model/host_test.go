@@ -869,6 +869,77 @@ func TestGetHostsByPageApp(t *testing.T) {
 	require.Equal(t, hosts[1].ID, returned[1].SubnetID)
 }
 
+// Test that page of the hosts can be filtered by the permission scopes and
+// the paging applies to the hosts covered by the scopes.
+func TestGetHostsByPagePermissionScopes(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	// Insert apps and hosts into the database.
+	apps, hosts := addTestHosts(t, db)
+
+	// The app scope covers the hosts of both daemons of the first app.
+	filters := HostsByPageFilters{
+		PermissionScopes: []*SystemGroupPermission{
+			{Permission: PermissionView, AppID: apps[0].ID},
+		},
+	}
+	returned, total, err := GetHostsByPage(db, 1, 1, filters, "", SortDirAny)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	require.Len(t, returned, 1)
+	require.Equal(t, hosts[2].ID, returned[0].ID)
+
+	// The subnet scope covers the hosts in the subnet.
+	filters.PermissionScopes = []*SystemGroupPermission{
+		{Permission: PermissionView, SubnetID: 2},
+	}
+	returned, total, err = GetHostsByPage(db, 0, 10, filters, "", SortDirAny)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Len(t, returned, 1)
+	require.Equal(t, hosts[2].ID, returned[0].ID)
+
+	// The scope must match both the daemon and the subnet. The host of
+	// this daemon is global.
+	filters.PermissionScopes = []*SystemGroupPermission{
+		{Permission: PermissionView, DaemonID: apps[1].Daemons[0].ID, SubnetID: 1},
+	}
+	returned, total, err = GetHostsByPage(db, 0, 10, filters, "", SortDirAny)
+	require.NoError(t, err)
+	require.Zero(t, total)
+	require.Empty(t, returned)
+
+	// The hosts covered by any of the scopes are returned.
+	filters.PermissionScopes = []*SystemGroupPermission{
+		{Permission: PermissionView, SubnetID: 1},
+		{Permission: PermissionManageHosts, DaemonID: apps[1].Daemons[1].ID},
+	}
+	returned, total, err = GetHostsByPage(db, 0, 10, filters, "", SortDirAny)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	require.Len(t, returned, 2)
+	require.Equal(t, hosts[0].ID, returned[0].ID)
+	require.Equal(t, hosts[3].ID, returned[1].ID)
+
+	// The scopes can be combined with the other filters.
+	filters.AppID = &apps[1].ID
+	returned, total, err = GetHostsByPage(db, 0, 10, filters, "", SortDirAny)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Len(t, returned, 1)
+	require.Equal(t, hosts[3].ID, returned[0].ID)
+
+	// The empty scopes exclude all hosts.
+	filters = HostsByPageFilters{
+		PermissionScopes: []*SystemGroupPermission{},
+	}
+	returned, total, err = GetHostsByPage(db, 0, 10, filters, "", SortDirAny)
+	require.NoError(t, err)
+	require.Zero(t, total)
+	require.Empty(t, returned)
+}
+
 // Test that page of the hosts can be filtered by IP reservations and
 // hostnames.
 func TestGetHostsByPageFilteringText(t *testing.T) {
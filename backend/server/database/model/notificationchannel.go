@@ -0,0 +1,179 @@
+package dbmodel
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	pkgerrors "github.com/pkg/errors"
+	dbops "isc.org/stork/server/database"
+)
+
+// Type of the outbound notification channel.
+type NotificationChannelType string
+
+// Supported types of the notification channels.
+const (
+	// The notifications are sent in the HTTP POST requests.
+	NotificationChannelTypeWebhook NotificationChannelType = "webhook"
+)
+
+// A structure reflecting the notification_channel SQL table. It describes
+// an outbound channel receiving the events and the new configuration
+// review issues. A notification is sent over the channel if its level is
+// not lower than the channel's level and it relates to the objects
+// specified in the non-empty filters.
+type NotificationChannel struct {
+	ID        int64
+	CreatedAt time.Time
+	Name      string
+	Type      NotificationChannelType
+	Enabled   bool `pg:",use_zero"`
+	// URL receiving the notifications.
+	URL string
+	// Go template of the request body. The default JSON payload is sent
+	// if it is empty.
+	BodyTemplate string
+	// Content type of the request body. The application/json is used if
+	// it is empty.
+	ContentType string
+	// Minimum level of the notifications sent over the channel.
+	Level EventLevel `pg:",use_zero"`
+	// Object filters. The notification must relate to one of the objects
+	// specified in each non-empty filter.
+	MachineIDs []int64 `pg:",array"`
+	AppIDs     []int64 `pg:",array"`
+	DaemonIDs  []int64 `pg:",array"`
+	SubnetIDs  []int64 `pg:",array"`
+	// Maximum number of the delivery retries after a failure.
+	MaxRetries int64 `pg:",use_zero"`
+}
+
+// Checks if the notification with the specified level and related to the
+// specified objects should be sent over the channel.
+func (c *NotificationChannel) Accepts(level EventLevel, relations *Relations) bool {
+	if !c.Enabled || level < c.Level {
+		return false
+	}
+	if relations == nil {
+		relations = &Relations{}
+	}
+	return matchesNotificationFilters(
+		notificationFilter{c.MachineIDs, relations.MachineID},
+		notificationFilter{c.AppIDs, relations.AppID},
+		notificationFilter{c.DaemonIDs, relations.DaemonID},
+		notificationFilter{c.SubnetIDs, relations.SubnetID},
+	)
+}
+
+// An object filter of the notifications. It holds the IDs of the accepted
+// objects and the ID of the object the notification relates to.
+type notificationFilter struct {
+	ids []int64
+	id  int64
+}
+
+// Checks if the notification relates to one of the objects specified in
+// each non-empty filter.
+func matchesNotificationFilters(filters ...notificationFilter) bool {
+	for _, filter := range filters {
+		if len(filter.ids) == 0 {
+			continue
+		}
+		matched := false
+		for _, id := range filter.ids {
+			if id == filter.id {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// Adds a notification channel to the database. It returns a conflict flag
+// set to true if the channel with the same name already exists.
+func AddNotificationChannel(dbi dbops.DBI, channel *NotificationChannel) (conflict bool, err error) {
+	_, err = dbi.Model(channel).Insert()
+	if err != nil {
+		var pgError pg.Error
+		if errors.As(err, &pgError) {
+			conflict = pgError.IntegrityViolation()
+		}
+		err = pkgerrors.Wrapf(err, "problem inserting notification channel %s", channel.Name)
+	}
+	return
+}
+
+// Updates the notification channel in the database. It returns a conflict
+// flag set to true if another channel with the same name already exists.
+func UpdateNotificationChannel(dbi dbops.DBI, channel *NotificationChannel) (conflict bool, err error) {
+	result, err := dbi.Model(channel).WherePK().ExcludeColumn("created_at").Update()
+	if err != nil {
+		var pgError pg.Error
+		if errors.As(err, &pgError) {
+			conflict = pgError.IntegrityViolation()
+		}
+		err = pkgerrors.Wrapf(err, "problem updating notification channel with ID %d", channel.ID)
+	} else if result.RowsAffected() <= 0 {
+		err = pkgerrors.Wrapf(ErrNotExists, "notification channel with ID %d does not exist", channel.ID)
+	}
+	return
+}
+
+// Fetches the notification channel by ID. It returns nil if the channel
+// does not exist.
+func GetNotificationChannel(dbi dbops.DBI, id int64) (*NotificationChannel, error) {
+	channel := &NotificationChannel{}
+	err := dbi.Model(channel).
+		Where("id = ?", id).
+		Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, pkgerrors.Wrapf(err, "problem getting notification channel with ID %d", id)
+	}
+	return channel, nil
+}
+
+// Fetches all notification channels ordered by ID.
+func GetAllNotificationChannels(dbi dbops.DBI) ([]NotificationChannel, error) {
+	channels := []NotificationChannel{}
+	err := dbi.Model(&channels).
+		OrderExpr("id ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrap(err, "problem getting notification channels")
+	}
+	return channels, nil
+}
+
+// Fetches the enabled notification channels ordered by ID.
+func GetEnabledNotificationChannels(dbi dbops.DBI) ([]NotificationChannel, error) {
+	channels := []NotificationChannel{}
+	err := dbi.Model(&channels).
+		Where("enabled = TRUE").
+		OrderExpr("id ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrap(err, "problem getting enabled notification channels")
+	}
+	return channels, nil
+}
+
+// Deletes the notification channel from the database.
+func DeleteNotificationChannel(dbi dbops.DBI, id int64) error {
+	result, err := dbi.Model(&NotificationChannel{}).
+		Where("id = ?", id).
+		Delete()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem deleting notification channel with ID %d", id)
+	} else if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "notification channel with ID %d does not exist", id)
+	}
+	return nil
+}
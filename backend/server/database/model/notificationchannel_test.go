@@ -0,0 +1,118 @@
+package dbmodel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbtest "isc.org/stork/server/database/test"
+)
+
+// Test that the channel accepts the notifications matching its level
+// and object filters.
+func TestNotificationChannelAccepts(t *testing.T) {
+	channel := &NotificationChannel{
+		Enabled: true,
+		Level:   EvWarning,
+	}
+	require.False(t, channel.Accepts(EvInfo, nil))
+	require.True(t, channel.Accepts(EvWarning, nil))
+	require.True(t, channel.Accepts(EvError, &Relations{DaemonID: 1}))
+
+	channel.DaemonIDs = []int64{1, 2}
+	channel.MachineIDs = []int64{3}
+	require.True(t, channel.Accepts(EvError, &Relations{DaemonID: 2, MachineID: 3}))
+	require.False(t, channel.Accepts(EvError, &Relations{DaemonID: 4, MachineID: 3}))
+	require.False(t, channel.Accepts(EvError, &Relations{DaemonID: 1, MachineID: 4}))
+	require.False(t, channel.Accepts(EvError, nil))
+
+	channel.Enabled = false
+	require.False(t, channel.Accepts(EvError, &Relations{DaemonID: 2, MachineID: 3}))
+}
+
+// Test that the notification channels can be added, updated, fetched and
+// deleted.
+func TestAddUpdateGetDeleteNotificationChannel(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	channel := &NotificationChannel{
+		Name:         "chat",
+		Type:         NotificationChannelTypeWebhook,
+		Enabled:      true,
+		URL:          "https://chat.example.org/hooks/1",
+		BodyTemplate: `{"text": {{ json .Text }}}`,
+		Level:        EvWarning,
+		DaemonIDs:    []int64{1, 2},
+		MaxRetries:   3,
+	}
+	conflict, err := AddNotificationChannel(db, channel)
+	require.NoError(t, err)
+	require.False(t, conflict)
+	require.NotZero(t, channel.ID)
+
+	// The channel name must be unique.
+	conflict, err = AddNotificationChannel(db, &NotificationChannel{
+		Name: "chat",
+		Type: NotificationChannelTypeWebhook,
+		URL:  "https://chat.example.org/hooks/2",
+	})
+	require.Error(t, err)
+	require.True(t, conflict)
+
+	disabled := &NotificationChannel{
+		Name: "tickets",
+		Type: NotificationChannelTypeWebhook,
+		URL:  "https://tickets.example.org/api",
+	}
+	_, err = AddNotificationChannel(db, disabled)
+	require.NoError(t, err)
+
+	returned, err := GetNotificationChannel(db, channel.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returned)
+	require.Equal(t, "chat", returned.Name)
+	require.Equal(t, NotificationChannelTypeWebhook, returned.Type)
+	require.True(t, returned.Enabled)
+	require.Equal(t, `{"text": {{ json .Text }}}`, returned.BodyTemplate)
+	require.Equal(t, EvWarning, returned.Level)
+	require.Equal(t, []int64{1, 2}, returned.DaemonIDs)
+	require.Empty(t, returned.MachineIDs)
+	require.EqualValues(t, 3, returned.MaxRetries)
+	require.False(t, returned.CreatedAt.IsZero())
+
+	channels, err := GetAllNotificationChannels(db)
+	require.NoError(t, err)
+	require.Len(t, channels, 2)
+
+	channels, err = GetEnabledNotificationChannels(db)
+	require.NoError(t, err)
+	require.Len(t, channels, 1)
+	require.Equal(t, channel.ID, channels[0].ID)
+
+	returned.Enabled = false
+	returned.DaemonIDs = nil
+	conflict, err = UpdateNotificationChannel(db, returned)
+	require.NoError(t, err)
+	require.False(t, conflict)
+
+	channels, err = GetEnabledNotificationChannels(db)
+	require.NoError(t, err)
+	require.Empty(t, channels)
+
+	returned.Name = "tickets"
+	conflict, err = UpdateNotificationChannel(db, returned)
+	require.Error(t, err)
+	require.True(t, conflict)
+
+	err = DeleteNotificationChannel(db, channel.ID)
+	require.NoError(t, err)
+	returned, err = GetNotificationChannel(db, channel.ID)
+	require.NoError(t, err)
+	require.Nil(t, returned)
+
+	err = DeleteNotificationChannel(db, channel.ID)
+	require.ErrorIs(t, err, ErrNotExists)
+
+	_, err = UpdateNotificationChannel(db, &NotificationChannel{ID: channel.ID, Name: "foo", Type: NotificationChannelTypeWebhook})
+	require.ErrorIs(t, err, ErrNotExists)
+}
@@ -0,0 +1,141 @@
+package dbmodel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// Permission that can be granted to a custom group of users.
+type Permission string
+
+// Permissions supported by the server. The built-in groups (super-admin
+// and admin) don't use them. Their access rules are hard-coded.
+const (
+	// Grants read-only access to the monitored system, i.e., the
+	// machines, apps, subnets, host reservations, leases, events etc.
+	PermissionView Permission = "view"
+	// Grants the right to create, update and delete host reservations.
+	PermissionManageHosts Permission = "manage-hosts"
+	// Grants the right to create, update and delete subnets, shared
+	// networks and client classes, to edit the global DHCP parameters, and
+	// to roll back the daemons' configurations.
+	PermissionManageSubnets Permission = "manage-subnets"
+	// Grants the right to authorize, modify and remove machines and
+	// their apps, and to control the HA state of the Kea servers.
+	PermissionManageMachines Permission = "manage-machines"
+	// Grants the right to add, update and delete the leases in the
+	// Kea DHCP servers.
+	PermissionManageLeases Permission = "manage-leases"
+)
+
+// Returns all supported permissions.
+func GetAllPermissions() []Permission {
+	return []Permission{
+		PermissionView,
+		PermissionManageHosts,
+		PermissionManageSubnets,
+		PermissionManageMachines,
+		PermissionManageLeases,
+	}
+}
+
+// Checks if the permission is one of the supported permissions.
+func (p Permission) IsValid() bool {
+	for _, permission := range GetAllPermissions() {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks if the permission implies the other permission. Each permission
+// implies itself. Any of the management permissions implies the view
+// permission because it is impossible to manage the objects without
+// seeing them.
+func (p Permission) Implies(other Permission) bool {
+	return p == other || other == PermissionView
+}
+
+// Represents a permission granted to a group of users. The permission can
+// be optionally limited to a specific app, daemon or subnet. The zero
+// value of the AppID, DaemonID or SubnetID means that the permission is
+// not limited by the respective object. If more than one of these values
+// is non-zero the object must match all of them.
+type SystemGroupPermission struct {
+	ID         int64
+	GroupID    int
+	Permission Permission
+	AppID      int64
+	DaemonID   int64
+	SubnetID   int64
+}
+
+// Describes an object being accessed by a user. It is matched against the
+// scopes of the permissions granted to the user. The zero values denote
+// that the object is not associated with an app, daemon or subnet.
+type PermissionTarget struct {
+	AppID    int64
+	DaemonID int64
+	SubnetID int64
+}
+
+// Checks if the permission is limited to a specific app, daemon or subnet.
+func (p *SystemGroupPermission) IsScoped() bool {
+	return p.AppID != 0 || p.DaemonID != 0 || p.SubnetID != 0
+}
+
+// Checks if the permission scope covers the specified target.
+func (p *SystemGroupPermission) Covers(target PermissionTarget) bool {
+	switch {
+	case p.AppID != 0 && p.AppID != target.AppID:
+		return false
+	case p.DaemonID != 0 && p.DaemonID != target.DaemonID:
+		return false
+	case p.SubnetID != 0 && p.SubnetID != target.SubnetID:
+		return false
+	default:
+		return true
+	}
+}
+
+// Narrows down the query to the objects covered by any of the permission
+// scopes. The objects are covered when any of their local objects (e.g.,
+// the local hosts of a host) belongs to a daemon matching the scope. The
+// localTable is the table holding the local objects, the ownerColumn is
+// its column referencing the object, the ownerExpr is the object ID in the
+// query and the subnetExpr is the ID of the object's subnet in the query.
+// The nil scopes don't narrow down the query. The empty scopes exclude all
+// objects.
+func wherePermissionScopes(q *orm.Query, scopes []*SystemGroupPermission, localTable, ownerColumn, ownerExpr, subnetExpr string) *orm.Query {
+	if scopes == nil {
+		return q
+	}
+	if len(scopes) == 0 {
+		return q.Where("FALSE")
+	}
+	exists := fmt.Sprintf("EXISTS (SELECT 1 FROM %s AS scope_local JOIN daemon AS scope_daemon ON scope_local.daemon_id = scope_daemon.id WHERE scope_local.%s = %s",
+		localTable, ownerColumn, ownerExpr)
+	return q.WhereGroup(func(q *orm.Query) (*orm.Query, error) {
+		for _, scope := range scopes {
+			conditions := []string{exists}
+			var params []any
+			if scope.AppID != 0 {
+				conditions = append(conditions, "scope_daemon.app_id = ?")
+				params = append(params, scope.AppID)
+			}
+			if scope.DaemonID != 0 {
+				conditions = append(conditions, "scope_daemon.id = ?")
+				params = append(params, scope.DaemonID)
+			}
+			if scope.SubnetID != 0 {
+				conditions = append(conditions, subnetExpr+" = ?")
+				params = append(params, scope.SubnetID)
+			}
+			q = q.WhereOr(strings.Join(conditions, " AND ")+")", params...)
+		}
+		return q, nil
+	})
+}
@@ -0,0 +1,128 @@
+package dbmodel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test that the supported permissions are recognized as valid.
+func TestPermissionIsValid(t *testing.T) {
+	for _, p := range GetAllPermissions() {
+		require.True(t, p.IsValid())
+	}
+	require.False(t, Permission("").IsValid())
+	require.False(t, Permission("manage-users").IsValid())
+}
+
+// Test that the management permissions imply the view permission.
+func TestPermissionImplies(t *testing.T) {
+	require.True(t, PermissionView.Implies(PermissionView))
+	require.False(t, PermissionView.Implies(PermissionManageHosts))
+	require.True(t, PermissionManageHosts.Implies(PermissionView))
+	require.True(t, PermissionManageHosts.Implies(PermissionManageHosts))
+	require.False(t, PermissionManageHosts.Implies(PermissionManageSubnets))
+	require.True(t, PermissionManageLeases.Implies(PermissionView))
+	require.False(t, PermissionManageLeases.Implies(PermissionManageHosts))
+	require.True(t, PermissionManageMachines.Implies(PermissionView))
+}
+
+// Test that the permission scope is matched against the targets.
+func TestSystemGroupPermissionCovers(t *testing.T) {
+	unscoped := &SystemGroupPermission{Permission: PermissionView}
+	require.False(t, unscoped.IsScoped())
+	require.True(t, unscoped.Covers(PermissionTarget{}))
+	require.True(t, unscoped.Covers(PermissionTarget{AppID: 1, DaemonID: 2, SubnetID: 3}))
+
+	scoped := &SystemGroupPermission{Permission: PermissionView, AppID: 1, SubnetID: 3}
+	require.True(t, scoped.IsScoped())
+	require.True(t, scoped.Covers(PermissionTarget{AppID: 1, DaemonID: 2, SubnetID: 3}))
+	require.False(t, scoped.Covers(PermissionTarget{AppID: 1, DaemonID: 2}))
+	require.False(t, scoped.Covers(PermissionTarget{AppID: 2, DaemonID: 2, SubnetID: 3}))
+	require.False(t, scoped.Covers(PermissionTarget{}))
+}
+
+// Test that the user permissions are combined from all custom groups.
+func TestUserHasPermission(t *testing.T) {
+	user := &SystemUser{
+		Groups: []*SystemGroup{
+			{ID: AdminGroupID},
+		},
+	}
+	require.False(t, user.HasCustomGroups())
+	require.False(t, user.HasPermission(PermissionView))
+
+	user.Groups = append(user.Groups,
+		&SystemGroup{
+			ID: 100,
+			Permissions: []*SystemGroupPermission{
+				{Permission: PermissionView},
+			},
+		},
+		&SystemGroup{
+			ID: 101,
+			Permissions: []*SystemGroupPermission{
+				{Permission: PermissionManageSubnets, DaemonID: 1},
+			},
+		},
+	)
+	require.True(t, user.HasCustomGroups())
+	require.True(t, user.HasPermission(PermissionView, PermissionTarget{DaemonID: 5}))
+	require.True(t, user.HasPermission(PermissionManageSubnets))
+	require.True(t, user.HasPermission(PermissionManageSubnets, PermissionTarget{DaemonID: 1}))
+	require.False(t, user.HasPermission(PermissionManageSubnets, PermissionTarget{DaemonID: 1}, PermissionTarget{DaemonID: 2}))
+	require.False(t, user.HasPermission(PermissionManageHosts))
+}
+
+// Test that the unscoped permissions are distinguished from the scoped ones.
+func TestUserHasUnscopedPermission(t *testing.T) {
+	user := &SystemUser{
+		Groups: []*SystemGroup{
+			{
+				ID: 100,
+				Permissions: []*SystemGroupPermission{
+					{Permission: PermissionView, SubnetID: 3},
+					{Permission: PermissionManageHosts},
+				},
+			},
+		},
+	}
+	require.True(t, user.HasPermission(PermissionView))
+	require.True(t, user.HasUnscopedPermission(PermissionManageHosts))
+	// The unscoped management permission implies the unscoped view.
+	require.True(t, user.HasUnscopedPermission(PermissionView))
+	require.False(t, user.HasUnscopedPermission(PermissionManageSubnets))
+
+	user.Groups[0].Permissions[1].AppID = 1
+	require.False(t, user.HasUnscopedPermission(PermissionView))
+	require.False(t, user.HasUnscopedPermission(PermissionManageHosts))
+}
+
+// Test getting the scopes of the permissions implying the view permission.
+func TestUserGetPermissionScopes(t *testing.T) {
+	user := &SystemUser{
+		Groups: []*SystemGroup{
+			{
+				ID: 100,
+				Permissions: []*SystemGroupPermission{
+					{Permission: PermissionView, SubnetID: 3},
+					{Permission: PermissionManageHosts, DaemonID: 2},
+				},
+			},
+		},
+	}
+	scopes := user.GetPermissionScopes(PermissionView)
+	require.Len(t, scopes, 2)
+	require.EqualValues(t, 3, scopes[0].SubnetID)
+	require.EqualValues(t, 2, scopes[1].DaemonID)
+
+	scopes = user.GetPermissionScopes(PermissionManageHosts)
+	require.Len(t, scopes, 1)
+	require.EqualValues(t, 2, scopes[0].DaemonID)
+
+	// The scopes of the permission that is not granted are empty but
+	// not nil, so they exclude all objects.
+	scopes = user.GetPermissionScopes(PermissionManageSubnets)
+	require.NotNil(t, scopes)
+	require.Empty(t, scopes)
+}
@@ -4,6 +4,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/go-pg/pg/v10"
 	errors "github.com/pkg/errors"
 	keaconfig "isc.org/stork/appcfg/kea"
 	dhcpmodel "isc.org/stork/datamodel/dhcp"
@@ -74,6 +75,10 @@ type PrefixPool struct {
 	LocalSubnet       *LocalSubnet `pg:"rel:has-one"`
 
 	KeaParameters *keaconfig.PoolParameters
+
+	PdUtilization    int16
+	StatsCollectedAt time.Time
+	PdExhaustionAt   time.Time
 }
 
 // Returns a pointer to a structure holding the delegated prefix data.
@@ -237,3 +242,67 @@ func DeletePrefixPool(db *dbops.PgDB, poolID int64) error {
 	}
 	return err
 }
+
+// Fetches the prefix pools of the local subnets served by the daemons of
+// the app. The pools are ordered by ID and include their local subnets.
+func GetAppPrefixPools(dbi dbops.DBI, appID int64) ([]*PrefixPool, error) {
+	pools := []*PrefixPool{}
+	err := dbi.Model(&pools).
+		Relation("LocalSubnet").
+		Where("prefix_pool.local_subnet_id IN (SELECT ls.id FROM local_subnet AS ls INNER JOIN daemon AS d ON ls.daemon_id = d.id WHERE d.app_id = ?)", appID).
+		OrderExpr("prefix_pool.id ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, errors.Wrapf(err, "problem getting prefix pools for app %d", appID)
+	}
+	return pools, nil
+}
+
+// Fetches the prefix pools having the delegated prefix utilization with
+// their local subnets, subnets and daemons.
+func GetPrefixPoolsWithUtilization(dbi dbops.DBI) ([]*PrefixPool, error) {
+	pools := []*PrefixPool{}
+	err := dbi.Model(&pools).
+		Relation("LocalSubnet.Subnet").
+		Relation("LocalSubnet.Daemon.App").
+		Where("prefix_pool.stats_collected_at IS NOT NULL").
+		OrderExpr("prefix_pool.id ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, errors.Wrap(err, "problem getting prefix pools with utilization")
+	}
+	return pools, nil
+}
+
+// Updates the delegated prefix utilization of the prefix pool. The
+// utilization is expressed in permille.
+func (pp *PrefixPool) UpdateUtilization(dbi dbops.DBI, pdUtilization int16, collectedAt time.Time) error {
+	pp.PdUtilization = pdUtilization
+	pp.StatsCollectedAt = collectedAt
+	result, err := dbi.Model(pp).
+		Column("pd_utilization", "stats_collected_at").
+		WherePK().
+		Update()
+	if err != nil {
+		err = errors.Wrapf(err, "problem updating utilization in the prefix pool with ID %d", pp.ID)
+	} else if result.RowsAffected() <= 0 {
+		err = errors.Wrapf(ErrNotExists, "prefix pool with ID %d does not exist", pp.ID)
+	}
+	return err
+}
+
+// Updates the forecast time of the delegated prefix exhaustion in the
+// prefix pool. The zero time indicates that no exhaustion is forecast.
+func (pp *PrefixPool) UpdateExhaustionForecast(dbi dbops.DBI, pdExhaustionAt time.Time) error {
+	pp.PdExhaustionAt = pdExhaustionAt
+	result, err := dbi.Model(pp).
+		Column("pd_exhaustion_at").
+		WherePK().
+		Update()
+	if err != nil {
+		err = errors.Wrapf(err, "problem updating exhaustion forecast in the prefix pool with ID %d", pp.ID)
+	} else if result.RowsAffected() <= 0 {
+		err = errors.Wrapf(ErrNotExists, "prefix pool with ID %d does not exist", pp.ID)
+	}
+	return err
+}
@@ -171,6 +171,84 @@ func TestAddDeletePrefixPool(t *testing.T) {
 	require.Empty(t, returnedSubnet.LocalSubnets[0].PrefixPools)
 }
 
+// Test that the delegated prefix utilization and the exhaustion forecast
+// are stored in the prefix pool and the pools are fetched with their
+// local subnets.
+func TestUpdatePrefixPoolUtilization(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	apps := addTestSubnetApps(t, db)
+
+	subnet := Subnet{
+		Prefix: "2001:db8:1::/64",
+		LocalSubnets: []*LocalSubnet{
+			{
+				DaemonID:      apps[0].Daemons[1].ID,
+				LocalSubnetID: 7,
+			},
+		},
+	}
+	err := AddSubnet(db, &subnet)
+	require.NoError(t, err)
+	err = AddLocalSubnets(db, &subnet)
+	require.NoError(t, err)
+
+	for _, prefix := range []string{"2001:db8:1:1::/80", "2001:db8:1:2::/80"} {
+		pool := &PrefixPool{
+			Prefix:        prefix,
+			DelegatedLen:  96,
+			LocalSubnetID: subnet.LocalSubnets[0].ID,
+		}
+		err = AddPrefixPool(db, pool)
+		require.NoError(t, err)
+	}
+
+	// The pools of the other app are not returned.
+	pools, err := GetAppPrefixPools(db, apps[1].ID)
+	require.NoError(t, err)
+	require.Empty(t, pools)
+
+	pools, err = GetAppPrefixPools(db, apps[0].ID)
+	require.NoError(t, err)
+	require.Len(t, pools, 2)
+	require.Equal(t, "2001:db8:1:1::/80", pools[0].Prefix)
+	require.NotNil(t, pools[0].LocalSubnet)
+	require.EqualValues(t, 7, pools[0].LocalSubnet.LocalSubnetID)
+
+	// Only the pools with the utilization are fetched for forecasting.
+	pools, err = GetPrefixPoolsWithUtilization(db)
+	require.NoError(t, err)
+	require.Empty(t, pools)
+
+	collectedAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	pools, err = GetAppPrefixPools(db, apps[0].ID)
+	require.NoError(t, err)
+	pool := pools[1]
+	err = pool.UpdateUtilization(db, 420, collectedAt)
+	require.NoError(t, err)
+	err = pool.UpdateExhaustionForecast(db, collectedAt.Add(24*time.Hour))
+	require.NoError(t, err)
+
+	pools, err = GetPrefixPoolsWithUtilization(db)
+	require.NoError(t, err)
+	require.Len(t, pools, 1)
+	require.Equal(t, pool.ID, pools[0].ID)
+	require.EqualValues(t, 420, pools[0].PdUtilization)
+	require.Equal(t, collectedAt, pools[0].StatsCollectedAt)
+	require.Equal(t, collectedAt.Add(24*time.Hour), pools[0].PdExhaustionAt)
+	require.NotNil(t, pools[0].LocalSubnet)
+	require.NotNil(t, pools[0].LocalSubnet.Subnet)
+	require.Equal(t, "2001:db8:1::/64", pools[0].LocalSubnet.Subnet.Prefix)
+	require.NotNil(t, pools[0].LocalSubnet.Daemon)
+	require.NotNil(t, pools[0].LocalSubnet.Daemon.App)
+
+	// Updating the non-existing pool fails.
+	pool = &PrefixPool{ID: pool.ID + 100}
+	require.ErrorIs(t, pool.UpdateUtilization(db, 100, collectedAt), ErrNotExists)
+	require.ErrorIs(t, pool.UpdateExhaustionForecast(db, collectedAt), ErrNotExists)
+}
+
 // Test the implementation of the dhcpmodel.PrefixPoolAccessor interface
 // (GetModel() function).
 func TestPrefixPoolGetModel(t *testing.T) {
@@ -91,6 +91,31 @@ func InitializeSettings(db *pg.DB, initialPullerInterval int64) error {
 			ValType: SettingValTypeBool,
 			Value:   "true",
 		},
+		{
+			Name:    "utilization_warning_threshold", // in percent
+			ValType: SettingValTypeInt,
+			Value:   "80",
+		},
+		{
+			Name:    "utilization_error_threshold", // in percent
+			ValType: SettingValTypeInt,
+			Value:   "90",
+		},
+		{
+			Name:    "utilization_alert_hysteresis", // in percent
+			ValType: SettingValTypeInt,
+			Value:   "5",
+		},
+		{
+			Name:    "exhaustion_forecast_window", // in days
+			ValType: SettingValTypeInt,
+			Value:   "30",
+		},
+		{
+			Name:    "zone_serial_lag_threshold", // in minutes
+			ValType: SettingValTypeInt,
+			Value:   "60",
+		},
 	}
 
 	// Check if there are new settings vs existing ones. Add new ones to DB.
@@ -53,6 +53,26 @@ func TestInitializeSettings(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, boolVal)
 
+	val, err = GetSettingInt(db, "utilization_warning_threshold")
+	require.NoError(t, err)
+	require.EqualValues(t, 80, val)
+
+	val, err = GetSettingInt(db, "utilization_error_threshold")
+	require.NoError(t, err)
+	require.EqualValues(t, 90, val)
+
+	val, err = GetSettingInt(db, "utilization_alert_hysteresis")
+	require.NoError(t, err)
+	require.EqualValues(t, 5, val)
+
+	val, err = GetSettingInt(db, "exhaustion_forecast_window")
+	require.NoError(t, err)
+	require.EqualValues(t, 30, val)
+
+	val, err = GetSettingInt(db, "zone_serial_lag_threshold")
+	require.NoError(t, err)
+	require.EqualValues(t, 60, val)
+
 	// change the settings
 	err = SetSettingInt(db, "kea_stats_puller_interval", 123)
 	require.NoError(t, err)
@@ -33,6 +33,8 @@ type SharedNetwork struct {
 	PdUtilization    int16
 	Stats            SubnetStats
 	StatsCollectedAt time.Time
+	AddrExhaustionAt time.Time
+	PdExhaustionAt   time.Time
 }
 
 // This structure holds shared network information retrieved from an app.
@@ -460,6 +462,43 @@ func UpdateStatisticsInSharedNetwork(dbi dbops.DBI, sharedNetworkID int64, stati
 	return err
 }
 
+// Fetches the shared networks with the earliest forecast exhaustion of the
+// addresses or delegated prefixes. The shared networks without the
+// forecast are skipped. The subnets are not fetched.
+func GetSharedNetworksByExhaustionForecast(dbi dbops.DBI, limit int) ([]SharedNetwork, error) {
+	networks := []SharedNetwork{}
+	err := dbi.Model(&networks).
+		Where("addr_exhaustion_at IS NOT NULL OR pd_exhaustion_at IS NOT NULL").
+		OrderExpr("LEAST(addr_exhaustion_at, pd_exhaustion_at) ASC, id ASC").
+		Limit(limit).
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrap(err, "problem getting shared networks by exhaustion forecast")
+	}
+	return networks, nil
+}
+
+// Updates the forecast time of the address and delegated prefix exhaustion
+// in the shared network. The zero time indicates that no exhaustion is
+// forecast.
+func UpdateExhaustionForecastInSharedNetwork(dbi dbops.DBI, sharedNetworkID int64, addrExhaustionAt, pdExhaustionAt time.Time) error {
+	net := &SharedNetwork{
+		ID:               sharedNetworkID,
+		AddrExhaustionAt: addrExhaustionAt,
+		PdExhaustionAt:   pdExhaustionAt,
+	}
+	result, err := dbi.Model(net).
+		Column("addr_exhaustion_at", "pd_exhaustion_at").
+		WherePK().
+		Update()
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "problem updating exhaustion forecast in the shared network: %d", sharedNetworkID)
+	} else if result.RowsAffected() <= 0 {
+		err = pkgerrors.Wrapf(ErrNotExists, "shared network with ID %d does not exist", sharedNetworkID)
+	}
+	return err
+}
+
 // Deletes shared networks which include no subnets. Returns deleted shared networks
 // count and an error.
 func DeleteEmptySharedNetworks(dbi dbops.DBI) (int64, error) {
@@ -526,6 +526,39 @@ func TestUpdateSharedNetwork(t *testing.T) {
 	require.Equal(t, createdAt, returned.CreatedAt)
 }
 
+// Test that the exhaustion forecast is updated in the shared network and
+// the shared networks are fetched by the earliest forecast.
+func TestUpdateExhaustionForecastInSharedNetwork(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	networks := []*SharedNetwork{
+		{Name: "frog", Family: 4},
+		{Name: "mouse", Family: 4},
+	}
+	for _, network := range networks {
+		err := AddSharedNetwork(db, network)
+		require.NoError(t, err)
+	}
+
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	err := UpdateExhaustionForecastInSharedNetwork(db, networks[1].ID, time.Time{}, now.Add(24*time.Hour))
+	require.NoError(t, err)
+
+	returned, err := GetSharedNetwork(db, networks[1].ID)
+	require.NoError(t, err)
+	require.Zero(t, returned.AddrExhaustionAt)
+	require.Equal(t, now.Add(24*time.Hour), returned.PdExhaustionAt)
+
+	exhausting, err := GetSharedNetworksByExhaustionForecast(db, 5)
+	require.NoError(t, err)
+	require.Len(t, exhausting, 1)
+	require.Equal(t, "mouse", exhausting[0].Name)
+
+	err = UpdateExhaustionForecastInSharedNetwork(db, networks[1].ID+100, now, now)
+	require.ErrorIs(t, err, ErrNotExists)
+}
+
 // Tests that the shared network can be deleted.
 func TestDeleteSharedNetwork(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
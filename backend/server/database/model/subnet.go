@@ -210,6 +210,8 @@ type Subnet struct {
 	PdUtilization    int16
 	Stats            SubnetStats
 	StatsCollectedAt time.Time
+	AddrExhaustionAt time.Time
+	PdExhaustionAt   time.Time
 }
 
 // Returns local subnet id for the specified daemon.
@@ -626,6 +628,9 @@ type SubnetsByPageFilters struct {
 	LocalSubnetID *int64
 	Family        *int64
 	Text          *string
+	// If not nil, only the subnets covered by any of these permission
+	// scopes are returned. The empty scopes exclude all subnets.
+	PermissionScopes []*SystemGroupPermission
 }
 
 // Shorthand to set the IPv4 family.
@@ -694,6 +699,9 @@ func GetSubnetsByPage(dbi dbops.DBI, offset, limit int64, filters *SubnetsByPage
 		q = q.Where("ls.local_subnet_id = ?", *filters.LocalSubnetID)
 	}
 
+	// Filter by the permission scopes.
+	q = wherePermissionScopes(q, filters.PermissionScopes, "local_subnet", "subnet_id", "subnet.id", "subnet.id")
+
 	// Quick filtering by subnet prefix, pool ranges or shared network name.
 	if filters.Text != nil {
 		// The combination of the concat and host functions reconstruct the textual
@@ -1013,6 +1021,40 @@ func (s *Subnet) UpdateStatistics(dbi dbops.DBI, statistics utilizationStats) er
 	return err
 }
 
+// Fetches the subnets with the earliest forecast exhaustion of the
+// addresses or delegated prefixes. The subnets without the forecast are
+// skipped. The local subnets are not fetched.
+func GetSubnetsByExhaustionForecast(dbi dbops.DBI, limit int) ([]Subnet, error) {
+	subnets := []Subnet{}
+	err := dbi.Model(&subnets).
+		Relation("SharedNetwork").
+		Where("subnet.addr_exhaustion_at IS NOT NULL OR subnet.pd_exhaustion_at IS NOT NULL").
+		OrderExpr("LEAST(subnet.addr_exhaustion_at, subnet.pd_exhaustion_at) ASC, subnet.id ASC").
+		Limit(limit).
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrap(err, "problem getting subnets by exhaustion forecast")
+	}
+	return subnets, nil
+}
+
+// Updates the forecast time of the address and delegated prefix exhaustion
+// in the subnet. The zero time indicates that no exhaustion is forecast.
+func (s *Subnet) UpdateExhaustionForecast(dbi dbops.DBI, addrExhaustionAt, pdExhaustionAt time.Time) error {
+	s.AddrExhaustionAt = addrExhaustionAt
+	s.PdExhaustionAt = pdExhaustionAt
+	result, err := dbi.Model(s).
+		Column("addr_exhaustion_at", "pd_exhaustion_at").
+		WherePK().
+		Update()
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "problem updating exhaustion forecast in the subnet: %d", s.ID)
+	} else if result.RowsAffected() <= 0 {
+		err = pkgerrors.Wrapf(ErrNotExists, "subnet with ID %d does not exist", s.ID)
+	}
+	return err
+}
+
 // Deletes subnets which are not associated with any apps. Returns deleted subnet
 // count and an error.
 func DeleteOrphanedSubnets(dbi dbops.DBI) (int64, error) {
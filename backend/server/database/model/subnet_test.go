@@ -696,6 +696,86 @@ func TestGetSubnetsByPage(t *testing.T) {
 	require.Empty(t, returned)
 }
 
+// Test that page of the subnets can be filtered by the permission scopes
+// and the paging applies to the subnets covered by the scopes.
+func TestGetSubnetsByPagePermissionScopes(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	apps := addTestApps(t, db)
+
+	// The first two subnets belong to the daemon of the first app and
+	// the last one belongs to the daemon of the second app.
+	subnets := []Subnet{
+		{
+			Prefix: "192.0.2.0/24",
+			LocalSubnets: []*LocalSubnet{
+				{DaemonID: apps[0].Daemons[0].ID},
+			},
+		},
+		{
+			Prefix: "192.0.3.0/24",
+			LocalSubnets: []*LocalSubnet{
+				{DaemonID: apps[0].Daemons[0].ID},
+			},
+		},
+		{
+			Prefix: "192.0.4.0/24",
+			LocalSubnets: []*LocalSubnet{
+				{DaemonID: apps[1].Daemons[0].ID},
+			},
+		},
+	}
+	for i := range subnets {
+		err := AddSubnet(db, &subnets[i])
+		require.NoError(t, err)
+		require.NotZero(t, subnets[i].ID)
+
+		err = AddLocalSubnets(db, &subnets[i])
+		require.NoError(t, err)
+	}
+
+	// The daemon scope covers the first two subnets.
+	filters := &SubnetsByPageFilters{
+		PermissionScopes: []*SystemGroupPermission{
+			{Permission: PermissionView, DaemonID: apps[0].Daemons[0].ID},
+		},
+	}
+	returned, count, err := GetSubnetsByPage(db, 1, 1, filters, "id", SortDirAsc)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, count)
+	require.Len(t, returned, 1)
+	require.Equal(t, subnets[1].ID, returned[0].ID)
+
+	// The subnets covered by any of the scopes are returned.
+	filters.PermissionScopes = []*SystemGroupPermission{
+		{Permission: PermissionView, SubnetID: subnets[0].ID},
+		{Permission: PermissionManageSubnets, AppID: apps[1].ID},
+	}
+	returned, count, err = GetSubnetsByPage(db, 0, 10, filters, "id", SortDirAsc)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, count)
+	require.Len(t, returned, 2)
+	require.Equal(t, subnets[0].ID, returned[0].ID)
+	require.Equal(t, subnets[2].ID, returned[1].ID)
+
+	// The scope must match both the app and the subnet.
+	filters.PermissionScopes = []*SystemGroupPermission{
+		{Permission: PermissionView, AppID: apps[1].ID, SubnetID: subnets[0].ID},
+	}
+	returned, count, err = GetSubnetsByPage(db, 0, 10, filters, "id", SortDirAsc)
+	require.NoError(t, err)
+	require.Zero(t, count)
+	require.Empty(t, returned)
+
+	// The empty scopes exclude all subnets.
+	filters.PermissionScopes = []*SystemGroupPermission{}
+	returned, count, err = GetSubnetsByPage(db, 0, 10, filters, "id", SortDirAsc)
+	require.NoError(t, err)
+	require.Zero(t, count)
+	require.Empty(t, returned)
+}
+
 // Test that the subnet can be fetched by local ID and app ID.
 func TestGetAppLocalSubnets(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
@@ -1053,6 +1133,56 @@ func TestUpdateUtilization(t *testing.T) {
 	require.InDelta(t, time.Now().UTC().Unix(), returnedSubnet2.StatsCollectedAt.Unix(), 10.0)
 }
 
+// Test that the exhaustion forecast is updated in the subnet and the
+// subnets are fetched by the earliest forecast.
+func TestUpdateExhaustionForecast(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	subnets := []*Subnet{
+		{Prefix: "192.0.2.0/24"},
+		{Prefix: "192.0.3.0/24"},
+		{Prefix: "2001:db8:1::/64"},
+	}
+	for _, subnet := range subnets {
+		err := AddSubnet(db, subnet)
+		require.NoError(t, err)
+	}
+
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	err := subnets[0].UpdateExhaustionForecast(db, now.Add(48*time.Hour), time.Time{})
+	require.NoError(t, err)
+	err = subnets[2].UpdateExhaustionForecast(db, now.Add(72*time.Hour), now.Add(24*time.Hour))
+	require.NoError(t, err)
+
+	returned, err := GetSubnet(db, subnets[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(48*time.Hour), returned.AddrExhaustionAt)
+	require.Zero(t, returned.PdExhaustionAt)
+
+	exhausting, err := GetSubnetsByExhaustionForecast(db, 5)
+	require.NoError(t, err)
+	require.Len(t, exhausting, 2)
+	require.Equal(t, subnets[2].ID, exhausting[0].ID)
+	require.Equal(t, subnets[0].ID, exhausting[1].ID)
+
+	exhausting, err = GetSubnetsByExhaustionForecast(db, 1)
+	require.NoError(t, err)
+	require.Len(t, exhausting, 1)
+
+	// Clear the forecast.
+	err = subnets[0].UpdateExhaustionForecast(db, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	returned, err = GetSubnet(db, subnets[0].ID)
+	require.NoError(t, err)
+	require.Zero(t, returned.AddrExhaustionAt)
+
+	// Non-existing subnet.
+	subnet := &Subnet{ID: subnets[2].ID + 100}
+	err = subnet.UpdateExhaustionForecast(db, now, now)
+	require.ErrorIs(t, err, ErrNotExists)
+}
+
 // Test deleting subnets not assigned to any apps.
 func TestDeleteOrphanedSubnets(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
@@ -378,3 +378,112 @@ func (user *SystemUser) InGroup(group *SystemGroup) bool {
 	}
 	return false
 }
+
+// Checks if the user has a custom (not built-in) group.
+func (user *SystemUser) HasCustomGroups() bool {
+	for _, g := range user.Groups {
+		if !g.IsBuiltIn() {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks if any of the custom groups the user belongs to grants the
+// specified permission. If the targets are specified, the permission must
+// cover each of them. Otherwise, any granted permission, including the
+// scoped ones, is sufficient. The permissions must be populated in the
+// user's groups before calling this function. The hard-coded rights of
+// the built-in groups are not taken into account.
+func (user *SystemUser) HasPermission(permission Permission, targets ...PermissionTarget) bool {
+	var granted []*SystemGroupPermission
+	for _, g := range user.Groups {
+		for _, p := range g.Permissions {
+			if p.Permission.Implies(permission) {
+				granted = append(granted, p)
+			}
+		}
+	}
+	if len(granted) == 0 {
+		return false
+	}
+	for _, target := range targets {
+		covered := false
+		for _, p := range granted {
+			if p.Covers(target) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// Checks if any of the custom groups the user belongs to grants the
+// specified permission without limiting it to specific apps, daemons or
+// subnets. The permissions must be populated in the user's groups before
+// calling this function.
+func (user *SystemUser) HasUnscopedPermission(permission Permission) bool {
+	for _, g := range user.Groups {
+		for _, p := range g.Permissions {
+			if p.Permission.Implies(permission) && !p.IsScoped() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the scopes of the custom group permissions implying the specified
+// permission. The returned slice is empty, but not nil, if the permission
+// is not granted. It is meant to be used for the users whose permission is
+// scoped. The permissions must be populated in the user's groups before
+// calling this function.
+func (user *SystemUser) GetPermissionScopes(permission Permission) []*SystemGroupPermission {
+	scopes := []*SystemGroupPermission{}
+	for _, g := range user.Groups {
+		for _, p := range g.Permissions {
+			if p.Permission.Implies(permission) {
+				scopes = append(scopes, p)
+			}
+		}
+	}
+	return scopes
+}
+
+// Fetches the permissions of the user's custom groups from the database
+// and assigns them to the respective groups. The groups are matched by ID.
+// It is a no-op when the user belongs to the built-in groups only.
+func PopulateUserPermissions(dbi dbops.DBI, user *SystemUser) error {
+	var groupIDs []int
+	for _, g := range user.Groups {
+		if !g.IsBuiltIn() {
+			groupIDs = append(groupIDs, g.ID)
+		}
+	}
+	if len(groupIDs) == 0 {
+		return nil
+	}
+
+	var permissions []*SystemGroupPermission
+	err := dbi.Model(&permissions).
+		WhereIn("group_id IN (?)", groupIDs).
+		OrderExpr("id ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return pkgerrors.Wrapf(err, "problem fetching permissions of user %s", user.Identity())
+	}
+
+	for _, g := range user.Groups {
+		g.Permissions = nil
+		for _, p := range permissions {
+			if p.GroupID == g.ID {
+				g.Permissions = append(g.Permissions, p)
+			}
+		}
+	}
+	return nil
+}
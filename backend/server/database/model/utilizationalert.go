@@ -0,0 +1,193 @@
+package dbmodel
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	pkgerrors "github.com/pkg/errors"
+	dbops "isc.org/stork/server/database"
+)
+
+// Kind of the utilization monitored by the utilization alerts.
+type UtilizationAlertKind string
+
+// Supported kinds of the utilization alerts. The exhaustion alerts are
+// raised when the exhaustion is forecast within the configured window.
+const (
+	UtilizationAlertKindAddress                   UtilizationAlertKind = "address"
+	UtilizationAlertKindDelegatedPrefix           UtilizationAlertKind = "delegated-prefix"
+	UtilizationAlertKindAddressExhaustion         UtilizationAlertKind = "address-exhaustion"
+	UtilizationAlertKindDelegatedPrefixExhaustion UtilizationAlertKind = "delegated-prefix-exhaustion"
+)
+
+// A structure reflecting the utilization_alert_rule SQL table. It overrides
+// the global utilization thresholds for a subnet or a shared network. The
+// rule specifies either the subnet or the shared network. The thresholds
+// are expressed in percent. A zero threshold disables the alerts of the
+// respective level. The disabled rule suppresses all alerts for the
+// subnet or the shared network.
+type UtilizationAlertRule struct {
+	ID               int64
+	SubnetID         int64
+	Subnet           *Subnet `pg:"rel:has-one"`
+	SharedNetworkID  int64
+	SharedNetwork    *SharedNetwork `pg:"rel:has-one"`
+	Enabled          bool           `pg:",use_zero"`
+	WarningThreshold int16          `pg:",use_zero"`
+	ErrorThreshold   int16          `pg:",use_zero"`
+}
+
+// A structure reflecting the utilization_alert SQL table. It describes an
+// alert currently raised for the subnet or the shared network because its
+// address or delegated prefix utilization has exceeded the threshold. The
+// exhaustion alerts are also raised for the prefix pools. The utilization
+// is expressed in permille, like in the subnets.
+type UtilizationAlert struct {
+	ID              int64
+	CreatedAt       time.Time
+	SubnetID        int64
+	Subnet          *Subnet `pg:"rel:has-one"`
+	SharedNetworkID int64
+	SharedNetwork   *SharedNetwork `pg:"rel:has-one"`
+	PrefixPoolID    int64
+	PrefixPool      *PrefixPool `pg:"rel:has-one"`
+	Kind            UtilizationAlertKind
+	Level           EventLevel `pg:",use_zero"`
+	Utilization     int16      `pg:",use_zero"`
+}
+
+// Inserts the utilization alert rule or replaces the existing rule of the
+// same subnet or shared network.
+func SetUtilizationAlertRule(dbi dbops.DBI, rule *UtilizationAlertRule) error {
+	q := dbi.Model(rule)
+	if rule.SubnetID != 0 {
+		q = q.OnConflict("(subnet_id) DO UPDATE")
+	} else {
+		q = q.OnConflict("(shared_network_id) DO UPDATE")
+	}
+	_, err := q.Set("enabled = EXCLUDED.enabled").
+		Set("warning_threshold = EXCLUDED.warning_threshold").
+		Set("error_threshold = EXCLUDED.error_threshold").
+		Returning("id").
+		Insert()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem setting utilization alert rule for subnet %d or shared network %d",
+			rule.SubnetID, rule.SharedNetworkID)
+	}
+	return nil
+}
+
+// Fetches all utilization alert rules with their subnets and shared
+// networks ordered by ID.
+func GetAllUtilizationAlertRules(dbi dbops.DBI) ([]UtilizationAlertRule, error) {
+	rules := []UtilizationAlertRule{}
+	err := dbi.Model(&rules).
+		Relation("Subnet").
+		Relation("SharedNetwork").
+		OrderExpr("utilization_alert_rule.id ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrap(err, "problem getting utilization alert rules")
+	}
+	return rules, nil
+}
+
+// Fetches the utilization alert rule of the subnet. It returns nil if the
+// subnet has no rule.
+func GetUtilizationAlertRuleBySubnetID(dbi dbops.DBI, subnetID int64) (*UtilizationAlertRule, error) {
+	return getUtilizationAlertRule(dbi, "utilization_alert_rule.subnet_id = ?", subnetID)
+}
+
+// Fetches the utilization alert rule of the shared network. It returns nil
+// if the shared network has no rule.
+func GetUtilizationAlertRuleBySharedNetworkID(dbi dbops.DBI, sharedNetworkID int64) (*UtilizationAlertRule, error) {
+	return getUtilizationAlertRule(dbi, "utilization_alert_rule.shared_network_id = ?", sharedNetworkID)
+}
+
+// Fetches the utilization alert rule matching the condition.
+func getUtilizationAlertRule(dbi dbops.DBI, condition string, id int64) (*UtilizationAlertRule, error) {
+	rule := &UtilizationAlertRule{}
+	err := dbi.Model(rule).
+		Relation("Subnet").
+		Relation("SharedNetwork").
+		Where(condition, id).
+		Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, pkgerrors.Wrapf(err, "problem getting utilization alert rule for ID %d", id)
+	}
+	return rule, nil
+}
+
+// Deletes the utilization alert rule of the subnet.
+func DeleteUtilizationAlertRuleBySubnetID(dbi dbops.DBI, subnetID int64) error {
+	result, err := dbi.Model(&UtilizationAlertRule{}).
+		Where("subnet_id = ?", subnetID).
+		Delete()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem deleting utilization alert rule for subnet %d", subnetID)
+	} else if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "utilization alert rule for subnet %d does not exist", subnetID)
+	}
+	return nil
+}
+
+// Deletes the utilization alert rule of the shared network.
+func DeleteUtilizationAlertRuleBySharedNetworkID(dbi dbops.DBI, sharedNetworkID int64) error {
+	result, err := dbi.Model(&UtilizationAlertRule{}).
+		Where("shared_network_id = ?", sharedNetworkID).
+		Delete()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem deleting utilization alert rule for shared network %d", sharedNetworkID)
+	} else if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "utilization alert rule for shared network %d does not exist", sharedNetworkID)
+	}
+	return nil
+}
+
+// Fetches all currently raised utilization alerts with their subnets,
+// shared networks and prefix pools. The prefix pools include their local
+// subnets and subnets. The alerts are ordered from the most severe.
+func GetAllUtilizationAlerts(dbi dbops.DBI) ([]UtilizationAlert, error) {
+	alerts := []UtilizationAlert{}
+	err := dbi.Model(&alerts).
+		Relation("Subnet").
+		Relation("SharedNetwork").
+		Relation("PrefixPool.LocalSubnet.Subnet").
+		OrderExpr("utilization_alert.level DESC").
+		OrderExpr("utilization_alert.utilization DESC").
+		OrderExpr("utilization_alert.id ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrap(err, "problem getting utilization alerts")
+	}
+	return alerts, nil
+}
+
+// Inserts a new utilization alert or updates the level and utilization of
+// the existing alert.
+func SetUtilizationAlert(dbi dbops.DBI, alert *UtilizationAlert) error {
+	var err error
+	if alert.ID == 0 {
+		_, err = dbi.Model(alert).Insert()
+	} else {
+		var result pg.Result
+		result, err = dbi.Model(alert).Column("level", "utilization").WherePK().Update()
+		if err == nil && result.RowsAffected() <= 0 {
+			err = pkgerrors.Wrapf(ErrNotExists, "utilization alert with ID %d does not exist", alert.ID)
+		}
+	}
+	return pkgerrors.Wrapf(err, "problem setting utilization alert for subnet %d, shared network %d or prefix pool %d",
+		alert.SubnetID, alert.SharedNetworkID, alert.PrefixPoolID)
+}
+
+// Deletes the utilization alert. It is called when the alert is cleared.
+func DeleteUtilizationAlert(dbi dbops.DBI, id int64) error {
+	_, err := dbi.Model(&UtilizationAlert{}).
+		Where("id = ?", id).
+		Delete()
+	return pkgerrors.Wrapf(err, "problem deleting utilization alert with ID %d", id)
+}
@@ -0,0 +1,178 @@
+package dbmodel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbtest "isc.org/stork/server/database/test"
+)
+
+// Test that the utilization alert rules can be set, fetched and deleted.
+func TestSetGetDeleteUtilizationAlertRule(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	sharedNetwork := &SharedNetwork{
+		Name:   "frog",
+		Family: 4,
+	}
+	err := AddSharedNetwork(db, sharedNetwork)
+	require.NoError(t, err)
+
+	subnet := &Subnet{
+		Prefix:          "192.0.2.0/24",
+		SharedNetworkID: sharedNetwork.ID,
+	}
+	err = AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	// Set the rules for the subnet and the shared network.
+	subnetRule := &UtilizationAlertRule{
+		SubnetID:         subnet.ID,
+		Enabled:          true,
+		WarningThreshold: 70,
+		ErrorThreshold:   80,
+	}
+	err = SetUtilizationAlertRule(db, subnetRule)
+	require.NoError(t, err)
+	require.NotZero(t, subnetRule.ID)
+
+	sharedNetworkRule := &UtilizationAlertRule{
+		SharedNetworkID:  sharedNetwork.ID,
+		Enabled:          false,
+		WarningThreshold: 50,
+		ErrorThreshold:   0,
+	}
+	err = SetUtilizationAlertRule(db, sharedNetworkRule)
+	require.NoError(t, err)
+	require.NotZero(t, sharedNetworkRule.ID)
+
+	// Replace the subnet rule.
+	err = SetUtilizationAlertRule(db, &UtilizationAlertRule{
+		SubnetID:         subnet.ID,
+		Enabled:          true,
+		WarningThreshold: 75,
+		ErrorThreshold:   95,
+	})
+	require.NoError(t, err)
+
+	rules, err := GetAllUtilizationAlertRules(db)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	require.Equal(t, subnet.ID, rules[0].SubnetID)
+	require.NotNil(t, rules[0].Subnet)
+	require.Equal(t, "192.0.2.0/24", rules[0].Subnet.Prefix)
+	require.EqualValues(t, 75, rules[0].WarningThreshold)
+	require.EqualValues(t, 95, rules[0].ErrorThreshold)
+	require.Equal(t, sharedNetwork.ID, rules[1].SharedNetworkID)
+	require.NotNil(t, rules[1].SharedNetwork)
+	require.Equal(t, "frog", rules[1].SharedNetwork.Name)
+	require.False(t, rules[1].Enabled)
+	require.EqualValues(t, 50, rules[1].WarningThreshold)
+	require.Zero(t, rules[1].ErrorThreshold)
+
+	rule, err := GetUtilizationAlertRuleBySubnetID(db, subnet.ID)
+	require.NoError(t, err)
+	require.NotNil(t, rule)
+	require.Equal(t, rules[0].ID, rule.ID)
+
+	rule, err = GetUtilizationAlertRuleBySharedNetworkID(db, sharedNetwork.ID)
+	require.NoError(t, err)
+	require.NotNil(t, rule)
+	require.Equal(t, sharedNetworkRule.ID, rule.ID)
+
+	// Delete the rules.
+	err = DeleteUtilizationAlertRuleBySubnetID(db, subnet.ID)
+	require.NoError(t, err)
+	err = DeleteUtilizationAlertRuleBySubnetID(db, subnet.ID)
+	require.ErrorIs(t, err, ErrNotExists)
+
+	rule, err = GetUtilizationAlertRuleBySubnetID(db, subnet.ID)
+	require.NoError(t, err)
+	require.Nil(t, rule)
+
+	err = DeleteUtilizationAlertRuleBySharedNetworkID(db, sharedNetwork.ID)
+	require.NoError(t, err)
+	err = DeleteUtilizationAlertRuleBySharedNetworkID(db, sharedNetwork.ID)
+	require.ErrorIs(t, err, ErrNotExists)
+
+	rules, err = GetAllUtilizationAlertRules(db)
+	require.NoError(t, err)
+	require.Empty(t, rules)
+}
+
+// Test that the utilization alerts can be set, fetched and deleted and
+// that they are deleted together with their subnets.
+func TestSetGetDeleteUtilizationAlert(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	sharedNetwork := &SharedNetwork{
+		Name:   "frog",
+		Family: 6,
+	}
+	err := AddSharedNetwork(db, sharedNetwork)
+	require.NoError(t, err)
+
+	subnet := &Subnet{
+		Prefix: "2001:db8:1::/64",
+	}
+	err = AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	subnetAlert := &UtilizationAlert{
+		SubnetID:    subnet.ID,
+		Kind:        UtilizationAlertKindAddress,
+		Level:       EvWarning,
+		Utilization: 850,
+	}
+	err = SetUtilizationAlert(db, subnetAlert)
+	require.NoError(t, err)
+	require.NotZero(t, subnetAlert.ID)
+
+	sharedNetworkAlert := &UtilizationAlert{
+		SharedNetworkID: sharedNetwork.ID,
+		Kind:            UtilizationAlertKindDelegatedPrefix,
+		Level:           EvWarning,
+		Utilization:     810,
+	}
+	err = SetUtilizationAlert(db, sharedNetworkAlert)
+	require.NoError(t, err)
+
+	// Escalate the subnet alert.
+	subnetAlert.Level = EvError
+	subnetAlert.Utilization = 960
+	err = SetUtilizationAlert(db, subnetAlert)
+	require.NoError(t, err)
+
+	// The most severe alerts should be returned first.
+	alerts, err := GetAllUtilizationAlerts(db)
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+	require.Equal(t, subnetAlert.ID, alerts[0].ID)
+	require.Equal(t, EvError, alerts[0].Level)
+	require.EqualValues(t, 960, alerts[0].Utilization)
+	require.Equal(t, UtilizationAlertKindAddress, alerts[0].Kind)
+	require.NotNil(t, alerts[0].Subnet)
+	require.NotZero(t, alerts[0].CreatedAt)
+	require.Equal(t, sharedNetworkAlert.ID, alerts[1].ID)
+	require.Equal(t, UtilizationAlertKindDelegatedPrefix, alerts[1].Kind)
+	require.NotNil(t, alerts[1].SharedNetwork)
+	require.Equal(t, "frog", alerts[1].SharedNetwork.Name)
+
+	// Updating a non-existing alert should fail.
+	err = SetUtilizationAlert(db, &UtilizationAlert{ID: 12345, Level: EvError})
+	require.ErrorIs(t, err, ErrNotExists)
+
+	// Clear the shared network alert.
+	err = DeleteUtilizationAlert(db, sharedNetworkAlert.ID)
+	require.NoError(t, err)
+
+	// Deleting the subnet should delete its alert.
+	err = DeleteSubnet(db, subnet.ID)
+	require.NoError(t, err)
+
+	alerts, err = GetAllUtilizationAlerts(db)
+	require.NoError(t, err)
+	require.Empty(t, alerts)
+}
@@ -0,0 +1,217 @@
+package dbmodel
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	pkgerrors "github.com/pkg/errors"
+	dbops "isc.org/stork/server/database"
+)
+
+// Resolutions of the utilization history. The samples are aggregated in
+// the periods of each resolution.
+const (
+	UtilizationHistoryResolutionShort = 5 * time.Minute
+	UtilizationHistoryResolutionLong  = time.Hour
+)
+
+// Retention of the utilization history for each resolution. The older
+// periods are deleted.
+var utilizationHistoryRetention = map[time.Duration]time.Duration{
+	UtilizationHistoryResolutionShort: 7 * 24 * time.Hour,
+	UtilizationHistoryResolutionLong:  365 * 24 * time.Hour,
+}
+
+// Returns the retention of the utilization history with the resolution.
+// It returns zero for an unsupported resolution.
+func GetUtilizationHistoryRetention(resolution time.Duration) time.Duration {
+	return utilizationHistoryRetention[resolution]
+}
+
+// A single utilization sample collected by the statistics puller. It
+// describes a subnet, a shared network, a prefix pool or the global
+// utilization of the family if all IDs are zero. The utilization is
+// expressed in permille.
+type UtilizationSample struct {
+	SubnetID        int64
+	SharedNetworkID int64
+	PrefixPoolID    int64
+	Family          int
+	AddrUtilization int16
+	PdUtilization   int16
+}
+
+// A structure reflecting the utilization_history SQL table. It aggregates
+// the utilization samples collected within a single period of the
+// resolution. The period starts at the collection time. The resolution is
+// expressed in seconds and the utilization in permille.
+type UtilizationHistory struct {
+	ID                 int64
+	Resolution         int64
+	CollectedAt        time.Time
+	SubnetID           int64
+	SharedNetworkID    int64
+	PrefixPoolID       int64
+	Family             int
+	Samples            int64
+	AddrUtilizationSum int64 `pg:",use_zero"`
+	AddrUtilizationMax int16 `pg:",use_zero"`
+	PdUtilizationSum   int64 `pg:",use_zero"`
+	PdUtilizationMax   int16 `pg:",use_zero"`
+}
+
+// Returns the average address utilization in the period in permille.
+func (h *UtilizationHistory) GetAddrUtilization() float64 {
+	if h.Samples == 0 {
+		return 0
+	}
+	return float64(h.AddrUtilizationSum) / float64(h.Samples)
+}
+
+// Returns the average delegated prefix utilization in the period in
+// permille.
+func (h *UtilizationHistory) GetPdUtilization() float64 {
+	if h.Samples == 0 {
+		return 0
+	}
+	return float64(h.PdUtilizationSum) / float64(h.Samples)
+}
+
+// Adds the utilization samples collected at the specified time to the
+// history. The samples are aggregated with the samples collected earlier
+// in the same period of each resolution.
+func AddUtilizationSamples(dbi dbops.DBI, samples []UtilizationSample, collectedAt time.Time) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	for _, resolution := range []time.Duration{UtilizationHistoryResolutionShort, UtilizationHistoryResolutionLong} {
+		periodStart := collectedAt.UTC().Truncate(resolution)
+		history := make([]UtilizationHistory, 0, len(samples))
+		for _, sample := range samples {
+			history = append(history, UtilizationHistory{
+				Resolution:         int64(resolution / time.Second),
+				CollectedAt:        periodStart,
+				SubnetID:           sample.SubnetID,
+				SharedNetworkID:    sample.SharedNetworkID,
+				PrefixPoolID:       sample.PrefixPoolID,
+				Family:             sample.Family,
+				Samples:            1,
+				AddrUtilizationSum: int64(sample.AddrUtilization),
+				AddrUtilizationMax: sample.AddrUtilization,
+				PdUtilizationSum:   int64(sample.PdUtilization),
+				PdUtilizationMax:   sample.PdUtilization,
+			})
+		}
+		_, err := dbi.Model(&history).
+			OnConflict("(resolution, COALESCE(subnet_id, 0), COALESCE(shared_network_id, 0), COALESCE(prefix_pool_id, 0), family, collected_at) DO UPDATE").
+			Set("samples = utilization_history.samples + EXCLUDED.samples").
+			Set("addr_utilization_sum = utilization_history.addr_utilization_sum + EXCLUDED.addr_utilization_sum").
+			Set("addr_utilization_max = GREATEST(utilization_history.addr_utilization_max, EXCLUDED.addr_utilization_max)").
+			Set("pd_utilization_sum = utilization_history.pd_utilization_sum + EXCLUDED.pd_utilization_sum").
+			Set("pd_utilization_max = GREATEST(utilization_history.pd_utilization_max, EXCLUDED.pd_utilization_max)").
+			Insert()
+		if err != nil {
+			return pkgerrors.Wrapf(err, "problem adding utilization samples with resolution %s", resolution)
+		}
+	}
+	return nil
+}
+
+// Deletes the utilization history periods older than the retention of
+// their resolutions.
+func AgeOffUtilizationHistory(dbi dbops.DBI, now time.Time) error {
+	for resolution, retention := range utilizationHistoryRetention {
+		_, err := dbi.Model(&UtilizationHistory{}).
+			Where("resolution = ?", int64(resolution/time.Second)).
+			Where("collected_at < ?", now.UTC().Add(-retention)).
+			Delete()
+		if err != nil {
+			return pkgerrors.Wrapf(err, "problem deleting utilization history with resolution %s", resolution)
+		}
+	}
+	return nil
+}
+
+// Fetches the utilization history matching the condition with the
+// resolution and collected within the time range, ordered by time.
+func getUtilizationHistory(dbi dbops.DBI, resolution time.Duration, from, to time.Time, condition string, params ...any) ([]UtilizationHistory, error) {
+	history := []UtilizationHistory{}
+	err := dbi.Model(&history).
+		Where(condition, params...).
+		Where("resolution = ?", int64(resolution/time.Second)).
+		Where("collected_at >= ?", from.UTC()).
+		Where("collected_at <= ?", to.UTC()).
+		OrderExpr("collected_at ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrap(err, "problem getting utilization history")
+	}
+	return history, nil
+}
+
+// Fetches the utilization history of the subnet.
+func GetSubnetUtilizationHistory(dbi dbops.DBI, subnetID int64, resolution time.Duration, from, to time.Time) ([]UtilizationHistory, error) {
+	history, err := getUtilizationHistory(dbi, resolution, from, to, "subnet_id = ?", subnetID)
+	return history, pkgerrors.WithMessagef(err, "subnet %d", subnetID)
+}
+
+// Fetches the utilization history of the shared network.
+func GetSharedNetworkUtilizationHistory(dbi dbops.DBI, sharedNetworkID int64, resolution time.Duration, from, to time.Time) ([]UtilizationHistory, error) {
+	history, err := getUtilizationHistory(dbi, resolution, from, to, "shared_network_id = ?", sharedNetworkID)
+	return history, pkgerrors.WithMessagef(err, "shared network %d", sharedNetworkID)
+}
+
+// Fetches the utilization history of the prefix pool.
+func GetPrefixPoolUtilizationHistory(dbi dbops.DBI, prefixPoolID int64, resolution time.Duration, from, to time.Time) ([]UtilizationHistory, error) {
+	history, err := getUtilizationHistory(dbi, resolution, from, to, "prefix_pool_id = ?", prefixPoolID)
+	return history, pkgerrors.WithMessagef(err, "prefix pool %d", prefixPoolID)
+}
+
+// Fetches the history of the global utilization of the family.
+func GetGlobalUtilizationHistory(dbi dbops.DBI, family int, resolution time.Duration, from, to time.Time) ([]UtilizationHistory, error) {
+	history, err := getUtilizationHistory(dbi, resolution, from, to,
+		"subnet_id IS NULL AND shared_network_id IS NULL AND prefix_pool_id IS NULL AND family = ?", family)
+	return history, pkgerrors.WithMessagef(err, "global family %d", family)
+}
+
+// Utilization trend of a subnet, a shared network or a prefix pool
+// computed from its hourly history. The slopes are expressed in permille
+// per second.
+type UtilizationTrend struct {
+	SubnetID        int64
+	SharedNetworkID int64
+	PrefixPoolID    int64
+	Periods         int64
+	AddrSlope       float64
+	PdSlope         float64
+}
+
+// Computes the utilization trends of the subnets, shared networks and
+// prefix pools using the linear regression of their average hourly
+// utilization collected since the specified time. The subnets, shared
+// networks and prefix pools with fewer hourly periods than specified are
+// skipped.
+func GetUtilizationTrends(dbi dbops.DBI, since time.Time, minPeriods int) ([]UtilizationTrend, error) {
+	trends := []UtilizationTrend{}
+	_, err := dbi.Query(&trends, `
+		SELECT
+			subnet_id,
+			shared_network_id,
+			prefix_pool_id,
+			COUNT(*) AS periods,
+			COALESCE(regr_slope(addr_utilization_sum::float8 / samples, EXTRACT(EPOCH FROM collected_at)), 0) AS addr_slope,
+			COALESCE(regr_slope(pd_utilization_sum::float8 / samples, EXTRACT(EPOCH FROM collected_at)), 0) AS pd_slope
+		FROM utilization_history
+		WHERE resolution = ?
+			AND collected_at >= ?
+			AND samples > 0
+			AND (subnet_id IS NOT NULL OR shared_network_id IS NOT NULL OR prefix_pool_id IS NOT NULL)
+		GROUP BY subnet_id, shared_network_id, prefix_pool_id
+		HAVING COUNT(*) >= ?
+	`, int64(UtilizationHistoryResolutionLong/time.Second), since.UTC(), minPeriods)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "problem computing utilization trends")
+	}
+	return trends, nil
+}
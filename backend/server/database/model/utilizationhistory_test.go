@@ -0,0 +1,263 @@
+package dbmodel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	dbtest "isc.org/stork/server/database/test"
+)
+
+// Test that the average utilization is computed from the sums.
+func TestUtilizationHistoryAverages(t *testing.T) {
+	history := UtilizationHistory{
+		Samples:            4,
+		AddrUtilizationSum: 2000,
+		PdUtilizationSum:   100,
+	}
+	require.EqualValues(t, 500, history.GetAddrUtilization())
+	require.EqualValues(t, 25, history.GetPdUtilization())
+
+	history = UtilizationHistory{}
+	require.Zero(t, history.GetAddrUtilization())
+	require.Zero(t, history.GetPdUtilization())
+}
+
+// Test that the samples are aggregated in the periods of each resolution
+// and can be fetched for the subnets, shared networks and globally.
+func TestAddGetUtilizationSamples(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	sharedNetwork := &SharedNetwork{
+		Name:   "frog",
+		Family: 4,
+	}
+	err := AddSharedNetwork(db, sharedNetwork)
+	require.NoError(t, err)
+
+	subnet := &Subnet{
+		Prefix:          "192.0.2.0/24",
+		SharedNetworkID: sharedNetwork.ID,
+	}
+	err = AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i, utilization := range []int16{100, 300, 200} {
+		err = AddUtilizationSamples(db, []UtilizationSample{
+			{SubnetID: subnet.ID, Family: 4, AddrUtilization: utilization},
+			{SharedNetworkID: sharedNetwork.ID, Family: 4, AddrUtilization: utilization / 2},
+			{Family: 4, AddrUtilization: utilization / 4},
+			{Family: 6, AddrUtilization: 10, PdUtilization: 20},
+		}, start.Add(time.Duration(i)*2*time.Minute))
+		require.NoError(t, err)
+	}
+
+	// The first two samples fall in the first 5-minute period.
+	history, err := GetSubnetUtilizationHistory(db, subnet.ID, UtilizationHistoryResolutionShort, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, start, history[0].CollectedAt)
+	require.EqualValues(t, 2, history[0].Samples)
+	require.EqualValues(t, 200, history[0].GetAddrUtilization())
+	require.EqualValues(t, 300, history[0].AddrUtilizationMax)
+	require.Equal(t, start.Add(5*time.Minute), history[1].CollectedAt)
+	require.EqualValues(t, 1, history[1].Samples)
+
+	// All samples fall in the same hourly period.
+	history, err = GetSubnetUtilizationHistory(db, subnet.ID, UtilizationHistoryResolutionLong, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.EqualValues(t, 3, history[0].Samples)
+	require.EqualValues(t, 600, history[0].AddrUtilizationSum)
+
+	history, err = GetSharedNetworkUtilizationHistory(db, sharedNetwork.ID, UtilizationHistoryResolutionLong, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.EqualValues(t, 150, history[0].AddrUtilizationMax)
+
+	history, err = GetGlobalUtilizationHistory(db, 4, UtilizationHistoryResolutionLong, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.EqualValues(t, 75, history[0].AddrUtilizationMax)
+
+	history, err = GetGlobalUtilizationHistory(db, 6, UtilizationHistoryResolutionLong, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.EqualValues(t, 20, history[0].GetPdUtilization())
+
+	// The time range is respected.
+	history, err = GetSubnetUtilizationHistory(db, subnet.ID, UtilizationHistoryResolutionShort, start.Add(time.Minute), start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+
+	// Deleting the subnet deletes its history.
+	err = DeleteSubnet(db, subnet.ID)
+	require.NoError(t, err)
+	history, err = GetSubnetUtilizationHistory(db, subnet.ID, UtilizationHistoryResolutionLong, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, history)
+}
+
+// Test that the history older than the retention of its resolution is
+// deleted.
+func TestAgeOffUtilizationHistory(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	now := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	samples := []UtilizationSample{{Family: 4, AddrUtilization: 100}}
+	err := AddUtilizationSamples(db, samples, now.Add(-8*24*time.Hour))
+	require.NoError(t, err)
+	err = AddUtilizationSamples(db, samples, now.Add(-time.Hour))
+	require.NoError(t, err)
+
+	err = AgeOffUtilizationHistory(db, now)
+	require.NoError(t, err)
+
+	from := now.Add(-30 * 24 * time.Hour)
+	history, err := GetGlobalUtilizationHistory(db, 4, UtilizationHistoryResolutionShort, from, now)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Equal(t, now.Add(-time.Hour), history[0].CollectedAt)
+
+	// The hourly history is kept for a year.
+	history, err = GetGlobalUtilizationHistory(db, 4, UtilizationHistoryResolutionLong, from, now)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+}
+
+// Test that the utilization trends are computed from the hourly history.
+func TestGetUtilizationTrends(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	sharedNetwork := &SharedNetwork{
+		Name:   "frog",
+		Family: 6,
+	}
+	err := AddSharedNetwork(db, sharedNetwork)
+	require.NoError(t, err)
+
+	subnets := []*Subnet{
+		{Prefix: "192.0.2.0/24"},
+		{Prefix: "192.0.3.0/24"},
+	}
+	for _, subnet := range subnets {
+		err = AddSubnet(db, subnet)
+		require.NoError(t, err)
+	}
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 24; i++ {
+		samples := []UtilizationSample{
+			// Growing by 2 permille per hour.
+			{SubnetID: subnets[0].ID, Family: 4, AddrUtilization: int16(100 + 2*i)},
+			{SharedNetworkID: sharedNetwork.ID, Family: 6, AddrUtilization: 300, PdUtilization: int16(500 - i)},
+			{Family: 4, AddrUtilization: int16(i)},
+		}
+		// The second subnet has too short history.
+		if i < 10 {
+			samples = append(samples, UtilizationSample{SubnetID: subnets[1].ID, Family: 4, AddrUtilization: int16(i)})
+		}
+		err = AddUtilizationSamples(db, samples, start.Add(time.Duration(i)*time.Hour))
+		require.NoError(t, err)
+	}
+
+	trends, err := GetUtilizationTrends(db, start, 20)
+	require.NoError(t, err)
+	require.Len(t, trends, 2)
+
+	for _, trend := range trends {
+		require.EqualValues(t, 24, trend.Periods)
+		if trend.SubnetID != 0 {
+			require.Equal(t, subnets[0].ID, trend.SubnetID)
+			require.InDelta(t, 2.0/3600, trend.AddrSlope, 1e-9)
+			require.Zero(t, trend.PdSlope)
+		} else {
+			require.Equal(t, sharedNetwork.ID, trend.SharedNetworkID)
+			require.Zero(t, trend.AddrSlope)
+			require.InDelta(t, -1.0/3600, trend.PdSlope, 1e-9)
+		}
+	}
+
+	// The history before the specified time is excluded.
+	trends, err = GetUtilizationTrends(db, start.Add(12*time.Hour), 20)
+	require.NoError(t, err)
+	require.Empty(t, trends)
+}
+
+// Test that the utilization history and the trend of the prefix pool are
+// kept apart from its subnet and the global history.
+func TestPrefixPoolUtilizationHistory(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	apps := addTestSubnetApps(t, db)
+
+	subnet := &Subnet{
+		Prefix: "2001:db8:1::/64",
+		LocalSubnets: []*LocalSubnet{
+			{
+				DaemonID: apps[0].Daemons[1].ID,
+			},
+		},
+	}
+	err := AddSubnet(db, subnet)
+	require.NoError(t, err)
+	err = AddLocalSubnets(db, subnet)
+	require.NoError(t, err)
+
+	pool := &PrefixPool{
+		Prefix:        "2001:db8:1:8000::/64",
+		DelegatedLen:  80,
+		LocalSubnetID: subnet.LocalSubnets[0].ID,
+	}
+	err = AddPrefixPool(db, pool)
+	require.NoError(t, err)
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 24; i++ {
+		err = AddUtilizationSamples(db, []UtilizationSample{
+			{SubnetID: subnet.ID, Family: 6, PdUtilization: 100},
+			// Growing by 3 permille per hour.
+			{PrefixPoolID: pool.ID, Family: 6, PdUtilization: int16(200 + 3*i)},
+			{Family: 6, PdUtilization: 100},
+		}, start.Add(time.Duration(i)*time.Hour))
+		require.NoError(t, err)
+	}
+
+	history, err := GetPrefixPoolUtilizationHistory(db, pool.ID, UtilizationHistoryResolutionLong, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.EqualValues(t, 200, history[0].PdUtilizationMax)
+	require.EqualValues(t, 203, history[1].PdUtilizationMax)
+
+	// The global history excludes the prefix pool samples.
+	history, err = GetGlobalUtilizationHistory(db, 6, UtilizationHistoryResolutionLong, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.EqualValues(t, 100, history[1].PdUtilizationMax)
+
+	trends, err := GetUtilizationTrends(db, start, 20)
+	require.NoError(t, err)
+	require.Len(t, trends, 2)
+	for _, trend := range trends {
+		if trend.PrefixPoolID != 0 {
+			require.Equal(t, pool.ID, trend.PrefixPoolID)
+			require.Zero(t, trend.SubnetID)
+			require.InDelta(t, 3.0/3600, trend.PdSlope, 1e-9)
+		} else {
+			require.Equal(t, subnet.ID, trend.SubnetID)
+			require.Zero(t, trend.PdSlope)
+		}
+	}
+
+	// Deleting the prefix pool deletes its history.
+	err = DeletePrefixPool(db, pool.ID)
+	require.NoError(t, err)
+	history, err = GetPrefixPoolUtilizationHistory(db, pool.ID, UtilizationHistoryResolutionLong, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, history)
+}
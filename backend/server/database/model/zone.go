@@ -0,0 +1,269 @@
+package dbmodel
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	pkgerrors "github.com/pkg/errors"
+	dbops "isc.org/stork/server/database"
+)
+
+// Types of the zones served by the BIND 9 daemons. The other types
+// reported by BIND 9, e.g., stub or forward, are stored as is.
+const (
+	ZoneTypePrimary   = "primary"
+	ZoneTypeSecondary = "secondary"
+)
+
+// Replication statuses of the secondary zones.
+const (
+	// The secondary zone has the same serial as its primary or it has
+	// been out of sync for a time shorter than the lag threshold.
+	ZoneReplicationStatusInSync = "in-sync"
+	// The secondary zone has been out of sync with its primary for a time
+	// longer than the lag threshold.
+	ZoneReplicationStatusLagging = "lagging"
+	// The secondary zone is not loaded or it has expired, so its last
+	// transfer from the primary has failed.
+	ZoneReplicationStatusTransferFailed = "transfer-failed"
+	// The primary of the secondary zone is not monitored or it cannot be
+	// determined unambiguously.
+	ZoneReplicationStatusUnknown = "unknown"
+)
+
+// A structure reflecting the zone SQL table. It holds a DNS zone served
+// by a BIND 9 daemon in a view. The zones are associated with the daemons
+// rather than shared between them because the same zone may be served
+// with a different serial by a primary and its secondaries.
+type Zone struct {
+	ID        int64
+	CreatedAt time.Time
+	DaemonID  int64
+	Daemon    *Daemon `pg:"rel:has-one"`
+	View      string
+	Name      string
+	Class     string
+	ZoneType  string
+	Serial    int64 `pg:",use_zero"`
+	// Path to the zone file. It is empty if the zone has no file, e.g.,
+	// a secondary zone kept in memory.
+	File    string
+	Dynamic bool `pg:",use_zero"`
+	// Time when the zone was last loaded from a file or transferred from
+	// a primary.
+	LoadedAt time.Time
+	// Time of the next refresh of a secondary zone.
+	RefreshAt time.Time
+	// Time when a secondary zone expires if it is not refreshed.
+	ExpiresAt time.Time
+
+	// The primary zone the secondary zone is compared with.
+	PrimaryZoneID int64
+	PrimaryZone   *Zone `pg:"rel:has-one"`
+	// Replication status of a secondary zone. It is empty for the other
+	// zone types.
+	ReplicationStatus string
+	// Time when the secondary zone was first seen with a serial different
+	// from its primary.
+	OutOfSyncSince time.Time
+	// Time of the first refresh of the secondary zone scheduled after it
+	// went out of sync. It is kept when named reschedules the refresh
+	// after a failed transfer.
+	OutOfSyncRefreshAt time.Time
+}
+
+// A structure containing the filters for selecting the zones. A nil value
+// of a filter means that it is not applied.
+type ZonesByPageFilters struct {
+	AppID    *int64
+	DaemonID *int64
+	ZoneType *string
+	// Replication status of the secondary zones.
+	ReplicationStatus *string
+	// Matches the zone name or view.
+	Text *string
+}
+
+// Fetches the zone by ID. It includes the daemon and the app the zone
+// belongs to. It returns nil if the zone does not exist.
+func GetZoneByID(dbi dbops.DBI, zoneID int64) (*Zone, error) {
+	zone := &Zone{}
+	err := dbi.Model(zone).
+		Relation("Daemon.App.AccessPoints").
+		Relation("Daemon.App.Machine").
+		Relation("PrimaryZone").
+		Where("zone.id = ?", zoneID).
+		Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, pkgerrors.Wrapf(err, "problem getting the zone with ID %d", zoneID)
+	}
+	return zone, nil
+}
+
+// Fetches the zones of the daemon ordered by view and name.
+func GetZonesByDaemonID(dbi dbops.DBI, daemonID int64) ([]Zone, error) {
+	zones := []Zone{}
+	err := dbi.Model(&zones).
+		Where("zone.daemon_id = ?", daemonID).
+		OrderExpr("zone.view ASC").
+		OrderExpr("zone.name ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrapf(err, "problem getting zones of the daemon with ID %d", daemonID)
+	}
+	return zones, nil
+}
+
+// Fetches all zones with the daemons and apps serving them.
+func GetAllZones(dbi dbops.DBI) ([]Zone, error) {
+	zones := []Zone{}
+	err := dbi.Model(&zones).
+		Relation("Daemon.App").
+		OrderExpr("zone.id ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrap(err, "problem getting all zones")
+	}
+	return zones, nil
+}
+
+// Updates the primary zone reference and the replication status of the
+// secondary zone.
+func UpdateZoneReplication(dbi dbops.DBI, zone *Zone) error {
+	result, err := dbi.Model(zone).
+		Column("primary_zone_id", "replication_status", "out_of_sync_since", "out_of_sync_refresh_at").
+		WherePK().
+		Update()
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "problem updating replication status of the zone with ID %d", zone.ID)
+	} else if result.RowsAffected() <= 0 {
+		err = pkgerrors.Wrapf(ErrNotExists, "zone with ID %d does not exist", zone.ID)
+	}
+	return err
+}
+
+// Fetches a collection of the zones from the database. The offset and
+// limit specify the beginning of the page and the maximum size of the
+// page. The sortField allows indicating the sort column and the sortDir
+// allows selecting the order of sorting. If the sortField is empty, the
+// zones are sorted by name, view and daemon. It returns the zones and the
+// total number of zones matching the filters.
+func GetZonesByPage(dbi dbops.DBI, offset, limit int64, filters *ZonesByPageFilters, sortField string, sortDir SortDirEnum) ([]Zone, int64, error) {
+	zones := []Zone{}
+	q := dbi.Model(&zones).
+		Relation("Daemon.App.AccessPoints").
+		Relation("Daemon.App.Machine").
+		Relation("PrimaryZone")
+
+	if filters != nil {
+		if filters.AppID != nil {
+			q = q.Where("daemon.app_id = ?", *filters.AppID)
+		}
+		if filters.DaemonID != nil {
+			q = q.Where("zone.daemon_id = ?", *filters.DaemonID)
+		}
+		if filters.ZoneType != nil {
+			q = q.Where("zone.zone_type = ?", *filters.ZoneType)
+		}
+		if filters.ReplicationStatus != nil {
+			q = q.Where("zone.replication_status = ?", *filters.ReplicationStatus)
+		}
+		if filters.Text != nil {
+			q = q.WhereGroup(func(q *orm.Query) (*orm.Query, error) {
+				q = q.WhereOr("zone.name ILIKE ?", "%"+*filters.Text+"%").
+					WhereOr("zone.view ILIKE ?", "%"+*filters.Text+"%")
+				return q, nil
+			})
+		}
+	}
+
+	if sortField == "" {
+		q = q.OrderExpr(prepareOrderExpr("zone", "name", sortDir)).
+			OrderExpr(prepareOrderExpr("zone", "view", sortDir)).
+			OrderExpr(prepareOrderExpr("zone", "daemon_id", sortDir))
+	} else {
+		q = q.OrderExpr(prepareOrderExpr("zone", sortField, sortDir))
+	}
+	q = q.Offset(int(offset)).Limit(int(limit))
+
+	total, err := q.SelectAndCount()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return []Zone{}, 0, nil
+		}
+		return nil, 0, pkgerrors.Wrapf(err, "problem getting zones by page")
+	}
+	return zones, int64(total), nil
+}
+
+// Replaces the zones of the daemon with the zones fetched from the daemon.
+// The existing zones are updated, the new zones are inserted, and the
+// zones no longer served by the daemon are deleted. Updating rather than
+// re-creating the zones preserves their IDs, so they remain valid in the
+// REST API. All zones are committed in a single transaction.
+func CommitZonesIntoDB(dbi dbops.DBI, daemonID int64, zones []Zone) error {
+	if db, ok := dbi.(*pg.DB); ok {
+		return db.RunInTransaction(context.Background(), func(tx *pg.Tx) error {
+			return commitZonesIntoDB(tx, daemonID, zones)
+		})
+	}
+	return commitZonesIntoDB(dbi.(*pg.Tx), daemonID, zones)
+}
+
+// Replaces the zones of the daemon within a transaction.
+func commitZonesIntoDB(tx *pg.Tx, daemonID int64, zones []Zone) error {
+	ids := []int64{}
+	for i := range zones {
+		zone := &zones[i]
+		zone.DaemonID = daemonID
+		_, err := tx.Model(zone).
+			OnConflict("(daemon_id, view, name, class) DO UPDATE").
+			Set("zone_type = EXCLUDED.zone_type").
+			Set("serial = EXCLUDED.serial").
+			Set("file = EXCLUDED.file").
+			Set("dynamic = EXCLUDED.dynamic").
+			Set("loaded_at = EXCLUDED.loaded_at").
+			Set("refresh_at = EXCLUDED.refresh_at").
+			Set("expires_at = EXCLUDED.expires_at").
+			Returning("id").
+			Insert()
+		if err != nil {
+			return pkgerrors.Wrapf(err, "problem upserting zone %s in view %s for the daemon with ID %d", zone.Name, zone.View, daemonID)
+		}
+		ids = append(ids, zone.ID)
+	}
+	q := tx.Model((*Zone)(nil)).Where("daemon_id = ?", daemonID)
+	if len(ids) > 0 {
+		q = q.Where("id NOT IN (?)", pg.In(ids))
+	}
+	if _, err := q.Delete(); err != nil {
+		return pkgerrors.Wrapf(err, "problem deleting stale zones of the daemon with ID %d", daemonID)
+	}
+	return nil
+}
+
+// Updates the zone details fetched with the rndc zonestatus command, i.e.,
+// the zone file and the dynamic flag. The last load time is only updated
+// when it is set in the zone. The zone is identified by the daemon ID,
+// view, name and class.
+func UpdateZoneDetails(dbi dbops.DBI, zone *Zone) error {
+	q := dbi.Model(zone).
+		Column("file", "dynamic").
+		Where("daemon_id = ?daemon_id").
+		Where("view = ?view").
+		Where("name = ?name").
+		Where("class = ?class")
+	if !zone.LoadedAt.IsZero() {
+		q = q.Column("loaded_at")
+	}
+	if _, err := q.Update(); err != nil {
+		return pkgerrors.Wrapf(err, "problem updating details of zone %s in view %s for the daemon with ID %d", zone.Name, zone.View, zone.DaemonID)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package dbmodel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	dbops "isc.org/stork/server/database"
+	dbtest "isc.org/stork/server/database/test"
+	storkutil "isc.org/stork/util"
+)
+
+// Adds a machine with a BIND 9 app and returns the app.
+func addTestBind9App(t *testing.T, db *dbops.PgDB, address string) *App {
+	machine := &Machine{
+		Address:   address,
+		AgentPort: 8080,
+	}
+	err := AddMachine(db, machine)
+	require.NoError(t, err)
+
+	app := &App{
+		Type:      AppTypeBind9,
+		MachineID: machine.ID,
+		Daemons: []*Daemon{
+			NewBind9Daemon(true),
+		},
+	}
+	_, err = AddApp(db, app)
+	require.NoError(t, err)
+	return app
+}
+
+// Test that the zones of the daemon are inserted, updated and deleted
+// when committing the zones fetched from the daemon.
+func TestCommitZonesIntoDB(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestBind9App(t, db, "dns.example.org")
+	daemonID := app.Daemons[0].ID
+	loadedAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	// Add the zones.
+	err := CommitZonesIntoDB(db, daemonID, []Zone{
+		{View: "_default", Name: "example.org", Class: "IN", ZoneType: ZoneTypePrimary, Serial: 1, File: "db.example.org", LoadedAt: loadedAt},
+		{View: "_default", Name: "example.com", Class: "IN", ZoneType: ZoneTypeSecondary, Serial: 7},
+	})
+	require.NoError(t, err)
+
+	zones, err := GetZonesByDaemonID(db, daemonID)
+	require.NoError(t, err)
+	require.Len(t, zones, 2)
+	require.Equal(t, "example.com", zones[0].Name)
+	require.Equal(t, ZoneTypeSecondary, zones[0].ZoneType)
+	require.EqualValues(t, 7, zones[0].Serial)
+	require.Empty(t, zones[0].File)
+	require.Zero(t, zones[0].LoadedAt)
+	require.Equal(t, "example.org", zones[1].Name)
+	require.Equal(t, "db.example.org", zones[1].File)
+	require.Equal(t, loadedAt, zones[1].LoadedAt)
+	orgID := zones[1].ID
+
+	// Replace the zones. The example.org zone should be updated rather
+	// than re-created.
+	err = CommitZonesIntoDB(db, daemonID, []Zone{
+		{View: "_default", Name: "example.org", Class: "IN", ZoneType: ZoneTypePrimary, Serial: 2, Dynamic: true},
+		{View: "internal", Name: "example.org", Class: "IN", ZoneType: ZoneTypePrimary, Serial: 5},
+	})
+	require.NoError(t, err)
+
+	zones, err = GetZonesByDaemonID(db, daemonID)
+	require.NoError(t, err)
+	require.Len(t, zones, 2)
+	require.Equal(t, orgID, zones[0].ID)
+	require.Equal(t, "_default", zones[0].View)
+	require.EqualValues(t, 2, zones[0].Serial)
+	require.True(t, zones[0].Dynamic)
+	require.Zero(t, zones[0].LoadedAt)
+	require.Equal(t, "internal", zones[1].View)
+
+	// Remove all zones.
+	require.NoError(t, CommitZonesIntoDB(db, daemonID, nil))
+	zones, err = GetZonesByDaemonID(db, daemonID)
+	require.NoError(t, err)
+	require.Empty(t, zones)
+}
+
+// Test getting the zone by ID.
+func TestGetZoneByID(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestBind9App(t, db, "dns.example.org")
+	zones := []Zone{
+		{View: "_default", Name: "example.org", Class: "IN", ZoneType: ZoneTypePrimary, Serial: 1},
+	}
+	require.NoError(t, CommitZonesIntoDB(db, app.Daemons[0].ID, zones))
+
+	zone, err := GetZoneByID(db, zones[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, zone)
+	require.Equal(t, "example.org", zone.Name)
+	require.NotNil(t, zone.Daemon)
+	require.NotNil(t, zone.Daemon.App)
+	require.NotNil(t, zone.Daemon.App.Machine)
+	require.Equal(t, "dns.example.org", zone.Daemon.App.Machine.Address)
+
+	zone, err = GetZoneByID(db, zones[0].ID+1)
+	require.NoError(t, err)
+	require.Nil(t, zone)
+}
+
+// Test getting the zones by page with filtering and sorting.
+func TestGetZonesByPage(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app1 := addTestBind9App(t, db, "dns1.example.org")
+	app2 := addTestBind9App(t, db, "dns2.example.org")
+	require.NoError(t, CommitZonesIntoDB(db, app1.Daemons[0].ID, []Zone{
+		{View: "_default", Name: "example.org", Class: "IN", ZoneType: ZoneTypePrimary, Serial: 3},
+		{View: "internal", Name: "example.org", Class: "IN", ZoneType: ZoneTypePrimary, Serial: 4},
+		{View: "_default", Name: "example.net", Class: "IN", ZoneType: ZoneTypePrimary, Serial: 1},
+	}))
+	require.NoError(t, CommitZonesIntoDB(db, app2.Daemons[0].ID, []Zone{
+		{View: "_default", Name: "example.org", Class: "IN", ZoneType: ZoneTypeSecondary, Serial: 3},
+	}))
+
+	// All zones sorted by name and view.
+	zones, total, err := GetZonesByPage(db, 0, 10, nil, "", SortDirAsc)
+	require.NoError(t, err)
+	require.EqualValues(t, 4, total)
+	require.Len(t, zones, 4)
+	require.Equal(t, "example.net", zones[0].Name)
+	require.Equal(t, "example.org", zones[1].Name)
+	require.Equal(t, "_default", zones[1].View)
+	require.Equal(t, "internal", zones[3].View)
+	require.NotNil(t, zones[0].Daemon)
+	require.NotNil(t, zones[0].Daemon.App)
+
+	// Paging.
+	zones, total, err = GetZonesByPage(db, 3, 2, nil, "", SortDirAsc)
+	require.NoError(t, err)
+	require.EqualValues(t, 4, total)
+	require.Len(t, zones, 1)
+
+	// Filter by app.
+	zones, total, err = GetZonesByPage(db, 0, 10, &ZonesByPageFilters{AppID: &app2.ID}, "", SortDirAsc)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Equal(t, ZoneTypeSecondary, zones[0].ZoneType)
+
+	// Filter by type.
+	zones, total, err = GetZonesByPage(db, 0, 10, &ZonesByPageFilters{ZoneType: storkutil.Ptr(ZoneTypePrimary)}, "", SortDirAsc)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, total)
+	require.Len(t, zones, 3)
+
+	// Filter by text matching the view.
+	zones, total, err = GetZonesByPage(db, 0, 10, &ZonesByPageFilters{Text: storkutil.Ptr("intern")}, "", SortDirAsc)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.EqualValues(t, 4, zones[0].Serial)
+
+	// Filter by text matching the name and sort by serial.
+	zones, total, err = GetZonesByPage(db, 0, 10, &ZonesByPageFilters{Text: storkutil.Ptr("ORG")}, "serial", SortDirDesc)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, total)
+	require.EqualValues(t, 4, zones[0].Serial)
+}
+
+// Test updating the replication status of the secondary zone and that the
+// reference to the primary zone is cleared when the primary is deleted.
+func TestUpdateZoneReplication(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app1 := addTestBind9App(t, db, "dns1.example.org")
+	app2 := addTestBind9App(t, db, "dns2.example.org")
+	primaries := []Zone{
+		{View: "_default", Name: "example.org", Class: "IN", ZoneType: ZoneTypePrimary, Serial: 3},
+	}
+	require.NoError(t, CommitZonesIntoDB(db, app1.Daemons[0].ID, primaries))
+	secondaries := []Zone{
+		{View: "_default", Name: "example.org", Class: "IN", ZoneType: ZoneTypeSecondary, Serial: 2},
+	}
+	require.NoError(t, CommitZonesIntoDB(db, app2.Daemons[0].ID, secondaries))
+
+	outOfSyncSince := time.Date(2024, 2, 5, 12, 0, 0, 0, time.UTC)
+	secondaries[0].PrimaryZoneID = primaries[0].ID
+	secondaries[0].ReplicationStatus = ZoneReplicationStatusLagging
+	secondaries[0].OutOfSyncSince = outOfSyncSince
+	secondaries[0].OutOfSyncRefreshAt = outOfSyncSince.Add(time.Hour)
+	require.NoError(t, UpdateZoneReplication(db, &secondaries[0]))
+
+	// The replication status should be preserved when the zones are
+	// committed again.
+	require.NoError(t, CommitZonesIntoDB(db, app2.Daemons[0].ID, []Zone{
+		{View: "_default", Name: "example.org", Class: "IN", ZoneType: ZoneTypeSecondary, Serial: 2},
+	}))
+	zone, err := GetZoneByID(db, secondaries[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, primaries[0].ID, zone.PrimaryZoneID)
+	require.NotNil(t, zone.PrimaryZone)
+	require.EqualValues(t, 3, zone.PrimaryZone.Serial)
+	require.Equal(t, ZoneReplicationStatusLagging, zone.ReplicationStatus)
+	require.Equal(t, outOfSyncSince, zone.OutOfSyncSince)
+	require.Equal(t, outOfSyncSince.Add(time.Hour), zone.OutOfSyncRefreshAt)
+
+	zones, total, err := GetZonesByPage(db, 0, 10, &ZonesByPageFilters{ReplicationStatus: storkutil.Ptr(ZoneReplicationStatusLagging)}, "", SortDirAsc)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Equal(t, secondaries[0].ID, zones[0].ID)
+
+	// Delete the primary zone.
+	require.NoError(t, CommitZonesIntoDB(db, app1.Daemons[0].ID, nil))
+	zone, err = GetZoneByID(db, secondaries[0].ID)
+	require.NoError(t, err)
+	require.Zero(t, zone.PrimaryZoneID)
+	require.Nil(t, zone.PrimaryZone)
+
+	// Updating a non-existing zone should fail.
+	require.ErrorIs(t, UpdateZoneReplication(db, &Zone{ID: secondaries[0].ID + 100}), ErrNotExists)
+}
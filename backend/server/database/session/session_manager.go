@@ -18,6 +18,10 @@ import (
 	dbmodel "isc.org/stork/server/database/model"
 )
 
+// The key under which the user authenticated with an API token is stored
+// in the request context.
+type apiTokenUserKey struct{}
+
 // Provides session management mechanisms for Stork. It wraps the scs.SessionManager
 // structure with Stork-specific implementation of sessions.
 type SessionMgr struct {
@@ -78,6 +82,34 @@ func (s *SessionMgr) LoginHandler(ctx context.Context, user *dbmodel.SystemUser)
 	return nil
 }
 
+// This function should be invoked upon successful authentication of the user
+// with a personal API token. Such requests don't use the sessions, so the user
+// is stored in the returned context instead. The Logged function returns this
+// user for the lifetime of the request.
+func (s *SessionMgr) APITokenLoginHandler(ctx context.Context, user *dbmodel.SystemUser) context.Context {
+	return context.WithValue(ctx, apiTokenUserKey{}, user)
+}
+
+// Stores the data of the redirect-based authentication started by the user.
+// The state and nonce are validated when the identity provider redirects the
+// user back to the server.
+func (s *SessionMgr) PutAuthenticationRedirect(ctx context.Context, authenticationMethodID, state, nonce string) {
+	s.scsSessionMgr.Put(ctx, "redirectAuthenticationMethodID", authenticationMethodID)
+	s.scsSessionMgr.Put(ctx, "redirectState", state)
+	s.scsSessionMgr.Put(ctx, "redirectNonce", nonce)
+}
+
+// Returns the data of the redirect-based authentication started by the user
+// and removes them from the session. It guarantees that the state can't be
+// used more than once. The returned values are empty if the user hasn't
+// started the redirect-based authentication.
+func (s *SessionMgr) PopAuthenticationRedirect(ctx context.Context) (authenticationMethodID, state, nonce string) {
+	authenticationMethodID = s.scsSessionMgr.PopString(ctx, "redirectAuthenticationMethodID")
+	state = s.scsSessionMgr.PopString(ctx, "redirectState")
+	nonce = s.scsSessionMgr.PopString(ctx, "redirectNonce")
+	return
+}
+
 // Destroys user session as a result of logout.
 func (s *SessionMgr) LogoutHandler(ctx context.Context) error {
 	err := s.scsSessionMgr.Destroy(ctx)
@@ -118,6 +150,23 @@ func (s *SessionMgr) HasToken(token string) bool {
 // The returned values are: ok - if the user is logged, user identifier and user
 // login.
 func (s *SessionMgr) Logged(ctx context.Context) (ok bool, user *dbmodel.SystemUser) {
+	// User authenticated with an API token. Return a copy of the user
+	// holding only the group IDs, like in case of the session.
+	if tokenUser, ok := ctx.Value(apiTokenUserKey{}).(*dbmodel.SystemUser); ok && tokenUser != nil {
+		user = &dbmodel.SystemUser{
+			ID:                     tokenUser.ID,
+			Login:                  tokenUser.Login,
+			Email:                  tokenUser.Email,
+			Lastname:               tokenUser.Lastname,
+			Name:                   tokenUser.Name,
+			AuthenticationMethodID: tokenUser.AuthenticationMethodID,
+		}
+		for _, g := range tokenUser.Groups {
+			user.Groups = append(user.Groups, &dbmodel.SystemGroup{ID: g.ID})
+		}
+		return true, user
+	}
+
 	id := s.scsSessionMgr.GetInt(ctx, "userID")
 	// User has no session.
 	if id == 0 {
@@ -210,3 +210,61 @@ func TestLogOutUser(t *testing.T) {
 	logged, _ = mgr.Logged(ctx)
 	require.False(t, logged)
 }
+
+// Tests that the user authenticated with an API token is returned by the
+// Logged function without creating a session.
+func TestAPITokenLoginHandler(t *testing.T) {
+	_, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	mgr, err := NewSessionMgr(dbSettings)
+	require.NoError(t, err)
+
+	user := &dbmodel.SystemUser{
+		ID:    5,
+		Login: "automation",
+		Groups: []*dbmodel.SystemGroup{
+			{
+				ID:   dbmodel.AdminGroupID,
+				Name: "admin",
+			},
+		},
+	}
+
+	ctx := mgr.APITokenLoginHandler(context.Background(), user)
+
+	ok, loggedUser := mgr.Logged(ctx)
+	require.True(t, ok)
+	require.NotNil(t, loggedUser)
+	require.NotSame(t, user, loggedUser)
+	require.Equal(t, 5, loggedUser.ID)
+	require.Equal(t, "automation", loggedUser.Login)
+	require.Len(t, loggedUser.Groups, 1)
+	require.Equal(t, dbmodel.AdminGroupID, loggedUser.Groups[0].ID)
+	require.Empty(t, loggedUser.Groups[0].Name)
+}
+
+// Test that the redirect-based authentication data can be stored in the
+// session and retrieved only once.
+func TestPutPopAuthenticationRedirect(t *testing.T) {
+	_, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	mgr, err := NewSessionMgr(dbSettings)
+	require.NoError(t, err)
+
+	ctx, err := mgr.Load(context.Background(), "")
+	require.NoError(t, err)
+
+	mgr.PutAuthenticationRedirect(ctx, "oidc", "state", "nonce")
+
+	methodID, state, nonce := mgr.PopAuthenticationRedirect(ctx)
+	require.Equal(t, "oidc", methodID)
+	require.Equal(t, "state", state)
+	require.Equal(t, "nonce", nonce)
+
+	methodID, state, nonce = mgr.PopAuthenticationRedirect(ctx)
+	require.Empty(t, methodID)
+	require.Empty(t, state)
+	require.Empty(t, nonce)
+}
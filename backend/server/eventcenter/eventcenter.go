@@ -10,6 +10,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	dbops "isc.org/stork/server/database"
 	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/notifications"
 )
 
 // An interface to EventCenter.
@@ -22,8 +23,9 @@ type EventCenter interface {
 	ServeHTTP(w http.ResponseWriter, req *http.Request)
 }
 
-// EventCenter. It has channel for receiving events
-// and a SSE broker for dispatching events to subscribers.
+// EventCenter. It has channel for receiving events,
+// a SSE broker for dispatching events to subscribers and
+// a notifier sending events over the notification channels.
 type eventCenter struct {
 	db     *dbops.PgDB
 	done   chan bool
@@ -31,16 +33,19 @@ type eventCenter struct {
 	events chan *dbmodel.Event
 
 	sseBroker *SSEBroker
+	notifier  notifications.Notifier
 }
 
-// Create new EventCenter object.
-func NewEventCenter(db *pg.DB) EventCenter {
+// Create new EventCenter object. The notifier may be nil, in which
+// case the events are not sent over the notification channels.
+func NewEventCenter(db *pg.DB, notifier notifications.Notifier) EventCenter {
 	ec := &eventCenter{
 		db:        db,
 		done:      make(chan bool),
 		wg:        &sync.WaitGroup{},
 		events:    make(chan *dbmodel.Event),
 		sseBroker: NewSSEBroker(db),
+		notifier:  notifier,
 	}
 	ec.wg.Add(1)
 	go ec.mainLoop()
@@ -182,6 +187,9 @@ func (ec *eventCenter) mainLoop() {
 				continue
 			}
 			ec.sseBroker.dispatchEvent(event)
+			if ec.notifier != nil {
+				ec.notifier.Notify(notifications.NewEventNotification(event))
+			}
 		}
 	}
 }
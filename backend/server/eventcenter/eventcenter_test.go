@@ -1,6 +1,7 @@
 package eventcenter
 
 import (
+	"sync"
 	"testing"
 	"time"
 
@@ -8,8 +9,37 @@ import (
 	"github.com/stretchr/testify/require"
 	dbmodel "isc.org/stork/server/database/model"
 	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/notifications"
 )
 
+// Notifier recording the notifications in the unit tests.
+type testNotifier struct {
+	mutex         sync.Mutex
+	notifications []*notifications.Notification
+}
+
+// Records the notification.
+func (n *testNotifier) Notify(notification *notifications.Notification) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.notifications = append(n.notifications, notification)
+}
+
+// Does nothing.
+func (n *testNotifier) SendTestEmail(to string) error {
+	return nil
+}
+
+// Does nothing.
+func (n *testNotifier) Shutdown() {}
+
+// Returns the recorded notifications.
+func (n *testNotifier) getNotifications() []*notifications.Notification {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.notifications
+}
+
 // Test that the event with a machine entry is created property.
 func TestCreateEventMachine(t *testing.T) {
 	// Arrange
@@ -441,7 +471,7 @@ func TestAddEvent(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
 	defer teardown()
 
-	ec := NewEventCenter(db)
+	ec := NewEventCenter(db, nil)
 
 	app := &dbmodel.App{
 		ID:   123,
@@ -482,3 +512,28 @@ func TestAddEvent(t *testing.T) {
 	require.Len(t, events, 3)
 	require.EqualValues(t, "some text", events[0].Text)
 }
+
+// Check that the added events are passed to the notifier.
+func TestAddEventNotify(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	notifier := &testNotifier{}
+	ec := NewEventCenter(db, notifier)
+	defer ec.Shutdown()
+
+	machine := &dbmodel.Machine{
+		ID: 456,
+	}
+	ec.AddWarningEvent("some text", machine)
+
+	require.Eventually(t, func() bool {
+		return len(notifier.getNotifications()) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	notification := notifier.getNotifications()[0]
+	require.Equal(t, notifications.NotificationTypeEvent, notification.Type)
+	require.Equal(t, dbmodel.EvWarning, notification.Level)
+	require.Equal(t, "some text", notification.Text)
+	require.EqualValues(t, 456, notification.Relations.MachineID)
+}
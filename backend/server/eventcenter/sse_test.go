@@ -18,7 +18,7 @@ func TestSSEBroker(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
 	defer teardown()
 
-	ec := NewEventCenter(db)
+	ec := NewEventCenter(db, nil)
 
 	req := httptest.NewRequest("GET", "http://localhost/sse?stream=message", nil)
 	w := httptest.NewRecorder()
@@ -51,7 +51,7 @@ func TestSSEBrokerShutdown(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
 	defer teardown()
 
-	ec := NewEventCenter(db)
+	ec := NewEventCenter(db, nil)
 
 	// Serve the request in background.
 	req := httptest.NewRequest("GET", "http://localhost/sse?stream=message", nil)
@@ -77,7 +77,7 @@ func TestSSEBrokerNonMainStream(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
 	defer teardown()
 
-	ec := NewEventCenter(db)
+	ec := NewEventCenter(db, nil)
 
 	req := httptest.NewRequest("GET", "http://localhost/sse?stream=connectivity", nil)
 	w := httptest.NewRecorder()
@@ -112,7 +112,7 @@ func TestSSEBrokerWithDifferentStreams(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
 	defer teardown()
 
-	ec := NewEventCenter(db)
+	ec := NewEventCenter(db, nil)
 
 	req := httptest.NewRequest("GET", "http://localhost/sse?stream=message&stream=connectivity&stream=ha", nil)
 	w := httptest.NewRecorder()
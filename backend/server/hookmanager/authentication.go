@@ -57,3 +57,60 @@ func (hm *HookManager) GetAuthenticationMetadata() []authenticationcallouts.Auth
 		return carrier.GetMetadata()
 	})
 }
+
+// Callout to obtain the URL of the external identity provider the user should
+// be redirected to. It is supported only by the authentication methods
+// implementing the redirect-based flow.
+func (hm *HookManager) GetAuthorizationURL(ctx context.Context, authenticationMethodID, state, nonce, callbackURL string) (string, error) {
+	type output struct {
+		url string
+		err error
+	}
+
+	ok, data := hooksutil.CallSequentialUntilProcessed(hm.GetExecutor(), func(carrier authenticationcallouts.AuthenticationRedirectCallouts) (hooksutil.CallStatus, *output) {
+		if carrier.GetMetadata().GetID() != authenticationMethodID {
+			// Go to next authentication callout.
+			return hooksutil.CallStatusSkipped, nil
+		}
+
+		url, err := carrier.GetAuthorizationURL(ctx, state, nonce, callbackURL)
+		err = errors.Wrap(err, "error occurred in the GetAuthorizationURL callout")
+		return hooksutil.CallStatusProcessed, &output{
+			url: url,
+			err: err,
+		}
+	})
+
+	if !ok {
+		return "", errors.Errorf("the '%s' authentication method doesn't support redirecting", authenticationMethodID)
+	}
+	return data.url, data.err
+}
+
+// Callout to authenticate the user based on the HTTP request sent by the
+// external identity provider redirecting the user back to the server.
+func (hm *HookManager) HandleAuthenticationCallback(ctx context.Context, request *http.Request, authenticationMethodID, nonce, callbackURL string) (*authenticationcallouts.User, error) {
+	type output struct {
+		user *authenticationcallouts.User
+		err  error
+	}
+
+	ok, data := hooksutil.CallSequentialUntilProcessed(hm.GetExecutor(), func(carrier authenticationcallouts.AuthenticationRedirectCallouts) (hooksutil.CallStatus, *output) {
+		if carrier.GetMetadata().GetID() != authenticationMethodID {
+			// Go to next authentication callout.
+			return hooksutil.CallStatusSkipped, nil
+		}
+
+		user, err := carrier.HandleCallback(ctx, request, nonce, callbackURL)
+		err = errors.Wrap(err, "error occurred in the HandleCallback callout")
+		return hooksutil.CallStatusProcessed, &output{
+			user: user,
+			err:  err,
+		}
+	})
+
+	if !ok {
+		return nil, errors.Errorf("the '%s' authentication method doesn't support redirecting", authenticationMethodID)
+	}
+	return data.user, data.err
+}
@@ -18,7 +18,13 @@ type authenticationCalloutCarrier interface { //nolint:unused
 	hooks.CalloutCarrier
 }
 
-//go:generate mockgen -package=hookmanager -destination=authenticationcalloutcarriermock_test.go -source=authentication_test.go -mock_names=authenticationCalloutCarrier=MockAuthenticationCalloutCarrier isc.org/server/hookmanager authenticationCalloutCarrier
+// Carrier mock interface of the redirect-based authentication for mockgen.
+type authenticationRedirectCalloutCarrier interface { //nolint:unused
+	authenticationcallouts.AuthenticationRedirectCallouts
+	hooks.CalloutCarrier
+}
+
+//go:generate mockgen -package=hookmanager -destination=authenticationcalloutcarriermock_test.go -source=authentication_test.go -mock_names=authenticationCalloutCarrier=MockAuthenticationCalloutCarrier,authenticationRedirectCalloutCarrier=MockAuthenticationRedirectCalloutCarrier isc.org/server/hookmanager authenticationCalloutCarrier
 //go:generate mockgen -package=hookmanager -destination=authenticationcalloutsmock_test.go -source=../../hooks/server/authenticationcallouts/authenticationcallouts.go isc.org/server/hookmanager AuthenticationMetadata
 
 // Test that the authentication callout is called.
@@ -270,3 +276,138 @@ func TestGetAuthenticationMetadataNoCalloutCarriers(t *testing.T) {
 	// Assert
 	require.Len(t, results, 0)
 }
+
+// Test that the authorization URL is returned by the matching redirect-based
+// authentication callout.
+func TestGetAuthorizationURL(t *testing.T) {
+	// Arrange
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	metadataMock1 := NewMockAuthenticationMetadata(ctrl)
+	metadataMock1.EXPECT().
+		GetID().
+		Return("mock1")
+
+	metadataMock2 := NewMockAuthenticationMetadata(ctrl)
+	metadataMock2.EXPECT().
+		GetID().
+		Return("mock2")
+
+	mock1 := NewMockAuthenticationRedirectCalloutCarrier(ctrl)
+	mock1.EXPECT().
+		GetAuthorizationURL(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Times(0)
+	mock1.EXPECT().
+		GetMetadata().
+		Return(metadataMock1)
+
+	mock2 := NewMockAuthenticationRedirectCalloutCarrier(ctrl)
+	mock2.EXPECT().
+		GetAuthorizationURL(gomock.Any(), "state", "nonce", "http://localhost/callback").
+		Return("http://idp.example.org/authorize?state=state", nil).
+		Times(1)
+	mock2.EXPECT().
+		GetMetadata().
+		Return(metadataMock2)
+
+	hookManager := NewHookManager()
+	hookManager.RegisterCalloutCarriers([]hooks.CalloutCarrier{mock1, mock2})
+
+	// Act
+	url, err := hookManager.GetAuthorizationURL(context.Background(), "mock2", "state", "nonce", "http://localhost/callback")
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, "http://idp.example.org/authorize?state=state", url)
+}
+
+// Test that the authorization URL can't be returned for the form-based
+// authentication methods.
+func TestGetAuthorizationURLNotSupported(t *testing.T) {
+	// Arrange
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := NewMockAuthenticationCalloutCarrier(ctrl)
+	mock.EXPECT().
+		GetMetadata().
+		Times(0)
+
+	hookManager := NewHookManager()
+	hookManager.RegisterCalloutCarrier(mock)
+
+	// Act
+	url, err := hookManager.GetAuthorizationURL(context.Background(), "mock", "state", "nonce", "http://localhost/callback")
+
+	// Assert
+	require.ErrorContains(t, err, "doesn't support redirecting")
+	require.Empty(t, url)
+}
+
+// Test that the callback is handled by the matching redirect-based
+// authentication callout.
+func TestHandleAuthenticationCallback(t *testing.T) {
+	// Arrange
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	metadataMock := NewMockAuthenticationMetadata(ctrl)
+	metadataMock.EXPECT().
+		GetID().
+		Return("mock")
+
+	mock := NewMockAuthenticationRedirectCalloutCarrier(ctrl)
+	mock.EXPECT().
+		HandleCallback(gomock.Any(), gomock.Any(), "nonce", "http://localhost/callback").
+		Return(&authenticationcallouts.User{
+			ID:    "42",
+			Email: "foo@example.com",
+		}, nil).
+		Times(1)
+	mock.EXPECT().
+		GetMetadata().
+		Return(metadataMock)
+
+	hookManager := NewHookManager()
+	hookManager.RegisterCalloutCarrier(mock)
+
+	// Act
+	user, err := hookManager.HandleAuthenticationCallback(context.Background(), nil, "mock", "nonce", "http://localhost/callback")
+
+	// Assert
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	require.Equal(t, "foo@example.com", user.Email)
+}
+
+// Test that the error returned by the callback handler is propagated.
+func TestHandleAuthenticationCallbackError(t *testing.T) {
+	// Arrange
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	metadataMock := NewMockAuthenticationMetadata(ctrl)
+	metadataMock.EXPECT().
+		GetID().
+		Return("mock")
+
+	mock := NewMockAuthenticationRedirectCalloutCarrier(ctrl)
+	mock.EXPECT().
+		HandleCallback(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("invalid nonce")).
+		Times(1)
+	mock.EXPECT().
+		GetMetadata().
+		Return(metadataMock)
+
+	hookManager := NewHookManager()
+	hookManager.RegisterCalloutCarrier(mock)
+
+	// Act
+	user, err := hookManager.HandleAuthenticationCallback(context.Background(), nil, "mock", "nonce", "http://localhost/callback")
+
+	// Assert
+	require.ErrorContains(t, err, "invalid nonce")
+	require.Nil(t, user)
+}
@@ -18,6 +18,7 @@ func NewHookManager() *HookManager {
 	return &HookManager{
 		HookManager: *hooksutil.NewHookManager([]reflect.Type{
 			reflect.TypeOf((*authenticationcallouts.AuthenticationCallouts)(nil)).Elem(),
+			reflect.TypeOf((*authenticationcallouts.AuthenticationRedirectCallouts)(nil)).Elem(),
 		}),
 	}
 }
@@ -0,0 +1,202 @@
+package notifications
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// Maximum number of the notifications listed in a single digest. The
+// remaining notifications are only counted.
+const maxDigestNotifications = 100
+
+// Maximum length of the notification text included in the subject.
+const maxSubjectTextLength = 100
+
+// Timeout of the whole SMTP session sending a single email.
+const smtpTimeout = 30 * time.Second
+
+// SMTP server settings used to send the email notifications. The email
+// notifications are disabled if the host is not specified.
+type SMTPSettings struct {
+	Host           string        `long:"smtp-host" description:"The SMTP server host sending the email notifications; the email notifications are disabled if it is not specified" env:"STORK_SERVER_SMTP_HOST"`
+	Port           int           `long:"smtp-port" description:"The SMTP server port" default:"25" env:"STORK_SERVER_SMTP_PORT"`
+	Username       string        `long:"smtp-username" description:"The user name to authenticate to the SMTP server; the authentication is disabled if it is not specified" env:"STORK_SERVER_SMTP_USERNAME"`
+	Password       string        `long:"smtp-password" description:"The password to authenticate to the SMTP server" env:"STORK_SERVER_SMTP_PASSWORD"`
+	From           string        `long:"smtp-from" description:"The sender address of the email notifications" default:"stork@localhost" env:"STORK_SERVER_SMTP_FROM"`
+	DigestInterval time.Duration `long:"smtp-digest-interval" description:"The interval of collecting the notifications sent in a single email to each recipient" default:"1m" env:"STORK_SERVER_SMTP_DIGEST_INTERVAL"`
+}
+
+// Checks if the email notifications are enabled.
+func (s *SMTPSettings) IsEnabled() bool {
+	return s != nil && s.Host != ""
+}
+
+// Collects the notifications awaiting sending to the email recipients.
+// The notifications for each recipient are sent together in a single
+// email to avoid flooding the recipients' mailboxes.
+type emailDigest struct {
+	mutex   sync.Mutex
+	pending map[string][]*Notification
+}
+
+// Creates new digest instance.
+func newEmailDigest() *emailDigest {
+	return &emailDigest{
+		pending: make(map[string][]*Notification),
+	}
+}
+
+// Appends the notification to the pending notifications of the recipient.
+func (d *emailDigest) add(recipient string, notification *Notification) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.pending[recipient] = append(d.pending[recipient], notification)
+}
+
+// Returns the pending notifications by recipients and clears them.
+func (d *emailDigest) flush() map[string][]*Notification {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	pending := d.pending
+	d.pending = make(map[string][]*Notification)
+	return pending
+}
+
+// Pattern matching the tags describing the objects in the event text,
+// e.g., <daemon id="1" name="dhcp4" appId="2" appType="kea">.
+var objectTagPattern = regexp.MustCompile(`<(\w+)((?:\s+\w+="[^"]*")*)\s*>`)
+
+// Pattern matching the attributes of the object tags.
+var tagAttributePattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// Replaces the tags describing the objects in the notification text with
+// their short human-readable descriptions, e.g., "daemon dhcp4".
+func plainText(text string) string {
+	return objectTagPattern.ReplaceAllStringFunc(text, func(tag string) string {
+		match := objectTagPattern.FindStringSubmatch(tag)
+		attributes := make(map[string]string)
+		for _, attribute := range tagAttributePattern.FindAllStringSubmatch(match[2], -1) {
+			attributes[attribute[1]] = attribute[2]
+		}
+		for _, key := range []string{"name", "login", "hostname", "address", "prefix", "id"} {
+			if value := attributes[key]; value != "" {
+				return fmt.Sprintf("%s %s", match[1], value)
+			}
+		}
+		return match[1]
+	})
+}
+
+// Returns the email subject and body listing the notifications.
+func formatDigest(notifications []*Notification) (subject string, body string) {
+	if len(notifications) == 1 {
+		text := strings.Join(strings.Fields(plainText(notifications[0].Text)), " ")
+		if len(text) > maxSubjectTextLength {
+			text = text[:maxSubjectTextLength] + "..."
+		}
+		subject = fmt.Sprintf("[Stork] %s: %s", notifications[0].Level, text)
+	} else {
+		subject = fmt.Sprintf("[Stork] %d notifications", len(notifications))
+	}
+
+	var builder strings.Builder
+	builder.WriteString("The Stork server reported the following:\r\n\r\n")
+	for i, notification := range notifications {
+		if i >= maxDigestNotifications {
+			fmt.Fprintf(&builder, "... and %d more notifications.\r\n", len(notifications)-i)
+			break
+		}
+		fmt.Fprintf(&builder, "%s [%s] %s\r\n",
+			notification.CreatedAt.UTC().Format("2006-01-02 15:04:05 MST"),
+			notification.Level, plainText(notification.Text))
+		if notification.Checker != "" {
+			fmt.Fprintf(&builder, "    Config checker: %s\r\n", notification.Checker)
+		}
+		if notification.Details != "" {
+			for _, line := range strings.Split(strings.TrimSpace(notification.Details), "\n") {
+				fmt.Fprintf(&builder, "    %s\r\n", strings.TrimRight(line, "\r"))
+			}
+		}
+		builder.WriteString("\r\n")
+	}
+	return subject, builder.String()
+}
+
+// Returns the email message with the headers and the plain text body.
+func buildEmailMessage(from, to, subject, body string, date time.Time) []byte {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "From: %s\r\n", from)
+	fmt.Fprintf(&builder, "To: %s\r\n", to)
+	fmt.Fprintf(&builder, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&builder, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	builder.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(body)
+	return []byte(builder.String())
+}
+
+// Sends the email to the recipient over the configured SMTP server. The
+// STARTTLS is used if the server supports it. The credentials are sent
+// only if the user name is specified.
+func sendEmail(settings *SMTPSettings, to, subject, body string) error {
+	if !settings.IsEnabled() {
+		return pkgerrors.New("SMTP server is not configured")
+	}
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return pkgerrors.New("email address and subject must not contain line breaks")
+	}
+	address := net.JoinHostPort(settings.Host, strconv.Itoa(settings.Port))
+	conn, err := net.DialTimeout("tcp", address, smtpTimeout)
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem connecting to SMTP server %s", address)
+	}
+	_ = conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, settings.Host)
+	if err != nil {
+		conn.Close()
+		return pkgerrors.Wrapf(err, "problem starting SMTP session with %s", address)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: settings.Host, MinVersion: tls.VersionTLS12}); err != nil {
+			return pkgerrors.Wrapf(err, "problem starting TLS with SMTP server %s", address)
+		}
+	}
+	if settings.Username != "" {
+		auth := smtp.PlainAuth("", settings.Username, settings.Password, settings.Host)
+		if err = client.Auth(auth); err != nil {
+			return pkgerrors.Wrapf(err, "problem authenticating to SMTP server %s", address)
+		}
+	}
+	if err = client.Mail(settings.From); err != nil {
+		return pkgerrors.Wrapf(err, "SMTP server %s rejected sender %s", address, settings.From)
+	}
+	if err = client.Rcpt(to); err != nil {
+		return pkgerrors.Wrapf(err, "SMTP server %s rejected recipient %s", address, to)
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem sending email to %s", to)
+	}
+	if _, err = writer.Write(buildEmailMessage(settings.From, to, subject, body, time.Now())); err != nil {
+		writer.Close()
+		return pkgerrors.Wrapf(err, "problem sending email to %s", to)
+	}
+	if err = writer.Close(); err != nil {
+		return pkgerrors.Wrapf(err, "SMTP server %s rejected email to %s", address, to)
+	}
+	return pkgerrors.Wrap(client.Quit(), "problem closing SMTP session")
+}
@@ -0,0 +1,312 @@
+package notifications
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Email received by the test SMTP server.
+type testEmail struct {
+	from string
+	to   []string
+	data string
+}
+
+// Minimal SMTP server receiving the emails in the unit tests. It rejects
+// the recipients with the "reject" local part.
+type testSMTPServer struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	emails   []testEmail
+	wg       sync.WaitGroup
+}
+
+// Starts the test SMTP server on a random local port.
+func newTestSMTPServer(t *testing.T) *testSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &testSMTPServer{
+		listener: listener,
+	}
+	server.wg.Add(1)
+	go func() {
+		defer server.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			server.wg.Add(1)
+			go func() {
+				defer server.wg.Done()
+				server.handle(conn)
+			}()
+		}
+	}()
+	return server
+}
+
+// Handles a single SMTP session.
+func (s *testSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		fmt.Fprintf(conn, "%s\r\n", line)
+	}
+	reply("220 localhost ESMTP test")
+	email := testEmail{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			email.from = strings.Trim(strings.TrimSpace(line)[len("MAIL FROM:"):], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			to := strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>")
+			if strings.HasPrefix(to, "reject@") {
+				reply("550 No such user")
+				continue
+			}
+			email.to = append(email.to, to)
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err = reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			email.data = data.String()
+			s.mutex.Lock()
+			s.emails = append(s.emails, email)
+			s.mutex.Unlock()
+			email = testEmail{}
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+// Returns the received emails.
+func (s *testSMTPServer) getEmails() []testEmail {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]testEmail{}, s.emails...)
+}
+
+// Returns the SMTP settings pointing to the test server.
+func (s *testSMTPServer) getSettings() *SMTPSettings {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return &SMTPSettings{
+		Host:           host,
+		Port:           portNumber,
+		From:           "stork@example.org",
+		DigestInterval: time.Hour,
+	}
+}
+
+// Stops the test server.
+func (s *testSMTPServer) close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+// Test that the email notifications are enabled only when the host
+// is specified.
+func TestSMTPSettingsIsEnabled(t *testing.T) {
+	var settings *SMTPSettings
+	require.False(t, settings.IsEnabled())
+	settings = &SMTPSettings{}
+	require.False(t, settings.IsEnabled())
+	settings.Host = "localhost"
+	require.True(t, settings.IsEnabled())
+}
+
+// Test that the object tags are replaced with the human-readable text.
+func TestPlainText(t *testing.T) {
+	require.Equal(t, "communication with daemon dhcp4 failed",
+		plainText(`communication with <daemon id="1" name="dhcp4" appId="2" appType="kea"> failed`))
+	require.Equal(t, "user admin added machine server1",
+		plainText(`<user id="1" login="admin" email="" name="" lastname=""> added <machine id="3" address="10.0.0.1" hostname="server1">`))
+	require.Equal(t, "subnet 192.0.2.0/24 is full",
+		plainText(`<subnet id="5" prefix="192.0.2.0/24"> is full`))
+	require.Equal(t, "a < b", plainText("a < b"))
+}
+
+// Test that the digest collects the notifications by recipients.
+func TestEmailDigest(t *testing.T) {
+	digest := newEmailDigest()
+	digest.add("a@example.org", &Notification{Text: "foo"})
+	digest.add("b@example.org", &Notification{Text: "bar"})
+	digest.add("a@example.org", &Notification{Text: "baz"})
+
+	pending := digest.flush()
+	require.Len(t, pending, 2)
+	require.Len(t, pending["a@example.org"], 2)
+	require.Equal(t, "baz", pending["a@example.org"][1].Text)
+	require.Len(t, pending["b@example.org"], 1)
+
+	require.Empty(t, digest.flush())
+}
+
+// Test formatting the email with a single notification.
+func TestFormatDigestSingle(t *testing.T) {
+	subject, body := formatDigest([]*Notification{
+		{
+			Level:     dbmodel.EvError,
+			Text:      `communication with <daemon id="1" name="dhcp4" appId="2" appType="kea"> failed`,
+			Details:   "connection refused\nretrying",
+			CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	})
+	require.Equal(t, "[Stork] error: communication with daemon dhcp4 failed", subject)
+	require.Contains(t, body, "2024-01-02 03:04:05 UTC [error] communication with daemon dhcp4 failed\r\n")
+	require.Contains(t, body, "    connection refused\r\n    retrying\r\n")
+}
+
+// Test formatting the digest with many notifications.
+func TestFormatDigestMultiple(t *testing.T) {
+	var notifications []*Notification
+	for i := 0; i < maxDigestNotifications+5; i++ {
+		notifications = append(notifications, &Notification{
+			Level:   dbmodel.EvWarning,
+			Text:    fmt.Sprintf("issue %d", i),
+			Checker: "foo_checker",
+		})
+	}
+	subject, body := formatDigest(notifications)
+	require.Equal(t, "[Stork] 105 notifications", subject)
+	require.Contains(t, body, "[warning] issue 0\r\n    Config checker: foo_checker\r\n")
+	require.Contains(t, body, "issue 99\r\n")
+	require.NotContains(t, body, "issue 100\r\n")
+	require.Contains(t, body, "... and 5 more notifications.")
+}
+
+// Test that the long notification text is truncated in the subject.
+func TestFormatDigestLongSubject(t *testing.T) {
+	subject, _ := formatDigest([]*Notification{
+		{
+			Level: dbmodel.EvInfo,
+			Text:  strings.Repeat("a", 200) + "\nfoo",
+		},
+	})
+	require.Equal(t, "[Stork] info: "+strings.Repeat("a", 100)+"...", subject)
+}
+
+// Test sending an email to the SMTP server.
+func TestSendEmail(t *testing.T) {
+	server := newTestSMTPServer(t)
+	defer server.close()
+
+	err := sendEmail(server.getSettings(), "oncall@example.org", "[Stork] test", "foo\r\nbar\r\n")
+	require.NoError(t, err)
+
+	emails := server.getEmails()
+	require.Len(t, emails, 1)
+	require.Equal(t, "stork@example.org", emails[0].from)
+	require.Equal(t, []string{"oncall@example.org"}, emails[0].to)
+	require.Contains(t, emails[0].data, "From: stork@example.org\r\n")
+	require.Contains(t, emails[0].data, "To: oncall@example.org\r\n")
+	require.Contains(t, emails[0].data, "Subject: [Stork] test\r\n")
+	require.Contains(t, emails[0].data, "Content-Type: text/plain; charset=UTF-8\r\n")
+	require.True(t, strings.HasSuffix(emails[0].data, "\r\n\r\nfoo\r\nbar\r\n"))
+}
+
+// Test that sending an email fails when the SMTP server rejects the
+// recipient or it is not configured.
+func TestSendEmailErrors(t *testing.T) {
+	server := newTestSMTPServer(t)
+	defer server.close()
+
+	err := sendEmail(server.getSettings(), "reject@example.org", "[Stork] test", "foo")
+	require.ErrorContains(t, err, "rejected recipient")
+
+	err = sendEmail(server.getSettings(), "oncall@example.org\r\nBcc: foo@example.org", "[Stork] test", "foo")
+	require.Error(t, err)
+
+	err = sendEmail(&SMTPSettings{}, "oncall@example.org", "[Stork] test", "foo")
+	require.ErrorContains(t, err, "not configured")
+
+	require.Empty(t, server.getEmails())
+}
+
+// Test that the notifier sends a test email immediately.
+func TestNotifierSendTestEmail(t *testing.T) {
+	server := newTestSMTPServer(t)
+	defer server.close()
+
+	n := NewNotifier(nil, server.getSettings())
+	defer n.Shutdown()
+
+	err := n.SendTestEmail("oncall@example.org")
+	require.NoError(t, err)
+
+	emails := server.getEmails()
+	require.Len(t, emails, 1)
+	require.Contains(t, emails[0].data, "Subject: [Stork] Test notification\r\n")
+
+	// The SMTP server is not configured.
+	n2 := NewNotifier(nil, nil)
+	defer n2.Shutdown()
+	require.Error(t, n2.SendTestEmail("oncall@example.org"))
+}
+
+// Test that the notifier sends a single email to each recipient with
+// all collected notifications.
+func TestNotifierSendDigests(t *testing.T) {
+	server := newTestSMTPServer(t)
+	defer server.close()
+
+	n := &notifier{
+		smtpSettings: server.getSettings(),
+		digest:       newEmailDigest(),
+	}
+	n.digest.add("a@example.org", &Notification{Level: dbmodel.EvError, Text: "foo"})
+	n.digest.add("a@example.org", &Notification{Level: dbmodel.EvError, Text: "bar"})
+	n.digest.add("b@example.org", &Notification{Level: dbmodel.EvError, Text: "foo"})
+	n.sendDigests()
+
+	emails := server.getEmails()
+	require.Len(t, emails, 2)
+	for _, email := range emails {
+		require.Len(t, email.to, 1)
+		switch email.to[0] {
+		case "a@example.org":
+			require.Contains(t, email.data, "Subject: [Stork] 2 notifications\r\n")
+		case "b@example.org":
+			require.Contains(t, email.data, "Subject: [Stork] error: foo\r\n")
+		default:
+			require.Fail(t, "unexpected recipient %s", email.to[0])
+		}
+	}
+
+	// The digests have been sent.
+	n.sendDigests()
+	require.Len(t, server.getEmails(), 2)
+}
@@ -0,0 +1,380 @@
+package notifications
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"isc.org/stork/server/auth"
+	dbops "isc.org/stork/server/database"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Type of the notification.
+type NotificationType string
+
+// Supported notification types.
+const (
+	// Notification about an event registered in the event center.
+	NotificationTypeEvent NotificationType = "event"
+	// Notification about a new issue found by the configuration review.
+	NotificationTypeConfigReview NotificationType = "config-review"
+)
+
+// Size of the queue holding the notifications awaiting delivery. The
+// notifications are dropped when the queue is full.
+const notificationQueueSize = 1000
+
+// Size of the queue holding the notifications awaiting delivery over a
+// single channel. The notifications are dropped when the queue is full,
+// e.g., when the receiver is unavailable and the deliveries are retried.
+const channelQueueSize = 100
+
+// Timeout of a single HTTP request sending a notification.
+const requestTimeout = 10 * time.Second
+
+// Initial delay between the delivery retries. It is doubled after
+// each failed attempt.
+const initialRetryDelay = time.Second
+
+// Maximum delay between the delivery retries.
+const maxRetryDelay = 5 * time.Minute
+
+// A notification sent over the notification channels.
+type Notification struct {
+	Type      NotificationType
+	Level     dbmodel.EventLevel
+	Text      string
+	Details   string
+	CreatedAt time.Time
+	Relations dbmodel.Relations
+	// Name of the configuration checker which found the issue. It is
+	// empty for the event notifications.
+	Checker string
+}
+
+// Creates a notification from the event. The current time is used
+// when the event lacks the creation time.
+func NewEventNotification(event *dbmodel.Event) *Notification {
+	notification := &Notification{
+		Type:      NotificationTypeEvent,
+		Level:     event.Level,
+		Text:      event.Text,
+		Details:   event.Details,
+		CreatedAt: event.CreatedAt,
+	}
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now().UTC()
+	}
+	if event.Relations != nil {
+		notification.Relations = *event.Relations
+	}
+	return notification
+}
+
+// Creates a notification about a new configuration review issue.
+func NewConfigReviewNotification(checker, text string, relations dbmodel.Relations) *Notification {
+	return &Notification{
+		Type:      NotificationTypeConfigReview,
+		Level:     dbmodel.EvWarning,
+		Text:      text,
+		CreatedAt: time.Now().UTC(),
+		Relations: relations,
+		Checker:   checker,
+	}
+}
+
+// An interface to the notifier sending the notifications over the
+// configured notification channels and to the email subscribers.
+type Notifier interface {
+	Notify(notification *Notification)
+	SendTestEmail(to string) error
+	Shutdown()
+}
+
+// Notifier implementation. It queues the notifications and sends them
+// in the background over the enabled notification channels stored in
+// the database. The notifications for the email subscribers are collected
+// and sent periodically in digests.
+type notifier struct {
+	db            *dbops.PgDB
+	client        *http.Client
+	notifications chan *Notification
+	ctx           context.Context
+	cancel        context.CancelFunc
+	wg            *sync.WaitGroup
+	// Delay before the first retry. It is a field to allow for
+	// shortening it in the unit tests.
+	retryDelay time.Duration
+	// SMTP server settings. The email notifications are disabled if
+	// the server is not configured.
+	smtpSettings *SMTPSettings
+	digest       *emailDigest
+	// Queues of the notifications awaiting delivery over the channels,
+	// by channel ID. Each queue is served by a single goroutine. They
+	// are only accessed in the main loop.
+	channelQueues map[int64]chan channelDelivery
+}
+
+// A notification awaiting delivery over the channel. The channel is
+// fetched from the database when the notification is dispatched, so
+// the delivery uses the current channel settings.
+type channelDelivery struct {
+	channel      *dbmodel.NotificationChannel
+	notification *Notification
+}
+
+// Creates new notifier instance and starts its main loop. The SMTP
+// settings may be nil, in which case the email notifications are
+// disabled.
+func NewNotifier(db *dbops.PgDB, smtpSettings *SMTPSettings) Notifier {
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &notifier{
+		db: db,
+		client: &http.Client{
+			Timeout: requestTimeout,
+		},
+		notifications: make(chan *Notification, notificationQueueSize),
+		ctx:           ctx,
+		cancel:        cancel,
+		wg:            &sync.WaitGroup{},
+		retryDelay:    initialRetryDelay,
+		smtpSettings:  smtpSettings,
+		digest:        newEmailDigest(),
+		channelQueues: make(map[int64]chan channelDelivery),
+	}
+	n.wg.Add(1)
+	go n.mainLoop()
+
+	log.Printf("Started Notifier")
+	return n
+}
+
+// Queues the notification for sending. The notification is dropped
+// when the queue is full to avoid blocking the caller.
+func (n *notifier) Notify(notification *Notification) {
+	select {
+	case n.notifications <- notification:
+	default:
+		log.WithField("text", notification.Text).Warn("Notification queue is full; dropping the notification")
+	}
+}
+
+// Sends a test email to the specified address. It is sent immediately,
+// bypassing the digest, to let the user verify the SMTP configuration
+// and the address.
+func (n *notifier) SendTestEmail(to string) error {
+	subject := "[Stork] Test notification"
+	body := "This is a test notification sent by the Stork server.\r\n" +
+		"The email notifications are configured properly.\r\n"
+	return sendEmail(n.smtpSettings, to, subject, body)
+}
+
+// Stops the notifier. The pending deliveries over the notification
+// channels are cancelled. The pending email digests are sent.
+func (n *notifier) Shutdown() {
+	log.Printf("Stopping Notifier")
+	n.cancel()
+	n.wg.Wait()
+	log.Printf("Stopped Notifier")
+}
+
+// Receives the queued notifications and sends them over the matching
+// channels. Each channel has its own delivery queue and goroutine, so a
+// slow or unavailable receiver does not delay the other channels. The email
+// digests are sent periodically if the SMTP server is configured.
+func (n *notifier) mainLoop() {
+	defer n.wg.Done()
+	var digestTicks <-chan time.Time
+	if n.smtpSettings.IsEnabled() {
+		interval := n.smtpSettings.DigestInterval
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		digestTicks = ticker.C
+	}
+	for {
+		select {
+		case <-n.ctx.Done():
+			if n.smtpSettings.IsEnabled() {
+				n.sendDigests()
+			}
+			return
+		case notification := <-n.notifications:
+			n.dispatch(notification)
+		case <-digestTicks:
+			n.wg.Add(1)
+			go func() {
+				defer n.wg.Done()
+				n.sendDigests()
+			}()
+		}
+	}
+}
+
+// Sends the notification over the matching channels and appends it to
+// the digests of the matching email subscribers.
+func (n *notifier) dispatch(notification *Notification) {
+	channels, err := dbmodel.GetEnabledNotificationChannels(n.db)
+	if err != nil {
+		log.WithError(err).Error("Problem getting notification channels")
+	} else {
+		n.stopRemovedChannelQueues(channels)
+		for i := range channels {
+			if !channels[i].Accepts(notification.Level, &notification.Relations) {
+				continue
+			}
+			n.enqueueDelivery(&channels[i], notification)
+		}
+	}
+
+	if !n.smtpSettings.IsEnabled() {
+		return
+	}
+	subscriptions, err := dbmodel.GetEnabledEmailSubscriptions(n.db)
+	if err != nil {
+		log.WithError(err).Error("Problem getting email subscriptions")
+		return
+	}
+	// The same address may be used in several subscriptions. Include the
+	// notification in its digest only once.
+	recipients := make(map[string]bool)
+	subscribers := make(map[int]*dbmodel.SystemUser)
+	for i := range subscriptions {
+		email := subscriptions[i].Email
+		if recipients[email] || !subscriptions[i].Accepts(notification.Level, &notification.Relations) {
+			continue
+		}
+		subscriber, ok := subscribers[subscriptions[i].UserID]
+		if !ok {
+			subscriber, err = n.getSubscriber(subscriptions[i].UserID)
+			if err != nil {
+				log.WithError(err).
+					WithField("userID", subscriptions[i].UserID).
+					Error("Problem getting email subscriber")
+			}
+			subscribers[subscriptions[i].UserID] = subscriber
+		}
+		if !isPermittedToReceive(subscriber, email, notification) {
+			continue
+		}
+		recipients[email] = true
+		n.digest.add(email, notification)
+	}
+}
+
+// Queues the notification for delivery over the channel. It starts the
+// goroutine serving the channel's queue if it is not running yet. The
+// notification is dropped when the queue is full.
+func (n *notifier) enqueueDelivery(channel *dbmodel.NotificationChannel, notification *Notification) {
+	queue, ok := n.channelQueues[channel.ID]
+	if !ok {
+		queue = make(chan channelDelivery, channelQueueSize)
+		n.channelQueues[channel.ID] = queue
+		n.wg.Add(1)
+		go n.channelLoop(queue)
+	}
+	select {
+	case queue <- channelDelivery{channel: channel, notification: notification}:
+	default:
+		log.WithField("channel", channel.Name).
+			WithField("text", notification.Text).
+			Warn("Notification channel queue is full; dropping the notification")
+	}
+}
+
+// Stops the goroutines serving the queues of the channels which have
+// been deleted or disabled. The goroutines exit after delivering the
+// notifications remaining in their queues.
+func (n *notifier) stopRemovedChannelQueues(channels []dbmodel.NotificationChannel) {
+	enabled := make(map[int64]bool)
+	for i := range channels {
+		enabled[channels[i].ID] = true
+	}
+	for id, queue := range n.channelQueues {
+		if !enabled[id] {
+			close(queue)
+			delete(n.channelQueues, id)
+		}
+	}
+}
+
+// Delivers the notifications from the channel's queue one by one until
+// the queue is closed or the notifier is stopped.
+func (n *notifier) channelLoop(queue <-chan channelDelivery) {
+	defer n.wg.Done()
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case delivery, ok := <-queue:
+			if !ok {
+				return
+			}
+			if err := n.deliver(delivery.channel, delivery.notification); err != nil {
+				log.WithError(err).
+					WithField("channel", delivery.channel.Name).
+					Error("Problem sending notification")
+			}
+		}
+	}
+}
+
+// Fetches the user owning the email subscription with the permissions
+// granted by the user's groups. It returns nil if the user does not exist.
+func (n *notifier) getSubscriber(userID int) (*dbmodel.SystemUser, error) {
+	user, err := dbmodel.GetUserByID(n.db, userID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	if err = dbmodel.PopulateUserPermissions(n.db, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Checks if the notification may be sent to the subscriber's address.
+// The subscriber must be permitted to view the objects the notification
+// is related to, so the subscription does not reveal more than the user
+// can see in the UI. The address must be the subscriber's own address,
+// so the notifications are not sent to the addresses the user does not
+// own, e.g., after the user has changed the address.
+func isPermittedToReceive(subscriber *dbmodel.SystemUser, email string, notification *Notification) bool {
+	if subscriber == nil || !strings.EqualFold(subscriber.Email, email) {
+		return false
+	}
+	return auth.AuthorizeView(subscriber, dbmodel.PermissionTarget{
+		AppID:    notification.Relations.AppID,
+		DaemonID: notification.Relations.DaemonID,
+		SubnetID: notification.Relations.SubnetID,
+	})
+}
+
+// Sends the collected notifications to the email recipients. Each
+// recipient receives a single email.
+func (n *notifier) sendDigests() {
+	for recipient, notifications := range n.digest.flush() {
+		subject, body := formatDigest(notifications)
+		if err := sendEmail(n.smtpSettings, recipient, subject, body); err != nil {
+			log.WithError(err).
+				WithField("recipient", recipient).
+				Errorf("Problem sending email with %d notifications", len(notifications))
+		}
+	}
+}
+
+// Sends the notification over the channel according to the channel type.
+func (n *notifier) deliver(channel *dbmodel.NotificationChannel, notification *Notification) error {
+	switch channel.Type {
+	case dbmodel.NotificationChannelTypeWebhook:
+		return sendWebhook(n.ctx, n.client, channel, notification, n.retryDelay)
+	default:
+		return pkgerrors.Errorf("unsupported notification channel type %s", channel.Type)
+	}
+}
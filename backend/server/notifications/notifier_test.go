@@ -0,0 +1,294 @@
+package notifications
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+)
+
+// Test creating a notification from an event.
+func TestNewEventNotification(t *testing.T) {
+	event := &dbmodel.Event{
+		Text:      "foo",
+		Level:     dbmodel.EvWarning,
+		Details:   "bar",
+		CreatedAt: time.Now(),
+		Relations: &dbmodel.Relations{
+			AppID: 2,
+		},
+	}
+	notification := NewEventNotification(event)
+	require.Equal(t, NotificationTypeEvent, notification.Type)
+	require.Equal(t, dbmodel.EvWarning, notification.Level)
+	require.Equal(t, "foo", notification.Text)
+	require.Equal(t, "bar", notification.Details)
+	require.Equal(t, event.CreatedAt, notification.CreatedAt)
+	require.EqualValues(t, 2, notification.Relations.AppID)
+	require.Empty(t, notification.Checker)
+
+	// The event without relations.
+	event.Relations = nil
+	notification = NewEventNotification(event)
+	require.Zero(t, notification.Relations)
+}
+
+// Test creating a notification about a config review issue.
+func TestNewConfigReviewNotification(t *testing.T) {
+	notification := NewConfigReviewNotification("foo_checker", "issue", dbmodel.Relations{DaemonID: 3})
+	require.Equal(t, NotificationTypeConfigReview, notification.Type)
+	require.Equal(t, dbmodel.EvWarning, notification.Level)
+	require.Equal(t, "issue", notification.Text)
+	require.Equal(t, "foo_checker", notification.Checker)
+	require.EqualValues(t, 3, notification.Relations.DaemonID)
+	require.False(t, notification.CreatedAt.IsZero())
+}
+
+// Test that the notifier sends the notifications over the enabled
+// channels accepting them.
+func TestNotifierNotify(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	receiver := &testReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	channels := []*dbmodel.NotificationChannel{
+		{
+			Name:    "all",
+			Type:    dbmodel.NotificationChannelTypeWebhook,
+			Enabled: true,
+			URL:     server.URL,
+		},
+		{
+			Name:    "errors",
+			Type:    dbmodel.NotificationChannelTypeWebhook,
+			Enabled: true,
+			URL:     server.URL,
+			Level:   dbmodel.EvError,
+		},
+		{
+			Name: "disabled",
+			Type: dbmodel.NotificationChannelTypeWebhook,
+			URL:  server.URL,
+		},
+	}
+	for _, channel := range channels {
+		_, err := dbmodel.AddNotificationChannel(db, channel)
+		require.NoError(t, err)
+	}
+
+	n := NewNotifier(db, nil)
+	defer n.Shutdown()
+
+	n.Notify(&Notification{Type: NotificationTypeEvent, Level: dbmodel.EvWarning, Text: "warning"})
+	require.Eventually(t, func() bool {
+		return receiver.getRequestCount() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	n.Notify(&Notification{Type: NotificationTypeEvent, Level: dbmodel.EvError, Text: "error"})
+	require.Eventually(t, func() bool {
+		return receiver.getRequestCount() == 3
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// Test that the notifications are collected for the matching email
+// subscribers and sent when the notifier is stopped.
+func TestNotifierEmailSubscriptions(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	server := newTestSMTPServer(t)
+	defer server.close()
+
+	user := &dbmodel.SystemUser{
+		Login:    "oncall",
+		Email:    "oncall@example.org",
+		Name:     "On",
+		Lastname: "Call",
+		Groups:   []*dbmodel.SystemGroup{{ID: dbmodel.SuperAdminGroupID}},
+	}
+	_, err := dbmodel.CreateUser(db, user)
+	require.NoError(t, err)
+
+	subscriptions := []*dbmodel.EmailSubscription{
+		{
+			UserID:  user.ID,
+			Email:   "oncall@example.org",
+			Enabled: true,
+			Level:   dbmodel.EvError,
+		},
+		{
+			UserID:    user.ID,
+			Email:     "oncall@example.org",
+			Enabled:   true,
+			Level:     dbmodel.EvWarning,
+			DaemonIDs: []int64{3},
+		},
+		{
+			UserID: user.ID,
+			Email:  "disabled@example.org",
+		},
+	}
+	for _, subscription := range subscriptions {
+		err := dbmodel.AddEmailSubscription(db, subscription)
+		require.NoError(t, err)
+	}
+
+	n := NewNotifier(db, server.getSettings())
+	n.Notify(&Notification{Type: NotificationTypeEvent, Level: dbmodel.EvError, Text: "error", Relations: dbmodel.Relations{DaemonID: 3}})
+	n.Notify(&Notification{Type: NotificationTypeEvent, Level: dbmodel.EvWarning, Text: "warning", Relations: dbmodel.Relations{DaemonID: 4}})
+	n.Notify(&Notification{Type: NotificationTypeEvent, Level: dbmodel.EvWarning, Text: "daemon warning", Relations: dbmodel.Relations{DaemonID: 3}})
+
+	// Wait for the notifications to be collected.
+	require.Eventually(t, func() bool {
+		return len(n.(*notifier).notifications) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	n.Shutdown()
+
+	emails := server.getEmails()
+	require.Len(t, emails, 1)
+	require.Equal(t, []string{"oncall@example.org"}, emails[0].to)
+	require.Contains(t, emails[0].data, "Subject: [Stork] 2 notifications\r\n")
+	require.Contains(t, emails[0].data, "[error] error\r\n")
+	require.Contains(t, emails[0].data, "[warning] daemon warning\r\n")
+	require.NotContains(t, emails[0].data, "[warning] warning\r\n")
+}
+
+// Test that the email subscribers receive only the notifications related
+// to the objects they are permitted to view, and only at their own
+// addresses.
+func TestNotifierEmailSubscriptionsPermissions(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	server := newTestSMTPServer(t)
+	defer server.close()
+
+	// The user permitted to view the daemon with ID 3 only.
+	group := &dbmodel.SystemGroup{
+		Name: "viewers",
+		Permissions: []*dbmodel.SystemGroupPermission{
+			{Permission: dbmodel.PermissionView, DaemonID: 3},
+		},
+	}
+	_, err := dbmodel.AddGroup(db, group)
+	require.NoError(t, err)
+	viewer := &dbmodel.SystemUser{
+		Login:    "viewer",
+		Email:    "viewer@example.org",
+		Name:     "View",
+		Lastname: "Er",
+		Groups:   []*dbmodel.SystemGroup{group},
+	}
+	_, err = dbmodel.CreateUser(db, viewer)
+	require.NoError(t, err)
+
+	// The user without any permissions.
+	guest := &dbmodel.SystemUser{
+		Login:    "guest",
+		Email:    "guest@example.org",
+		Name:     "Gu",
+		Lastname: "Est",
+	}
+	_, err = dbmodel.CreateUser(db, guest)
+	require.NoError(t, err)
+
+	subscriptions := []*dbmodel.EmailSubscription{
+		{
+			UserID:  viewer.ID,
+			Email:   "viewer@example.org",
+			Enabled: true,
+			Level:   dbmodel.EvWarning,
+		},
+		// Not the user's address.
+		{
+			UserID:  viewer.ID,
+			Email:   "noc@example.org",
+			Enabled: true,
+			Level:   dbmodel.EvWarning,
+		},
+		{
+			UserID:  guest.ID,
+			Email:   "guest@example.org",
+			Enabled: true,
+			Level:   dbmodel.EvWarning,
+		},
+	}
+	for _, subscription := range subscriptions {
+		err := dbmodel.AddEmailSubscription(db, subscription)
+		require.NoError(t, err)
+	}
+
+	n := NewNotifier(db, server.getSettings())
+	n.Notify(&Notification{Type: NotificationTypeEvent, Level: dbmodel.EvError, Text: "daemon error", Relations: dbmodel.Relations{DaemonID: 3}})
+	n.Notify(&Notification{Type: NotificationTypeEvent, Level: dbmodel.EvError, Text: "other error", Relations: dbmodel.Relations{DaemonID: 4}})
+	n.Notify(&Notification{Type: NotificationTypeEvent, Level: dbmodel.EvError, Text: "server error"})
+
+	require.Eventually(t, func() bool {
+		return len(n.(*notifier).notifications) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	n.Shutdown()
+
+	emails := server.getEmails()
+	require.Len(t, emails, 1)
+	require.Equal(t, []string{"viewer@example.org"}, emails[0].to)
+	require.Contains(t, emails[0].data, "Subject: [Stork] error: daemon error\r\n")
+	require.Contains(t, emails[0].data, "[error] daemon error\r\n")
+	require.NotContains(t, emails[0].data, "other error")
+	require.NotContains(t, emails[0].data, "server error")
+}
+
+// Test that the notifications are delivered over the channel's queue one
+// by one, that they are dropped when the queue is full, and that the
+// queue is stopped when the channel is removed.
+func TestNotifierChannelQueue(t *testing.T) {
+	release := make(chan struct{})
+	receiver := &testReceiver{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-release
+		receiver.ServeHTTP(w, req)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := &notifier{
+		client:        server.Client(),
+		ctx:           ctx,
+		cancel:        cancel,
+		wg:            &sync.WaitGroup{},
+		retryDelay:    time.Millisecond,
+		channelQueues: make(map[int64]chan channelDelivery),
+	}
+	channel := &dbmodel.NotificationChannel{
+		ID:   1,
+		Name: "chat",
+		Type: dbmodel.NotificationChannelTypeWebhook,
+		URL:  server.URL,
+	}
+
+	// The receiver blocks the first delivery, so the remaining
+	// notifications wait in the queue until it is full.
+	for i := 0; i < channelQueueSize+10; i++ {
+		n.enqueueDelivery(channel, newTestNotification())
+	}
+	require.Len(t, n.channelQueues, 1)
+	close(release)
+	require.Eventually(t, func() bool {
+		return receiver.getRequestCount() >= channelQueueSize
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The queue should be stopped when the channel is no longer enabled.
+	n.stopRemovedChannelQueues([]dbmodel.NotificationChannel{})
+	require.Empty(t, n.channelQueues)
+	n.wg.Wait()
+	require.LessOrEqual(t, receiver.getRequestCount(), channelQueueSize+1)
+}
@@ -0,0 +1,148 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"text/template"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Content type of the webhook request body used when the channel
+// does not specify it.
+const defaultContentType = "application/json"
+
+// Default JSON payload sent in the webhook request when the channel
+// does not specify the body template.
+type webhookPayload struct {
+	Type      NotificationType `json:"type"`
+	Level     string           `json:"level"`
+	Text      string           `json:"text"`
+	Details   string           `json:"details,omitempty"`
+	CreatedAt time.Time        `json:"createdAt"`
+	MachineID int64            `json:"machineId,omitempty"`
+	AppID     int64            `json:"appId,omitempty"`
+	DaemonID  int64            `json:"daemonId,omitempty"`
+	SubnetID  int64            `json:"subnetId,omitempty"`
+	Checker   string           `json:"checker,omitempty"`
+}
+
+// Functions available in the body templates. The json function returns
+// a value encoded as JSON. It is useful for inserting the notification
+// text into a JSON document because it quotes and escapes the text.
+var templateFuncs = template.FuncMap{
+	"json": func(value any) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+// Parses the body template. It returns an error if the template is
+// invalid. It is used to validate the template before it is stored in
+// the database.
+func ParseBodyTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("body").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "invalid notification body template")
+	}
+	return tmpl, nil
+}
+
+// Renders the webhook request body for the notification. If the channel
+// specifies the body template, it is executed with the notification as
+// data. Otherwise, the default JSON payload is returned.
+func renderWebhookBody(channel *dbmodel.NotificationChannel, notification *Notification) ([]byte, error) {
+	if channel.BodyTemplate == "" {
+		payload := webhookPayload{
+			Type:      notification.Type,
+			Level:     notification.Level.String(),
+			Text:      notification.Text,
+			Details:   notification.Details,
+			CreatedAt: notification.CreatedAt,
+			MachineID: notification.Relations.MachineID,
+			AppID:     notification.Relations.AppID,
+			DaemonID:  notification.Relations.DaemonID,
+			SubnetID:  notification.Relations.SubnetID,
+			Checker:   notification.Checker,
+		}
+		body, err := json.Marshal(payload)
+		return body, pkgerrors.Wrap(err, "problem serializing notification payload")
+	}
+	tmpl, err := ParseBodyTemplate(channel.BodyTemplate)
+	if err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	if err = tmpl.Execute(&body, notification); err != nil {
+		return nil, pkgerrors.Wrap(err, "problem executing notification body template")
+	}
+	return body.Bytes(), nil
+}
+
+// Sends a single webhook request. It returns a flag indicating whether
+// the request may succeed when it is retried.
+func postWebhook(ctx context.Context, client *http.Client, channel *dbmodel.NotificationChannel, body []byte) (retry bool, err error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, channel.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, pkgerrors.Wrapf(err, "problem creating request to %s", channel.URL)
+	}
+	contentType := channel.ContentType
+	if contentType == "" {
+		contentType = defaultContentType
+	}
+	request.Header.Set("Content-Type", contentType)
+
+	response, err := client.Do(request)
+	if err != nil {
+		return true, pkgerrors.Wrapf(err, "problem sending request to %s", channel.URL)
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return false, nil
+	}
+	// Server errors and throttling are usually transient.
+	retry = response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+	return retry, pkgerrors.Errorf("request to %s returned status %d", channel.URL, response.StatusCode)
+}
+
+// Returns the delay before the next delivery retry. The delay is doubled
+// after each failed attempt but it does not exceed the maxRetryDelay.
+func nextRetryDelay(delay time.Duration) time.Duration {
+	return min(2*delay, maxRetryDelay)
+}
+
+// Sends the notification in the HTTP POST request to the channel's URL.
+// A failed delivery is retried up to the channel's maximum number of
+// retries with an exponentially growing delay, capped at maxRetryDelay.
+// Only the network errors, server errors and throttling responses are
+// retried.
+func sendWebhook(ctx context.Context, client *http.Client, channel *dbmodel.NotificationChannel, notification *Notification, retryDelay time.Duration) error {
+	body, err := renderWebhookBody(channel, notification)
+	if err != nil {
+		return err
+	}
+	delay := retryDelay
+	for attempt := int64(0); ; attempt++ {
+		retry, err := postWebhook(ctx, client, channel, body)
+		if err == nil || !retry || attempt >= channel.MaxRetries {
+			return err
+		}
+		log.WithError(err).
+			WithField("channel", channel.Name).
+			Warnf("Retrying notification delivery in %s", delay)
+		select {
+		case <-ctx.Done():
+			return pkgerrors.Wrap(ctx.Err(), "notification delivery cancelled")
+		case <-time.After(delay):
+		}
+		delay = nextRetryDelay(delay)
+	}
+}
@@ -0,0 +1,236 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Test receiver recording the webhook requests and responding with
+// the specified status codes.
+type testReceiver struct {
+	mutex        sync.Mutex
+	bodies       []string
+	contentTypes []string
+	statuses     []int
+}
+
+// Records the request and responds with the next status code. It responds
+// with 200 when no more status codes are specified.
+func (r *testReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	body, _ := io.ReadAll(req.Body)
+	r.bodies = append(r.bodies, string(body))
+	r.contentTypes = append(r.contentTypes, req.Header.Get("Content-Type"))
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status = r.statuses[0]
+		r.statuses = r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+// Returns the number of the received requests.
+func (r *testReceiver) getRequestCount() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.bodies)
+}
+
+// Returns a test notification.
+func newTestNotification() *Notification {
+	return &Notification{
+		Type:      NotificationTypeEvent,
+		Level:     dbmodel.EvError,
+		Text:      `communication with "server1" failed`,
+		Details:   "connection refused",
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Relations: dbmodel.Relations{
+			MachineID: 1,
+			DaemonID:  3,
+		},
+	}
+}
+
+// Test that the default JSON payload is sent when the body template
+// is not specified.
+func TestSendWebhookDefaultPayload(t *testing.T) {
+	receiver := &testReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	channel := &dbmodel.NotificationChannel{
+		Name: "chat",
+		Type: dbmodel.NotificationChannelTypeWebhook,
+		URL:  server.URL,
+	}
+	err := sendWebhook(context.Background(), server.Client(), channel, newTestNotification(), time.Millisecond)
+	require.NoError(t, err)
+
+	require.Len(t, receiver.bodies, 1)
+	require.Equal(t, "application/json", receiver.contentTypes[0])
+
+	var payload map[string]any
+	err = json.Unmarshal([]byte(receiver.bodies[0]), &payload)
+	require.NoError(t, err)
+	require.Equal(t, "event", payload["type"])
+	require.Equal(t, "error", payload["level"])
+	require.Equal(t, `communication with "server1" failed`, payload["text"])
+	require.Equal(t, "connection refused", payload["details"])
+	require.Equal(t, "2024-01-02T03:04:05Z", payload["createdAt"])
+	require.EqualValues(t, 1, payload["machineId"])
+	require.EqualValues(t, 3, payload["daemonId"])
+	require.NotContains(t, payload, "appId")
+	require.NotContains(t, payload, "checker")
+}
+
+// Test that the body is rendered from the channel's template.
+func TestSendWebhookTemplate(t *testing.T) {
+	receiver := &testReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	channel := &dbmodel.NotificationChannel{
+		Name:         "chat",
+		Type:         dbmodel.NotificationChannelTypeWebhook,
+		URL:          server.URL,
+		BodyTemplate: `{"text": {{ json (printf "[%s] %s" .Level .Text) }}, "daemon": {{ .Relations.DaemonID }}}`,
+		ContentType:  "application/vnd.chat+json",
+	}
+	err := sendWebhook(context.Background(), server.Client(), channel, newTestNotification(), time.Millisecond)
+	require.NoError(t, err)
+
+	require.Len(t, receiver.bodies, 1)
+	require.Equal(t, "application/vnd.chat+json", receiver.contentTypes[0])
+	require.JSONEq(t, `{"text": "[error] communication with \"server1\" failed", "daemon": 3}`, receiver.bodies[0])
+}
+
+// Test that an invalid template is rejected.
+func TestParseBodyTemplate(t *testing.T) {
+	_, err := ParseBodyTemplate(`{"text": {{ json .Text }}}`)
+	require.NoError(t, err)
+
+	_, err = ParseBodyTemplate(`{"text": {{ json .Text }`)
+	require.Error(t, err)
+
+	_, err = ParseBodyTemplate(`{{ unknown .Text }}`)
+	require.Error(t, err)
+}
+
+// Test that the template referencing a non-existing field is not sent.
+func TestSendWebhookTemplateExecutionError(t *testing.T) {
+	receiver := &testReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	channel := &dbmodel.NotificationChannel{
+		Name:         "chat",
+		Type:         dbmodel.NotificationChannelTypeWebhook,
+		URL:          server.URL,
+		BodyTemplate: `{{ .Foo }}`,
+	}
+	err := sendWebhook(context.Background(), server.Client(), channel, newTestNotification(), time.Millisecond)
+	require.Error(t, err)
+	require.Zero(t, receiver.getRequestCount())
+}
+
+// Test that the retry delay is doubled and capped.
+func TestNextRetryDelay(t *testing.T) {
+	require.Equal(t, 2*time.Second, nextRetryDelay(time.Second))
+	require.Equal(t, 4*time.Minute, nextRetryDelay(2*time.Minute))
+	require.Equal(t, maxRetryDelay, nextRetryDelay(4*time.Minute))
+	require.Equal(t, maxRetryDelay, nextRetryDelay(maxRetryDelay))
+}
+
+// Test that the delivery is retried after the server errors.
+func TestSendWebhookRetry(t *testing.T) {
+	receiver := &testReceiver{
+		statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+	}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	channel := &dbmodel.NotificationChannel{
+		Name:       "chat",
+		Type:       dbmodel.NotificationChannelTypeWebhook,
+		URL:        server.URL,
+		MaxRetries: 2,
+	}
+	err := sendWebhook(context.Background(), server.Client(), channel, newTestNotification(), time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, 3, receiver.getRequestCount())
+}
+
+// Test that the delivery fails when the maximum number of retries is
+// exceeded.
+func TestSendWebhookRetriesExceeded(t *testing.T) {
+	receiver := &testReceiver{
+		statuses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+	}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	channel := &dbmodel.NotificationChannel{
+		Name:       "chat",
+		Type:       dbmodel.NotificationChannelTypeWebhook,
+		URL:        server.URL,
+		MaxRetries: 1,
+	}
+	err := sendWebhook(context.Background(), server.Client(), channel, newTestNotification(), time.Millisecond)
+	require.ErrorContains(t, err, "returned status 502")
+	require.Equal(t, 2, receiver.getRequestCount())
+}
+
+// Test that the client errors are not retried.
+func TestSendWebhookNoRetryOnClientError(t *testing.T) {
+	receiver := &testReceiver{
+		statuses: []int{http.StatusBadRequest},
+	}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	channel := &dbmodel.NotificationChannel{
+		Name:       "chat",
+		Type:       dbmodel.NotificationChannelTypeWebhook,
+		URL:        server.URL,
+		MaxRetries: 5,
+	}
+	err := sendWebhook(context.Background(), server.Client(), channel, newTestNotification(), time.Millisecond)
+	require.ErrorContains(t, err, "returned status 400")
+	require.Equal(t, 1, receiver.getRequestCount())
+}
+
+// Test that the retries are interrupted when the context is cancelled.
+func TestSendWebhookCancel(t *testing.T) {
+	receiver := &testReceiver{
+		statuses: []int{http.StatusInternalServerError},
+	}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	channel := &dbmodel.NotificationChannel{
+		Name:       "chat",
+		Type:       dbmodel.NotificationChannelTypeWebhook,
+		URL:        server.URL,
+		MaxRetries: 5,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		require.Eventually(t, func() bool {
+			return receiver.getRequestCount() > 0
+		}, time.Second, 10*time.Millisecond)
+		cancel()
+	}()
+	err := sendWebhook(ctx, server.Client(), channel, newTestNotification(), time.Hour)
+	require.ErrorContains(t, err, "cancelled")
+	require.Equal(t, 1, receiver.getRequestCount())
+}
@@ -0,0 +1,147 @@
+package restservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
+	log "github.com/sirupsen/logrus"
+
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/users"
+)
+
+// Creates new instance of the API token model used by REST API from the
+// token instance returned from the database. The token value is never
+// included because the database holds only its hash.
+func newRestAPIToken(t dbmodel.APIToken) *models.APIToken {
+	name := t.Name
+	return &models.APIToken{
+		ID:         t.ID,
+		Name:       &name,
+		CreatedAt:  strfmt.DateTime(t.CreatedAt),
+		ExpiresAt:  convertToOptionalDatetime(t.ExpiresAt),
+		LastUsedAt: convertToOptionalDatetime(t.LastUsedAt),
+	}
+}
+
+// Returns the personal API tokens of the user.
+func (r *RestAPI) GetAPITokens(ctx context.Context, params users.GetAPITokensParams) middleware.Responder {
+	id := int(params.ID)
+	dbTokens, err := dbmodel.GetAPITokensByUserID(r.DB, id)
+	if err != nil {
+		log.WithField("userID", id).WithError(err).Error("Failed to fetch API tokens from the database")
+
+		msg := fmt.Sprintf("Failed to fetch API tokens of user with ID %d from the database", id)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewGetAPITokensDefault(http.StatusInternalServerError).WithPayload(&rspErr)
+	}
+
+	tokens := &models.APITokens{
+		Items: []*models.APIToken{},
+		Total: int64(len(dbTokens)),
+	}
+	for _, t := range dbTokens {
+		tokens.Items = append(tokens.Items, newRestAPIToken(t))
+	}
+	return users.NewGetAPITokensOK().WithPayload(tokens)
+}
+
+// Creates new personal API token of the user. The token value is returned
+// in the response and it is not stored in the database.
+func (r *RestAPI) CreateAPIToken(ctx context.Context, params users.CreateAPITokenParams) middleware.Responder {
+	id := int(params.ID)
+	if params.Token == nil || params.Token.Name == nil || strings.TrimSpace(*params.Token.Name) == "" {
+		msg := "Failed to create new API token: missing name"
+		log.Warn(msg)
+		rspErr := models.APIError{Message: &msg}
+		return users.NewCreateAPITokenDefault(http.StatusBadRequest).WithPayload(&rspErr)
+	}
+	name := strings.TrimSpace(*params.Token.Name)
+
+	var expiresAt time.Time
+	if params.Token.ExpiresAt != nil {
+		expiresAt = time.Time(*params.Token.ExpiresAt).UTC()
+		if !expiresAt.After(time.Now().UTC()) {
+			msg := "Failed to create new API token: expiration time must be in the future"
+			log.Warn(msg)
+			rspErr := models.APIError{Message: &msg}
+			return users.NewCreateAPITokenDefault(http.StatusBadRequest).WithPayload(&rspErr)
+		}
+	}
+
+	su, err := dbmodel.GetUserByID(r.DB, id)
+	if err != nil {
+		log.WithField("userID", id).WithError(err).Error("Failed to fetch user from the database")
+
+		msg := fmt.Sprintf("Failed to fetch user with ID %d from the database", id)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewCreateAPITokenDefault(http.StatusInternalServerError).WithPayload(&rspErr)
+	}
+	if su == nil {
+		msg := fmt.Sprintf("Failed to find user with ID %d in the database", id)
+		log.WithField("userID", id).Error(msg)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewCreateAPITokenDefault(http.StatusNotFound).WithPayload(&rspErr)
+	}
+
+	dbToken, value, err := dbmodel.NewAPIToken(id, name, expiresAt)
+	if err == nil {
+		var con bool
+		con, err = dbmodel.AddAPIToken(r.DB, dbToken)
+		if con {
+			log.WithField("userID", id).WithError(err).Info("Failed to create conflicting API token")
+
+			msg := fmt.Sprintf("API token with name %s already exists", name)
+			rspErr := models.APIError{
+				Message: &msg,
+			}
+			return users.NewCreateAPITokenDefault(http.StatusConflict).WithPayload(&rspErr)
+		}
+	}
+	if err != nil {
+		log.WithField("userID", id).WithError(err).Error("Failed to create new API token")
+
+		msg := fmt.Sprintf("Failed to create new API token for user %s", su.Identity())
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewCreateAPITokenDefault(http.StatusInternalServerError).WithPayload(&rspErr)
+	}
+
+	token := newRestAPIToken(*dbToken)
+	token.Token = value
+	return users.NewCreateAPITokenOK().WithPayload(token)
+}
+
+// Revokes the personal API token of the user.
+func (r *RestAPI) DeleteAPIToken(ctx context.Context, params users.DeleteAPITokenParams) middleware.Responder {
+	id := int(params.ID)
+	err := dbmodel.DeleteAPIToken(r.DB, id, params.TokenID)
+	if err != nil {
+		code := http.StatusInternalServerError
+		msg := fmt.Sprintf("Failed to delete API token with ID %d", params.TokenID)
+		if errors.Is(err, dbmodel.ErrNotExists) {
+			code = http.StatusNotFound
+			msg = fmt.Sprintf("Cannot find API token with ID %d of user with ID %d", params.TokenID, id)
+		}
+		log.WithField("userID", id).WithError(err).Error(msg)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewDeleteAPITokenDefault(code).WithPayload(&rspErr)
+	}
+	return users.NewDeleteAPITokenOK()
+}
@@ -0,0 +1,164 @@
+package restservice
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+	dbsession "isc.org/stork/server/database/session"
+	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/users"
+	storkutil "isc.org/stork/util"
+)
+
+// Test that the API tokens can be created, listed and revoked.
+func TestCreateGetDeleteAPIToken(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	ctx := context.Background()
+	rapi, err := NewRestAPI(dbSettings, db)
+	require.NoError(t, err)
+
+	// Create a token of the default admin user.
+	rsp := rapi.CreateAPIToken(ctx, users.CreateAPITokenParams{
+		ID: 1,
+		Token: &models.APIToken{
+			Name: storkutil.Ptr("ipam-sync"),
+		},
+	})
+	require.IsType(t, &users.CreateAPITokenOK{}, rsp)
+	created := rsp.(*users.CreateAPITokenOK).Payload
+	require.NotZero(t, created.ID)
+	require.Equal(t, "ipam-sync", *created.Name)
+	require.Contains(t, created.Token, "stork_")
+	require.Nil(t, created.ExpiresAt)
+	require.Nil(t, created.LastUsedAt)
+
+	// The name must be unique for the user.
+	rsp = rapi.CreateAPIToken(ctx, users.CreateAPITokenParams{
+		ID: 1,
+		Token: &models.APIToken{
+			Name: storkutil.Ptr("ipam-sync"),
+		},
+	})
+	require.IsType(t, &users.CreateAPITokenDefault{}, rsp)
+	require.Equal(t, http.StatusConflict, getStatusCode(*rsp.(*users.CreateAPITokenDefault)))
+
+	// The expiration time must be in the future.
+	past := strfmt.DateTime(time.Now().Add(-time.Hour))
+	rsp = rapi.CreateAPIToken(ctx, users.CreateAPITokenParams{
+		ID: 1,
+		Token: &models.APIToken{
+			Name:      storkutil.Ptr("expired"),
+			ExpiresAt: &past,
+		},
+	})
+	require.IsType(t, &users.CreateAPITokenDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*users.CreateAPITokenDefault)))
+
+	// The user must exist.
+	rsp = rapi.CreateAPIToken(ctx, users.CreateAPITokenParams{
+		ID: 1000,
+		Token: &models.APIToken{
+			Name: storkutil.Ptr("foo"),
+		},
+	})
+	require.IsType(t, &users.CreateAPITokenDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*users.CreateAPITokenDefault)))
+
+	// List the tokens. The token value is not returned.
+	rsp = rapi.GetAPITokens(ctx, users.GetAPITokensParams{ID: 1})
+	require.IsType(t, &users.GetAPITokensOK{}, rsp)
+	tokens := rsp.(*users.GetAPITokensOK).Payload
+	require.EqualValues(t, 1, tokens.Total)
+	require.Len(t, tokens.Items, 1)
+	require.Equal(t, created.ID, tokens.Items[0].ID)
+	require.Empty(t, tokens.Items[0].Token)
+
+	// Revoke the token.
+	rsp = rapi.DeleteAPIToken(ctx, users.DeleteAPITokenParams{ID: 1, TokenID: created.ID})
+	require.IsType(t, &users.DeleteAPITokenOK{}, rsp)
+
+	rsp = rapi.DeleteAPIToken(ctx, users.DeleteAPITokenParams{ID: 1, TokenID: created.ID})
+	require.IsType(t, &users.DeleteAPITokenDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*users.DeleteAPITokenDefault)))
+
+	rsp = rapi.GetAPITokens(ctx, users.GetAPITokensParams{ID: 1})
+	require.IsType(t, &users.GetAPITokensOK{}, rsp)
+	require.Empty(t, rsp.(*users.GetAPITokensOK).Payload.Items)
+}
+
+// Test that the requests carrying valid API tokens are authenticated and
+// the requests carrying invalid tokens are rejected.
+func TestAPITokenMiddleware(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	rapi, err := NewRestAPI(dbSettings, db)
+	require.NoError(t, err)
+	sm, err := dbsession.NewSessionMgr(rapi.DBSettings)
+	require.NoError(t, err)
+	rapi.SessionManager = sm
+
+	apiToken, value, err := dbmodel.NewAPIToken(1, "ipam-sync", time.Time{})
+	require.NoError(t, err)
+	_, err = dbmodel.AddAPIToken(db, apiToken)
+	require.NoError(t, err)
+
+	expiredToken, expiredValue, err := dbmodel.NewAPIToken(1, "expired", time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	_, err = dbmodel.AddAPIToken(db, expiredToken)
+	require.NoError(t, err)
+
+	var loggedUser *dbmodel.SystemUser
+	handler := rapi.InnerMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, loggedUser = rapi.SessionManager.Logged(r.Context())
+	}))
+
+	// Valid token.
+	req := httptest.NewRequest("GET", "http://localhost/api/hosts", nil)
+	req.Header.Set("Authorization", "Bearer "+value)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, loggedUser)
+	require.Equal(t, 1, loggedUser.ID)
+	require.True(t, loggedUser.InGroup(&dbmodel.SystemGroup{ID: dbmodel.SuperAdminGroupID}))
+
+	tokens, err := dbmodel.GetAPITokensByUserID(db, 1)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	require.False(t, tokens[0].LastUsedAt.IsZero())
+	require.True(t, tokens[1].LastUsedAt.IsZero())
+
+	// Expired token.
+	loggedUser = nil
+	req = httptest.NewRequest("GET", "http://localhost/api/hosts", nil)
+	req.Header.Set("Authorization", "Bearer "+expiredValue)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Nil(t, loggedUser)
+
+	// Unknown token.
+	req = httptest.NewRequest("GET", "http://localhost/api/hosts", nil)
+	req.Header.Set("Authorization", "Bearer stork_unknown")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Nil(t, loggedUser)
+
+	// No token and no session.
+	req = httptest.NewRequest("GET", "http://localhost/api/hosts", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Nil(t, loggedUser)
+}
@@ -0,0 +1,212 @@
+package restservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/audit"
+)
+
+// Converts the audit entry from the database to the REST API format.
+func newRestAuditEntry(dbEntry *dbmodel.AuditEntry) *models.AuditEntry {
+	entry := &models.AuditEntry{
+		ID:           dbEntry.ID,
+		CreatedAt:    strfmt.DateTime(dbEntry.CreatedAt),
+		UserID:       dbEntry.UserID,
+		UserLogin:    dbEntry.UserLogin,
+		Target:       dbEntry.Target,
+		Operation:    dbEntry.Operation,
+		EntityType:   dbEntry.EntityType,
+		EntityID:     dbEntry.EntityID,
+		DaemonIds:    dbEntry.DaemonIDs,
+		EntityBefore: string(dbEntry.EntityBefore),
+		EntityAfter:  string(dbEntry.EntityAfter),
+		Scheduled:    dbEntry.Scheduled,
+		Error:        dbEntry.Error,
+	}
+	for _, command := range dbEntry.Commands {
+		entry.Commands = append(entry.Commands, &models.AuditCommand{
+			AppID:   command.AppID,
+			AppName: command.AppName,
+			Command: string(command.Command),
+			Result:  int64(command.Result),
+			Text:    command.Text,
+		})
+	}
+	return entry
+}
+
+// Creates the audit entries filters from the REST API parameters.
+func newAuditEntriesFilters(user, daemon *int64, entityType *string, entityID *int64, operation *string, from, to *strfmt.DateTime) *dbmodel.AuditEntriesByPageFilters {
+	filters := &dbmodel.AuditEntriesByPageFilters{
+		UserID:     user,
+		DaemonID:   daemon,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Operation:  operation,
+	}
+	if from != nil {
+		fromTime := time.Time(*from)
+		filters.From = &fromTime
+	}
+	if to != nil {
+		toTime := time.Time(*to)
+		filters.To = &toTime
+	}
+	return filters
+}
+
+// Get the audit entries describing the configuration changes made through
+// Stork. The entries are returned from the newest to the oldest.
+func (r *RestAPI) GetAuditEntries(ctx context.Context, params audit.GetAuditEntriesParams) middleware.Responder {
+	var start int64
+	if params.Start != nil {
+		start = *params.Start
+	}
+
+	var limit int64 = 10
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+
+	filters := newAuditEntriesFilters(params.User, params.Daemon, params.EntityType, params.EntityID, params.Operation, params.From, params.To)
+	dbEntries, total, err := dbmodel.GetAuditEntriesByPage(r.DB, start, limit, filters, dbmodel.SortDirDesc)
+	if err != nil {
+		msg := "Problem fetching audit entries from the database"
+		log.WithError(err).Error(msg)
+		rsp := audit.NewGetAuditEntriesDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	entries := &models.AuditEntries{
+		Total: total,
+	}
+	for i := range dbEntries {
+		entries.Items = append(entries.Items, newRestAuditEntry(&dbEntries[i]))
+	}
+	return audit.NewGetAuditEntriesOK().WithPayload(entries)
+}
+
+// Writes the audit entries in the CSV format. The entities and commands
+// are written in the JSON format.
+func writeAuditEntriesCSV(w io.Writer, entries []dbmodel.AuditEntry) error {
+	writer := csv.NewWriter(w)
+	header := []string{
+		"id", "created_at", "user_id", "user_login", "target", "operation",
+		"entity_type", "entity_id", "daemon_ids", "scheduled", "error",
+		"commands", "entity_before", "entity_after",
+	}
+	if err := writer.Write(header); err != nil {
+		return errors.Wrap(err, "cannot write the CSV header")
+	}
+	for _, entry := range entries {
+		var daemonIDs []string
+		for _, id := range entry.DaemonIDs {
+			daemonIDs = append(daemonIDs, strconv.FormatInt(id, 10))
+		}
+		var commands []byte
+		if len(entry.Commands) > 0 {
+			var err error
+			if commands, err = json.Marshal(entry.Commands); err != nil {
+				return errors.Wrapf(err, "cannot serialize commands of the audit entry %d", entry.ID)
+			}
+		}
+		record := []string{
+			strconv.FormatInt(entry.ID, 10),
+			entry.CreatedAt.UTC().Format(time.RFC3339),
+			strconv.FormatInt(entry.UserID, 10),
+			entry.UserLogin,
+			entry.Target,
+			entry.Operation,
+			entry.EntityType,
+			strconv.FormatInt(entry.EntityID, 10),
+			strings.Join(daemonIDs, " "),
+			strconv.FormatBool(entry.Scheduled),
+			entry.Error,
+			string(commands),
+			string(entry.EntityBefore),
+			string(entry.EntityAfter),
+		}
+		if err := writer.Write(record); err != nil {
+			return errors.Wrapf(err, "cannot write the audit entry %d", entry.ID)
+		}
+	}
+	writer.Flush()
+	return errors.Wrap(writer.Error(), "cannot write the audit entries")
+}
+
+// Exports the audit entries matching the filters to a file in the CSV or
+// JSON format. The entries are exported from the oldest to the newest.
+func (r *RestAPI) ExportAuditEntries(ctx context.Context, params audit.ExportAuditEntriesParams) middleware.Responder {
+	filters := newAuditEntriesFilters(params.User, params.Daemon, params.EntityType, params.EntityID, params.Operation, params.From, params.To)
+	dbEntries, err := dbmodel.GetAuditEntries(r.DB, filters)
+	if err != nil {
+		msg := "Problem fetching audit entries from the database"
+		log.WithError(err).Error(msg)
+		rsp := audit.NewExportAuditEntriesDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	format := "csv"
+	if params.Format != nil {
+		format = *params.Format
+	}
+
+	var (
+		buffer      bytes.Buffer
+		contentType string
+	)
+	switch format {
+	case "json":
+		contentType = "application/json"
+		items := []*models.AuditEntry{}
+		for i := range dbEntries {
+			items = append(items, newRestAuditEntry(&dbEntries[i]))
+		}
+		err = json.NewEncoder(&buffer).Encode(items)
+	default:
+		format = "csv"
+		contentType = "text/csv"
+		err = writeAuditEntriesCSV(&buffer, dbEntries)
+	}
+	if err != nil {
+		msg := "Problem exporting audit entries"
+		log.WithError(err).Error(msg)
+		rsp := audit.NewExportAuditEntriesDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	dispositionHeaderValue := fmt.Sprintf(
+		"attachment; filename=\"stork-audit_%s.%s\"",
+		strings.ReplaceAll(time.Now().UTC().Format(time.RFC3339), ":", "-"),
+		format,
+	)
+
+	rsp := audit.
+		NewExportAuditEntriesOK().
+		WithContentType(contentType).
+		WithContentDisposition(dispositionHeaderValue).
+		WithPayload(io.NopCloser(&buffer))
+	return rsp
+}
@@ -0,0 +1,181 @@
+package restservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-pg/pg/v10"
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/audit"
+	storkutil "isc.org/stork/util"
+)
+
+// Adds the audit entries used in the tests.
+func addTestAuditEntries(t *testing.T, db *pg.DB) {
+	entries := []dbmodel.AuditEntry{
+		{
+			CreatedAt:   time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC),
+			UserLogin:   "jdoe",
+			Target:      "kea",
+			Operation:   "host_add",
+			EntityType:  "host",
+			EntityID:    1,
+			DaemonIDs:   []int64{1, 2},
+			EntityAfter: json.RawMessage(`{"ID": 1}`),
+			Commands: []dbmodel.AuditCommand{
+				{
+					AppID:   1,
+					AppName: "kea@192.0.2.1",
+					Command: json.RawMessage(`{"command": "reservation-add"}`),
+					Text:    "Host added.",
+				},
+			},
+		},
+		{
+			CreatedAt:    time.Date(2024, 3, 12, 10, 0, 0, 0, time.UTC),
+			UserLogin:    "jdoe",
+			Target:       "kea",
+			Operation:    "subnet_delete",
+			EntityType:   "subnet",
+			EntityID:     3,
+			DaemonIDs:    []int64{2},
+			EntityBefore: json.RawMessage(`{"ID": 3}`),
+			Error:        "subnet4-del command failed",
+		},
+	}
+	for i := range entries {
+		require.NoError(t, dbmodel.AddAuditEntry(db, &entries[i]))
+	}
+}
+
+// Test that the audit entries are returned over the REST API.
+func TestGetAuditEntries(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	addTestAuditEntries(t, db)
+
+	rapi, err := NewRestAPI(dbSettings, db)
+	require.NoError(t, err)
+
+	// Get all entries.
+	rsp := rapi.GetAuditEntries(context.Background(), audit.GetAuditEntriesParams{})
+	require.IsType(t, &audit.GetAuditEntriesOK{}, rsp)
+	entries := rsp.(*audit.GetAuditEntriesOK).Payload
+	require.EqualValues(t, 2, entries.Total)
+	require.Len(t, entries.Items, 2)
+
+	// The newest entry goes first.
+	require.Equal(t, "subnet_delete", entries.Items[0].Operation)
+	require.Equal(t, "subnet4-del command failed", entries.Items[0].Error)
+	require.JSONEq(t, `{"ID": 3}`, entries.Items[0].EntityBefore)
+	require.Empty(t, entries.Items[0].EntityAfter)
+
+	require.Equal(t, "host_add", entries.Items[1].Operation)
+	require.Equal(t, "jdoe", entries.Items[1].UserLogin)
+	require.Equal(t, []int64{1, 2}, entries.Items[1].DaemonIds)
+	require.Len(t, entries.Items[1].Commands, 1)
+	require.Equal(t, "kea@192.0.2.1", entries.Items[1].Commands[0].AppName)
+	require.JSONEq(t, `{"command": "reservation-add"}`, entries.Items[1].Commands[0].Command)
+
+	// Filter the entries.
+	rsp = rapi.GetAuditEntries(context.Background(), audit.GetAuditEntriesParams{
+		Daemon:     storkutil.Ptr(int64(1)),
+		EntityType: storkutil.Ptr("host"),
+		From:       storkutil.Ptr(strfmt.DateTime(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))),
+		To:         storkutil.Ptr(strfmt.DateTime(time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC))),
+	})
+	require.IsType(t, &audit.GetAuditEntriesOK{}, rsp)
+	entries = rsp.(*audit.GetAuditEntriesOK).Payload
+	require.EqualValues(t, 1, entries.Total)
+	require.Equal(t, "host_add", entries.Items[0].Operation)
+}
+
+// Test exporting the audit entries in the CSV format.
+func TestExportAuditEntriesCSV(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	addTestAuditEntries(t, db)
+
+	rapi, err := NewRestAPI(dbSettings, db)
+	require.NoError(t, err)
+
+	rsp := rapi.ExportAuditEntries(context.Background(), audit.NewExportAuditEntriesParams())
+	require.IsType(t, &audit.ExportAuditEntriesOK{}, rsp)
+	okRsp := rsp.(*audit.ExportAuditEntriesOK)
+	require.Equal(t, "text/csv", okRsp.ContentType)
+	require.Contains(t, okRsp.ContentDisposition, ".csv")
+
+	records, err := csv.NewReader(okRsp.Payload).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, "operation", records[0][5])
+	// The oldest entry goes first.
+	require.Equal(t, "host_add", records[1][5])
+	require.Equal(t, "1 2", records[1][8])
+	require.Equal(t, "subnet_delete", records[2][5])
+	require.Equal(t, "subnet4-del command failed", records[2][10])
+}
+
+// Test exporting the audit entries in the JSON format.
+func TestExportAuditEntriesJSON(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	addTestAuditEntries(t, db)
+
+	rapi, err := NewRestAPI(dbSettings, db)
+	require.NoError(t, err)
+
+	params := audit.NewExportAuditEntriesParams()
+	params.Format = storkutil.Ptr("json")
+	params.Operation = storkutil.Ptr("subnet_delete")
+	rsp := rapi.ExportAuditEntries(context.Background(), params)
+	require.IsType(t, &audit.ExportAuditEntriesOK{}, rsp)
+	okRsp := rsp.(*audit.ExportAuditEntriesOK)
+	require.Equal(t, "application/json", okRsp.ContentType)
+
+	data, err := io.ReadAll(okRsp.Payload)
+	require.NoError(t, err)
+	var items []*models.AuditEntry
+	require.NoError(t, json.Unmarshal(data, &items))
+	require.Len(t, items, 1)
+	require.Equal(t, "subnet_delete", items[0].Operation)
+	require.EqualValues(t, 3, items[0].EntityID)
+}
+
+// Test that the CSV export escapes the JSON contents.
+func TestWriteAuditEntriesCSV(t *testing.T) {
+	var buffer bytes.Buffer
+	err := writeAuditEntriesCSV(&buffer, []dbmodel.AuditEntry{
+		{
+			ID:          7,
+			CreatedAt:   time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC),
+			UserID:      3,
+			UserLogin:   "jdoe",
+			Operation:   "host_update",
+			EntityAfter: json.RawMessage(`{"Hostname": "foo, bar"}`),
+		},
+	})
+	require.NoError(t, err)
+
+	records, err := csv.NewReader(&buffer).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Len(t, records[1], 14)
+	require.Equal(t, "7", records[1][0])
+	require.Equal(t, "2024-03-05T10:00:00Z", records[1][1])
+	require.Equal(t, "3", records[1][2])
+	require.Empty(t, records[1][11])
+	require.Equal(t, `{"Hostname": "foo, bar"}`, records[1][13])
+}
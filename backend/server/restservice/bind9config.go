@@ -0,0 +1,104 @@
+package restservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-openapi/runtime/middleware"
+	log "github.com/sirupsen/logrus"
+
+	bind9config "isc.org/stork/appcfg/bind9"
+	"isc.org/stork/server/apps/bind9"
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/dns"
+)
+
+// Converts the zones parsed from the BIND 9 configuration to the REST API
+// format.
+func newRestBind9ConfigZones(zones []*bind9config.Zone) []*models.Bind9ConfigZone {
+	restZones := []*models.Bind9ConfigZone{}
+	for _, zone := range zones {
+		restZones = append(restZones, &models.Bind9ConfigZone{
+			Name:      zone.Name,
+			Class:     zone.Class,
+			View:      zone.View,
+			ZoneType:  zone.Type,
+			File:      zone.File,
+			Primaries: zone.Primaries,
+		})
+	}
+	return restZones
+}
+
+// Converts the parsed BIND 9 configuration to the REST API format. The
+// secrets of the keys are not returned.
+func newRestBind9Config(daemonID int64, config *bind9config.Config) *models.Bind9Config {
+	restConfig := &models.Bind9Config{
+		DaemonID: daemonID,
+		Acls:     []*models.Bind9ACL{},
+		Keys:     []*models.Bind9Key{},
+		Views:    []*models.Bind9View{},
+		Zones:    newRestBind9ConfigZones(config.GetZones()),
+		Text:     config.String(),
+	}
+	for _, acl := range config.GetACLs() {
+		restConfig.Acls = append(restConfig.Acls, &models.Bind9ACL{
+			Name:     acl.Name,
+			Elements: acl.Elements,
+		})
+	}
+	for _, key := range config.GetKeys() {
+		restConfig.Keys = append(restConfig.Keys, &models.Bind9Key{
+			Name:      key.Name,
+			Algorithm: key.Algorithm,
+		})
+	}
+	for _, view := range config.GetViews() {
+		restConfig.Views = append(restConfig.Views, &models.Bind9View{
+			Name:         view.Name,
+			Class:        view.Class,
+			MatchClients: view.MatchClients,
+			Zones:        newRestBind9ConfigZones(view.Zones),
+		})
+	}
+	return restConfig
+}
+
+// Implements the GET call returning the configuration of a BIND 9 daemon
+// (daemons/{id}/bind9-config). The configuration is fetched from the
+// agent which parses named.conf and hides the secrets of the keys.
+func (r *RestAPI) GetBind9Config(ctx context.Context, params dns.GetBind9ConfigParams) middleware.Responder {
+	daemon, err := dbmodel.GetDaemonByID(r.DB, params.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Problem with fetching daemon %d from the database", params.ID)
+		log.WithError(err).Error(msg)
+		return dns.NewGetBind9ConfigDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if daemon == nil {
+		msg := fmt.Sprintf("Cannot find daemon with ID %d", params.ID)
+		log.Error(msg)
+		return dns.NewGetBind9ConfigDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if daemon.Name != dbmodel.DaemonNameBind9 {
+		msg := fmt.Sprintf("Daemon %d is not a BIND 9 daemon", params.ID)
+		log.Error(msg)
+		return dns.NewGetBind9ConfigDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	config, err := bind9.GetConfig(ctx, r.Agents, daemon)
+	if err != nil {
+		msg := fmt.Sprintf("Problem with fetching the BIND 9 configuration: %s", err)
+		log.WithError(err).Error(msg)
+		return dns.NewGetBind9ConfigDefault(http.StatusConflict).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	return dns.NewGetBind9ConfigOK().WithPayload(newRestBind9Config(daemon.ID, config))
+}
@@ -0,0 +1,105 @@
+package restservice
+
+import (
+	"net/http"
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+
+	bind9config "isc.org/stork/appcfg/bind9"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/dns"
+)
+
+// Test converting the parsed BIND 9 configuration to the REST API format.
+func TestNewRestBind9Config(t *testing.T) {
+	config, err := bind9config.Parse(`
+		acl trusted { 192.0.2.0/24; };
+		key "rndc-key" { algorithm hmac-sha256; secret "c2VjcmV0"; };
+		zone "example.org" { type primary; file "db.example.org"; };
+		view "internal" {
+			match-clients { trusted; };
+			zone "example.com" { type secondary; primaries { 192.0.2.2; }; };
+		};
+	`)
+	require.NoError(t, err)
+	config.HideSecrets()
+
+	restConfig := newRestBind9Config(3, config)
+	require.EqualValues(t, 3, restConfig.DaemonID)
+	require.Equal(t, []*models.Bind9ACL{{Name: "trusted", Elements: []string{"192.0.2.0/24"}}}, restConfig.Acls)
+	require.Equal(t, []*models.Bind9Key{{Name: "rndc-key", Algorithm: "hmac-sha256"}}, restConfig.Keys)
+	require.Equal(t, []*models.Bind9ConfigZone{{
+		Name:     "example.org",
+		Class:    "IN",
+		ZoneType: "primary",
+		File:     "db.example.org",
+	}}, restConfig.Zones)
+	require.Len(t, restConfig.Views, 1)
+	require.Equal(t, "internal", restConfig.Views[0].Name)
+	require.Equal(t, []string{"trusted"}, restConfig.Views[0].MatchClients)
+	require.Equal(t, []*models.Bind9ConfigZone{{
+		Name:      "example.com",
+		Class:     "IN",
+		View:      "internal",
+		ZoneType:  "secondary",
+		Primaries: []string{"192.0.2.2"},
+	}}, restConfig.Views[0].Zones)
+	require.NotContains(t, restConfig.Text, "c2VjcmV0")
+	require.Contains(t, restConfig.Text, bind9config.HiddenSecret)
+}
+
+// Test getting the BIND 9 configuration via the REST API.
+func TestGetBind9Config(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewBind9FakeAgents(nil, nil)
+	var err error
+	agents.Bind9Config, err = bind9config.Parse(`options { directory "/var/cache/bind"; };`)
+	require.NoError(t, err)
+	rapi, ctx, _ := newTestHAControlRestAPI(t, db, dbSettings, agents)
+	app := addTestRndcApp(t, db)
+
+	rsp := rapi.GetBind9Config(ctx, dns.GetBind9ConfigParams{
+		ID: app.Daemons[0].ID,
+	})
+	require.IsType(t, &dns.GetBind9ConfigOK{}, rsp)
+	payload := rsp.(*dns.GetBind9ConfigOK).Payload
+	require.Equal(t, app.Daemons[0].ID, payload.DaemonID)
+	require.Contains(t, payload.Text, `directory "/var/cache/bind";`)
+	require.Equal(t, "127.0.0.1", agents.RecordedAddress)
+}
+
+// Test that the errors are returned when the daemon does not exist, it is
+// not a BIND 9 daemon or the configuration cannot be fetched.
+func TestGetBind9ConfigError(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewBind9FakeAgents(nil, nil)
+	rapi, ctx, _ := newTestHAControlRestAPI(t, db, dbSettings, agents)
+	app := addTestRndcApp(t, db)
+	keaApp, _ := addTestHAService(t, db)
+
+	rsp := rapi.GetBind9Config(ctx, dns.GetBind9ConfigParams{
+		ID: app.Daemons[0].ID + keaApp.Daemons[0].ID,
+	})
+	require.IsType(t, &dns.GetBind9ConfigDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*dns.GetBind9ConfigDefault)))
+
+	rsp = rapi.GetBind9Config(ctx, dns.GetBind9ConfigParams{
+		ID: keaApp.Daemons[0].ID,
+	})
+	require.IsType(t, &dns.GetBind9ConfigDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*dns.GetBind9ConfigDefault)))
+
+	// The fake agents have no configuration.
+	rsp = rapi.GetBind9Config(ctx, dns.GetBind9ConfigParams{
+		ID: app.Daemons[0].ID,
+	})
+	require.IsType(t, &dns.GetBind9ConfigDefault{}, rsp)
+	require.Equal(t, http.StatusConflict, getStatusCode(*rsp.(*dns.GetBind9ConfigDefault)))
+}
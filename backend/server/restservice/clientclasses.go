@@ -0,0 +1,565 @@
+package restservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	keaconfig "isc.org/stork/appcfg/kea"
+	"isc.org/stork/server/apps/kea"
+	"isc.org/stork/server/config"
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
+	storkutil "isc.org/stork/util"
+)
+
+// Converts the client class from the database to the REST API format.
+func (r *RestAPI) convertClientClassToRestAPI(class *dbmodel.ClientClass) *models.ClientClass {
+	restClass := &models.ClientClass{
+		ID:       class.ID,
+		DaemonID: class.DaemonID,
+		Position: int64(class.Position),
+		Name:     storkutil.Ptr(class.Name),
+	}
+	if class.Daemon != nil {
+		restClass.DaemonName = class.Daemon.Name
+		restClass.AppID = class.Daemon.AppID
+		if class.Daemon.App != nil {
+			restClass.AppName = class.Daemon.App.Name
+		}
+	}
+	if params := class.KeaParameters; params != nil {
+		restClass.Test = params.Test
+		restClass.TemplateTest = params.TemplateTest
+		restClass.OnlyIfRequired = params.OnlyIfRequired
+		restClass.NextServer = params.NextServer
+		restClass.ServerHostname = params.ServerHostname
+		restClass.BootFileName = params.BootFileName
+		restClass.ValidLifetime = params.ValidLifetime
+		restClass.MinValidLifetime = params.MinValidLifetime
+		restClass.MaxValidLifetime = params.MaxValidLifetime
+		restClass.PreferredLifetime = params.PreferredLifetime
+		restClass.MinPreferredLifetime = params.MinPreferredLifetime
+		restClass.MaxPreferredLifetime = params.MaxPreferredLifetime
+		for _, option := range params.OptionData {
+			restClass.OptionData = append(restClass.OptionData, &models.ClientClassOptionData{
+				AlwaysSend: option.AlwaysSend,
+				Code:       int64(option.Code),
+				CsvFormat:  option.CSVFormat,
+				Data:       option.Data,
+				Name:       option.Name,
+				NeverSend:  option.NeverSend,
+				Space:      option.Space,
+			})
+		}
+		if params.UserContext != nil {
+			restClass.UserContext = params.UserContext
+		}
+	}
+	return restClass
+}
+
+// Converts the client class from the REST API format to the database
+// format. The daemon is not populated.
+func (r *RestAPI) convertClientClassFromRestAPI(restClass *models.ClientClass) (*dbmodel.ClientClass, error) {
+	if restClass.Name == nil || *restClass.Name == "" {
+		return nil, errors.New("client class name must not be empty")
+	}
+	params := &keaconfig.ClientClass{
+		Name:                 *restClass.Name,
+		Test:                 restClass.Test,
+		TemplateTest:         restClass.TemplateTest,
+		OnlyIfRequired:       restClass.OnlyIfRequired,
+		NextServer:           restClass.NextServer,
+		ServerHostname:       restClass.ServerHostname,
+		BootFileName:         restClass.BootFileName,
+		ValidLifetime:        restClass.ValidLifetime,
+		MinValidLifetime:     restClass.MinValidLifetime,
+		MaxValidLifetime:     restClass.MaxValidLifetime,
+		PreferredLifetime:    restClass.PreferredLifetime,
+		MinPreferredLifetime: restClass.MinPreferredLifetime,
+		MaxPreferredLifetime: restClass.MaxPreferredLifetime,
+	}
+	for _, option := range restClass.OptionData {
+		if option == nil {
+			continue
+		}
+		if option.Code < 0 || option.Code > 65535 {
+			return nil, errors.Errorf("invalid option code %d in client class %s", option.Code, params.Name)
+		}
+		params.OptionData = append(params.OptionData, keaconfig.ClientClassOptionData{
+			AlwaysSend: option.AlwaysSend,
+			Code:       uint16(option.Code),
+			CSVFormat:  option.CsvFormat,
+			Data:       option.Data,
+			Name:       option.Name,
+			NeverSend:  option.NeverSend,
+			Space:      option.Space,
+		})
+	}
+	if restClass.UserContext != nil {
+		userContext, ok := restClass.UserContext.(map[string]any)
+		if !ok {
+			return nil, errors.Errorf("user context of the client class %s must be an object", params.Name)
+		}
+		params.UserContext = userContext
+	}
+	class := &dbmodel.ClientClass{
+		ID:            restClass.ID,
+		DaemonID:      restClass.DaemonID,
+		Name:          params.Name,
+		KeaParameters: params,
+	}
+	return class, nil
+}
+
+// Get list of DHCP client classes. The list can be filtered by app ID,
+// daemon ID and text.
+func (r *RestAPI) GetClientClasses(ctx context.Context, params dhcp.GetClientClassesParams) middleware.Responder {
+	var start int64
+	if params.Start != nil {
+		start = *params.Start
+	}
+
+	var limit int64 = 10
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+
+	filters := &dbmodel.ClientClassesByPageFilters{
+		AppID:    params.AppID,
+		DaemonID: params.DaemonID,
+		Text:     params.Text,
+	}
+
+	dbClasses, total, err := dbmodel.GetClientClassesByPage(r.DB, start, limit, filters, "", dbmodel.SortDirAsc)
+	if err != nil {
+		msg := "Cannot get client classes from db"
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewGetClientClassesDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	classes := &models.ClientClasses{
+		Total: total,
+	}
+	for i := range dbClasses {
+		classes.Items = append(classes.Items, r.convertClientClassToRestAPI(&dbClasses[i]))
+	}
+	rsp := dhcp.NewGetClientClassesOK().WithPayload(classes)
+	return rsp
+}
+
+// Returns the client class with its DHCP configuration.
+func (r *RestAPI) GetClientClass(ctx context.Context, params dhcp.GetClientClassParams) middleware.Responder {
+	dbClass, err := dbmodel.GetClientClass(r.DB, params.ID)
+	if err != nil {
+		// Error while communicating with the database.
+		msg := fmt.Sprintf("Problem fetching client class with ID %d from db", params.ID)
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewGetClientClassDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	if dbClass == nil {
+		// Client class not found.
+		msg := fmt.Sprintf("Cannot find client class with ID %d", params.ID)
+		log.Error(msg)
+		rsp := dhcp.NewGetClientClassDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	rsp := dhcp.NewGetClientClassOK().WithPayload(r.convertClientClassToRestAPI(dbClass))
+	return rsp
+}
+
+// Common function executed when a client class is submitted after adding
+// or updating it. It recovers the transaction context, converts the class
+// to the database format, applies it and commits the changes. It returns
+// the HTTP error code and message if an error occurs. Otherwise, it returns
+// 0, the ID of the client class and an empty string.
+func (r *RestAPI) commonCreateOrUpdateClientClassSubmit(ctx context.Context, transactionID int64, restClass *models.ClientClass, applyFunc func(context.Context, *dbmodel.ClientClass) (context.Context, error)) (int, int64, string) {
+	// Make sure that the client class information is present.
+	if restClass == nil {
+		msg := "Client class information not specified"
+		log.Errorf("Problem with submitting a client class because the client class information is missing")
+		return http.StatusBadRequest, 0, msg
+	}
+	// Retrieve the context from the config manager.
+	_, user := r.SessionManager.Logged(ctx)
+	cctx, _ := r.ConfigManager.RecoverContext(transactionID, int64(user.ID))
+	if cctx == nil {
+		msg := "Transaction expired for the client class update"
+		log.Errorf("Problem with recovering transaction context for transaction ID %d and user ID %d", transactionID, user.ID)
+		return http.StatusNotFound, 0, msg
+	}
+
+	// Convert client class information from REST API to database format.
+	class, err := r.convertClientClassFromRestAPI(restClass)
+	if err != nil {
+		msg := "Error parsing specified client class"
+		log.WithError(err).Error(msg)
+		return http.StatusBadRequest, 0, msg
+	}
+	class.Daemon, err = dbmodel.GetDaemonByID(r.DB, class.DaemonID)
+	if err != nil {
+		msg := "Problem with fetching the daemon of the client class from the database"
+		log.WithError(err).Error(msg)
+		return http.StatusInternalServerError, 0, msg
+	}
+	if class.Daemon == nil {
+		msg := "Specified client class is associated with a daemon that no longer exists"
+		log.Error(msg)
+		return http.StatusNotFound, 0, msg
+	}
+	// Make sure the user is permitted to modify the class on this daemon.
+	if !r.authorizeTargets(ctx, dbmodel.PermissionManageSubnets, getClientClassPermissionTarget(class)) {
+		return http.StatusForbidden, 0, "User is forbidden to modify the client class on the selected server"
+	}
+	// Preserve the parameters not exposed over the REST API.
+	if state, ok := config.GetTransactionState[kea.ConfigRecipe](cctx); ok && len(state.Updates) > 0 {
+		if existingClass := state.Updates[0].Recipe.ClientClassBeforeUpdate; existingClass != nil && existingClass.KeaParameters != nil {
+			class.KeaParameters.Unrecognized = existingClass.KeaParameters.Unrecognized
+			if class.KeaParameters.UserContext == nil {
+				class.KeaParameters.UserContext = existingClass.KeaParameters.UserContext
+			}
+		}
+	}
+	// Apply the client class information (create Kea commands).
+	cctx, err = applyFunc(cctx, class)
+	if err != nil {
+		msg := "Problem with applying client class information"
+		log.WithError(err).Error(msg)
+		return http.StatusInternalServerError, 0, msg
+	}
+	// Send the commands to Kea servers.
+	cctx, err = r.ConfigManager.Commit(cctx)
+	if err != nil {
+		msg := fmt.Sprintf("Problem with committing client class information: %s", err)
+		log.WithError(err).Error(msg)
+		return http.StatusConflict, 0, msg
+	}
+	classID := restClass.ID
+	if classID == 0 {
+		recipe, err := config.GetRecipeForUpdate[kea.ConfigRecipe](cctx, 0)
+		if err != nil {
+			msg := "Problem recovering client class ID from the context"
+			log.WithError(err).Error(msg)
+			return http.StatusInternalServerError, 0, msg
+		}
+		if recipe.ClientClassID != nil {
+			classID = *recipe.ClientClassID
+		}
+	}
+	// Everything ok. Cleanup and send OK to the client.
+	r.ConfigManager.Done(cctx)
+	return 0, classID, ""
+}
+
+// Common function that implements the DELETE calls to cancel adding new
+// or updating a client class. It removes the specified transaction from the
+// config manager, if the transaction exists. It returns the HTTP error code
+// and the error message if an error occurs or 0 and an empty string
+// otherwise.
+func (r *RestAPI) commonCreateOrUpdateClientClassDelete(ctx context.Context, transactionID int64) (int, string) {
+	// Retrieve the context from the config manager.
+	_, user := r.SessionManager.Logged(ctx)
+	cctx, _ := r.ConfigManager.RecoverContext(transactionID, int64(user.ID))
+	if cctx == nil {
+		msg := "Transaction expired for the client class update"
+		log.Errorf("Problem with recovering transaction context for transaction ID %d and user ID %d", transactionID, user.ID)
+		return http.StatusNotFound, msg
+	}
+	r.ConfigManager.Done(cctx)
+	return 0, ""
+}
+
+// Implements the POST call to create new transaction for adding a new
+// client class (client-classes/new/transaction).
+func (r *RestAPI) CreateClientClassBegin(ctx context.Context, params dhcp.CreateClientClassBeginParams) middleware.Responder {
+	// A list of Kea DHCP daemons will be needed in the user form,
+	// so the user can select which server the class is created in.
+	daemons, err := dbmodel.GetKeaDHCPDaemons(r.DB)
+	if err != nil {
+		msg := "Problem with fetching Kea daemons from the database"
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewCreateClientClassBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	respDaemons := []*models.KeaDaemon{}
+	for i := range daemons {
+		// The classes are added with the class_cmds hook library commands
+		// or with config-set when the hook library is not loaded, so any
+		// daemon with a known configuration can be selected.
+		if daemons[i].KeaDaemon != nil && daemons[i].KeaDaemon.Config != nil {
+			respDaemons = append(respDaemons, keaDaemonToRestAPI(&daemons[i]))
+		}
+	}
+	// If there are no Kea DHCP daemons there is no way to add new client
+	// class. In that case, we don't begin a transaction.
+	if len(respDaemons) == 0 {
+		msg := "Unable to begin transaction because there are no Kea servers available"
+		log.Error(msg)
+		rsp := dhcp.NewCreateClientClassBeginDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Create configuration context.
+	_, user := r.SessionManager.Logged(ctx)
+	cctx, err := r.ConfigManager.CreateContext(int64(user.ID))
+	if err != nil {
+		msg := "Problem with creating transaction context"
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewCreateClientClassBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Begin client class add transaction.
+	if cctx, err = r.ConfigManager.GetKeaModule().BeginClientClassAdd(cctx); err != nil {
+		msg := "Problem with initializing transaction for creating client class"
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewCreateClientClassBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	// Retrieve the generated context ID.
+	cctxID, ok := config.GetValueAsInt64(cctx, config.ContextIDKey)
+	if !ok {
+		msg := "problem with retrieving context ID for a transaction to create a client class"
+		log.Error(msg)
+		rsp := dhcp.NewCreateClientClassBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Remember the context, i.e. new transaction has been successfully created.
+	_ = r.ConfigManager.RememberContext(cctx, time.Minute*10)
+
+	// Return transaction ID and daemons to the user.
+	contents := &models.CreateClientClassBeginResponse{
+		ID:      cctxID,
+		Daemons: respDaemons,
+	}
+	rsp := dhcp.NewCreateClientClassBeginOK().WithPayload(contents)
+	return rsp
+}
+
+// Implements the POST call and commits a new client class
+// (client-classes/new/transaction/{id}/submit).
+func (r *RestAPI) CreateClientClassSubmit(ctx context.Context, params dhcp.CreateClientClassSubmitParams) middleware.Responder {
+	code, classID, msg := r.commonCreateOrUpdateClientClassSubmit(ctx, params.ID, params.ClientClass, r.ConfigManager.GetKeaModule().ApplyClientClassAdd)
+	if code != 0 {
+		// Error case.
+		rsp := dhcp.NewCreateClientClassSubmitDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	contents := &models.CreateClientClassSubmitResponse{
+		ClientClassID: classID,
+	}
+	rsp := dhcp.NewCreateClientClassSubmitOK().WithPayload(contents)
+	return rsp
+}
+
+// Implements the DELETE call to cancel creating a client class
+// (client-classes/new/transaction/{id}). It removes the specified transaction
+// from the config manager, if the transaction exists.
+func (r *RestAPI) CreateClientClassDelete(ctx context.Context, params dhcp.CreateClientClassDeleteParams) middleware.Responder {
+	if code, msg := r.commonCreateOrUpdateClientClassDelete(ctx, params.ID); code != 0 {
+		// Error case.
+		rsp := dhcp.NewCreateClientClassDeleteDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewCreateClientClassDeleteOK()
+	return rsp
+}
+
+// Implements the POST call to create new transaction for updating an
+// existing client class (client-classes/{clientClassId}/transaction).
+func (r *RestAPI) UpdateClientClassBegin(ctx context.Context, params dhcp.UpdateClientClassBeginParams) middleware.Responder {
+	// Create configuration context.
+	_, user := r.SessionManager.Logged(ctx)
+	cctx, err := r.ConfigManager.CreateContext(int64(user.ID))
+	if err != nil {
+		msg := "Problem with creating transaction context"
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewUpdateClientClassBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Begin client class update transaction. It retrieves current client class
+	// information and locks the daemon for updates.
+	cctx, err = r.ConfigManager.GetKeaModule().BeginClientClassUpdate(cctx, params.ClientClassID)
+	if err != nil {
+		var (
+			classNotFound *config.ClientClassNotFoundError
+			lock          *config.LockError
+		)
+		switch {
+		case errors.As(err, &classNotFound):
+			// Failed to find client class.
+			msg := fmt.Sprintf("Unable to edit the client class with ID %d because it cannot be found", params.ClientClassID)
+			log.Error(msg)
+			rsp := dhcp.NewUpdateClientClassBeginDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		case errors.As(err, &lock):
+			// Failed to lock daemons.
+			msg := fmt.Sprintf("Unable to edit the client class with ID %d because it may be currently edited by another user", params.ClientClassID)
+			log.WithError(err).Error(msg)
+			rsp := dhcp.NewUpdateClientClassBeginDefault(http.StatusLocked).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		default:
+			// Other error.
+			msg := fmt.Sprintf("Problem with initializing transaction for an update of the client class with ID %d", params.ClientClassID)
+			log.WithError(err).Error(msg)
+			rsp := dhcp.NewUpdateClientClassBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		}
+	}
+	state, _ := config.GetTransactionState[kea.ConfigRecipe](cctx)
+	class := state.Updates[0].Recipe.ClientClassBeforeUpdate
+
+	// Retrieve the generated context ID.
+	cctxID, ok := config.GetValueAsInt64(cctx, config.ContextIDKey)
+	if !ok {
+		msg := "problem with retrieving context ID for a transaction to update a client class"
+		log.Error(msg)
+		rsp := dhcp.NewUpdateClientClassBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Remember the context, i.e. new transaction has been successfully created.
+	_ = r.ConfigManager.RememberContext(cctx, time.Minute*10)
+
+	// Return transaction ID and the client class to the user.
+	contents := &models.UpdateClientClassBeginResponse{
+		ID:          cctxID,
+		ClientClass: r.convertClientClassToRestAPI(class),
+	}
+	rsp := dhcp.NewUpdateClientClassBeginOK().WithPayload(contents)
+	return rsp
+}
+
+// Implements the POST call and commits an updated client class
+// (client-classes/{clientClassId}/transaction/{id}/submit).
+func (r *RestAPI) UpdateClientClassSubmit(ctx context.Context, params dhcp.UpdateClientClassSubmitParams) middleware.Responder {
+	if code, _, msg := r.commonCreateOrUpdateClientClassSubmit(ctx, params.ID, params.ClientClass, r.ConfigManager.GetKeaModule().ApplyClientClassUpdate); code != 0 {
+		// Error case.
+		rsp := dhcp.NewUpdateClientClassSubmitDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewUpdateClientClassSubmitOK()
+	return rsp
+}
+
+// Implements the DELETE call to cancel updating a client class
+// (client-classes/{clientClassId}/transaction/{id}). It removes the specified
+// transaction from the config manager, if the transaction exists.
+func (r *RestAPI) UpdateClientClassDelete(ctx context.Context, params dhcp.UpdateClientClassDeleteParams) middleware.Responder {
+	if code, msg := r.commonCreateOrUpdateClientClassDelete(ctx, params.ID); code != 0 {
+		// Error case.
+		rsp := dhcp.NewUpdateClientClassDeleteDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewUpdateClientClassDeleteOK()
+	return rsp
+}
+
+// Implements the DELETE call for a client class (client-classes/{id}). It sends
+// the class-del command to the Kea server owning the class. Similarly to
+// deleting a subnet, this operation is not transactional.
+func (r *RestAPI) DeleteClientClass(ctx context.Context, params dhcp.DeleteClientClassParams) middleware.Responder {
+	dbClass, err := dbmodel.GetClientClass(r.DB, params.ID)
+	if err != nil {
+		// Error while communicating with the database.
+		msg := fmt.Sprintf("Problem fetching client class with ID %d from db", params.ID)
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewDeleteClientClassDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if dbClass == nil {
+		// Client class not found.
+		msg := fmt.Sprintf("Cannot find a client class with ID %d", params.ID)
+		rsp := dhcp.NewDeleteClientClassDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Make sure the user is permitted to delete the class from its daemon.
+	if !r.authorizeTargets(ctx, dbmodel.PermissionManageSubnets, getClientClassPermissionTarget(dbClass)) {
+		msg := fmt.Sprintf("User is forbidden to delete client class with ID %d", params.ID)
+		rsp := dhcp.NewDeleteClientClassDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Create configuration context.
+	_, user := r.SessionManager.Logged(ctx)
+	cctx, err := r.ConfigManager.CreateContext(int64(user.ID))
+	if err != nil {
+		msg := "Problem with creating transaction context for deleting the client class"
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewDeleteClientClassDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Create Kea commands to delete the client class.
+	cctx, err = r.ConfigManager.GetKeaModule().ApplyClientClassDelete(cctx, dbClass)
+	if err != nil {
+		msg := "Problem with preparing commands for deleting the client class"
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewDeleteClientClassDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Send the commands to Kea servers.
+	_, err = r.ConfigManager.Commit(cctx)
+	if err != nil {
+		msg := fmt.Sprintf("Problem with deleting a client class: %s", err)
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewDeleteClientClassDefault(http.StatusConflict).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Send OK to the client.
+	rsp := dhcp.NewDeleteClientClassOK()
+	return rsp
+}
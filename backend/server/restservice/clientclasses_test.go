@@ -0,0 +1,482 @@
+package restservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/stretchr/testify/require"
+	keaconfig "isc.org/stork/appcfg/kea"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	"isc.org/stork/server/apps"
+	"isc.org/stork/server/apps/kea"
+	appstest "isc.org/stork/server/apps/test"
+	dbops "isc.org/stork/server/database"
+	dbmodel "isc.org/stork/server/database/model"
+	dbmodeltest "isc.org/stork/server/database/model/test"
+	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/models"
+	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
+	storktest "isc.org/stork/server/test/dbmodel"
+	storkutil "isc.org/stork/util"
+)
+
+// Adds a Kea server with the client classes to the database.
+func addTestClientClassesServer(t *testing.T, db *pg.DB, hook string) *dbmodel.App {
+	serverConfig := `{
+		"Dhcp4": {
+			"client-classes": [
+				{
+					"name": "foo",
+					"test": "member('ALL')",
+					"valid-lifetime": 1800,
+					"option-data": [
+						{
+							"name": "domain-name-servers",
+							"data": "192.0.2.1",
+							"always-send": true
+						}
+					]
+				},
+				{
+					"name": "bar",
+					"next-server": "192.0.2.2",
+					"user-context": {
+						"comment": "baz"
+					},
+					"option-def": [
+						{
+							"name": "qux",
+							"code": 224,
+							"type": "uint32"
+						}
+					]
+				}
+			],
+			"hooks-libraries": [
+				{
+					"library": "` + hook + `"
+				}
+			]
+		}
+	}`
+	server, err := dbmodeltest.NewKeaDHCPv4Server(db)
+	require.NoError(t, err)
+	require.NoError(t, server.Configure(serverConfig))
+	app, err := server.GetKea()
+	require.NoError(t, err)
+	err = kea.CommitAppIntoDB(db, app, &storktest.FakeEventCenter{}, nil, dbmodel.NewDHCPOptionDefinitionLookup())
+	require.NoError(t, err)
+	return app
+}
+
+// Creates the REST API with the config manager and the logged user.
+func newTestClientClassesRestAPI(t *testing.T, db *pg.DB, dbSettings *dbops.DatabaseSettings, fa *agentcommtest.FakeAgents) (*RestAPI, context.Context) {
+	lookup := dbmodel.NewDHCPOptionDefinitionLookup()
+	cm := apps.NewManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    fa,
+		DefLookup: lookup,
+	})
+	rapi, err := NewRestAPI(dbSettings, db, fa, cm, lookup)
+	require.NoError(t, err)
+
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
+	user := &dbmodel.SystemUser{
+		ID: 1234,
+	}
+	err = rapi.SessionManager.LoginHandler(ctx, user)
+	require.NoError(t, err)
+	return rapi, ctx
+}
+
+// Test converting the client class between the database and the REST API
+// formats.
+func TestConvertClientClassToAndFromRestAPI(t *testing.T) {
+	rapi := &RestAPI{}
+	class := &dbmodel.ClientClass{
+		ID:       3,
+		DaemonID: 4,
+		Position: 1,
+		Name:     "foo",
+		Daemon: &dbmodel.Daemon{
+			Name:  "dhcp4",
+			AppID: 5,
+			App: &dbmodel.App{
+				Name: "kea@192.0.2.1",
+			},
+		},
+		KeaParameters: &keaconfig.ClientClass{
+			Name:           "foo",
+			Test:           "member('ALL')",
+			OnlyIfRequired: storkutil.Ptr(true),
+			ValidLifetime:  storkutil.Ptr(int64(1800)),
+			OptionData: []keaconfig.ClientClassOptionData{
+				{
+					Code:      6,
+					Data:      "192.0.2.1",
+					CSVFormat: storkutil.Ptr(true),
+				},
+			},
+			UserContext: map[string]any{
+				"comment": "bar",
+			},
+		},
+	}
+
+	restClass := rapi.convertClientClassToRestAPI(class)
+	require.EqualValues(t, 3, restClass.ID)
+	require.EqualValues(t, 4, restClass.DaemonID)
+	require.EqualValues(t, 5, restClass.AppID)
+	require.Equal(t, "kea@192.0.2.1", restClass.AppName)
+	require.Equal(t, "dhcp4", restClass.DaemonName)
+	require.EqualValues(t, 1, restClass.Position)
+	require.Equal(t, "foo", *restClass.Name)
+	require.Equal(t, "member('ALL')", restClass.Test)
+	require.True(t, *restClass.OnlyIfRequired)
+	require.EqualValues(t, 1800, *restClass.ValidLifetime)
+	require.Nil(t, restClass.PreferredLifetime)
+	require.Len(t, restClass.OptionData, 1)
+	require.EqualValues(t, 6, restClass.OptionData[0].Code)
+	require.True(t, *restClass.OptionData[0].CsvFormat)
+
+	converted, err := rapi.convertClientClassFromRestAPI(restClass)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, converted.ID)
+	require.EqualValues(t, 4, converted.DaemonID)
+	require.Equal(t, "foo", converted.Name)
+	require.Nil(t, converted.Daemon)
+	require.Equal(t, class.KeaParameters, converted.KeaParameters)
+}
+
+// Test that the client class without a name is rejected.
+func TestConvertClientClassFromRestAPIInvalid(t *testing.T) {
+	rapi := &RestAPI{}
+	_, err := rapi.convertClientClassFromRestAPI(&models.ClientClass{})
+	require.Error(t, err)
+
+	_, err = rapi.convertClientClassFromRestAPI(&models.ClientClass{
+		Name: storkutil.Ptr("foo"),
+		OptionData: []*models.ClientClassOptionData{
+			{Code: 65536},
+		},
+	})
+	require.Error(t, err)
+
+	_, err = rapi.convertClientClassFromRestAPI(&models.ClientClass{
+		Name:        storkutil.Ptr("foo"),
+		UserContext: "bar",
+	})
+	require.Error(t, err)
+}
+
+// Test getting the client classes pulled from the Kea configuration.
+func TestGetClientClasses(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestClientClassesServer(t, db, "libdhcp_class_cmds.so")
+
+	rapi, err := NewRestAPI(dbSettings, db)
+	require.NoError(t, err)
+
+	rsp := rapi.GetClientClasses(context.Background(), dhcp.GetClientClassesParams{})
+	require.IsType(t, &dhcp.GetClientClassesOK{}, rsp)
+	classes := rsp.(*dhcp.GetClientClassesOK).Payload
+	require.EqualValues(t, 2, classes.Total)
+	require.Len(t, classes.Items, 2)
+	require.Equal(t, "foo", *classes.Items[0].Name)
+	require.Equal(t, app.Daemons[0].ID, classes.Items[0].DaemonID)
+	require.Equal(t, app.ID, classes.Items[0].AppID)
+	require.Len(t, classes.Items[0].OptionData, 1)
+	require.Equal(t, "bar", *classes.Items[1].Name)
+	require.Equal(t, "192.0.2.2", classes.Items[1].NextServer)
+
+	// Filter by text.
+	rsp = rapi.GetClientClasses(context.Background(), dhcp.GetClientClassesParams{
+		Text: storkutil.Ptr("ALL"),
+	})
+	require.IsType(t, &dhcp.GetClientClassesOK{}, rsp)
+	classes = rsp.(*dhcp.GetClientClassesOK).Payload
+	require.EqualValues(t, 1, classes.Total)
+	require.Equal(t, "foo", *classes.Items[0].Name)
+
+	// Get a single class.
+	rsp = rapi.GetClientClass(context.Background(), dhcp.GetClientClassParams{
+		ID: classes.Items[0].ID,
+	})
+	require.IsType(t, &dhcp.GetClientClassOK{}, rsp)
+	require.Equal(t, "foo", *rsp.(*dhcp.GetClientClassOK).Payload.Name)
+
+	// Non-existing class.
+	rsp = rapi.GetClientClass(context.Background(), dhcp.GetClientClassParams{
+		ID: 12345,
+	})
+	require.IsType(t, &dhcp.GetClientClassDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*dhcp.GetClientClassDefault)))
+}
+
+// Test the transaction for adding a new client class.
+func TestCreateClientClassBeginSubmit(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestClientClassesServer(t, db, "libdhcp_class_cmds.so")
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, ctx := newTestClientClassesRestAPI(t, db, dbSettings, fa)
+
+	// Begin the transaction.
+	rsp := rapi.CreateClientClassBegin(ctx, dhcp.CreateClientClassBeginParams{})
+	require.IsType(t, &dhcp.CreateClientClassBeginOK{}, rsp)
+	contents := rsp.(*dhcp.CreateClientClassBeginOK).Payload
+	require.NotZero(t, contents.ID)
+	require.Len(t, contents.Daemons, 1)
+
+	// Submit the new class.
+	rsp = rapi.CreateClientClassSubmit(ctx, dhcp.CreateClientClassSubmitParams{
+		ID: contents.ID,
+		ClientClass: &models.ClientClass{
+			DaemonID: app.Daemons[0].ID,
+			Name:     storkutil.Ptr("baz"),
+			Test:     "member('foo')",
+		},
+	})
+	require.IsType(t, &dhcp.CreateClientClassSubmitOK{}, rsp)
+	classID := rsp.(*dhcp.CreateClientClassSubmitOK).Payload.ClientClassID
+	require.NotZero(t, classID)
+
+	require.Len(t, fa.RecordedCommands, 2)
+	require.JSONEq(t, `{
+		"command": "class-add",
+		"service": ["dhcp4"],
+		"arguments": {
+			"client-classes": [
+				{
+					"name": "baz",
+					"test": "member('foo')"
+				}
+			]
+		}
+	}`, fa.RecordedCommands[0].Marshal())
+	require.JSONEq(t, `{
+		"command": "config-write",
+		"service": ["dhcp4"]
+	}`, fa.RecordedCommands[1].Marshal())
+
+	class, err := dbmodel.GetClientClass(db, classID)
+	require.NoError(t, err)
+	require.NotNil(t, class)
+	require.Equal(t, "baz", class.Name)
+	require.EqualValues(t, 2, class.Position)
+}
+
+// Test that the servers lacking the class_cmds hook library are offered
+// for adding a client class.
+func TestCreateClientClassBeginNoHook(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestClientClassesServer(t, db, "libdhcp_subnet_cmds.so")
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, ctx := newTestClientClassesRestAPI(t, db, dbSettings, fa)
+
+	rsp := rapi.CreateClientClassBegin(ctx, dhcp.CreateClientClassBeginParams{})
+	require.IsType(t, &dhcp.CreateClientClassBeginOK{}, rsp)
+	contents := rsp.(*dhcp.CreateClientClassBeginOK).Payload
+	require.Len(t, contents.Daemons, 1)
+	require.Equal(t, app.Daemons[0].ID, contents.Daemons[0].ID)
+}
+
+// Test that the transaction for adding a client class is not started when
+// there are no Kea servers.
+func TestCreateClientClassBeginNoServers(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, ctx := newTestClientClassesRestAPI(t, db, dbSettings, fa)
+
+	rsp := rapi.CreateClientClassBegin(ctx, dhcp.CreateClientClassBeginParams{})
+	require.IsType(t, &dhcp.CreateClientClassBeginDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*dhcp.CreateClientClassBeginDefault)))
+}
+
+// Test cancelling the transaction for adding a client class.
+func TestCreateClientClassBeginCancel(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	_ = addTestClientClassesServer(t, db, "libdhcp_class_cmds.so")
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, ctx := newTestClientClassesRestAPI(t, db, dbSettings, fa)
+
+	rsp := rapi.CreateClientClassBegin(ctx, dhcp.CreateClientClassBeginParams{})
+	require.IsType(t, &dhcp.CreateClientClassBeginOK{}, rsp)
+	transactionID := rsp.(*dhcp.CreateClientClassBeginOK).Payload.ID
+
+	rsp = rapi.CreateClientClassDelete(ctx, dhcp.CreateClientClassDeleteParams{
+		ID: transactionID,
+	})
+	require.IsType(t, &dhcp.CreateClientClassDeleteOK{}, rsp)
+
+	// The transaction no longer exists.
+	rsp = rapi.CreateClientClassSubmit(ctx, dhcp.CreateClientClassSubmitParams{
+		ID: transactionID,
+		ClientClass: &models.ClientClass{
+			Name: storkutil.Ptr("baz"),
+		},
+	})
+	require.IsType(t, &dhcp.CreateClientClassSubmitDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*dhcp.CreateClientClassSubmitDefault)))
+	require.Empty(t, fa.RecordedCommands)
+}
+
+// Test the transaction for updating a client class. The parameters not
+// exposed over the REST API should be preserved.
+func TestUpdateClientClassBeginSubmit(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestClientClassesServer(t, db, "libdhcp_class_cmds.so")
+	classes, err := dbmodel.GetClientClassesByDaemonID(db, app.Daemons[0].ID)
+	require.NoError(t, err)
+	require.Len(t, classes, 2)
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, ctx := newTestClientClassesRestAPI(t, db, dbSettings, fa)
+
+	rsp := rapi.UpdateClientClassBegin(ctx, dhcp.UpdateClientClassBeginParams{
+		ClientClassID: classes[1].ID,
+	})
+	require.IsType(t, &dhcp.UpdateClientClassBeginOK{}, rsp)
+	contents := rsp.(*dhcp.UpdateClientClassBeginOK).Payload
+	require.NotNil(t, contents.ClientClass)
+	require.Equal(t, "bar", *contents.ClientClass.Name)
+
+	// The class is locked for updates by other users.
+	rsp2 := rapi.UpdateClientClassBegin(ctx, dhcp.UpdateClientClassBeginParams{
+		ClientClassID: classes[1].ID,
+	})
+	require.IsType(t, &dhcp.UpdateClientClassBeginDefault{}, rsp2)
+	require.Equal(t, http.StatusLocked, getStatusCode(*rsp2.(*dhcp.UpdateClientClassBeginDefault)))
+
+	// Submit the updated class without the user context.
+	restClass := contents.ClientClass
+	restClass.NextServer = "192.0.2.3"
+	restClass.UserContext = nil
+	rsp = rapi.UpdateClientClassSubmit(ctx, dhcp.UpdateClientClassSubmitParams{
+		ClientClassID: classes[1].ID,
+		ID:            contents.ID,
+		ClientClass:   restClass,
+	})
+	require.IsType(t, &dhcp.UpdateClientClassSubmitOK{}, rsp)
+
+	require.Len(t, fa.RecordedCommands, 2)
+	require.JSONEq(t, `{
+		"command": "class-update",
+		"service": ["dhcp4"],
+		"arguments": {
+			"client-classes": [
+				{
+					"name": "bar",
+					"next-server": "192.0.2.3",
+					"user-context": {
+						"comment": "baz"
+					},
+					"option-def": [
+						{
+							"name": "qux",
+							"code": 224,
+							"type": "uint32"
+						}
+					]
+				}
+			]
+		}
+	}`, fa.RecordedCommands[0].Marshal())
+
+	class, err := dbmodel.GetClientClass(db, classes[1].ID)
+	require.NoError(t, err)
+	require.Equal(t, "192.0.2.3", class.KeaParameters.NextServer)
+	require.Contains(t, class.KeaParameters.Unrecognized, "option-def")
+}
+
+// Test that the client class update transaction is not started when the
+// class doesn't exist.
+func TestUpdateClientClassBeginError(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	_ = addTestClientClassesServer(t, db, "libdhcp_class_cmds.so")
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, ctx := newTestClientClassesRestAPI(t, db, dbSettings, fa)
+
+	rsp := rapi.UpdateClientClassBegin(ctx, dhcp.UpdateClientClassBeginParams{
+		ClientClassID: 12345,
+	})
+	require.IsType(t, &dhcp.UpdateClientClassBeginDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*dhcp.UpdateClientClassBeginDefault)))
+}
+
+// Test deleting a client class.
+func TestDeleteClientClass(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestClientClassesServer(t, db, "libdhcp_class_cmds.so")
+	classes, err := dbmodel.GetClientClassesByDaemonID(db, app.Daemons[0].ID)
+	require.NoError(t, err)
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, ctx := newTestClientClassesRestAPI(t, db, dbSettings, fa)
+
+	rsp := rapi.DeleteClientClass(ctx, dhcp.DeleteClientClassParams{
+		ID: classes[0].ID,
+	})
+	require.IsType(t, &dhcp.DeleteClientClassOK{}, rsp)
+
+	require.Len(t, fa.RecordedCommands, 2)
+	require.JSONEq(t, `{
+		"command": "class-del",
+		"service": ["dhcp4"],
+		"arguments": {
+			"name": "foo"
+		}
+	}`, fa.RecordedCommands[0].Marshal())
+
+	class, err := dbmodel.GetClientClass(db, classes[0].ID)
+	require.NoError(t, err)
+	require.Nil(t, class)
+}
+
+// Test that an error is returned when Kea fails to delete the client class.
+func TestDeleteClientClassError(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestClientClassesServer(t, db, "libdhcp_class_cmds.so")
+	classes, err := dbmodel.GetClientClassesByDaemonID(db, app.Daemons[0].ID)
+	require.NoError(t, err)
+
+	fa := agentcommtest.NewFakeAgents(func(callNo int, cmdResponses []interface{}) {
+		mockStatusError("class-del", cmdResponses)
+	}, nil)
+	rapi, ctx := newTestClientClassesRestAPI(t, db, dbSettings, fa)
+
+	rsp := rapi.DeleteClientClass(ctx, dhcp.DeleteClientClassParams{
+		ID: classes[0].ID,
+	})
+	require.IsType(t, &dhcp.DeleteClientClassDefault{}, rsp)
+	require.Equal(t, http.StatusConflict, getStatusCode(*rsp.(*dhcp.DeleteClientClassDefault)))
+
+	// The class should remain in the database.
+	class, err := dbmodel.GetClientClass(db, classes[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, class)
+}
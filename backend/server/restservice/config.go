@@ -180,16 +180,17 @@ func (r *RestAPI) PutDaemonConfigReview(ctx context.Context, params services.Put
 		})
 		return rsp
 	}
-	// Config review is currently only supported for Kea.
-	if daemon.KeaDaemon == nil {
-		msg := fmt.Sprintf("Daemon with ID %d is not a Kea daemon", params.ID)
+	// Config review is currently only supported for Kea and BIND 9.
+	if daemon.KeaDaemon == nil && daemon.Bind9Daemon == nil {
+		msg := fmt.Sprintf("Daemon with ID %d is neither a Kea nor a BIND 9 daemon", params.ID)
 		rsp := services.NewPutDaemonConfigReviewDefault(http.StatusBadRequest).WithPayload(&models.APIError{
 			Message: &msg,
 		})
 		return rsp
 	}
 	// Config must be present to perform the review.
-	if daemon.KeaDaemon.Config == nil {
+	if (daemon.KeaDaemon != nil && daemon.KeaDaemon.Config == nil) ||
+		(daemon.Bind9Daemon != nil && daemon.Bind9Daemon.Config == nil) {
 		msg := fmt.Sprintf("Configuration not found for daemon with ID %d", params.ID)
 		rsp := services.NewPutDaemonConfigReviewDefault(http.StatusBadRequest).WithPayload(&models.APIError{
 			Message: &msg,
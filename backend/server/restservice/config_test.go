@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	bind9config "isc.org/stork/appcfg/bind9"
 	agentcommtest "isc.org/stork/server/agentcomm/test"
 	"isc.org/stork/server/configreview"
 	dbmodel "isc.org/stork/server/database/model"
@@ -837,8 +838,9 @@ func TestPutDaemonConfigReviewDatabaseError(t *testing.T) {
 }
 
 // Test that HTTP Bad Request status is returned as a result of requesting
-// a configuration review for a non-Kea daemon.
-func TestPutDaemonConfigReviewNotKeaDaemon(t *testing.T) {
+// a configuration review for a BIND 9 daemon without the configuration, and
+// that the review is scheduled when the configuration is present.
+func TestPutDaemonConfigReviewBind9Daemon(t *testing.T) {
 	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
 	defer teardown()
 
@@ -880,8 +882,20 @@ func TestPutDaemonConfigReviewNotKeaDaemon(t *testing.T) {
 	defaultRsp := rsp.(*services.PutDaemonConfigReviewDefault)
 	require.NotNil(t, defaultRsp)
 	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
-	require.Equal(t, fmt.Sprintf("Daemon with ID %d is not a Kea daemon", daemons[0].ID),
+	require.Equal(t, fmt.Sprintf("Configuration not found for daemon with ID %d", daemons[0].ID),
 		*defaultRsp.Payload.Message)
+	require.Empty(t, fd.CallLog)
+
+	// Store the configuration.
+	daemons[0].Bind9Daemon.Config, err = bind9config.Parse(`options { recursion no; };`)
+	require.NoError(t, err)
+	daemons[0].Bind9Daemon.ConfigHash = "hash"
+	require.NoError(t, dbmodel.UpdateDaemon(db, daemons[0]))
+
+	rsp = rapi.PutDaemonConfigReview(ctx, params)
+	require.IsType(t, &services.PutDaemonConfigReviewAccepted{}, rsp)
+	require.Len(t, fd.CallLog, 1)
+	require.Equal(t, "BeginReview", fd.CallLog[0].CallName)
 }
 
 // Test that HTTP Bad Request status is returned as a result of requesting
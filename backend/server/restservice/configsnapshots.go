@@ -0,0 +1,267 @@
+package restservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	keaconfig "isc.org/stork/appcfg/kea"
+	"isc.org/stork/server/apps/kea"
+	"isc.org/stork/server/config"
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/services"
+)
+
+// Converts the configuration snapshot from the database to the REST API
+// format. The configuration is included if it has been fetched from the
+// database.
+func newRestKeaConfigSnapshot(dbSnapshot *dbmodel.KeaConfigSnapshot) *models.KeaConfigSnapshot {
+	snapshot := &models.KeaConfigSnapshot{
+		ID:           dbSnapshot.ID,
+		DaemonID:     dbSnapshot.DaemonID,
+		CreatedAt:    strfmt.DateTime(dbSnapshot.CreatedAt),
+		ConfigHash:   dbSnapshot.ConfigHash,
+		Source:       string(dbSnapshot.Source),
+		UserID:       dbSnapshot.UserID,
+		UserLogin:    dbSnapshot.UserLogin,
+		AuditEntryID: dbSnapshot.AuditEntryID,
+		Operation:    dbSnapshot.Operation,
+		Description:  dbSnapshot.Description,
+	}
+	if dbSnapshot.Config != nil {
+		snapshot.Config = dbSnapshot.Config
+	}
+	return snapshot
+}
+
+// Hides the passwords and other secrets in the configuration unless the
+// logged user is a super-admin.
+func (r *RestAPI) hideConfigSensitiveData(ctx context.Context, config *dbmodel.KeaConfig) {
+	if config == nil {
+		return
+	}
+	_, user := r.SessionManager.Logged(ctx)
+	if user == nil || !user.InGroup(&dbmodel.SystemGroup{ID: dbmodel.SuperAdminGroupID}) {
+		config.HideSensitiveData()
+	}
+}
+
+// Get the stored versions of the daemon's configuration from the newest to
+// the oldest. The configurations are not returned.
+func (r *RestAPI) GetDaemonConfigSnapshots(ctx context.Context, params services.GetDaemonConfigSnapshotsParams) middleware.Responder {
+	var start int64
+	if params.Start != nil {
+		start = *params.Start
+	}
+
+	var limit int64 = 10
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+
+	dbSnapshots, total, err := dbmodel.GetKeaConfigSnapshotsByPage(r.DB, params.ID, start, limit)
+	if err != nil {
+		msg := fmt.Sprintf("Problem fetching configuration snapshots of daemon %d from the database", params.ID)
+		log.WithError(err).Error(msg)
+		return services.NewGetDaemonConfigSnapshotsDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+
+	snapshots := &models.KeaConfigSnapshots{
+		Items: []*models.KeaConfigSnapshot{},
+		Total: total,
+	}
+	for i := range dbSnapshots {
+		snapshots.Items = append(snapshots.Items, newRestKeaConfigSnapshot(&dbSnapshots[i]))
+	}
+	return services.NewGetDaemonConfigSnapshotsOK().WithPayload(snapshots)
+}
+
+// Get the stored version of the daemon's configuration including the
+// configuration. The sensitive data are hidden unless the user is a
+// super-admin.
+func (r *RestAPI) GetConfigSnapshot(ctx context.Context, params services.GetConfigSnapshotParams) middleware.Responder {
+	dbSnapshot, err := dbmodel.GetKeaConfigSnapshot(r.DB, params.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Problem fetching configuration snapshot %d from the database", params.ID)
+		log.WithError(err).Error(msg)
+		return services.NewGetConfigSnapshotDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if dbSnapshot == nil {
+		msg := fmt.Sprintf("Cannot find configuration snapshot with ID %d", params.ID)
+		log.Error(msg)
+		return services.NewGetConfigSnapshotDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	r.hideConfigSensitiveData(ctx, dbSnapshot.Config)
+	return services.NewGetConfigSnapshotOK().WithPayload(newRestKeaConfigSnapshot(dbSnapshot))
+}
+
+// Returns the configuration for comparison. It is the configuration held
+// in the snapshot or, if the snapshot is not specified, the current
+// configuration of the daemon. The sensitive data are hidden unless the
+// user is a super-admin. It returns the HTTP status code and the error
+// message when the configuration cannot be returned.
+func (r *RestAPI) getConfigForDiff(ctx context.Context, snapshotID, daemonID *int64) (*keaconfig.Config, int, string) {
+	switch {
+	case snapshotID != nil && daemonID != nil:
+		return nil, http.StatusBadRequest, "Either a configuration snapshot or a daemon must be specified, but not both"
+	case snapshotID != nil:
+		dbSnapshot, err := dbmodel.GetKeaConfigSnapshot(r.DB, *snapshotID)
+		if err != nil {
+			msg := fmt.Sprintf("Problem fetching configuration snapshot %d from the database", *snapshotID)
+			log.WithError(err).Error(msg)
+			return nil, http.StatusInternalServerError, msg
+		}
+		if dbSnapshot == nil {
+			return nil, http.StatusNotFound, fmt.Sprintf("Cannot find configuration snapshot with ID %d", *snapshotID)
+		}
+		r.hideConfigSensitiveData(ctx, dbSnapshot.Config)
+		if dbSnapshot.Config == nil {
+			return nil, http.StatusOK, ""
+		}
+		return dbSnapshot.Config.Config, http.StatusOK, ""
+	case daemonID != nil:
+		daemon, err := dbmodel.GetDaemonByID(r.DB, *daemonID)
+		if err != nil {
+			msg := fmt.Sprintf("Problem with fetching daemon %d from the database", *daemonID)
+			log.WithError(err).Error(msg)
+			return nil, http.StatusInternalServerError, msg
+		}
+		if daemon == nil {
+			return nil, http.StatusNotFound, fmt.Sprintf("Cannot find daemon with ID %d", *daemonID)
+		}
+		if daemon.KeaDaemon == nil {
+			return nil, http.StatusBadRequest, fmt.Sprintf("Daemon with ID %d is not a Kea daemon", *daemonID)
+		}
+		if daemon.KeaDaemon.Config == nil {
+			return nil, http.StatusOK, ""
+		}
+		r.hideConfigSensitiveData(ctx, daemon.KeaDaemon.Config)
+		return daemon.KeaDaemon.Config.Config, http.StatusOK, ""
+	default:
+		return nil, http.StatusBadRequest, "Either a configuration snapshot or a daemon must be specified"
+	}
+}
+
+// Get the semantic differences between two configurations. Each of the
+// configurations is either a stored version or the current configuration
+// of a daemon. The sensitive data are hidden in both configurations unless
+// the user is a super-admin.
+func (r *RestAPI) GetConfigDiff(ctx context.Context, params services.GetConfigDiffParams) middleware.Responder {
+	var configs []*keaconfig.Config
+	for _, source := range [][]*int64{
+		{params.FromSnapshot, params.FromDaemon},
+		{params.ToSnapshot, params.ToDaemon},
+	} {
+		config, status, msg := r.getConfigForDiff(ctx, source[0], source[1])
+		if status != http.StatusOK {
+			if status != http.StatusInternalServerError {
+				log.Error(msg)
+			}
+			return services.NewGetConfigDiffDefault(status).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+		}
+		configs = append(configs, config)
+	}
+
+	diff := &models.ConfigDiff{
+		Items: []*models.ConfigDiffEntry{},
+	}
+	for _, entry := range keaconfig.DiffConfigs(configs[0], configs[1]) {
+		diff.Items = append(diff.Items, &models.ConfigDiffEntry{
+			Path:      entry.Path,
+			Operation: string(entry.Operation),
+			Before:    entry.Before,
+			After:     entry.After,
+		})
+	}
+	diff.Total = int64(len(diff.Items))
+	return services.NewGetConfigDiffOK().WithPayload(diff)
+}
+
+// Rolls the daemon back to the stored configuration. The rollback must be
+// confirmed in the request. The outcome is recorded in the events.
+func (r *RestAPI) RollbackConfigSnapshot(ctx context.Context, params services.RollbackConfigSnapshotParams) middleware.Responder {
+	if params.Request.Confirm == nil || !*params.Request.Confirm {
+		msg := "The configuration rollback must be confirmed"
+		log.Error(msg)
+		return services.NewRollbackConfigSnapshotDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	dbSnapshot, err := dbmodel.GetKeaConfigSnapshot(r.DB, params.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Problem fetching configuration snapshot %d from the database", params.ID)
+		log.WithError(err).Error(msg)
+		return services.NewRollbackConfigSnapshotDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if dbSnapshot == nil {
+		msg := fmt.Sprintf("Cannot find configuration snapshot with ID %d", params.ID)
+		log.Error(msg)
+		return services.NewRollbackConfigSnapshotDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	daemon, err := dbmodel.GetDaemonByID(r.DB, dbSnapshot.DaemonID)
+	if err != nil {
+		msg := fmt.Sprintf("Problem with fetching daemon %d from the database", dbSnapshot.DaemonID)
+		log.WithError(err).Error(msg)
+		return services.NewRollbackConfigSnapshotDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if daemon == nil {
+		msg := fmt.Sprintf("Cannot find daemon with ID %d", dbSnapshot.DaemonID)
+		log.Error(msg)
+		return services.NewRollbackConfigSnapshotDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if !r.authorizeTargets(ctx, dbmodel.PermissionManageSubnets, newPermissionTarget(daemon.ID, daemon, 0)) {
+		msg := "User is forbidden to roll back the configuration of the selected server"
+		return services.NewRollbackConfigSnapshotDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	_, user := r.SessionManager.Logged(ctx)
+	restored, err := kea.RollbackConfig(ctx, r.DB, r.Agents, r.ConfigManager, daemon, dbSnapshot, user)
+	if r.EventCenter != nil {
+		if err != nil {
+			r.EventCenter.AddErrorEvent(fmt.Sprintf("{user} failed to roll back {daemon} to configuration snapshot %d", dbSnapshot.ID), user, daemon, err)
+		} else {
+			r.EventCenter.AddInfoEvent(fmt.Sprintf("{user} rolled back {daemon} to configuration snapshot %d", dbSnapshot.ID), user, daemon)
+		}
+	}
+	var lock *config.LockError
+	switch {
+	case errors.As(err, &lock):
+		msg := "Unable to roll back the configuration because the server may be currently edited by another user"
+		log.WithError(err).Error(msg)
+		return services.NewRollbackConfigSnapshotDefault(http.StatusLocked).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	case err != nil:
+		msg := fmt.Sprintf("Problem with rolling back the configuration: %s", err)
+		log.WithError(err).Error(msg)
+		return services.NewRollbackConfigSnapshotDefault(http.StatusConflict).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	// The restored configuration is not returned to limit the response size.
+	restored.Config = nil
+	return services.NewRollbackConfigSnapshotOK().WithPayload(newRestKeaConfigSnapshot(restored))
+}
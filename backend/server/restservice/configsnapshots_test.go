@@ -0,0 +1,377 @@
+package restservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/go-pg/pg/v10"
+	require "github.com/stretchr/testify/require"
+
+	keaconfig "isc.org/stork/appcfg/kea"
+	keactrl "isc.org/stork/appctrl/kea"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	"isc.org/stork/server/apps"
+	appstest "isc.org/stork/server/apps/test"
+	dbops "isc.org/stork/server/database"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/restapi/operations/services"
+	storktest "isc.org/stork/server/test/dbmodel"
+	storkutil "isc.org/stork/util"
+)
+
+// Current configuration of the test server used in the configuration
+// snapshot tests. It is also stored in the newest snapshot.
+const testConfigSnapshotsServerConfig = `{
+	"Dhcp4": {
+		"valid-lifetime": 3000,
+		"lease-database": { "type": "mysql", "password": "secret" }
+	}
+}`
+
+// Adds a Kea app with a DHCPv4 server and two snapshots of its
+// configuration. It returns the app and the snapshots from the oldest
+// to the newest. The server's configuration hash matches the configuration
+// returned by the mockConfigSnapshotsConfigGet function.
+func addTestConfigSnapshots(t *testing.T, db *pg.DB) (*dbmodel.App, []*dbmodel.KeaConfigSnapshot) {
+	machine := &dbmodel.Machine{
+		Address:   "machine",
+		AgentPort: 8080,
+	}
+	err := dbmodel.AddMachine(db, machine)
+	require.NoError(t, err)
+
+	daemon := dbmodel.NewKeaDaemon(dbmodel.DaemonNameDHCPv4, true)
+	err = daemon.SetConfigFromJSON(testConfigSnapshotsServerConfig)
+	require.NoError(t, err)
+	daemon.KeaDaemon.ConfigHash = keaconfig.NewHasher().Hash([]byte(testConfigSnapshotsServerConfig))
+
+	accessPoints := []*dbmodel.AccessPoint{}
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "localhost", "", 8000, true)
+	app := &dbmodel.App{
+		Name:         "kea",
+		MachineID:    machine.ID,
+		Type:         dbmodel.AppTypeKea,
+		AccessPoints: accessPoints,
+		Daemons:      []*dbmodel.Daemon{daemon},
+	}
+	_, err = dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+
+	var snapshots []*dbmodel.KeaConfigSnapshot
+	for i, config := range []string{
+		`{
+			"Dhcp4": {
+				"valid-lifetime": 4000,
+				"lease-database": { "type": "mysql", "password": "secret" }
+			}
+		}`,
+		testConfigSnapshotsServerConfig,
+	} {
+		keaConfig, err := dbmodel.NewKeaConfigFromJSON(config)
+		require.NoError(t, err)
+		snapshot := &dbmodel.KeaConfigSnapshot{
+			DaemonID:   app.Daemons[0].ID,
+			Config:     keaConfig,
+			ConfigHash: string(rune('a' + i)),
+			Source:     dbmodel.KeaConfigSnapshotSourceFetched,
+		}
+		err = dbmodel.AddKeaConfigSnapshot(db, snapshot)
+		require.NoError(t, err)
+		snapshots = append(snapshots, snapshot)
+	}
+	return app, snapshots
+}
+
+// Generates the response to the config-get command returning the current
+// configuration of the test server.
+func mockConfigSnapshotsConfigGet(callNo int, cmdResponses []interface{}) {
+	json := []byte(fmt.Sprintf(`[{"result": 0, "arguments": %s}]`, testConfigSnapshotsServerConfig))
+	command := keactrl.NewCommandBase(keactrl.ConfigGet, keactrl.DHCPv4)
+	_ = keactrl.UnmarshalResponseList(command, json, cmdResponses[0])
+}
+
+// Creates the REST API with the config manager and the logged user.
+func newTestConfigSnapshotsRestAPI(t *testing.T, db *pg.DB, dbSettings *dbops.DatabaseSettings, agents *agentcommtest.FakeAgents) (*RestAPI, context.Context, *storktest.FakeEventCenter) {
+	rapi, ctx, fec := newTestHAControlRestAPI(t, db, dbSettings, agents)
+	rapi.ConfigManager = apps.NewManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    agents,
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	return rapi, ctx, fec
+}
+
+// Test listing the daemon's configuration snapshots.
+func TestGetDaemonConfigSnapshots(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	rapi, ctx, _ := newTestHAControlRestAPI(t, db, dbSettings, agentcommtest.NewFakeAgents(nil, nil))
+	app, snapshots := addTestConfigSnapshots(t, db)
+
+	rsp := rapi.GetDaemonConfigSnapshots(ctx, services.GetDaemonConfigSnapshotsParams{
+		ID: app.Daemons[0].ID,
+	})
+	require.IsType(t, &services.GetDaemonConfigSnapshotsOK{}, rsp)
+	payload := rsp.(*services.GetDaemonConfigSnapshotsOK).Payload
+	require.EqualValues(t, 2, payload.Total)
+	require.Len(t, payload.Items, 2)
+	require.Equal(t, snapshots[1].ID, payload.Items[0].ID)
+	require.Equal(t, "fetched", payload.Items[0].Source)
+	require.Nil(t, payload.Items[0].Config)
+
+	rsp = rapi.GetDaemonConfigSnapshots(ctx, services.GetDaemonConfigSnapshotsParams{
+		ID:    app.Daemons[0].ID,
+		Start: storkutil.Ptr(int64(1)),
+		Limit: storkutil.Ptr(int64(1)),
+	})
+	require.IsType(t, &services.GetDaemonConfigSnapshotsOK{}, rsp)
+	payload = rsp.(*services.GetDaemonConfigSnapshotsOK).Payload
+	require.EqualValues(t, 2, payload.Total)
+	require.Len(t, payload.Items, 1)
+	require.Equal(t, snapshots[0].ID, payload.Items[0].ID)
+}
+
+// Test getting a single configuration snapshot with the sensitive data
+// hidden from a user who is not a super-admin.
+func TestGetConfigSnapshot(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	rapi, ctx, _ := newTestHAControlRestAPI(t, db, dbSettings, agentcommtest.NewFakeAgents(nil, nil))
+	_, snapshots := addTestConfigSnapshots(t, db)
+
+	rsp := rapi.GetConfigSnapshot(ctx, services.GetConfigSnapshotParams{
+		ID: snapshots[0].ID,
+	})
+	require.IsType(t, &services.GetConfigSnapshotOK{}, rsp)
+	payload := rsp.(*services.GetConfigSnapshotOK).Payload
+	require.Equal(t, snapshots[0].ID, payload.ID)
+	require.NotNil(t, payload.Config)
+
+	config, ok := payload.Config.(*dbmodel.KeaConfig)
+	require.True(t, ok)
+	password, ok := config.Raw["Dhcp4"].(map[string]any)["lease-database"].(map[string]any)["password"]
+	require.True(t, ok)
+	require.Nil(t, password)
+
+	rsp = rapi.GetConfigSnapshot(ctx, services.GetConfigSnapshotParams{
+		ID: snapshots[1].ID + 1,
+	})
+	require.IsType(t, &services.GetConfigSnapshotDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*services.GetConfigSnapshotDefault)))
+}
+
+// Test getting the differences between two configuration snapshots.
+func TestGetConfigDiff(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	rapi, ctx, _ := newTestHAControlRestAPI(t, db, dbSettings, agentcommtest.NewFakeAgents(nil, nil))
+	_, snapshots := addTestConfigSnapshots(t, db)
+
+	rsp := rapi.GetConfigDiff(ctx, services.GetConfigDiffParams{
+		FromSnapshot: storkutil.Ptr(snapshots[0].ID),
+		ToSnapshot:   storkutil.Ptr(snapshots[1].ID),
+	})
+	require.IsType(t, &services.GetConfigDiffOK{}, rsp)
+	payload := rsp.(*services.GetConfigDiffOK).Payload
+	require.EqualValues(t, 1, payload.Total)
+	require.Len(t, payload.Items, 1)
+	require.Equal(t, "Dhcp4.valid-lifetime", payload.Items[0].Path)
+	require.Equal(t, "changed", payload.Items[0].Operation)
+	require.EqualValues(t, 4000, payload.Items[0].Before)
+	require.EqualValues(t, 3000, payload.Items[0].After)
+
+	rsp = rapi.GetConfigDiff(ctx, services.GetConfigDiffParams{
+		FromSnapshot: storkutil.Ptr(snapshots[0].ID),
+		ToSnapshot:   storkutil.Ptr(snapshots[1].ID + 1),
+	})
+	require.IsType(t, &services.GetConfigDiffDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*services.GetConfigDiffDefault)))
+}
+
+// Test getting the differences between the current configuration of a
+// daemon and a stored version and between two daemons.
+func TestGetConfigDiffDaemons(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	rapi, ctx, _ := newTestHAControlRestAPI(t, db, dbSettings, agentcommtest.NewFakeAgents(nil, nil))
+	app, snapshots := addTestConfigSnapshots(t, db)
+	err := app.Daemons[0].SetConfigFromJSON(`{
+		"Dhcp4": {
+			"valid-lifetime": 3000,
+			"lease-database": { "type": "mysql", "password": "other" }
+		}
+	}`)
+	require.NoError(t, err)
+	err = dbmodel.UpdateDaemon(db, app.Daemons[0])
+	require.NoError(t, err)
+
+	// The differing password is not reported.
+	rsp := rapi.GetConfigDiff(ctx, services.GetConfigDiffParams{
+		FromSnapshot: storkutil.Ptr(snapshots[1].ID),
+		ToDaemon:     storkutil.Ptr(app.Daemons[0].ID),
+	})
+	require.IsType(t, &services.GetConfigDiffOK{}, rsp)
+	require.Empty(t, rsp.(*services.GetConfigDiffOK).Payload.Items)
+
+	rsp = rapi.GetConfigDiff(ctx, services.GetConfigDiffParams{
+		FromDaemon: storkutil.Ptr(app.Daemons[0].ID),
+		ToDaemon:   storkutil.Ptr(app.Daemons[0].ID),
+	})
+	require.IsType(t, &services.GetConfigDiffOK{}, rsp)
+	require.Empty(t, rsp.(*services.GetConfigDiffOK).Payload.Items)
+
+	rsp = rapi.GetConfigDiff(ctx, services.GetConfigDiffParams{
+		FromDaemon: storkutil.Ptr(app.Daemons[0].ID),
+		ToDaemon:   storkutil.Ptr(app.Daemons[0].ID + 1),
+	})
+	require.IsType(t, &services.GetConfigDiffDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*services.GetConfigDiffDefault)))
+}
+
+// Test that the configuration diff request must specify exactly one
+// source of each compared configuration.
+func TestGetConfigDiffInvalidRequest(t *testing.T) {
+	rapi := &RestAPI{}
+
+	rsp := rapi.GetConfigDiff(context.Background(), services.GetConfigDiffParams{
+		ToSnapshot: storkutil.Ptr(int64(1)),
+	})
+	require.IsType(t, &services.GetConfigDiffDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*services.GetConfigDiffDefault)))
+
+	rsp = rapi.GetConfigDiff(context.Background(), services.GetConfigDiffParams{
+		FromSnapshot: storkutil.Ptr(int64(1)),
+		FromDaemon:   storkutil.Ptr(int64(1)),
+		ToSnapshot:   storkutil.Ptr(int64(2)),
+	})
+	require.IsType(t, &services.GetConfigDiffDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*services.GetConfigDiffDefault)))
+}
+
+// Test rolling back the daemon's configuration to a snapshot.
+func TestRollbackConfigSnapshot(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewKeaFakeAgents(mockConfigSnapshotsConfigGet, mockHACommandResult(0, "Configuration successfully set."))
+	rapi, ctx, fec := newTestConfigSnapshotsRestAPI(t, db, dbSettings, agents)
+	app, snapshots := addTestConfigSnapshots(t, db)
+
+	rsp := rapi.RollbackConfigSnapshot(ctx, services.RollbackConfigSnapshotParams{
+		ID: snapshots[0].ID,
+		Request: services.RollbackConfigSnapshotBody{
+			Confirm: storkutil.Ptr(true),
+		},
+	})
+	require.IsType(t, &services.RollbackConfigSnapshotOK{}, rsp)
+	payload := rsp.(*services.RollbackConfigSnapshotOK).Payload
+	require.NotZero(t, payload.ID)
+	require.Equal(t, app.Daemons[0].ID, payload.DaemonID)
+	require.Equal(t, "rollback", payload.Source)
+	require.Equal(t, "jdoe", payload.UserLogin)
+	require.Nil(t, payload.Config)
+
+	require.Len(t, agents.RecordedCommands, 3)
+	require.EqualValues(t, keactrl.ConfigGet, agents.RecordedCommands[0].GetCommand())
+	require.EqualValues(t, keactrl.ConfigSet, agents.RecordedCommands[1].GetCommand())
+	require.EqualValues(t, keactrl.ConfigWrite, agents.RecordedCommands[2].GetCommand())
+
+	require.Len(t, fec.Events, 1)
+	require.Equal(t, dbmodel.EvInfo, fec.Events[0].Level)
+	require.Contains(t, fec.Events[0].Text, "to configuration snapshot")
+
+	latest, err := dbmodel.GetLatestKeaConfigSnapshot(db, app.Daemons[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, payload.ID, latest.ID)
+}
+
+// Test that the failed rollback is recorded in the events and returned
+// to the caller.
+func TestRollbackConfigSnapshotError(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewKeaFakeAgents(mockConfigSnapshotsConfigGet, mockHACommandResult(1, "Configuration rejected."))
+	rapi, ctx, fec := newTestConfigSnapshotsRestAPI(t, db, dbSettings, agents)
+	_, snapshots := addTestConfigSnapshots(t, db)
+
+	rsp := rapi.RollbackConfigSnapshot(ctx, services.RollbackConfigSnapshotParams{
+		ID: snapshots[0].ID,
+		Request: services.RollbackConfigSnapshotBody{
+			Confirm: storkutil.Ptr(true),
+		},
+	})
+	require.IsType(t, &services.RollbackConfigSnapshotDefault{}, rsp)
+	defaultRsp := rsp.(*services.RollbackConfigSnapshotDefault)
+	require.Equal(t, http.StatusConflict, getStatusCode(*defaultRsp))
+	require.Contains(t, *defaultRsp.Payload.Message, "Configuration rejected.")
+
+	require.Len(t, fec.Events, 1)
+	require.Equal(t, dbmodel.EvError, fec.Events[0].Level)
+	require.Contains(t, fec.Events[0].Text, "failed to roll back")
+
+	rsp = rapi.RollbackConfigSnapshot(ctx, services.RollbackConfigSnapshotParams{
+		ID: snapshots[1].ID + 1,
+		Request: services.RollbackConfigSnapshotBody{
+			Confirm: storkutil.Ptr(true),
+		},
+	})
+	require.IsType(t, &services.RollbackConfigSnapshotDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*services.RollbackConfigSnapshotDefault)))
+}
+
+// Test that the rollback is rejected when the server is edited by another
+// user.
+func TestRollbackConfigSnapshotLocked(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewKeaFakeAgents(mockConfigSnapshotsConfigGet)
+	rapi, ctx, fec := newTestConfigSnapshotsRestAPI(t, db, dbSettings, agents)
+	app, snapshots := addTestConfigSnapshots(t, db)
+
+	// Another user edits the server.
+	_, err := rapi.ConfigManager.Lock(context.Background(), app.Daemons[0].ID)
+	require.NoError(t, err)
+
+	rsp := rapi.RollbackConfigSnapshot(ctx, services.RollbackConfigSnapshotParams{
+		ID: snapshots[0].ID,
+		Request: services.RollbackConfigSnapshotBody{
+			Confirm: storkutil.Ptr(true),
+		},
+	})
+	require.IsType(t, &services.RollbackConfigSnapshotDefault{}, rsp)
+	require.Equal(t, http.StatusLocked, getStatusCode(*rsp.(*services.RollbackConfigSnapshotDefault)))
+	require.Empty(t, agents.RecordedCommands)
+	require.Len(t, fec.Events, 1)
+	require.Equal(t, dbmodel.EvError, fec.Events[0].Level)
+}
+
+// Test that the rollback which has not been confirmed is rejected.
+func TestRollbackConfigSnapshotNotConfirmed(t *testing.T) {
+	rapi := &RestAPI{}
+
+	rsp := rapi.RollbackConfigSnapshot(context.Background(), services.RollbackConfigSnapshotParams{
+		ID: 1,
+	})
+	require.IsType(t, &services.RollbackConfigSnapshotDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*services.RollbackConfigSnapshotDefault)))
+
+	rsp = rapi.RollbackConfigSnapshot(context.Background(), services.RollbackConfigSnapshotParams{
+		ID: 1,
+		Request: services.RollbackConfigSnapshotBody{
+			Confirm: storkutil.Ptr(false),
+		},
+	})
+	require.IsType(t, &services.RollbackConfigSnapshotDefault{}, rsp)
+	defaultRsp := rsp.(*services.RollbackConfigSnapshotDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+	require.Equal(t, "The configuration rollback must be confirmed", *defaultRsp.Payload.Message)
+}
@@ -0,0 +1,274 @@
+package restservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
+	log "github.com/sirupsen/logrus"
+
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/users"
+)
+
+// Creates new instance of the email subscription model used by REST API
+// from the subscription instance returned from the database.
+func newRestEmailSubscription(s dbmodel.EmailSubscription) *models.EmailSubscription {
+	level := int64(s.Level)
+	return &models.EmailSubscription{
+		ID:        s.ID,
+		CreatedAt: strfmt.DateTime(s.CreatedAt),
+		Email:     s.Email,
+		Enabled:   s.Enabled,
+		Level:     &level,
+		AppIds:    s.AppIDs,
+		DaemonIds: s.DaemonIDs,
+		SubnetIds: s.SubnetIDs,
+	}
+}
+
+// Validates the email subscription received in the request and converts
+// it to the database model. The user's email address is used if the
+// subscription does not specify the address. Other addresses are rejected
+// because they are not verified to belong to the user. It returns an error
+// message if the subscription is invalid.
+func newDBEmailSubscription(user *dbmodel.SystemUser, s *models.EmailSubscription) (*dbmodel.EmailSubscription, string) {
+	if s == nil {
+		return nil, "missing subscription"
+	}
+	email := strings.TrimSpace(s.Email)
+	if email == "" {
+		email = user.Email
+	}
+	if email == "" {
+		return nil, "missing email address"
+	}
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+		return nil, fmt.Sprintf("invalid email address %s", email)
+	}
+	if !strings.EqualFold(email, user.Email) {
+		return nil, fmt.Sprintf("email address %s is not the address of the user", email)
+	}
+	level := dbmodel.EvError
+	if s.Level != nil {
+		level = dbmodel.EventLevel(*s.Level)
+		if level < dbmodel.EvInfo || level > dbmodel.EvError {
+			return nil, "invalid level"
+		}
+	}
+	return &dbmodel.EmailSubscription{
+		UserID:    user.ID,
+		Email:     email,
+		Enabled:   s.Enabled,
+		Level:     level,
+		AppIDs:    s.AppIds,
+		DaemonIDs: s.DaemonIds,
+		SubnetIDs: s.SubnetIds,
+	}, ""
+}
+
+// Returns the email subscriptions of the user.
+func (r *RestAPI) GetEmailSubscriptions(ctx context.Context, params users.GetEmailSubscriptionsParams) middleware.Responder {
+	id := int(params.ID)
+	dbSubscriptions, err := dbmodel.GetEmailSubscriptionsByUserID(r.DB, id)
+	if err != nil {
+		log.WithField("userID", id).WithError(err).Error("Failed to fetch email subscriptions from the database")
+
+		msg := fmt.Sprintf("Failed to fetch email subscriptions of user with ID %d from the database", id)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewGetEmailSubscriptionsDefault(http.StatusInternalServerError).WithPayload(&rspErr)
+	}
+
+	subscriptions := &models.EmailSubscriptions{
+		Items: []*models.EmailSubscription{},
+		Total: int64(len(dbSubscriptions)),
+	}
+	for _, s := range dbSubscriptions {
+		subscriptions.Items = append(subscriptions.Items, newRestEmailSubscription(s))
+	}
+	return users.NewGetEmailSubscriptionsOK().WithPayload(subscriptions)
+}
+
+// Creates new email subscription of the user.
+func (r *RestAPI) CreateEmailSubscription(ctx context.Context, params users.CreateEmailSubscriptionParams) middleware.Responder {
+	id := int(params.ID)
+	su, err := dbmodel.GetUserByID(r.DB, id)
+	if err != nil {
+		log.WithField("userID", id).WithError(err).Error("Failed to fetch user from the database")
+
+		msg := fmt.Sprintf("Failed to fetch user with ID %d from the database", id)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewCreateEmailSubscriptionDefault(http.StatusInternalServerError).WithPayload(&rspErr)
+	}
+	if su == nil {
+		msg := fmt.Sprintf("Failed to find user with ID %d in the database", id)
+		log.WithField("userID", id).Error(msg)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewCreateEmailSubscriptionDefault(http.StatusNotFound).WithPayload(&rspErr)
+	}
+
+	dbSubscription, invalid := newDBEmailSubscription(su, params.Subscription)
+	if invalid != "" {
+		msg := fmt.Sprintf("Failed to create new email subscription: %s", invalid)
+		log.Warn(msg)
+		rspErr := models.APIError{Message: &msg}
+		return users.NewCreateEmailSubscriptionDefault(http.StatusBadRequest).WithPayload(&rspErr)
+	}
+
+	err = dbmodel.AddEmailSubscription(r.DB, dbSubscription)
+	if err != nil {
+		log.WithField("userID", id).WithError(err).Error("Failed to create new email subscription")
+
+		msg := fmt.Sprintf("Failed to create new email subscription for user %s", su.Identity())
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewCreateEmailSubscriptionDefault(http.StatusInternalServerError).WithPayload(&rspErr)
+	}
+	return users.NewCreateEmailSubscriptionOK().WithPayload(newRestEmailSubscription(*dbSubscription))
+}
+
+// Updates the email subscription of the user.
+func (r *RestAPI) UpdateEmailSubscription(ctx context.Context, params users.UpdateEmailSubscriptionParams) middleware.Responder {
+	id := int(params.ID)
+	su, err := dbmodel.GetUserByID(r.DB, id)
+	if err != nil {
+		log.WithField("userID", id).WithError(err).Error("Failed to fetch user from the database")
+
+		msg := fmt.Sprintf("Failed to fetch user with ID %d from the database", id)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewUpdateEmailSubscriptionDefault(http.StatusInternalServerError).WithPayload(&rspErr)
+	}
+	if su == nil {
+		msg := fmt.Sprintf("Failed to find user with ID %d in the database", id)
+		log.WithField("userID", id).Error(msg)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewUpdateEmailSubscriptionDefault(http.StatusNotFound).WithPayload(&rspErr)
+	}
+
+	dbSubscription, invalid := newDBEmailSubscription(su, params.Subscription)
+	if invalid != "" {
+		msg := fmt.Sprintf("Failed to update email subscription with ID %d: %s", params.SubscriptionID, invalid)
+		log.Warn(msg)
+		rspErr := models.APIError{Message: &msg}
+		return users.NewUpdateEmailSubscriptionDefault(http.StatusBadRequest).WithPayload(&rspErr)
+	}
+	dbSubscription.ID = params.SubscriptionID
+
+	err = dbmodel.UpdateEmailSubscription(r.DB, dbSubscription)
+	if err != nil {
+		code := http.StatusInternalServerError
+		msg := fmt.Sprintf("Failed to update email subscription with ID %d", params.SubscriptionID)
+		if errors.Is(err, dbmodel.ErrNotExists) {
+			code = http.StatusNotFound
+			msg = fmt.Sprintf("Cannot find email subscription with ID %d of user with ID %d", params.SubscriptionID, id)
+		}
+		log.WithField("userID", id).WithError(err).Error(msg)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewUpdateEmailSubscriptionDefault(code).WithPayload(&rspErr)
+	}
+
+	// Fetch the subscription to return its creation time.
+	updated, err := dbmodel.GetEmailSubscription(r.DB, id, params.SubscriptionID)
+	if err == nil && updated != nil {
+		dbSubscription = updated
+	}
+	return users.NewUpdateEmailSubscriptionOK().WithPayload(newRestEmailSubscription(*dbSubscription))
+}
+
+// Deletes the email subscription of the user.
+func (r *RestAPI) DeleteEmailSubscription(ctx context.Context, params users.DeleteEmailSubscriptionParams) middleware.Responder {
+	id := int(params.ID)
+	err := dbmodel.DeleteEmailSubscription(r.DB, id, params.SubscriptionID)
+	if err != nil {
+		code := http.StatusInternalServerError
+		msg := fmt.Sprintf("Failed to delete email subscription with ID %d", params.SubscriptionID)
+		if errors.Is(err, dbmodel.ErrNotExists) {
+			code = http.StatusNotFound
+			msg = fmt.Sprintf("Cannot find email subscription with ID %d of user with ID %d", params.SubscriptionID, id)
+		}
+		log.WithField("userID", id).WithError(err).Error(msg)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewDeleteEmailSubscriptionDefault(code).WithPayload(&rspErr)
+	}
+	return users.NewDeleteEmailSubscriptionOK()
+}
+
+// Sends a test email to the address of the user's email subscription.
+func (r *RestAPI) SendTestEmail(ctx context.Context, params users.SendTestEmailParams) middleware.Responder {
+	id := int(params.ID)
+	dbSubscription, err := dbmodel.GetEmailSubscription(r.DB, id, params.SubscriptionID)
+	if err != nil {
+		log.WithField("userID", id).WithError(err).Error("Failed to fetch email subscription from the database")
+
+		msg := fmt.Sprintf("Failed to fetch email subscription with ID %d from the database", params.SubscriptionID)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewSendTestEmailDefault(http.StatusInternalServerError).WithPayload(&rspErr)
+	}
+	if dbSubscription == nil {
+		msg := fmt.Sprintf("Cannot find email subscription with ID %d of user with ID %d", params.SubscriptionID, id)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewSendTestEmailDefault(http.StatusNotFound).WithPayload(&rspErr)
+	}
+	su, err := dbmodel.GetUserByID(r.DB, id)
+	if err != nil {
+		log.WithField("userID", id).WithError(err).Error("Failed to fetch user from the database")
+
+		msg := fmt.Sprintf("Failed to fetch user with ID %d from the database", id)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewSendTestEmailDefault(http.StatusInternalServerError).WithPayload(&rspErr)
+	}
+	// The user may have changed the address after subscribing.
+	if su == nil || !strings.EqualFold(dbSubscription.Email, su.Email) {
+		msg := fmt.Sprintf("Email address %s is not the address of user with ID %d", dbSubscription.Email, id)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewSendTestEmailDefault(http.StatusBadRequest).WithPayload(&rspErr)
+	}
+	if r.Notifier == nil {
+		msg := "Email notifications are not available"
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewSendTestEmailDefault(http.StatusServiceUnavailable).WithPayload(&rspErr)
+	}
+
+	err = r.Notifier.SendTestEmail(dbSubscription.Email)
+	if err != nil {
+		log.WithField("userID", id).WithError(err).Error("Failed to send test email")
+
+		msg := fmt.Sprintf("Failed to send test email to %s: %s", dbSubscription.Email, err)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewSendTestEmailDefault(http.StatusBadGateway).WithPayload(&rspErr)
+	}
+	return users.NewSendTestEmailOK()
+}
@@ -0,0 +1,244 @@
+package restservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/users"
+	"isc.org/stork/server/notifications"
+	storkutil "isc.org/stork/util"
+)
+
+// Notifier recording the test emails in the unit tests.
+type testEmailNotifier struct {
+	recipients []string
+	err        error
+}
+
+// Does nothing.
+func (n *testEmailNotifier) Notify(notification *notifications.Notification) {}
+
+// Records the recipient and returns the configured error.
+func (n *testEmailNotifier) SendTestEmail(to string) error {
+	n.recipients = append(n.recipients, to)
+	return n.err
+}
+
+// Does nothing.
+func (n *testEmailNotifier) Shutdown() {}
+
+// Test that the email subscription received over the REST API is
+// validated and converted to the database model.
+func TestNewDBEmailSubscription(t *testing.T) {
+	user := &dbmodel.SystemUser{
+		ID:    5,
+		Email: "jdoe@example.org",
+	}
+	subscription, invalid := newDBEmailSubscription(user, &models.EmailSubscription{
+		Email:     " jdoe@example.org ",
+		Enabled:   true,
+		Level:     storkutil.Ptr(int64(1)),
+		DaemonIds: []int64{1},
+	})
+	require.Empty(t, invalid)
+	require.NotNil(t, subscription)
+	require.Equal(t, 5, subscription.UserID)
+	require.Equal(t, "jdoe@example.org", subscription.Email)
+	require.True(t, subscription.Enabled)
+	require.Equal(t, dbmodel.EvWarning, subscription.Level)
+	require.Equal(t, []int64{1}, subscription.DaemonIDs)
+
+	// The user's address and the error level are used by default.
+	subscription, invalid = newDBEmailSubscription(user, &models.EmailSubscription{})
+	require.Empty(t, invalid)
+	require.Equal(t, "jdoe@example.org", subscription.Email)
+	require.Equal(t, dbmodel.EvError, subscription.Level)
+
+	// The user has no address.
+	subscription, invalid = newDBEmailSubscription(&dbmodel.SystemUser{ID: 6}, &models.EmailSubscription{})
+	require.NotEmpty(t, invalid)
+	require.Nil(t, subscription)
+
+	for _, s := range []*models.EmailSubscription{
+		nil,
+		{Email: "oncall"},
+		{Email: "John <oncall@example.org>"},
+		{Email: "jdoe@example.org", Level: storkutil.Ptr(int64(-1))},
+		// Not the user's address.
+		{Email: "oncall@example.org"},
+	} {
+		subscription, invalid = newDBEmailSubscription(user, s)
+		require.NotEmpty(t, invalid)
+		require.Nil(t, subscription)
+	}
+}
+
+// Test that the email subscriptions can be created, listed, updated and
+// deleted.
+func TestCreateGetUpdateDeleteEmailSubscription(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	user := &dbmodel.SystemUser{
+		Login:    "jdoe",
+		Email:    "jdoe@example.org",
+		Name:     "John",
+		Lastname: "Doe",
+	}
+	_, err := dbmodel.CreateUser(db, user)
+	require.NoError(t, err)
+	id := int64(user.ID)
+
+	ctx := context.Background()
+	rapi, err := NewRestAPI(dbSettings, db)
+	require.NoError(t, err)
+
+	rsp := rapi.CreateEmailSubscription(ctx, users.CreateEmailSubscriptionParams{
+		ID: id,
+		Subscription: &models.EmailSubscription{
+			Email:     "jdoe@example.org",
+			Enabled:   true,
+			DaemonIds: []int64{1, 2},
+		},
+	})
+	require.IsType(t, &users.CreateEmailSubscriptionOK{}, rsp)
+	created := rsp.(*users.CreateEmailSubscriptionOK).Payload
+	require.NotZero(t, created.ID)
+	require.Equal(t, "jdoe@example.org", created.Email)
+	require.EqualValues(t, 2, *created.Level)
+
+	// The subscription must be valid.
+	rsp = rapi.CreateEmailSubscription(ctx, users.CreateEmailSubscriptionParams{
+		ID: id,
+		Subscription: &models.EmailSubscription{
+			Email: "oncall",
+		},
+	})
+	require.IsType(t, &users.CreateEmailSubscriptionDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*users.CreateEmailSubscriptionDefault)))
+
+	// The address must belong to the user.
+	rsp = rapi.CreateEmailSubscription(ctx, users.CreateEmailSubscriptionParams{
+		ID: id,
+		Subscription: &models.EmailSubscription{
+			Email: "oncall@example.org",
+		},
+	})
+	require.IsType(t, &users.CreateEmailSubscriptionDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*users.CreateEmailSubscriptionDefault)))
+
+	// The user must exist.
+	rsp = rapi.CreateEmailSubscription(ctx, users.CreateEmailSubscriptionParams{
+		ID:           1000,
+		Subscription: &models.EmailSubscription{},
+	})
+	require.IsType(t, &users.CreateEmailSubscriptionDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*users.CreateEmailSubscriptionDefault)))
+
+	rsp = rapi.UpdateEmailSubscription(ctx, users.UpdateEmailSubscriptionParams{
+		ID:             id,
+		SubscriptionID: created.ID,
+		Subscription: &models.EmailSubscription{
+			Level: storkutil.Ptr(int64(0)),
+		},
+	})
+	require.IsType(t, &users.UpdateEmailSubscriptionOK{}, rsp)
+	updated := rsp.(*users.UpdateEmailSubscriptionOK).Payload
+	require.Equal(t, "jdoe@example.org", updated.Email)
+	require.False(t, updated.Enabled)
+	require.EqualValues(t, 0, *updated.Level)
+	require.Empty(t, updated.DaemonIds)
+
+	// The address cannot be changed to the address of someone else.
+	rsp = rapi.UpdateEmailSubscription(ctx, users.UpdateEmailSubscriptionParams{
+		ID:             id,
+		SubscriptionID: created.ID,
+		Subscription:   &models.EmailSubscription{Email: "noc@example.org"},
+	})
+	require.IsType(t, &users.UpdateEmailSubscriptionDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*users.UpdateEmailSubscriptionDefault)))
+
+	rsp = rapi.GetEmailSubscriptions(ctx, users.GetEmailSubscriptionsParams{ID: id})
+	require.IsType(t, &users.GetEmailSubscriptionsOK{}, rsp)
+	subscriptions := rsp.(*users.GetEmailSubscriptionsOK).Payload
+	require.EqualValues(t, 1, subscriptions.Total)
+	require.Len(t, subscriptions.Items, 1)
+	require.Equal(t, "jdoe@example.org", subscriptions.Items[0].Email)
+
+	// Update non-existing subscription.
+	rsp = rapi.UpdateEmailSubscription(ctx, users.UpdateEmailSubscriptionParams{
+		ID:             id,
+		SubscriptionID: created.ID + 1,
+		Subscription:   &models.EmailSubscription{Email: "jdoe@example.org"},
+	})
+	require.IsType(t, &users.UpdateEmailSubscriptionDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*users.UpdateEmailSubscriptionDefault)))
+
+	rsp = rapi.DeleteEmailSubscription(ctx, users.DeleteEmailSubscriptionParams{ID: id, SubscriptionID: created.ID})
+	require.IsType(t, &users.DeleteEmailSubscriptionOK{}, rsp)
+
+	rsp = rapi.DeleteEmailSubscription(ctx, users.DeleteEmailSubscriptionParams{ID: id, SubscriptionID: created.ID})
+	require.IsType(t, &users.DeleteEmailSubscriptionDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*users.DeleteEmailSubscriptionDefault)))
+}
+
+// Test sending a test email to the subscription's address.
+func TestSendTestEmail(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	user := &dbmodel.SystemUser{
+		Login:    "jdoe",
+		Email:    "jdoe@example.org",
+		Name:     "John",
+		Lastname: "Doe",
+	}
+	_, err := dbmodel.CreateUser(db, user)
+	require.NoError(t, err)
+	id := int64(user.ID)
+
+	subscription := &dbmodel.EmailSubscription{
+		UserID: user.ID,
+		Email:  "jdoe@example.org",
+	}
+	err = dbmodel.AddEmailSubscription(db, subscription)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	notifier := &testEmailNotifier{}
+	rapi, err := NewRestAPI(dbSettings, db, notifier)
+	require.NoError(t, err)
+
+	rsp := rapi.SendTestEmail(ctx, users.SendTestEmailParams{ID: id, SubscriptionID: subscription.ID})
+	require.IsType(t, &users.SendTestEmailOK{}, rsp)
+	require.Equal(t, []string{"jdoe@example.org"}, notifier.recipients)
+
+	// The subscription does not exist.
+	rsp = rapi.SendTestEmail(ctx, users.SendTestEmailParams{ID: id, SubscriptionID: subscription.ID + 1})
+	require.IsType(t, &users.SendTestEmailDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*users.SendTestEmailDefault)))
+
+	// Sending the email failed.
+	notifier.err = pkgerrors.New("connection refused")
+	rsp = rapi.SendTestEmail(ctx, users.SendTestEmailParams{ID: id, SubscriptionID: subscription.ID})
+	require.IsType(t, &users.SendTestEmailDefault{}, rsp)
+	require.Equal(t, http.StatusBadGateway, getStatusCode(*rsp.(*users.SendTestEmailDefault)))
+	require.Contains(t, *rsp.(*users.SendTestEmailDefault).Payload.Message, "connection refused")
+
+	// The subscription address is no longer the user's address.
+	notifier.err = nil
+	notifier.recipients = nil
+	subscription.Email = "oncall@example.org"
+	err = dbmodel.UpdateEmailSubscription(db, subscription)
+	require.NoError(t, err)
+	rsp = rapi.SendTestEmail(ctx, users.SendTestEmailParams{ID: id, SubscriptionID: subscription.ID})
+	require.IsType(t, &users.SendTestEmailDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*users.SendTestEmailDefault)))
+	require.Empty(t, notifier.recipients)
+}
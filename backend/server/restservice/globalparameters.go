@@ -0,0 +1,416 @@
+package restservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	keaconfig "isc.org/stork/appcfg/kea"
+	"isc.org/stork/server/apps/kea"
+	"isc.org/stork/server/config"
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
+	storkutil "isc.org/stork/util"
+)
+
+// Converts the global parameters of the Kea DHCP server to the REST API
+// format. It includes the global DHCP options.
+func (r *RestAPI) convertGlobalParametersToRestAPI(cfg *dbmodel.KeaConfig) *models.KeaConfigGlobalParameters {
+	params := cfg.GetGlobalParameters()
+	if params == nil {
+		return nil
+	}
+	restParams := &models.KeaConfigGlobalParameters{
+		KeaConfigDdnsParameters: models.KeaConfigDdnsParameters{
+			DdnsGeneratedPrefix:       storkutil.NullifyEmptyString(params.DDNSGeneratedPrefix),
+			DdnsOverrideClientUpdate:  params.DDNSOverrideClientUpdate,
+			DdnsOverrideNoUpdate:      params.DDNSOverrideNoUpdate,
+			DdnsQualifyingSuffix:      storkutil.NullifyEmptyString(params.DDNSQualifyingSuffix),
+			DdnsReplaceClientName:     storkutil.NullifyEmptyString(params.DDNSReplaceClientName),
+			DdnsSendUpdates:           params.DDNSSendUpdates,
+			DdnsUpdateOnRenew:         params.DDNSUpdateOnRenew,
+			DdnsUseConflictResolution: params.DDNSUseConflictResolution,
+		},
+		KeaConfigPreferredLifetimeParameters: models.KeaConfigPreferredLifetimeParameters{
+			MaxPreferredLifetime: params.MaxPreferredLifetime,
+			MinPreferredLifetime: params.MinPreferredLifetime,
+			PreferredLifetime:    params.PreferredLifetime,
+		},
+		KeaConfigTimerParameters: models.KeaConfigTimerParameters{
+			CalculateTeeTimes: params.CalculateTeeTimes,
+			RebindTimer:       params.RebindTimer,
+			RenewTimer:        params.RenewTimer,
+			T1Percent:         params.T1Percent,
+			T2Percent:         params.T2Percent,
+		},
+		KeaConfigValidLifetimeParameters: models.KeaConfigValidLifetimeParameters{
+			MaxValidLifetime: params.MaxValidLifetime,
+			MinValidLifetime: params.MinValidLifetime,
+			ValidLifetime:    params.ValidLifetime,
+		},
+	}
+	if elp := params.ExpiredLeasesProcessing; elp != nil {
+		restParams.ExpiredLeasesProcessing = &models.KeaConfigExpiredLeasesProcessingParameters{
+			FlushReclaimedTimerWaitTime: elp.FlushReclaimedTimerWaitTime,
+			HoldReclaimedTime:           elp.HoldReclaimedTime,
+			MaxReclaimLeases:            elp.MaxReclaimLeases,
+			MaxReclaimTime:              elp.MaxReclaimTime,
+			ReclaimTimerWaitTime:        elp.ReclaimTimerWaitTime,
+			UnwarnedReclaimCycles:       elp.UnwarnedReclaimCycles,
+		}
+	}
+	universe := storkutil.IPv4
+	if cfg.IsDHCPv6() {
+		universe = storkutil.IPv6
+	}
+	var options []dbmodel.DHCPOption
+	for _, optionData := range params.OptionData {
+		option, err := dbmodel.NewDHCPOptionFromKea(optionData, universe, r.DHCPOptionDefinitionLookup)
+		if err != nil {
+			log.WithError(err).Warn("Problem with converting a global DHCP option")
+			continue
+		}
+		options = append(options, *option)
+	}
+	restParams.Options = r.unflattenDHCPOptions(options, "", 0)
+	return restParams
+}
+
+// Converts the global parameters from the REST API format. It returns the
+// parameters and the global DHCP options. The options are nil if they are
+// not specified, i.e., they should remain unchanged.
+func (r *RestAPI) convertGlobalParametersFromRestAPI(restParams *models.KeaConfigGlobalParameters) (*keaconfig.GlobalParameters, []dbmodel.DHCPOption, error) {
+	params := &keaconfig.GlobalParameters{
+		DDNSParameters: keaconfig.DDNSParameters{
+			DDNSGeneratedPrefix:       restParams.DdnsGeneratedPrefix,
+			DDNSOverrideClientUpdate:  restParams.DdnsOverrideClientUpdate,
+			DDNSOverrideNoUpdate:      restParams.DdnsOverrideNoUpdate,
+			DDNSQualifyingSuffix:      restParams.DdnsQualifyingSuffix,
+			DDNSReplaceClientName:     restParams.DdnsReplaceClientName,
+			DDNSSendUpdates:           restParams.DdnsSendUpdates,
+			DDNSUpdateOnRenew:         restParams.DdnsUpdateOnRenew,
+			DDNSUseConflictResolution: restParams.DdnsUseConflictResolution,
+		},
+		PreferredLifetimeParameters: keaconfig.PreferredLifetimeParameters{
+			MaxPreferredLifetime: restParams.MaxPreferredLifetime,
+			MinPreferredLifetime: restParams.MinPreferredLifetime,
+			PreferredLifetime:    restParams.PreferredLifetime,
+		},
+		TimerParameters: keaconfig.TimerParameters{
+			CalculateTeeTimes: restParams.CalculateTeeTimes,
+			RebindTimer:       restParams.RebindTimer,
+			RenewTimer:        restParams.RenewTimer,
+			T1Percent:         restParams.T1Percent,
+			T2Percent:         restParams.T2Percent,
+		},
+		ValidLifetimeParameters: keaconfig.ValidLifetimeParameters{
+			MaxValidLifetime: restParams.MaxValidLifetime,
+			MinValidLifetime: restParams.MinValidLifetime,
+			ValidLifetime:    restParams.ValidLifetime,
+		},
+	}
+	if elp := restParams.ExpiredLeasesProcessing; elp != nil {
+		params.ExpiredLeasesProcessing = &keaconfig.ExpiredLeasesProcessing{
+			FlushReclaimedTimerWaitTime: elp.FlushReclaimedTimerWaitTime,
+			HoldReclaimedTime:           elp.HoldReclaimedTime,
+			MaxReclaimLeases:            elp.MaxReclaimLeases,
+			MaxReclaimTime:              elp.MaxReclaimTime,
+			ReclaimTimerWaitTime:        elp.ReclaimTimerWaitTime,
+			UnwarnedReclaimCycles:       elp.UnwarnedReclaimCycles,
+		}
+	}
+	if restParams.Options == nil {
+		return params, nil, nil
+	}
+	options, err := r.flattenDHCPOptions("", restParams.Options, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if options == nil {
+		options = []dbmodel.DHCPOption{}
+	}
+	return params, options, nil
+}
+
+// Converts the global parameters updates stored in the transaction to
+// the REST API format.
+func convertGlobalParametersChangesToRestAPI(updates []kea.DaemonGlobalParametersUpdate) *models.UpdateKeaGlobalParametersChangesResponse {
+	response := &models.UpdateKeaGlobalParametersChangesResponse{
+		Daemons: []*models.KeaDaemonGlobalParametersChanges{},
+	}
+	for _, update := range updates {
+		daemonChanges := &models.KeaDaemonGlobalParametersChanges{
+			DaemonID:   update.DaemonID,
+			DaemonName: update.DaemonName,
+			AppID:      update.AppID,
+			AppName:    update.AppName,
+			Changes:    []*models.KeaGlobalParameterChange{},
+		}
+		for _, change := range update.Changes {
+			daemonChanges.Changes = append(daemonChanges.Changes, &models.KeaGlobalParameterChange{
+				Name:   change.Name,
+				Before: change.Before,
+				After:  change.After,
+			})
+		}
+		response.Daemons = append(response.Daemons, daemonChanges)
+	}
+	return response
+}
+
+// Common function executed when the global parameters are previewed or
+// submitted. It recovers the transaction context, converts the parameters
+// and applies them. It returns the context with the applied parameters or
+// the HTTP error code and message if an error occurs.
+func (r *RestAPI) commonApplyGlobalParameters(ctx context.Context, transactionID int64, restParams *models.KeaConfigGlobalParameters) (context.Context, int, string) {
+	// Make sure that the global parameters are present.
+	if restParams == nil {
+		msg := "Global parameters not specified"
+		log.Errorf("Problem with applying global parameters because they are missing")
+		return nil, http.StatusBadRequest, msg
+	}
+	// Retrieve the context from the config manager.
+	_, user := r.SessionManager.Logged(ctx)
+	cctx, _ := r.ConfigManager.RecoverContext(transactionID, int64(user.ID))
+	if cctx == nil {
+		msg := "Transaction expired for the global parameters update"
+		log.Errorf("Problem with recovering transaction context for transaction ID %d and user ID %d", transactionID, user.ID)
+		return nil, http.StatusNotFound, msg
+	}
+	params, options, err := r.convertGlobalParametersFromRestAPI(restParams)
+	if err != nil {
+		msg := "Error parsing specified global parameters"
+		log.WithError(err).Error(msg)
+		return nil, http.StatusBadRequest, msg
+	}
+	cctx, err = r.ConfigManager.GetKeaModule().ApplyGlobalParametersUpdate(cctx, params, options)
+	if err != nil {
+		msg := fmt.Sprintf("Problem with applying global parameters: %s", err)
+		log.WithError(err).Error(msg)
+		return nil, http.StatusBadRequest, msg
+	}
+	return cctx, 0, ""
+}
+
+// Returns the global parameters updates from the transaction context.
+func getGlobalParametersUpdates(cctx context.Context) ([]kea.DaemonGlobalParametersUpdate, error) {
+	recipe, err := config.GetRecipeForUpdate[kea.ConfigRecipe](cctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	return recipe.GlobalParametersUpdates, nil
+}
+
+// Implements the POST call to create new transaction for updating the
+// global parameters of the Kea DHCP servers (kea-global-parameters/transaction).
+// If no daemons are specified, all Kea DHCP servers are updated.
+func (r *RestAPI) UpdateKeaGlobalParametersBegin(ctx context.Context, params dhcp.UpdateKeaGlobalParametersBeginParams) middleware.Responder {
+	var daemonIDs []int64
+	if params.Request != nil {
+		daemonIDs = params.Request.DaemonIds
+	}
+	if len(daemonIDs) == 0 {
+		daemons, err := dbmodel.GetKeaDHCPDaemons(r.DB)
+		if err != nil {
+			msg := "Problem with fetching Kea daemons from the database"
+			log.WithError(err).Error(msg)
+			rsp := dhcp.NewUpdateKeaGlobalParametersBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		}
+		for _, daemon := range daemons {
+			if daemon.KeaDaemon != nil && daemon.KeaDaemon.Config != nil {
+				daemonIDs = append(daemonIDs, daemon.ID)
+			}
+		}
+		if len(daemonIDs) == 0 {
+			msg := "Unable to begin transaction because there are no Kea DHCP servers"
+			log.Error(msg)
+			rsp := dhcp.NewUpdateKeaGlobalParametersBeginDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		}
+	}
+	// Create configuration context.
+	_, user := r.SessionManager.Logged(ctx)
+	cctx, err := r.ConfigManager.CreateContext(int64(user.ID))
+	if err != nil {
+		msg := "Problem with creating transaction context"
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewUpdateKeaGlobalParametersBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Begin global parameters update transaction. It retrieves the daemons
+	// and locks them for updates.
+	cctx, err = r.ConfigManager.GetKeaModule().BeginGlobalParametersUpdate(cctx, daemonIDs)
+	if err != nil {
+		var (
+			daemonNotFound *config.DaemonNotFoundError
+			lock           *config.LockError
+		)
+		switch {
+		case errors.As(err, &daemonNotFound):
+			// Failed to find a daemon.
+			msg := fmt.Sprintf("Unable to edit the global parameters: %s", err)
+			log.Error(msg)
+			rsp := dhcp.NewUpdateKeaGlobalParametersBeginDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		case errors.As(err, &lock):
+			// Failed to lock daemons.
+			msg := "Unable to edit the global parameters because some of the servers may be currently edited by another user"
+			log.WithError(err).Error(msg)
+			rsp := dhcp.NewUpdateKeaGlobalParametersBeginDefault(http.StatusLocked).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		default:
+			// Other error.
+			msg := "Problem with initializing transaction for an update of the global parameters"
+			log.WithError(err).Error(msg)
+			rsp := dhcp.NewUpdateKeaGlobalParametersBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		}
+	}
+	recipe, _ := config.GetRecipeForUpdate[kea.ConfigRecipe](cctx, 0)
+
+	// Make sure the user is permitted to modify all selected daemons.
+	var targets []dbmodel.PermissionTarget
+	for _, daemon := range recipe.GlobalParametersDaemons {
+		targets = append(targets, newPermissionTarget(daemon.ID, daemon, 0))
+	}
+	if !r.authorizeTargets(ctx, dbmodel.PermissionManageSubnets, targets...) {
+		r.ConfigManager.Done(cctx)
+		msg := "User is forbidden to modify the global parameters on the selected servers"
+		rsp := dhcp.NewUpdateKeaGlobalParametersBeginDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	// Retrieve the generated context ID.
+	cctxID, ok := config.GetValueAsInt64(cctx, config.ContextIDKey)
+	if !ok {
+		msg := "problem with retrieving context ID for a transaction to update the global parameters"
+		log.Error(msg)
+		rsp := dhcp.NewUpdateKeaGlobalParametersBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Remember the context, i.e. new transaction has been successfully created.
+	_ = r.ConfigManager.RememberContext(cctx, time.Minute*10)
+
+	// Return transaction ID and the current global parameters to the user.
+	contents := &models.UpdateKeaGlobalParametersBeginResponse{
+		ID:      cctxID,
+		Daemons: []*models.KeaDaemonGlobalParameters{},
+	}
+	for _, daemon := range recipe.GlobalParametersDaemons {
+		contents.Daemons = append(contents.Daemons, &models.KeaDaemonGlobalParameters{
+			DaemonID:   daemon.ID,
+			DaemonName: daemon.Name,
+			AppID:      daemon.AppID,
+			AppName:    daemon.App.Name,
+			Parameters: r.convertGlobalParametersToRestAPI(daemon.KeaDaemon.Config),
+		})
+	}
+	rsp := dhcp.NewUpdateKeaGlobalParametersBeginOK().WithPayload(contents)
+	return rsp
+}
+
+// Implements the POST call returning the changes of the global parameters
+// in each daemon without committing them
+// (kea-global-parameters/transaction/{id}/preview). The transaction
+// remains open.
+func (r *RestAPI) UpdateKeaGlobalParametersPreview(ctx context.Context, params dhcp.UpdateKeaGlobalParametersPreviewParams) middleware.Responder {
+	cctx, code, msg := r.commonApplyGlobalParameters(ctx, params.ID, params.Parameters)
+	if code != 0 {
+		// Error case.
+		rsp := dhcp.NewUpdateKeaGlobalParametersPreviewDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	updates, err := getGlobalParametersUpdates(cctx)
+	if err != nil {
+		msg := "Problem recovering global parameters changes from the context"
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewUpdateKeaGlobalParametersPreviewDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewUpdateKeaGlobalParametersPreviewOK().WithPayload(convertGlobalParametersChangesToRestAPI(updates))
+	return rsp
+}
+
+// Implements the POST call and commits the updated global parameters
+// (kea-global-parameters/transaction/{id}/submit). It returns the changes
+// of the global parameters in each daemon.
+func (r *RestAPI) UpdateKeaGlobalParametersSubmit(ctx context.Context, params dhcp.UpdateKeaGlobalParametersSubmitParams) middleware.Responder {
+	cctx, code, msg := r.commonApplyGlobalParameters(ctx, params.ID, params.Parameters)
+	if code != 0 {
+		// Error case.
+		rsp := dhcp.NewUpdateKeaGlobalParametersSubmitDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Send the commands to Kea servers.
+	cctx, err := r.ConfigManager.Commit(cctx)
+	if err != nil {
+		msg := fmt.Sprintf("Problem with committing global parameters: %s", err)
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewUpdateKeaGlobalParametersSubmitDefault(http.StatusConflict).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	updates, err := getGlobalParametersUpdates(cctx)
+	if err != nil {
+		msg := "Problem recovering global parameters changes from the context"
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewUpdateKeaGlobalParametersSubmitDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Everything ok. Cleanup and send OK to the client.
+	r.ConfigManager.Done(cctx)
+	rsp := dhcp.NewUpdateKeaGlobalParametersSubmitOK().WithPayload(convertGlobalParametersChangesToRestAPI(updates))
+	return rsp
+}
+
+// Implements the DELETE call to cancel updating the global parameters
+// (kea-global-parameters/transaction/{id}). It removes the specified
+// transaction from the config manager, if the transaction exists.
+func (r *RestAPI) UpdateKeaGlobalParametersDelete(ctx context.Context, params dhcp.UpdateKeaGlobalParametersDeleteParams) middleware.Responder {
+	// Retrieve the context from the config manager.
+	_, user := r.SessionManager.Logged(ctx)
+	cctx, _ := r.ConfigManager.RecoverContext(params.ID, int64(user.ID))
+	if cctx == nil {
+		msg := "Transaction expired for the global parameters update"
+		log.Errorf("Problem with recovering transaction context for transaction ID %d and user ID %d", params.ID, user.ID)
+		rsp := dhcp.NewUpdateKeaGlobalParametersDeleteDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	r.ConfigManager.Done(cctx)
+	rsp := dhcp.NewUpdateKeaGlobalParametersDeleteOK()
+	return rsp
+}
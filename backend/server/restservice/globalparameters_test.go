@@ -0,0 +1,281 @@
+package restservice
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/stretchr/testify/require"
+	keaconfig "isc.org/stork/appcfg/kea"
+	keactrl "isc.org/stork/appctrl/kea"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	"isc.org/stork/server/apps/kea"
+	dbmodel "isc.org/stork/server/database/model"
+	dbmodeltest "isc.org/stork/server/database/model/test"
+	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/models"
+	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
+	storktest "isc.org/stork/server/test/dbmodel"
+	storkutil "isc.org/stork/util"
+)
+
+// Configuration of the test Kea DHCPv4 server with the global parameters.
+const testGlobalParametersServerConfig = `{
+	"Dhcp4": {
+		"valid-lifetime": 3600,
+		"renew-timer": 900,
+		"expired-leases-processing": {
+			"max-reclaim-time": 250
+		},
+		"option-data": [
+			{
+				"name": "domain-name-servers",
+				"code": 6,
+				"space": "dhcp4",
+				"data": "192.0.2.1"
+			}
+		]
+	}
+}`
+
+// Adds a Kea DHCPv4 server with the global parameters to the database.
+// The configuration hash matches the configuration returned by the
+// mockGlobalParametersConfigGet function.
+func addTestGlobalParametersServer(t *testing.T, db *pg.DB) *dbmodel.App {
+	server, err := dbmodeltest.NewKeaDHCPv4Server(db)
+	require.NoError(t, err)
+	require.NoError(t, server.Configure(testGlobalParametersServerConfig))
+	app, err := server.GetKea()
+	require.NoError(t, err)
+	app.Daemons[0].KeaDaemon.ConfigHash = keaconfig.NewHasher().Hash([]byte(testGlobalParametersServerConfig))
+	err = kea.CommitAppIntoDB(db, app, &storktest.FakeEventCenter{}, nil, dbmodel.NewDHCPOptionDefinitionLookup())
+	require.NoError(t, err)
+	return app
+}
+
+// Generates the response to the config-get command returning the
+// configuration of the test server. The same response is returned
+// to the config-set and config-write commands.
+func mockGlobalParametersConfigGet(callNo int, cmdResponses []interface{}) {
+	json := []byte(fmt.Sprintf(`[{"result": 0, "arguments": %s}]`, testGlobalParametersServerConfig))
+	command := keactrl.NewCommandBase(keactrl.ConfigGet, keactrl.DHCPv4)
+	_ = keactrl.UnmarshalResponseList(command, json, cmdResponses[0])
+}
+
+// Test converting the global parameters between the configuration and
+// the REST API formats.
+func TestConvertGlobalParametersToAndFromRestAPI(t *testing.T) {
+	rapi := &RestAPI{
+		DHCPOptionDefinitionLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	}
+	cfg, err := dbmodel.NewKeaConfigFromJSON(`{
+		"Dhcp6": {
+			"valid-lifetime": 4000,
+			"preferred-lifetime": 3000,
+			"ddns-qualifying-suffix": "example.org",
+			"expired-leases-processing": {
+				"hold-reclaimed-time": 1800
+			},
+			"option-data": [
+				{
+					"name": "dns-servers",
+					"code": 23,
+					"space": "dhcp6",
+					"data": "2001:db8:1::1"
+				}
+			]
+		}
+	}`)
+	require.NoError(t, err)
+
+	restParams := rapi.convertGlobalParametersToRestAPI(cfg)
+	require.NotNil(t, restParams)
+	require.EqualValues(t, 4000, *restParams.ValidLifetime)
+	require.EqualValues(t, 3000, *restParams.PreferredLifetime)
+	require.Nil(t, restParams.RenewTimer)
+	require.Equal(t, "example.org", *restParams.DdnsQualifyingSuffix)
+	require.Nil(t, restParams.DdnsGeneratedPrefix)
+	require.NotNil(t, restParams.ExpiredLeasesProcessing)
+	require.EqualValues(t, 1800, *restParams.ExpiredLeasesProcessing.HoldReclaimedTime)
+	require.Len(t, restParams.Options, 1)
+	require.EqualValues(t, 23, restParams.Options[0].Code)
+
+	params, options, err := rapi.convertGlobalParametersFromRestAPI(restParams)
+	require.NoError(t, err)
+	require.NotNil(t, params)
+	require.EqualValues(t, 4000, *params.ValidLifetime)
+	require.EqualValues(t, 3000, *params.PreferredLifetime)
+	require.Equal(t, "example.org", *params.DDNSQualifyingSuffix)
+	require.EqualValues(t, 1800, *params.ExpiredLeasesProcessing.HoldReclaimedTime)
+	require.Len(t, options, 1)
+	require.EqualValues(t, 23, options[0].Code)
+
+	// Unspecified options should remain unchanged.
+	restParams.Options = nil
+	_, options, err = rapi.convertGlobalParametersFromRestAPI(restParams)
+	require.NoError(t, err)
+	require.Nil(t, options)
+
+	// Empty options should remove all options.
+	restParams.Options = []*models.DHCPOption{}
+	_, options, err = rapi.convertGlobalParametersFromRestAPI(restParams)
+	require.NoError(t, err)
+	require.NotNil(t, options)
+	require.Empty(t, options)
+}
+
+// Test converting the global parameters changes to the REST API format.
+func TestConvertGlobalParametersChangesToRestAPI(t *testing.T) {
+	updates := []kea.DaemonGlobalParametersUpdate{
+		{
+			DaemonID:   1,
+			DaemonName: "dhcp4",
+			AppID:      2,
+			AppName:    "kea@192.0.2.1",
+			Changes: []keaconfig.GlobalParameterChange{
+				{
+					Name:   "valid-lifetime",
+					Before: float64(3600),
+					After:  float64(7200),
+				},
+			},
+		},
+	}
+	response := convertGlobalParametersChangesToRestAPI(updates)
+	require.NotNil(t, response)
+	require.Len(t, response.Daemons, 1)
+	require.EqualValues(t, 1, response.Daemons[0].DaemonID)
+	require.Equal(t, "dhcp4", response.Daemons[0].DaemonName)
+	require.EqualValues(t, 2, response.Daemons[0].AppID)
+	require.Equal(t, "kea@192.0.2.1", response.Daemons[0].AppName)
+	require.Len(t, response.Daemons[0].Changes, 1)
+	require.Equal(t, "valid-lifetime", response.Daemons[0].Changes[0].Name)
+	require.EqualValues(t, 3600, response.Daemons[0].Changes[0].Before)
+	require.EqualValues(t, 7200, response.Daemons[0].Changes[0].After)
+}
+
+// Test the transaction for updating the global parameters, including
+// the preview of the changes.
+func TestUpdateKeaGlobalParametersBeginPreviewSubmit(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestGlobalParametersServer(t, db)
+
+	fa := agentcommtest.NewFakeAgents(mockGlobalParametersConfigGet, nil)
+	rapi, ctx := newTestClientClassesRestAPI(t, db, dbSettings, fa)
+
+	// Begin the transaction for all servers.
+	rsp := rapi.UpdateKeaGlobalParametersBegin(ctx, dhcp.UpdateKeaGlobalParametersBeginParams{})
+	require.IsType(t, &dhcp.UpdateKeaGlobalParametersBeginOK{}, rsp)
+	contents := rsp.(*dhcp.UpdateKeaGlobalParametersBeginOK).Payload
+	require.NotZero(t, contents.ID)
+	require.Len(t, contents.Daemons, 1)
+	require.Equal(t, app.Daemons[0].ID, contents.Daemons[0].DaemonID)
+	restParams := contents.Daemons[0].Parameters
+	require.NotNil(t, restParams)
+	require.EqualValues(t, 3600, *restParams.ValidLifetime)
+	require.Len(t, restParams.Options, 1)
+
+	// The daemon is locked for updates by other users.
+	rsp2 := rapi.UpdateKeaGlobalParametersBegin(ctx, dhcp.UpdateKeaGlobalParametersBeginParams{})
+	require.IsType(t, &dhcp.UpdateKeaGlobalParametersBeginDefault{}, rsp2)
+	require.Equal(t, http.StatusLocked, getStatusCode(*rsp2.(*dhcp.UpdateKeaGlobalParametersBeginDefault)))
+
+	// Leave the options unchanged.
+	restParams.ValidLifetime = storkutil.Ptr(int64(7200))
+	restParams.Options = nil
+
+	// Preview the changes.
+	rsp = rapi.UpdateKeaGlobalParametersPreview(ctx, dhcp.UpdateKeaGlobalParametersPreviewParams{
+		ID:         contents.ID,
+		Parameters: restParams,
+	})
+	require.IsType(t, &dhcp.UpdateKeaGlobalParametersPreviewOK{}, rsp)
+	preview := rsp.(*dhcp.UpdateKeaGlobalParametersPreviewOK).Payload
+	require.Len(t, preview.Daemons, 1)
+	require.Len(t, preview.Daemons[0].Changes, 1)
+	require.Equal(t, "valid-lifetime", preview.Daemons[0].Changes[0].Name)
+	require.Empty(t, fa.RecordedCommands)
+
+	// Submit the changes.
+	rsp = rapi.UpdateKeaGlobalParametersSubmit(ctx, dhcp.UpdateKeaGlobalParametersSubmitParams{
+		ID:         contents.ID,
+		Parameters: restParams,
+	})
+	require.IsType(t, &dhcp.UpdateKeaGlobalParametersSubmitOK{}, rsp)
+	submitted := rsp.(*dhcp.UpdateKeaGlobalParametersSubmitOK).Payload
+	require.Len(t, submitted.Daemons, 1)
+	require.Len(t, submitted.Daemons[0].Changes, 1)
+
+	require.Len(t, fa.RecordedCommands, 3)
+	require.EqualValues(t, "config-get", fa.RecordedCommands[0].GetCommand())
+	require.EqualValues(t, "config-set", fa.RecordedCommands[1].GetCommand())
+	require.EqualValues(t, "config-write", fa.RecordedCommands[2].GetCommand())
+
+	daemon, err := dbmodel.GetDaemonByID(db, app.Daemons[0].ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 7200, *daemon.KeaDaemon.Config.GetValidLifetimeParameters().ValidLifetime)
+	require.EqualValues(t, 250, *daemon.KeaDaemon.Config.GetExpiredLeasesProcessing().MaxReclaimTime)
+	require.Len(t, daemon.KeaDaemon.Config.GetDHCPOptions(), 1)
+}
+
+// Test cancelling the transaction for updating the global parameters.
+func TestUpdateKeaGlobalParametersBeginCancel(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestGlobalParametersServer(t, db)
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, ctx := newTestClientClassesRestAPI(t, db, dbSettings, fa)
+
+	rsp := rapi.UpdateKeaGlobalParametersBegin(ctx, dhcp.UpdateKeaGlobalParametersBeginParams{
+		Request: &models.UpdateKeaGlobalParametersBeginRequest{
+			DaemonIds: []int64{app.Daemons[0].ID},
+		},
+	})
+	require.IsType(t, &dhcp.UpdateKeaGlobalParametersBeginOK{}, rsp)
+	transactionID := rsp.(*dhcp.UpdateKeaGlobalParametersBeginOK).Payload.ID
+
+	rsp = rapi.UpdateKeaGlobalParametersDelete(ctx, dhcp.UpdateKeaGlobalParametersDeleteParams{
+		ID: transactionID,
+	})
+	require.IsType(t, &dhcp.UpdateKeaGlobalParametersDeleteOK{}, rsp)
+
+	// The transaction no longer exists.
+	rsp = rapi.UpdateKeaGlobalParametersSubmit(ctx, dhcp.UpdateKeaGlobalParametersSubmitParams{
+		ID:         transactionID,
+		Parameters: &models.KeaConfigGlobalParameters{},
+	})
+	require.IsType(t, &dhcp.UpdateKeaGlobalParametersSubmitDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*dhcp.UpdateKeaGlobalParametersSubmitDefault)))
+	require.Empty(t, fa.RecordedCommands)
+
+	// The daemon should be unlocked.
+	rsp = rapi.UpdateKeaGlobalParametersBegin(ctx, dhcp.UpdateKeaGlobalParametersBeginParams{})
+	require.IsType(t, &dhcp.UpdateKeaGlobalParametersBeginOK{}, rsp)
+}
+
+// Test that the transaction for updating the global parameters is not
+// started for non-existing daemons or when there are no DHCP servers.
+func TestUpdateKeaGlobalParametersBeginError(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, ctx := newTestClientClassesRestAPI(t, db, dbSettings, fa)
+
+	rsp := rapi.UpdateKeaGlobalParametersBegin(ctx, dhcp.UpdateKeaGlobalParametersBeginParams{})
+	require.IsType(t, &dhcp.UpdateKeaGlobalParametersBeginDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*dhcp.UpdateKeaGlobalParametersBeginDefault)))
+
+	rsp = rapi.UpdateKeaGlobalParametersBegin(ctx, dhcp.UpdateKeaGlobalParametersBeginParams{
+		Request: &models.UpdateKeaGlobalParametersBeginRequest{
+			DaemonIds: []int64{12345},
+		},
+	})
+	require.IsType(t, &dhcp.UpdateKeaGlobalParametersBeginDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*dhcp.UpdateKeaGlobalParametersBeginDefault)))
+}
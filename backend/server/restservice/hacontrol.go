@@ -0,0 +1,109 @@
+package restservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-openapi/runtime/middleware"
+	log "github.com/sirupsen/logrus"
+
+	"isc.org/stork/server/apps/kea"
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/services"
+)
+
+// Records the outcome of the HA action in the events.
+func (r *RestAPI) recordHAAction(ctx context.Context, action kea.HAAction, daemon *dbmodel.Daemon, actionErr error) {
+	if r.EventCenter == nil {
+		return
+	}
+	_, user := r.SessionManager.Logged(ctx)
+	if actionErr != nil {
+		r.EventCenter.AddErrorEvent(fmt.Sprintf("{user} failed to run HA %s action in {daemon}", action), user, daemon, actionErr)
+		return
+	}
+	r.EventCenter.AddInfoEvent(fmt.Sprintf("{user} ran HA %s action in {daemon}", action), user, daemon)
+}
+
+// Implements the POST call running an action controlling the HA state
+// machine of a Kea server belonging to the HA service
+// (services/{id}/ha-actions).
+func (r *RestAPI) RunHAAction(ctx context.Context, params services.RunHAActionParams) middleware.Responder {
+	request := params.Action
+	if request == nil || request.DaemonID == nil || request.Action == nil {
+		msg := "Daemon ID and action are required to run the HA action"
+		log.Error(msg)
+		return services.NewRunHAActionDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	action := kea.HAAction(*request.Action)
+	if !action.IsValid() {
+		msg := fmt.Sprintf("Unsupported HA action %s", action)
+		log.Error(msg)
+		return services.NewRunHAActionDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	service, err := dbmodel.GetDetailedService(r.DB, params.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Problem with fetching service %d from the database", params.ID)
+		log.WithError(err).Error(msg)
+		return services.NewRunHAActionDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if service == nil || service.HAService == nil {
+		msg := fmt.Sprintf("Cannot find HA service with ID %d", params.ID)
+		log.Error(msg)
+		return services.NewRunHAActionDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if !kea.IsHAServiceDaemon(service, *request.DaemonID) {
+		msg := fmt.Sprintf("Daemon %d does not belong to HA service %d", *request.DaemonID, params.ID)
+		log.Error(msg)
+		return services.NewRunHAActionDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	daemon, err := dbmodel.GetDaemonByID(r.DB, *request.DaemonID)
+	if err != nil {
+		msg := fmt.Sprintf("Problem with fetching daemon %d from the database", *request.DaemonID)
+		log.WithError(err).Error(msg)
+		return services.NewRunHAActionDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if daemon == nil {
+		msg := fmt.Sprintf("Cannot find daemon with ID %d", *request.DaemonID)
+		log.Error(msg)
+		return services.NewRunHAActionDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if !r.authorizeTargets(ctx, dbmodel.PermissionManageMachines, newPermissionTarget(daemon.ID, daemon, 0)) {
+		msg := "User is forbidden to control the HA state of the selected server"
+		return services.NewRunHAActionDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	result, err := kea.RunHAAction(r.Agents, service, daemon, action, &kea.HAActionParams{
+		Scopes:    request.Scopes,
+		MaxPeriod: request.MaxPeriod,
+	})
+	r.recordHAAction(ctx, action, daemon, err)
+	if err != nil {
+		msg := fmt.Sprintf("Problem with running the HA %s action: %s", action, err)
+		log.WithError(err).Error(msg)
+		return services.NewRunHAActionDefault(http.StatusConflict).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	return services.NewRunHAActionOK().WithPayload(&models.HAActionResult{
+		Result: int64(result.Result),
+		Text:   result.Text,
+	})
+}
@@ -0,0 +1,247 @@
+package restservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/go-pg/pg/v10"
+	require "github.com/stretchr/testify/require"
+
+	keactrl "isc.org/stork/appctrl/kea"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	dbops "isc.org/stork/server/database"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/services"
+	storktest "isc.org/stork/server/test/dbmodel"
+	storkutil "isc.org/stork/util"
+)
+
+// Adds a Kea app with the DHCPv4 server being the primary server in the
+// HA relationship and the HA service the daemon belongs to.
+func addTestHAService(t *testing.T, db *pg.DB) (*dbmodel.App, *dbmodel.Service) {
+	machine := &dbmodel.Machine{
+		Address:   "machine",
+		AgentPort: 8080,
+	}
+	err := dbmodel.AddMachine(db, machine)
+	require.NoError(t, err)
+
+	config, err := dbmodel.NewKeaConfigFromJSON(`{
+		"Dhcp4": {
+			"hooks-libraries": [
+				{
+					"library": "libdhcp_ha.so",
+					"parameters": {
+						"high-availability": [
+							{
+								"this-server-name": "server1",
+								"mode": "hot-standby",
+								"peers": [
+									{ "name": "server1", "url": "http://192.0.2.1:8000", "role": "primary" },
+									{ "name": "server2", "url": "http://192.0.2.2:8000", "role": "standby" }
+								]
+							}
+						]
+					}
+				}
+			]
+		}
+	}`)
+	require.NoError(t, err)
+
+	accessPoints := []*dbmodel.AccessPoint{}
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "localhost", "", 8000, true)
+	app := &dbmodel.App{
+		Name:         "fxz",
+		MachineID:    machine.ID,
+		Type:         dbmodel.AppTypeKea,
+		AccessPoints: accessPoints,
+		Daemons: []*dbmodel.Daemon{
+			{
+				Name: dbmodel.DaemonNameDHCPv4,
+				KeaDaemon: &dbmodel.KeaDaemon{
+					Config: config,
+				},
+			},
+		},
+	}
+	_, err = dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+
+	service := &dbmodel.Service{
+		BaseService: dbmodel.BaseService{
+			ServiceType: "ha_dhcp",
+		},
+		HAService: &dbmodel.BaseHAService{
+			HAType:       dbmodel.DaemonNameDHCPv4,
+			HAMode:       dbmodel.HAModeHotStandby,
+			Relationship: "server1",
+			PrimaryID:    app.Daemons[0].ID,
+		},
+	}
+	err = dbmodel.AddService(db, service)
+	require.NoError(t, err)
+	err = dbmodel.AddDaemonToService(db, service.ID, app.Daemons[0])
+	require.NoError(t, err)
+	return app, service
+}
+
+// Creates the REST API with the logged user.
+func newTestHAControlRestAPI(t *testing.T, db *pg.DB, dbSettings *dbops.DatabaseSettings, agents *agentcommtest.FakeAgents) (*RestAPI, context.Context, *storktest.FakeEventCenter) {
+	fec := &storktest.FakeEventCenter{}
+	rapi, err := NewRestAPI(dbSettings, db, agents, fec)
+	require.NoError(t, err)
+
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
+	user := &dbmodel.SystemUser{
+		Login:    "jdoe",
+		Name:     "John",
+		Lastname: "Doe",
+	}
+	_, err = dbmodel.CreateUser(db, user)
+	require.NoError(t, err)
+	err = rapi.SessionManager.LoginHandler(ctx, user)
+	require.NoError(t, err)
+	return rapi, ctx, fec
+}
+
+// Returns a function generating the mock response to an HA command.
+func mockHACommandResult(result int, text string) func(int, []interface{}) {
+	return func(callNo int, responses []interface{}) {
+		json := []byte(fmt.Sprintf(`[
+            {
+                "result": %d,
+                "text": "%s"
+            }
+        ]`, result, text))
+		daemons := []keactrl.DaemonName{keactrl.DHCPv4}
+		command := keactrl.NewCommandBase(keactrl.HAMaintenanceStart, daemons...)
+		_ = keactrl.UnmarshalResponseList(command, json, responses[0])
+	}
+}
+
+// Test that the HA action is sent to the server and that the outcome is
+// recorded in the events.
+func TestRunHAAction(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewFakeAgents(mockHACommandResult(0, "Lease database synchronization complete."), nil)
+	rapi, ctx, fec := newTestHAControlRestAPI(t, db, dbSettings, agents)
+	app, service := addTestHAService(t, db)
+
+	rsp := rapi.RunHAAction(ctx, services.RunHAActionParams{
+		ID: service.ID,
+		Action: &models.HAActionRequest{
+			DaemonID: storkutil.Ptr(app.Daemons[0].ID),
+			Action:   storkutil.Ptr("sync"),
+		},
+	})
+	require.IsType(t, &services.RunHAActionOK{}, rsp)
+	require.Equal(t, "Lease database synchronization complete.", rsp.(*services.RunHAActionOK).Payload.Text)
+
+	require.Len(t, agents.RecordedCommands, 1)
+	require.JSONEq(t, `{
+		"command": "ha-sync",
+		"service": ["dhcp4"],
+		"arguments": {
+			"server-name": "server2"
+		}
+	}`, agents.RecordedCommands[0].Marshal())
+
+	require.Len(t, fec.Events, 1)
+	require.Equal(t, dbmodel.EvInfo, fec.Events[0].Level)
+	require.Contains(t, fec.Events[0].Text, "ran HA sync action")
+}
+
+// Test that the error returned by Kea is recorded in the events and
+// returned to the caller.
+func TestRunHAActionError(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewFakeAgents(mockHACommandResult(1, "Unable to transition the server."), nil)
+	rapi, ctx, fec := newTestHAControlRestAPI(t, db, dbSettings, agents)
+	app, service := addTestHAService(t, db)
+
+	rsp := rapi.RunHAAction(ctx, services.RunHAActionParams{
+		ID: service.ID,
+		Action: &models.HAActionRequest{
+			DaemonID: storkutil.Ptr(app.Daemons[0].ID),
+			Action:   storkutil.Ptr("maintenance-start"),
+		},
+	})
+	require.IsType(t, &services.RunHAActionDefault{}, rsp)
+	defaultRsp := rsp.(*services.RunHAActionDefault)
+	require.Equal(t, http.StatusConflict, getStatusCode(*defaultRsp))
+	require.Contains(t, *defaultRsp.Payload.Message, "Unable to transition the server.")
+
+	require.Len(t, fec.Events, 1)
+	require.Equal(t, dbmodel.EvError, fec.Events[0].Level)
+	require.Contains(t, fec.Events[0].Text, "failed to run HA maintenance-start action")
+}
+
+// Test that the HA action is rejected when the service or the daemon
+// does not exist or the daemon does not belong to the service.
+func TestRunHAActionInvalidTarget(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, ctx, _ := newTestHAControlRestAPI(t, db, dbSettings, agents)
+	app, service := addTestHAService(t, db)
+
+	rsp := rapi.RunHAAction(ctx, services.RunHAActionParams{
+		ID: service.ID + 1,
+		Action: &models.HAActionRequest{
+			DaemonID: storkutil.Ptr(app.Daemons[0].ID),
+			Action:   storkutil.Ptr("reset"),
+		},
+	})
+	require.IsType(t, &services.RunHAActionDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*services.RunHAActionDefault)))
+
+	rsp = rapi.RunHAAction(ctx, services.RunHAActionParams{
+		ID: service.ID,
+		Action: &models.HAActionRequest{
+			DaemonID: storkutil.Ptr(app.Daemons[0].ID + 1),
+			Action:   storkutil.Ptr("reset"),
+		},
+	})
+	require.IsType(t, &services.RunHAActionDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*services.RunHAActionDefault)))
+
+	require.Empty(t, agents.RecordedCommands)
+}
+
+// Test that the HA action request lacking the required parameters or
+// specifying an unsupported action is rejected.
+func TestRunHAActionInvalidRequest(t *testing.T) {
+	rapi := &RestAPI{}
+
+	rsp := rapi.RunHAAction(context.Background(), services.RunHAActionParams{
+		ID: 1,
+		Action: &models.HAActionRequest{
+			Action: storkutil.Ptr("reset"),
+		},
+	})
+	require.IsType(t, &services.RunHAActionDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*services.RunHAActionDefault)))
+
+	rsp = rapi.RunHAAction(context.Background(), services.RunHAActionParams{
+		ID: 1,
+		Action: &models.HAActionRequest{
+			DaemonID: storkutil.Ptr(int64(1)),
+			Action:   storkutil.Ptr("restart"),
+		},
+	})
+	require.IsType(t, &services.RunHAActionDefault{}, rsp)
+	defaultRsp := rsp.(*services.RunHAActionDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+	require.Equal(t, "Unsupported HA action restart", *defaultRsp.Payload.Message)
+}
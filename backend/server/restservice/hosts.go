@@ -150,8 +150,12 @@ func (r *RestAPI) convertToHost(restHost *models.Host) (*dbmodel.Host, error) {
 }
 
 // Fetches host reservations from the database and converts to the data formats
-// used in REST API.
-func (r *RestAPI) getHosts(offset, limit int64, filters dbmodel.HostsByPageFilters, sortField string, sortDir dbmodel.SortDirEnum) (*models.Hosts, error) {
+// used in REST API. If the viewer is specified, only the hosts the viewer is
+// permitted to view are returned.
+func (r *RestAPI) getHosts(viewer *dbmodel.SystemUser, offset, limit int64, filters dbmodel.HostsByPageFilters, sortField string, sortDir dbmodel.SortDirEnum) (*models.Hosts, error) {
+	if viewer != nil {
+		filters.PermissionScopes = viewer.GetPermissionScopes(dbmodel.PermissionView)
+	}
 	// Get the hosts from the database.
 	dbHosts, total, err := dbmodel.GetHostsByPage(r.DB, offset, limit, filters, sortField, sortDir)
 	if err != nil {
@@ -193,7 +197,16 @@ func (r *RestAPI) GetHosts(ctx context.Context, params dhcp.GetHostsParams) midd
 		Global:           params.Global,
 		DHCPDataConflict: params.Conflict,
 	}
-	hosts, err := r.getHosts(start, limit, filters, "", dbmodel.SortDirAny)
+	viewer, err := r.getScopedViewer(ctx)
+	if err != nil {
+		msg := "Cannot check the user permissions"
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewGetHostsDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	hosts, err := r.getHosts(viewer, start, limit, filters, "", dbmodel.SortDirAny)
 	if err != nil {
 		msg := "Problem fetching hosts from the database"
 		log.Error(err)
@@ -229,6 +242,13 @@ func (r *RestAPI) GetHost(ctx context.Context, params dhcp.GetHostParams) middle
 		})
 		return rsp
 	}
+	if !r.authorizeView(ctx, getHostPermissionTargets(dbHost)...) {
+		msg := fmt.Sprintf("User is forbidden to view host reservation with ID %d", params.ID)
+		rsp := dhcp.NewGetHostDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
 	// Host found. Convert it to the format used in REST API.
 	host := r.convertHostFromRestAPI(dbHost)
 	rsp := dhcp.NewGetHostOK().WithPayload(host)
@@ -399,6 +419,10 @@ func (r *RestAPI) commonCreateOrUpdateHostSubmit(ctx context.Context, transactio
 		log.WithError(err).Error(msg)
 		return http.StatusInternalServerError, msg
 	}
+	// Make sure the user is permitted to modify the host on these daemons.
+	if code, msg := r.authorizeHostChange(ctx, host); code != 0 {
+		return code, msg
+	}
 	// Apply the host information (create Kea commands).
 	cctx, err = applyFunc(cctx, host)
 	if err != nil {
@@ -593,6 +617,14 @@ func (r *RestAPI) DeleteHost(ctx context.Context, params dhcp.DeleteHostParams)
 		})
 		return rsp
 	}
+	// Make sure the user is permitted to delete the host from its daemons.
+	if !r.authorizeTargets(ctx, dbmodel.PermissionManageHosts, getHostPermissionTargets(dbHost)...) {
+		msg := fmt.Sprintf("User is forbidden to delete host reservation with ID %d", params.ID)
+		rsp := dhcp.NewDeleteHostDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
 	// Create configuration context.
 	_, user := r.SessionManager.Logged(ctx)
 	cctx, err := r.ConfigManager.CreateContext(int64(user.ID))
@@ -37,7 +37,7 @@ func TestGetHostsNoFiltering(t *testing.T) {
 
 	rapi, err := NewRestAPI(dbSettings, db, dbmodel.NewDHCPOptionDefinitionLookup())
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx := newTestUserContext(t, rapi, &dbmodel.SystemGroup{ID: dbmodel.AdminGroupID})
 
 	// Add four hosts. Two with IPv4 and two with IPv6 reservations.
 	hosts, apps := storktestdbmodel.AddTestHosts(t, db)
@@ -120,7 +120,7 @@ func TestGetHostsBySubnetID(t *testing.T) {
 
 	rapi, err := NewRestAPI(dbSettings, db, dbmodel.NewDHCPOptionDefinitionLookup())
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx := newTestUserContext(t, rapi, &dbmodel.SystemGroup{ID: dbmodel.AdminGroupID})
 
 	// Add four hosts. Two with IPv4 and two with IPv6 reservations.
 	_, _ = storktestdbmodel.AddTestHosts(t, db)
@@ -143,7 +143,7 @@ func TestGetHostsByLocalSubnetID(t *testing.T) {
 
 	rapi, err := NewRestAPI(dbSettings, db, dbmodel.NewDHCPOptionDefinitionLookup())
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx := newTestUserContext(t, rapi, &dbmodel.SystemGroup{ID: dbmodel.AdminGroupID})
 
 	// Add four hosts. Two with IPv4 and two with IPv6 reservations.
 	_, _ = storktestdbmodel.AddTestHosts(t, db)
@@ -166,7 +166,7 @@ func TestGetHostsByConflicts(t *testing.T) {
 
 	rapi, err := NewRestAPI(dbSettings, db, dbmodel.NewDHCPOptionDefinitionLookup())
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx := newTestUserContext(t, rapi, &dbmodel.SystemGroup{ID: dbmodel.AdminGroupID})
 
 	// Add hosts. All hosts have local hosts from the API data source only.
 	hosts, _ := storktestdbmodel.AddTestHosts(t, db)
@@ -200,7 +200,7 @@ func TestGetHostsWithFiltering(t *testing.T) {
 
 	rapi, err := NewRestAPI(dbSettings, db, dbmodel.NewDHCPOptionDefinitionLookup())
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx := newTestUserContext(t, rapi, &dbmodel.SystemGroup{ID: dbmodel.AdminGroupID})
 
 	// Add four hosts. Two with IPv4 and two with IPv6 reservations.
 	_, _ = storktestdbmodel.AddTestHosts(t, db)
@@ -223,7 +223,7 @@ func TestGetHost(t *testing.T) {
 
 	rapi, err := NewRestAPI(dbSettings, db, dbmodel.NewDHCPOptionDefinitionLookup())
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx := newTestUserContext(t, rapi, &dbmodel.SystemGroup{ID: dbmodel.AdminGroupID})
 
 	// Add four hosts. Two with IPv4 and two with IPv6 reservations.
 	hosts, _ := storktestdbmodel.AddTestHosts(t, db)
@@ -254,7 +254,7 @@ func TestGetHostWithClientClasses(t *testing.T) {
 
 	rapi, err := NewRestAPI(dbSettings, db, dbmodel.NewDHCPOptionDefinitionLookup())
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx := newTestUserContext(t, rapi, &dbmodel.SystemGroup{ID: dbmodel.AdminGroupID})
 
 	// Add hosts.
 	hosts, _ := storktestdbmodel.AddTestHosts(t, db)
@@ -285,7 +285,7 @@ func TestGetHostWithBootFields(t *testing.T) {
 
 	rapi, err := NewRestAPI(dbSettings, db, dbmodel.NewDHCPOptionDefinitionLookup())
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx := newTestUserContext(t, rapi, &dbmodel.SystemGroup{ID: dbmodel.AdminGroupID})
 
 	// Add hosts.
 	hosts, _ := storktestdbmodel.AddTestHosts(t, db)
@@ -315,7 +315,7 @@ func TestGetHostWithOptions(t *testing.T) {
 
 	rapi, err := NewRestAPI(dbSettings, db, dbmodel.NewDHCPOptionDefinitionLookup())
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx := newTestUserContext(t, rapi, &dbmodel.SystemGroup{ID: dbmodel.AdminGroupID})
 
 	// Add hosts.
 	hosts, _ := storktestdbmodel.AddTestHosts(t, db)
@@ -355,7 +355,7 @@ func TestGetHostWithIPReservations(t *testing.T) {
 	defer teardown()
 
 	rapi, _ := NewRestAPI(dbSettings, db, dbmodel.NewDHCPOptionDefinitionLookup())
-	ctx := context.Background()
+	ctx := newTestUserContext(t, rapi, &dbmodel.SystemGroup{ID: dbmodel.AdminGroupID})
 
 	hosts, _ := storktestdbmodel.AddTestHosts(t, db)
 
@@ -0,0 +1,120 @@
+package restservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
+	log "github.com/sirupsen/logrus"
+
+	keaconfig "isc.org/stork/appcfg/kea"
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/services"
+)
+
+// Converts the DDNS domains configured in the Kea D2 server to the REST
+// API format.
+func newRestKeaD2DDNSDomains(domains []keaconfig.DDNSDomain) []*models.KeaD2DDNSDomain {
+	restDomains := []*models.KeaD2DDNSDomain{}
+	for _, domain := range domains {
+		restDomain := &models.KeaD2DDNSDomain{
+			Name:       domain.Name,
+			KeyName:    domain.KeyName,
+			DNSServers: []*models.KeaD2DNSServer{},
+		}
+		for _, server := range domain.DNSServers {
+			restDomain.DNSServers = append(restDomain.DNSServers, &models.KeaD2DNSServer{
+				Hostname:  server.HostName,
+				IPAddress: server.IPAddress,
+				Port:      server.Port,
+				KeyName:   server.KeyName,
+			})
+		}
+		restDomains = append(restDomains, restDomain)
+	}
+	return restDomains
+}
+
+// Converts the Kea D2 statistics stored in the database to the REST API
+// format.
+func newRestKeaD2Stats(stats *dbmodel.KeaD2DaemonStats) *models.KeaD2Stats {
+	restStats := &models.KeaD2Stats{
+		NcrReceived:   stats.NCRReceived,
+		NcrInvalid:    stats.NCRInvalid,
+		NcrError:      stats.NCRError,
+		UpdateSent:    stats.UpdateSent,
+		UpdateSuccess: stats.UpdateSuccess,
+		UpdateTimeout: stats.UpdateTimeout,
+		UpdateError:   stats.UpdateError,
+		Keys:          []*models.KeaD2KeyStats{},
+		CollectedAt:   strfmt.DateTime(stats.CollectedAt),
+	}
+	for _, key := range stats.Keys {
+		restStats.Keys = append(restStats.Keys, &models.KeaD2KeyStats{
+			Name:          key.Name,
+			UpdateSent:    key.UpdateSent,
+			UpdateSuccess: key.UpdateSuccess,
+			UpdateTimeout: key.UpdateTimeout,
+			UpdateError:   key.UpdateError,
+		})
+	}
+	return restStats
+}
+
+// Converts the configuration and the statistics of the Kea D2 daemon to
+// the REST API format. The secrets of the TSIG keys are not returned.
+func newRestKeaD2DDNS(daemon *dbmodel.Daemon) *models.KeaD2DDNS {
+	ddns := &models.KeaD2DDNS{
+		DaemonID:       daemon.ID,
+		ForwardDomains: []*models.KeaD2DDNSDomain{},
+		ReverseDomains: []*models.KeaD2DDNSDomain{},
+		TsigKeys:       []*models.KeaD2TSIGKey{},
+	}
+	if config := daemon.KeaDaemon.Config; config != nil && config.IsD2() {
+		ddns.ForwardDomains = newRestKeaD2DDNSDomains(config.GetForwardDDNSDomains())
+		ddns.ReverseDomains = newRestKeaD2DDNSDomains(config.GetReverseDDNSDomains())
+		for _, key := range config.GetTSIGKeys() {
+			ddns.TsigKeys = append(ddns.TsigKeys, &models.KeaD2TSIGKey{
+				Name:       key.Name,
+				Algorithm:  key.Algorithm,
+				DigestBits: key.DigestBits,
+			})
+		}
+	}
+	if daemon.KeaDaemon.KeaD2Daemon != nil {
+		ddns.Stats = newRestKeaD2Stats(&daemon.KeaDaemon.KeaD2Daemon.Stats)
+	}
+	return ddns
+}
+
+// Implements the GET call returning the DDNS configuration and statistics
+// of a Kea D2 daemon (daemons/{id}/ddns). The configuration and the
+// statistics are taken from the database.
+func (r *RestAPI) GetDaemonDDNS(ctx context.Context, params services.GetDaemonDDNSParams) middleware.Responder {
+	daemon, err := dbmodel.GetDaemonByID(r.DB, params.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Problem with fetching daemon %d from the database", params.ID)
+		log.WithError(err).Error(msg)
+		return services.NewGetDaemonDDNSDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if daemon == nil {
+		msg := fmt.Sprintf("Cannot find daemon with ID %d", params.ID)
+		log.Error(msg)
+		return services.NewGetDaemonDDNSDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if daemon.Name != dbmodel.DaemonNameD2 || daemon.KeaDaemon == nil {
+		msg := fmt.Sprintf("Daemon %d is not a Kea D2 daemon", params.ID)
+		log.Error(msg)
+		return services.NewGetDaemonDDNSDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	return services.NewGetDaemonDDNSOK().WithPayload(newRestKeaD2DDNS(daemon))
+}
@@ -0,0 +1,164 @@
+package restservice
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	require "github.com/stretchr/testify/require"
+
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/services"
+)
+
+// Sample D2 configuration with the DDNS domains and the TSIG keys.
+const testD2Config = `{
+	"DhcpDdns": {
+		"tsig-keys": [
+			{
+				"name": "d2.key",
+				"algorithm": "HMAC-SHA256",
+				"secret": "LSWXnfkKZjdPJI5QxlpnfQ=="
+			}
+		],
+		"forward-ddns": {
+			"ddns-domains": [
+				{
+					"name": "example.com.",
+					"key-name": "d2.key",
+					"dns-servers": [
+						{
+							"ip-address": "192.0.2.1",
+							"port": 53
+						}
+					]
+				}
+			]
+		},
+		"reverse-ddns": {
+			"ddns-domains": [
+				{
+					"name": "2.0.192.in-addr.arpa."
+				}
+			]
+		}
+	}
+}`
+
+// Test converting the Kea D2 configuration and statistics to the REST API
+// format.
+func TestNewRestKeaD2DDNS(t *testing.T) {
+	daemon := dbmodel.NewKeaDaemon(dbmodel.DaemonNameD2, true)
+	daemon.ID = 4
+	require.NoError(t, daemon.SetConfigFromJSON(testD2Config))
+
+	// The statistics haven't been pulled yet.
+	ddns := newRestKeaD2DDNS(daemon)
+	require.EqualValues(t, 4, ddns.DaemonID)
+	require.Equal(t, []*models.KeaD2DDNSDomain{{
+		Name:    "example.com.",
+		KeyName: "d2.key",
+		DNSServers: []*models.KeaD2DNSServer{{
+			IPAddress: "192.0.2.1",
+			Port:      53,
+		}},
+	}}, ddns.ForwardDomains)
+	require.Equal(t, []*models.KeaD2DDNSDomain{{
+		Name:       "2.0.192.in-addr.arpa.",
+		DNSServers: []*models.KeaD2DNSServer{},
+	}}, ddns.ReverseDomains)
+	require.Equal(t, []*models.KeaD2TSIGKey{{
+		Name:      "d2.key",
+		Algorithm: "HMAC-SHA256",
+	}}, ddns.TsigKeys)
+	require.Nil(t, ddns.Stats)
+
+	daemon.KeaDaemon.KeaD2Daemon = &dbmodel.KeaD2Daemon{
+		Stats: dbmodel.KeaD2DaemonStats{
+			NCRReceived: 5,
+			UpdateSent:  4,
+			UpdateError: 1,
+			Keys: []dbmodel.KeaD2KeyStats{
+				{
+					Name:        "d2.key",
+					UpdateSent:  4,
+					UpdateError: 1,
+				},
+			},
+			CollectedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	}
+	ddns = newRestKeaD2DDNS(daemon)
+	require.NotNil(t, ddns.Stats)
+	require.EqualValues(t, 5, ddns.Stats.NcrReceived)
+	require.EqualValues(t, 4, ddns.Stats.UpdateSent)
+	require.EqualValues(t, 1, ddns.Stats.UpdateError)
+	require.Equal(t, []*models.KeaD2KeyStats{{
+		Name:        "d2.key",
+		UpdateSent:  4,
+		UpdateError: 1,
+	}}, ddns.Stats.Keys)
+	require.Equal(t, "2024-01-02T03:04:05.000Z", ddns.Stats.CollectedAt.String())
+}
+
+// Test getting the DDNS configuration and statistics of the Kea D2 daemon
+// via the REST API.
+func TestGetDaemonDDNS(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	rapi, ctx, _ := newTestHAControlRestAPI(t, db, dbSettings, agentcommtest.NewFakeAgents(nil, nil))
+
+	machine := &dbmodel.Machine{
+		Address:   "localhost",
+		AgentPort: 8080,
+	}
+	require.NoError(t, dbmodel.AddMachine(db, machine))
+
+	d2Daemon := dbmodel.NewKeaDaemon(dbmodel.DaemonNameD2, true)
+	require.NoError(t, d2Daemon.SetConfigFromJSON(testD2Config))
+	app := &dbmodel.App{
+		MachineID: machine.ID,
+		Type:      dbmodel.AppTypeKea,
+		Daemons: []*dbmodel.Daemon{
+			d2Daemon,
+			dbmodel.NewKeaDaemon(dbmodel.DaemonNameDHCPv4, true),
+		},
+	}
+	_, err := dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+	require.NoError(t, dbmodel.UpdateKeaD2DaemonStats(db, app.Daemons[0].KeaDaemon.ID, dbmodel.KeaD2DaemonStats{
+		UpdateSent:  3,
+		UpdateError: 2,
+	}))
+
+	rsp := rapi.GetDaemonDDNS(ctx, services.GetDaemonDDNSParams{
+		ID: app.Daemons[0].ID,
+	})
+	require.IsType(t, &services.GetDaemonDDNSOK{}, rsp)
+	ddns := rsp.(*services.GetDaemonDDNSOK).Payload
+	require.Equal(t, app.Daemons[0].ID, ddns.DaemonID)
+	require.Len(t, ddns.ForwardDomains, 1)
+	require.Len(t, ddns.ReverseDomains, 1)
+	require.Len(t, ddns.TsigKeys, 1)
+	require.NotNil(t, ddns.Stats)
+	require.EqualValues(t, 3, ddns.Stats.UpdateSent)
+	require.EqualValues(t, 2, ddns.Stats.UpdateError)
+
+	// The DHCP daemon is not a D2 daemon.
+	rsp = rapi.GetDaemonDDNS(ctx, services.GetDaemonDDNSParams{
+		ID: app.Daemons[1].ID,
+	})
+	require.IsType(t, &services.GetDaemonDDNSDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*services.GetDaemonDDNSDefault)))
+
+	// The daemon doesn't exist.
+	rsp = rapi.GetDaemonDDNS(ctx, services.GetDaemonDDNSParams{
+		ID: app.Daemons[1].ID + 100,
+	})
+	require.IsType(t, &services.GetDaemonDDNSDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*services.GetDaemonDDNSDefault)))
+}
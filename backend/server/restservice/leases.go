@@ -2,17 +2,26 @@ package restservice
 
 import (
 	"context"
+	"fmt"
+	"net"
 	"net/http"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
+	keactrl "isc.org/stork/appctrl/kea"
+	keadata "isc.org/stork/appdata/kea"
 	"isc.org/stork/server/apps/kea"
+	"isc.org/stork/server/config"
 	dbmodel "isc.org/stork/server/database/model"
 	"isc.org/stork/server/gen/models"
 	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
+	storkutil "isc.org/stork/util"
 )
 
 // This call searches for leases allocated by monitored DHCP servers.
@@ -20,6 +29,8 @@ import (
 // MAC address, client identifier, hostname or the text state:declined.
 // The Stork Server tries to identify the specified value type and
 // sends queries to the Kea servers to find a lease or multiple leases.
+// Alternatively, the leases can be searched by subnet, state and
+// expiration time. Such leases can be returned page by page.
 func (r *RestAPI) GetLeases(ctx context.Context, params dhcp.GetLeasesParams) middleware.Responder {
 	leases := &models.Leases{
 		Total: 0,
@@ -42,7 +53,24 @@ func (r *RestAPI) GetLeases(ctx context.Context, params dhcp.GetLeasesParams) mi
 		})
 		return rsp
 	}
-	if len(text) == 0 && hostID == 0 {
+	filter, err := parseLeasesFilter(params)
+	if err != nil {
+		msg := fmt.Sprintf("Invalid lease search filter: %s", err)
+		log.Error(msg)
+		rsp := dhcp.NewGetLeasesDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if filter != nil && (len(text) > 0 || hostID > 0) {
+		msg := "Subnet, state and expiration filters are mutually exclusive with text and host identifier when searching for leases"
+		log.Error(msg)
+		rsp := dhcp.NewGetLeasesDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if len(text) == 0 && hostID == 0 && filter == nil {
 		// There is nothing to do if none if the parameters were specified.
 		rsp := dhcp.NewGetLeasesOK().WithPayload(leases)
 		return rsp
@@ -53,9 +81,11 @@ func (r *RestAPI) GetLeases(ctx context.Context, params dhcp.GetLeasesParams) mi
 		keaLeases []dbmodel.Lease
 		conflicts []int64
 		erredApps []*dbmodel.App
-		err       error
 	)
-	if len(text) > 0 {
+	switch {
+	case filter != nil:
+		keaLeases, erredApps, err = kea.FindLeasesByFilter(r.DB, r.Agents, filter)
+	case len(text) > 0:
 		// Handle a special case when user specified state:declined search text
 		// to find declined leases.
 		if ok, _ := regexp.MatchString(`^state:\s*declined$`, text); ok {
@@ -63,7 +93,7 @@ func (r *RestAPI) GetLeases(ctx context.Context, params dhcp.GetLeasesParams) mi
 		} else {
 			keaLeases, erredApps, err = kea.FindLeases(r.DB, r.Agents, text)
 		}
-	} else {
+	default:
 		keaLeases, conflicts, erredApps, err = kea.FindLeasesByHostID(r.DB, r.Agents, hostID)
 	}
 	if err != nil {
@@ -75,6 +105,13 @@ func (r *RestAPI) GetLeases(ctx context.Context, params dhcp.GetLeasesParams) mi
 		return rsp
 	}
 
+	// The leases found with the filter can be returned page by page. The
+	// total number includes all leases matching the filter.
+	total := int64(len(keaLeases))
+	if filter != nil {
+		keaLeases = getLeasesPage(keaLeases, params.Start, params.Limit)
+	}
+
 	// Return leases over the REST API.
 	for i := range keaLeases {
 		l := keaLeases[i]
@@ -119,7 +156,7 @@ func (r *RestAPI) GetLeases(ctx context.Context, params dhcp.GetLeasesParams) mi
 
 	// Record conflicting leases and leases count.
 	leases.Conflicts = append(leases.Conflicts, conflicts...)
-	leases.Total = int64(len(leases.Items))
+	leases.Total = total
 
 	// Record apps for which there was an error communicating with the Kea servers.
 	for i := range erredApps {
@@ -132,3 +169,510 @@ func (r *RestAPI) GetLeases(ctx context.Context, params dhcp.GetLeasesParams) mi
 	rsp := dhcp.NewGetLeasesOK().WithPayload(leases)
 	return rsp
 }
+
+// Parses the subnet, state and expiration filters of the lease search.
+// It returns nil if none of the filters is specified.
+func parseLeasesFilter(params dhcp.GetLeasesParams) (*kea.LeasesFilter, error) {
+	if params.Subnet == nil && params.State == nil && params.ExpiresWithin == nil {
+		return nil, nil
+	}
+	filter := &kea.LeasesFilter{}
+	if params.Subnet != nil {
+		_, subnet, err := net.ParseCIDR(strings.TrimSpace(*params.Subnet))
+		if err != nil {
+			return nil, errors.Errorf("invalid subnet prefix %s", *params.Subnet)
+		}
+		filter.Subnet = subnet
+	}
+	if params.State != nil {
+		switch *params.State {
+		case "default":
+			filter.State = storkutil.Ptr(keadata.LeaseStateDefault)
+		case "declined":
+			filter.State = storkutil.Ptr(keadata.LeaseStateDeclined)
+		case "expired-reclaimed":
+			filter.State = storkutil.Ptr(keadata.LeaseStateExpiredReclaimed)
+		default:
+			return nil, errors.Errorf("invalid lease state %s", *params.State)
+		}
+	}
+	if params.ExpiresWithin != nil {
+		if *params.ExpiresWithin <= 0 {
+			return nil, errors.Errorf("expiration time must be positive")
+		}
+		filter.ExpiresWithin = time.Duration(*params.ExpiresWithin) * time.Second
+	}
+	return filter, nil
+}
+
+// Returns the specified page of the leases. All leases from the start
+// index are returned when the limit is not specified.
+func getLeasesPage(leases []dbmodel.Lease, start, limit *int64) []dbmodel.Lease {
+	from := int64(0)
+	if start != nil && *start > 0 {
+		from = *start
+	}
+	if from >= int64(len(leases)) {
+		return []dbmodel.Lease{}
+	}
+	to := int64(len(leases))
+	if limit != nil && *limit > 0 && from+*limit < to {
+		to = from + *limit
+	}
+	return leases[from:to]
+}
+
+// Fetches the daemon in which the leases are modified and checks if the
+// logged user is permitted to modify the leases in it. It returns the
+// daemon or the HTTP error code and the error message.
+func (r *RestAPI) getLeaseDaemon(ctx context.Context, daemonID int64) (*dbmodel.Daemon, int, string) {
+	daemon, err := dbmodel.GetDaemonByID(r.DB, daemonID)
+	if err != nil {
+		msg := fmt.Sprintf("Problem with fetching daemon %d from the database", daemonID)
+		log.WithError(err).Error(msg)
+		return nil, http.StatusInternalServerError, msg
+	}
+	if daemon == nil {
+		msg := fmt.Sprintf("Cannot find daemon with ID %d", daemonID)
+		log.Error(msg)
+		return nil, http.StatusBadRequest, msg
+	}
+	if !r.authorizeTargets(ctx, dbmodel.PermissionManageLeases, newPermissionTarget(daemon.ID, daemon, 0)) {
+		return nil, http.StatusForbidden, "User is forbidden to modify the leases on the selected server"
+	}
+	return daemon, 0, ""
+}
+
+// Attempts to fetch the lease before it is modified to record it in the
+// audit trail. It returns nil if the lease does not exist or it cannot be
+// fetched.
+func (r *RestAPI) getLeaseBeforeChange(daemon *dbmodel.Daemon, leaseType keactrl.LeaseType, ipAddress string) *keadata.Lease {
+	var (
+		lease *dbmodel.Lease
+		err   error
+	)
+	if daemon.Name == dbmodel.DaemonNameDHCPv4 {
+		lease, err = kea.GetLease4ByIPAddress(r.Agents, daemon.App, ipAddress)
+	} else {
+		if leaseType == "" {
+			leaseType = keactrl.LeaseTypeNA
+		}
+		lease, err = kea.GetLease6ByIPAddress(r.Agents, daemon.App, leaseType, ipAddress)
+	}
+	if err != nil {
+		log.WithError(err).Warnf("Cannot fetch lease %s before the change", ipAddress)
+		return nil
+	}
+	if lease == nil {
+		return nil
+	}
+	return &lease.Lease
+}
+
+// Records the lease modification in the audit trail and in the events.
+// The description is a text describing the change, e.g., "deleted lease
+// 192.0.2.1". Failing to record the audit entry is only logged.
+func (r *RestAPI) recordLeaseChange(ctx context.Context, operation, description string, daemon *dbmodel.Daemon, before, after any, command *dbmodel.AuditCommand, changeErr error) {
+	_, user := r.SessionManager.Logged(ctx)
+	entry := kea.NewLeaseAuditEntry(operation, daemon, before, after, command, changeErr)
+	if user != nil {
+		entry.UserID = int64(user.ID)
+		entry.UserLogin = user.Login
+		if entry.UserLogin == "" {
+			entry.UserLogin = user.Email
+		}
+	}
+	if err := dbmodel.AddAuditEntry(r.DB, entry); err != nil {
+		log.WithError(err).WithField("operation", operation).Error("Cannot record the lease change in the audit trail")
+	}
+	if r.EventCenter == nil {
+		return
+	}
+	if changeErr != nil {
+		r.EventCenter.AddErrorEvent(fmt.Sprintf("{user} failed to %s in {daemon}", description), user, daemon, changeErr)
+		return
+	}
+	r.EventCenter.AddInfoEvent(fmt.Sprintf("{user} requested to %s in {daemon}", description), user, daemon)
+}
+
+// Returns the HTTP error code and the message for an error returned while
+// modifying the leases.
+func getLeaseChangeErrorResponse(err error) (int, string) {
+	var (
+		noHook   *config.NoLeaseCmdsHookError
+		notFound *config.LeaseNotFoundError
+	)
+	switch {
+	case errors.As(err, &noHook):
+		return http.StatusBadRequest, fmt.Sprintf("Unable to modify the leases: %s", err)
+	case errors.As(err, &notFound):
+		return http.StatusNotFound, fmt.Sprintf("Unable to modify the lease: %s", err)
+	default:
+		return http.StatusConflict, fmt.Sprintf("Problem with modifying the leases: %s", err)
+	}
+}
+
+// Converts the lease from the REST API format to the parameters of the
+// commands adding and updating the leases.
+func convertLeaseChangeFromRestAPI(restLease *models.LeaseChange) *keactrl.LeaseParameters {
+	lease := &keactrl.LeaseParameters{
+		Type:              keactrl.LeaseType(restLease.LeaseType),
+		PrefixLength:      restLease.PrefixLength,
+		SubnetID:          restLease.SubnetID,
+		HWAddress:         restLease.HwAddress,
+		ClientID:          restLease.ClientID,
+		DUID:              restLease.Duid,
+		IAID:              restLease.Iaid,
+		ValidLifetime:     restLease.ValidLifetime,
+		PreferredLifetime: restLease.PreferredLifetime,
+		Expire:            restLease.Expire,
+		FqdnFwd:           restLease.FqdnFwd,
+		FqdnRev:           restLease.FqdnRev,
+		Hostname:          restLease.Hostname,
+		State:             restLease.State,
+		ForceCreate:       restLease.ForceCreate,
+	}
+	if restLease.IPAddress != nil {
+		lease.IPAddress = *restLease.IPAddress
+	}
+	if userContext, ok := restLease.UserContext.(map[string]any); ok {
+		lease.UserContext = userContext
+	}
+	return lease
+}
+
+// Implements the POST call adding a lease to a Kea DHCP server (leases).
+func (r *RestAPI) AddLease(ctx context.Context, params dhcp.AddLeaseParams) middleware.Responder {
+	if params.Lease == nil || params.Lease.DaemonID == nil || params.Lease.IPAddress == nil {
+		msg := "Daemon ID and IP address are required to add a lease"
+		log.Error(msg)
+		return dhcp.NewAddLeaseDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	daemon, code, msg := r.getLeaseDaemon(ctx, *params.Lease.DaemonID)
+	if code != 0 {
+		return dhcp.NewAddLeaseDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	lease := convertLeaseChangeFromRestAPI(params.Lease)
+	command, err := kea.AddLease(r.Agents, daemon, lease)
+	r.recordLeaseChange(ctx, "lease_add", fmt.Sprintf("add lease %s", lease.IPAddress), daemon, nil, lease, command, err)
+	if err != nil {
+		code, msg := getLeaseChangeErrorResponse(err)
+		log.WithError(err).Error(msg)
+		return dhcp.NewAddLeaseDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	return dhcp.NewAddLeaseOK().WithPayload(&models.LeaseCommandResult{
+		Text: command.Text,
+	})
+}
+
+// Implements the PUT call updating a lease in a Kea DHCP server (leases).
+func (r *RestAPI) UpdateLease(ctx context.Context, params dhcp.UpdateLeaseParams) middleware.Responder {
+	if params.Lease == nil || params.Lease.DaemonID == nil || params.Lease.IPAddress == nil {
+		msg := "Daemon ID and IP address are required to update a lease"
+		log.Error(msg)
+		return dhcp.NewUpdateLeaseDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	daemon, code, msg := r.getLeaseDaemon(ctx, *params.Lease.DaemonID)
+	if code != 0 {
+		return dhcp.NewUpdateLeaseDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	lease := convertLeaseChangeFromRestAPI(params.Lease)
+	before := r.getLeaseBeforeChange(daemon, lease.Type, lease.IPAddress)
+	command, err := kea.UpdateLease(r.Agents, daemon, lease)
+	r.recordLeaseChange(ctx, "lease_update", fmt.Sprintf("update lease %s", lease.IPAddress), daemon, before, lease, command, err)
+	if err != nil {
+		code, msg := getLeaseChangeErrorResponse(err)
+		log.WithError(err).Error(msg)
+		return dhcp.NewUpdateLeaseDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	return dhcp.NewUpdateLeaseOK().WithPayload(&models.LeaseCommandResult{
+		Text: command.Text,
+	})
+}
+
+// Implements the POST call deleting a lease from a Kea DHCP server
+// (leases/delete). The deletion must be confirmed in the request.
+func (r *RestAPI) DeleteLease(ctx context.Context, params dhcp.DeleteLeaseParams) middleware.Responder {
+	request := params.Request
+	if request == nil || request.DaemonID == nil || request.IPAddress == nil {
+		msg := "Daemon ID and IP address are required to delete a lease"
+		log.Error(msg)
+		return dhcp.NewDeleteLeaseDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if request.Confirm == nil || !*request.Confirm {
+		msg := "The lease deletion must be confirmed"
+		log.Error(msg)
+		return dhcp.NewDeleteLeaseDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	daemon, code, msg := r.getLeaseDaemon(ctx, *request.DaemonID)
+	if code != 0 {
+		return dhcp.NewDeleteLeaseDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	leaseType := keactrl.LeaseType(request.LeaseType)
+	before := r.getLeaseBeforeChange(daemon, leaseType, *request.IPAddress)
+	command, err := kea.DeleteLease(r.Agents, daemon, leaseType, *request.IPAddress)
+	r.recordLeaseChange(ctx, "lease_delete", fmt.Sprintf("delete lease %s", *request.IPAddress), daemon, before, nil, command, err)
+	if err != nil {
+		code, msg := getLeaseChangeErrorResponse(err)
+		log.WithError(err).Error(msg)
+		return dhcp.NewDeleteLeaseDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	return dhcp.NewDeleteLeaseOK().WithPayload(&models.LeaseCommandResult{
+		Text: command.Text,
+	})
+}
+
+// Implements the POST call deleting all leases from a subnet in a Kea
+// DHCP server (leases/wipe). The operation must be confirmed in the request.
+func (r *RestAPI) WipeLeases(ctx context.Context, params dhcp.WipeLeasesParams) middleware.Responder {
+	request := params.Request
+	if request == nil || request.DaemonID == nil || request.SubnetID == nil {
+		msg := "Daemon ID and subnet ID are required to wipe the leases"
+		log.Error(msg)
+		return dhcp.NewWipeLeasesDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if request.Confirm == nil || !*request.Confirm {
+		msg := "Wiping the leases must be confirmed"
+		log.Error(msg)
+		return dhcp.NewWipeLeasesDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	daemon, code, msg := r.getLeaseDaemon(ctx, *request.DaemonID)
+	if code != 0 {
+		return dhcp.NewWipeLeasesDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	command, err := kea.WipeLeases(r.Agents, daemon, *request.SubnetID)
+	r.recordLeaseChange(ctx, "lease_wipe", fmt.Sprintf("wipe leases in subnet %d", *request.SubnetID), daemon, nil, nil, command, err)
+	if err != nil {
+		code, msg := getLeaseChangeErrorResponse(err)
+		log.WithError(err).Error(msg)
+		return dhcp.NewWipeLeasesDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	return dhcp.NewWipeLeasesOK().WithPayload(&models.LeaseCommandResult{
+		Text: command.Text,
+	})
+}
+
+// Implements the POST call requesting a Kea DHCP server to resend the DNS
+// update for a lease (leases/resend-ddns).
+func (r *RestAPI) ResendLeaseDdns(ctx context.Context, params dhcp.ResendLeaseDdnsParams) middleware.Responder {
+	request := params.Request
+	if request == nil || request.DaemonID == nil || request.IPAddress == nil {
+		msg := "Daemon ID and IP address are required to resend the DNS update"
+		log.Error(msg)
+		return dhcp.NewResendLeaseDdnsDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	daemon, code, msg := r.getLeaseDaemon(ctx, *request.DaemonID)
+	if code != 0 {
+		return dhcp.NewResendLeaseDdnsDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	command, err := kea.ResendLeaseDDNS(r.Agents, daemon, *request.IPAddress)
+	r.recordLeaseChange(ctx, "lease_resend_ddns", fmt.Sprintf("resend DNS update for lease %s", *request.IPAddress), daemon, nil, nil, command, err)
+	if err != nil {
+		code, msg := getLeaseChangeErrorResponse(err)
+		log.WithError(err).Error(msg)
+		return dhcp.NewResendLeaseDdnsDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	return dhcp.NewResendLeaseDdnsOK().WithPayload(&models.LeaseCommandResult{
+		Text: command.Text,
+	})
+}
+
+// Returns the permission targets for releasing the declined leases. These
+// are the DHCP servers of the specified app or of all Kea apps when the
+// app ID is zero. If the subnet ID is specified, the targets are the DHCP
+// servers owning the subnet, optionally limited to the specified app.
+func (r *RestAPI) getDeclinedLeasesPermissionTargets(appID, subnetID int64) (targets []dbmodel.PermissionTarget, err error) {
+	if subnetID != 0 {
+		subnet, err := dbmodel.GetSubnet(r.DB, subnetID)
+		if err != nil {
+			return nil, err
+		}
+		if subnet == nil {
+			return nil, errors.Errorf("cannot find subnet with ID %d", subnetID)
+		}
+		for _, target := range getSubnetPermissionTargets(subnet) {
+			if appID == 0 || target.AppID == appID {
+				targets = append(targets, target)
+			}
+		}
+		if len(targets) == 0 {
+			return nil, errors.Errorf("subnet with ID %d does not belong to app with ID %d", subnetID, appID)
+		}
+		return targets, nil
+	}
+	var apps []dbmodel.App
+	if appID != 0 {
+		app, err := dbmodel.GetAppByID(r.DB, appID)
+		if err != nil {
+			return nil, err
+		}
+		if app == nil {
+			return nil, errors.Errorf("cannot find app with ID %d", appID)
+		}
+		apps = append(apps, *app)
+	} else {
+		apps, err = dbmodel.GetAppsByType(r.DB, dbmodel.AppTypeKea)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, app := range apps {
+		for _, daemon := range app.Daemons {
+			if daemon.Name == dbmodel.DaemonNameDHCPv4 || daemon.Name == dbmodel.DaemonNameDHCPv6 {
+				targets = append(targets, newPermissionTarget(daemon.ID, daemon, 0))
+			}
+		}
+	}
+	return targets, nil
+}
+
+// Converts the state of the job releasing the declined leases to the
+// REST API format.
+func convertDeclinedLeasesReleaseJobToRestAPI(status *kea.DeclinedLeasesReleaseStatus) *models.DeclinedLeasesReleaseJob {
+	job := &models.DeclinedLeasesReleaseJob{
+		ID:        status.ID,
+		Mode:      string(status.Mode),
+		AppID:     status.Filter.AppID,
+		SubnetID:  status.Filter.SubnetID,
+		MinAge:    int64(status.Filter.MinAge / time.Second),
+		Total:     status.Total,
+		Processed: status.Processed,
+		Finished:  status.FinishedAt != nil,
+		StartedAt: strfmt.DateTime(status.StartedAt),
+		ErredApps: status.ErredApps,
+		Error:     status.Error,
+		Results:   []*models.DeclinedLeasesReleaseResult{},
+	}
+	if status.FinishedAt != nil {
+		job.FinishedAt = strfmt.DateTime(*status.FinishedAt)
+	}
+	for _, result := range status.Results {
+		job.Results = append(job.Results, &models.DeclinedLeasesReleaseResult{
+			DaemonID:   result.DaemonID,
+			DaemonName: result.DaemonName,
+			AppID:      result.AppID,
+			AppName:    result.AppName,
+			Released:   result.Released,
+			Failed:     result.Failed,
+			Errors:     result.Errors,
+		})
+	}
+	return job
+}
+
+// Implements the POST call starting the background job releasing the
+// declined leases in the Kea DHCP servers (leases/declined/release). The
+// operation must be confirmed in the request.
+func (r *RestAPI) ReleaseDeclinedLeases(ctx context.Context, params dhcp.ReleaseDeclinedLeasesParams) middleware.Responder {
+	request := params.Request
+	if request == nil || request.Mode == nil {
+		msg := "Mode is required to release the declined leases"
+		log.Error(msg)
+		return dhcp.NewReleaseDeclinedLeasesDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if request.Confirm == nil || !*request.Confirm {
+		msg := "Releasing the declined leases must be confirmed"
+		log.Error(msg)
+		return dhcp.NewReleaseDeclinedLeasesDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if request.MinAge < 0 {
+		msg := "Minimum age of the released declined leases must not be negative"
+		log.Error(msg)
+		return dhcp.NewReleaseDeclinedLeasesDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	targets, err := r.getDeclinedLeasesPermissionTargets(request.AppID, request.SubnetID)
+	if err != nil {
+		msg := "Problem with fetching the apps holding the declined leases"
+		log.WithError(err).Error(msg)
+		return dhcp.NewReleaseDeclinedLeasesDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if !r.authorizeTargets(ctx, dbmodel.PermissionManageLeases, targets...) {
+		msg := "User is forbidden to modify the leases on the selected servers"
+		return dhcp.NewReleaseDeclinedLeasesDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if r.DeclinedLeasesReleaser == nil {
+		msg := "Releasing the declined leases is not available"
+		log.Error(msg)
+		return dhcp.NewReleaseDeclinedLeasesDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	_, user := r.SessionManager.Logged(ctx)
+	filter := kea.DeclinedLeasesFilter{
+		AppID:    request.AppID,
+		SubnetID: request.SubnetID,
+		MinAge:   time.Duration(request.MinAge) * time.Second,
+	}
+	job, err := r.DeclinedLeasesReleaser.Start(filter, kea.DeclinedLeasesReleaseMode(*request.Mode), user)
+	if err != nil {
+		msg := fmt.Sprintf("Unable to release the declined leases: %s", err)
+		log.WithError(err).Error(msg)
+		return dhcp.NewReleaseDeclinedLeasesDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	status := job.GetStatus()
+	return dhcp.NewReleaseDeclinedLeasesOK().WithPayload(convertDeclinedLeasesReleaseJobToRestAPI(&status))
+}
+
+// Implements the GET call returning the state of the job releasing the
+// declined leases (leases/declined/release/{id}).
+func (r *RestAPI) GetDeclinedLeasesReleaseJob(ctx context.Context, params dhcp.GetDeclinedLeasesReleaseJobParams) middleware.Responder {
+	var job *kea.DeclinedLeasesReleaseJob
+	if r.DeclinedLeasesReleaser != nil {
+		job = r.DeclinedLeasesReleaser.GetJob(params.ID)
+	}
+	if job == nil {
+		msg := fmt.Sprintf("Cannot find the job releasing the declined leases with ID %d", params.ID)
+		log.Error(msg)
+		return dhcp.NewGetDeclinedLeasesReleaseJobDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	status := job.GetStatus()
+	return dhcp.NewGetDeclinedLeasesReleaseJobOK().WithPayload(convertDeclinedLeasesReleaseJobToRestAPI(&status))
+}
@@ -2,14 +2,25 @@ package restservice
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"testing"
+	"time"
 
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 	keactrl "isc.org/stork/appctrl/kea"
 	agentcommtest "isc.org/stork/server/agentcomm/test"
+	"isc.org/stork/server/apps/kea"
+	"isc.org/stork/server/config"
+	dbops "isc.org/stork/server/database"
 	dbmodel "isc.org/stork/server/database/model"
 	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/models"
 	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
+	storktest "isc.org/stork/server/test/dbmodel"
+	storkutil "isc.org/stork/util"
 )
 
 // Generates a success mock response to a command fetching a DHCPv4
@@ -716,3 +727,659 @@ func TestFindLeasesByHostID(t *testing.T) {
 	require.Len(t, okRsp.Payload.Conflicts, 1)
 	require.EqualValues(t, *okRsp.Payload.Items[1].ID, okRsp.Payload.Conflicts[0])
 }
+
+// Adds a Kea DHCPv4 server with the lease_cmds hooks library to the
+// database and creates the REST API with a logged user.
+func newTestLeaseChangeRestAPI(t *testing.T, db *pg.DB, dbSettings *dbops.DatabaseSettings, agents *agentcommtest.FakeAgents) (*RestAPI, context.Context, *dbmodel.App) {
+	machine := &dbmodel.Machine{
+		Address:   "machine",
+		AgentPort: 8080,
+	}
+	err := dbmodel.AddMachine(db, machine)
+	require.NoError(t, err)
+
+	accessPoints := []*dbmodel.AccessPoint{}
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "localhost", "", 8000, true)
+	app := &dbmodel.App{
+		Name:         "fxz",
+		MachineID:    machine.ID,
+		Type:         dbmodel.AppTypeKea,
+		AccessPoints: accessPoints,
+		Daemons: []*dbmodel.Daemon{
+			{
+				Name: dbmodel.DaemonNameDHCPv4,
+				KeaDaemon: &dbmodel.KeaDaemon{
+					Config: dbmodel.NewKeaConfig(&map[string]interface{}{
+						"Dhcp4": map[string]interface{}{
+							"hooks-libraries": []interface{}{
+								map[string]interface{}{
+									"library": "libdhcp_lease_cmds.so",
+								},
+							},
+						},
+					}),
+				},
+			},
+		},
+	}
+	_, err = dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+
+	rapi, err := NewRestAPI(dbSettings, db, agents, &storktest.FakeEventCenter{})
+	require.NoError(t, err)
+
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
+	user := &dbmodel.SystemUser{
+		Login:    "jdoe",
+		Name:     "John",
+		Lastname: "Doe",
+	}
+	_, err = dbmodel.CreateUser(db, user)
+	require.NoError(t, err)
+	err = rapi.SessionManager.LoginHandler(ctx, user)
+	require.NoError(t, err)
+	return rapi, ctx, app
+}
+
+// Generates the mock responses to the lease4-get command fetching the lease
+// before the change and to the command modifying the lease.
+func mockLease4GetAndChange(callNo int, responses []interface{}) {
+	if callNo == 0 {
+		mockLease4Get(callNo, responses)
+		return
+	}
+	json := []byte(`[
+        {
+            "result": 0,
+            "text": "IPv4 lease deleted."
+        }
+    ]`)
+	command := keactrl.NewCommandBase(keactrl.Lease4Del, keactrl.DHCPv4)
+	_ = keactrl.UnmarshalResponseList(command, json, responses[0])
+}
+
+// Test converting the lease from the REST API format to the command
+// parameters.
+func TestConvertLeaseChangeFromRestAPI(t *testing.T) {
+	restLease := &models.LeaseChange{
+		DaemonID:      storkutil.Ptr(int64(1)),
+		IPAddress:     storkutil.Ptr("2001:db8:1::"),
+		LeaseType:     "IA_PD",
+		PrefixLength:  storkutil.Ptr(int64(64)),
+		Duid:          "01:02:03",
+		Iaid:          storkutil.Ptr(int64(2)),
+		ValidLifetime: storkutil.Ptr(int64(3600)),
+		FqdnFwd:       storkutil.Ptr(true),
+		UserContext:   map[string]any{"foo": "bar"},
+		ForceCreate:   true,
+	}
+	lease := convertLeaseChangeFromRestAPI(restLease)
+	require.NotNil(t, lease)
+	require.Equal(t, "2001:db8:1::", lease.IPAddress)
+	require.Equal(t, keactrl.LeaseTypePD, lease.Type)
+	require.EqualValues(t, 64, *lease.PrefixLength)
+	require.Equal(t, "01:02:03", lease.DUID)
+	require.EqualValues(t, 2, *lease.IAID)
+	require.EqualValues(t, 3600, *lease.ValidLifetime)
+	require.True(t, *lease.FqdnFwd)
+	require.Nil(t, lease.FqdnRev)
+	require.Equal(t, "bar", lease.UserContext["foo"])
+	require.True(t, lease.ForceCreate)
+}
+
+// Test mapping the lease modification errors to the HTTP status codes.
+func TestGetLeaseChangeErrorResponse(t *testing.T) {
+	code, _ := getLeaseChangeErrorResponse(config.NewNoLeaseCmdsHookError())
+	require.Equal(t, http.StatusBadRequest, code)
+	code, _ = getLeaseChangeErrorResponse(config.NewLeaseNotFoundError("192.0.2.1"))
+	require.Equal(t, http.StatusNotFound, code)
+	code, msg := getLeaseChangeErrorResponse(errors.New("lease4-add failed"))
+	require.Equal(t, http.StatusConflict, code)
+	require.Contains(t, msg, "lease4-add failed")
+}
+
+// Test deleting a lease. The lease before the deletion and the sent
+// command should be recorded in the audit trail.
+func TestDeleteLease(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewFakeAgents(mockLease4GetAndChange, nil)
+	rapi, ctx, app := newTestLeaseChangeRestAPI(t, db, dbSettings, agents)
+
+	rsp := rapi.DeleteLease(ctx, dhcp.DeleteLeaseParams{
+		Request: &models.LeaseDeleteRequest{
+			DaemonID:  storkutil.Ptr(app.Daemons[0].ID),
+			IPAddress: storkutil.Ptr("192.0.2.1"),
+			Confirm:   storkutil.Ptr(true),
+		},
+	})
+	require.IsType(t, &dhcp.DeleteLeaseOK{}, rsp)
+	require.Equal(t, "IPv4 lease deleted.", rsp.(*dhcp.DeleteLeaseOK).Payload.Text)
+
+	require.Len(t, agents.RecordedCommands, 2)
+	require.EqualValues(t, keactrl.Lease4Get, agents.RecordedCommands[0].GetCommand())
+	require.EqualValues(t, keactrl.Lease4Del, agents.RecordedCommands[1].GetCommand())
+
+	entries, err := dbmodel.GetAuditEntries(db, nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "lease_delete", entries[0].Operation)
+	require.Equal(t, "lease", entries[0].EntityType)
+	require.Equal(t, "jdoe", entries[0].UserLogin)
+	require.Equal(t, []int64{app.Daemons[0].ID}, entries[0].DaemonIDs)
+	require.Contains(t, string(entries[0].EntityBefore), "08:08:08:08:08:08")
+	require.Len(t, entries[0].Commands, 1)
+	require.Empty(t, entries[0].Error)
+}
+
+// Test that the lease deletion and wiping the leases must be confirmed.
+func TestLeaseChangeNotConfirmed(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewFakeAgents(mockLease4GetAndChange, nil)
+	rapi, ctx, app := newTestLeaseChangeRestAPI(t, db, dbSettings, agents)
+
+	rsp := rapi.DeleteLease(ctx, dhcp.DeleteLeaseParams{
+		Request: &models.LeaseDeleteRequest{
+			DaemonID:  storkutil.Ptr(app.Daemons[0].ID),
+			IPAddress: storkutil.Ptr("192.0.2.1"),
+			Confirm:   storkutil.Ptr(false),
+		},
+	})
+	require.IsType(t, &dhcp.DeleteLeaseDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*dhcp.DeleteLeaseDefault)))
+
+	rsp = rapi.WipeLeases(ctx, dhcp.WipeLeasesParams{
+		Request: &models.LeaseWipeRequest{
+			DaemonID: storkutil.Ptr(app.Daemons[0].ID),
+			SubnetID: storkutil.Ptr(int64(1)),
+		},
+	})
+	require.IsType(t, &dhcp.WipeLeasesDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*dhcp.WipeLeasesDefault)))
+
+	require.Empty(t, agents.RecordedCommands)
+}
+
+// Test that a failed lease addition is reported and recorded in the audit
+// trail.
+func TestAddLeaseError(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewFakeAgents(func(callNo int, responses []interface{}) {
+		json := []byte(`[
+            {
+                "result": 1,
+                "text": "lease already exists"
+            }
+        ]`)
+		command := keactrl.NewCommandBase(keactrl.Lease4Add, keactrl.DHCPv4)
+		_ = keactrl.UnmarshalResponseList(command, json, responses[0])
+	}, nil)
+	rapi, ctx, app := newTestLeaseChangeRestAPI(t, db, dbSettings, agents)
+
+	rsp := rapi.AddLease(ctx, dhcp.AddLeaseParams{
+		Lease: &models.LeaseChange{
+			DaemonID:  storkutil.Ptr(app.Daemons[0].ID),
+			IPAddress: storkutil.Ptr("192.0.2.1"),
+		},
+	})
+	require.IsType(t, &dhcp.AddLeaseDefault{}, rsp)
+	require.Equal(t, http.StatusConflict, getStatusCode(*rsp.(*dhcp.AddLeaseDefault)))
+
+	entries, err := dbmodel.GetAuditEntries(db, nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "lease_add", entries[0].Operation)
+	require.Contains(t, entries[0].Error, "lease already exists")
+
+	// Non-existing daemon.
+	rsp = rapi.AddLease(ctx, dhcp.AddLeaseParams{
+		Lease: &models.LeaseChange{
+			DaemonID:  storkutil.Ptr(int64(12345)),
+			IPAddress: storkutil.Ptr("192.0.2.1"),
+		},
+	})
+	require.IsType(t, &dhcp.AddLeaseDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*dhcp.AddLeaseDefault)))
+}
+
+// Test converting the state of the job releasing the declined leases to
+// the REST API format.
+func TestConvertDeclinedLeasesReleaseJobToRestAPI(t *testing.T) {
+	startedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	status := &kea.DeclinedLeasesReleaseStatus{
+		ID:   3,
+		Mode: kea.DeclinedLeasesReleaseReclaim,
+		Filter: kea.DeclinedLeasesFilter{
+			AppID:    1,
+			SubnetID: 2,
+			MinAge:   time.Hour,
+		},
+		Total:     5,
+		Processed: 4,
+		StartedAt: startedAt,
+		ErredApps: []string{"kea@machine2"},
+		Results: []kea.DeclinedLeasesReleaseResult{
+			{
+				DaemonID:   6,
+				DaemonName: dbmodel.DaemonNameDHCPv4,
+				AppID:      1,
+				AppName:    "kea@machine1",
+				Released:   3,
+				Failed:     1,
+				Errors:     []string{"lease not found"},
+			},
+		},
+	}
+	job := convertDeclinedLeasesReleaseJobToRestAPI(status)
+	require.EqualValues(t, 3, job.ID)
+	require.Equal(t, "reclaim", job.Mode)
+	require.EqualValues(t, 1, job.AppID)
+	require.EqualValues(t, 2, job.SubnetID)
+	require.EqualValues(t, 3600, job.MinAge)
+	require.EqualValues(t, 5, job.Total)
+	require.EqualValues(t, 4, job.Processed)
+	require.False(t, job.Finished)
+	require.Equal(t, startedAt, time.Time(job.StartedAt))
+	require.Equal(t, []string{"kea@machine2"}, job.ErredApps)
+	require.Len(t, job.Results, 1)
+	require.EqualValues(t, 6, job.Results[0].DaemonID)
+	require.Equal(t, "kea@machine1", job.Results[0].AppName)
+	require.EqualValues(t, 3, job.Results[0].Released)
+	require.EqualValues(t, 1, job.Results[0].Failed)
+	require.Equal(t, []string{"lease not found"}, job.Results[0].Errors)
+
+	finishedAt := startedAt.Add(time.Minute)
+	status.FinishedAt = &finishedAt
+	job = convertDeclinedLeasesReleaseJobToRestAPI(status)
+	require.True(t, job.Finished)
+	require.Equal(t, finishedAt, time.Time(job.FinishedAt))
+}
+
+// Test that the not found status is returned for a non-existing job
+// releasing the declined leases.
+func TestGetDeclinedLeasesReleaseJobNotFound(t *testing.T) {
+	rapi := &RestAPI{}
+	rsp := rapi.GetDeclinedLeasesReleaseJob(context.Background(), dhcp.GetDeclinedLeasesReleaseJobParams{
+		ID: 1,
+	})
+	require.IsType(t, &dhcp.GetDeclinedLeasesReleaseJobDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*dhcp.GetDeclinedLeasesReleaseJobDefault)))
+
+	rapi.DeclinedLeasesReleaser = kea.NewDeclinedLeasesReleaser(nil, nil, nil)
+	defer rapi.DeclinedLeasesReleaser.Shutdown()
+	rsp = rapi.GetDeclinedLeasesReleaseJob(context.Background(), dhcp.GetDeclinedLeasesReleaseJobParams{
+		ID: 1,
+	})
+	require.IsType(t, &dhcp.GetDeclinedLeasesReleaseJobDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*dhcp.GetDeclinedLeasesReleaseJobDefault)))
+}
+
+// Generates the mock response to the command searching for the declined
+// DHCPv4 leases and to the commands deleting them.
+func mockLeases4GetDeclinedAndDelete(callNo int, responses []interface{}) {
+	if callNo > 0 {
+		json := []byte(`[
+            {
+                "result": 0,
+                "text": "IPv4 lease deleted."
+            }
+        ]`)
+		command := keactrl.NewCommandBase(keactrl.Lease4Del, keactrl.DHCPv4)
+		_ = keactrl.UnmarshalResponseList(command, json, responses[0])
+		return
+	}
+	json := []byte(`[
+        {
+            "result": 0,
+            "text": "Leases found.",
+            "arguments": {
+                "leases": [
+                    {
+                        "cltt": 12345678,
+                        "hw-address": "",
+                        "ip-address": "192.0.2.1",
+                        "state": 1,
+                        "subnet-id": 1,
+                        "valid-lft": 3600
+                    },
+                    {
+                        "cltt": 12345678,
+                        "hw-address": "",
+                        "ip-address": "192.0.2.2",
+                        "state": 1,
+                        "subnet-id": 2,
+                        "valid-lft": 3600
+                    }
+                ]
+            }
+        }
+    ]`)
+	command := keactrl.NewCommandBase(keactrl.Lease4GetByHWAddress, keactrl.DHCPv4)
+	_ = keactrl.UnmarshalResponseList(command, json, responses[0])
+}
+
+// Test starting the job releasing the declined leases and polling its
+// state.
+func TestReleaseDeclinedLeases(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewFakeAgents(mockLeases4GetDeclinedAndDelete, nil)
+	rapi, ctx, app := newTestLeaseChangeRestAPI(t, db, dbSettings, agents)
+	rapi.DeclinedLeasesReleaser = kea.NewDeclinedLeasesReleaser(db, agents, nil)
+	defer rapi.DeclinedLeasesReleaser.Shutdown()
+
+	// The subnet has ID 2 in the Kea configuration.
+	subnet := &dbmodel.Subnet{
+		Prefix: "192.0.2.0/24",
+	}
+	err := dbmodel.AddSubnet(db, subnet)
+	require.NoError(t, err)
+	subnet.SetLocalSubnet(&dbmodel.LocalSubnet{
+		DaemonID:      app.Daemons[0].ID,
+		LocalSubnetID: 2,
+	})
+	err = dbmodel.AddLocalSubnets(db, subnet)
+	require.NoError(t, err)
+
+	rsp := rapi.ReleaseDeclinedLeases(ctx, dhcp.ReleaseDeclinedLeasesParams{
+		Request: &models.DeclinedLeasesReleaseRequest{
+			AppID:    app.ID,
+			SubnetID: subnet.ID,
+			Mode:     storkutil.Ptr("delete"),
+			Confirm:  storkutil.Ptr(true),
+		},
+	})
+	require.IsType(t, &dhcp.ReleaseDeclinedLeasesOK{}, rsp)
+	job := rsp.(*dhcp.ReleaseDeclinedLeasesOK).Payload
+	require.NotZero(t, job.ID)
+	require.Equal(t, "delete", job.Mode)
+
+	rapi.DeclinedLeasesReleaser.GetJob(job.ID).Wait()
+
+	rsp = rapi.GetDeclinedLeasesReleaseJob(ctx, dhcp.GetDeclinedLeasesReleaseJobParams{
+		ID: job.ID,
+	})
+	require.IsType(t, &dhcp.GetDeclinedLeasesReleaseJobOK{}, rsp)
+	job = rsp.(*dhcp.GetDeclinedLeasesReleaseJobOK).Payload
+	require.True(t, job.Finished)
+	require.Empty(t, job.Error)
+	require.EqualValues(t, 1, job.Total)
+	require.EqualValues(t, 1, job.Processed)
+	require.Len(t, job.Results, 1)
+	require.Equal(t, app.Daemons[0].ID, job.Results[0].DaemonID)
+	require.EqualValues(t, 1, job.Results[0].Released)
+	require.Zero(t, job.Results[0].Failed)
+
+	// Only the lease from the selected subnet should be deleted.
+	require.Len(t, agents.RecordedCommands, 2)
+	require.JSONEq(t, `{
+		"command": "lease4-del",
+		"service": ["dhcp4"],
+		"arguments": {
+			"ip-address": "192.0.2.2"
+		}
+	}`, agents.RecordedCommands[1].Marshal())
+
+	entries, err := dbmodel.GetAuditEntries(db, nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "lease_release_declined", entries[0].Operation)
+	require.Equal(t, "jdoe", entries[0].UserLogin)
+}
+
+// Test that the declined leases are not released when the request is
+// invalid or not confirmed.
+func TestReleaseDeclinedLeasesInvalidRequest(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewFakeAgents(mockLeases4GetDeclinedAndDelete, nil)
+	rapi, ctx, app := newTestLeaseChangeRestAPI(t, db, dbSettings, agents)
+	rapi.DeclinedLeasesReleaser = kea.NewDeclinedLeasesReleaser(db, agents, nil)
+	defer rapi.DeclinedLeasesReleaser.Shutdown()
+
+	subnet := &dbmodel.Subnet{
+		Prefix: "192.0.2.0/24",
+	}
+	err := dbmodel.AddSubnet(db, subnet)
+	require.NoError(t, err)
+	subnet.SetLocalSubnet(&dbmodel.LocalSubnet{
+		DaemonID:      app.Daemons[0].ID,
+		LocalSubnetID: 2,
+	})
+	err = dbmodel.AddLocalSubnets(db, subnet)
+	require.NoError(t, err)
+
+	requests := []*models.DeclinedLeasesReleaseRequest{
+		// Not confirmed.
+		{
+			Mode:    storkutil.Ptr("delete"),
+			Confirm: storkutil.Ptr(false),
+		},
+		// Invalid mode.
+		{
+			Mode:    storkutil.Ptr("purge"),
+			Confirm: storkutil.Ptr(true),
+		},
+		// Negative age.
+		{
+			Mode:    storkutil.Ptr("delete"),
+			MinAge:  -1,
+			Confirm: storkutil.Ptr(true),
+		},
+		// Non-existing app.
+		{
+			AppID:   app.ID + 1,
+			Mode:    storkutil.Ptr("delete"),
+			Confirm: storkutil.Ptr(true),
+		},
+		// Non-existing subnet.
+		{
+			SubnetID: subnet.ID + 1,
+			Mode:     storkutil.Ptr("delete"),
+			Confirm:  storkutil.Ptr(true),
+		},
+		// Subnet not belonging to the app.
+		{
+			AppID:    app.ID + 1,
+			SubnetID: subnet.ID,
+			Mode:     storkutil.Ptr("delete"),
+			Confirm:  storkutil.Ptr(true),
+		},
+	}
+	for _, request := range requests {
+		rsp := rapi.ReleaseDeclinedLeases(ctx, dhcp.ReleaseDeclinedLeasesParams{
+			Request: request,
+		})
+		require.IsType(t, &dhcp.ReleaseDeclinedLeasesDefault{}, rsp)
+		require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*dhcp.ReleaseDeclinedLeasesDefault)))
+	}
+	require.Empty(t, agents.RecordedCommands)
+}
+
+// Test parsing the subnet, state and expiration filters of the lease
+// search.
+func TestParseLeasesFilter(t *testing.T) {
+	filter, err := parseLeasesFilter(dhcp.GetLeasesParams{})
+	require.NoError(t, err)
+	require.Nil(t, filter)
+
+	filter, err = parseLeasesFilter(dhcp.GetLeasesParams{
+		Subnet:        storkutil.Ptr("10.20.0.0/16"),
+		State:         storkutil.Ptr("expired-reclaimed"),
+		ExpiresWithin: storkutil.Ptr(int64(3600)),
+	})
+	require.NoError(t, err)
+	require.NotNil(t, filter)
+	require.Equal(t, "10.20.0.0/16", filter.Subnet.String())
+	require.NotNil(t, filter.State)
+	require.EqualValues(t, 2, *filter.State)
+	require.Equal(t, time.Hour, filter.ExpiresWithin)
+
+	filter, err = parseLeasesFilter(dhcp.GetLeasesParams{
+		State: storkutil.Ptr("declined"),
+	})
+	require.NoError(t, err)
+	require.Nil(t, filter.Subnet)
+	require.EqualValues(t, 1, *filter.State)
+
+	_, err = parseLeasesFilter(dhcp.GetLeasesParams{
+		Subnet: storkutil.Ptr("10.20.0.0"),
+	})
+	require.Error(t, err)
+
+	_, err = parseLeasesFilter(dhcp.GetLeasesParams{
+		State: storkutil.Ptr("unknown"),
+	})
+	require.Error(t, err)
+
+	_, err = parseLeasesFilter(dhcp.GetLeasesParams{
+		ExpiresWithin: storkutil.Ptr(int64(0)),
+	})
+	require.Error(t, err)
+}
+
+// Test returning the leases page by page.
+func TestGetLeasesPage(t *testing.T) {
+	leases := make([]dbmodel.Lease, 5)
+	for i := range leases {
+		leases[i].ID = int64(i)
+	}
+	require.Len(t, getLeasesPage(leases, nil, nil), 5)
+
+	page := getLeasesPage(leases, storkutil.Ptr(int64(1)), storkutil.Ptr(int64(2)))
+	require.Len(t, page, 2)
+	require.EqualValues(t, 1, page[0].ID)
+	require.EqualValues(t, 2, page[1].ID)
+
+	page = getLeasesPage(leases, storkutil.Ptr(int64(3)), storkutil.Ptr(int64(10)))
+	require.Len(t, page, 2)
+	require.EqualValues(t, 3, page[0].ID)
+
+	require.Empty(t, getLeasesPage(leases, storkutil.Ptr(int64(5)), nil))
+}
+
+// Test that the invalid filters are rejected and that the filters cannot
+// be combined with the search text.
+func TestGetLeasesInvalidFilter(t *testing.T) {
+	rapi := &RestAPI{}
+
+	rsp := rapi.GetLeases(context.Background(), dhcp.GetLeasesParams{
+		Subnet: storkutil.Ptr("invalid"),
+	})
+	require.IsType(t, &dhcp.GetLeasesDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*dhcp.GetLeasesDefault)))
+
+	rsp = rapi.GetLeases(context.Background(), dhcp.GetLeasesParams{
+		Text:  storkutil.Ptr("192.0.2.1"),
+		State: storkutil.Ptr("declined"),
+	})
+	require.IsType(t, &dhcp.GetLeasesDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*dhcp.GetLeasesDefault)))
+}
+
+// Generates the response to the lease4-get-all command returning three
+// leases from two subnets.
+func mockLease4GetAll(callNo int, responses []interface{}) {
+	now := time.Now().Unix()
+	json := []byte(fmt.Sprintf(`[
+        {
+            "result": 0,
+            "text": "3 IPv4 lease(s) found.",
+            "arguments": {
+                "leases": [
+                    {
+                        "cltt": %d,
+                        "hw-address": "01:02:03:04:05:06",
+                        "ip-address": "10.20.0.1",
+                        "state": 0,
+                        "subnet-id": 1,
+                        "valid-lft": 3600
+                    },
+                    {
+                        "cltt": %d,
+                        "hw-address": "01:02:03:04:05:07",
+                        "ip-address": "10.20.0.2",
+                        "state": 0,
+                        "subnet-id": 1,
+                        "valid-lft": 3600
+                    },
+                    {
+                        "cltt": %d,
+                        "hw-address": "01:02:03:04:05:08",
+                        "ip-address": "10.30.0.1",
+                        "state": 0,
+                        "subnet-id": 2,
+                        "valid-lft": 3600
+                    }
+                ],
+                "count": 3
+            }
+        }
+    ]`, now-3000, now, now-3000))
+	command := keactrl.NewCommandBase(keactrl.Lease4GetAll, keactrl.DHCPv4)
+	_ = keactrl.UnmarshalResponseList(command, json, responses[0])
+}
+
+// Test searching the leases expiring within an hour in a subnet.
+func TestGetLeasesByFilter(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewFakeAgents(mockLease4GetAll, nil)
+	rapi, ctx, app := newTestLeaseChangeRestAPI(t, db, dbSettings, agents)
+
+	// The subnet has ID 1 in the Kea configuration.
+	subnet := &dbmodel.Subnet{
+		Prefix: "10.20.0.0/16",
+	}
+	err := dbmodel.AddSubnet(db, subnet)
+	require.NoError(t, err)
+	subnet.SetLocalSubnet(&dbmodel.LocalSubnet{
+		DaemonID:      app.Daemons[0].ID,
+		LocalSubnetID: 1,
+	})
+	err = dbmodel.AddLocalSubnets(db, subnet)
+	require.NoError(t, err)
+
+	rsp := rapi.GetLeases(ctx, dhcp.GetLeasesParams{
+		Subnet:        storkutil.Ptr("10.20.0.0/16"),
+		ExpiresWithin: storkutil.Ptr(int64(900)),
+	})
+	require.IsType(t, &dhcp.GetLeasesOK{}, rsp)
+	leases := rsp.(*dhcp.GetLeasesOK).Payload
+	require.EqualValues(t, 1, leases.Total)
+	require.Len(t, leases.Items, 1)
+	require.Equal(t, "10.20.0.1", *leases.Items[0].IPAddress)
+	require.Equal(t, app.ID, *leases.Items[0].AppID)
+	require.Empty(t, leases.ErredApps)
+
+	// Get the second page of the leases in the subnet.
+	rsp = rapi.GetLeases(ctx, dhcp.GetLeasesParams{
+		Subnet: storkutil.Ptr("10.20.0.0/16"),
+		Start:  storkutil.Ptr(int64(1)),
+		Limit:  storkutil.Ptr(int64(1)),
+	})
+	require.IsType(t, &dhcp.GetLeasesOK{}, rsp)
+	leases = rsp.(*dhcp.GetLeasesOK).Payload
+	require.EqualValues(t, 2, leases.Total)
+	require.Len(t, leases.Items, 1)
+	require.Equal(t, "10.20.0.2", *leases.Items[0].IPAddress)
+
+	// Only the leases in the subnet should be fetched from Kea.
+	require.Len(t, agents.RecordedCommands, 2)
+	require.JSONEq(t, `{
+		"command": "lease4-get-all",
+		"service": ["dhcp4"],
+		"arguments": {
+			"subnets": [1]
+		}
+	}`, agents.RecordedCommands[0].Marshal())
+}
@@ -631,9 +631,9 @@ func (r *RestAPI) UpdateMachine(ctx context.Context, params services.UpdateMachi
 	}
 
 	// if machine authorization is changed then this action requires super-admin group
+	// or a custom group permitted to manage the machines
 	if dbMachine.Authorized != params.Machine.Authorized {
-		_, dbUser := r.SessionManager.Logged(ctx)
-		if !dbUser.InGroup(&dbmodel.SystemGroup{ID: dbmodel.SuperAdminGroupID}) {
+		if !r.isSuperAdminOrPermitted(ctx, dbmodel.PermissionManageMachines) {
 			msg := "User is forbidden to change machine authorization"
 			rsp := services.NewUpdateMachineDefault(http.StatusForbidden).WithPayload(&models.APIError{
 				Message: &msg,
@@ -677,9 +677,9 @@ func (r *RestAPI) UpdateMachine(ctx context.Context, params services.UpdateMachi
 
 // Get machine's server token. It is used by user during manual agent registration.
 func (r *RestAPI) GetMachinesServerToken(ctx context.Context, params services.GetMachinesServerTokenParams) middleware.Responder {
-	// only super-admin can get server token
-	_, dbUser := r.SessionManager.Logged(ctx)
-	if !dbUser.InGroup(&dbmodel.SystemGroup{ID: dbmodel.SuperAdminGroupID}) {
+	// only super-admin and the users permitted to manage the machines can
+	// get server token
+	if !r.isSuperAdminOrPermitted(ctx, dbmodel.PermissionManageMachines) {
 		msg := "User is forbidden to get server token"
 		rsp := services.NewGetMachinesServerTokenDefault(http.StatusForbidden).WithPayload(&models.APIError{
 			Message: &msg,
@@ -714,9 +714,9 @@ func (r *RestAPI) GetMachinesServerToken(ctx context.Context, params services.Ge
 
 // Regenerate machines server token.
 func (r *RestAPI) RegenerateMachinesServerToken(ctx context.Context, params services.RegenerateMachinesServerTokenParams) middleware.Responder {
-	// only super-admin can get server token
-	_, dbUser := r.SessionManager.Logged(ctx)
-	if !dbUser.InGroup(&dbmodel.SystemGroup{ID: dbmodel.SuperAdminGroupID}) {
+	// only super-admin and the users permitted to manage the machines can
+	// regenerate server token
+	if !r.isSuperAdminOrPermitted(ctx, dbmodel.PermissionManageMachines) {
 		msg := "User is forbidden to generate new server token"
 		rsp := services.NewGetMachinesServerTokenDefault(http.StatusForbidden).WithPayload(&models.APIError{
 			Message: &msg,
@@ -1313,6 +1313,7 @@ func getKeaServicesStatus(db *dbops.PgDB, app *dbmodel.App) *models.ServicesStat
 			}
 		}
 		keaStatus.HaServers = &models.KeaStatusHaServers{
+			ServiceID:    s.ID,
 			Relationship: ha.Relationship,
 			PrimaryServer: &models.KeaHAServerStatus{
 				Age:                age[0],
@@ -1455,7 +1456,7 @@ func (r *RestAPI) GetDhcpOverview(ctx context.Context, params dhcp.GetDhcpOvervi
 	filters := &dbmodel.SubnetsByPageFilters{}
 	filters.SetIPv4Family()
 
-	subnets4, err := r.getSubnets(0, 5, filters, "addr_utilization", dbmodel.SortDirDesc)
+	subnets4, err := r.getSubnets(nil, 0, 5, filters, "addr_utilization", dbmodel.SortDirDesc)
 	if err != nil {
 		log.Error(err)
 		msg := "Cannot get IPv4 subnets from db"
@@ -1466,7 +1467,7 @@ func (r *RestAPI) GetDhcpOverview(ctx context.Context, params dhcp.GetDhcpOvervi
 	}
 
 	filters.SetIPv6Family()
-	subnets6, err := r.getSubnets(0, 5, filters, "addr_utilization", dbmodel.SortDirDesc)
+	subnets6, err := r.getSubnets(nil, 0, 5, filters, "addr_utilization", dbmodel.SortDirDesc)
 	if err != nil {
 		log.Error(err)
 		msg := "Cannot get IPv6 subnets from db"
@@ -1497,6 +1498,27 @@ func (r *RestAPI) GetDhcpOverview(ctx context.Context, params dhcp.GetDhcpOvervi
 		return rsp
 	}
 
+	// get list of subnets and shared networks with the earliest forecast exhaustion
+	exhaustingSubnets, err := dbmodel.GetSubnetsByExhaustionForecast(r.DB, 5)
+	if err != nil {
+		log.Error(err)
+		msg := "Cannot get subnets forecast to be exhausted from db"
+		rsp := dhcp.NewGetDhcpOverviewDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	exhaustingSharedNetworks, err := dbmodel.GetSharedNetworksByExhaustionForecast(r.DB, 5)
+	if err != nil {
+		log.Error(err)
+		msg := "Cannot get shared networks forecast to be exhausted from db"
+		rsp := dhcp.NewGetDhcpOverviewDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
 	// get dhcp statistics
 	stats, err := dbmodel.GetAllStats(r.DB)
 	if err != nil {
@@ -1599,15 +1621,32 @@ func (r *RestAPI) GetDhcpOverview(ctx context.Context, params dhcp.GetDhcpOvervi
 		}
 	}
 
+	restExhaustingSubnets := &models.Subnets{
+		Items: []*models.Subnet{},
+		Total: int64(len(exhaustingSubnets)),
+	}
+	for i := range exhaustingSubnets {
+		restExhaustingSubnets.Items = append(restExhaustingSubnets.Items, r.convertSubnetToRestAPI(&exhaustingSubnets[i]))
+	}
+	restExhaustingSharedNetworks := &models.SharedNetworks{
+		Items: []*models.SharedNetwork{},
+		Total: int64(len(exhaustingSharedNetworks)),
+	}
+	for i := range exhaustingSharedNetworks {
+		restExhaustingSharedNetworks.Items = append(restExhaustingSharedNetworks.Items, r.convertSharedNetworkToRestAPI(&exhaustingSharedNetworks[i]))
+	}
+
 	// combine gathered information
 	overview := &models.DhcpOverview{
-		Subnets4:        subnets4,
-		Subnets6:        subnets6,
-		SharedNetworks4: sharedNetworks4,
-		SharedNetworks6: sharedNetworks6,
-		Dhcp4Stats:      dhcp4Stats,
-		Dhcp6Stats:      dhcp6Stats,
-		DhcpDaemons:     dhcpDaemons,
+		Subnets4:                 subnets4,
+		Subnets6:                 subnets6,
+		SharedNetworks4:          sharedNetworks4,
+		SharedNetworks6:          sharedNetworks6,
+		ExhaustingSubnets:        restExhaustingSubnets,
+		ExhaustingSharedNetworks: restExhaustingSharedNetworks,
+		Dhcp4Stats:               dhcp4Stats,
+		Dhcp6Stats:               dhcp6Stats,
+		DhcpDaemons:              dhcpDaemons,
 	}
 
 	rsp := dhcp.NewGetDhcpOverviewOK().WithPayload(overview)
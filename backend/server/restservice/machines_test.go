@@ -1830,6 +1830,7 @@ func TestRestGetAppServicesStatus(t *testing.T) {
 	require.NotNil(t, haStatus.SecondaryServer)
 
 	require.Equal(t, "server1", haStatus.Relationship)
+	require.EqualValues(t, keaServices[0].ID, haStatus.ServiceID)
 
 	require.EqualValues(t, keaApp.Daemons[0].ID, haStatus.PrimaryServer.ID)
 	require.Equal(t, "primary", haStatus.PrimaryServer.Role)
@@ -2181,6 +2182,8 @@ func TestGetDhcpOverview(t *testing.T) {
 	require.Nil(t, okRsp.Payload.Subnets6.Items[0].LocalSubnets)
 	require.Len(t, okRsp.Payload.SharedNetworks4.Items, 0)
 	require.Len(t, okRsp.Payload.SharedNetworks6.Items, 0)
+	require.Empty(t, okRsp.Payload.ExhaustingSubnets.Items)
+	require.Empty(t, okRsp.Payload.ExhaustingSharedNetworks.Items)
 	require.Len(t, okRsp.Payload.DhcpDaemons, 1)
 
 	// only dhcp4 is present
@@ -2195,6 +2198,58 @@ func TestGetDhcpOverview(t *testing.T) {
 	require.Empty(t, okRsp.Payload.DhcpDaemons[0].HaOverview)
 }
 
+// Test that the overview includes the subnets and shared networks with
+// the earliest forecast exhaustion.
+func TestGetDhcpOverviewExhaustionForecast(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	sharedNetwork := &dbmodel.SharedNetwork{
+		Name:   "frog",
+		Family: 4,
+	}
+	err := dbmodel.AddSharedNetwork(db, sharedNetwork)
+	require.NoError(t, err)
+
+	now := storkutil.UTCNow().Truncate(time.Second)
+	subnets := []*dbmodel.Subnet{
+		{Prefix: "192.0.2.0/24"},
+		{Prefix: "192.0.3.0/24"},
+		{Prefix: "2001:db8:1::/64"},
+	}
+	for _, subnet := range subnets {
+		err = dbmodel.AddSubnet(db, subnet)
+		require.NoError(t, err)
+	}
+	err = subnets[0].UpdateExhaustionForecast(db, now.Add(20*24*time.Hour), time.Time{})
+	require.NoError(t, err)
+	err = subnets[2].UpdateExhaustionForecast(db, time.Time{}, now.Add(10*24*time.Hour))
+	require.NoError(t, err)
+	err = dbmodel.UpdateExhaustionForecastInSharedNetwork(db, sharedNetwork.ID, now.Add(40*24*time.Hour), time.Time{})
+	require.NoError(t, err)
+
+	settings := RestAPISettings{}
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, err := NewRestAPI(&settings, dbSettings, db, fa)
+	require.NoError(t, err)
+
+	rsp := rapi.GetDhcpOverview(context.Background(), dhcp.GetDhcpOverviewParams{})
+	require.IsType(t, &dhcp.GetDhcpOverviewOK{}, rsp)
+	overview := rsp.(*dhcp.GetDhcpOverviewOK).Payload
+
+	// The subnet without the forecast is skipped.
+	require.Len(t, overview.ExhaustingSubnets.Items, 2)
+	require.Equal(t, "2001:db8:1::/64", overview.ExhaustingSubnets.Items[0].Subnet)
+	require.Nil(t, overview.ExhaustingSubnets.Items[0].AddrExhaustionAt)
+	require.WithinDuration(t, now.Add(10*24*time.Hour), time.Time(*overview.ExhaustingSubnets.Items[0].PdExhaustionAt), 0)
+	require.Equal(t, "192.0.2.0/24", overview.ExhaustingSubnets.Items[1].Subnet)
+	require.WithinDuration(t, now.Add(20*24*time.Hour), time.Time(*overview.ExhaustingSubnets.Items[1].AddrExhaustionAt), 0)
+
+	require.Len(t, overview.ExhaustingSharedNetworks.Items, 1)
+	require.Equal(t, "frog", overview.ExhaustingSharedNetworks.Items[0].Name)
+	require.NotNil(t, overview.ExhaustingSharedNetworks.Items[0].AddrExhaustionAt)
+}
+
 // This test verifies that the overview response includes HA state.
 func TestHAInDhcpOverview(t *testing.T) {
 	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
@@ -14,6 +14,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"isc.org/stork/server/auth"
+	dbmodel "isc.org/stork/server/database/model"
 	"isc.org/stork/server/eventcenter"
 	"isc.org/stork/server/metrics"
 )
@@ -343,22 +344,69 @@ func (r *RestAPI) GlobalMiddleware(handler http.Handler, staticFilesDir, baseURL
 	return handler
 }
 
+// Returns the token sent in the Authorization header with the Bearer
+// scheme. The second returned value is false if there is no such token.
+func getBearerToken(req *http.Request) (string, bool) {
+	header := req.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// Install a middleware that authenticates the requests carrying the personal
+// API tokens. The authenticated user is stored in the request context, so
+// these requests don't need the sessions. The requests with invalid or
+// expired tokens are rejected.
+func (r *RestAPI) apiTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token, ok := getBearerToken(req)
+		if !ok {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		apiToken, err := dbmodel.GetAPITokenByValue(r.DB, token)
+		if err != nil {
+			log.WithError(err).Error("Failed to fetch API token from the database")
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		now := time.Now().UTC()
+		if apiToken == nil || apiToken.IsExpired(now) {
+			http.Error(w, "invalid or expired API token", http.StatusUnauthorized)
+			return
+		}
+
+		// Failing to record the token use should not prevent the access.
+		if err = dbmodel.UpdateAPITokenLastUsed(r.DB, apiToken.ID, now); err != nil {
+			log.WithError(err).Warn("Failed to update last use time of the API token")
+		}
+
+		ctx := r.SessionManager.APITokenLoginHandler(req.Context(), apiToken.User)
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
 // Inner middleware function provides a common place to setup middlewares for
 // the server. It is invoked after routing but before authentication, binding and validation.
 func (r *RestAPI) InnerMiddleware(handler http.Handler) http.Handler {
 	// last handler is executed first for incoming request
+	handler = r.apiTokenMiddleware(handler)
 	handler = r.SessionManager.SessionMiddleware(handler)
 	return handler
 }
 
 // Checks if the user us authorized to access the system (has session).
 func (r *RestAPI) Authorizer(req *http.Request) error {
-	ok, u := r.SessionManager.Logged(req.Context())
-	if !ok {
-		return errors.Errorf("user unauthorized")
+	u, err := r.getLoggedUserWithPermissions(req.Context())
+	if err != nil {
+		return err
 	}
 
-	ok, _ = auth.Authorize(u, req)
+	ok, _ := auth.Authorize(u, req)
 	if !ok {
 		return errors.Errorf("user logged in but not allowed to access the resource")
 	}
@@ -556,3 +556,28 @@ func TestTrimBaseURLMiddelware(t *testing.T) {
 		require.EqualValues(t, "/endpoint", url.Path)
 	})
 }
+
+// Test that the bearer token is extracted from the Authorization header.
+func TestGetBearerToken(t *testing.T) {
+	req := httptest.NewRequest("GET", "http://localhost/api/hosts", nil)
+	_, ok := getBearerToken(req)
+	require.False(t, ok)
+
+	req.Header.Set("Authorization", "Basic Zm9vOmJhcg==")
+	_, ok = getBearerToken(req)
+	require.False(t, ok)
+
+	req.Header.Set("Authorization", "Bearer ")
+	_, ok = getBearerToken(req)
+	require.False(t, ok)
+
+	req.Header.Set("Authorization", "Bearer stork_abc")
+	token, ok := getBearerToken(req)
+	require.True(t, ok)
+	require.Equal(t, "stork_abc", token)
+
+	req.Header.Set("Authorization", "bearer  stork_abc ")
+	token, ok = getBearerToken(req)
+	require.True(t, ok)
+	require.Equal(t, "stork_abc", token)
+}
@@ -0,0 +1,244 @@
+package restservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
+	log "github.com/sirupsen/logrus"
+
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/events"
+	"isc.org/stork/server/notifications"
+)
+
+// Default maximum number of the delivery retries of the notification
+// channel. It is used when the number is not specified in the request.
+const defaultNotificationChannelMaxRetries int64 = 5
+
+// Maximum number of the delivery retries of the notification channel.
+// It limits the time a failing delivery blocks the channel's queue.
+const maxNotificationChannelMaxRetries int64 = 10
+
+// Creates new instance of the notification channel model used by REST API
+// from the channel instance returned from the database.
+func newRestNotificationChannel(c dbmodel.NotificationChannel) *models.NotificationChannel {
+	name := c.Name
+	channelType := string(c.Type)
+	channelURL := c.URL
+	level := int64(c.Level)
+	maxRetries := c.MaxRetries
+	return &models.NotificationChannel{
+		ID:           c.ID,
+		CreatedAt:    strfmt.DateTime(c.CreatedAt),
+		Name:         &name,
+		Type:         &channelType,
+		Enabled:      c.Enabled,
+		URL:          &channelURL,
+		BodyTemplate: c.BodyTemplate,
+		ContentType:  c.ContentType,
+		Level:        &level,
+		MachineIds:   c.MachineIDs,
+		AppIds:       c.AppIDs,
+		DaemonIds:    c.DaemonIDs,
+		SubnetIds:    c.SubnetIDs,
+		MaxRetries:   &maxRetries,
+	}
+}
+
+// Validates the notification channel received in the request and converts
+// it to the database model. It returns an error message if the channel is
+// invalid.
+func newDBNotificationChannel(c *models.NotificationChannel) (*dbmodel.NotificationChannel, string) {
+	if c == nil || c.Name == nil || strings.TrimSpace(*c.Name) == "" {
+		return nil, "missing name"
+	}
+	if c.Type == nil || *c.Type != string(dbmodel.NotificationChannelTypeWebhook) {
+		return nil, "unsupported type"
+	}
+	if c.URL == nil {
+		return nil, "missing URL"
+	}
+	parsedURL, err := url.Parse(*c.URL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return nil, "URL must be a valid HTTP or HTTPS URL"
+	}
+	if c.BodyTemplate != "" {
+		if _, err := notifications.ParseBodyTemplate(c.BodyTemplate); err != nil {
+			return nil, err.Error()
+		}
+	}
+	level := dbmodel.EvInfo
+	if c.Level != nil {
+		level = dbmodel.EventLevel(*c.Level)
+		if level < dbmodel.EvInfo || level > dbmodel.EvError {
+			return nil, "invalid level"
+		}
+	}
+	maxRetries := defaultNotificationChannelMaxRetries
+	if c.MaxRetries != nil {
+		maxRetries = *c.MaxRetries
+		if maxRetries < 0 {
+			return nil, "maximum number of retries must not be negative"
+		}
+		if maxRetries > maxNotificationChannelMaxRetries {
+			return nil, fmt.Sprintf("maximum number of retries must not exceed %d", maxNotificationChannelMaxRetries)
+		}
+	}
+	return &dbmodel.NotificationChannel{
+		ID:           c.ID,
+		Name:         strings.TrimSpace(*c.Name),
+		Type:         dbmodel.NotificationChannelType(*c.Type),
+		Enabled:      c.Enabled,
+		URL:          *c.URL,
+		BodyTemplate: c.BodyTemplate,
+		ContentType:  c.ContentType,
+		Level:        level,
+		MachineIDs:   c.MachineIds,
+		AppIDs:       c.AppIds,
+		DaemonIDs:    c.DaemonIds,
+		SubnetIDs:    c.SubnetIds,
+		MaxRetries:   maxRetries,
+	}, ""
+}
+
+// Returns all notification channels.
+func (r *RestAPI) GetNotificationChannels(ctx context.Context, params events.GetNotificationChannelsParams) middleware.Responder {
+	dbChannels, err := dbmodel.GetAllNotificationChannels(r.DB)
+	if err != nil {
+		log.WithError(err).Error("Failed to fetch notification channels from the database")
+
+		msg := "Failed to fetch notification channels from the database"
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return events.NewGetNotificationChannelsDefault(http.StatusInternalServerError).WithPayload(&rspErr)
+	}
+
+	channels := &models.NotificationChannels{
+		Items: []*models.NotificationChannel{},
+		Total: int64(len(dbChannels)),
+	}
+	for _, c := range dbChannels {
+		channels.Items = append(channels.Items, newRestNotificationChannel(c))
+	}
+	return events.NewGetNotificationChannelsOK().WithPayload(channels)
+}
+
+// Returns the notification channel with the specified ID.
+func (r *RestAPI) GetNotificationChannel(ctx context.Context, params events.GetNotificationChannelParams) middleware.Responder {
+	dbChannel, err := dbmodel.GetNotificationChannel(r.DB, params.ID)
+	if err != nil {
+		log.WithField("channelID", params.ID).WithError(err).Error("Failed to fetch notification channel from the database")
+
+		msg := fmt.Sprintf("Failed to fetch notification channel with ID %d from the database", params.ID)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return events.NewGetNotificationChannelDefault(http.StatusInternalServerError).WithPayload(&rspErr)
+	}
+	if dbChannel == nil {
+		msg := fmt.Sprintf("Cannot find notification channel with ID %d", params.ID)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return events.NewGetNotificationChannelDefault(http.StatusNotFound).WithPayload(&rspErr)
+	}
+	return events.NewGetNotificationChannelOK().WithPayload(newRestNotificationChannel(*dbChannel))
+}
+
+// Creates new notification channel.
+func (r *RestAPI) CreateNotificationChannel(ctx context.Context, params events.CreateNotificationChannelParams) middleware.Responder {
+	dbChannel, invalid := newDBNotificationChannel(params.Channel)
+	if invalid != "" {
+		msg := fmt.Sprintf("Failed to create new notification channel: %s", invalid)
+		log.Warn(msg)
+		rspErr := models.APIError{Message: &msg}
+		return events.NewCreateNotificationChannelDefault(http.StatusBadRequest).WithPayload(&rspErr)
+	}
+	dbChannel.ID = 0
+
+	con, err := dbmodel.AddNotificationChannel(r.DB, dbChannel)
+	if con {
+		log.WithError(err).Info("Failed to create conflicting notification channel")
+
+		msg := fmt.Sprintf("Notification channel with name %s already exists", dbChannel.Name)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return events.NewCreateNotificationChannelDefault(http.StatusConflict).WithPayload(&rspErr)
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to create new notification channel")
+
+		msg := fmt.Sprintf("Failed to create new notification channel %s", dbChannel.Name)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return events.NewCreateNotificationChannelDefault(http.StatusInternalServerError).WithPayload(&rspErr)
+	}
+	return events.NewCreateNotificationChannelOK().WithPayload(newRestNotificationChannel(*dbChannel))
+}
+
+// Updates the notification channel with the specified ID.
+func (r *RestAPI) UpdateNotificationChannel(ctx context.Context, params events.UpdateNotificationChannelParams) middleware.Responder {
+	dbChannel, invalid := newDBNotificationChannel(params.Channel)
+	if invalid != "" {
+		msg := fmt.Sprintf("Failed to update notification channel with ID %d: %s", params.ID, invalid)
+		log.Warn(msg)
+		rspErr := models.APIError{Message: &msg}
+		return events.NewUpdateNotificationChannelDefault(http.StatusBadRequest).WithPayload(&rspErr)
+	}
+	dbChannel.ID = params.ID
+
+	con, err := dbmodel.UpdateNotificationChannel(r.DB, dbChannel)
+	if err != nil {
+		code := http.StatusInternalServerError
+		msg := fmt.Sprintf("Failed to update notification channel with ID %d", params.ID)
+		switch {
+		case con:
+			code = http.StatusConflict
+			msg = fmt.Sprintf("Notification channel with name %s already exists", dbChannel.Name)
+		case errors.Is(err, dbmodel.ErrNotExists):
+			code = http.StatusNotFound
+			msg = fmt.Sprintf("Cannot find notification channel with ID %d", params.ID)
+		}
+		log.WithField("channelID", params.ID).WithError(err).Error(msg)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return events.NewUpdateNotificationChannelDefault(code).WithPayload(&rspErr)
+	}
+
+	// Fetch the channel to return its creation time.
+	updated, err := dbmodel.GetNotificationChannel(r.DB, params.ID)
+	if err == nil && updated != nil {
+		dbChannel = updated
+	}
+	return events.NewUpdateNotificationChannelOK().WithPayload(newRestNotificationChannel(*dbChannel))
+}
+
+// Deletes the notification channel with the specified ID.
+func (r *RestAPI) DeleteNotificationChannel(ctx context.Context, params events.DeleteNotificationChannelParams) middleware.Responder {
+	err := dbmodel.DeleteNotificationChannel(r.DB, params.ID)
+	if err != nil {
+		code := http.StatusInternalServerError
+		msg := fmt.Sprintf("Failed to delete notification channel with ID %d", params.ID)
+		if errors.Is(err, dbmodel.ErrNotExists) {
+			code = http.StatusNotFound
+			msg = fmt.Sprintf("Cannot find notification channel with ID %d", params.ID)
+		}
+		log.WithField("channelID", params.ID).WithError(err).Error(msg)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return events.NewDeleteNotificationChannelDefault(code).WithPayload(&rspErr)
+	}
+	return events.NewDeleteNotificationChannelOK()
+}
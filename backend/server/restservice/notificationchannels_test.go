@@ -0,0 +1,166 @@
+package restservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/events"
+	storkutil "isc.org/stork/util"
+)
+
+// Test that the notification channel received over the REST API is
+// validated and converted to the database model.
+func TestNewDBNotificationChannel(t *testing.T) {
+	channel, invalid := newDBNotificationChannel(&models.NotificationChannel{
+		Name:       storkutil.Ptr(" chat "),
+		Type:       storkutil.Ptr("webhook"),
+		URL:        storkutil.Ptr("https://chat.example.org/hooks/1"),
+		Enabled:    true,
+		Level:      storkutil.Ptr(int64(2)),
+		DaemonIds:  []int64{1},
+		MaxRetries: storkutil.Ptr(int64(0)),
+	})
+	require.Empty(t, invalid)
+	require.NotNil(t, channel)
+	require.Equal(t, "chat", channel.Name)
+	require.Equal(t, dbmodel.NotificationChannelTypeWebhook, channel.Type)
+	require.True(t, channel.Enabled)
+	require.Equal(t, dbmodel.EvError, channel.Level)
+	require.Equal(t, []int64{1}, channel.DaemonIDs)
+	require.Zero(t, channel.MaxRetries)
+
+	// Default level and number of retries.
+	channel, invalid = newDBNotificationChannel(&models.NotificationChannel{
+		Name: storkutil.Ptr("chat"),
+		Type: storkutil.Ptr("webhook"),
+		URL:  storkutil.Ptr("http://localhost:8080"),
+	})
+	require.Empty(t, invalid)
+	require.Equal(t, dbmodel.EvInfo, channel.Level)
+	require.EqualValues(t, 5, channel.MaxRetries)
+
+	for _, c := range []*models.NotificationChannel{
+		nil,
+		{Name: storkutil.Ptr(" "), Type: storkutil.Ptr("webhook"), URL: storkutil.Ptr("http://localhost")},
+		{Name: storkutil.Ptr("chat"), Type: storkutil.Ptr("email"), URL: storkutil.Ptr("http://localhost")},
+		{Name: storkutil.Ptr("chat"), Type: storkutil.Ptr("webhook")},
+		{Name: storkutil.Ptr("chat"), Type: storkutil.Ptr("webhook"), URL: storkutil.Ptr("ftp://localhost")},
+		{Name: storkutil.Ptr("chat"), Type: storkutil.Ptr("webhook"), URL: storkutil.Ptr("localhost")},
+		{Name: storkutil.Ptr("chat"), Type: storkutil.Ptr("webhook"), URL: storkutil.Ptr("http://localhost"), BodyTemplate: "{{ .Text "},
+		{Name: storkutil.Ptr("chat"), Type: storkutil.Ptr("webhook"), URL: storkutil.Ptr("http://localhost"), Level: storkutil.Ptr(int64(3))},
+		{Name: storkutil.Ptr("chat"), Type: storkutil.Ptr("webhook"), URL: storkutil.Ptr("http://localhost"), MaxRetries: storkutil.Ptr(int64(-1))},
+		{Name: storkutil.Ptr("chat"), Type: storkutil.Ptr("webhook"), URL: storkutil.Ptr("http://localhost"), MaxRetries: storkutil.Ptr(int64(11))},
+	} {
+		channel, invalid = newDBNotificationChannel(c)
+		require.NotEmpty(t, invalid)
+		require.Nil(t, channel)
+	}
+}
+
+// Test that the notification channels can be created, listed, updated
+// and deleted.
+func TestCreateGetUpdateDeleteNotificationChannel(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	ctx := context.Background()
+	rapi, err := NewRestAPI(dbSettings, db)
+	require.NoError(t, err)
+
+	rsp := rapi.CreateNotificationChannel(ctx, events.CreateNotificationChannelParams{
+		Channel: &models.NotificationChannel{
+			Name:         storkutil.Ptr("chat"),
+			Type:         storkutil.Ptr("webhook"),
+			URL:          storkutil.Ptr("https://chat.example.org/hooks/1"),
+			Enabled:      true,
+			BodyTemplate: `{"text": {{ json .Text }}}`,
+			Level:        storkutil.Ptr(int64(1)),
+			MachineIds:   []int64{1, 2},
+		},
+	})
+	require.IsType(t, &events.CreateNotificationChannelOK{}, rsp)
+	created := rsp.(*events.CreateNotificationChannelOK).Payload
+	require.NotZero(t, created.ID)
+	require.Equal(t, "chat", *created.Name)
+	require.True(t, created.Enabled)
+	require.EqualValues(t, 1, *created.Level)
+	require.EqualValues(t, 5, *created.MaxRetries)
+	require.Equal(t, []int64{1, 2}, created.MachineIds)
+
+	// The name must be unique.
+	rsp = rapi.CreateNotificationChannel(ctx, events.CreateNotificationChannelParams{
+		Channel: &models.NotificationChannel{
+			Name: storkutil.Ptr("chat"),
+			Type: storkutil.Ptr("webhook"),
+			URL:  storkutil.Ptr("https://chat.example.org/hooks/2"),
+		},
+	})
+	require.IsType(t, &events.CreateNotificationChannelDefault{}, rsp)
+	require.Equal(t, http.StatusConflict, getStatusCode(*rsp.(*events.CreateNotificationChannelDefault)))
+
+	// The channel must be valid.
+	rsp = rapi.CreateNotificationChannel(ctx, events.CreateNotificationChannelParams{
+		Channel: &models.NotificationChannel{
+			Name: storkutil.Ptr("tickets"),
+			Type: storkutil.Ptr("webhook"),
+			URL:  storkutil.Ptr("tickets"),
+		},
+	})
+	require.IsType(t, &events.CreateNotificationChannelDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*events.CreateNotificationChannelDefault)))
+
+	rsp = rapi.GetNotificationChannels(ctx, events.GetNotificationChannelsParams{})
+	require.IsType(t, &events.GetNotificationChannelsOK{}, rsp)
+	channels := rsp.(*events.GetNotificationChannelsOK).Payload
+	require.EqualValues(t, 1, channels.Total)
+	require.Len(t, channels.Items, 1)
+	require.Equal(t, created.ID, channels.Items[0].ID)
+
+	rsp = rapi.UpdateNotificationChannel(ctx, events.UpdateNotificationChannelParams{
+		ID: created.ID,
+		Channel: &models.NotificationChannel{
+			Name:    storkutil.Ptr("chat"),
+			Type:    storkutil.Ptr("webhook"),
+			URL:     storkutil.Ptr("https://chat.example.org/hooks/3"),
+			Enabled: false,
+		},
+	})
+	require.IsType(t, &events.UpdateNotificationChannelOK{}, rsp)
+
+	rsp = rapi.GetNotificationChannel(ctx, events.GetNotificationChannelParams{ID: created.ID})
+	require.IsType(t, &events.GetNotificationChannelOK{}, rsp)
+	returned := rsp.(*events.GetNotificationChannelOK).Payload
+	require.Equal(t, "https://chat.example.org/hooks/3", *returned.URL)
+	require.False(t, returned.Enabled)
+	require.Empty(t, returned.BodyTemplate)
+	require.Empty(t, returned.MachineIds)
+	require.EqualValues(t, 0, *returned.Level)
+
+	// Update non-existing channel.
+	rsp = rapi.UpdateNotificationChannel(ctx, events.UpdateNotificationChannelParams{
+		ID: created.ID + 1,
+		Channel: &models.NotificationChannel{
+			Name: storkutil.Ptr("foo"),
+			Type: storkutil.Ptr("webhook"),
+			URL:  storkutil.Ptr("https://chat.example.org/hooks/3"),
+		},
+	})
+	require.IsType(t, &events.UpdateNotificationChannelDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*events.UpdateNotificationChannelDefault)))
+
+	rsp = rapi.DeleteNotificationChannel(ctx, events.DeleteNotificationChannelParams{ID: created.ID})
+	require.IsType(t, &events.DeleteNotificationChannelOK{}, rsp)
+
+	rsp = rapi.DeleteNotificationChannel(ctx, events.DeleteNotificationChannelParams{ID: created.ID})
+	require.IsType(t, &events.DeleteNotificationChannelDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*events.DeleteNotificationChannelDefault)))
+
+	rsp = rapi.GetNotificationChannel(ctx, events.GetNotificationChannelParams{ID: created.ID})
+	require.IsType(t, &events.GetNotificationChannelDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*events.GetNotificationChannelDefault)))
+}
@@ -0,0 +1,192 @@
+package restservice
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"isc.org/stork/server/auth"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Returns the logged user with the permissions granted by its custom
+// groups. The session holds merely the IDs of the groups, so the
+// permissions are fetched from the database. It guarantees that the
+// changes in the group permissions take effect immediately.
+func (r *RestAPI) getLoggedUserWithPermissions(ctx context.Context) (*dbmodel.SystemUser, error) {
+	ok, user := r.SessionManager.Logged(ctx)
+	if !ok {
+		return nil, errors.Errorf("user unauthorized")
+	}
+	if err := dbmodel.PopulateUserPermissions(r.DB, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Checks if the logged user is permitted to perform an operation requiring
+// the specified permission on all specified targets. See auth.AuthorizeTargets.
+func (r *RestAPI) authorizeTargets(ctx context.Context, permission dbmodel.Permission, targets ...dbmodel.PermissionTarget) bool {
+	user, err := r.getLoggedUserWithPermissions(ctx)
+	if err != nil {
+		log.WithError(err).Error("Cannot check the user permissions")
+		return false
+	}
+	return auth.AuthorizeTargets(user, permission, targets...)
+}
+
+// Checks if the logged user is permitted to view an object associated with
+// the specified targets. See auth.AuthorizeView.
+func (r *RestAPI) authorizeView(ctx context.Context, targets ...dbmodel.PermissionTarget) bool {
+	user, err := r.getLoggedUserWithPermissions(ctx)
+	if err != nil {
+		log.WithError(err).Error("Cannot check the user permissions")
+		return false
+	}
+	return auth.AuthorizeView(user, targets...)
+}
+
+// Checks if the logged user belongs to the super-admin group or to a custom
+// group granting the specified permission. It is used to guard the operations
+// that are not available to the admin group.
+func (r *RestAPI) isSuperAdminOrPermitted(ctx context.Context, permission dbmodel.Permission) bool {
+	user, err := r.getLoggedUserWithPermissions(ctx)
+	if err != nil {
+		log.WithError(err).Error("Cannot check the user permissions")
+		return false
+	}
+	return user.InGroup(&dbmodel.SystemGroup{ID: dbmodel.SuperAdminGroupID}) || user.HasPermission(permission)
+}
+
+// Returns the logged user if the user's view permission is limited to
+// specific apps, daemons or subnets. Otherwise, it returns nil, meaning
+// that the user is permitted to view all objects. See auth.IsViewScoped.
+func (r *RestAPI) getScopedViewer(ctx context.Context) (*dbmodel.SystemUser, error) {
+	user, err := r.getLoggedUserWithPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !auth.IsViewScoped(user) {
+		return nil, nil
+	}
+	return user, nil
+}
+
+// Returns a permission target for a daemon and optionally a subnet.
+func newPermissionTarget(daemonID int64, daemon *dbmodel.Daemon, subnetID int64) dbmodel.PermissionTarget {
+	target := dbmodel.PermissionTarget{
+		DaemonID: daemonID,
+		SubnetID: subnetID,
+	}
+	if daemon != nil {
+		target.AppID = daemon.AppID
+	}
+	return target
+}
+
+// Returns the permission targets for a host reservation. Each daemon
+// owning the reservation is a separate target.
+func getHostPermissionTargets(host *dbmodel.Host) (targets []dbmodel.PermissionTarget) {
+	for _, lh := range host.LocalHosts {
+		targets = append(targets, newPermissionTarget(lh.DaemonID, lh.Daemon, host.SubnetID))
+	}
+	return
+}
+
+// Returns the permission targets for a subnet. Each daemon owning the
+// subnet is a separate target.
+func getSubnetPermissionTargets(subnet *dbmodel.Subnet) (targets []dbmodel.PermissionTarget) {
+	for _, ls := range subnet.LocalSubnets {
+		targets = append(targets, newPermissionTarget(ls.DaemonID, ls.Daemon, subnet.ID))
+	}
+	return
+}
+
+// Returns the permission targets for a shared network. Each daemon owning
+// the shared network is a separate target.
+func getSharedNetworkPermissionTargets(sharedNetwork *dbmodel.SharedNetwork) (targets []dbmodel.PermissionTarget) {
+	for _, lsn := range sharedNetwork.LocalSharedNetworks {
+		targets = append(targets, newPermissionTarget(lsn.DaemonID, lsn.Daemon, 0))
+	}
+	return
+}
+
+// Returns the permission target for a client class. The class belongs to
+// a single daemon.
+func getClientClassPermissionTarget(class *dbmodel.ClientClass) dbmodel.PermissionTarget {
+	return newPermissionTarget(class.DaemonID, class.Daemon, 0)
+}
+
+// Checks if the logged user is permitted to modify the host reservation.
+// In case of updating an existing reservation, the user must be permitted
+// to modify it on the daemons currently owning it and on the daemons it
+// is going to be assigned to. It returns the HTTP error code and the error
+// message if the access is denied or 0 and an empty string otherwise.
+func (r *RestAPI) authorizeHostChange(ctx context.Context, host *dbmodel.Host) (int, string) {
+	targets := getHostPermissionTargets(host)
+	if host.ID != 0 {
+		existingHost, err := dbmodel.GetHost(r.DB, host.ID)
+		if err != nil {
+			msg := "Problem with fetching the host reservation to check user permissions"
+			log.WithError(err).Error(msg)
+			return http.StatusInternalServerError, msg
+		}
+		if existingHost != nil {
+			targets = append(targets, getHostPermissionTargets(existingHost)...)
+		}
+	}
+	if !r.authorizeTargets(ctx, dbmodel.PermissionManageHosts, targets...) {
+		return http.StatusForbidden, "User is forbidden to modify the host reservation on the selected servers"
+	}
+	return 0, ""
+}
+
+// Checks if the logged user is permitted to modify the subnet. In case of
+// updating an existing subnet, the user must be permitted to modify it on
+// the daemons currently owning it and on the daemons it is going to be
+// assigned to. It returns the HTTP error code and the error message if the
+// access is denied or 0 and an empty string otherwise.
+func (r *RestAPI) authorizeSubnetChange(ctx context.Context, subnet *dbmodel.Subnet) (int, string) {
+	targets := getSubnetPermissionTargets(subnet)
+	if subnet.ID != 0 {
+		existingSubnet, err := dbmodel.GetSubnet(r.DB, subnet.ID)
+		if err != nil {
+			msg := "Problem with fetching the subnet to check user permissions"
+			log.WithError(err).Error(msg)
+			return http.StatusInternalServerError, msg
+		}
+		if existingSubnet != nil {
+			targets = append(targets, getSubnetPermissionTargets(existingSubnet)...)
+		}
+	}
+	if !r.authorizeTargets(ctx, dbmodel.PermissionManageSubnets, targets...) {
+		return http.StatusForbidden, "User is forbidden to modify the subnet on the selected servers"
+	}
+	return 0, ""
+}
+
+// Checks if the logged user is permitted to modify the shared network. In
+// case of updating an existing shared network, the user must be permitted
+// to modify it on the daemons currently owning it and on the daemons it is
+// going to be assigned to. It returns the HTTP error code and the error
+// message if the access is denied or 0 and an empty string otherwise.
+func (r *RestAPI) authorizeSharedNetworkChange(ctx context.Context, sharedNetwork *dbmodel.SharedNetwork) (int, string) {
+	targets := getSharedNetworkPermissionTargets(sharedNetwork)
+	if sharedNetwork.ID != 0 {
+		existingSharedNetwork, err := dbmodel.GetSharedNetwork(r.DB, sharedNetwork.ID)
+		if err != nil {
+			msg := "Problem with fetching the shared network to check user permissions"
+			log.WithError(err).Error(msg)
+			return http.StatusInternalServerError, msg
+		}
+		if existingSharedNetwork != nil {
+			targets = append(targets, getSharedNetworkPermissionTargets(existingSharedNetwork)...)
+		}
+	}
+	if !r.authorizeTargets(ctx, dbmodel.PermissionManageSubnets, targets...) {
+		return http.StatusForbidden, "User is forbidden to modify the shared network on the selected servers"
+	}
+	return 0, ""
+}
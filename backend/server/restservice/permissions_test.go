@@ -0,0 +1,217 @@
+package restservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	apps "isc.org/stork/server/apps"
+	appstest "isc.org/stork/server/apps/test"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
+	storktestdbmodel "isc.org/stork/server/test/dbmodel"
+	storkutil "isc.org/stork/util"
+)
+
+// Test that the host reservation can be deleted only by the user whose
+// custom group is permitted to manage the hosts on all daemons owning the
+// reservation.
+func TestDeleteHostScopedPermission(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	lookup := dbmodel.NewDHCPOptionDefinitionLookup()
+	cm := apps.NewManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    fa,
+		DefLookup: lookup,
+	})
+	rapi, err := NewRestAPI(dbSettings, db, fa, cm, lookup)
+	require.NoError(t, err)
+
+	hosts, _ := storktestdbmodel.AddTestHosts(t, db)
+	host, err := dbmodel.GetHost(db, hosts[0].ID)
+	require.NoError(t, err)
+	require.Len(t, host.LocalHosts, 2)
+
+	// The group permits managing the hosts on one of the daemons only.
+	group := &dbmodel.SystemGroup{
+		Name: "operators",
+		Permissions: []*dbmodel.SystemGroupPermission{
+			{Permission: dbmodel.PermissionManageHosts, DaemonID: host.LocalHosts[0].DaemonID},
+		},
+	}
+	_, err = dbmodel.AddGroup(db, group)
+	require.NoError(t, err)
+
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
+	user := &dbmodel.SystemUser{
+		ID:     1234,
+		Groups: []*dbmodel.SystemGroup{group},
+	}
+	err = rapi.SessionManager.LoginHandler(ctx, user)
+	require.NoError(t, err)
+
+	params := dhcp.DeleteHostParams{
+		ID: host.ID,
+	}
+	rsp := rapi.DeleteHost(ctx, params)
+	require.IsType(t, &dhcp.DeleteHostDefault{}, rsp)
+	defaultRsp := rsp.(*dhcp.DeleteHostDefault)
+	require.Equal(t, http.StatusForbidden, getStatusCode(*defaultRsp))
+	require.Empty(t, fa.RecordedCommands)
+
+	// Extend the permission to the other daemon.
+	group.Permissions = append(group.Permissions, &dbmodel.SystemGroupPermission{
+		Permission: dbmodel.PermissionManageHosts,
+		DaemonID:   host.LocalHosts[1].DaemonID,
+	})
+	_, err = dbmodel.UpdateGroup(db, group)
+	require.NoError(t, err)
+
+	rsp = rapi.DeleteHost(ctx, params)
+	require.IsType(t, &dhcp.DeleteHostOK{}, rsp)
+	require.Len(t, fa.RecordedCommands, 2)
+}
+
+// Test that the permission targets are collected from all daemons owning
+// the host reservation.
+func TestGetHostPermissionTargets(t *testing.T) {
+	host := &dbmodel.Host{
+		SubnetID: 3,
+		LocalHosts: []dbmodel.LocalHost{
+			{
+				DaemonID: 1,
+				Daemon:   &dbmodel.Daemon{ID: 1, AppID: 10},
+			},
+			{
+				DaemonID: 2,
+			},
+		},
+	}
+	targets := getHostPermissionTargets(host)
+	require.Len(t, targets, 2)
+	require.Equal(t, dbmodel.PermissionTarget{AppID: 10, DaemonID: 1, SubnetID: 3}, targets[0])
+	require.Equal(t, dbmodel.PermissionTarget{DaemonID: 2, SubnetID: 3}, targets[1])
+}
+
+// Test that the read-only user cannot delete a host reservation even when
+// the request bypasses the authorizer.
+func TestDeleteHostReadOnly(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	lookup := dbmodel.NewDHCPOptionDefinitionLookup()
+	cm := apps.NewManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    fa,
+		DefLookup: lookup,
+	})
+	rapi, err := NewRestAPI(dbSettings, db, fa, cm, lookup)
+	require.NoError(t, err)
+
+	hosts, _ := storktestdbmodel.AddTestHosts(t, db)
+
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
+	user := &dbmodel.SystemUser{
+		ID:     1234,
+		Groups: []*dbmodel.SystemGroup{{ID: dbmodel.ReadOnlyGroupID}},
+	}
+	err = rapi.SessionManager.LoginHandler(ctx, user)
+	require.NoError(t, err)
+
+	rsp := rapi.DeleteHost(ctx, dhcp.DeleteHostParams{
+		ID: hosts[0].ID,
+	})
+	require.IsType(t, &dhcp.DeleteHostDefault{}, rsp)
+	defaultRsp := rsp.(*dhcp.DeleteHostDefault)
+	require.Equal(t, http.StatusForbidden, getStatusCode(*defaultRsp))
+	require.Empty(t, fa.RecordedCommands)
+}
+
+// Returns the context with the session of the user belonging to the
+// specified groups.
+func newTestUserContext(t *testing.T, rapi *RestAPI, groups ...*dbmodel.SystemGroup) context.Context {
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
+	user := &dbmodel.SystemUser{
+		ID:     1234,
+		Groups: groups,
+	}
+	err = rapi.SessionManager.LoginHandler(ctx, user)
+	require.NoError(t, err)
+	return ctx
+}
+
+// Test that the user whose view permission is scoped to a daemon can only
+// view the subnets and host reservations of this daemon.
+func TestGetSubnetsAndHostsScopedViewer(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	rapi, err := NewRestAPI(dbSettings, db, dbmodel.NewDHCPOptionDefinitionLookup())
+	require.NoError(t, err)
+
+	hosts, apps := storktestdbmodel.AddTestHosts(t, db)
+	daemonID := apps[0].Daemons[0].ID
+
+	group := &dbmodel.SystemGroup{
+		Name: "viewers",
+		Permissions: []*dbmodel.SystemGroupPermission{
+			{Permission: dbmodel.PermissionView, DaemonID: daemonID},
+		},
+	}
+	_, err = dbmodel.AddGroup(db, group)
+	require.NoError(t, err)
+	ctx := newTestUserContext(t, rapi, group)
+
+	// Only the DHCPv4 subnet belongs to the daemon.
+	rsp := rapi.GetSubnets(ctx, dhcp.GetSubnetsParams{})
+	require.IsType(t, &dhcp.GetSubnetsOK{}, rsp)
+	subnets := rsp.(*dhcp.GetSubnetsOK).Payload
+	require.EqualValues(t, 1, subnets.Total)
+	require.Len(t, subnets.Items, 1)
+	require.Equal(t, "192.0.2.0/24", subnets.Items[0].Subnet)
+
+	rsp = rapi.GetSubnet(ctx, dhcp.GetSubnetParams{ID: subnets.Items[0].ID})
+	require.IsType(t, &dhcp.GetSubnetOK{}, rsp)
+
+	allSubnets, err := dbmodel.GetSubnetsByPrefix(db, "2001:db8:1::/64")
+	require.NoError(t, err)
+	require.Len(t, allSubnets, 1)
+	rsp = rapi.GetSubnet(ctx, dhcp.GetSubnetParams{ID: allSubnets[0].ID})
+	require.IsType(t, &dhcp.GetSubnetDefault{}, rsp)
+	require.Equal(t, http.StatusForbidden, getStatusCode(*rsp.(*dhcp.GetSubnetDefault)))
+
+	// The first two hosts belong to the daemon. The page is selected from
+	// the permitted hosts.
+	rsp = rapi.GetHosts(ctx, dhcp.GetHostsParams{
+		Start: storkutil.Ptr(int64(1)),
+		Limit: storkutil.Ptr(int64(10)),
+	})
+	require.IsType(t, &dhcp.GetHostsOK{}, rsp)
+	hostsPage := rsp.(*dhcp.GetHostsOK).Payload
+	require.EqualValues(t, 2, hostsPage.Total)
+	require.Len(t, hostsPage.Items, 1)
+	require.Equal(t, hosts[1].ID, hostsPage.Items[0].ID)
+
+	rsp = rapi.GetHost(ctx, dhcp.GetHostParams{ID: hosts[0].ID})
+	require.IsType(t, &dhcp.GetHostOK{}, rsp)
+
+	rsp = rapi.GetHost(ctx, dhcp.GetHostParams{ID: hosts[2].ID})
+	require.IsType(t, &dhcp.GetHostDefault{}, rsp)
+	require.Equal(t, http.StatusForbidden, getStatusCode(*rsp.(*dhcp.GetHostDefault)))
+
+	// The unscoped view permission gives access to all objects.
+	ctx = newTestUserContext(t, rapi, &dbmodel.SystemGroup{ID: dbmodel.ReadOnlyGroupID})
+	rsp = rapi.GetHosts(ctx, dhcp.GetHostsParams{})
+	require.IsType(t, &dhcp.GetHostsOK{}, rsp)
+	require.EqualValues(t, 5, rsp.(*dhcp.GetHostsOK).Payload.Total)
+}
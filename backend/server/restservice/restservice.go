@@ -28,6 +28,7 @@ import (
 	keaconfig "isc.org/stork/appcfg/kea"
 	"isc.org/stork/server/agentcomm"
 	"isc.org/stork/server/apps"
+	"isc.org/stork/server/apps/kea"
 	"isc.org/stork/server/config"
 	"isc.org/stork/server/configreview"
 	dbops "isc.org/stork/server/database"
@@ -37,6 +38,7 @@ import (
 	"isc.org/stork/server/gen/restapi/operations"
 	"isc.org/stork/server/hookmanager"
 	"isc.org/stork/server/metrics"
+	"isc.org/stork/server/notifications"
 	storkutil "isc.org/stork/util"
 )
 
@@ -60,6 +62,7 @@ type RestAPISettings struct {
 
 	StaticFilesDir string `long:"rest-static-files-dir" description:"The directory with static files for the UI" default:"" env:"STORK_REST_STATIC_FILES_DIR"`
 	BaseURL        string `long:"rest-base-url" description:"The base URL of the UI. Specify this flag if the UI is served from a subdirectory (not the root URL). It must start and end with a slash. Example: https://www.example.com/admin/stork/ would need to have '/admin/stork/' as the rest-base-url" default:"/" env:"STORK_REST_BASE_URL"`
+	TrustedProxies string `long:"rest-trusted-proxies" description:"A comma-separated list of the IP addresses or prefixes of the reverse proxies allowed to set the X-Forwarded-Proto and X-Forwarded-Host headers. The headers from the other clients are ignored" default:"" env:"STORK_REST_TRUSTED_PROXIES"`
 }
 
 // Runtime information and settings for RestAPI service.
@@ -76,6 +79,8 @@ type RestAPI struct {
 	DHCPOptionDefinitionLookup keaconfig.DHCPOptionDefinitionLookup
 	HookManager                *hookmanager.HookManager
 	EndpointControl            *EndpointControl
+	DeclinedLeasesReleaser     *kea.DeclinedLeasesReleaser
+	Notifier                   notifications.Notifier
 
 	Agents agentcomm.ConnectedAgents
 
@@ -103,7 +108,8 @@ type RestAPI struct {
 // - *dbops.DatabaseSettings,
 // - *pg.DB,
 // - *apps.Pullers,
-// - *EndpointControl
+// - *EndpointControl,
+// - *kea.DeclinedLeasesReleaser
 //
 // Accepted interfaces:
 // - agentcomm.ConnectedAgents,
@@ -169,6 +175,10 @@ func NewRestAPI(args ...interface{}) (*RestAPI, error) {
 			api.ConfigManager = arg.(config.Manager)
 			continue
 		}
+		if argType.Implements(reflect.TypeOf((*notifications.Notifier)(nil)).Elem()) {
+			api.Notifier = arg.(notifications.Notifier)
+			continue
+		}
 		if argType.Implements(reflect.TypeOf((*keaconfig.DHCPOptionDefinitionLookup)(nil)).Elem()) {
 			api.DHCPOptionDefinitionLookup = arg.(keaconfig.DHCPOptionDefinitionLookup)
 			continue
@@ -199,6 +209,10 @@ func NewRestAPI(args ...interface{}) (*RestAPI, error) {
 			api.EndpointControl = arg.(*EndpointControl)
 			continue
 		}
+		if argType.AssignableTo(reflect.TypeOf((*kea.DeclinedLeasesReleaser)(nil))) {
+			api.DeclinedLeasesReleaser = arg.(*kea.DeclinedLeasesReleaser)
+			continue
+		}
 		return nil, pkgerrors.Errorf("unknown argument type %s specified for NewRestAPI", argType.Elem().Name())
 	}
 
@@ -443,6 +457,8 @@ func (r *RestAPI) Serve() (err error) {
 		SettingsAPI:     r,
 		SearchAPI:       r,
 		EventsAPI:       r,
+		AuditAPI:        r,
+		DNSAPI:          r,
 		Logger:          log.Infof,
 		InnerMiddleware: r.InnerMiddleware,
 		Authorizer:      r.Authorizer,
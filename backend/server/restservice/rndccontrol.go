@@ -0,0 +1,89 @@
+package restservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-openapi/runtime/middleware"
+	log "github.com/sirupsen/logrus"
+
+	bind9ctrl "isc.org/stork/appctrl/bind9"
+	"isc.org/stork/server/apps/bind9"
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/dns"
+)
+
+// Records the outcome of the rndc command in the events.
+func (r *RestAPI) recordRndcCommand(ctx context.Context, command string, daemon *dbmodel.Daemon, commandErr error) {
+	if r.EventCenter == nil {
+		return
+	}
+	_, user := r.SessionManager.Logged(ctx)
+	if commandErr != nil {
+		r.EventCenter.AddErrorEvent(fmt.Sprintf("{user} failed to run rndc %s command in {daemon}", command), user, daemon, commandErr)
+		return
+	}
+	r.EventCenter.AddInfoEvent(fmt.Sprintf("{user} ran rndc %s command in {daemon}", command), user, daemon)
+}
+
+// Implements the POST call running an allowed rndc command on a BIND 9
+// daemon (daemons/{id}/rndc-commands).
+func (r *RestAPI) RunRndcCommand(ctx context.Context, params dns.RunRndcCommandParams) middleware.Responder {
+	request := params.Command
+	if request == nil || request.Command == nil {
+		msg := "Command is required to run the rndc command"
+		log.Error(msg)
+		return dns.NewRunRndcCommandDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if _, err := bind9ctrl.ParseRndcCommand(*request.Command); err != nil {
+		msg := fmt.Sprintf("Invalid rndc command: %s", err)
+		log.Error(msg)
+		return dns.NewRunRndcCommandDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	daemon, err := dbmodel.GetDaemonByID(r.DB, params.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Problem with fetching daemon %d from the database", params.ID)
+		log.WithError(err).Error(msg)
+		return dns.NewRunRndcCommandDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if daemon == nil {
+		msg := fmt.Sprintf("Cannot find daemon with ID %d", params.ID)
+		log.Error(msg)
+		return dns.NewRunRndcCommandDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if daemon.Name != dbmodel.DaemonNameBind9 {
+		msg := fmt.Sprintf("Daemon %d is not a BIND 9 daemon", params.ID)
+		log.Error(msg)
+		return dns.NewRunRndcCommandDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	if !r.authorizeTargets(ctx, dbmodel.PermissionManageMachines, newPermissionTarget(daemon.ID, daemon, 0)) {
+		msg := "User is forbidden to run rndc commands on the selected server"
+		return dns.NewRunRndcCommandDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	output, err := bind9.RunRndcCommand(ctx, r.Agents, daemon, *request.Command)
+	r.recordRndcCommand(ctx, *request.Command, daemon, err)
+	if err != nil {
+		msg := fmt.Sprintf("Problem with running the rndc command: %s", err)
+		log.WithError(err).Error(msg)
+		return dns.NewRunRndcCommandDefault(http.StatusConflict).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+	}
+	return dns.NewRunRndcCommandOK().WithPayload(&models.RndcCommandResult{
+		Output: output,
+	})
+}
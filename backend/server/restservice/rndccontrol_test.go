@@ -0,0 +1,123 @@
+package restservice
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-pg/pg/v10"
+	require "github.com/stretchr/testify/require"
+
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/dns"
+	storkutil "isc.org/stork/util"
+)
+
+// Adds a BIND 9 app with the control access point.
+func addTestRndcApp(t *testing.T, db *pg.DB) *dbmodel.App {
+	machine := &dbmodel.Machine{
+		Address:   "dns.example.org",
+		AgentPort: 8080,
+	}
+	require.NoError(t, dbmodel.AddMachine(db, machine))
+
+	accessPoints := []*dbmodel.AccessPoint{}
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "127.0.0.1", "abcd", 953, false)
+	app := &dbmodel.App{
+		Type:         dbmodel.AppTypeBind9,
+		Name:         "dns1",
+		MachineID:    machine.ID,
+		AccessPoints: accessPoints,
+		Daemons: []*dbmodel.Daemon{
+			dbmodel.NewBind9Daemon(true),
+		},
+	}
+	_, err := dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+	return app
+}
+
+// Test that the rndc command is sent to the BIND 9 daemon and that the
+// outcome is recorded in the events.
+func TestRunRndcCommand(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewBind9FakeAgents(nil, func(command string) string {
+		return "zone reload queued"
+	})
+	rapi, ctx, fec := newTestHAControlRestAPI(t, db, dbSettings, agents)
+	app := addTestRndcApp(t, db)
+
+	rsp := rapi.RunRndcCommand(ctx, dns.RunRndcCommandParams{
+		ID: app.Daemons[0].ID,
+		Command: &models.RndcCommandRequest{
+			Command: storkutil.Ptr("reload example.org"),
+		},
+	})
+	require.IsType(t, &dns.RunRndcCommandOK{}, rsp)
+	require.Equal(t, "zone reload queued", rsp.(*dns.RunRndcCommandOK).Payload.Output)
+	require.Equal(t, []string{"reload example.org"}, agents.RecordedRndcCommands)
+
+	require.Len(t, fec.Events, 1)
+	require.Equal(t, dbmodel.EvInfo, fec.Events[0].Level)
+	require.Contains(t, fec.Events[0].Text, "ran rndc reload example.org command")
+}
+
+// Test that the rndc commands not in the allow-list are rejected and not
+// sent to the daemon.
+func TestRunRndcCommandNotAllowed(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewBind9FakeAgents(nil, nil)
+	rapi, ctx, fec := newTestHAControlRestAPI(t, db, dbSettings, agents)
+	app := addTestRndcApp(t, db)
+
+	for _, command := range []string{"stop", "halt -p", "reload", "flushname www.example.org;rm"} {
+		rsp := rapi.RunRndcCommand(ctx, dns.RunRndcCommandParams{
+			ID: app.Daemons[0].ID,
+			Command: &models.RndcCommandRequest{
+				Command: storkutil.Ptr(command),
+			},
+		})
+		require.IsType(t, &dns.RunRndcCommandDefault{}, rsp, command)
+		require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*dns.RunRndcCommandDefault)))
+	}
+	require.Empty(t, agents.RecordedRndcCommands)
+	require.Empty(t, fec.Events)
+}
+
+// Test that the rndc command is rejected when the daemon does not exist
+// or it is not a BIND 9 daemon.
+func TestRunRndcCommandInvalidTarget(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewBind9FakeAgents(nil, nil)
+	rapi, ctx, _ := newTestHAControlRestAPI(t, db, dbSettings, agents)
+	app := addTestRndcApp(t, db)
+	keaApp, _ := addTestHAService(t, db)
+
+	rsp := rapi.RunRndcCommand(ctx, dns.RunRndcCommandParams{
+		ID: app.Daemons[0].ID + keaApp.Daemons[0].ID,
+		Command: &models.RndcCommandRequest{
+			Command: storkutil.Ptr("flush"),
+		},
+	})
+	require.IsType(t, &dns.RunRndcCommandDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*dns.RunRndcCommandDefault)))
+
+	rsp = rapi.RunRndcCommand(ctx, dns.RunRndcCommandParams{
+		ID: keaApp.Daemons[0].ID,
+		Command: &models.RndcCommandRequest{
+			Command: storkutil.Ptr("flush"),
+		},
+	})
+	require.IsType(t, &dns.RunRndcCommandDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*dns.RunRndcCommandDefault)))
+
+	require.Empty(t, agents.RecordedRndcCommands)
+}
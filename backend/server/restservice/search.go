@@ -43,7 +43,7 @@ func (r *RestAPI) SearchRecords(ctx context.Context, params search.SearchRecords
 	filters := &dbmodel.SubnetsByPageFilters{Text: &text}
 
 	// get list of subnets
-	subnets, err := r.getSubnets(0, 5, filters, "", dbmodel.SortDirAny)
+	subnets, err := r.getSubnets(nil, 0, 5, filters, "", dbmodel.SortDirAny)
 	if err != nil {
 		return handleSearchError(err, "Cannot get subnets from the db")
 	}
@@ -55,7 +55,7 @@ func (r *RestAPI) SearchRecords(ctx context.Context, params search.SearchRecords
 	}
 
 	// get list of hosts
-	hosts, err := r.getHosts(0, 5, dbmodel.HostsByPageFilters{FilterText: &text}, "", dbmodel.SortDirAny)
+	hosts, err := r.getHosts(nil, 0, 5, dbmodel.HostsByPageFilters{FilterText: &text}, "", dbmodel.SortDirAny)
 	if err != nil {
 		return handleSearchError(err, "Cannot get hosts from the db")
 	}
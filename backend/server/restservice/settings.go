@@ -34,6 +34,23 @@ func (r *RestAPI) GetSettings(ctx context.Context, params settings.GetSettingsPa
 		PrometheusURL:             dbSettingsMap["prometheus_url"].(string),
 		EnableMachineRegistration: dbSettingsMap["enable_machine_registration"].(bool),
 	}
+	// The utilization alert settings are optional in the update request,
+	// so they are pointers.
+	if val, ok := dbSettingsMap["utilization_warning_threshold"].(int64); ok {
+		s.UtilizationWarningThreshold = &val
+	}
+	if val, ok := dbSettingsMap["utilization_error_threshold"].(int64); ok {
+		s.UtilizationErrorThreshold = &val
+	}
+	if val, ok := dbSettingsMap["utilization_alert_hysteresis"].(int64); ok {
+		s.UtilizationAlertHysteresis = &val
+	}
+	if val, ok := dbSettingsMap["exhaustion_forecast_window"].(int64); ok {
+		s.ExhaustionForecastWindow = &val
+	}
+	if val, ok := dbSettingsMap["zone_serial_lag_threshold"].(int64); ok {
+		s.ZoneSerialLagThreshold = &val
+	}
 	rsp := settings.NewGetSettingsOK().WithPayload(s)
 
 	return rsp
@@ -98,6 +115,25 @@ func (r *RestAPI) UpdateSettings(ctx context.Context, params settings.UpdateSett
 	}
 	r.EndpointControl.SetEnabled(EndpointOpCreateNewMachine, s.EnableMachineRegistration)
 
+	// The utilization alert, exhaustion forecast and zone replication
+	// settings are updated only if specified.
+	for name, val := range map[string]*int64{
+		"utilization_warning_threshold": s.UtilizationWarningThreshold,
+		"utilization_error_threshold":   s.UtilizationErrorThreshold,
+		"utilization_alert_hysteresis":  s.UtilizationAlertHysteresis,
+		"exhaustion_forecast_window":    s.ExhaustionForecastWindow,
+		"zone_serial_lag_threshold":     s.ZoneSerialLagThreshold,
+	} {
+		if val == nil {
+			continue
+		}
+		err = dbmodel.SetSettingInt(r.DB, name, *val)
+		if err != nil {
+			log.Error(err)
+			return errRsp
+		}
+	}
+
 	rsp := settings.NewUpdateSettingsOK()
 	return rsp
 }
@@ -11,6 +11,7 @@ import (
 	"isc.org/stork/server/gen/models"
 	"isc.org/stork/server/gen/restapi/operations/settings"
 	storktest "isc.org/stork/server/test/dbmodel"
+	storkutil "isc.org/stork/util"
 )
 
 // Check getting and setting global settings via rest api functions.
@@ -39,18 +40,27 @@ func TestSettings(t *testing.T) {
 	okRsp := rsp.(*settings.GetSettingsOK)
 	require.EqualValues(t, 60, okRsp.Payload.Bind9StatsPullerInterval)
 	require.Empty(t, okRsp.Payload.GrafanaURL)
+	require.EqualValues(t, 80, *okRsp.Payload.UtilizationWarningThreshold)
+	require.EqualValues(t, 90, *okRsp.Payload.UtilizationErrorThreshold)
+	require.EqualValues(t, 5, *okRsp.Payload.UtilizationAlertHysteresis)
+	require.EqualValues(t, 30, *okRsp.Payload.ExhaustionForecastWindow)
+	require.EqualValues(t, 60, *okRsp.Payload.ZoneSerialLagThreshold)
 
 	// Update settings.
 	paramsUS := settings.UpdateSettingsParams{
 		Settings: &models.Settings{
-			Bind9StatsPullerInterval:  1,
-			AppsStatePullerInterval:   2,
-			KeaHostsPullerInterval:    3,
-			KeaStatsPullerInterval:    4,
-			KeaStatusPullerInterval:   5,
-			GrafanaURL:                "http://foo:3000",
-			PrometheusURL:             "http://bar:3000",
-			EnableMachineRegistration: false,
+			Bind9StatsPullerInterval:    1,
+			AppsStatePullerInterval:     2,
+			KeaHostsPullerInterval:      3,
+			KeaStatsPullerInterval:      4,
+			KeaStatusPullerInterval:     5,
+			GrafanaURL:                  "http://foo:3000",
+			PrometheusURL:               "http://bar:3000",
+			EnableMachineRegistration:   false,
+			UtilizationWarningThreshold: storkutil.Ptr[int64](70),
+			UtilizationErrorThreshold:   storkutil.Ptr[int64](85),
+			ExhaustionForecastWindow:    storkutil.Ptr[int64](60),
+			ZoneSerialLagThreshold:      storkutil.Ptr[int64](15),
 		},
 	}
 	rsp = rapi.UpdateSettings(ctx, paramsUS)
@@ -72,4 +82,11 @@ func TestSettings(t *testing.T) {
 	require.EqualValues(t, "http://bar:3000", okRsp.Payload.PrometheusURL)
 
 	require.False(t, okRsp.Payload.EnableMachineRegistration)
+
+	// The unspecified hysteresis should not be modified.
+	require.EqualValues(t, 70, *okRsp.Payload.UtilizationWarningThreshold)
+	require.EqualValues(t, 85, *okRsp.Payload.UtilizationErrorThreshold)
+	require.EqualValues(t, 5, *okRsp.Payload.UtilizationAlertHysteresis)
+	require.EqualValues(t, 60, *okRsp.Payload.ExhaustionForecastWindow)
+	require.EqualValues(t, 15, *okRsp.Payload.ZoneSerialLagThreshold)
 }
@@ -109,6 +109,8 @@ func (r *RestAPI) convertSharedNetworkToRestAPI(sn *dbmodel.SharedNetwork) *mode
 		PdUtilization:    float64(sn.PdUtilization) / 10,
 		Stats:            sn.Stats,
 		StatsCollectedAt: convertToOptionalDatetime(sn.StatsCollectedAt),
+		AddrExhaustionAt: convertToOptionalDatetime(sn.AddrExhaustionAt),
+		PdExhaustionAt:   convertToOptionalDatetime(sn.PdExhaustionAt),
 	}
 
 	for _, lsn := range sn.LocalSharedNetworks {
@@ -388,6 +390,10 @@ func (r *RestAPI) commonCreateOrUpdateSharedNetworkSubmit(ctx context.Context, t
 		log.WithError(err).Error(msg)
 		return http.StatusNotFound, 0, msg
 	}
+	// Make sure the user is permitted to modify the shared network on these daemons.
+	if code, msg := r.authorizeSharedNetworkChange(ctx, sharedNetwork); code != 0 {
+		return code, 0, msg
+	}
 	// Apply the shared network information (create Kea commands).
 	cctx, err = applyFunc(cctx, sharedNetwork)
 	if err != nil {
@@ -671,6 +677,14 @@ func (r *RestAPI) DeleteSharedNetwork(ctx context.Context, params dhcp.DeleteSha
 		})
 		return rsp
 	}
+	// Make sure the user is permitted to delete the shared network from its daemons.
+	if !r.authorizeTargets(ctx, dbmodel.PermissionManageSubnets, getSharedNetworkPermissionTargets(dbSharedNetwork)...) {
+		msg := fmt.Sprintf("User is forbidden to delete shared network with ID %d", params.ID)
+		rsp := dhcp.NewDeleteSharedNetworkDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
 	// Create configuration context.
 	_, user := r.SessionManager.Logged(ctx)
 	cctx, err := r.ConfigManager.CreateContext(int64(user.ID))
@@ -89,6 +89,8 @@ func (r *RestAPI) convertSubnetToRestAPI(sn *dbmodel.Subnet) *models.Subnet {
 		PdUtilization:    float64(sn.PdUtilization) / 10,
 		Stats:            sn.Stats,
 		StatsCollectedAt: convertToOptionalDatetime(sn.StatsCollectedAt),
+		AddrExhaustionAt: convertToOptionalDatetime(sn.AddrExhaustionAt),
+		PdExhaustionAt:   convertToOptionalDatetime(sn.PdExhaustionAt),
 	}
 
 	if sn.SharedNetwork != nil {
@@ -136,6 +138,10 @@ func (r *RestAPI) convertSubnetToRestAPI(sn *dbmodel.Subnet) *models.Subnet {
 				Prefix:          &prefix,
 				DelegatedLength: &delegatedLength,
 				ExcludedPrefix:  prefixPoolDetails.ExcludedPrefix,
+				PdExhaustionAt:  convertToOptionalDatetime(prefixPoolDetails.PdExhaustionAt),
+			}
+			if !prefixPoolDetails.StatsCollectedAt.IsZero() {
+				pool.PdUtilization = storkutil.Ptr(float64(prefixPoolDetails.PdUtilization) / 10)
 			}
 			localSubnet.PrefixDelegationPools = append(localSubnet.PrefixDelegationPools, pool)
 			if prefixPoolDetails.KeaParameters != nil {
@@ -422,8 +428,16 @@ func (r *RestAPI) convertSubnetFromRestAPI(restSubnet *models.Subnet) (*dbmodel.
 	return subnet, nil
 }
 
-func (r *RestAPI) getSubnets(offset, limit int64, filters *dbmodel.SubnetsByPageFilters, sortField string, sortDir dbmodel.SortDirEnum) (*models.Subnets, error) {
-	// get subnets from db
+// Fetches the subnets from the database and converts them to the REST API
+// format. If the viewer is specified, only the subnets the viewer is
+// permitted to view are returned.
+func (r *RestAPI) getSubnets(viewer *dbmodel.SystemUser, offset, limit int64, filters *dbmodel.SubnetsByPageFilters, sortField string, sortDir dbmodel.SortDirEnum) (*models.Subnets, error) {
+	if viewer != nil {
+		if filters == nil {
+			filters = &dbmodel.SubnetsByPageFilters{}
+		}
+		filters.PermissionScopes = viewer.GetPermissionScopes(dbmodel.PermissionView)
+	}
 	dbSubnets, total, err := dbmodel.GetSubnetsByPage(r.DB, offset, limit, filters, sortField, sortDir)
 	if err != nil {
 		return nil, err
@@ -464,7 +478,17 @@ func (r *RestAPI) GetSubnets(ctx context.Context, params dhcp.GetSubnetsParams)
 		LocalSubnetID: params.LocalSubnetID,
 	}
 
-	subnets, err := r.getSubnets(start, limit, filters, "", dbmodel.SortDirAsc)
+	viewer, err := r.getScopedViewer(ctx)
+	if err != nil {
+		msg := "Cannot check the user permissions"
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewGetSubnetsDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	subnets, err := r.getSubnets(viewer, start, limit, filters, "", dbmodel.SortDirAsc)
 	if err != nil {
 		msg := "Cannot get subnets from db"
 		log.Error(err)
@@ -502,6 +526,14 @@ func (r *RestAPI) GetSubnet(ctx context.Context, params dhcp.GetSubnetParams) mi
 		return rsp
 	}
 
+	if !r.authorizeView(ctx, getSubnetPermissionTargets(dbSubnet)...) {
+		msg := fmt.Sprintf("User is forbidden to view subnet with ID %d", params.ID)
+		rsp := dhcp.NewGetSubnetDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
 	subnet := r.convertSubnetToRestAPI(dbSubnet)
 	rsp := dhcp.NewGetSubnetOK().WithPayload(subnet)
 	return rsp
@@ -623,6 +655,10 @@ func (r *RestAPI) commonCreateOrUpdateSubnetSubmit(ctx context.Context, transact
 		log.WithError(err).Error(msg)
 		return http.StatusNotFound, 0, msg
 	}
+	// Make sure the user is permitted to modify the subnet on these daemons.
+	if code, msg := r.authorizeSubnetChange(ctx, subnet); code != 0 {
+		return code, 0, msg
+	}
 	if restSubnet.SharedNetwork != "" {
 		subnet.SharedNetwork = &dbmodel.SharedNetwork{
 			Name: restSubnet.SharedNetwork,
@@ -906,6 +942,14 @@ func (r *RestAPI) DeleteSubnet(ctx context.Context, params dhcp.DeleteSubnetPara
 		})
 		return rsp
 	}
+	// Make sure the user is permitted to delete the subnet from its daemons.
+	if !r.authorizeTargets(ctx, dbmodel.PermissionManageSubnets, getSubnetPermissionTargets(dbSubnet)...) {
+		msg := fmt.Sprintf("User is forbidden to delete subnet with ID %d", params.ID)
+		rsp := dhcp.NewDeleteSubnetDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
 	// Create configuration context.
 	_, user := r.SessionManager.Logged(ctx)
 	cctx, err := r.ConfigManager.CreateContext(int64(user.ID))
@@ -34,7 +34,7 @@ func TestGetSubnets(t *testing.T) {
 	fd := &storktest.FakeDispatcher{}
 	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec, nil, fd, nil)
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx := newTestUserContext(t, rapi, &dbmodel.SystemGroup{ID: dbmodel.AdminGroupID})
 
 	// get empty list of subnets
 	params := dhcp.GetSubnetsParams{}
@@ -316,7 +316,7 @@ func TestGetSubnet4(t *testing.T) {
 	fd := &storktest.FakeDispatcher{}
 	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec, nil, fd, dbmodel.NewDHCPOptionDefinitionLookup())
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx := newTestUserContext(t, rapi, &dbmodel.SystemGroup{ID: dbmodel.AdminGroupID})
 
 	// Create DHCPv4 server in the database.
 	dhcp4, err := dbmodeltest.NewKeaDHCPv4Server(db)
@@ -738,7 +738,7 @@ func TestGetSubnet4MinimalParameters(t *testing.T) {
 	fd := &storktest.FakeDispatcher{}
 	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec, nil, fd, nil)
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx := newTestUserContext(t, rapi, &dbmodel.SystemGroup{ID: dbmodel.AdminGroupID})
 
 	// Create a new Kea DHCPv4 server instance in the database.
 	dhcp4, err := dbmodeltest.NewKeaDHCPv4Server(db)
@@ -802,7 +802,7 @@ func TestGetSubnet6(t *testing.T) {
 	fd := &storktest.FakeDispatcher{}
 	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec, nil, fd, dbmodel.NewDHCPOptionDefinitionLookup())
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx := newTestUserContext(t, rapi, &dbmodel.SystemGroup{ID: dbmodel.AdminGroupID})
 
 	// Create DHCPv6 server in the database.
 	dhcp6, err := dbmodeltest.NewKeaDHCPv6Server(db)
@@ -844,6 +844,9 @@ func TestGetSubnet6(t *testing.T) {
 	require.NotNil(t, ls.PrefixDelegationPools[0].DelegatedLength)
 	require.EqualValues(t, 64, *ls.PrefixDelegationPools[0].DelegatedLength)
 	require.Equal(t, "2001:db8:1::/72", ls.PrefixDelegationPools[0].ExcludedPrefix)
+	// The pool has no per-pool statistics yet.
+	require.Nil(t, ls.PrefixDelegationPools[0].PdUtilization)
+	require.Nil(t, ls.PrefixDelegationPools[0].PdExhaustionAt)
 
 	require.NotNil(t, ls.PrefixDelegationPools[0].KeaConfigPoolParameters)
 
@@ -969,7 +972,7 @@ func TestGetSubnet6MinimalParameters(t *testing.T) {
 	fd := &storktest.FakeDispatcher{}
 	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec, nil, fd, nil)
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx := newTestUserContext(t, rapi, &dbmodel.SystemGroup{ID: dbmodel.AdminGroupID})
 
 	// Create DHCPv6 server in the database.
 	dhcp6, err := dbmodeltest.NewKeaDHCPv6Server(db)
@@ -1032,7 +1035,7 @@ func TestGetSubnetNonExisting(t *testing.T) {
 	fd := &storktest.FakeDispatcher{}
 	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec, nil, fd, nil)
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx := newTestUserContext(t, rapi, &dbmodel.SystemGroup{ID: dbmodel.AdminGroupID})
 
 	params := dhcp.GetSubnetParams{
 		ID: 1000000,
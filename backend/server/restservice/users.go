@@ -2,8 +2,14 @@ package restservice
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"path"
 	"strings"
 
 	"github.com/go-openapi/runtime/middleware"
@@ -49,11 +55,50 @@ func newRestGroup(g dbmodel.SystemGroup) *models.Group {
 		ID:          &id,
 		Name:        &g.Name,
 		Description: &g.Description,
+		BuiltIn:     g.IsBuiltIn(),
+		Permissions: []*models.GroupPermission{},
+	}
+
+	for _, p := range g.Permissions {
+		permission := string(p.Permission)
+		r.Permissions = append(r.Permissions, &models.GroupPermission{
+			Permission: &permission,
+			AppID:      p.AppID,
+			DaemonID:   p.DaemonID,
+			SubnetID:   p.SubnetID,
+		})
 	}
 
 	return r
 }
 
+// Creates new instance of the group stored in the database from the
+// group model received over the REST API.
+func newDBGroup(g *models.Group) *dbmodel.SystemGroup {
+	group := &dbmodel.SystemGroup{}
+	if g.ID != nil {
+		group.ID = int(*g.ID)
+	}
+	if g.Name != nil {
+		group.Name = strings.TrimSpace(*g.Name)
+	}
+	if g.Description != nil {
+		group.Description = *g.Description
+	}
+	for _, p := range g.Permissions {
+		if p == nil || p.Permission == nil {
+			continue
+		}
+		group.Permissions = append(group.Permissions, &dbmodel.SystemGroupPermission{
+			Permission: dbmodel.Permission(*p.Permission),
+			AppID:      p.AppID,
+			DaemonID:   p.DaemonID,
+			SubnetID:   p.SubnetID,
+		})
+	}
+	return group
+}
+
 // The internal authentication flow based on the login and password stored in
 // the database.
 func (r *RestAPI) internalAuthentication(params users.CreateSessionParams) (*dbmodel.SystemUser, error) {
@@ -95,9 +140,18 @@ func (r *RestAPI) externalAuthentication(ctx context.Context, params users.Creat
 		return nil, errors.WithMessage(err, "cannot authenticate a user")
 	}
 
+	return r.storeExternalUser(*params.Credentials.AuthenticationMethodID, calloutUser)
+}
+
+// Creates or updates the profile of the user authenticated by a hook in the
+// database. The groups returned by the hook are mapped to the internal
+// groups. If the hook doesn't return the groups, the groups of the existing
+// profile are preserved.
+func (r *RestAPI) storeExternalUser(authenticationMethodID string, calloutUser *authenticationcallouts.User) (*dbmodel.SystemUser, error) {
 	groupIDMapping := map[authenticationcallouts.UserGroupID]int{
 		authenticationcallouts.UserGroupIDSuperAdmin: dbmodel.SuperAdminGroupID,
 		authenticationcallouts.UserGroupIDAdmin:      dbmodel.AdminGroupID,
+		authenticationcallouts.UserGroupIDReadOnly:   dbmodel.ReadOnlyGroupID,
 	}
 
 	var groups []*dbmodel.SystemGroup
@@ -119,7 +173,7 @@ func (r *RestAPI) externalAuthentication(ctx context.Context, params users.Creat
 		Lastname:               calloutUser.Lastname,
 		Name:                   calloutUser.Name,
 		Groups:                 groups,
-		AuthenticationMethodID: *params.Credentials.AuthenticationMethodID,
+		AuthenticationMethodID: authenticationMethodID,
 		ExternalID:             calloutUser.ID,
 	}
 
@@ -128,7 +182,7 @@ func (r *RestAPI) externalAuthentication(ctx context.Context, params users.Creat
 		var dbUser *dbmodel.SystemUser
 		dbUser, err = dbmodel.GetUserByExternalID(
 			r.DB,
-			*params.Credentials.AuthenticationMethodID,
+			authenticationMethodID,
 			calloutUser.ID,
 		)
 		if err != nil {
@@ -199,6 +253,182 @@ func (r *RestAPI) CreateSession(ctx context.Context, params users.CreateSessionP
 	return users.NewCreateSessionOK().WithPayload(rspUser)
 }
 
+// Generates a random value for the state and nonce parameters of the
+// redirect-based authentication. The value is safe to use in the URLs.
+func generateAuthenticationRedirectValue() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "cannot generate random value")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Returns the first value of the X-Forwarded-* header set by a reverse
+// proxy. The proxies chained together append their values separated with
+// commas. The first value is set by the proxy facing the client.
+func getForwardedHeaderValue(req *http.Request, name string) string {
+	value, _, _ := strings.Cut(req.Header.Get(name), ",")
+	return strings.TrimSpace(value)
+}
+
+// Checks if the request comes directly from one of the trusted reverse
+// proxies specified in the settings. The proxies are specified as IP
+// addresses or prefixes. Invalid entries are ignored.
+func (r *RestAPI) isFromTrustedProxy(req *http.Request) bool {
+	if r.Settings == nil || r.Settings.TrustedProxies == "" {
+		return false
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	remoteIP := net.ParseIP(host)
+	if remoteIP == nil {
+		return false
+	}
+	for _, proxy := range strings.Split(r.Settings.TrustedProxies, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if _, prefix, err := net.ParseCIDR(proxy); err == nil {
+			if prefix.Contains(remoteIP) {
+				return true
+			}
+		} else if proxyIP := net.ParseIP(proxy); proxyIP != nil && proxyIP.Equal(remoteIP) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the URL the identity provider should redirect the user to after
+// the authentication using the specified method. The scheme and host are
+// taken from the X-Forwarded-Proto and X-Forwarded-Host headers if the
+// request comes from a trusted reverse proxy terminating TLS. Otherwise,
+// the headers are ignored because any client can set them.
+func (r *RestAPI) getAuthenticationCallbackURL(req *http.Request, authenticationMethodID string) string {
+	scheme := "http"
+	host := ""
+	if req != nil {
+		if req.TLS != nil {
+			scheme = "https"
+		}
+		host = req.Host
+		if r.isFromTrustedProxy(req) {
+			if proto := strings.ToLower(getForwardedHeaderValue(req, "X-Forwarded-Proto")); proto == "http" || proto == "https" {
+				scheme = proto
+			}
+			if forwardedHost := getForwardedHeaderValue(req, "X-Forwarded-Host"); forwardedHost != "" {
+				host = forwardedHost
+			}
+		}
+	}
+	callbackURL := url.URL{
+		Scheme: scheme,
+		Host:   host,
+		Path: path.Join(
+			r.getBaseURL(), "api", "authentication-methods",
+			authenticationMethodID, "callback",
+		),
+	}
+	return callbackURL.String()
+}
+
+// Returns the base URL of the UI.
+func (r *RestAPI) getBaseURL() string {
+	if r.Settings == nil || r.Settings.BaseURL == "" {
+		return "/"
+	}
+	return r.Settings.BaseURL
+}
+
+// Starts the redirect-based authentication. It returns the URL of the
+// identity provider the user should be redirected to. The generated state
+// and nonce are stored in the user's session.
+func (r *RestAPI) GetAuthenticationRedirect(ctx context.Context, params users.GetAuthenticationRedirectParams) middleware.Responder {
+	state, err := generateAuthenticationRedirectValue()
+	var nonce string
+	if err == nil {
+		nonce, err = generateAuthenticationRedirectValue()
+	}
+	if err != nil {
+		log.WithError(err).Error("Cannot start the redirect-based authentication")
+		msg := "Cannot start the redirect-based authentication"
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewGetAuthenticationRedirectDefault(http.StatusInternalServerError).WithPayload(&rspErr)
+	}
+
+	callbackURL := r.getAuthenticationCallbackURL(params.HTTPRequest, params.ID)
+	authorizationURL, err := r.HookManager.GetAuthorizationURL(ctx, params.ID, state, nonce, callbackURL)
+	if err != nil {
+		log.
+			WithError(err).
+			WithField("method", params.ID).
+			Error("Cannot get the authorization URL")
+		msg := fmt.Sprintf("Cannot start the authentication using the %s method", params.ID)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewGetAuthenticationRedirectDefault(http.StatusBadRequest).WithPayload(&rspErr)
+	}
+
+	r.SessionManager.PutAuthenticationRedirect(ctx, params.ID, state, nonce)
+
+	return users.NewGetAuthenticationRedirectOK().WithPayload(&models.AuthenticationRedirect{
+		URL: &authorizationURL,
+	})
+}
+
+// Completes the redirect-based authentication. The identity provider
+// redirects the user to this endpoint. The state must match the value
+// stored in the user's session. If the hook accepts the authorization
+// response, the user is logged in and redirected to the UI.
+func (r *RestAPI) HandleAuthenticationCallback(ctx context.Context, params users.HandleAuthenticationCallbackParams) middleware.Responder {
+	methodID, state, nonce := r.SessionManager.PopAuthenticationRedirect(ctx)
+	if methodID == "" || methodID != params.ID ||
+		subtle.ConstantTimeCompare([]byte(state), []byte(params.State)) != 1 {
+		log.WithField("method", params.ID).Warn("Invalid state of the redirect-based authentication")
+		msg := "Invalid authentication state; please try to log in again"
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewHandleAuthenticationCallbackDefault(http.StatusBadRequest).WithPayload(&rspErr)
+	}
+
+	callbackURL := r.getAuthenticationCallbackURL(params.HTTPRequest, methodID)
+	calloutUser, err := r.HookManager.HandleAuthenticationCallback(ctx, params.HTTPRequest, methodID, nonce, callbackURL)
+	if calloutUser == nil || err != nil {
+		log.
+			WithError(err).
+			WithField("method", methodID).
+			Error("Cannot authenticate a user")
+		msg := "Cannot authenticate a user"
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewHandleAuthenticationCallbackDefault(http.StatusUnauthorized).WithPayload(&rspErr)
+	}
+
+	systemUser, err := r.storeExternalUser(methodID, calloutUser)
+	if err == nil {
+		err = r.SessionManager.LoginHandler(ctx, systemUser)
+	}
+	if err != nil {
+		log.
+			WithError(err).
+			WithField("method", methodID).
+			WithField("identifier", calloutUser.ID).
+			Error("Cannot log in a user")
+		msg := "Cannot log in a user"
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewHandleAuthenticationCallbackDefault(http.StatusInternalServerError).WithPayload(&rspErr)
+	}
+
+	return users.NewHandleAuthenticationCallbackFound().WithLocation(r.getBaseURL())
+}
+
 // Attempts to logout a user from the system.
 func (r *RestAPI) DeleteSession(ctx context.Context, params users.DeleteSessionParams) middleware.Responder {
 	ok, user := r.SessionManager.Logged(ctx)
@@ -629,6 +859,144 @@ func (r *RestAPI) GetGroups(ctx context.Context, params users.GetGroupsParams) m
 	return rsp
 }
 
+// Get the group with the specified ID.
+func (r *RestAPI) GetGroup(ctx context.Context, params users.GetGroupParams) middleware.Responder {
+	id := int(params.ID)
+
+	group, err := dbmodel.GetGroupByID(r.DB, id)
+	if err != nil {
+		log.WithField("groupID", id).WithError(err).Error("Failed to fetch group from the database")
+
+		msg := fmt.Sprintf("Failed to fetch group with ID %d from the database", id)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewGetGroupDefault(http.StatusInternalServerError).WithPayload(&rspErr)
+	}
+	if group == nil {
+		msg := fmt.Sprintf("Cannot find group with ID %d", id)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewGetGroupDefault(http.StatusNotFound).WithPayload(&rspErr)
+	}
+
+	return users.NewGetGroupOK().WithPayload(newRestGroup(*group))
+}
+
+// Creates new custom group of users in the database.
+func (r *RestAPI) CreateGroup(ctx context.Context, params users.CreateGroupParams) middleware.Responder {
+	if params.Group == nil || params.Group.Name == nil || strings.TrimSpace(*params.Group.Name) == "" {
+		msg := "Failed to create new group: missing data"
+		log.Warn(msg)
+		rspErr := models.APIError{Message: &msg}
+		return users.NewCreateGroupDefault(http.StatusBadRequest).WithPayload(&rspErr)
+	}
+
+	group := newDBGroup(params.Group)
+	// The ID is assigned by the database.
+	group.ID = 0
+
+	con, err := dbmodel.AddGroup(r.DB, group)
+	if err != nil {
+		if con {
+			log.WithField("group", group.Name).WithError(err).Info("Failed to create conflicting group")
+
+			msg := fmt.Sprintf("Group with name %s already exists", group.Name)
+			rspErr := models.APIError{
+				Message: &msg,
+			}
+			return users.NewCreateGroupDefault(http.StatusConflict).WithPayload(&rspErr)
+		}
+		log.WithField("group", group.Name).WithError(err).Error("Failed to create new group")
+
+		msg := fmt.Sprintf("Failed to create new group %s", group.Name)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewCreateGroupDefault(http.StatusInternalServerError).WithPayload(&rspErr)
+	}
+
+	return users.NewCreateGroupOK().WithPayload(newRestGroup(*group))
+}
+
+// Updates the name, description and permissions of an existing custom
+// group of users. The built-in groups cannot be updated.
+func (r *RestAPI) UpdateGroup(ctx context.Context, params users.UpdateGroupParams) middleware.Responder {
+	if params.Group == nil || params.Group.Name == nil || strings.TrimSpace(*params.Group.Name) == "" {
+		msg := "Failed to update group: missing data"
+		log.Warn(msg)
+		rspErr := models.APIError{Message: &msg}
+		return users.NewUpdateGroupDefault(http.StatusBadRequest).WithPayload(&rspErr)
+	}
+
+	group := newDBGroup(params.Group)
+	// The ID specified in the path takes precedence.
+	group.ID = int(params.ID)
+
+	if group.IsBuiltIn() {
+		msg := fmt.Sprintf("Built-in group with ID %d cannot be modified", group.ID)
+		log.Warn(msg)
+		rspErr := models.APIError{Message: &msg}
+		return users.NewUpdateGroupDefault(http.StatusBadRequest).WithPayload(&rspErr)
+	}
+
+	con, err := dbmodel.UpdateGroup(r.DB, group)
+	if err != nil {
+		var (
+			code int
+			msg  string
+		)
+		switch {
+		case con:
+			code = http.StatusConflict
+			msg = fmt.Sprintf("Group with name %s already exists", group.Name)
+		case errors.Is(err, dbmodel.ErrNotExists):
+			code = http.StatusNotFound
+			msg = fmt.Sprintf("Cannot find group with ID %d", group.ID)
+		default:
+			code = http.StatusInternalServerError
+			msg = fmt.Sprintf("Failed to update group with ID %d", group.ID)
+		}
+		log.WithField("groupID", group.ID).WithError(err).Error(msg)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewUpdateGroupDefault(code).WithPayload(&rspErr)
+	}
+
+	return users.NewUpdateGroupOK().WithPayload(newRestGroup(*group))
+}
+
+// Deletes a custom group of users from the database. The built-in groups
+// cannot be deleted.
+func (r *RestAPI) DeleteGroup(ctx context.Context, params users.DeleteGroupParams) middleware.Responder {
+	group := &dbmodel.SystemGroup{ID: int(params.ID)}
+	if group.IsBuiltIn() {
+		msg := fmt.Sprintf("Built-in group with ID %d cannot be deleted", group.ID)
+		log.Warn(msg)
+		rspErr := models.APIError{Message: &msg}
+		return users.NewDeleteGroupDefault(http.StatusBadRequest).WithPayload(&rspErr)
+	}
+
+	err := dbmodel.DeleteGroup(r.DB, group.ID)
+	if err != nil {
+		code := http.StatusInternalServerError
+		msg := fmt.Sprintf("Failed to delete group with ID %d", group.ID)
+		if errors.Is(err, dbmodel.ErrNotExists) {
+			code = http.StatusNotFound
+			msg = fmt.Sprintf("Cannot find group with ID %d", group.ID)
+		}
+		log.WithField("groupID", group.ID).WithError(err).Error(msg)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewDeleteGroupDefault(code).WithPayload(&rspErr)
+	}
+
+	return users.NewDeleteGroupOK()
+}
+
 // Get authentication methods supported by server. Endpoint is allowed without log in.
 func (r *RestAPI) GetAuthenticationMethods(ctx context.Context, params users.GetAuthenticationMethodsParams) middleware.Responder {
 	metadata := r.HookManager.GetAuthenticationMetadata()
@@ -653,6 +1021,11 @@ func (r *RestAPI) GetAuthenticationMethods(ctx context.Context, params users.Get
 			method.FormLabelSecret = metaForm.GetSecretFormLabel()
 		}
 
+		if metaRedirect, ok := meta.(authenticationcallouts.AuthenticationMetadataRedirect); ok {
+			method.Redirect = true
+			method.RedirectButtonLabel = metaRedirect.GetRedirectButtonLabel()
+		}
+
 		methods = append(methods, method)
 	}
 
@@ -2,10 +2,16 @@ package restservice
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"isc.org/stork/hooks"
@@ -1021,7 +1027,8 @@ func TestGetGroups(t *testing.T) {
 
 	groups := rspOK.Payload
 	require.NotNil(t, groups.Items)
-	require.GreaterOrEqual(t, 2, len(groups.Items))
+	require.Len(t, groups.Items, 3)
+	require.True(t, groups.Items[2].BuiltIn)
 }
 
 // Tests that user information can be retrieved via REST API.
@@ -1400,3 +1407,432 @@ func TestGetAuthenticationMethodsFromHooks(t *testing.T) {
 		require.EqualValues(t, fmt.Sprintf("mock-%d", i), method.ID)
 	}
 }
+
+// Tests that the custom groups can be created, updated and deleted
+// via REST API and that the built-in groups are protected.
+func TestCreateUpdateDeleteGroup(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	ctx := context.Background()
+	rapi, err := NewRestAPI(dbSettings, db)
+	require.NoError(t, err)
+
+	id := int64(0)
+	name := "operators"
+	description := "Operators"
+	view := string(dbmodel.PermissionView)
+	manageHosts := string(dbmodel.PermissionManageHosts)
+	rsp := rapi.CreateGroup(ctx, users.CreateGroupParams{
+		Group: &models.Group{
+			ID:          &id,
+			Name:        &name,
+			Description: &description,
+			Permissions: []*models.GroupPermission{
+				{Permission: &view},
+				{Permission: &manageHosts, DaemonID: 5},
+			},
+		},
+	})
+	require.IsType(t, &users.CreateGroupOK{}, rsp)
+	created := rsp.(*users.CreateGroupOK).Payload
+	require.NotZero(t, *created.ID)
+	require.False(t, created.BuiltIn)
+	require.Len(t, created.Permissions, 2)
+
+	// Conflicting name.
+	rsp = rapi.CreateGroup(ctx, users.CreateGroupParams{
+		Group: &models.Group{ID: &id, Name: &name, Description: &description},
+	})
+	require.IsType(t, &users.CreateGroupDefault{}, rsp)
+	require.Equal(t, http.StatusConflict, getStatusCode(*rsp.(*users.CreateGroupDefault)))
+
+	// Get the group.
+	rsp = rapi.GetGroup(ctx, users.GetGroupParams{ID: *created.ID})
+	require.IsType(t, &users.GetGroupOK{}, rsp)
+	returned := rsp.(*users.GetGroupOK).Payload
+	require.Equal(t, name, *returned.Name)
+	require.Len(t, returned.Permissions, 2)
+	require.Equal(t, manageHosts, *returned.Permissions[1].Permission)
+	require.EqualValues(t, 5, returned.Permissions[1].DaemonID)
+
+	// Update the group.
+	newName := "viewers"
+	rsp = rapi.UpdateGroup(ctx, users.UpdateGroupParams{
+		ID: *created.ID,
+		Group: &models.Group{
+			ID:          created.ID,
+			Name:        &newName,
+			Description: &description,
+			Permissions: []*models.GroupPermission{
+				{Permission: &view},
+			},
+		},
+	})
+	require.IsType(t, &users.UpdateGroupOK{}, rsp)
+
+	rsp = rapi.GetGroup(ctx, users.GetGroupParams{ID: *created.ID})
+	require.IsType(t, &users.GetGroupOK{}, rsp)
+	returned = rsp.(*users.GetGroupOK).Payload
+	require.Equal(t, newName, *returned.Name)
+	require.Len(t, returned.Permissions, 1)
+
+	// The built-in groups cannot be updated nor deleted.
+	adminID := int64(dbmodel.AdminGroupID)
+	rsp = rapi.UpdateGroup(ctx, users.UpdateGroupParams{
+		ID:    adminID,
+		Group: &models.Group{ID: &adminID, Name: &newName, Description: &description},
+	})
+	require.IsType(t, &users.UpdateGroupDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*users.UpdateGroupDefault)))
+
+	rsp = rapi.DeleteGroup(ctx, users.DeleteGroupParams{ID: adminID})
+	require.IsType(t, &users.DeleteGroupDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*users.DeleteGroupDefault)))
+
+	// Delete the custom group.
+	rsp = rapi.DeleteGroup(ctx, users.DeleteGroupParams{ID: *created.ID})
+	require.IsType(t, &users.DeleteGroupOK{}, rsp)
+
+	rsp = rapi.GetGroup(ctx, users.GetGroupParams{ID: *created.ID})
+	require.IsType(t, &users.GetGroupDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*users.GetGroupDefault)))
+
+	rsp = rapi.DeleteGroup(ctx, users.DeleteGroupParams{ID: *created.ID})
+	require.IsType(t, &users.DeleteGroupDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*users.DeleteGroupDefault)))
+}
+
+// Metadata of the fake redirect-based authentication method.
+type fakeRedirectAuthenticationMetadata struct{}
+
+// Returns the ID of the fake authentication method.
+func (m *fakeRedirectAuthenticationMetadata) GetID() string {
+	return "oidc"
+}
+
+// Returns the name of the fake authentication method.
+func (m *fakeRedirectAuthenticationMetadata) GetName() string {
+	return "OpenID Connect"
+}
+
+// Returns the description of the fake authentication method.
+func (m *fakeRedirectAuthenticationMetadata) GetDescription() string {
+	return "Mock identity provider"
+}
+
+// Returns no icon.
+func (m *fakeRedirectAuthenticationMetadata) GetIcon() (io.ReadCloser, error) {
+	return nil, nil
+}
+
+// Returns the label of the login button.
+func (m *fakeRedirectAuthenticationMetadata) GetRedirectButtonLabel() string {
+	return "Log in with SSO"
+}
+
+// Fake hook implementing the redirect-based authentication against the
+// mock identity provider.
+type fakeRedirectAuthenticationHook struct {
+	idpURL string
+}
+
+// The form-based authentication is not supported by this hook.
+func (h *fakeRedirectAuthenticationHook) Authenticate(ctx context.Context, request *http.Request, identifier, secret *string) (*authenticationcallouts.User, error) {
+	return nil, errors.New("use the redirect-based flow")
+}
+
+// Does nothing.
+func (h *fakeRedirectAuthenticationHook) Unauthenticate(ctx context.Context) error {
+	return nil
+}
+
+// Returns the fake metadata.
+func (h *fakeRedirectAuthenticationHook) GetMetadata() authenticationcallouts.AuthenticationMetadata {
+	return &fakeRedirectAuthenticationMetadata{}
+}
+
+// Returns the authorization endpoint of the mock identity provider.
+func (h *fakeRedirectAuthenticationHook) GetAuthorizationURL(ctx context.Context, state, nonce, callbackURL string) (string, error) {
+	query := url.Values{}
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("redirect_uri", callbackURL)
+	return h.idpURL + "/authorize?" + query.Encode(), nil
+}
+
+// Exchanges the authorization code for the user info and validates the
+// nonce.
+func (h *fakeRedirectAuthenticationHook) HandleCallback(ctx context.Context, request *http.Request, nonce, callbackURL string) (*authenticationcallouts.User, error) {
+	code := request.URL.Query().Get("code")
+	req, _ := http.NewRequestWithContext(ctx, "GET", h.idpURL+"/token?code="+url.QueryEscape(code), nil)
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("invalid authorization code")
+	}
+	var claims struct {
+		Subject string `json:"sub"`
+		Email   string `json:"email"`
+		Nonce   string `json:"nonce"`
+	}
+	if err = json.NewDecoder(rsp.Body).Decode(&claims); err != nil {
+		return nil, err
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.Errorf("invalid nonce")
+	}
+	return &authenticationcallouts.User{
+		ID:       claims.Subject,
+		Login:    "jdoe",
+		Email:    claims.Email,
+		Name:     "John",
+		Lastname: "Doe",
+		Groups:   []authenticationcallouts.UserGroupID{authenticationcallouts.UserGroupIDReadOnly},
+	}, nil
+}
+
+// Does nothing.
+func (h *fakeRedirectAuthenticationHook) Close() error {
+	return nil
+}
+
+// Creates a mock identity provider. It issues an authorization code for
+// each authorization request and returns the claims, including the nonce,
+// in exchange for the code.
+func newMockIdentityProvider() *httptest.Server {
+	nonces := map[string]string{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		code := fmt.Sprintf("code-%d", len(nonces))
+		nonces[code] = query.Get("nonce")
+		redirect := url.Values{}
+		redirect.Set("code", code)
+		redirect.Set("state", query.Get("state"))
+		http.Redirect(w, r, query.Get("redirect_uri")+"?"+redirect.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		nonce, ok := nonces[r.URL.Query().Get("code")]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"sub":   "42",
+			"email": "jdoe@example.org",
+			"nonce": nonce,
+		})
+	})
+	return httptest.NewServer(mux)
+}
+
+// Test that the redirect-based authentication methods are marked in the
+// list of the authentication methods.
+func TestGetAuthenticationMethodsRedirect(t *testing.T) {
+	// Arrange
+	hookManager := hookmanager.NewHookManager()
+	hookManager.RegisterCalloutCarrier(&fakeRedirectAuthenticationHook{})
+	rapi, _ := NewRestAPI(&dbops.DatabaseSettings{}, hookManager)
+
+	// Act
+	response := rapi.GetAuthenticationMethods(context.Background(), users.GetAuthenticationMethodsParams{})
+
+	// Assert
+	require.IsType(t, &users.GetAuthenticationMethodsOK{}, response)
+	items := response.(*users.GetAuthenticationMethodsOK).Payload.Items
+	require.Len(t, items, 2)
+	require.False(t, items[0].Redirect)
+	require.Equal(t, "oidc", items[1].ID)
+	require.True(t, items[1].Redirect)
+	require.Equal(t, "Log in with SSO", items[1].RedirectButtonLabel)
+	require.Empty(t, items[1].FormLabelIdentifier)
+}
+
+// Test the complete redirect-based authentication flow against the mock
+// identity provider.
+func TestRedirectAuthentication(t *testing.T) {
+	// Arrange
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	idp := newMockIdentityProvider()
+	defer idp.Close()
+
+	hookManager := hookmanager.NewHookManager()
+	hookManager.RegisterCalloutCarrier(&fakeRedirectAuthenticationHook{idpURL: idp.URL})
+	rapi, err := NewRestAPI(dbSettings, db, hookManager)
+	require.NoError(t, err)
+
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
+
+	// Act
+	// Start the authentication.
+	rsp := rapi.GetAuthenticationRedirect(ctx, users.GetAuthenticationRedirectParams{
+		ID:          "oidc",
+		HTTPRequest: httptest.NewRequest("GET", "http://stork.example.org/api/authentication-methods/oidc/redirect", nil),
+	})
+	require.IsType(t, &users.GetAuthenticationRedirectOK{}, rsp)
+	authorizationURL := *rsp.(*users.GetAuthenticationRedirectOK).Payload.URL
+	require.True(t, strings.HasPrefix(authorizationURL, idp.URL))
+
+	// Visit the identity provider.
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	idpRsp, err := client.Get(authorizationURL)
+	require.NoError(t, err)
+	idpRsp.Body.Close()
+	require.Equal(t, http.StatusFound, idpRsp.StatusCode)
+	callbackURL, err := url.Parse(idpRsp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, "/api/authentication-methods/oidc/callback", callbackURL.Path)
+	require.Equal(t, "stork.example.org", callbackURL.Host)
+
+	// Go back to the server.
+	callbackParams := users.HandleAuthenticationCallbackParams{
+		ID:          "oidc",
+		State:       callbackURL.Query().Get("state"),
+		HTTPRequest: httptest.NewRequest("GET", callbackURL.String(), nil),
+	}
+	rsp = rapi.HandleAuthenticationCallback(ctx, callbackParams)
+
+	// Assert
+	require.IsType(t, &users.HandleAuthenticationCallbackFound{}, rsp)
+	require.Equal(t, "/", rsp.(*users.HandleAuthenticationCallbackFound).Location)
+
+	ok, user := rapi.SessionManager.Logged(ctx)
+	require.True(t, ok)
+	require.Equal(t, "jdoe@example.org", user.Email)
+	require.Equal(t, "oidc", user.AuthenticationMethodID)
+	require.True(t, user.InGroup(&dbmodel.SystemGroup{ID: dbmodel.ReadOnlyGroupID}))
+
+	dbUser, err := dbmodel.GetUserByExternalID(db, "oidc", "42")
+	require.NoError(t, err)
+	require.NotNil(t, dbUser)
+
+	// The state can't be reused.
+	rsp = rapi.HandleAuthenticationCallback(ctx, callbackParams)
+	require.IsType(t, &users.HandleAuthenticationCallbackDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*users.HandleAuthenticationCallbackDefault)))
+}
+
+// Test that the callback is rejected if the state doesn't match the
+// value stored in the session.
+func TestRedirectAuthenticationInvalidState(t *testing.T) {
+	// Arrange
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	idp := newMockIdentityProvider()
+	defer idp.Close()
+
+	hookManager := hookmanager.NewHookManager()
+	hookManager.RegisterCalloutCarrier(&fakeRedirectAuthenticationHook{idpURL: idp.URL})
+	rapi, err := NewRestAPI(dbSettings, db, hookManager)
+	require.NoError(t, err)
+
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
+
+	rsp := rapi.GetAuthenticationRedirect(ctx, users.GetAuthenticationRedirectParams{
+		ID:          "oidc",
+		HTTPRequest: httptest.NewRequest("GET", "http://stork.example.org/api/authentication-methods/oidc/redirect", nil),
+	})
+	require.IsType(t, &users.GetAuthenticationRedirectOK{}, rsp)
+
+	// Act
+	rsp = rapi.HandleAuthenticationCallback(ctx, users.HandleAuthenticationCallbackParams{
+		ID:          "oidc",
+		State:       "forged",
+		HTTPRequest: httptest.NewRequest("GET", "http://stork.example.org/api/authentication-methods/oidc/callback?code=code-0&state=forged", nil),
+	})
+
+	// Assert
+	require.IsType(t, &users.HandleAuthenticationCallbackDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*users.HandleAuthenticationCallbackDefault)))
+	ok, _ := rapi.SessionManager.Logged(ctx)
+	require.False(t, ok)
+}
+
+// Test that the form-based authentication methods don't support the
+// redirect-based flow.
+func TestGetAuthenticationRedirectNotSupported(t *testing.T) {
+	// Arrange
+	hookManager := hookmanager.NewHookManager()
+	rapi, _ := NewRestAPI(&dbops.DatabaseSettings{}, hookManager)
+
+	// Act
+	rsp := rapi.GetAuthenticationRedirect(context.Background(), users.GetAuthenticationRedirectParams{
+		ID:          "internal",
+		HTTPRequest: httptest.NewRequest("GET", "http://stork.example.org/api/authentication-methods/internal/redirect", nil),
+	})
+
+	// Assert
+	require.IsType(t, &users.GetAuthenticationRedirectDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*users.GetAuthenticationRedirectDefault)))
+}
+
+// Test that the authentication callback URL honors the headers set by
+// the trusted reverse proxy terminating TLS.
+func TestGetAuthenticationCallbackURL(t *testing.T) {
+	rapi := &RestAPI{
+		Settings: &RestAPISettings{
+			TrustedProxies: "192.0.2.1, 2001:db8:1::/64, invalid",
+		},
+	}
+
+	req := httptest.NewRequest("GET", "http://stork.example.org/api/authentication-methods/oidc/redirect", nil)
+	require.Equal(t, "http://stork.example.org/api/authentication-methods/oidc/callback",
+		rapi.getAuthenticationCallbackURL(req, "oidc"))
+
+	req = httptest.NewRequest("GET", "https://stork.example.org/api/authentication-methods/oidc/redirect", nil)
+	require.Equal(t, "https://stork.example.org/api/authentication-methods/oidc/callback",
+		rapi.getAuthenticationCallbackURL(req, "oidc"))
+
+	req = httptest.NewRequest("GET", "http://127.0.0.1:8080/api/authentication-methods/oidc/redirect", nil)
+	req.RemoteAddr = "192.0.2.1:54321"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Host", "stork.example.org, proxy.example.org")
+	require.Equal(t, "https://stork.example.org/api/authentication-methods/oidc/callback",
+		rapi.getAuthenticationCallbackURL(req, "oidc"))
+
+	req = httptest.NewRequest("GET", "http://127.0.0.1:8080/api/authentication-methods/oidc/redirect", nil)
+	req.RemoteAddr = "[2001:db8:1::5]:54321"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Host", "stork.example.org")
+	require.Equal(t, "https://stork.example.org/api/authentication-methods/oidc/callback",
+		rapi.getAuthenticationCallbackURL(req, "oidc"))
+
+	// Unsupported schemes are ignored.
+	req = httptest.NewRequest("GET", "http://stork.example.org/api/authentication-methods/oidc/redirect", nil)
+	req.RemoteAddr = "192.0.2.1:54321"
+	req.Header.Set("X-Forwarded-Proto", "javascript")
+	require.Equal(t, "http://stork.example.org/api/authentication-methods/oidc/callback",
+		rapi.getAuthenticationCallbackURL(req, "oidc"))
+}
+
+// Test that the X-Forwarded-* headers set by a client other than the
+// trusted reverse proxy are ignored.
+func TestGetAuthenticationCallbackURLSpoofedHeaders(t *testing.T) {
+	req := httptest.NewRequest("GET", "http://stork.example.org/api/authentication-methods/oidc/redirect", nil)
+	req.RemoteAddr = "198.51.100.7:54321"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Host", "attacker.example.net")
+
+	// No trusted proxies.
+	rapi := &RestAPI{}
+	require.Equal(t, "http://stork.example.org/api/authentication-methods/oidc/callback",
+		rapi.getAuthenticationCallbackURL(req, "oidc"))
+
+	// The client is not a trusted proxy.
+	rapi.Settings = &RestAPISettings{TrustedProxies: "192.0.2.1,192.0.2.128/25"}
+	require.Equal(t, "http://stork.example.org/api/authentication-methods/oidc/callback",
+		rapi.getAuthenticationCallbackURL(req, "oidc"))
+}
@@ -0,0 +1,335 @@
+package restservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
+	log "github.com/sirupsen/logrus"
+
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
+)
+
+// Creates new instance of the utilization alert rule model used by REST
+// API from the rule instance returned from the database.
+func newRestUtilizationAlertRule(rule *dbmodel.UtilizationAlertRule) *models.UtilizationAlertRule {
+	warningThreshold := int64(rule.WarningThreshold)
+	errorThreshold := int64(rule.ErrorThreshold)
+	restRule := &models.UtilizationAlertRule{
+		ID:               rule.ID,
+		SubnetID:         rule.SubnetID,
+		SharedNetworkID:  rule.SharedNetworkID,
+		Enabled:          rule.Enabled,
+		WarningThreshold: &warningThreshold,
+		ErrorThreshold:   &errorThreshold,
+	}
+	if rule.Subnet != nil {
+		restRule.Subnet = rule.Subnet.Prefix
+	}
+	if rule.SharedNetwork != nil {
+		restRule.SharedNetwork = rule.SharedNetwork.Name
+	}
+	return restRule
+}
+
+// Creates new instance of the utilization alert model used by REST API
+// from the alert instance returned from the database. The utilization is
+// converted from permille to percent.
+func newRestUtilizationAlert(alert *dbmodel.UtilizationAlert) *models.UtilizationAlert {
+	restAlert := &models.UtilizationAlert{
+		ID:              alert.ID,
+		CreatedAt:       strfmt.DateTime(alert.CreatedAt),
+		SubnetID:        alert.SubnetID,
+		SharedNetworkID: alert.SharedNetworkID,
+		Kind:            string(alert.Kind),
+		Level:           int64(alert.Level),
+		Utilization:     float64(alert.Utilization) / 10,
+	}
+	if alert.Subnet != nil {
+		restAlert.Subnet = alert.Subnet.Prefix
+		restAlert.ExhaustionAt = getExhaustionForecast(alert.Kind, alert.Subnet.AddrExhaustionAt, alert.Subnet.PdExhaustionAt)
+	}
+	if alert.SharedNetwork != nil {
+		restAlert.SharedNetwork = alert.SharedNetwork.Name
+		restAlert.ExhaustionAt = getExhaustionForecast(alert.Kind, alert.SharedNetwork.AddrExhaustionAt, alert.SharedNetwork.PdExhaustionAt)
+	}
+	if alert.PrefixPool != nil {
+		restAlert.PrefixPoolID = alert.PrefixPoolID
+		restAlert.PrefixPool = alert.PrefixPool.Prefix
+		restAlert.ExhaustionAt = getExhaustionForecast(alert.Kind, time.Time{}, alert.PrefixPool.PdExhaustionAt)
+		// The PD pool alerts are presented with the subnet of the pool.
+		if alert.PrefixPool.LocalSubnet != nil && alert.PrefixPool.LocalSubnet.Subnet != nil {
+			restAlert.SubnetID = alert.PrefixPool.LocalSubnet.SubnetID
+			restAlert.Subnet = alert.PrefixPool.LocalSubnet.Subnet.Prefix
+		}
+	}
+	return restAlert
+}
+
+// Returns the forecast exhaustion time for the exhaustion alert of the
+// specified kind. It returns nil for the other alerts.
+func getExhaustionForecast(kind dbmodel.UtilizationAlertKind, addrExhaustionAt, pdExhaustionAt time.Time) *strfmt.DateTime {
+	switch kind {
+	case dbmodel.UtilizationAlertKindAddressExhaustion:
+		return convertToOptionalDatetime(addrExhaustionAt)
+	case dbmodel.UtilizationAlertKindDelegatedPrefixExhaustion:
+		return convertToOptionalDatetime(pdExhaustionAt)
+	default:
+		return nil
+	}
+}
+
+// Validates the utilization alert rule received in the request and converts
+// it to the database model. It returns an error message if the rule is
+// invalid.
+func newDBUtilizationAlertRule(rule *models.UtilizationAlertRule) (*dbmodel.UtilizationAlertRule, string) {
+	if rule == nil || rule.WarningThreshold == nil || rule.ErrorThreshold == nil {
+		return nil, "missing thresholds"
+	}
+	for _, threshold := range []int64{*rule.WarningThreshold, *rule.ErrorThreshold} {
+		if threshold < 0 || threshold > 100 {
+			return nil, "thresholds must be in the range of 0 to 100"
+		}
+	}
+	if *rule.WarningThreshold > 0 && *rule.ErrorThreshold > 0 && *rule.WarningThreshold >= *rule.ErrorThreshold {
+		return nil, "warning threshold must be lower than error threshold"
+	}
+	return &dbmodel.UtilizationAlertRule{
+		Enabled:          rule.Enabled,
+		WarningThreshold: int16(*rule.WarningThreshold),
+		ErrorThreshold:   int16(*rule.ErrorThreshold),
+	}, ""
+}
+
+// Returns the utilization alert rules of all subnets and shared networks.
+func (r *RestAPI) GetUtilizationAlertRules(ctx context.Context, params dhcp.GetUtilizationAlertRulesParams) middleware.Responder {
+	dbRules, err := dbmodel.GetAllUtilizationAlertRules(r.DB)
+	if err != nil {
+		msg := "Failed to fetch utilization alert rules from the database"
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewGetUtilizationAlertRulesDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	rules := &models.UtilizationAlertRules{
+		Items: []*models.UtilizationAlertRule{},
+		Total: int64(len(dbRules)),
+	}
+	for i := range dbRules {
+		rules.Items = append(rules.Items, newRestUtilizationAlertRule(&dbRules[i]))
+	}
+	return dhcp.NewGetUtilizationAlertRulesOK().WithPayload(rules)
+}
+
+// Returns the utilization alerts currently raised for the subnets and
+// shared networks.
+func (r *RestAPI) GetUtilizationAlerts(ctx context.Context, params dhcp.GetUtilizationAlertsParams) middleware.Responder {
+	dbAlerts, err := dbmodel.GetAllUtilizationAlerts(r.DB)
+	if err != nil {
+		msg := "Failed to fetch utilization alerts from the database"
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewGetUtilizationAlertsDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	alerts := &models.UtilizationAlerts{
+		Items: []*models.UtilizationAlert{},
+		Total: int64(len(dbAlerts)),
+	}
+	for i := range dbAlerts {
+		alerts.Items = append(alerts.Items, newRestUtilizationAlert(&dbAlerts[i]))
+	}
+	return dhcp.NewGetUtilizationAlertsOK().WithPayload(alerts)
+}
+
+// Sets the utilization alert rule of the subnet.
+func (r *RestAPI) UpdateSubnetUtilizationAlertRule(ctx context.Context, params dhcp.UpdateSubnetUtilizationAlertRuleParams) middleware.Responder {
+	dbRule, invalid := newDBUtilizationAlertRule(params.Rule)
+	if invalid != "" {
+		msg := fmt.Sprintf("Failed to set utilization alert rule for subnet with ID %d: %s", params.ID, invalid)
+		log.Warn(msg)
+		rsp := dhcp.NewUpdateSubnetUtilizationAlertRuleDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	dbSubnet, err := dbmodel.GetSubnet(r.DB, params.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Problem fetching subnet with ID %d from db", params.ID)
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewUpdateSubnetUtilizationAlertRuleDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if dbSubnet == nil {
+		msg := fmt.Sprintf("Cannot find subnet with ID %d", params.ID)
+		rsp := dhcp.NewUpdateSubnetUtilizationAlertRuleDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if !r.authorizeTargets(ctx, dbmodel.PermissionManageSubnets, getSubnetPermissionTargets(dbSubnet)...) {
+		msg := fmt.Sprintf("User is forbidden to set utilization alert rule for subnet with ID %d", params.ID)
+		rsp := dhcp.NewUpdateSubnetUtilizationAlertRuleDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	dbRule.SubnetID = params.ID
+	if err = dbmodel.SetUtilizationAlertRule(r.DB, dbRule); err != nil {
+		msg := fmt.Sprintf("Failed to set utilization alert rule for subnet with ID %d", params.ID)
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewUpdateSubnetUtilizationAlertRuleDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	dbRule.Subnet = dbSubnet
+	return dhcp.NewUpdateSubnetUtilizationAlertRuleOK().WithPayload(newRestUtilizationAlertRule(dbRule))
+}
+
+// Deletes the utilization alert rule of the subnet.
+func (r *RestAPI) DeleteSubnetUtilizationAlertRule(ctx context.Context, params dhcp.DeleteSubnetUtilizationAlertRuleParams) middleware.Responder {
+	dbSubnet, err := dbmodel.GetSubnet(r.DB, params.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Problem fetching subnet with ID %d from db", params.ID)
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewDeleteSubnetUtilizationAlertRuleDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if dbSubnet == nil {
+		msg := fmt.Sprintf("Cannot find subnet with ID %d", params.ID)
+		rsp := dhcp.NewDeleteSubnetUtilizationAlertRuleDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if !r.authorizeTargets(ctx, dbmodel.PermissionManageSubnets, getSubnetPermissionTargets(dbSubnet)...) {
+		msg := fmt.Sprintf("User is forbidden to delete utilization alert rule for subnet with ID %d", params.ID)
+		rsp := dhcp.NewDeleteSubnetUtilizationAlertRuleDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	err = dbmodel.DeleteUtilizationAlertRuleBySubnetID(r.DB, params.ID)
+	if err != nil {
+		code := http.StatusInternalServerError
+		msg := fmt.Sprintf("Failed to delete utilization alert rule for subnet with ID %d", params.ID)
+		if errors.Is(err, dbmodel.ErrNotExists) {
+			code = http.StatusNotFound
+			msg = fmt.Sprintf("Cannot find utilization alert rule for subnet with ID %d", params.ID)
+		}
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewDeleteSubnetUtilizationAlertRuleDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	return dhcp.NewDeleteSubnetUtilizationAlertRuleOK()
+}
+
+// Sets the utilization alert rule of the shared network.
+func (r *RestAPI) UpdateSharedNetworkUtilizationAlertRule(ctx context.Context, params dhcp.UpdateSharedNetworkUtilizationAlertRuleParams) middleware.Responder {
+	dbRule, invalid := newDBUtilizationAlertRule(params.Rule)
+	if invalid != "" {
+		msg := fmt.Sprintf("Failed to set utilization alert rule for shared network with ID %d: %s", params.ID, invalid)
+		log.Warn(msg)
+		rsp := dhcp.NewUpdateSharedNetworkUtilizationAlertRuleDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	dbSharedNetwork, err := dbmodel.GetSharedNetwork(r.DB, params.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Problem fetching shared network with ID %d from db", params.ID)
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewUpdateSharedNetworkUtilizationAlertRuleDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if dbSharedNetwork == nil {
+		msg := fmt.Sprintf("Cannot find shared network with ID %d", params.ID)
+		rsp := dhcp.NewUpdateSharedNetworkUtilizationAlertRuleDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if !r.authorizeTargets(ctx, dbmodel.PermissionManageSubnets, getSharedNetworkPermissionTargets(dbSharedNetwork)...) {
+		msg := fmt.Sprintf("User is forbidden to set utilization alert rule for shared network with ID %d", params.ID)
+		rsp := dhcp.NewUpdateSharedNetworkUtilizationAlertRuleDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	dbRule.SharedNetworkID = params.ID
+	if err = dbmodel.SetUtilizationAlertRule(r.DB, dbRule); err != nil {
+		msg := fmt.Sprintf("Failed to set utilization alert rule for shared network with ID %d", params.ID)
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewUpdateSharedNetworkUtilizationAlertRuleDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	dbRule.SharedNetwork = dbSharedNetwork
+	return dhcp.NewUpdateSharedNetworkUtilizationAlertRuleOK().WithPayload(newRestUtilizationAlertRule(dbRule))
+}
+
+// Deletes the utilization alert rule of the shared network.
+func (r *RestAPI) DeleteSharedNetworkUtilizationAlertRule(ctx context.Context, params dhcp.DeleteSharedNetworkUtilizationAlertRuleParams) middleware.Responder {
+	dbSharedNetwork, err := dbmodel.GetSharedNetwork(r.DB, params.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Problem fetching shared network with ID %d from db", params.ID)
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewDeleteSharedNetworkUtilizationAlertRuleDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if dbSharedNetwork == nil {
+		msg := fmt.Sprintf("Cannot find shared network with ID %d", params.ID)
+		rsp := dhcp.NewDeleteSharedNetworkUtilizationAlertRuleDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if !r.authorizeTargets(ctx, dbmodel.PermissionManageSubnets, getSharedNetworkPermissionTargets(dbSharedNetwork)...) {
+		msg := fmt.Sprintf("User is forbidden to delete utilization alert rule for shared network with ID %d", params.ID)
+		rsp := dhcp.NewDeleteSharedNetworkUtilizationAlertRuleDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	err = dbmodel.DeleteUtilizationAlertRuleBySharedNetworkID(r.DB, params.ID)
+	if err != nil {
+		code := http.StatusInternalServerError
+		msg := fmt.Sprintf("Failed to delete utilization alert rule for shared network with ID %d", params.ID)
+		if errors.Is(err, dbmodel.ErrNotExists) {
+			code = http.StatusNotFound
+			msg = fmt.Sprintf("Cannot find utilization alert rule for shared network with ID %d", params.ID)
+		}
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewDeleteSharedNetworkUtilizationAlertRuleDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	return dhcp.NewDeleteSharedNetworkUtilizationAlertRuleOK()
+}
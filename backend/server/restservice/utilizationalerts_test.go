@@ -0,0 +1,251 @@
+package restservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/models"
+	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
+	storkutil "isc.org/stork/util"
+)
+
+// Test that the utilization alert rule received over the REST API is
+// validated and converted to the database model.
+func TestNewDBUtilizationAlertRule(t *testing.T) {
+	rule, invalid := newDBUtilizationAlertRule(&models.UtilizationAlertRule{
+		Enabled:          true,
+		WarningThreshold: storkutil.Ptr[int64](70),
+		ErrorThreshold:   storkutil.Ptr[int64](85),
+	})
+	require.Empty(t, invalid)
+	require.NotNil(t, rule)
+	require.True(t, rule.Enabled)
+	require.EqualValues(t, 70, rule.WarningThreshold)
+	require.EqualValues(t, 85, rule.ErrorThreshold)
+
+	// The zero thresholds disable the alerts, so they are not compared.
+	rule, invalid = newDBUtilizationAlertRule(&models.UtilizationAlertRule{
+		WarningThreshold: storkutil.Ptr[int64](70),
+		ErrorThreshold:   storkutil.Ptr[int64](0),
+	})
+	require.Empty(t, invalid)
+	require.NotNil(t, rule)
+
+	for _, r := range []*models.UtilizationAlertRule{
+		nil,
+		{ErrorThreshold: storkutil.Ptr[int64](90)},
+		{WarningThreshold: storkutil.Ptr[int64](80)},
+		{WarningThreshold: storkutil.Ptr[int64](-1), ErrorThreshold: storkutil.Ptr[int64](90)},
+		{WarningThreshold: storkutil.Ptr[int64](80), ErrorThreshold: storkutil.Ptr[int64](101)},
+		{WarningThreshold: storkutil.Ptr[int64](90), ErrorThreshold: storkutil.Ptr[int64](90)},
+	} {
+		rule, invalid = newDBUtilizationAlertRule(r)
+		require.NotEmpty(t, invalid)
+		require.Nil(t, rule)
+	}
+}
+
+// Test that the exhaustion alerts include the forecast exhaustion time.
+func TestNewRestUtilizationAlertExhaustion(t *testing.T) {
+	addrExhaustionAt := time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)
+	pdExhaustionAt := time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)
+	subnet := &dbmodel.Subnet{
+		Prefix:           "2001:db8:1::/64",
+		AddrExhaustionAt: addrExhaustionAt,
+		PdExhaustionAt:   pdExhaustionAt,
+	}
+
+	alert := newRestUtilizationAlert(&dbmodel.UtilizationAlert{
+		Subnet: subnet,
+		Kind:   dbmodel.UtilizationAlertKindDelegatedPrefixExhaustion,
+		Level:  dbmodel.EvWarning,
+	})
+	require.Equal(t, "delegated-prefix-exhaustion", alert.Kind)
+	require.NotNil(t, alert.ExhaustionAt)
+	require.Equal(t, pdExhaustionAt, time.Time(*alert.ExhaustionAt))
+
+	alert = newRestUtilizationAlert(&dbmodel.UtilizationAlert{
+		Subnet: subnet,
+		Kind:   dbmodel.UtilizationAlertKindAddressExhaustion,
+	})
+	require.Equal(t, addrExhaustionAt, time.Time(*alert.ExhaustionAt))
+
+	// The threshold alerts have no forecast.
+	alert = newRestUtilizationAlert(&dbmodel.UtilizationAlert{
+		Subnet: subnet,
+		Kind:   dbmodel.UtilizationAlertKindAddress,
+	})
+	require.Nil(t, alert.ExhaustionAt)
+}
+
+// Test that the PD pool exhaustion alert includes the pool prefix, the
+// subnet of the pool and the forecast exhaustion time of the pool.
+func TestNewRestUtilizationAlertPrefixPoolExhaustion(t *testing.T) {
+	pdExhaustionAt := time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)
+	alert := newRestUtilizationAlert(&dbmodel.UtilizationAlert{
+		PrefixPoolID: 5,
+		PrefixPool: &dbmodel.PrefixPool{
+			ID:             5,
+			Prefix:         "2001:db8:1:8000::/64",
+			PdExhaustionAt: pdExhaustionAt,
+			LocalSubnet: &dbmodel.LocalSubnet{
+				SubnetID: 3,
+				Subnet: &dbmodel.Subnet{
+					ID:     3,
+					Prefix: "2001:db8:1::/48",
+				},
+			},
+		},
+		Kind:  dbmodel.UtilizationAlertKindDelegatedPrefixExhaustion,
+		Level: dbmodel.EvWarning,
+	})
+	require.EqualValues(t, 5, alert.PrefixPoolID)
+	require.Equal(t, "2001:db8:1:8000::/64", alert.PrefixPool)
+	require.EqualValues(t, 3, alert.SubnetID)
+	require.Equal(t, "2001:db8:1::/48", alert.Subnet)
+	require.Zero(t, alert.SharedNetworkID)
+	require.NotNil(t, alert.ExhaustionAt)
+	require.Equal(t, pdExhaustionAt, time.Time(*alert.ExhaustionAt))
+}
+
+// Test that the utilization alert rules of the subnets and shared networks
+// can be set, listed and deleted.
+func TestSetGetDeleteUtilizationAlertRules(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	sharedNetwork := &dbmodel.SharedNetwork{
+		Name:   "frog",
+		Family: 4,
+	}
+	err := dbmodel.AddSharedNetwork(db, sharedNetwork)
+	require.NoError(t, err)
+
+	subnet := &dbmodel.Subnet{
+		Prefix: "192.0.2.0/24",
+	}
+	err = dbmodel.AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	rapi, err := NewRestAPI(dbSettings, db)
+	require.NoError(t, err)
+
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
+	err = rapi.SessionManager.LoginHandler(ctx, &dbmodel.SystemUser{ID: 1234})
+	require.NoError(t, err)
+
+	// Set the subnet rule.
+	rsp := rapi.UpdateSubnetUtilizationAlertRule(ctx, dhcp.UpdateSubnetUtilizationAlertRuleParams{
+		ID: subnet.ID,
+		Rule: &models.UtilizationAlertRule{
+			Enabled:          true,
+			WarningThreshold: storkutil.Ptr[int64](60),
+			ErrorThreshold:   storkutil.Ptr[int64](75),
+		},
+	})
+	require.IsType(t, &dhcp.UpdateSubnetUtilizationAlertRuleOK{}, rsp)
+	rule := rsp.(*dhcp.UpdateSubnetUtilizationAlertRuleOK).Payload
+	require.NotZero(t, rule.ID)
+	require.Equal(t, subnet.ID, rule.SubnetID)
+	require.Equal(t, "192.0.2.0/24", rule.Subnet)
+
+	// Set the shared network rule.
+	rsp = rapi.UpdateSharedNetworkUtilizationAlertRule(ctx, dhcp.UpdateSharedNetworkUtilizationAlertRuleParams{
+		ID: sharedNetwork.ID,
+		Rule: &models.UtilizationAlertRule{
+			WarningThreshold: storkutil.Ptr[int64](50),
+			ErrorThreshold:   storkutil.Ptr[int64](60),
+		},
+	})
+	require.IsType(t, &dhcp.UpdateSharedNetworkUtilizationAlertRuleOK{}, rsp)
+
+	// Invalid rule.
+	rsp = rapi.UpdateSubnetUtilizationAlertRule(ctx, dhcp.UpdateSubnetUtilizationAlertRuleParams{
+		ID: subnet.ID,
+		Rule: &models.UtilizationAlertRule{
+			WarningThreshold: storkutil.Ptr[int64](80),
+			ErrorThreshold:   storkutil.Ptr[int64](70),
+		},
+	})
+	require.IsType(t, &dhcp.UpdateSubnetUtilizationAlertRuleDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*dhcp.UpdateSubnetUtilizationAlertRuleDefault)))
+
+	// Non-existing subnet.
+	rsp = rapi.UpdateSubnetUtilizationAlertRule(ctx, dhcp.UpdateSubnetUtilizationAlertRuleParams{
+		ID: subnet.ID + 100,
+		Rule: &models.UtilizationAlertRule{
+			WarningThreshold: storkutil.Ptr[int64](80),
+			ErrorThreshold:   storkutil.Ptr[int64](90),
+		},
+	})
+	require.IsType(t, &dhcp.UpdateSubnetUtilizationAlertRuleDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*dhcp.UpdateSubnetUtilizationAlertRuleDefault)))
+
+	// List the rules.
+	rsp = rapi.GetUtilizationAlertRules(ctx, dhcp.GetUtilizationAlertRulesParams{})
+	require.IsType(t, &dhcp.GetUtilizationAlertRulesOK{}, rsp)
+	rules := rsp.(*dhcp.GetUtilizationAlertRulesOK).Payload
+	require.EqualValues(t, 2, rules.Total)
+	require.Len(t, rules.Items, 2)
+	require.Equal(t, subnet.ID, rules.Items[0].SubnetID)
+	require.True(t, rules.Items[0].Enabled)
+	require.EqualValues(t, 60, *rules.Items[0].WarningThreshold)
+	require.EqualValues(t, 75, *rules.Items[0].ErrorThreshold)
+	require.Equal(t, sharedNetwork.ID, rules.Items[1].SharedNetworkID)
+	require.Equal(t, "frog", rules.Items[1].SharedNetwork)
+	require.False(t, rules.Items[1].Enabled)
+
+	// Delete the rules.
+	rsp = rapi.DeleteSubnetUtilizationAlertRule(ctx, dhcp.DeleteSubnetUtilizationAlertRuleParams{ID: subnet.ID})
+	require.IsType(t, &dhcp.DeleteSubnetUtilizationAlertRuleOK{}, rsp)
+	rsp = rapi.DeleteSubnetUtilizationAlertRule(ctx, dhcp.DeleteSubnetUtilizationAlertRuleParams{ID: subnet.ID})
+	require.IsType(t, &dhcp.DeleteSubnetUtilizationAlertRuleDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*dhcp.DeleteSubnetUtilizationAlertRuleDefault)))
+
+	rsp = rapi.DeleteSharedNetworkUtilizationAlertRule(ctx, dhcp.DeleteSharedNetworkUtilizationAlertRuleParams{ID: sharedNetwork.ID})
+	require.IsType(t, &dhcp.DeleteSharedNetworkUtilizationAlertRuleOK{}, rsp)
+
+	rsp = rapi.GetUtilizationAlertRules(ctx, dhcp.GetUtilizationAlertRulesParams{})
+	require.IsType(t, &dhcp.GetUtilizationAlertRulesOK{}, rsp)
+	require.Empty(t, rsp.(*dhcp.GetUtilizationAlertRulesOK).Payload.Items)
+}
+
+// Test that the currently raised utilization alerts are returned.
+func TestGetUtilizationAlerts(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	subnet := &dbmodel.Subnet{
+		Prefix: "192.0.2.0/24",
+	}
+	err := dbmodel.AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	err = dbmodel.SetUtilizationAlert(db, &dbmodel.UtilizationAlert{
+		SubnetID:    subnet.ID,
+		Kind:        dbmodel.UtilizationAlertKindAddress,
+		Level:       dbmodel.EvError,
+		Utilization: 925,
+	})
+	require.NoError(t, err)
+
+	rapi, err := NewRestAPI(dbSettings, db)
+	require.NoError(t, err)
+
+	rsp := rapi.GetUtilizationAlerts(context.Background(), dhcp.GetUtilizationAlertsParams{})
+	require.IsType(t, &dhcp.GetUtilizationAlertsOK{}, rsp)
+	alerts := rsp.(*dhcp.GetUtilizationAlertsOK).Payload
+	require.EqualValues(t, 1, alerts.Total)
+	require.Len(t, alerts.Items, 1)
+	require.Equal(t, subnet.ID, alerts.Items[0].SubnetID)
+	require.Equal(t, "192.0.2.0/24", alerts.Items[0].Subnet)
+	require.Equal(t, "address", alerts.Items[0].Kind)
+	require.EqualValues(t, dbmodel.EvError, alerts.Items[0].Level)
+	require.InDelta(t, 92.5, alerts.Items[0].Utilization, 0.001)
+}
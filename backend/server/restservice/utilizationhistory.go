@@ -0,0 +1,184 @@
+package restservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
+	log "github.com/sirupsen/logrus"
+
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
+	storkutil "isc.org/stork/util"
+)
+
+// Names of the utilization history resolutions used by the REST API.
+var utilizationHistoryResolutionNames = map[string]time.Duration{
+	"5m": dbmodel.UtilizationHistoryResolutionShort,
+	"1h": dbmodel.UtilizationHistoryResolutionLong,
+}
+
+// Selects the resolution and the time range of the utilization history
+// from the optional query parameters. If the resolution is not specified,
+// the hourly resolution is selected when the range begins earlier than the
+// retention of the 5-minute history. The range defaults to the retention
+// of the resolution ending at the current time. It returns an error
+// message if the parameters are invalid.
+func getUtilizationHistoryRange(resolution *string, from, to *strfmt.DateTime, now time.Time) (string, time.Time, time.Time, string) {
+	end := now
+	if to != nil {
+		end = time.Time(*to).UTC()
+	}
+
+	var name string
+	switch {
+	case resolution != nil:
+		name = *resolution
+		if _, ok := utilizationHistoryResolutionNames[name]; !ok {
+			return "", time.Time{}, time.Time{}, fmt.Sprintf("unsupported resolution %s", name)
+		}
+	case from != nil && now.Sub(time.Time(*from)) > dbmodel.GetUtilizationHistoryRetention(dbmodel.UtilizationHistoryResolutionShort):
+		name = "1h"
+	default:
+		name = "5m"
+	}
+
+	start := now.Add(-dbmodel.GetUtilizationHistoryRetention(utilizationHistoryResolutionNames[name]))
+	if from != nil {
+		start = time.Time(*from).UTC()
+	}
+	if start.After(end) {
+		return "", time.Time{}, time.Time{}, "the beginning of the time range must not be later than its end"
+	}
+	return name, start, end, ""
+}
+
+// Creates new instance of the utilization history model used by REST API
+// from the history returned from the database. The utilization is
+// converted from permille to percent.
+func newRestUtilizationHistory(resolution string, history []dbmodel.UtilizationHistory) *models.UtilizationHistory {
+	restHistory := &models.UtilizationHistory{
+		Resolution: resolution,
+		Items:      []*models.UtilizationHistoryItem{},
+		Total:      int64(len(history)),
+	}
+	for i := range history {
+		restHistory.Items = append(restHistory.Items, &models.UtilizationHistoryItem{
+			CollectedAt:        strfmt.DateTime(history[i].CollectedAt),
+			Samples:            history[i].Samples,
+			AddrUtilization:    history[i].GetAddrUtilization() / 10,
+			AddrUtilizationMax: float64(history[i].AddrUtilizationMax) / 10,
+			PdUtilization:      history[i].GetPdUtilization() / 10,
+			PdUtilizationMax:   float64(history[i].PdUtilizationMax) / 10,
+		})
+	}
+	return restHistory
+}
+
+// Get the utilization history of the subnet.
+func (r *RestAPI) GetSubnetUtilizationHistory(ctx context.Context, params dhcp.GetSubnetUtilizationHistoryParams) middleware.Responder {
+	resolution, from, to, invalid := getUtilizationHistoryRange(params.Resolution, params.From, params.To, storkutil.UTCNow())
+	if invalid != "" {
+		msg := fmt.Sprintf("Failed to get utilization history of subnet with ID %d: %s", params.ID, invalid)
+		log.Warn(msg)
+		rsp := dhcp.NewGetSubnetUtilizationHistoryDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	dbSubnet, err := dbmodel.GetSubnet(r.DB, params.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Problem fetching subnet with ID %d from db", params.ID)
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewGetSubnetUtilizationHistoryDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if dbSubnet == nil {
+		msg := fmt.Sprintf("Cannot find subnet with ID %d", params.ID)
+		rsp := dhcp.NewGetSubnetUtilizationHistoryDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	history, err := dbmodel.GetSubnetUtilizationHistory(r.DB, params.ID, utilizationHistoryResolutionNames[resolution], from, to)
+	if err != nil {
+		msg := fmt.Sprintf("Problem fetching utilization history of subnet with ID %d from db", params.ID)
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewGetSubnetUtilizationHistoryDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewGetSubnetUtilizationHistoryOK().WithPayload(newRestUtilizationHistory(resolution, history))
+	return rsp
+}
+
+// Get the utilization history of the shared network.
+func (r *RestAPI) GetSharedNetworkUtilizationHistory(ctx context.Context, params dhcp.GetSharedNetworkUtilizationHistoryParams) middleware.Responder {
+	resolution, from, to, invalid := getUtilizationHistoryRange(params.Resolution, params.From, params.To, storkutil.UTCNow())
+	if invalid != "" {
+		msg := fmt.Sprintf("Failed to get utilization history of shared network with ID %d: %s", params.ID, invalid)
+		log.Warn(msg)
+		rsp := dhcp.NewGetSharedNetworkUtilizationHistoryDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	dbSharedNetwork, err := dbmodel.GetSharedNetwork(r.DB, params.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Problem fetching shared network with ID %d from db", params.ID)
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewGetSharedNetworkUtilizationHistoryDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if dbSharedNetwork == nil {
+		msg := fmt.Sprintf("Cannot find shared network with ID %d", params.ID)
+		rsp := dhcp.NewGetSharedNetworkUtilizationHistoryDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	history, err := dbmodel.GetSharedNetworkUtilizationHistory(r.DB, params.ID, utilizationHistoryResolutionNames[resolution], from, to)
+	if err != nil {
+		msg := fmt.Sprintf("Problem fetching utilization history of shared network with ID %d from db", params.ID)
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewGetSharedNetworkUtilizationHistoryDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewGetSharedNetworkUtilizationHistoryOK().WithPayload(newRestUtilizationHistory(resolution, history))
+	return rsp
+}
+
+// Get the global utilization history of the family.
+func (r *RestAPI) GetGlobalUtilizationHistory(ctx context.Context, params dhcp.GetGlobalUtilizationHistoryParams) middleware.Responder {
+	resolution, from, to, invalid := getUtilizationHistoryRange(params.Resolution, params.From, params.To, storkutil.UTCNow())
+	if invalid != "" {
+		msg := fmt.Sprintf("Failed to get global utilization history: %s", invalid)
+		log.Warn(msg)
+		rsp := dhcp.NewGetGlobalUtilizationHistoryDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	history, err := dbmodel.GetGlobalUtilizationHistory(r.DB, int(params.Family), utilizationHistoryResolutionNames[resolution], from, to)
+	if err != nil {
+		msg := fmt.Sprintf("Problem fetching global IPv%d utilization history from db", params.Family)
+		log.WithError(err).Error(msg)
+		rsp := dhcp.NewGetGlobalUtilizationHistoryDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewGetGlobalUtilizationHistoryOK().WithPayload(newRestUtilizationHistory(resolution, history))
+	return rsp
+}
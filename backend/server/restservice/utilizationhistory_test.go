@@ -0,0 +1,145 @@
+package restservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
+	storkutil "isc.org/stork/util"
+)
+
+// Test that the resolution and the time range of the utilization history
+// are selected from the query parameters.
+func TestGetUtilizationHistoryRange(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	// Defaults.
+	resolution, from, to, invalid := getUtilizationHistoryRange(nil, nil, nil, now)
+	require.Empty(t, invalid)
+	require.Equal(t, "5m", resolution)
+	require.Equal(t, now.Add(-7*24*time.Hour), from)
+	require.Equal(t, now, to)
+
+	// The range beginning before the retention of the 5-minute history.
+	monthAgo := strfmt.DateTime(now.Add(-30 * 24 * time.Hour))
+	resolution, from, _, invalid = getUtilizationHistoryRange(nil, &monthAgo, nil, now)
+	require.Empty(t, invalid)
+	require.Equal(t, "1h", resolution)
+	require.Equal(t, time.Time(monthAgo), from)
+
+	// Explicit resolution.
+	resolution, from, _, invalid = getUtilizationHistoryRange(storkutil.Ptr("1h"), nil, nil, now)
+	require.Empty(t, invalid)
+	require.Equal(t, "1h", resolution)
+	require.Equal(t, now.Add(-365*24*time.Hour), from)
+
+	// Explicit end.
+	dayAgo := strfmt.DateTime(now.Add(-24 * time.Hour))
+	_, _, to, invalid = getUtilizationHistoryRange(nil, nil, &dayAgo, now)
+	require.Empty(t, invalid)
+	require.Equal(t, time.Time(dayAgo), to)
+
+	// Invalid parameters.
+	_, _, _, invalid = getUtilizationHistoryRange(storkutil.Ptr("1d"), nil, nil, now)
+	require.NotEmpty(t, invalid)
+	_, _, _, invalid = getUtilizationHistoryRange(nil, &dayAgo, &monthAgo, now)
+	require.NotEmpty(t, invalid)
+}
+
+// Test that the average utilization is converted to percent.
+func TestNewRestUtilizationHistory(t *testing.T) {
+	collectedAt := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	history := newRestUtilizationHistory("1h", []dbmodel.UtilizationHistory{
+		{
+			CollectedAt:        collectedAt,
+			Samples:            2,
+			AddrUtilizationSum: 1500,
+			AddrUtilizationMax: 800,
+			PdUtilizationSum:   100,
+			PdUtilizationMax:   60,
+		},
+	})
+	require.Equal(t, "1h", history.Resolution)
+	require.EqualValues(t, 1, history.Total)
+	require.Len(t, history.Items, 1)
+	require.Equal(t, strfmt.DateTime(collectedAt), history.Items[0].CollectedAt)
+	require.EqualValues(t, 2, history.Items[0].Samples)
+	require.InDelta(t, 75, history.Items[0].AddrUtilization, 0.001)
+	require.InDelta(t, 80, history.Items[0].AddrUtilizationMax, 0.001)
+	require.InDelta(t, 5, history.Items[0].PdUtilization, 0.001)
+	require.InDelta(t, 6, history.Items[0].PdUtilizationMax, 0.001)
+}
+
+// Test that the utilization history of the subnets, shared networks and
+// the global utilization history are returned.
+func TestGetUtilizationHistory(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	sharedNetwork := &dbmodel.SharedNetwork{
+		Name:   "frog",
+		Family: 4,
+	}
+	err := dbmodel.AddSharedNetwork(db, sharedNetwork)
+	require.NoError(t, err)
+
+	subnet := &dbmodel.Subnet{
+		Prefix:          "192.0.2.0/24",
+		SharedNetworkID: sharedNetwork.ID,
+	}
+	err = dbmodel.AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	collectedAt := storkutil.UTCNow().Add(-time.Hour)
+	err = dbmodel.AddUtilizationSamples(db, []dbmodel.UtilizationSample{
+		{SubnetID: subnet.ID, Family: 4, AddrUtilization: 500},
+		{SharedNetworkID: sharedNetwork.ID, Family: 4, AddrUtilization: 400},
+		{Family: 4, AddrUtilization: 300},
+	}, collectedAt)
+	require.NoError(t, err)
+
+	rapi, err := NewRestAPI(dbSettings, db)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	rsp := rapi.GetSubnetUtilizationHistory(ctx, dhcp.GetSubnetUtilizationHistoryParams{ID: subnet.ID})
+	require.IsType(t, &dhcp.GetSubnetUtilizationHistoryOK{}, rsp)
+	history := rsp.(*dhcp.GetSubnetUtilizationHistoryOK).Payload
+	require.Equal(t, "5m", history.Resolution)
+	require.Len(t, history.Items, 1)
+	require.InDelta(t, 50, history.Items[0].AddrUtilization, 0.001)
+
+	rsp = rapi.GetSubnetUtilizationHistory(ctx, dhcp.GetSubnetUtilizationHistoryParams{ID: subnet.ID + 100})
+	require.IsType(t, &dhcp.GetSubnetUtilizationHistoryDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*dhcp.GetSubnetUtilizationHistoryDefault)))
+
+	rsp = rapi.GetSharedNetworkUtilizationHistory(ctx, dhcp.GetSharedNetworkUtilizationHistoryParams{
+		ID:         sharedNetwork.ID,
+		Resolution: storkutil.Ptr("1h"),
+	})
+	require.IsType(t, &dhcp.GetSharedNetworkUtilizationHistoryOK{}, rsp)
+	history = rsp.(*dhcp.GetSharedNetworkUtilizationHistoryOK).Payload
+	require.Equal(t, "1h", history.Resolution)
+	require.Len(t, history.Items, 1)
+	require.InDelta(t, 40, history.Items[0].AddrUtilization, 0.001)
+
+	rsp = rapi.GetSharedNetworkUtilizationHistory(ctx, dhcp.GetSharedNetworkUtilizationHistoryParams{ID: sharedNetwork.ID + 100})
+	require.IsType(t, &dhcp.GetSharedNetworkUtilizationHistoryDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*dhcp.GetSharedNetworkUtilizationHistoryDefault)))
+
+	rsp = rapi.GetGlobalUtilizationHistory(ctx, dhcp.GetGlobalUtilizationHistoryParams{Family: 4})
+	require.IsType(t, &dhcp.GetGlobalUtilizationHistoryOK{}, rsp)
+	history = rsp.(*dhcp.GetGlobalUtilizationHistoryOK).Payload
+	require.Len(t, history.Items, 1)
+	require.InDelta(t, 30, history.Items[0].AddrUtilization, 0.001)
+
+	rsp = rapi.GetGlobalUtilizationHistory(ctx, dhcp.GetGlobalUtilizationHistoryParams{Family: 6})
+	require.IsType(t, &dhcp.GetGlobalUtilizationHistoryOK{}, rsp)
+	require.Empty(t, rsp.(*dhcp.GetGlobalUtilizationHistoryOK).Payload.Items)
+}
@@ -0,0 +1,113 @@
+package restservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-openapi/runtime/middleware"
+	log "github.com/sirupsen/logrus"
+
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/dns"
+)
+
+// Converts the zone from the database to the REST API format.
+func (r *RestAPI) convertZoneToRestAPI(zone *dbmodel.Zone) *models.Zone {
+	restZone := &models.Zone{
+		ID:                zone.ID,
+		DaemonID:          zone.DaemonID,
+		View:              zone.View,
+		Name:              zone.Name,
+		Class:             zone.Class,
+		ZoneType:          zone.ZoneType,
+		Serial:            zone.Serial,
+		File:              zone.File,
+		Dynamic:           zone.Dynamic,
+		LoadedAt:          convertToOptionalDatetime(zone.LoadedAt),
+		RefreshAt:         convertToOptionalDatetime(zone.RefreshAt),
+		ExpiresAt:         convertToOptionalDatetime(zone.ExpiresAt),
+		PrimaryZoneID:     zone.PrimaryZoneID,
+		ReplicationStatus: zone.ReplicationStatus,
+		OutOfSyncSince:    convertToOptionalDatetime(zone.OutOfSyncSince),
+	}
+	if zone.PrimaryZone != nil {
+		restZone.PrimarySerial = zone.PrimaryZone.Serial
+	}
+	if zone.Daemon != nil {
+		restZone.AppID = zone.Daemon.AppID
+		if zone.Daemon.App != nil {
+			restZone.AppName = zone.Daemon.App.Name
+			if zone.Daemon.App.Machine != nil {
+				restZone.MachineAddress = zone.Daemon.App.Machine.Address
+			}
+		}
+	}
+	return restZone
+}
+
+// Get list of DNS zones. The list can be filtered by app ID, zone type,
+// replication status and text.
+func (r *RestAPI) GetZones(ctx context.Context, params dns.GetZonesParams) middleware.Responder {
+	var start int64
+	if params.Start != nil {
+		start = *params.Start
+	}
+
+	var limit int64 = 10
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+
+	filters := &dbmodel.ZonesByPageFilters{
+		AppID:             params.AppID,
+		ZoneType:          params.ZoneType,
+		ReplicationStatus: params.ReplicationStatus,
+		Text:              params.Text,
+	}
+
+	dbZones, total, err := dbmodel.GetZonesByPage(r.DB, start, limit, filters, "", dbmodel.SortDirAsc)
+	if err != nil {
+		msg := "Cannot get zones from db"
+		log.WithError(err).Error(msg)
+		rsp := dns.NewGetZonesDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	zones := &models.Zones{
+		Items: []*models.Zone{},
+		Total: total,
+	}
+	for i := range dbZones {
+		zones.Items = append(zones.Items, r.convertZoneToRestAPI(&dbZones[i]))
+	}
+	rsp := dns.NewGetZonesOK().WithPayload(zones)
+	return rsp
+}
+
+// Returns the zone with the details collected from the BIND 9 daemon.
+func (r *RestAPI) GetZone(ctx context.Context, params dns.GetZoneParams) middleware.Responder {
+	dbZone, err := dbmodel.GetZoneByID(r.DB, params.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Problem fetching zone with ID %d from db", params.ID)
+		log.WithError(err).Error(msg)
+		rsp := dns.NewGetZoneDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	if dbZone == nil {
+		msg := fmt.Sprintf("Cannot find zone with ID %d", params.ID)
+		rsp := dns.NewGetZoneDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	rsp := dns.NewGetZoneOK().WithPayload(r.convertZoneToRestAPI(dbZone))
+	return rsp
+}
@@ -0,0 +1,153 @@
+package restservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/restapi/operations/dns"
+	storkutil "isc.org/stork/util"
+)
+
+// Test getting the zones and a single zone over the REST API.
+func TestGetZones(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	machine := &dbmodel.Machine{
+		Address:   "dns.example.org",
+		AgentPort: 8080,
+	}
+	require.NoError(t, dbmodel.AddMachine(db, machine))
+	app := &dbmodel.App{
+		Type:      dbmodel.AppTypeBind9,
+		Name:      "dns1",
+		MachineID: machine.ID,
+		Daemons: []*dbmodel.Daemon{
+			dbmodel.NewBind9Daemon(true),
+		},
+	}
+	_, err := dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+
+	loadedAt := time.Date(2024, 2, 5, 13, 39, 36, 0, time.UTC)
+	err = dbmodel.CommitZonesIntoDB(db, app.Daemons[0].ID, []dbmodel.Zone{
+		{View: "_default", Name: "example.org", Class: "IN", ZoneType: dbmodel.ZoneTypePrimary, Serial: 3, File: "db.example.org", LoadedAt: loadedAt},
+		{View: "_default", Name: "example.com", Class: "IN", ZoneType: dbmodel.ZoneTypeSecondary, Serial: 7},
+	})
+	require.NoError(t, err)
+
+	rapi, err := NewRestAPI(dbSettings, db)
+	require.NoError(t, err)
+
+	rsp := rapi.GetZones(context.Background(), dns.GetZonesParams{})
+	require.IsType(t, &dns.GetZonesOK{}, rsp)
+	zones := rsp.(*dns.GetZonesOK).Payload
+	require.EqualValues(t, 2, zones.Total)
+	require.Len(t, zones.Items, 2)
+	require.Equal(t, "example.com", zones.Items[0].Name)
+	require.Equal(t, dbmodel.ZoneTypeSecondary, zones.Items[0].ZoneType)
+	require.Nil(t, zones.Items[0].LoadedAt)
+	require.Equal(t, "example.org", zones.Items[1].Name)
+	require.Equal(t, app.ID, zones.Items[1].AppID)
+	require.Equal(t, "dns1", zones.Items[1].AppName)
+	require.Equal(t, "dns.example.org", zones.Items[1].MachineAddress)
+	require.EqualValues(t, 3, zones.Items[1].Serial)
+	require.NotNil(t, zones.Items[1].LoadedAt)
+	require.WithinDuration(t, loadedAt, time.Time(*zones.Items[1].LoadedAt), 0)
+
+	// Filter by type.
+	rsp = rapi.GetZones(context.Background(), dns.GetZonesParams{
+		ZoneType: storkutil.Ptr(dbmodel.ZoneTypePrimary),
+	})
+	require.IsType(t, &dns.GetZonesOK{}, rsp)
+	zones = rsp.(*dns.GetZonesOK).Payload
+	require.EqualValues(t, 1, zones.Total)
+	require.Equal(t, "example.org", zones.Items[0].Name)
+
+	// Filter by text.
+	rsp = rapi.GetZones(context.Background(), dns.GetZonesParams{
+		Text: storkutil.Ptr("example.c"),
+	})
+	require.IsType(t, &dns.GetZonesOK{}, rsp)
+	zones = rsp.(*dns.GetZonesOK).Payload
+	require.EqualValues(t, 1, zones.Total)
+	require.Equal(t, "example.com", zones.Items[0].Name)
+
+	// Get a single zone.
+	rsp = rapi.GetZone(context.Background(), dns.GetZoneParams{
+		ID: zones.Items[0].ID,
+	})
+	require.IsType(t, &dns.GetZoneOK{}, rsp)
+	require.Equal(t, "example.com", rsp.(*dns.GetZoneOK).Payload.Name)
+
+	// Non-existing zone.
+	rsp = rapi.GetZone(context.Background(), dns.GetZoneParams{
+		ID: 12345,
+	})
+	require.IsType(t, &dns.GetZoneDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*dns.GetZoneDefault)))
+}
+
+// Test that the replication status of the secondary zones is returned
+// and can be used to filter the zones.
+func TestGetZonesReplicationStatus(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	machine := &dbmodel.Machine{
+		Address:   "dns.example.org",
+		AgentPort: 8080,
+	}
+	require.NoError(t, dbmodel.AddMachine(db, machine))
+	app := &dbmodel.App{
+		Type:      dbmodel.AppTypeBind9,
+		MachineID: machine.ID,
+		Daemons: []*dbmodel.Daemon{
+			dbmodel.NewBind9Daemon(true),
+		},
+	}
+	_, err := dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+
+	zones := []dbmodel.Zone{
+		{View: "external", Name: "example.org", Class: "IN", ZoneType: dbmodel.ZoneTypePrimary, Serial: 3},
+		{View: "internal", Name: "example.org", Class: "IN", ZoneType: dbmodel.ZoneTypeSecondary, Serial: 2},
+	}
+	require.NoError(t, dbmodel.CommitZonesIntoDB(db, app.Daemons[0].ID, zones))
+	outOfSyncSince := time.Date(2024, 2, 5, 12, 0, 0, 0, time.UTC)
+	zones[1].PrimaryZoneID = zones[0].ID
+	zones[1].ReplicationStatus = dbmodel.ZoneReplicationStatusLagging
+	zones[1].OutOfSyncSince = outOfSyncSince
+	require.NoError(t, dbmodel.UpdateZoneReplication(db, &zones[1]))
+
+	rapi, err := NewRestAPI(dbSettings, db)
+	require.NoError(t, err)
+
+	rsp := rapi.GetZones(context.Background(), dns.GetZonesParams{
+		ReplicationStatus: storkutil.Ptr(dbmodel.ZoneReplicationStatusLagging),
+	})
+	require.IsType(t, &dns.GetZonesOK{}, rsp)
+	payload := rsp.(*dns.GetZonesOK).Payload
+	require.EqualValues(t, 1, payload.Total)
+	zone := payload.Items[0]
+	require.Equal(t, "internal", zone.View)
+	require.Equal(t, dbmodel.ZoneReplicationStatusLagging, zone.ReplicationStatus)
+	require.Equal(t, zones[0].ID, zone.PrimaryZoneID)
+	require.EqualValues(t, 3, zone.PrimarySerial)
+	require.NotNil(t, zone.OutOfSyncSince)
+	require.WithinDuration(t, outOfSyncSince, time.Time(*zone.OutOfSyncSince), 0)
+
+	// The primary zone has no replication status.
+	rsp = rapi.GetZone(context.Background(), dns.GetZoneParams{
+		ID: zones[0].ID,
+	})
+	require.IsType(t, &dns.GetZoneOK{}, rsp)
+	require.Empty(t, rsp.(*dns.GetZoneOK).Payload.ReplicationStatus)
+	require.Nil(t, rsp.(*dns.GetZoneOK).Payload.OutOfSyncSince)
+}
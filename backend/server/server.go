@@ -23,6 +23,7 @@ import (
 	"isc.org/stork/server/eventcenter"
 	"isc.org/stork/server/hookmanager"
 	"isc.org/stork/server/metrics"
+	"isc.org/stork/server/notifications"
 	"isc.org/stork/server/restservice"
 )
 
@@ -39,12 +40,18 @@ type StorkServer struct {
 
 	GeneralSettings GeneralSettings
 
+	SMTPSettings notifications.SMTPSettings
+
 	Pullers *apps.Pullers
 
 	MetricsCollector metrics.Collector
 
 	EventCenter eventcenter.EventCenter
 
+	Notifier notifications.Notifier
+
+	DeclinedLeasesReleaser *kea.DeclinedLeasesReleaser
+
 	ReviewDispatcher configreview.Dispatcher
 	// Configuration manager instance. Note that it inherits some fields
 	// maintained by the server.
@@ -68,6 +75,7 @@ func (ss *StorkServer) ParseArgs() (command Command, err error) {
 		ss.DBSettings = *settings.DatabaseSettings
 		ss.GeneralSettings = *settings.GeneralSettings
 		ss.RestAPISettings = *settings.RestAPISettings
+		ss.SMTPSettings = *settings.SMTPSettings
 	}
 	return command, err
 }
@@ -128,8 +136,12 @@ func (ss *StorkServer) Bootstrap(reload bool) (err error) {
 		return err
 	}
 
+	// setup notifier sending the events and config review issues
+	// over the notification channels
+	ss.Notifier = notifications.NewNotifier(ss.DB, &ss.SMTPSettings)
+
 	// setup event center
-	ss.EventCenter = eventcenter.NewEventCenter(ss.DB)
+	ss.EventCenter = eventcenter.NewEventCenter(ss.DB, ss.Notifier)
 
 	// setup connected agents
 	ss.Agents = agentcomm.NewConnectedAgents(&ss.AgentsSettings, ss.EventCenter, caCertPEM, serverCertPEM, serverKeyPEM)
@@ -142,7 +154,7 @@ func (ss *StorkServer) Bootstrap(reload bool) (err error) {
 	// }()
 
 	// Setup configuration review dispatcher.
-	ss.ReviewDispatcher = configreview.NewDispatcher(ss.DB)
+	ss.ReviewDispatcher = configreview.NewDispatcher(ss.DB, ss.Notifier)
 	configreview.RegisterDefaultCheckers(ss.ReviewDispatcher)
 	err = configreview.LoadAndValidateCheckerPreferences(ss.DB, ss.ReviewDispatcher)
 	if err != nil {
@@ -175,7 +187,7 @@ func (ss *StorkServer) Bootstrap(reload bool) (err error) {
 	}
 
 	// setup kea stats puller
-	ss.Pullers.KeaStatsPuller, err = kea.NewStatsPuller(ss.DB, ss.Agents)
+	ss.Pullers.KeaStatsPuller, err = kea.NewStatsPuller(ss.DB, ss.Agents, ss.EventCenter)
 	if err != nil {
 		return err
 	}
@@ -218,6 +230,9 @@ func (ss *StorkServer) Bootstrap(reload bool) (err error) {
 		return err
 	}
 
+	// Releases the declined leases in the background on user's request.
+	ss.DeclinedLeasesReleaser = kea.NewDeclinedLeasesReleaser(ss.DB, ss.Agents, ss.EventCenter)
+
 	// Endpoint control holds the list of explicitly disabled REST API endpoints.
 	endpointControl := restservice.NewEndpointControl()
 	endpointControl.SetEnabled(restservice.EndpointOpCreateNewMachine, enableMachineRegistration)
@@ -226,8 +241,10 @@ func (ss *StorkServer) Bootstrap(reload bool) (err error) {
 	r, err := restservice.NewRestAPI(&ss.RestAPISettings, &ss.DBSettings,
 		ss.DB, ss.Agents, ss.EventCenter,
 		ss.Pullers, ss.ReviewDispatcher, ss.MetricsCollector, ss.ConfigManager,
-		ss.DHCPOptionDefinitionLookup, ss.HookManager, endpointControl)
+		ss.DHCPOptionDefinitionLookup, ss.HookManager, endpointControl,
+		ss.DeclinedLeasesReleaser, ss.Notifier)
 	if err != nil {
+		ss.DeclinedLeasesReleaser.Shutdown()
 		ss.Pullers.HAStatusPuller.Shutdown()
 		ss.Pullers.KeaHostsPuller.Shutdown()
 		ss.Pullers.KeaStatsPuller.Shutdown()
@@ -280,9 +297,11 @@ func (ss *StorkServer) Shutdown(reload bool) {
 		ss.Pullers.KeaStatsPuller.Shutdown()
 		ss.Pullers.Bind9StatsPuller.Shutdown()
 		ss.Pullers.AppsStatePuller.Shutdown()
+		ss.DeclinedLeasesReleaser.Shutdown()
 		ss.Agents.Shutdown()
 		ss.EventCenter.Shutdown()
 		ss.ReviewDispatcher.Shutdown()
+		ss.Notifier.Shutdown()
 		if ss.MetricsCollector != nil {
 			ss.MetricsCollector.Shutdown()
 		}
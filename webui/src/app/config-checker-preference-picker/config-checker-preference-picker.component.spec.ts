@@ -174,6 +174,26 @@ describe('ConfigCheckerPreferencePickerComponent', () => {
         expect(content).toContain('The checker verifying if the host_cmds hooks library is loaded')
     })
 
+    it('should have descriptions of the DDNS checkers', () => {
+        const checkers = ['ddns_updates_without_d2', 'd2_domains_without_servers']
+        for (const checker of checkers) {
+            expect(component.getCheckerDescription(checker)).toMatch(/^The checker verifying/)
+        }
+    })
+
+    it('should have descriptions of the BIND 9 checkers', () => {
+        const checkers = [
+            'bind9_open_recursion',
+            'bind9_unrestricted_zone_transfer',
+            'bind9_statistics_channel_exposed',
+            'bind9_rndc_key_weak_algorithm',
+            'bind9_dnssec_validation_disabled',
+        ]
+        for (const checker of checkers) {
+            expect(component.getCheckerDescription(checker)).toMatch(/^The checker verifying/)
+        }
+    })
+
     it('should display the checker selectors', () => {
         component.checkers = [
             {
@@ -202,6 +202,14 @@ export class ConfigCheckerPreferencePickerComponent {
                     'via the HTTP ports exposed by the dedicated listeners ' +
                     'rather than Kea Control Agent.'
                 )
+            case 'ha_peers_consistency':
+                return (
+                    'The checker verifies if the servers in the ' +
+                    'High-Availability relationships have consistent ' +
+                    'subnets, pools, reservations, client classes, option ' +
+                    'data and HA peer lists. The interface names, comments ' +
+                    'and user contexts are not compared.'
+                )
             case 'address_pools_exhausted_by_reservations':
                 return 'The checker verifying if all available addresses in IP pools are not reserved for hosts.'
             case 'pd_pools_exhausted_by_reservations':
@@ -227,6 +235,45 @@ export class ConfigCheckerPreferencePickerComponent {
                     'unavailable or inaccurate due to the number overflow in ' +
                     'the statistics returned by the Kea DHCP daemon.'
                 )
+            case 'ddns_updates_without_d2':
+                return (
+                    'The checker verifying if the Kea DHCP server sending the ' +
+                    'DNS update requests has a running D2 server on the same ' +
+                    'machine to receive them.'
+                )
+            case 'd2_domains_without_servers':
+                return (
+                    'The checker verifying if the forward and reverse DDNS ' +
+                    'domains configured in the D2 server have DNS servers.'
+                )
+            case 'bind9_open_recursion':
+                return (
+                    'The checker verifying if the BIND 9 server does not ' +
+                    'allow recursive queries from any host, i.e., it is not ' +
+                    'an open resolver.'
+                )
+            case 'bind9_unrestricted_zone_transfer':
+                return (
+                    'The checker verifying if the transfers of the primary ' +
+                    'and secondary zones are restricted with the ' +
+                    'allow-transfer statement.'
+                )
+            case 'bind9_statistics_channel_exposed':
+                return (
+                    'The checker verifying if the BIND 9 statistics channels ' +
+                    'listen only on the loopback addresses.'
+                )
+            case 'bind9_rndc_key_weak_algorithm':
+                return (
+                    'The checker verifying if the keys used by rndc to ' +
+                    'control the BIND 9 server do not use the weak HMAC-MD5 ' +
+                    'or HMAC-SHA1 algorithms.'
+                )
+            case 'bind9_dnssec_validation_disabled':
+                return (
+                    'The checker verifying if the DNSSEC validation is not ' +
+                    'disabled in the BIND 9 server allowing recursion.'
+                )
             default:
                 return ''
         }